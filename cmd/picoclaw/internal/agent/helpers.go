@@ -13,9 +13,12 @@ import (
 
 	"github.com/sipeed/picoclaw/cmd/picoclaw/internal"
 	"github.com/sipeed/picoclaw/pkg/agent"
+	"github.com/sipeed/picoclaw/pkg/audit"
 	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/egress"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/redact"
 )
 
 func agentCmd(message, sessionKey, model string, debug bool) error {
@@ -32,6 +35,13 @@ func agentCmd(message, sessionKey, model string, debug bool) error {
 	if err != nil {
 		return fmt.Errorf("error loading config: %w", err)
 	}
+	redact.Install(cfg)
+	if err := audit.Install(cfg.Audit, "agent"); err != nil {
+		return fmt.Errorf("error opening audit log: %w", err)
+	}
+	if err := egress.Install(cfg.Egress); err != nil {
+		return fmt.Errorf("invalid egress policy: %w", err)
+	}
 
 	if model != "" {
 		cfg.Agents.Defaults.ModelName = model
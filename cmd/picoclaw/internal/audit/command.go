@@ -0,0 +1,39 @@
+package audit
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sipeed/picoclaw/cmd/picoclaw/internal"
+	"github.com/sipeed/picoclaw/pkg/audit"
+)
+
+func NewAuditCommand() *cobra.Command {
+	var dir string
+
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Inspect the audit log of agent actions",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return cmd.Help()
+		},
+		// Resolve the log directory at execution time from the current config.
+		PersistentPreRunE: func(_ *cobra.Command, _ []string) error {
+			cfg, err := internal.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("error loading config: %w", err)
+			}
+			dir = audit.Dir(cfg.Audit)
+			return nil
+		},
+	}
+
+	cmd.AddCommand(
+		newVerifyCommand(func() string { return dir }),
+		newQueryCommand(func() string { return dir }),
+	)
+
+	return cmd
+}
@@ -0,0 +1,29 @@
+package audit
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAuditCommand(t *testing.T) {
+	cmd := NewAuditCommand()
+
+	require.NotNil(t, cmd)
+
+	assert.Equal(t, "Inspect the audit log of agent actions", cmd.Short)
+	assert.False(t, cmd.HasFlags())
+	assert.NotNil(t, cmd.RunE)
+	assert.NotNil(t, cmd.PersistentPreRunE)
+
+	allowedCommands := []string{"verify", "query"}
+
+	subcommands := cmd.Commands()
+	assert.Len(t, subcommands, len(allowedCommands))
+	for _, subcmd := range subcommands {
+		assert.True(t, slices.Contains(allowedCommands, subcmd.Name()), "unexpected subcommand %q", subcmd.Name())
+		assert.NotNil(t, subcmd.RunE)
+	}
+}
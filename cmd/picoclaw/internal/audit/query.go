@@ -0,0 +1,88 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sipeed/picoclaw/pkg/audit"
+)
+
+func newQueryCommand(dir func() string) *cobra.Command {
+	var (
+		filter   audit.Filter
+		since    string
+		until    string
+		jsonMode bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "query",
+		Short: "Search the audit log",
+		Args:  cobra.NoArgs,
+		Example: `picoclaw audit query --type tool_call --since 24h
+picoclaw audit query --actor telegram:123456 --limit 20
+picoclaw audit query --contains exec --json`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			var err error
+			if filter.Since, err = parseTime(since); err != nil {
+				return fmt.Errorf("invalid --since: %w", err)
+			}
+			if filter.Until, err = parseTime(until); err != nil {
+				return fmt.Errorf("invalid --until: %w", err)
+			}
+			return auditQueryCmd(cmd.OutOrStdout(), dir(), filter, jsonMode)
+		},
+	}
+
+	cmd.Flags().StringVar(&filter.Type, "type", "", "Event type (inbound_message, tool_call, model_switch, "+
+		"config_change, approval)")
+	cmd.Flags().StringVar(&filter.Actor, "actor", "", "Actor ID, e.g. telegram:123456")
+	cmd.Flags().StringVar(&filter.Source, "source", "", "Log source (gateway, web, agent, mcp)")
+	cmd.Flags().StringVar(&filter.Contains, "contains", "", "Substring of the event data")
+	cmd.Flags().StringVar(&since, "since", "", "Start time (RFC 3339) or age such as 24h")
+	cmd.Flags().StringVar(&until, "until", "", "End time (RFC 3339) or age such as 1h")
+	cmd.Flags().IntVarP(&filter.Limit, "limit", "n", 50, "Show at most this many of the newest entries (0 for all)")
+	cmd.Flags().BoolVar(&jsonMode, "json", false, "Print entries as JSON lines")
+
+	return cmd
+}
+
+// parseTime accepts an RFC 3339 timestamp or a duration meaning "that long ago".
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func auditQueryCmd(out io.Writer, dir string, filter audit.Filter, jsonMode bool) error {
+	entries, err := audit.Query(dir, filter)
+	if err != nil {
+		return err
+	}
+	if jsonMode {
+		enc := json.NewEncoder(out)
+		for _, e := range entries {
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if len(entries) == 0 {
+		fmt.Fprintln(out, "No matching audit entries.")
+		return nil
+	}
+	for _, e := range entries {
+		fmt.Fprintf(out, "%s  %-7s #%-6d %-15s %-24s %s\n",
+			e.Time.Local().Format("2006-01-02 15:04:05"), e.Source, e.Seq, e.Type, e.Actor, string(e.Data))
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package audit
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sipeed/picoclaw/pkg/audit"
+)
+
+func TestAuditQueryCmd(t *testing.T) {
+	dir := t.TempDir()
+	writeTestLog(t, dir)
+
+	var out bytes.Buffer
+	require.NoError(t, auditQueryCmd(&out, dir, audit.Filter{Contains: "exec"}, false))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 1)
+	assert.Contains(t, lines[0], "tool_call")
+	assert.Contains(t, lines[0], "telegram:1")
+
+	out.Reset()
+	require.NoError(t, auditQueryCmd(&out, dir, audit.Filter{Limit: 1}, true))
+	assert.Contains(t, out.String(), `"seq":2`)
+
+	out.Reset()
+	require.NoError(t, auditQueryCmd(&out, dir, audit.Filter{Type: audit.TypeApproval}, false))
+	assert.Equal(t, "No matching audit entries.\n", out.String())
+}
+
+func TestParseTime(t *testing.T) {
+	got, err := parseTime("2h")
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(-2*time.Hour), got, time.Minute)
+
+	got, err = parseTime("2026-01-02T03:04:05Z")
+	require.NoError(t, err)
+	assert.Equal(t, 2026, got.Year())
+
+	_, err = parseTime("yesterday")
+	assert.Error(t, err)
+}
@@ -0,0 +1,53 @@
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sipeed/picoclaw/pkg/audit"
+)
+
+func newVerifyCommand(dir func() string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Check the audit log hash chains for tampering",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return auditVerifyCmd(cmd.OutOrStdout(), dir())
+		},
+	}
+
+	return cmd
+}
+
+func auditVerifyCmd(out io.Writer, dir string) error {
+	reports, err := audit.Verify(dir)
+	if err != nil {
+		return err
+	}
+	if len(reports) == 0 {
+		fmt.Fprintf(out, "No audit logs in %s\n", dir)
+		return nil
+	}
+
+	failed := false
+	for _, r := range reports {
+		if r.Err != nil {
+			failed = true
+			fmt.Fprintf(out, "✗ %s: TAMPERED after %d valid entries: %v\n", r.Source, r.Entries, r.Err)
+			continue
+		}
+		fmt.Fprintf(out, "✓ %s: %d entries (#%d-#%d) in %d files\n",
+			r.Source, r.Entries, r.FirstSeq, r.LastSeq, r.Files)
+		if r.Truncated {
+			fmt.Fprintf(out, "  note: entries before #%d were removed by rotation (max_files)\n", r.FirstSeq)
+		}
+	}
+	if failed {
+		return errors.New("audit log verification failed")
+	}
+	return nil
+}
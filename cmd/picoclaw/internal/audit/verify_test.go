@@ -0,0 +1,46 @@
+package audit
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sipeed/picoclaw/pkg/audit"
+)
+
+func writeTestLog(t *testing.T, dir string) {
+	t.Helper()
+	l, err := audit.Open(audit.Options{Dir: dir, Source: "gateway"})
+	require.NoError(t, err)
+	defer l.Close()
+	for _, tool := range []string{"web_fetch", "exec"} {
+		require.NoError(t, l.Append(audit.Event{
+			Type:  audit.TypeToolCall,
+			Actor: audit.Actor{ID: "telegram:1"},
+			Data:  map[string]any{"tool": tool},
+		}))
+	}
+}
+
+func TestAuditVerifyCmd(t *testing.T) {
+	dir := t.TempDir()
+	writeTestLog(t, dir)
+
+	var out bytes.Buffer
+	require.NoError(t, auditVerifyCmd(&out, dir))
+	assert.Contains(t, out.String(), "✓ gateway: 2 entries")
+
+	path := filepath.Join(dir, "gateway.jsonl")
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, []byte(strings.Replace(string(data), "exec", "ls", 1)), 0o600))
+
+	out.Reset()
+	assert.Error(t, auditVerifyCmd(&out, dir))
+	assert.Contains(t, out.String(), "✗ gateway: TAMPERED after 1 valid entries")
+}
@@ -2,10 +2,13 @@ package auth
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"strings"
 	"time"
@@ -13,6 +16,7 @@ import (
 	"github.com/sipeed/picoclaw/cmd/picoclaw/internal"
 	"github.com/sipeed/picoclaw/pkg/auth"
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/mcp"
 	"github.com/sipeed/picoclaw/pkg/providers"
 )
 
@@ -507,6 +511,122 @@ func authModelsCmd() error {
 	return nil
 }
 
+func authMCPCmd(serverName string, port int) error {
+	appCfg, err := internal.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("error loading config: %w", err)
+	}
+	serverCfg, ok := appCfg.Tools.MCP.Servers[serverName]
+	if !ok {
+		return fmt.Errorf("MCP server %q is not configured", serverName)
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		return fmt.Errorf("starting callback server on port %d: %w", port, err)
+	}
+	redirectURI := fmt.Sprintf("http://localhost:%d/auth/callback", listener.Addr().(*net.TCPAddr).Port)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	flow, err := mcp.StartOAuth(ctx, serverName, serverCfg, redirectURI)
+	if err != nil {
+		listener.Close()
+		return fmt.Errorf("authorization failed: %w", err)
+	}
+
+	codeCh := make(chan string, 1)
+	errCh := make(chan error, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/auth/callback", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("state") != flow.State {
+			http.Error(w, "State mismatch", http.StatusBadRequest)
+			return
+		}
+		code := r.URL.Query().Get("code")
+		if code == "" {
+			select {
+			case errCh <- fmt.Errorf("no code received: %s", r.URL.Query().Get("error")):
+			default:
+			}
+			http.Error(w, "No authorization code received", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><body><h2>Authorization successful!</h2><p>You can close this window.</p></body></html>")
+		select {
+		case codeCh <- code:
+		default:
+		}
+	})
+
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	defer func() {
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer shutdownCancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Printf("Open this URL to authorize MCP server %s:\n\n%s\n\n", serverName, flow.AuthURL)
+	if err = auth.OpenBrowser(flow.AuthURL); err != nil {
+		fmt.Println("Could not open browser automatically. Please open the URL above manually.")
+	}
+	fmt.Println("If this machine is headless, PASTE the final redirect URL (or just the code) here.")
+	fmt.Println("Waiting for authorization...")
+
+	go func() {
+		input, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		input = strings.TrimSpace(input)
+		if strings.Contains(input, "?") {
+			if u, parseErr := url.Parse(input); parseErr == nil {
+				input = u.Query().Get("code")
+			}
+		}
+		if input != "" {
+			select {
+			case codeCh <- input:
+			default:
+			}
+		}
+	}()
+
+	var code string
+	select {
+	case code = <-codeCh:
+	case err = <-errCh:
+		return fmt.Errorf("authorization failed: %w", err)
+	case <-ctx.Done():
+		return fmt.Errorf("authorization timed out after 5 minutes")
+	}
+
+	cred, err := flow.Complete(ctx, code)
+	if err != nil {
+		return fmt.Errorf("authorization failed: %w", err)
+	}
+
+	// Tokens are only sent when oauth is enabled for the server.
+	if serverCfg.OAuth == nil || !serverCfg.OAuth.Enabled {
+		if serverCfg.OAuth == nil {
+			serverCfg.OAuth = &config.MCPOAuthConfig{}
+		}
+		serverCfg.OAuth.Enabled = true
+		appCfg.Tools.MCP.Servers[serverName] = serverCfg
+		if err = config.SaveConfig(internal.GetConfigPath(), appCfg); err != nil {
+			return fmt.Errorf("could not update config: %w", err)
+		}
+	}
+
+	fmt.Printf("MCP server %s authorized!\n", serverName)
+	if !cred.ExpiresAt.IsZero() {
+		fmt.Printf("Token expires: %s (refreshed automatically)\n", cred.ExpiresAt.Format("2006-01-02 15:04"))
+	}
+	fmt.Printf("Remove with: picoclaw auth logout --provider %s\n", mcp.OAuthCredentialKey(serverName))
+
+	return nil
+}
+
 // isAntigravityModel checks if a model string belongs to antigravity provider
 func isAntigravityModel(model string) bool {
 	return model == "antigravity" ||
@@ -0,0 +1,20 @@
+package auth
+
+import "github.com/spf13/cobra"
+
+func newMCPCommand() *cobra.Command {
+	var port int
+
+	cmd := &cobra.Command{
+		Use:   "mcp <server>",
+		Short: "Authorize a remote MCP server via OAuth",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return authMCPCmd(args[0], port)
+		},
+	}
+
+	cmd.Flags().IntVar(&port, "port", 0, "Local port for the OAuth callback (default: any free port)")
+
+	return cmd
+}
@@ -0,0 +1,21 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMCPSubcommand(t *testing.T) {
+	cmd := newMCPCommand()
+
+	require.NotNil(t, cmd)
+
+	assert.Equal(t, "mcp <server>", cmd.Use)
+	assert.Equal(t, "Authorize a remote MCP server via OAuth", cmd.Short)
+
+	assert.NotNil(t, cmd.Flags().Lookup("port"))
+	assert.Error(t, cmd.Args(cmd, nil))
+	assert.NoError(t, cmd.Args(cmd, []string{"github"}))
+}
@@ -0,0 +1,20 @@
+package credential
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func NewCredentialCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "credential",
+		Short: "Manage encrypted credentials (enc:// values)",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return cmd.Help()
+		},
+	}
+
+	cmd.AddCommand(newRotateCommand())
+
+	return cmd
+}
@@ -0,0 +1,24 @@
+package credential
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCredentialCommand(t *testing.T) {
+	cmd := NewCredentialCommand()
+
+	require.NotNil(t, cmd)
+
+	assert.Equal(t, "Manage encrypted credentials (enc:// values)", cmd.Short)
+	assert.False(t, cmd.HasFlags())
+	assert.NotNil(t, cmd.RunE)
+
+	subcommands := cmd.Commands()
+	require.Len(t, subcommands, 1)
+	assert.Equal(t, "rotate", subcommands[0].Name())
+	assert.NotNil(t, subcommands[0].Flags().Lookup("same-passphrase"))
+	assert.NotNil(t, subcommands[0].Flags().Lookup("dry-run"))
+}
@@ -0,0 +1,163 @@
+package credential
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/sipeed/picoclaw/cmd/picoclaw/internal"
+	"github.com/sipeed/picoclaw/pkg/credential"
+	"github.com/sipeed/picoclaw/pkg/fileutil"
+)
+
+// newPassphraseEnvVar supplies the new passphrase non-interactively.
+const newPassphraseEnvVar = "PICOCLAW_NEW_KEY_PASSPHRASE"
+
+type rotateOptions struct {
+	oldPassphrase string
+	oldSSHKey     string
+	newPassphrase string
+	newSSHKey     string
+	dryRun        bool
+}
+
+func newRotateCommand() *cobra.Command {
+	var (
+		samePassphrase bool
+		opts           rotateOptions
+	)
+
+	cmd := &cobra.Command{
+		Use:   "rotate",
+		Short: "Re-encrypt all enc:// values in the config with a new passphrase or SSH key",
+		Long: `Decrypts every enc:// value in the config with the current passphrase and
+SSH key, and encrypts it again with the new ones in the current format.
+
+The current passphrase comes from the usual sources (PICOCLAW_KEY_PASSPHRASE,
+PICOCLAW_KEY_KEYRING, PICOCLAW_KEY_PASSPHRASE_FILE or
+PICOCLAW_KEY_PASSPHRASE_COMMAND) or is prompted for. The new one comes from
+PICOCLAW_NEW_KEY_PASSPHRASE or is prompted for.
+
+Nothing is written unless every value decrypts.`,
+		Example: `  picoclaw credential rotate
+  picoclaw credential rotate --same-passphrase     # only upgrade legacy values
+  picoclaw credential rotate --new-ssh-key ~/.ssh/picoclaw_new.key`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			var err error
+			opts.oldPassphrase, err = currentPassphrase(cmd.ErrOrStderr())
+			if err != nil {
+				return err
+			}
+			switch {
+			case samePassphrase:
+				opts.newPassphrase = opts.oldPassphrase
+			case os.Getenv(newPassphraseEnvVar) != "":
+				opts.newPassphrase = os.Getenv(newPassphraseEnvVar)
+			default:
+				opts.newPassphrase, err = promptNewPassphrase(cmd.ErrOrStderr())
+				if err != nil {
+					return err
+				}
+			}
+			return rotateCmd(cmd.OutOrStdout(), internal.GetConfigPath(), opts)
+		},
+	}
+
+	cmd.Flags().BoolVar(&samePassphrase, "same-passphrase", false,
+		"Keep the current passphrase (re-encrypts legacy values in the current format)")
+	cmd.Flags().StringVar(&opts.oldSSHKey, "old-ssh-key", "", "SSH private key the values are encrypted with (default: auto-detect)")
+	cmd.Flags().StringVar(&opts.newSSHKey, "new-ssh-key", "", "SSH private key to encrypt with (default: auto-detect)")
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "Check that every value decrypts without writing the config")
+
+	return cmd
+}
+
+func rotateCmd(out io.Writer, configPath string, opts rotateOptions) error {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return fmt.Errorf("error reading config: %w", err)
+	}
+
+	legacy := 0
+	rotated, count, err := credential.RewriteEncrypted(data, func(value string) (string, error) {
+		if credential.IsLegacy(value) {
+			legacy++
+		}
+		if opts.dryRun {
+			if _, err := credential.Decrypt(opts.oldPassphrase, opts.oldSSHKey, value); err != nil {
+				return "", err
+			}
+			return value, nil
+		}
+		return credential.Reencrypt(value, opts.oldPassphrase, opts.oldSSHKey, opts.newPassphrase, opts.newSSHKey)
+	})
+	if err != nil {
+		return fmt.Errorf("config left unchanged: %w", err)
+	}
+	if count == 0 {
+		fmt.Fprintf(out, "No enc:// values in %s\n", configPath)
+		return nil
+	}
+	if opts.dryRun {
+		fmt.Fprintf(out, "All %d enc:// values decrypt (%d in the legacy format); nothing written\n", count, legacy)
+		return nil
+	}
+
+	if err := fileutil.WriteFileAtomic(configPath, rotated, 0o600); err != nil {
+		return fmt.Errorf("error writing config: %w", err)
+	}
+	fmt.Fprintf(out, "✓ Re-encrypted %d values in %s (%d upgraded from the legacy format)\n", count, configPath, legacy)
+	if opts.newPassphrase != opts.oldPassphrase {
+		fmt.Fprintln(out, "  Update your passphrase source (environment, keyring, file or password store) before restarting picoclaw.")
+	}
+	return nil
+}
+
+func currentPassphrase(prompt io.Writer) (string, error) {
+	passphrase, source, err := credential.ResolvePassphrase()
+	if err != nil {
+		return "", err
+	}
+	if passphrase != "" {
+		fmt.Fprintf(prompt, "Using the current passphrase from %s\n", source)
+		return passphrase, nil
+	}
+	return readPassphrase(prompt, "Current passphrase: ")
+}
+
+func promptNewPassphrase(prompt io.Writer) (string, error) {
+	p1, err := readPassphrase(prompt, "New passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	p2, err := readPassphrase(prompt, "Confirm new passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if p1 != p2 {
+		return "", errors.New("passphrases do not match")
+	}
+	return p1, nil
+}
+
+func readPassphrase(prompt io.Writer, label string) (string, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", fmt.Errorf("no passphrase available and stdin is not a terminal (set %s or %s)",
+			credential.PassphraseEnvVar, newPassphraseEnvVar)
+	}
+	fmt.Fprint(prompt, label)
+	p, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(prompt)
+	if err != nil {
+		return "", fmt.Errorf("reading passphrase: %w", err)
+	}
+	if len(p) == 0 {
+		return "", errors.New("passphrase must not be empty")
+	}
+	return string(p), nil
+}
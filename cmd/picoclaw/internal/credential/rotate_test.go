@@ -0,0 +1,75 @@
+package credential
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sipeed/picoclaw/pkg/credential"
+)
+
+func writeRotateConfig(t *testing.T) (configPath, value string) {
+	t.Helper()
+	dir := t.TempDir()
+	sshKey := filepath.Join(dir, "picoclaw_ed25519.key")
+	require.NoError(t, os.WriteFile(sshKey, []byte("fake-ssh-key\n"), 0o600))
+	t.Setenv("PICOCLAW_SSH_KEY_PATH", sshKey)
+
+	value, err := credential.Encrypt("old-passphrase", "", "sk-secret")
+	require.NoError(t, err)
+	configPath = filepath.Join(dir, "config.json")
+	require.NoError(t, os.WriteFile(configPath, []byte(`{"model_list":[{"api_key":"`+value+`"}]}`), 0o600))
+	return configPath, value
+}
+
+func TestRotateCmd(t *testing.T) {
+	configPath, value := writeRotateConfig(t)
+
+	var out bytes.Buffer
+	err := rotateCmd(&out, configPath, rotateOptions{oldPassphrase: "old-passphrase", newPassphrase: "new-passphrase"})
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), "Re-encrypted 1 values")
+
+	data, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), value)
+	_, _, err = credential.RewriteEncrypted(data, func(v string) (string, error) {
+		plain, err := credential.Decrypt("new-passphrase", "", v)
+		assert.Equal(t, "sk-secret", plain)
+		return v, err
+	})
+	require.NoError(t, err)
+}
+
+func TestRotateCmd_WrongPassphraseLeavesConfig(t *testing.T) {
+	configPath, _ := writeRotateConfig(t)
+	before, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	err = rotateCmd(&out, configPath, rotateOptions{oldPassphrase: "wrong", newPassphrase: "new-passphrase"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "config left unchanged")
+
+	after, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, before, after)
+}
+
+func TestRotateCmd_DryRun(t *testing.T) {
+	configPath, _ := writeRotateConfig(t)
+	before, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, rotateCmd(&out, configPath, rotateOptions{oldPassphrase: "old-passphrase", dryRun: true}))
+	assert.Contains(t, out.String(), "All 1 enc:// values decrypt")
+
+	after, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, before, after)
+}
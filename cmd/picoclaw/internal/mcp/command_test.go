@@ -0,0 +1,23 @@
+package mcp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMCPCommand(t *testing.T) {
+	cmd := NewMCPCommand()
+
+	require.NotNil(t, cmd)
+
+	assert.Equal(t, "Expose picoclaw as an MCP server", cmd.Short)
+	assert.False(t, cmd.HasFlags())
+	assert.NotNil(t, cmd.RunE)
+
+	subcommands := cmd.Commands()
+	require.Len(t, subcommands, 1)
+	assert.Equal(t, "serve", subcommands[0].Name())
+	assert.NotNil(t, subcommands[0].Flags().Lookup("debug"))
+}
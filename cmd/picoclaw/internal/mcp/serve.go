@@ -0,0 +1,86 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sipeed/picoclaw/cmd/picoclaw/internal"
+	"github.com/sipeed/picoclaw/pkg/agent"
+	"github.com/sipeed/picoclaw/pkg/audit"
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/egress"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/redact"
+)
+
+func newServeCommand() *cobra.Command {
+	var debug bool
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve allow-listed tools and agents over stdio",
+		Long: "Serve the tools and agents listed in tools.mcp.serve to an MCP client over stdin/stdout,\n" +
+			"for example as a command-based server in Claude Desktop or an IDE.",
+		Args: cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return serveCmd(debug)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&debug, "debug", "d", false, "Enable debug logging")
+
+	return cmd
+}
+
+func serveCmd(debug bool) error {
+	// stdout carries the protocol; everything else goes to stderr.
+	protocolOut := os.Stdout
+	os.Stdout = os.Stderr
+	logger.SetConsoleOutput(os.Stderr)
+
+	if debug {
+		logger.SetLevel(logger.DEBUG)
+	}
+
+	cfg, err := internal.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("error loading config: %w", err)
+	}
+	redact.Install(cfg)
+	if err := audit.Install(cfg.Audit, "mcp"); err != nil {
+		return fmt.Errorf("error opening audit log: %w", err)
+	}
+	if err := egress.Install(cfg.Egress); err != nil {
+		return fmt.Errorf("invalid egress policy: %w", err)
+	}
+
+	provider, modelID, err := providers.CreateProvider(cfg)
+	if err != nil {
+		return fmt.Errorf("error creating provider: %w", err)
+	}
+	if modelID != "" {
+		cfg.Agents.Defaults.ModelName = modelID
+	}
+
+	msgBus := bus.NewMessageBus()
+	defer msgBus.Close()
+	agentLoop := agent.NewAgentLoop(cfg, msgBus, provider)
+	defer agentLoop.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server, err := agentLoop.NewMCPServer(ctx, agent.MCPStdioChannel)
+	if err != nil {
+		return fmt.Errorf("error creating MCP server: %w", err)
+	}
+	logger.InfoCF("mcp", "Serving MCP over stdio", map[string]any{"tools": server.ToolNames()})
+
+	return server.ServeStdio(ctx, os.Stdin, protocolOut)
+}
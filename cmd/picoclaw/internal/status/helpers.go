@@ -97,5 +97,7 @@ func statusCmd() {
 				fmt.Printf("  %s (%s): %s\n", provider, cred.AuthMethod, status)
 			}
 		}
+
+		printMCPStatus(cfg)
 	}
 }
@@ -0,0 +1,96 @@
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/health"
+	"github.com/sipeed/picoclaw/pkg/mcp"
+)
+
+// fetchMCPStatus asks the running gateway for the state of its MCP servers.
+func fetchMCPStatus(cfg *config.Config) ([]mcp.ServerStatus, error) {
+	host := cfg.Gateway.Host
+	switch host {
+	case "", "0.0.0.0":
+		host = "127.0.0.1"
+	case "::":
+		host = "::1"
+	}
+	url := "http://" + net.JoinHostPort(host, strconv.Itoa(cfg.Gateway.Port)) + "/health"
+
+	client := http.Client{Timeout: 2 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var status health.StatusResponse
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, err
+	}
+	var servers []mcp.ServerStatus
+	if raw, ok := status.Components["mcp"]; ok {
+		if err := json.Unmarshal(raw, &servers); err != nil {
+			return nil, err
+		}
+	}
+	return servers, nil
+}
+
+// formatMCPStatus renders one line per configured server. live is nil when
+// the gateway is not reachable.
+func formatMCPStatus(servers map[string]config.MCPServerConfig, live []mcp.ServerStatus) []string {
+	byName := make(map[string]mcp.ServerStatus, len(live))
+	for _, st := range live {
+		byName[st.Name] = st
+	}
+	names := make([]string, 0, len(servers))
+	for name := range servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		st, ok := byName[name]
+		var line string
+		switch {
+		case !servers[name].Enabled:
+			line = "disabled"
+		case live == nil:
+			line = "configured (gateway not running)"
+		case !ok:
+			line = "pending"
+		case st.State == mcp.StateConnected:
+			line = fmt.Sprintf("✓ connected (%d tools)", st.Tools)
+		case st.State == mcp.StateReconnecting:
+			line = fmt.Sprintf("reconnecting (attempt %d): %s", st.Attempts, st.LastError)
+		default:
+			line = fmt.Sprintf("✗ %s: %s", st.State, st.LastError)
+		}
+		lines = append(lines, fmt.Sprintf("  %s: %s", name, line))
+	}
+	return lines
+}
+
+func printMCPStatus(cfg *config.Config) {
+	if !cfg.Tools.IsToolEnabled("mcp") || len(cfg.Tools.MCP.Servers) == 0 {
+		return
+	}
+	live, err := fetchMCPStatus(cfg)
+	if err == nil && live == nil {
+		live = []mcp.ServerStatus{}
+	}
+	fmt.Println("\nMCP Servers:")
+	for _, line := range formatMCPStatus(cfg.Tools.MCP.Servers, live) {
+		fmt.Println(line)
+	}
+}
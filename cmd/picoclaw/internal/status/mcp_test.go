@@ -0,0 +1,32 @@
+package status
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/mcp"
+)
+
+func TestFormatMCPStatus(t *testing.T) {
+	servers := map[string]config.MCPServerConfig{
+		"docs":   {Enabled: true},
+		"github": {Enabled: true},
+		"new":    {Enabled: true},
+		"off":    {Enabled: false},
+	}
+	live := []mcp.ServerStatus{
+		{Name: "docs", State: mcp.StateConnected, Tools: 3},
+		{Name: "github", State: mcp.StateReconnecting, Attempts: 2, LastError: "exit status 1"},
+	}
+
+	assert.Equal(t, []string{
+		"  docs: ✓ connected (3 tools)",
+		"  github: reconnecting (attempt 2): exit status 1",
+		"  new: pending",
+		"  off: disabled",
+	}, formatMCPStatus(servers, live))
+
+	assert.Equal(t, "  docs: configured (gateway not running)", formatMCPStatus(servers, nil)[0])
+}
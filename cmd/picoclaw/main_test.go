@@ -35,9 +35,12 @@ func TestNewPicoclawCommand(t *testing.T) {
 
 	allowedCommands := []string{
 		"agent",
+		"audit",
 		"auth",
+		"credential",
 		"cron",
 		"gateway",
+		"mcp",
 		"migrate",
 		"model",
 		"onboard",
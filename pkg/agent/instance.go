@@ -8,8 +8,10 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/media"
 	"github.com/sipeed/picoclaw/pkg/memory"
 	"github.com/sipeed/picoclaw/pkg/providers"
@@ -71,23 +73,43 @@ func NewAgentInstance(
 	allowWritePaths := compilePatterns(cfg.Tools.AllowWritePaths)
 
 	toolsRegistry := tools.NewToolRegistry()
+	toolsRegistry.SetTrustPolicy(tools.NewTrustPolicy(cfg.Tools.Trust))
 
 	if cfg.Tools.IsToolEnabled("read_file") {
 		maxReadFileSize := cfg.Tools.ReadFile.MaxReadFileSize
 		toolsRegistry.Register(tools.NewReadFileTool(workspace, readRestrict, maxReadFileSize, allowReadPaths))
 	}
+	if cfg.Tools.IsToolEnabled("read_document") {
+		maxChars := cfg.Tools.ReadDocument.MaxChars
+		toolsRegistry.Register(tools.NewReadDocumentTool(workspace, readRestrict, maxChars, allowReadPaths))
+	}
 	if cfg.Tools.IsToolEnabled("write_file") {
 		toolsRegistry.Register(tools.NewWriteFileTool(workspace, restrict, allowWritePaths))
 	}
 	if cfg.Tools.IsToolEnabled("list_dir") {
 		toolsRegistry.Register(tools.NewListDirTool(workspace, readRestrict, allowReadPaths))
 	}
+	if cfg.Tools.IsToolEnabled("glob") {
+		toolsRegistry.Register(tools.NewGlobTool(workspace, readRestrict, allowReadPaths))
+	}
+	if cfg.Tools.IsToolEnabled("grep") {
+		toolsRegistry.Register(tools.NewGrepTool(workspace, readRestrict, allowReadPaths))
+	}
 	if cfg.Tools.IsToolEnabled("exec") {
 		execTool, err := tools.NewExecToolWithConfig(workspace, restrict, cfg, allowReadPaths)
 		if err != nil {
 			log.Fatalf("Critical error: unable to initialize exec tool: %v", err)
 		}
 		toolsRegistry.Register(execTool)
+		if cfg.Tools.IsToolEnabled("shell_session") {
+			sessionCfg := cfg.Tools.ShellSession
+			toolsRegistry.Register(tools.NewShellSessionTool(
+				execTool,
+				sessionCfg.MaxSessions,
+				sessionCfg.BufferKB,
+				time.Duration(sessionCfg.IdleTimeoutMinutes)*time.Minute,
+			))
+		}
 	}
 
 	if cfg.Tools.IsToolEnabled("edit_file") {
@@ -96,6 +118,29 @@ func NewAgentInstance(
 	if cfg.Tools.IsToolEnabled("append_file") {
 		toolsRegistry.Register(tools.NewAppendFileTool(workspace, restrict, allowWritePaths))
 	}
+	if cfg.Tools.IsToolEnabled("apply_patch") {
+		toolsRegistry.Register(tools.NewApplyPatchTool(workspace, restrict, allowWritePaths))
+	}
+	if cfg.Tools.IsToolEnabled("git") {
+		gitCfg := cfg.Tools.Git
+		gitTool, err := tools.NewGitTool(tools.GitToolOptions{
+			Workspace:   workspace,
+			Restrict:    restrict,
+			AllowPaths:  allowWritePaths,
+			Timeout:     time.Duration(gitCfg.TimeoutSeconds) * time.Second,
+			MaxChars:    gitCfg.MaxChars,
+			AuthorName:  gitCfg.AuthorName,
+			AuthorEmail: gitCfg.AuthorEmail,
+			AllowRemote: gitCfg.AllowRemote,
+			Remotes:     gitCfg.Remotes,
+			Resolver:    cfg.CredentialResolver(),
+		})
+		if err != nil {
+			logger.WarnCF("agent", "git tool disabled", map[string]any{"error": err.Error()})
+		} else {
+			toolsRegistry.Register(gitTool)
+		}
+	}
 
 	sessionsDir := filepath.Join(workspace, "sessions")
 	sessions := initSessionStore(sessionsDir)
@@ -305,8 +350,11 @@ func mediaTempDirPattern() string {
 	return "^" + regexp.QuoteMeta(filepath.Clean(media.TempDir())) + "(?:" + sep + "|$)"
 }
 
-// Close releases resources held by the agent's session store.
+// Close releases resources held by the agent's tools and session store.
 func (a *AgentInstance) Close() error {
+	if a.Tools != nil {
+		a.Tools.Close()
+	}
 	if a.Sessions != nil {
 		return a.Sessions.Close()
 	}
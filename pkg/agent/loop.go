@@ -11,6 +11,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -19,13 +20,17 @@ import (
 	"time"
 	"unicode/utf8"
 
+	"github.com/sipeed/picoclaw/pkg/audit"
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/channels"
 	"github.com/sipeed/picoclaw/pkg/commands"
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/constants"
+	"github.com/sipeed/picoclaw/pkg/egress"
+	"github.com/sipeed/picoclaw/pkg/imagegen"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/media"
+	"github.com/sipeed/picoclaw/pkg/permissions"
 	"github.com/sipeed/picoclaw/pkg/providers"
 	"github.com/sipeed/picoclaw/pkg/routing"
 	"github.com/sipeed/picoclaw/pkg/skills"
@@ -46,18 +51,26 @@ type AgentLoop struct {
 	channelManager *channels.Manager
 	mediaStore     media.MediaStore
 	transcriber    voice.Transcriber
+	synthesizer    voice.Synthesizer
 	cmdRegistry    *commands.Registry
+	permissions    *permissions.Policy
 	mcp            mcpRuntime
 	mu             sync.RWMutex
 	// Track active requests for safe provider cleanup
 	activeRequests sync.WaitGroup
 }
 
+// roundSenderTools are tools that deliver their output to the user directly.
+// When one of them sent something during a round, the final response is not
+// published again.
+var roundSenderTools = []string{"message", "ask_user"}
+
 // processOptions configures how a message is processed
 type processOptions struct {
 	SessionKey        string   // Session identifier for history/context
 	Channel           string   // Target channel for tool execution
 	ChatID            string   // Target chat ID for tool execution
+	ThreadID          string   // Thread/topic the inbound message was posted in
 	SenderID          string   // Current sender ID for dynamic context
 	SenderDisplayName string   // Current sender display name for dynamic context
 	UserMessage       string   // User message content (may include prefix)
@@ -107,6 +120,7 @@ func NewAgentLoop(
 		summarizing: sync.Map{},
 		fallback:    fallbackChain,
 		cmdRegistry: commands.NewRegistry(commands.BuiltinDefinitions()),
+		permissions: permissions.NewPolicy(cfg.Permissions),
 	}
 
 	return al
@@ -160,6 +174,35 @@ func registerSharedTools(
 				agent.Tools.Register(searchTool)
 			}
 		}
+		if cfg.Tools.IsToolEnabled("http_request") {
+			httpTool, err := tools.NewHTTPRequestTool(tools.HTTPRequestToolOptions{
+				Timeout:              time.Duration(cfg.Tools.HTTPRequest.TimeoutSeconds) * time.Second,
+				MaxResponseBytes:     cfg.Tools.HTTPRequest.MaxResponseBytes,
+				Proxy:                cfg.Tools.Web.Proxy,
+				PrivateHostWhitelist: cfg.Tools.Web.PrivateHostWhitelist,
+				AuthProfiles:         cfg.Tools.HTTPRequest.AuthProfiles,
+				Resolver:             cfg.CredentialResolver(),
+			})
+			if err != nil {
+				logger.ErrorCF("agent", "Failed to create http_request tool", map[string]any{"error": err.Error()})
+			} else {
+				agent.Tools.Register(httpTool)
+			}
+		}
+		if cfg.Tools.IsToolEnabled("browser") {
+			browserTool, err := tools.NewBrowserTool(tools.BrowserToolOptions{
+				ExecutablePath:       cfg.Tools.Browser.ExecutablePath,
+				Timeout:              time.Duration(cfg.Tools.Browser.TimeoutSeconds) * time.Second,
+				IdleTimeout:          time.Duration(cfg.Tools.Browser.IdleTimeoutMinutes) * time.Minute,
+				MaxChars:             cfg.Tools.Browser.MaxChars,
+				PrivateHostWhitelist: cfg.Tools.Web.PrivateHostWhitelist,
+			})
+			if err != nil {
+				logger.ErrorCF("agent", "Failed to create browser tool", map[string]any{"error": err.Error()})
+			} else {
+				agent.Tools.Register(browserTool)
+			}
+		}
 		if cfg.Tools.IsToolEnabled("web_fetch") {
 			fetchTool, err := tools.NewWebFetchToolWithProxy(
 				50000,
@@ -197,6 +240,22 @@ func registerSharedTools(
 			agent.Tools.Register(messageTool)
 		}
 
+		// Ask user tool (choices rendered as buttons on interactive channels)
+		if cfg.Tools.IsToolEnabled("ask_user") {
+			askTool := tools.NewAskUserTool()
+			askTool.SetAskCallback(func(channel, chatID, question string, interactive *bus.Interactive) error {
+				pubCtx, pubCancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer pubCancel()
+				return msgBus.PublishOutbound(pubCtx, bus.OutboundMessage{
+					Channel:     channel,
+					ChatID:      chatID,
+					Content:     question,
+					Interactive: interactive,
+				})
+			})
+			agent.Tools.Register(askTool)
+		}
+
 		// Send file tool (outbound media via MediaStore — store injected later by SetMediaStore)
 		if cfg.Tools.IsToolEnabled("send_file") {
 			sendFileTool := tools.NewSendFileTool(
@@ -209,6 +268,31 @@ func registerSharedTools(
 			agent.Tools.Register(sendFileTool)
 		}
 
+		// Image generation (store injected later by SetMediaStore)
+		if cfg.Tools.IsToolEnabled("generate_image") {
+			imageCfg := cfg.Tools.GenerateImage
+			var imageProviders []imagegen.ImageProvider
+			for _, name := range imageCfg.Models {
+				mc, err := cfg.GetModelConfig(name)
+				if err != nil {
+					logger.ErrorCF("agent", "Unknown image model", map[string]any{"model": name, "error": err.Error()})
+					continue
+				}
+				provider, err := imagegen.NewProvider(mc, time.Duration(imageCfg.TimeoutSeconds)*time.Second)
+				if err != nil {
+					logger.ErrorCF("agent", "Failed to create image model", map[string]any{"model": name, "error": err.Error()})
+					continue
+				}
+				imageProviders = append(imageProviders, provider)
+			}
+			imageTool, err := tools.NewGenerateImageTool(imageProviders)
+			if err != nil {
+				logger.ErrorCF("agent", "Failed to create generate_image tool", map[string]any{"error": err.Error()})
+			} else {
+				agent.Tools.Register(imageTool)
+			}
+		}
+
 		// Skill discovery and installation tools
 		skills_enabled := cfg.Tools.IsToolEnabled("skills")
 		find_skills_enable := cfg.Tools.IsToolEnabled("find_skills")
@@ -285,30 +369,36 @@ func (al *AgentLoop) Run(ctx context.Context) error {
 			// 	}
 			// }()
 
-			response, err := al.processMessage(ctx, msg)
+			response, err := al.processMessage(al.withSenderRole(ctx, msg), msg)
 			if err != nil {
 				response = fmt.Sprintf("Error processing message: %v", err)
 			}
 
 			if response != "" {
-				// Check if the message tool already sent a response during this round.
-				// If so, skip publishing to avoid duplicate messages to the user.
-				// Use default agent's tools to check (message tool is shared).
+				// Check if the message or ask_user tool already sent a response during
+				// this round. If so, skip publishing to avoid duplicate messages to the user.
+				// Use default agent's tools to check (these tools are shared).
 				alreadySent := false
 				defaultAgent := al.GetRegistry().GetDefaultAgent()
 				if defaultAgent != nil {
-					if tool, ok := defaultAgent.Tools.Get("message"); ok {
-						if mt, ok := tool.(*tools.MessageTool); ok {
-							alreadySent = mt.HasSentInRound()
+					for _, name := range roundSenderTools {
+						if tool, ok := defaultAgent.Tools.Get(name); ok {
+							if st, ok := tool.(interface{ HasSentInRound() bool }); ok && st.HasSentInRound() {
+								alreadySent = true
+							}
 						}
 					}
 				}
 
 				if !alreadySent {
+					if al.sendVoiceReply(ctx, msg, response) && !al.GetConfig().Voice.TTS.SendText {
+						continue
+					}
 					al.bus.PublishOutbound(ctx, bus.OutboundMessage{
-						Channel: msg.Channel,
-						ChatID:  msg.ChatID,
-						Content: response,
+						Channel:  msg.Channel,
+						ChatID:   msg.ChatID,
+						ThreadID: msg.ThreadID,
+						Content:  response,
 					})
 					logger.InfoCF("agent", "Published outbound response",
 						map[string]any{
@@ -341,6 +431,7 @@ func (al *AgentLoop) Close() {
 	mcpManager := al.mcp.takeManager()
 
 	if mcpManager != nil {
+		al.unregisterMCPPrompts()
 		if err := mcpManager.Close(); err != nil {
 			logger.ErrorCF("agent", "Failed to close MCP manager",
 				map[string]any{
@@ -429,28 +520,36 @@ func (al *AgentLoop) ReloadProviderAndConfig(
 	// Store new values
 	al.cfg = cfg
 	al.registry = registry
+	al.permissions = permissions.NewPolicy(cfg.Permissions)
 
 	// Also update fallback chain with new config
 	al.fallback = providers.NewFallbackChain(providers.NewCooldownTracker())
 
 	al.mu.Unlock()
 
-	// Close old provider after releasing the lock
+	// The new registry starts without MCP tools; servers are reconciled
+	// against the new config in the background.
+	al.reloadMCP(cfg)
+
+	// Close old provider and agents after releasing the lock
 	// This prevents blocking readers while closing
-	if oldProvider, ok := extractProvider(oldRegistry); ok {
-		if stateful, ok := oldProvider.(providers.StatefulProvider); ok {
-			// Give in-flight requests a moment to complete
-			// Use a reasonable timeout that balances cleanup vs resource usage
-			select {
-			case <-time.After(100 * time.Millisecond):
-				stateful.Close()
-			case <-ctx.Done():
-				// Context canceled, close immediately but log warning
-				logger.WarnCF("agent", "Context canceled during provider cleanup, forcing close",
-					map[string]any{"error": ctx.Err()})
+	if oldRegistry != nil {
+		// Give in-flight requests a moment to complete
+		// Use a reasonable timeout that balances cleanup vs resource usage
+		select {
+		case <-time.After(100 * time.Millisecond):
+		case <-ctx.Done():
+			// Context canceled, close immediately but log warning
+			logger.WarnCF("agent", "Context canceled during provider cleanup, forcing close",
+				map[string]any{"error": ctx.Err()})
+		}
+		if oldProvider, ok := extractProvider(oldRegistry); ok {
+			if stateful, ok := oldProvider.(providers.StatefulProvider); ok {
 				stateful.Close()
 			}
 		}
+		// Old agents own shell sessions and browser processes.
+		oldRegistry.Close()
 	}
 
 	logger.InfoCF("agent", "Provider and config reloaded successfully",
@@ -479,13 +578,23 @@ func (al *AgentLoop) GetConfig() *config.Config {
 func (al *AgentLoop) SetMediaStore(s media.MediaStore) {
 	al.mediaStore = s
 
-	// Propagate store to send_file tools in all agents.
+	// Propagate store to the tools that produce media in all agents.
 	registry := al.GetRegistry()
 	registry.ForEachTool("send_file", func(t tools.Tool) {
 		if sf, ok := t.(*tools.SendFileTool); ok {
 			sf.SetMediaStore(s)
 		}
 	})
+	registry.ForEachTool("browser", func(t tools.Tool) {
+		if bt, ok := t.(*tools.BrowserTool); ok {
+			bt.SetMediaStore(s)
+		}
+	})
+	registry.ForEachTool("generate_image", func(t tools.Tool) {
+		if gt, ok := t.(*tools.GenerateImageTool); ok {
+			gt.SetMediaStore(s)
+		}
+	})
 }
 
 // SetTranscriber injects a voice transcriber for agent-level audio transcription.
@@ -493,6 +602,11 @@ func (al *AgentLoop) SetTranscriber(t voice.Transcriber) {
 	al.transcriber = t
 }
 
+// SetSynthesizer injects a speech synthesizer for voice replies.
+func (al *AgentLoop) SetSynthesizer(s voice.Synthesizer) {
+	al.synthesizer = s
+}
+
 var audioAnnotationRe = regexp.MustCompile(`\[(voice|audio)(?::[^\]]*)?\]`)
 
 // transcribeAudioInMessage resolves audio media refs, transcribes them, and
@@ -527,7 +641,7 @@ func (al *AgentLoop) transcribeAudioInMessage(ctx context.Context, msg bus.Inbou
 		return msg, false
 	}
 
-	al.sendTranscriptionFeedback(ctx, msg.Channel, msg.ChatID, msg.MessageID, transcriptions)
+	al.sendTranscriptionFeedback(ctx, msg.Channel, msg.ChatID, msg.ThreadID, msg.MessageID, transcriptions)
 
 	// Replace audio annotations sequentially with transcriptions.
 	idx := 0
@@ -555,7 +669,7 @@ func (al *AgentLoop) transcribeAudioInMessage(ctx context.Context, msg bus.Inbou
 // ordering with the subsequent placeholder is guaranteed.
 func (al *AgentLoop) sendTranscriptionFeedback(
 	ctx context.Context,
-	channel, chatID, messageID string,
+	channel, chatID, threadID, messageID string,
 	validTexts []string,
 ) {
 	if !al.cfg.Voice.EchoTranscription {
@@ -582,6 +696,7 @@ func (al *AgentLoop) sendTranscriptionFeedback(
 	err := al.channelManager.SendMessage(ctx, bus.OutboundMessage{
 		Channel:          channel,
 		ChatID:           chatID,
+		ThreadID:         threadID,
 		Content:          feedbackMsg,
 		ReplyToMessageID: messageID,
 	})
@@ -590,6 +705,75 @@ func (al *AgentLoop) sendTranscriptionFeedback(
 	}
 }
 
+// sendVoiceReply answers a voice message with synthesized speech when the
+// TTS settings ask for it and the channel can deliver audio. Returns true if
+// the voice reply was published.
+func (al *AgentLoop) sendVoiceReply(ctx context.Context, msg bus.InboundMessage, response string) bool {
+	if al.synthesizer == nil || al.mediaStore == nil || al.channelManager == nil {
+		return false
+	}
+	if utf8.RuneCountInString(response) > voice.MaxSynthesisChars || !al.hasAudioMedia(msg) {
+		return false
+	}
+	if !voice.ShouldReplyWithVoice(al.GetConfig().Voice.TTS, msg.Channel, msg.Sender) {
+		return false
+	}
+	ch, ok := al.channelManager.GetChannel(msg.Channel)
+	if !ok {
+		return false
+	}
+	if _, ok := ch.(channels.MediaSender); !ok {
+		return false
+	}
+
+	result, err := al.synthesizer.Synthesize(ctx, response)
+	if err != nil {
+		logger.WarnCF("voice", "Speech synthesis failed", map[string]any{"error": err.Error()})
+		return false
+	}
+
+	filename := filepath.Base(result.Path)
+	scope := fmt.Sprintf("voice:tts:%s:%s", msg.Channel, msg.ChatID)
+	ref, err := al.mediaStore.Store(result.Path, media.MediaMeta{
+		Filename:    filename,
+		ContentType: result.ContentType,
+		Source:      "voice:tts",
+	}, scope)
+	if err != nil {
+		logger.WarnCF("voice", "Failed to store synthesized audio", map[string]any{"error": err.Error()})
+		os.Remove(result.Path)
+		return false
+	}
+
+	err = al.bus.PublishOutboundMedia(ctx, bus.OutboundMediaMessage{
+		Channel:  msg.Channel,
+		ChatID:   msg.ChatID,
+		ThreadID: msg.ThreadID,
+		Parts: []bus.MediaPart{{
+			Type:        "audio",
+			Ref:         ref,
+			Filename:    filename,
+			ContentType: result.ContentType,
+		}},
+	})
+	if err != nil {
+		logger.WarnCF("voice", "Failed to publish voice reply", map[string]any{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// hasAudioMedia reports whether msg carries at least one audio attachment.
+func (al *AgentLoop) hasAudioMedia(msg bus.InboundMessage) bool {
+	for _, ref := range msg.Media {
+		_, meta, err := al.mediaStore.ResolveWithMeta(ref)
+		if err == nil && utils.IsAudioFile(meta.Filename, meta.ContentType) {
+			return true
+		}
+	}
+	return false
+}
+
 // inferMediaType determines the media type ("image", "audio", "video", "file")
 // from a filename and MIME content type.
 func inferMediaType(filename, contentType string) string {
@@ -686,6 +870,38 @@ func (al *AgentLoop) ProcessHeartbeat(
 	})
 }
 
+// ProcessForAgent runs one turn of the given agent, bypassing route
+// bindings. It is used by callers that address an agent explicitly, such as
+// MCP clients.
+func (al *AgentLoop) ProcessForAgent(
+	ctx context.Context,
+	agentID, content, sessionKey, channel, chatID string,
+) (string, error) {
+	if err := al.ensureMCPInitialized(ctx); err != nil {
+		return "", err
+	}
+	agent, ok := al.GetRegistry().GetAgent(agentID)
+	if !ok {
+		return "", fmt.Errorf("agent %q not found", agentID)
+	}
+	ctx = auditInbound(ctx, audit.Actor{
+		ID:      channel + ":" + chatID,
+		Channel: channel,
+		ChatID:  chatID,
+		Session: sessionKey,
+	}, agent.ID, content, 0)
+	return al.runAgentLoop(ctx, agent, processOptions{
+		SessionKey:      sessionKey,
+		Channel:         channel,
+		ChatID:          chatID,
+		SenderID:        channel,
+		UserMessage:     content,
+		DefaultResponse: defaultResponse,
+		EnableSummary:   true,
+		SendResponse:    false,
+	})
+}
+
 func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage) (string, error) {
 	// Add message preview to log (show full content for error messages)
 	var logContent string
@@ -725,9 +941,11 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 	}
 
 	// Reset message-tool state for this round so we don't skip publishing due to a previous round.
-	if tool, ok := agent.Tools.Get("message"); ok {
-		if resetter, ok := tool.(interface{ ResetSentInRound() }); ok {
-			resetter.ResetSentInRound()
+	for _, name := range roundSenderTools {
+		if tool, ok := agent.Tools.Get(name); ok {
+			if resetter, ok := tool.(interface{ ResetSentInRound() }); ok {
+				resetter.ResetSentInRound()
+			}
 		}
 	}
 
@@ -749,6 +967,7 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 		SessionKey:        sessionKey,
 		Channel:           msg.Channel,
 		ChatID:            msg.ChatID,
+		ThreadID:          msg.ThreadID,
 		SenderID:          msg.SenderID,
 		SenderDisplayName: msg.Sender.DisplayName,
 		UserMessage:       msg.Content,
@@ -758,6 +977,23 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 		SendResponse:      false,
 	}
 
+	ctx = auditInbound(ctx, audit.Actor{
+		ID:      auditSender(msg),
+		Channel: msg.Channel,
+		ChatID:  msg.ChatID,
+		Session: sessionKey,
+	}, agent.ID, msg.Content, len(msg.Media))
+
+	if role := permissions.RoleFrom(ctx); !role.AllowsAgent(agent.ID) {
+		logger.WarnCF("agent", "Message denied by role",
+			map[string]any{
+				"agent_id":  agent.ID,
+				"role":      role.Name,
+				"sender_id": msg.SenderID,
+			})
+		return fmt.Sprintf("You don't have permission to use agent %q.", agent.ID), nil
+	}
+
 	// context-dependent commands check their own Runtime fields and report
 	// "unavailable" when the required capability is nil.
 	if response, handled := al.handleCommand(ctx, msg, agent, &opts); handled {
@@ -774,6 +1010,7 @@ func (al *AgentLoop) resolveMessageRoute(msg bus.InboundMessage) (routing.Resolv
 		AccountID:  inboundMetadata(msg, metadataKeyAccountID),
 		Peer:       extractPeer(msg),
 		ParentPeer: extractParentPeer(msg),
+		ThreadID:   msg.ThreadID,
 		GuildID:    inboundMetadata(msg, metadataKeyGuildID),
 		TeamID:     inboundMetadata(msg, metadataKeyTeamID),
 	})
@@ -867,6 +1104,8 @@ func (al *AgentLoop) runAgentLoop(
 	agent *AgentInstance,
 	opts processOptions,
 ) (string, error) {
+	ctx = withAuditActor(ctx, opts)
+
 	// 0. Record last channel for heartbeat notifications (skip internal channels and cli)
 	if opts.Channel != "" && opts.ChatID != "" {
 		if !constants.IsInternalChannel(opts.Channel) {
@@ -904,6 +1143,22 @@ func (al *AgentLoop) runAgentLoop(
 	maxMediaSize := cfg.Agents.Defaults.GetMaxMediaSize()
 	messages = resolveMediaRefs(messages, al.mediaStore, maxMediaSize)
 
+	// Untrusted content (web pages, MCP results, inbound documents) restricts
+	// high-risk tools for as long as it remains in the context, not just for
+	// the turn that fetched it.
+	turnTrust := tools.NewTurnTrust()
+	ctx = tools.WithTurnTrust(ctx, turnTrust)
+	for i, m := range messages {
+		if !tools.ContainsUntrusted(m.Content) {
+			continue
+		}
+		if i == len(messages)-1 {
+			turnTrust.Taint("document")
+		} else {
+			turnTrust.Taint("history")
+		}
+	}
+
 	// 2. Save user message to session
 	agent.Sessions.AddMessage(opts.SessionKey, "user", opts.UserMessage)
 
@@ -933,9 +1188,10 @@ func (al *AgentLoop) runAgentLoop(
 	// 7. Optional: send response via bus
 	if opts.SendResponse {
 		al.bus.PublishOutbound(ctx, bus.OutboundMessage{
-			Channel: opts.Channel,
-			ChatID:  opts.ChatID,
-			Content: finalContent,
+			Channel:  opts.Channel,
+			ChatID:   opts.ChatID,
+			ThreadID: opts.ThreadID,
+			Content:  finalContent,
 		})
 	}
 
@@ -1035,7 +1291,7 @@ func (al *AgentLoop) runLLMIteration(
 			})
 
 		// Build tool definitions
-		providerToolDefs := agent.Tools.ToProviderDefs()
+		providerToolDefs := agent.Tools.ToProviderDefs(ctx)
 
 		// Log LLM request details
 		logger.DebugCF("agent", "LLM request",
@@ -1157,9 +1413,10 @@ func (al *AgentLoop) runLLMIteration(
 
 				if retry == 0 && !constants.IsInternalChannel(opts.Channel) {
 					al.bus.PublishOutbound(ctx, bus.OutboundMessage{
-						Channel: opts.Channel,
-						ChatID:  opts.ChatID,
-						Content: "Context window exceeded. Compressing history and retrying...",
+						Channel:  opts.Channel,
+						ChatID:   opts.ChatID,
+						ThreadID: opts.ThreadID,
+						Content:  "Context window exceeded. Compressing history and retrying...",
 					})
 				}
 
@@ -1305,9 +1562,10 @@ func (al *AgentLoop) runLLMIteration(
 						outCtx, outCancel := context.WithTimeout(context.Background(), 5*time.Second)
 						defer outCancel()
 						_ = al.bus.PublishOutbound(outCtx, bus.OutboundMessage{
-							Channel: opts.Channel,
-							ChatID:  opts.ChatID,
-							Content: result.ForUser,
+							Channel:  opts.Channel,
+							ChatID:   opts.ChatID,
+							ThreadID: opts.ThreadID,
+							Content:  result.ForUser,
 						})
 					}
 
@@ -1338,7 +1596,7 @@ func (al *AgentLoop) runLLMIteration(
 				}
 
 				toolResult := agent.Tools.ExecuteWithContext(
-					ctx,
+					egress.WithAgent(tools.WithToolSessionKey(ctx, opts.SessionKey), agent.ID),
 					tc.Name,
 					tc.Arguments,
 					opts.Channel,
@@ -1351,13 +1609,15 @@ func (al *AgentLoop) runLLMIteration(
 		wg.Wait()
 
 		// Process results in original order (send to user, save to session)
+		var llmMedia []string
 		for _, r := range agentResults {
 			// Send ForUser content to user immediately if not Silent
 			if !r.result.Silent && r.result.ForUser != "" && opts.SendResponse {
 				al.bus.PublishOutbound(ctx, bus.OutboundMessage{
-					Channel: opts.Channel,
-					ChatID:  opts.ChatID,
-					Content: r.result.ForUser,
+					Channel:  opts.Channel,
+					ChatID:   opts.ChatID,
+					ThreadID: opts.ThreadID,
+					Content:  r.result.ForUser,
 				})
 				logger.DebugCF("agent", "Sent tool result to user",
 					map[string]any{
@@ -1402,6 +1662,22 @@ func (al *AgentLoop) runLLMIteration(
 
 			// Save tool result message to session
 			agent.Sessions.AddFullMessage(opts.SessionKey, toolResultMsg)
+
+			llmMedia = append(llmMedia, r.result.LLMMedia...)
+		}
+
+		// Images produced by tools (e.g. browser screenshots) are shown to the
+		// model as a follow-up user message, since tool messages cannot carry
+		// images on most providers. They are kept out of the session history
+		// so later turns do not resend them.
+		if len(llmMedia) > 0 {
+			imageMsg := providers.Message{
+				Role:    "user",
+				Content: "[Images attached by the tool calls above]",
+				Media:   llmMedia,
+			}
+			maxMediaSize := al.GetConfig().Agents.Defaults.GetMaxMediaSize()
+			messages = append(messages, resolveMediaRefs([]providers.Message{imageMsg}, al.mediaStore, maxMediaSize)...)
 		}
 
 		// Tick down TTL of discovered tools after processing tool results.
@@ -1857,7 +2133,7 @@ func (al *AgentLoop) handleCommand(
 		return "", false
 	}
 
-	rt := al.buildCommandsRuntime(agent, opts)
+	rt := al.buildCommandsRuntime(ctx, agent, opts)
 	executor := commands.NewExecutor(al.cmdRegistry, rt)
 
 	var commandReply string
@@ -1886,13 +2162,22 @@ func (al *AgentLoop) handleCommand(
 	}
 }
 
-func (al *AgentLoop) buildCommandsRuntime(agent *AgentInstance, opts *processOptions) *commands.Runtime {
+func (al *AgentLoop) buildCommandsRuntime(
+	ctx context.Context,
+	agent *AgentInstance,
+	opts *processOptions,
+) *commands.Runtime {
 	registry := al.GetRegistry()
 	cfg := al.GetConfig()
+	role := permissions.RoleFrom(ctx)
 	rt := &commands.Runtime{
-		Config:          cfg,
-		ListAgentIDs:    registry.ListAgentIDs,
-		ListDefinitions: al.cmdRegistry.Definitions,
+		Config: cfg,
+		ListAgentIDs: func() []string {
+			return allowedAgentIDs(role, registry.ListAgentIDs())
+		},
+		ListDefinitions: func() []commands.Definition {
+			return allowedDefinitions(role, al.cmdRegistry.Definitions())
+		},
 		GetEnabledChannels: func() []string {
 			if al.channelManager == nil {
 				return nil
@@ -1914,8 +2199,16 @@ func (al *AgentLoop) buildCommandsRuntime(agent *AgentInstance, opts *processOpt
 			return agent.Model, cfg.Agents.Defaults.Provider
 		}
 		rt.SwitchModel = func(value string) (string, error) {
+			if !role.AllowsModel(value) {
+				return "", fmt.Errorf("you don't have permission to use model %s", value)
+			}
 			oldModel := agent.Model
 			agent.Model = value
+			audit.Record(ctx, audit.TypeModelSwitch, map[string]any{
+				"agent_id": agent.ID,
+				"from":     oldModel,
+				"to":       value,
+			})
 			return oldModel, nil
 		}
 
@@ -1932,6 +2225,29 @@ func (al *AgentLoop) buildCommandsRuntime(agent *AgentInstance, opts *processOpt
 			agent.Sessions.Save(opts.SessionKey)
 			return nil
 		}
+
+		if opts != nil {
+			rt.RunPrompt = func(ctx context.Context, text string) (string, error) {
+				promptOpts := *opts
+				promptOpts.UserMessage = text
+				promptOpts.Media = nil
+				return al.runAgentLoop(ctx, agent, promptOpts)
+			}
+			rt.ApproveToolCall = func(ctx context.Context, id string) (string, error) {
+				trust := agent.Tools.TrustPolicy()
+				tool, err := trust.Approve(opts.SessionKey, id)
+				if err != nil {
+					return "", err
+				}
+				// The approval covers this one retry only.
+				defer trust.Discard(opts.SessionKey, id)
+				promptOpts := *opts
+				promptOpts.UserMessage = fmt.Sprintf(
+					"I approve the held %s call %s. Run it again with exactly the same arguments.", tool, id)
+				promptOpts.Media = nil
+				return al.runAgentLoop(ctx, agent, promptOpts)
+			}
+		}
 	}
 	return rt
 }
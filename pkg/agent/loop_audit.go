@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/sipeed/picoclaw/pkg/audit"
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/redact"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+// maxAuditContentLen bounds the message text kept in the audit log.
+const maxAuditContentLen = 1000
+
+// auditSender returns the audit identity of an inbound message's sender:
+// its canonical "platform:id", or "channel:sender_id" when the channel does
+// not provide one.
+func auditSender(msg bus.InboundMessage) string {
+	if msg.Sender.CanonicalID != "" {
+		return msg.Sender.CanonicalID
+	}
+	return msg.Channel + ":" + msg.SenderID
+}
+
+// auditInbound attributes the rest of the turn to actor and records the
+// inbound message.
+func auditInbound(
+	ctx context.Context,
+	actor audit.Actor,
+	agentID, content string,
+	mediaCount int,
+) context.Context {
+	ctx = audit.WithActor(ctx, actor)
+	audit.Record(ctx, audit.TypeInboundMessage, map[string]any{
+		"agent_id": agentID,
+		"content":  utils.Truncate(redact.String(content), maxAuditContentLen),
+		"media":    mediaCount,
+	})
+	return ctx
+}
+
+// withAuditActor attributes a turn that did not come through processMessage
+// (heartbeats, background task results) to its channel.
+func withAuditActor(ctx context.Context, opts processOptions) context.Context {
+	if audit.ActorFrom(ctx).ID != "" {
+		return ctx
+	}
+	sender := opts.SenderID
+	if sender == "" {
+		sender = opts.SessionKey
+	}
+	return audit.WithActor(ctx, audit.Actor{
+		ID:      opts.Channel + ":" + sender,
+		Channel: opts.Channel,
+		ChatID:  opts.ChatID,
+		Session: opts.SessionKey,
+	})
+}
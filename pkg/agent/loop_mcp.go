@@ -11,6 +11,9 @@ import (
 	"fmt"
 	"sync"
 
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/mcp"
 	"github.com/sipeed/picoclaw/pkg/tools"
@@ -21,6 +24,10 @@ type mcpRuntime struct {
 	mu       sync.Mutex
 	manager  *mcp.Manager
 	initErr  error
+	// promptCommands and toolNames hold the slash commands and tool names
+	// registered for each server.
+	promptCommands map[string][]string
+	toolNames      map[string][]string
 }
 
 func (r *mcpRuntime) setManager(manager *mcp.Manager) {
@@ -42,6 +49,12 @@ func (r *mcpRuntime) getInitErr() error {
 	return r.initErr
 }
 
+func (r *mcpRuntime) getManager() *mcp.Manager {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.manager
+}
+
 func (r *mcpRuntime) takeManager() *mcp.Manager {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -82,68 +95,20 @@ func (al *AgentLoop) ensureMCPInitialized(ctx context.Context) error {
 	al.mcp.initOnce.Do(func() {
 		mcpManager := mcp.NewManager()
 
-		defaultAgent := al.registry.GetDefaultAgent()
-		workspacePath := al.cfg.WorkspacePath()
-		if defaultAgent != nil && defaultAgent.Workspace != "" {
-			workspacePath = defaultAgent.Workspace
-		}
+		// Tools and prompts are (re)registered whenever a server connects,
+		// reconnects, changes its lists or is removed.
+		mcpManager.SetToolsChangedHandler(func(serverName string, serverTools []*sdkmcp.Tool) {
+			al.setMCPServerTools(mcpManager, serverName, serverTools)
+		})
+		al.registerMCPPrompts(mcpManager)
 
-		if err := mcpManager.LoadFromMCPConfig(ctx, al.cfg.Tools.MCP, workspacePath); err != nil {
-			logger.WarnCF("agent", "Failed to load MCP servers, MCP tools will not be available",
+		if err := mcpManager.LoadFromMCPConfig(ctx, al.cfg.Tools.MCP, al.mcpWorkspace(al.cfg)); err != nil {
+			// The manager keeps retrying unreachable servers, so keep it.
+			logger.WarnCF("agent", "No MCP server connected yet, retrying in the background",
 				map[string]any{
 					"error": err.Error(),
 				})
-			if closeErr := mcpManager.Close(); closeErr != nil {
-				logger.ErrorCF("agent", "Failed to close MCP manager",
-					map[string]any{
-						"error": closeErr.Error(),
-					})
-			}
-			return
-		}
-
-		// Register MCP tools for all agents
-		servers := mcpManager.GetServers()
-		uniqueTools := 0
-		totalRegistrations := 0
-		agentIDs := al.registry.ListAgentIDs()
-		agentCount := len(agentIDs)
-
-		for serverName, conn := range servers {
-			uniqueTools += len(conn.Tools)
-			for _, tool := range conn.Tools {
-				for _, agentID := range agentIDs {
-					agent, ok := al.registry.GetAgent(agentID)
-					if !ok {
-						continue
-					}
-
-					mcpTool := tools.NewMCPTool(mcpManager, serverName, tool)
-
-					if al.cfg.Tools.MCP.Discovery.Enabled {
-						agent.Tools.RegisterHidden(mcpTool)
-					} else {
-						agent.Tools.Register(mcpTool)
-					}
-
-					totalRegistrations++
-					logger.DebugCF("agent", "Registered MCP tool",
-						map[string]any{
-							"agent_id": agentID,
-							"server":   serverName,
-							"tool":     tool.Name,
-							"name":     mcpTool.Name(),
-						})
-				}
-			}
 		}
-		logger.InfoCF("agent", "MCP tools registered successfully",
-			map[string]any{
-				"server_count":        len(servers),
-				"unique_tools":        uniqueTools,
-				"total_registrations": totalRegistrations,
-				"agent_count":         agentCount,
-			})
 
 		// Initializes Discovery Tools only if enabled by configuration
 		if al.cfg.Tools.MCP.Enabled && al.cfg.Tools.MCP.Discovery.Enabled {
@@ -163,38 +128,163 @@ func (al *AgentLoop) ensureMCPInitialized(ctx context.Context) error {
 				}
 				return
 			}
+		}
+		al.registerMCPDiscoveryTools(al.cfg, al.registry)
 
-			ttl := al.cfg.Tools.MCP.Discovery.TTL
-			if ttl <= 0 {
-				ttl = 5 // Default value
-			}
+		servers := mcpManager.GetServers()
+		uniqueTools := 0
+		for _, conn := range servers {
+			uniqueTools += len(conn.Tools)
+		}
+		logger.InfoCF("agent", "MCP tools registered successfully",
+			map[string]any{
+				"server_count": len(servers),
+				"unique_tools": uniqueTools,
+				"agent_count":  len(al.registry.ListAgentIDs()),
+			})
 
-			maxSearchResults := al.cfg.Tools.MCP.Discovery.MaxSearchResults
-			if maxSearchResults <= 0 {
-				maxSearchResults = 5 // Default value
-			}
+		al.mcp.setManager(mcpManager)
+	})
 
-			logger.InfoCF("agent", "Initializing tool discovery", map[string]any{
-				"bm25": useBM25, "regex": useRegex, "ttl": ttl, "max_results": maxSearchResults,
-			})
+	return al.mcp.getInitErr()
+}
 
-			for _, agentID := range agentIDs {
-				agent, ok := al.registry.GetAgent(agentID)
-				if !ok {
-					continue
-				}
+func (al *AgentLoop) mcpWorkspace(cfg *config.Config) string {
+	if defaultAgent := al.GetRegistry().GetDefaultAgent(); defaultAgent != nil && defaultAgent.Workspace != "" {
+		return defaultAgent.Workspace
+	}
+	return cfg.WorkspacePath()
+}
 
-				if useRegex {
-					agent.Tools.Register(tools.NewRegexSearchTool(agent.Tools, ttl, maxSearchResults))
-				}
-				if useBM25 {
-					agent.Tools.Register(tools.NewBM25SearchTool(agent.Tools, ttl, maxSearchResults))
-				}
+// setMCPServerTools replaces the tools of serverName in every agent with
+// serverTools. A nil list removes them.
+func (al *AgentLoop) setMCPServerTools(manager *mcp.Manager, serverName string, serverTools []*sdkmcp.Tool) {
+	registry := al.GetRegistry()
+	hidden := al.GetConfig().Tools.MCP.Discovery.Enabled
+
+	al.mcp.mu.Lock()
+	defer al.mcp.mu.Unlock()
+	if al.mcp.toolNames == nil {
+		al.mcp.toolNames = make(map[string][]string)
+	}
+	previous := al.mcp.toolNames[serverName]
+
+	names := make([]string, 0, len(serverTools))
+	for _, agentID := range registry.ListAgentIDs() {
+		agent, ok := registry.GetAgent(agentID)
+		if !ok {
+			continue
+		}
+		agent.Tools.Unregister(previous...)
+		names = names[:0]
+		for _, tool := range serverTools {
+			mcpTool := tools.NewMCPTool(manager, serverName, tool)
+			if hidden {
+				agent.Tools.RegisterHidden(mcpTool)
+			} else {
+				agent.Tools.Register(mcpTool)
 			}
+			names = append(names, mcpTool.Name())
 		}
+	}
+	if len(serverTools) == 0 {
+		delete(al.mcp.toolNames, serverName)
+	} else {
+		al.mcp.toolNames[serverName] = names
+	}
 
-		al.mcp.setManager(mcpManager)
+	// Resource tools are shared across servers, so register them once per
+	// agent when any server offers resources.
+	if len(manager.GetAllResources()) > 0 || len(manager.GetAllResourceTemplates()) > 0 {
+		for _, agentID := range registry.ListAgentIDs() {
+			agent, ok := registry.GetAgent(agentID)
+			if !ok {
+				continue
+			}
+			if _, ok := agent.Tools.Get("mcp_list_resources"); !ok {
+				agent.Tools.Register(tools.NewMCPListResourcesTool(manager))
+				agent.Tools.Register(tools.NewMCPReadResourceTool(manager))
+			}
+		}
+	}
+
+	logger.InfoCF("agent", "MCP server tools updated",
+		map[string]any{
+			"server":     serverName,
+			"tool_count": len(serverTools),
+		})
+}
+
+func (al *AgentLoop) registerMCPDiscoveryTools(cfg *config.Config, registry *AgentRegistry) {
+	discovery := cfg.Tools.MCP.Discovery
+	if !cfg.Tools.MCP.Enabled || !discovery.Enabled {
+		return
+	}
+
+	ttl := discovery.TTL
+	if ttl <= 0 {
+		ttl = 5 // Default value
+	}
+
+	maxSearchResults := discovery.MaxSearchResults
+	if maxSearchResults <= 0 {
+		maxSearchResults = 5 // Default value
+	}
+
+	logger.InfoCF("agent", "Initializing tool discovery", map[string]any{
+		"bm25": discovery.UseBM25, "regex": discovery.UseRegex, "ttl": ttl, "max_results": maxSearchResults,
 	})
 
-	return al.mcp.getInitErr()
+	for _, agentID := range registry.ListAgentIDs() {
+		agent, ok := registry.GetAgent(agentID)
+		if !ok {
+			continue
+		}
+
+		if discovery.UseRegex {
+			agent.Tools.Register(tools.NewRegexSearchTool(agent.Tools, ttl, maxSearchResults))
+		}
+		if discovery.UseBM25 {
+			agent.Tools.Register(tools.NewBM25SearchTool(agent.Tools, ttl, maxSearchResults))
+		}
+	}
+}
+
+// reloadMCP carries MCP tools over to a freshly built agent registry and
+// reconciles the connected servers with cfg without restarting the others.
+func (al *AgentLoop) reloadMCP(cfg *config.Config) {
+	manager := al.mcp.getManager()
+	if manager == nil {
+		// MCP may have just been enabled; initialization runs at most once.
+		go func() {
+			if err := al.ensureMCPInitialized(context.Background()); err != nil {
+				logger.WarnCF("agent", "MCP initialization failed after config reload",
+					map[string]any{"error": err.Error()})
+			}
+		}()
+		return
+	}
+
+	for name, conn := range manager.GetServers() {
+		al.setMCPServerTools(manager, name, conn.Tools)
+	}
+	al.registerMCPDiscoveryTools(cfg, al.GetRegistry())
+
+	workspace := al.mcpWorkspace(cfg)
+	go func() {
+		if err := manager.ApplyConfig(context.Background(), cfg.Tools.MCP, workspace); err != nil {
+			logger.WarnCF("agent", "Some MCP servers could not be applied from the new configuration",
+				map[string]any{"error": err.Error()})
+		}
+	}()
+}
+
+// MCPStatus reports the supervised state of the configured MCP servers, or
+// nil when MCP is not running.
+func (al *AgentLoop) MCPStatus() []mcp.ServerStatus {
+	manager := al.mcp.getManager()
+	if manager == nil {
+		return nil
+	}
+	return manager.Status()
 }
@@ -0,0 +1,37 @@
+package agent
+
+import (
+	"testing"
+
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/sipeed/picoclaw/pkg/mcp"
+)
+
+func TestSetMCPServerTools_ReplacesAndRemovesTools(t *testing.T) {
+	al, _, _, _, cleanup := newTestAgentLoop(t)
+	defer cleanup()
+	manager := mcp.NewManager()
+	defer manager.Close()
+	agent := al.GetRegistry().GetDefaultAgent()
+
+	al.setMCPServerTools(manager, "srv", []*sdkmcp.Tool{{Name: "alpha"}, {Name: "beta"}})
+	for _, name := range []string{"mcp_srv_alpha", "mcp_srv_beta"} {
+		if _, ok := agent.Tools.Get(name); !ok {
+			t.Fatalf("expected %s to be registered", name)
+		}
+	}
+
+	al.setMCPServerTools(manager, "srv", []*sdkmcp.Tool{{Name: "beta"}})
+	if _, ok := agent.Tools.Get("mcp_srv_alpha"); ok {
+		t.Fatal("tool dropped by the server should be unregistered")
+	}
+	if _, ok := agent.Tools.Get("mcp_srv_beta"); !ok {
+		t.Fatal("remaining tool should stay registered")
+	}
+
+	al.setMCPServerTools(manager, "srv", nil)
+	if _, ok := agent.Tools.Get("mcp_srv_beta"); ok {
+		t.Fatal("tools of a removed server should be unregistered")
+	}
+}
@@ -9,21 +9,28 @@ package agent
 import (
 	"bytes"
 	"encoding/base64"
+	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/h2non/filetype"
 
+	"github.com/sipeed/picoclaw/pkg/document"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/media"
 	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/tools"
+	"github.com/sipeed/picoclaw/pkg/utils"
 )
 
 // resolveMediaRefs resolves media:// refs in messages.
 // Images are base64-encoded into the Media array for multimodal LLMs.
 // Non-image files (documents, audio, video) have their local path injected
 // into Content so the agent can access them via file tools like read_file.
+// Documents in a supported format (PDF, office files, EPUB, HTML, CSV) also
+// get their leading pages of extracted text appended.
 // Returns a new slice; original messages are not mutated.
 func resolveMediaRefs(messages []providers.Message, store media.MediaStore, maxSize int) []providers.Message {
 	if store == nil {
@@ -39,7 +46,7 @@ func resolveMediaRefs(messages []providers.Message, store media.MediaStore, maxS
 		}
 
 		resolved := make([]string, 0, len(m.Media))
-		var pathTags []string
+		var pathTags, previews []string
 
 		for _, ref := range m.Media {
 			if !strings.HasPrefix(ref, "media://") {
@@ -76,12 +83,19 @@ func resolveMediaRefs(messages []providers.Message, store media.MediaStore, maxS
 			}
 
 			pathTags = append(pathTags, buildPathTag(mime, localPath))
+			if preview := documentPreview(localPath, mime, meta, info); preview != "" {
+				// Inbound documents often come from third parties; delimit their text.
+				previews = append(previews, tools.WrapUntrusted("inbound document", preview, nil))
+			}
 		}
 
 		result[i].Media = resolved
 		if len(pathTags) > 0 {
 			result[i].Content = injectPathTags(result[i].Content, pathTags)
 		}
+		if len(previews) > 0 {
+			result[i].Content += "\n\n" + strings.Join(previews, "\n\n")
+		}
 	}
 
 	return result
@@ -141,6 +155,79 @@ func encodeImageToDataURL(localPath, mime string, info os.FileInfo, maxSize int)
 	return buf.String()
 }
 
+// inlineDocumentChars bounds how much extracted text from an inbound document
+// is placed directly in the user message; the agent pages through the rest
+// with read_document.
+const inlineDocumentChars = 8000
+
+// documentPreview extracts the text of a supported document and renders its
+// metadata plus as many leading pages as fit in inlineDocumentChars. Returns
+// empty string for unsupported or unreadable files, which keep the bare path
+// tag.
+func documentPreview(localPath, mime string, meta media.MediaMeta, info os.FileInfo) string {
+	format := document.Detect(meta.Filename, mime)
+	if format == "" {
+		format = document.Detect(localPath, "")
+	}
+	if format == "" || info.Size() > document.MaxFileSize {
+		return ""
+	}
+
+	data, err := os.ReadFile(localPath)
+	if err != nil {
+		return ""
+	}
+	doc, err := document.Extract(format, data)
+	if err != nil {
+		logger.WarnCF("agent", "Failed to extract document text", map[string]any{
+			"path":  localPath,
+			"error": err.Error(),
+		})
+		return ""
+	}
+
+	name := meta.Filename
+	if name == "" {
+		name = filepath.Base(localPath)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[document %s: %s]", name, doc.Summary())
+
+	hasText := false
+	for _, p := range doc.Pages {
+		if strings.TrimSpace(p.Text) != "" {
+			hasText = true
+			break
+		}
+	}
+	if !hasText {
+		sb.WriteString("\n[No extractable text; the document may contain only scanned images.]")
+		return sb.String()
+	}
+
+	truncated := false
+	for n := 1; n <= len(doc.Pages); n++ {
+		page := doc.Text(n, n)
+		if n > 1 && sb.Len()+len(page) > inlineDocumentChars {
+			truncated = true
+			break
+		}
+		if len(page) > inlineDocumentChars {
+			page = utils.Truncate(page, inlineDocumentChars)
+			truncated = true
+		}
+		sb.WriteString("\n")
+		sb.WriteString(page)
+		if truncated {
+			break
+		}
+	}
+	if truncated {
+		fmt.Fprintf(&sb, "\n[Preview truncated. Use read_document on %s to read the rest.]", localPath)
+	}
+	return sb.String()
+}
+
 // buildPathTag creates a structured tag exposing the local file path.
 // Tag type is derived from MIME: [audio:/path], [video:/path], or [file:/path].
 func buildPathTag(mime, localPath string) string {
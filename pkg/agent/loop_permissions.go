@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/commands"
+	"github.com/sipeed/picoclaw/pkg/constants"
+	"github.com/sipeed/picoclaw/pkg/identity"
+	"github.com/sipeed/picoclaw/pkg/permissions"
+)
+
+// permissionPolicy returns the current role policy, or nil when
+// permissions are disabled.
+func (al *AgentLoop) permissionPolicy() *permissions.Policy {
+	al.mu.RLock()
+	defer al.mu.RUnlock()
+	return al.permissions
+}
+
+// withSenderRole attaches the role of a channel message's sender to ctx.
+// Internal channels (CLI, background task results) are not restricted.
+func (al *AgentLoop) withSenderRole(ctx context.Context, msg bus.InboundMessage) context.Context {
+	policy := al.permissionPolicy()
+	if policy == nil || constants.IsInternalChannel(msg.Channel) {
+		return ctx
+	}
+	sender := msg.Sender
+	if sender.CanonicalID == "" && sender.PlatformID == "" {
+		// Channels that only report a sender ID.
+		sender.Platform = msg.Channel
+		sender.PlatformID = msg.SenderID
+		sender.CanonicalID = identity.BuildCanonicalID(msg.Channel, msg.SenderID)
+	}
+	if sender.CanonicalID == "" {
+		sender.CanonicalID = identity.BuildCanonicalID(sender.Platform, sender.PlatformID)
+	}
+	ctx = permissions.WithSender(ctx, sender.CanonicalID)
+	return permissions.WithRole(ctx, policy.RoleFor(sender))
+}
+
+// RoleForSender returns the role of the sender with the given canonical ID,
+// or nil when permissions are disabled.
+func (al *AgentLoop) RoleForSender(canonicalID string) *permissions.Role {
+	return al.permissionPolicy().RoleForID(canonicalID)
+}
+
+// allowedDefinitions filters command definitions down to those role may run.
+func allowedDefinitions(role *permissions.Role, defs []commands.Definition) []commands.Definition {
+	allowed := make([]commands.Definition, 0, len(defs))
+	for _, def := range defs {
+		if role.AllowsCommand(def.Name) {
+			allowed = append(allowed, def)
+		}
+	}
+	return allowed
+}
+
+// allowedAgentIDs filters agent IDs down to those role may talk to.
+func allowedAgentIDs(role *permissions.Role, ids []string) []string {
+	allowed := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if role.AllowsAgent(id) {
+			allowed = append(allowed, id)
+		}
+	}
+	return allowed
+}
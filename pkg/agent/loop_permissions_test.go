@@ -0,0 +1,217 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/cron"
+	"github.com/sipeed/picoclaw/pkg/permissions"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+type toolRecordingProvider struct {
+	toolNames []string
+}
+
+func (p *toolRecordingProvider) Chat(
+	ctx context.Context,
+	messages []providers.Message,
+	tools []providers.ToolDefinition,
+	model string,
+	opts map[string]any,
+) (*providers.LLMResponse, error) {
+	p.toolNames = p.toolNames[:0]
+	for _, tool := range tools {
+		p.toolNames = append(p.toolNames, tool.Function.Name)
+	}
+	return &providers.LLMResponse{Content: "ok"}, nil
+}
+
+func (p *toolRecordingProvider) GetDefaultModel() string {
+	return "mock-model"
+}
+
+func newPermissionsTestLoop(t *testing.T) (*AgentLoop, *toolRecordingProvider) {
+	t.Helper()
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+		Tools: config.ToolsConfig{
+			ReadFile: config.ReadFileToolConfig{Enabled: true},
+			Exec:     config.ExecConfig{ToolConfig: config.ToolConfig{Enabled: true}},
+		},
+		Permissions: config.PermissionsConfig{
+			Enabled:     true,
+			DefaultRole: "guest",
+			Users:       map[string]string{"telegram:1": "owner", "telegram:3": "blocked"},
+			Roles: map[string]config.RoleConfig{
+				"owner": {Tools: []string{"*"}, Commands: []string{"*"}, Agents: []string{"*"}, Models: []string{"*"}},
+				"guest": {Tools: []string{"read_file"}, Commands: []string{"help"}, Agents: []string{"main"}},
+			},
+		},
+	}
+	provider := &toolRecordingProvider{}
+	return NewAgentLoop(cfg, bus.NewMessageBus(), provider), provider
+}
+
+func processAs(t *testing.T, al *AgentLoop, senderID, content string) string {
+	t.Helper()
+	msg := bus.InboundMessage{
+		Channel:  "telegram",
+		SenderID: senderID,
+		ChatID:   "chat" + senderID,
+		Content:  content,
+		Peer:     bus.Peer{Kind: "direct", ID: senderID},
+	}
+	resp, err := al.processMessage(al.withSenderRole(context.Background(), msg), msg)
+	if err != nil {
+		t.Fatalf("processMessage failed: %v", err)
+	}
+	return resp
+}
+
+func TestProcessMessage_RoleFiltersTools(t *testing.T) {
+	al, provider := newPermissionsTestLoop(t)
+
+	processAs(t, al, "2", "hello")
+	if !slices.Equal(provider.toolNames, []string{"read_file"}) {
+		t.Fatalf("guest saw tools %v, want only read_file", provider.toolNames)
+	}
+
+	processAs(t, al, "1", "hello")
+	if !slices.Contains(provider.toolNames, "exec") {
+		t.Fatalf("owner should see exec, got %v", provider.toolNames)
+	}
+}
+
+func TestProcessMessage_RoleRestrictsCommandsAndModels(t *testing.T) {
+	al, _ := newPermissionsTestLoop(t)
+
+	resp := processAs(t, al, "2", "/switch model to other-model")
+	if resp != "You don't have permission to use /switch." {
+		t.Fatalf("unexpected guest /switch reply: %q", resp)
+	}
+
+	resp = processAs(t, al, "2", "/help")
+	if !strings.Contains(resp, "/help") || strings.Contains(resp, "/switch") {
+		t.Fatalf("guest /help should list only permitted commands, got %q", resp)
+	}
+
+	resp = processAs(t, al, "1", "/switch model to other-model")
+	if !strings.Contains(resp, "Switched model from test-model to other-model") {
+		t.Fatalf("unexpected owner /switch reply: %q", resp)
+	}
+}
+
+func TestProcessMessage_RoleRestrictsAgents(t *testing.T) {
+	al, provider := newPermissionsTestLoop(t)
+
+	resp := processAs(t, al, "3", "hello")
+	if resp != `You don't have permission to use agent "main".` {
+		t.Fatalf("unexpected reply for undefined role: %q", resp)
+	}
+	if provider.toolNames != nil {
+		t.Fatal("LLM should not be called for a denied agent")
+	}
+}
+
+func TestSwitchModel_DeniedModel(t *testing.T) {
+	al, _ := newPermissionsTestLoop(t)
+	cfg := al.GetConfig().Permissions
+	cfg.Roles["owner"] = config.RoleConfig{Commands: []string{"*"}, Agents: []string{"*"}}
+	al.permissions = permissions.NewPolicy(cfg)
+
+	resp := processAs(t, al, "1", "/switch model to other-model")
+	if !strings.Contains(resp, "you don't have permission to use model other-model") {
+		t.Fatalf("unexpected /switch reply: %q", resp)
+	}
+	if agent := al.GetRegistry().GetDefaultAgent(); agent.Model != "test-model" {
+		t.Fatalf("model changed to %q despite denial", agent.Model)
+	}
+}
+
+// execCallingProvider asks for exec on its first call and records the tool
+// result it gets back.
+type execCallingProvider struct {
+	toolNames  []string
+	toolResult string
+}
+
+func (p *execCallingProvider) Chat(
+	ctx context.Context,
+	messages []providers.Message,
+	tools []providers.ToolDefinition,
+	model string,
+	opts map[string]any,
+) (*providers.LLMResponse, error) {
+	last := messages[len(messages)-1]
+	if last.Role == "tool" {
+		p.toolResult = last.Content
+		return &providers.LLMResponse{Content: "done"}, nil
+	}
+	p.toolNames = p.toolNames[:0]
+	for _, tool := range tools {
+		p.toolNames = append(p.toolNames, tool.Function.Name)
+	}
+	return &providers.LLMResponse{ToolCalls: []providers.ToolCall{{
+		ID:        "call-1",
+		Name:      "exec",
+		Arguments: map[string]any{"command": "echo pwned"},
+	}}}, nil
+}
+
+func (p *execCallingProvider) GetDefaultModel() string {
+	return "mock-model"
+}
+
+func TestCronJob_RunsWithCreatorRole(t *testing.T) {
+	al, _ := newPermissionsTestLoop(t)
+	cfg := al.GetConfig()
+	cfg.Permissions.Users["telegram:4"] = "operator"
+	cfg.Permissions.Roles["operator"] = config.RoleConfig{
+		Tools: []string{"cron", "read_file"}, Agents: []string{"*"},
+	}
+	al.permissions = permissions.NewPolicy(cfg.Permissions)
+	provider := &execCallingProvider{}
+	al.GetRegistry().GetDefaultAgent().Provider = provider
+
+	cronService := cron.NewCronService(filepath.Join(t.TempDir(), "jobs.json"), nil)
+	cronTool, err := tools.NewCronTool(cronService, al, al.bus, t.TempDir(), true, 0, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cronTool.SetRoleResolver(al.RoleForSender)
+
+	msg := bus.InboundMessage{Channel: "telegram", SenderID: "4", ChatID: "chat4"}
+	ctx := tools.WithToolContext(al.withSenderRole(context.Background(), msg), "telegram", "chat4")
+	result := cronTool.Execute(ctx, map[string]any{
+		"action": "add", "message": "use exec to run echo pwned", "at_seconds": float64(3600),
+	})
+	if result.IsError {
+		t.Fatalf("cron add failed: %s", result.ForLLM)
+	}
+	jobs := cronService.ListJobs(true)
+	if len(jobs) != 1 || jobs[0].Payload.CreatedBy != "telegram:4" {
+		t.Fatalf("job creator not recorded: %+v", jobs)
+	}
+
+	cronTool.ExecuteJob(context.Background(), &jobs[0])
+	if slices.Contains(provider.toolNames, "exec") {
+		t.Errorf("cron job offered exec to the model: %v", provider.toolNames)
+	}
+	if provider.toolResult == "" || strings.Contains(provider.toolResult, "pwned") {
+		t.Fatalf("exec ran in a restricted user's cron job: %q", provider.toolResult)
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/sipeed/picoclaw/pkg/providers"
 	"github.com/sipeed/picoclaw/pkg/routing"
 	"github.com/sipeed/picoclaw/pkg/tools"
+	"github.com/sipeed/picoclaw/pkg/voice"
 )
 
 type fakeChannel struct{ id string }
@@ -1309,6 +1310,42 @@ func TestResolveMediaRefs_PDFInjectsFilePath(t *testing.T) {
 	}
 }
 
+func TestResolveMediaRefs_DocumentAppendsExtractedText(t *testing.T) {
+	store := media.NewFileMediaStore()
+	dir := t.TempDir()
+
+	// Stored without an extension, as channels do; the filename carries it.
+	csvPath := filepath.Join(dir, "upload")
+	var sb strings.Builder
+	sb.WriteString("item,qty\n")
+	for i := 0; i < 400; i++ {
+		fmt.Fprintf(&sb, "widget-%d,%d\n", i, i)
+	}
+	os.WriteFile(csvPath, []byte(sb.String()), 0o644)
+	ref, _ := store.Store(csvPath, media.MediaMeta{Filename: "stock.csv"}, "test")
+
+	messages := []providers.Message{
+		{Role: "user", Content: "stock.csv [file]", Media: []string{ref}},
+	}
+	result := resolveMediaRefs(messages, store, config.DefaultMaxMediaSize)
+
+	content := result[0].Content
+	for _, want := range []string{
+		"stock.csv [file:" + csvPath + "]",
+		"[document stock.csv: CSV, 2 pages]",
+		"--- page 1 ---\nitem | qty\nwidget-0 | 0",
+		"--- page 2 ---",
+		tools.UntrustedBeginMarker,
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("content missing %q:\n%s", want, content)
+		}
+	}
+	if strings.Contains(content, "Preview truncated") {
+		t.Errorf("two short pages should fit in the preview:\n%s", content)
+	}
+}
+
 func TestResolveMediaRefs_AudioInjectsAudioPath(t *testing.T) {
 	store := media.NewFileMediaStore()
 	dir := t.TempDir()
@@ -1366,9 +1403,10 @@ func TestResolveMediaRefs_NoGenericTagAppendsPath(t *testing.T) {
 	}
 	result := resolveMediaRefs(messages, store, config.DefaultMaxMediaSize)
 
-	expected := "here is my data [file:" + csvPath + "]"
-	if result[0].Content != expected {
-		t.Fatalf("expected content %q, got %q", expected, result[0].Content)
+	// The extracted CSV text follows the path tag.
+	expected := "here is my data [file:" + csvPath + "]\n\n"
+	if !strings.HasPrefix(result[0].Content, expected) {
+		t.Fatalf("expected content to start with %q, got %q", expected, result[0].Content)
 	}
 }
 
@@ -1426,3 +1464,185 @@ func TestResolveMediaRefs_MixedImageAndFile(t *testing.T) {
 		t.Fatalf("expected content %q, got %q", expectedContent, result[0].Content)
 	}
 }
+
+type fakeMediaChannel struct{ fakeChannel }
+
+func (f *fakeMediaChannel) SendMedia(ctx context.Context, msg bus.OutboundMediaMessage) error {
+	return nil
+}
+
+type fakeSynthesizer struct{ dir string }
+
+func (f *fakeSynthesizer) Name() string { return "fake" }
+
+func (f *fakeSynthesizer) Synthesize(ctx context.Context, text string) (*voice.SynthesisResult, error) {
+	path := filepath.Join(f.dir, "reply.ogg")
+	if err := os.WriteFile(path, []byte(text), 0o600); err != nil {
+		return nil, err
+	}
+	return &voice.SynthesisResult{Path: path, ContentType: "audio/ogg"}, nil
+}
+
+func TestSendVoiceReply(t *testing.T) {
+	al, cfg, msgBus, _, cleanup := newTestAgentLoop(t)
+	defer cleanup()
+	cfg.Voice.TTS = config.TTSConfig{
+		ReplyWhenSpokenTo: true,
+		Channels:          map[string]bool{"quiet": false},
+	}
+
+	dir := t.TempDir()
+	store := media.NewFileMediaStore()
+	al.SetMediaStore(store)
+	al.SetSynthesizer(&fakeSynthesizer{dir: dir})
+
+	chManager, err := channels.NewManager(&config.Config{}, bus.NewMessageBus(), nil)
+	if err != nil {
+		t.Fatalf("Failed to create channel manager: %v", err)
+	}
+	chManager.RegisterChannel("telegram", &fakeMediaChannel{})
+	chManager.RegisterChannel("quiet", &fakeMediaChannel{})
+	chManager.RegisterChannel("textonly", &fakeChannel{})
+	al.SetChannelManager(chManager)
+
+	audioPath := filepath.Join(dir, "voice.ogg")
+	if err := os.WriteFile(audioPath, []byte("OggS"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	audioRef, err := store.Store(audioPath, media.MediaMeta{Filename: "voice.ogg", ContentType: "audio/ogg"}, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		channel string
+		media   []string
+		want    bool
+	}{
+		{name: "voice message", channel: "telegram", media: []string{audioRef}, want: true},
+		{name: "text message", channel: "telegram", want: false},
+		{name: "channel opted out", channel: "quiet", media: []string{audioRef}, want: false},
+		{name: "channel without media support", channel: "textonly", media: []string{audioRef}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := bus.InboundMessage{Channel: tt.channel, ChatID: "1", ThreadID: "9", Media: tt.media}
+			if got := al.sendVoiceReply(context.Background(), msg, "hello"); got != tt.want {
+				t.Fatalf("sendVoiceReply() = %v, want %v", got, tt.want)
+			}
+			if !tt.want {
+				return
+			}
+			select {
+			case out := <-msgBus.OutboundMediaChan():
+				if len(out.Parts) != 1 || out.Parts[0].Type != "audio" || out.Parts[0].ContentType != "audio/ogg" {
+					t.Errorf("unexpected media message: %+v", out)
+				}
+				if out.ThreadID != "9" {
+					t.Errorf("voice reply thread = %q, want the inbound thread", out.ThreadID)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("expected a voice reply on the media bus")
+			}
+		})
+	}
+}
+
+// screenshotTool returns an image for the LLM only, like browser screenshots.
+type screenshotTool struct {
+	ref string
+}
+
+func (s *screenshotTool) Name() string               { return "mock_screenshot" }
+func (s *screenshotTool) Description() string        { return "Mock screenshot tool" }
+func (s *screenshotTool) Parameters() map[string]any { return map[string]any{"type": "object"} }
+
+func (s *screenshotTool) Execute(ctx context.Context, args map[string]any) *tools.ToolResult {
+	result := tools.NewToolResult("Screenshot captured")
+	result.LLMMedia = []string{s.ref}
+	return result
+}
+
+// screenshotProvider calls mock_screenshot once and records what it sees next.
+type screenshotProvider struct {
+	calls        int
+	lastMessages []providers.Message
+}
+
+func (p *screenshotProvider) Chat(
+	ctx context.Context,
+	messages []providers.Message,
+	tools []providers.ToolDefinition,
+	model string,
+	opts map[string]any,
+) (*providers.LLMResponse, error) {
+	p.calls++
+	p.lastMessages = append([]providers.Message(nil), messages...)
+	if p.calls == 1 {
+		return &providers.LLMResponse{
+			ToolCalls: []providers.ToolCall{{ID: "call_1", Name: "mock_screenshot", Arguments: map[string]any{}}},
+		}, nil
+	}
+	return &providers.LLMResponse{Content: "I can see it"}, nil
+}
+
+func (p *screenshotProvider) GetDefaultModel() string { return "mock-model" }
+
+func TestToolResult_LLMMediaShownToModelOnly(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         tmpDir,
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+
+	store := media.NewFileMediaStore()
+	pngPath := filepath.Join(tmpDir, "shot.png")
+	png := []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A, 0x00, 0x00, 0x00, 0x0D, 0x49, 0x48, 0x44, 0x52}
+	if err := os.WriteFile(pngPath, png, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ref, err := store.Store(pngPath, media.MediaMeta{Filename: "shot.png", ContentType: "image/png"}, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgBus := bus.NewMessageBus()
+	provider := &screenshotProvider{}
+	al := NewAgentLoop(cfg, msgBus, provider)
+	al.SetMediaStore(store)
+	agent := al.registry.GetDefaultAgent()
+	agent.Tools.Register(&screenshotTool{ref: ref})
+
+	response := testHelper{al: al}.executeAndGetResponse(t, context.Background(), bus.InboundMessage{
+		Channel:    "test",
+		SenderID:   "user1",
+		ChatID:     "chat1",
+		Content:    "look at the page",
+		SessionKey: "test-session",
+	})
+	if response != "I can see it" {
+		t.Fatalf("response = %q", response)
+	}
+
+	last := provider.lastMessages[len(provider.lastMessages)-1]
+	if last.Role != "user" || len(last.Media) != 1 || !strings.HasPrefix(last.Media[0], "data:image/png;base64,") {
+		t.Fatalf("expected the screenshot as a trailing user image, got %+v", last)
+	}
+	for _, m := range agent.Sessions.GetHistory("test-session") {
+		if len(m.Media) > 0 {
+			t.Errorf("tool images should not be persisted to the session: %+v", m)
+		}
+	}
+	select {
+	case out := <-msgBus.OutboundMediaChan():
+		t.Errorf("LLM-only media should not be sent to the user: %+v", out)
+	default:
+	}
+}
@@ -0,0 +1,293 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/sipeed/picoclaw/pkg/commands"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/mcp"
+)
+
+// registerMCPPrompts publishes every prompt of every connected server as a
+// slash command and keeps the set in sync with prompts/list_changed.
+func (al *AgentLoop) registerMCPPrompts(manager *mcp.Manager) {
+	if al.cmdRegistry == nil {
+		return
+	}
+	manager.SetPromptsChangedHandler(func(serverName string, prompts []*sdkmcp.Prompt) {
+		al.setMCPPromptCommands(manager, serverName, prompts)
+	})
+	all := manager.GetAllPrompts()
+	servers := make([]string, 0, len(all))
+	for name := range all {
+		servers = append(servers, name)
+	}
+	sort.Strings(servers)
+	for _, name := range servers {
+		al.setMCPPromptCommands(manager, name, all[name])
+	}
+}
+
+func (al *AgentLoop) setMCPPromptCommands(manager *mcp.Manager, serverName string, prompts []*sdkmcp.Prompt) {
+	defs := make([]commands.Definition, 0, len(prompts))
+	for _, prompt := range prompts {
+		defs = append(defs, mcpPromptDefinition(manager, serverName, prompt))
+	}
+
+	al.mcp.mu.Lock()
+	defer al.mcp.mu.Unlock()
+	if al.mcp.promptCommands == nil {
+		al.mcp.promptCommands = make(map[string][]string)
+	}
+	al.cmdRegistry.Unregister(al.mcp.promptCommands[serverName]...)
+	added := al.cmdRegistry.Register(defs...)
+	al.mcp.promptCommands[serverName] = added
+
+	if len(added) < len(defs) {
+		logger.WarnCF("agent", "Some MCP prompts clash with existing commands and were not registered",
+			map[string]any{
+				"server":     serverName,
+				"prompts":    len(defs),
+				"registered": len(added),
+			})
+	}
+	logger.DebugCF("agent", "Registered MCP prompt commands",
+		map[string]any{
+			"server":   serverName,
+			"commands": added,
+		})
+}
+
+// unregisterMCPPrompts removes all prompt commands added by registerMCPPrompts.
+func (al *AgentLoop) unregisterMCPPrompts() {
+	if al.cmdRegistry == nil {
+		return
+	}
+	al.mcp.mu.Lock()
+	defer al.mcp.mu.Unlock()
+	for _, names := range al.mcp.promptCommands {
+		al.cmdRegistry.Unregister(names...)
+	}
+	al.mcp.promptCommands = nil
+}
+
+// mcpPromptCommandName builds "<server>_<prompt>" restricted to [a-z0-9_],
+// which every chat platform accepts as a command name.
+func mcpPromptCommandName(serverName, promptName string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(serverName + "_" + promptName) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+func mcpPromptDefinition(manager *mcp.Manager, serverName string, prompt *sdkmcp.Prompt) commands.Definition {
+	name := mcpPromptCommandName(serverName, prompt.Name)
+	description := prompt.Description
+	if description == "" {
+		description = prompt.Title
+	}
+	if description == "" {
+		description = fmt.Sprintf("Run the %q prompt from MCP server %s", prompt.Name, serverName)
+	}
+	usage := mcpPromptUsage(name, prompt.Arguments)
+
+	return commands.Definition{
+		Name:        name,
+		Description: description,
+		Usage:       usage,
+		Handler: func(ctx context.Context, req commands.Request, rt *commands.Runtime) error {
+			if rt == nil || rt.RunPrompt == nil {
+				return req.Reply("Command unavailable in current context.")
+			}
+			args, missing := parseMCPPromptArgs(req.Text, prompt.Arguments)
+			if len(missing) > 0 {
+				return req.Reply(fmt.Sprintf("Missing %s. Usage: %s", strings.Join(missing, ", "), usage))
+			}
+
+			result, err := manager.GetPrompt(ctx, serverName, prompt.Name, args)
+			if err != nil {
+				return err
+			}
+			text := renderMCPPrompt(result.Messages)
+			if text == "" {
+				return fmt.Errorf("prompt %q returned no text", prompt.Name)
+			}
+
+			reply, err := rt.RunPrompt(ctx, text)
+			if err != nil {
+				return err
+			}
+			return req.Reply(reply)
+		},
+	}
+}
+
+func mcpPromptUsage(name string, arguments []*sdkmcp.PromptArgument) string {
+	switch len(arguments) {
+	case 0:
+		return "/" + name
+	case 1:
+		if arguments[0].Required {
+			return fmt.Sprintf("/%s <%s>", name, arguments[0].Name)
+		}
+		return fmt.Sprintf("/%s [%s]", name, arguments[0].Name)
+	}
+	parts := []string{"/" + name}
+	for _, arg := range arguments {
+		if arg.Required {
+			parts = append(parts, arg.Name+"=<value>")
+		} else {
+			parts = append(parts, "["+arg.Name+"=<value>]")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// parseMCPPromptArgs reads name=value pairs (values may be quoted) for known
+// arguments from the command text. Remaining words are joined and given to
+// the first unset argument, preferring required ones, so single-argument
+// prompts can be called as "/cmd free text". It returns the names of
+// required arguments that are still missing.
+func parseMCPPromptArgs(text string, arguments []*sdkmcp.PromptArgument) (map[string]string, []string) {
+	known := make(map[string]bool, len(arguments))
+	for _, arg := range arguments {
+		known[arg.Name] = true
+	}
+
+	tokens := splitQuoted(text)
+	if len(tokens) > 0 {
+		tokens = tokens[1:] // command name
+	}
+
+	args := make(map[string]string)
+	var rest []string
+	for _, token := range tokens {
+		if key, value, ok := strings.Cut(token, "="); ok && known[key] {
+			args[key] = value
+			continue
+		}
+		rest = append(rest, token)
+	}
+
+	if len(rest) > 0 {
+		target := ""
+		for _, arg := range arguments {
+			if _, set := args[arg.Name]; set {
+				continue
+			}
+			if arg.Required {
+				target = arg.Name
+				break
+			}
+			if target == "" {
+				target = arg.Name
+			}
+		}
+		if target != "" {
+			args[target] = strings.Join(rest, " ")
+		}
+	}
+
+	var missing []string
+	for _, arg := range arguments {
+		if _, set := args[arg.Name]; arg.Required && !set {
+			missing = append(missing, arg.Name)
+		}
+	}
+	return args, missing
+}
+
+// splitQuoted splits on whitespace, keeping single- or double-quoted runs
+// together and dropping the quotes.
+func splitQuoted(s string) []string {
+	var (
+		tokens  []string
+		current strings.Builder
+		quote   rune
+		inToken bool
+	)
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inToken = true
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inToken {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				inToken = false
+			}
+		default:
+			current.WriteRune(r)
+			inToken = true
+		}
+	}
+	if inToken {
+		tokens = append(tokens, current.String())
+	}
+	return tokens
+}
+
+// renderMCPPrompt flattens prompt messages into a single user message. Role
+// labels are only added when the prompt mixes user and assistant turns.
+func renderMCPPrompt(messages []*sdkmcp.PromptMessage) string {
+	mixed := false
+	for _, msg := range messages {
+		if msg.Role != "user" {
+			mixed = true
+			break
+		}
+	}
+
+	var parts []string
+	for _, msg := range messages {
+		var text string
+		switch c := msg.Content.(type) {
+		case *sdkmcp.TextContent:
+			text = c.Text
+		case *sdkmcp.EmbeddedResource:
+			if c.Resource == nil {
+				continue
+			}
+			if c.Resource.Text != "" {
+				text = fmt.Sprintf("--- %s ---\n%s", c.Resource.URI, c.Resource.Text)
+			} else {
+				text = fmt.Sprintf("[Binary resource: %s]", c.Resource.URI)
+			}
+		case *sdkmcp.ImageContent:
+			text = "[Image omitted]"
+		case *sdkmcp.AudioContent:
+			text = "[Audio omitted]"
+		default:
+			continue
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		if mixed {
+			role := "User"
+			if msg.Role == "assistant" {
+				role = "Assistant"
+			}
+			text = role + ": " + text
+		}
+		parts = append(parts, text)
+	}
+	return strings.Join(parts, "\n\n")
+}
@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestMCPPromptCommandName(t *testing.T) {
+	if got := mcpPromptCommandName("Git-Hub", "code.review"); got != "git_hub_code_review" {
+		t.Fatalf("command name = %q", got)
+	}
+}
+
+func TestParseMCPPromptArgs(t *testing.T) {
+	arguments := []*sdkmcp.PromptArgument{
+		{Name: "style"},
+		{Name: "file", Required: true},
+	}
+
+	tests := []struct {
+		name        string
+		text        string
+		wantArgs    map[string]string
+		wantMissing []string
+	}{
+		{
+			name:     "free text goes to first required argument",
+			text:     "/docs_review main.go please",
+			wantArgs: map[string]string{"file": "main.go please"},
+		},
+		{
+			name:     "named arguments with quotes",
+			text:     `/docs_review file=main.go style="very terse"`,
+			wantArgs: map[string]string{"file": "main.go", "style": "very terse"},
+		},
+		{
+			name:     "free text fills the remaining argument",
+			text:     "/docs_review file=main.go terse",
+			wantArgs: map[string]string{"file": "main.go", "style": "terse"},
+		},
+		{
+			name:        "required argument missing",
+			text:        "/docs_review style=terse",
+			wantArgs:    map[string]string{"style": "terse"},
+			wantMissing: []string{"file"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, missing := parseMCPPromptArgs(tt.text, arguments)
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args = %v, want %v", args, tt.wantArgs)
+			}
+			if !reflect.DeepEqual(missing, tt.wantMissing) {
+				t.Errorf("missing = %v, want %v", missing, tt.wantMissing)
+			}
+		})
+	}
+}
+
+func TestRenderMCPPrompt(t *testing.T) {
+	userOnly := []*sdkmcp.PromptMessage{
+		{Role: "user", Content: &sdkmcp.TextContent{Text: "Review this file."}},
+		{Role: "user", Content: &sdkmcp.EmbeddedResource{
+			Resource: &sdkmcp.ResourceContents{URI: "file:///main.go", Text: "package main"},
+		}},
+	}
+	if got, want := renderMCPPrompt(userOnly), "Review this file.\n\n--- file:///main.go ---\npackage main"; got != want {
+		t.Fatalf("user-only render = %q, want %q", got, want)
+	}
+
+	mixed := []*sdkmcp.PromptMessage{
+		{Role: "user", Content: &sdkmcp.TextContent{Text: "Hi"}},
+		{Role: "assistant", Content: &sdkmcp.TextContent{Text: "Hello"}},
+	}
+	if got, want := renderMCPPrompt(mixed), "User: Hi\n\nAssistant: Hello"; got != want {
+		t.Fatalf("mixed render = %q, want %q", got, want)
+	}
+}
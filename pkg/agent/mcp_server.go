@@ -0,0 +1,174 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/sipeed/picoclaw/pkg/audit"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/constants"
+	"github.com/sipeed/picoclaw/pkg/egress"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/mcp"
+	"github.com/sipeed/picoclaw/pkg/permissions"
+	"github.com/sipeed/picoclaw/pkg/routing"
+)
+
+// Channel names tools see for calls that arrive through the MCP server.
+// stdio clients run as the local user, like the CLI, and are not restricted;
+// HTTP clients are remote, so exec's allow_remote and the permissions policy
+// apply to them.
+const (
+	MCPStdioChannel = "mcp-stdio"
+	MCPHTTPChannel  = "mcp"
+)
+
+// mcpHTTPSenderID is the canonical ID HTTP MCP clients are matched by in
+// permissions.users; unlisted, they get default_role.
+const mcpHTTPSenderID = "mcp:client"
+
+var mcpSessionRe = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// NewMCPServer builds an MCP server publishing the tools and agents
+// allow-listed in tools.mcp.serve. Tools come from the default agent; each
+// listed agent becomes a chat_with_<id> tool. Entries that do not resolve
+// are skipped with a warning. channel is MCPStdioChannel or MCPHTTPChannel,
+// depending on the transport the server is served over.
+func (al *AgentLoop) NewMCPServer(ctx context.Context, channel string) (*mcp.Server, error) {
+	serveCfg := al.cfg.Tools.MCP.Serve
+	if err := al.ensureMCPInitialized(ctx); err != nil {
+		logger.WarnCF("mcp", "MCP client initialization failed; proxied MCP tools are unavailable",
+			map[string]any{"error": err.Error()})
+	}
+
+	registry := al.GetRegistry()
+	defaultAgent := registry.GetDefaultAgent()
+	if defaultAgent == nil {
+		return nil, errors.New("no default agent")
+	}
+
+	var published []mcp.LocalTool
+	seen := make(map[string]bool)
+	for _, name := range serveCfg.Tools {
+		tool, ok := defaultAgent.Tools.Get(name)
+		if !ok {
+			logger.WarnCF("mcp", "Tool listed in tools.mcp.serve is not available", map[string]any{"tool": name})
+			continue
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		published = append(published, mcp.LocalTool{
+			Name:        tool.Name(),
+			Description: tool.Description(),
+			InputSchema: tool.Parameters(),
+			Handler:     al.mcpToolHandler(name, channel),
+		})
+	}
+
+	for _, id := range serveCfg.Agents {
+		agent, ok := registry.GetAgent(id)
+		if !ok {
+			logger.WarnCF("mcp", "Agent listed in tools.mcp.serve does not exist", map[string]any{"agent_id": id})
+			continue
+		}
+		name := "chat_with_" + agent.ID
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		published = append(published, mcp.LocalTool{
+			Name:        name,
+			Description: mcpAgentDescription(agent),
+			InputSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"message": map[string]any{
+						"type":        "string",
+						"description": "Message to send to the agent.",
+					},
+					"session": map[string]any{
+						"type": "string",
+						"description": "Conversation name. Calls with the same session share history; " +
+							"defaults to \"default\".",
+					},
+				},
+				"required": []string{"message"},
+			},
+			Handler: al.mcpAgentHandler(agent.ID, channel),
+		})
+	}
+
+	if len(published) == 0 {
+		return nil, errors.New("nothing to expose: list tools or agents in tools.mcp.serve")
+	}
+	return mcp.NewServer("picoclaw", config.GetVersion(), published), nil
+}
+
+// mcpToolHandler resolves the tool at call time so a config reload that
+// rebuilds the registry is picked up.
+func (al *AgentLoop) mcpToolHandler(name, channel string) func(context.Context, map[string]any) (string, error) {
+	return func(ctx context.Context, args map[string]any) (string, error) {
+		agent := al.GetRegistry().GetDefaultAgent()
+		if agent == nil {
+			return "", errors.New("no default agent")
+		}
+		ctx = audit.WithActor(ctx, audit.Actor{ID: channel + ":client", Channel: channel})
+		ctx = egress.WithAgent(ctx, agent.ID)
+		ctx = al.withMCPClientRole(ctx, channel)
+		result := agent.Tools.ExecuteWithContext(ctx, name, args, channel, channel, nil)
+		if result.IsError {
+			return "", errors.New(result.ForLLM)
+		}
+		if result.Async && result.ForLLM == "" {
+			return "Started in the background.", nil
+		}
+		return result.ForLLM, nil
+	}
+}
+
+func (al *AgentLoop) mcpAgentHandler(agentID, channel string) func(context.Context, map[string]any) (string, error) {
+	return func(ctx context.Context, args map[string]any) (string, error) {
+		message, _ := args["message"].(string)
+		if message == "" {
+			return "", errors.New("message is required")
+		}
+		session, _ := args["session"].(string)
+		if session == "" {
+			session = "default"
+		}
+		if !mcpSessionRe.MatchString(session) {
+			return "", errors.New("session may only contain letters, digits, '.', '_' and '-' (max 64)")
+		}
+		ctx = al.withMCPClientRole(ctx, channel)
+		if !permissions.RoleFrom(ctx).AllowsAgent(agentID) {
+			return "", fmt.Errorf("permission denied: agent %q", agentID)
+		}
+		// Both transports share one history per session name.
+		sessionKey := fmt.Sprintf("agent:%s:mcp:%s", routing.NormalizeAgentID(agentID), session)
+		return al.ProcessForAgent(ctx, agentID, message, sessionKey, channel, session)
+	}
+}
+
+// withMCPClientRole attaches the role of HTTP MCP clients to ctx. stdio
+// clients are local and keep full access.
+func (al *AgentLoop) withMCPClientRole(ctx context.Context, channel string) context.Context {
+	policy := al.permissionPolicy()
+	if policy == nil || constants.IsInternalChannel(channel) {
+		return ctx
+	}
+	ctx = permissions.WithSender(ctx, mcpHTTPSenderID)
+	return permissions.WithRole(ctx, policy.RoleForID(mcpHTTPSenderID))
+}
+
+func mcpAgentDescription(agent *AgentInstance) string {
+	name := agent.Name
+	if name == "" {
+		name = agent.ID
+	}
+	return fmt.Sprintf("Send a message to the picoclaw agent %q and return its reply. "+
+		"The agent runs with its own tools, skills and memory.", name)
+}
@@ -0,0 +1,137 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+func TestNewMCPServer_PublishesAllowListedToolsAndAgents(t *testing.T) {
+	al, cfg, _, _, cleanup := newTestAgentLoop(t)
+	defer cleanup()
+	al.RegisterTool(&mockCustomTool{})
+
+	cfg.Tools.MCP.Serve.Tools = []string{"mock_custom", "not_registered"}
+	cfg.Tools.MCP.Serve.Agents = []string{"main", "ghost"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server, err := al.NewMCPServer(ctx, MCPStdioChannel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := server.ToolNames(); len(names) != 2 || names[0] != "mock_custom" || names[1] != "chat_with_main" {
+		t.Fatalf("unexpected published tools: %v", names)
+	}
+
+	// Serve over stdio pipes, as "picoclaw mcp serve" does.
+	clientToServer, serverIn := io.Pipe()
+	serverOut, serverToClient := io.Pipe()
+	go server.ServeStdio(ctx, clientToServer, serverToClient)
+
+	client := sdkmcp.NewClient(&sdkmcp.Implementation{Name: "test", Version: "1"}, nil)
+	session, err := client.Connect(ctx, &sdkmcp.IOTransport{Reader: serverOut, Writer: serverIn}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+
+	res, err := session.CallTool(ctx, &sdkmcp.CallToolParams{Name: "mock_custom", Arguments: map[string]any{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.IsError || res.Content[0].(*sdkmcp.TextContent).Text != "Custom tool executed" {
+		t.Errorf("unexpected tool result: %+v", res)
+	}
+
+	res, err = session.CallTool(ctx, &sdkmcp.CallToolParams{
+		Name:      "chat_with_main",
+		Arguments: map[string]any{"message": "hello", "session": "ide"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.IsError || res.Content[0].(*sdkmcp.TextContent).Text != "Mock response" {
+		t.Errorf("unexpected agent reply: %+v", res)
+	}
+	agent, _ := al.GetRegistry().GetAgent("main")
+	if history := agent.Sessions.GetHistory("agent:main:mcp:ide"); len(history) == 0 {
+		t.Error("expected the MCP session to be recorded under its own key")
+	}
+
+	res, _ = session.CallTool(ctx, &sdkmcp.CallToolParams{
+		Name:      "chat_with_main",
+		Arguments: map[string]any{"message": "hello", "session": "../../etc"},
+	})
+	if res == nil || !res.IsError {
+		t.Errorf("expected an invalid session name to be rejected, got %+v", res)
+	}
+}
+
+func TestNewMCPServer_NothingToExpose(t *testing.T) {
+	al, cfg, _, _, cleanup := newTestAgentLoop(t)
+	defer cleanup()
+	cfg.Tools.MCP.Serve.Tools = []string{"not_registered"}
+
+	if _, err := al.NewMCPServer(context.Background(), MCPStdioChannel); err == nil {
+		t.Error("expected an error when no allow-listed entry resolves")
+	}
+}
+
+type mcpBearerTransport struct {
+	token string
+}
+
+func (t mcpBearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestNewMCPServer_HTTPExecHonoursAllowRemote(t *testing.T) {
+	al, cfg, _, _, cleanup := newTestAgentLoop(t)
+	defer cleanup()
+	execTool, err := tools.NewExecToolWithConfig(cfg.Agents.Defaults.Workspace, false, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	al.RegisterTool(execTool)
+	cfg.Tools.MCP.Serve.Tools = []string{"exec"}
+
+	ctx := context.Background()
+	server, err := al.NewMCPServer(ctx, MCPHTTPChannel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(server.HTTPHandler("secret"))
+	defer srv.Close()
+
+	client := sdkmcp.NewClient(&sdkmcp.Implementation{Name: "test", Version: "1"}, nil)
+	session, err := client.Connect(ctx, &sdkmcp.StreamableClientTransport{
+		Endpoint:   srv.URL,
+		HTTPClient: &http.Client{Transport: mcpBearerTransport{token: "secret"}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+
+	res, err := session.CallTool(ctx, &sdkmcp.CallToolParams{
+		Name:      "exec",
+		Arguments: map[string]any{"command": "echo reached"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := res.Content[0].(*sdkmcp.TextContent).Text
+	if !res.IsError || strings.Contains(text, "reached") {
+		t.Errorf("expected exec to be refused over HTTP with allow_remote off, got %+v", res)
+	}
+}
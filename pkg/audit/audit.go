@@ -0,0 +1,481 @@
+// Package audit keeps an append-only, hash-chained log of agent actions:
+// inbound messages, tool calls, model switches, config changes and approval
+// decisions. Every entry carries the hash of its predecessor, so editing,
+// reordering or deleting entries breaks the chain and is caught by Verify.
+//
+// Each source (the gateway, the web launcher, the CLI agent, the MCP server)
+// writes its own chain. Processes sharing a source, such as concurrent
+// `picoclaw agent` runs, hold a file lock while they read the chain's tail
+// and append, so the chain never forks.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// Event types.
+const (
+	TypeInboundMessage = "inbound_message"
+	TypeToolCall       = "tool_call"
+	TypeModelSwitch    = "model_switch"
+	TypeConfigChange   = "config_change"
+	TypeApproval       = "approval"
+	TypeEgressBlocked  = "egress_blocked"
+)
+
+// Entry is one line of the audit log.
+type Entry struct {
+	Seq     int64           `json:"seq"`
+	Time    time.Time       `json:"time"`
+	Source  string          `json:"source"`
+	Type    string          `json:"type"`
+	Actor   string          `json:"actor,omitempty"`
+	Channel string          `json:"channel,omitempty"`
+	ChatID  string          `json:"chat_id,omitempty"`
+	Session string          `json:"session,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Prev    string          `json:"prev"`
+	Hash    string          `json:"hash"`
+}
+
+// computeHash returns the hash of e with its Hash field cleared.
+func (e Entry) computeHash() (string, error) {
+	e.Hash = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Options configures a Logger.
+type Options struct {
+	// Dir holds the log files.
+	Dir string
+	// Source names the chain, e.g. "gateway" or "web".
+	Source string
+	// MaxSizeBytes rotates the active file once it grows past this size.
+	// Zero disables rotation.
+	MaxSizeBytes int64
+	// MaxFiles is the number of rotated files to keep. Zero keeps all.
+	MaxFiles int
+}
+
+// Logger appends entries to one hash chain.
+type Logger struct {
+	opts Options
+
+	mu sync.Mutex
+	// lock is held across processes while the tail is read and appended to.
+	lock *os.File
+	file *os.File
+	size int64
+	seq  int64
+	last string
+}
+
+// Open opens the chain for opts.Source, continuing from its last entry. A
+// final line left incomplete by a crash is cut off.
+func Open(opts Options) (*Logger, error) {
+	if opts.Dir == "" || opts.Source == "" {
+		return nil, errors.New("audit: dir and source are required")
+	}
+	if err := os.MkdirAll(opts.Dir, 0o700); err != nil {
+		return nil, fmt.Errorf("audit: create dir: %w", err)
+	}
+	lock, err := os.OpenFile(filepath.Join(opts.Dir, opts.Source+".lock"), os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("audit: open lock: %w", err)
+	}
+
+	l := &Logger{opts: opts, lock: lock}
+	if err := l.locked(l.openActive); err != nil {
+		lock.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *Logger) activePath() string {
+	return filepath.Join(l.opts.Dir, l.opts.Source+".jsonl")
+}
+
+// locked runs fn while holding the chain's file lock.
+func (l *Logger) locked(fn func() error) error {
+	if err := lockFile(l.lock); err != nil {
+		return fmt.Errorf("audit: lock log: %w", err)
+	}
+	defer unlockFile(l.lock)
+	return fn()
+}
+
+// openActive opens the active file, repairs a torn final line and loads the
+// chain's tail. The caller holds the file lock.
+func (l *Logger) openActive() error {
+	f, err := os.OpenFile(l.activePath(), os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("audit: open log: %w", err)
+	}
+	if err := repairTail(f); err != nil {
+		f.Close()
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("audit: stat log: %w", err)
+	}
+	l.file, l.size = f, info.Size()
+	return l.loadTail()
+}
+
+// loadTail continues from the newest entry of the chain, whichever process
+// wrote it. The caller holds the file lock.
+func (l *Logger) loadTail() error {
+	files, err := chainFiles(l.opts.Dir, l.opts.Source)
+	if err != nil {
+		return err
+	}
+	l.seq, l.last = 0, ""
+	// The newest non-empty file holds the tail of the chain.
+	for i := len(files) - 1; i >= 0; i-- {
+		last, ok, err := lastEntry(files[i])
+		if err != nil {
+			return err
+		}
+		if ok {
+			l.seq, l.last = last.Seq, last.Hash
+			return nil
+		}
+	}
+	a, ok, err := readAnchor(l.opts.Dir, l.opts.Source)
+	if ok {
+		l.seq, l.last = a.Seq, a.Hash
+	}
+	return err
+}
+
+// refresh picks up entries and rotations made by other processes since this
+// one last wrote. The caller holds the file lock.
+func (l *Logger) refresh() error {
+	current, err := l.file.Stat()
+	active, activeErr := os.Stat(l.activePath())
+	if err != nil || activeErr != nil || !os.SameFile(current, active) {
+		// Another process rotated the file we have open.
+		l.file.Close()
+		l.file = nil
+		return l.openActive()
+	}
+	if current.Size() != l.size {
+		l.size = current.Size()
+		return l.loadTail()
+	}
+	return nil
+}
+
+// Append writes ev as the next entry of the chain.
+func (l *Logger) Append(ev Event) error {
+	var data json.RawMessage
+	if len(ev.Data) > 0 {
+		raw, err := json.Marshal(ev.Data)
+		if err != nil {
+			return fmt.Errorf("audit: encode data: %w", err)
+		}
+		data = raw
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return errors.New("audit: logger closed")
+	}
+	return l.locked(func() error {
+		if err := l.refresh(); err != nil {
+			return err
+		}
+		return l.write(ev, data)
+	})
+}
+
+// write appends ev after the current tail. The caller holds the file lock.
+func (l *Logger) write(ev Event, data json.RawMessage) error {
+	entry := Entry{
+		Seq:     l.seq + 1,
+		Time:    time.Now().UTC(),
+		Source:  l.opts.Source,
+		Type:    ev.Type,
+		Actor:   ev.Actor.ID,
+		Channel: ev.Actor.Channel,
+		ChatID:  ev.Actor.ChatID,
+		Session: ev.Actor.Session,
+		Data:    data,
+		Prev:    l.last,
+	}
+	hash, err := entry.computeHash()
+	if err != nil {
+		return fmt.Errorf("audit: hash entry: %w", err)
+	}
+	entry.Hash = hash
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("audit: encode entry: %w", err)
+	}
+	line = append(line, '\n')
+
+	if l.opts.MaxSizeBytes > 0 && l.size > 0 && l.size+int64(len(line)) > l.opts.MaxSizeBytes {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("audit: write entry: %w", err)
+	}
+	l.seq, l.last = entry.Seq, entry.Hash
+	return nil
+}
+
+// rotate renames the active file with a timestamp suffix and starts a new
+// one. The chain continues across files.
+func (l *Logger) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return fmt.Errorf("audit: close log: %w", err)
+	}
+	l.file = nil
+	rotated := filepath.Join(l.opts.Dir,
+		fmt.Sprintf("%s-%s.jsonl", l.opts.Source, time.Now().UTC().Format(rotatedTimeFormat)))
+	if err := os.Rename(l.activePath(), rotated); err != nil {
+		return fmt.Errorf("audit: rotate log: %w", err)
+	}
+	if err := l.openActive(); err != nil {
+		return err
+	}
+	return l.prune()
+}
+
+// prune removes the oldest rotated files beyond MaxFiles.
+func (l *Logger) prune() error {
+	if l.opts.MaxFiles <= 0 {
+		return nil
+	}
+	files, err := chainFiles(l.opts.Dir, l.opts.Source)
+	if err != nil {
+		return err
+	}
+	rotated := files[:len(files)-1] // the active file is always last
+	for len(rotated) > l.opts.MaxFiles {
+		// Anchor the chain at the file's last entry first, so Verify can tell
+		// pruning apart from deleted entries.
+		last, ok, err := lastEntry(rotated[0])
+		if err != nil {
+			return err
+		}
+		if ok {
+			if err := writeAnchor(l.opts.Dir, l.opts.Source, anchor{Seq: last.Seq, Hash: last.Hash}); err != nil {
+				return err
+			}
+		}
+		if err := os.Remove(rotated[0]); err != nil {
+			return fmt.Errorf("audit: remove old log: %w", err)
+		}
+		rotated = rotated[1:]
+	}
+	return nil
+}
+
+// Close closes the active file.
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.lock.Close()
+	l.file = nil
+	return err
+}
+
+// tailChunk is how much of a file lastEntry reads at first.
+const tailChunk = 4096
+
+// lastLine returns the offset and content of the last non-blank line of f,
+// reading backwards from size. It returns offset -1 for a blank file.
+func lastLine(f *os.File, size int64) (int64, []byte, error) {
+	for chunk := int64(tailChunk); ; chunk *= 2 {
+		start := max(size-chunk, 0)
+		buf := make([]byte, size-start)
+		if _, err := f.ReadAt(buf, start); err != nil && err != io.EOF {
+			return 0, nil, err
+		}
+		trimmed := bytes.TrimRight(buf, " \t\r\n")
+		i := bytes.LastIndexByte(trimmed, '\n')
+		switch {
+		case i >= 0:
+			return start + int64(i) + 1, trimmed[i+1:], nil
+		case start == 0 && len(bytes.TrimSpace(trimmed)) == 0:
+			return -1, nil, nil
+		case start == 0:
+			return 0, trimmed, nil
+		}
+	}
+}
+
+// lastEntry returns the last entry in path, if any, without reading the
+// whole file.
+func lastEntry(path string) (Entry, bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Entry{}, false, nil
+		}
+		return Entry{}, false, fmt.Errorf("audit: open %s: %w", path, err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return Entry{}, false, fmt.Errorf("audit: stat %s: %w", path, err)
+	}
+	offset, line, err := lastLine(f, info.Size())
+	if err != nil {
+		return Entry{}, false, fmt.Errorf("audit: read %s: %w", path, err)
+	}
+	if offset < 0 {
+		return Entry{}, false, nil
+	}
+	var e Entry
+	if err := json.Unmarshal(line, &e); err != nil {
+		return Entry{}, false, fmt.Errorf("audit: %s: malformed last entry: %w", path, err)
+	}
+	return e, true, nil
+}
+
+// repairTail fixes a final line without a newline, which a crash during a
+// write leaves behind. A complete entry gets its newline; a torn one is cut
+// off, which is safe because no entry links to it yet.
+func repairTail(f *os.File) error {
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("audit: stat log: %w", err)
+	}
+	size := info.Size()
+	if size == 0 {
+		return nil
+	}
+	b := make([]byte, 1)
+	if _, err := f.ReadAt(b, size-1); err != nil {
+		return fmt.Errorf("audit: read log: %w", err)
+	}
+	if b[0] == '\n' {
+		return nil
+	}
+	offset, line, err := lastLine(f, size)
+	if err != nil {
+		return fmt.Errorf("audit: read log: %w", err)
+	}
+	var e Entry
+	if json.Unmarshal(line, &e) == nil {
+		if _, err := f.Write([]byte{'\n'}); err != nil {
+			return fmt.Errorf("audit: repair log: %w", err)
+		}
+		return nil
+	}
+	logger.WarnCF("audit", "Removing torn final line from audit log",
+		map[string]any{
+			"file":  f.Name(),
+			"bytes": size - offset,
+		})
+	if err := f.Truncate(offset); err != nil {
+		return fmt.Errorf("audit: repair log: %w", err)
+	}
+	return nil
+}
+
+// anchor is the last entry of the newest pruned file: the predecessor of
+// the first surviving entry.
+type anchor struct {
+	Seq  int64  `json:"seq"`
+	Hash string `json:"hash"`
+}
+
+func anchorPath(dir, source string) string {
+	return filepath.Join(dir, source+".anchor")
+}
+
+func readAnchor(dir, source string) (anchor, bool, error) {
+	var a anchor
+	data, err := os.ReadFile(anchorPath(dir, source))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return a, false, nil
+		}
+		return a, false, fmt.Errorf("audit: read anchor: %w", err)
+	}
+	if err := json.Unmarshal(data, &a); err != nil {
+		return a, false, fmt.Errorf("audit: malformed anchor: %w", err)
+	}
+	return a, true, nil
+}
+
+func writeAnchor(dir, source string, a anchor) error {
+	data, err := json.Marshal(a)
+	if err != nil {
+		return fmt.Errorf("audit: encode anchor: %w", err)
+	}
+	tmp := anchorPath(dir, source) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("audit: write anchor: %w", err)
+	}
+	if err := os.Rename(tmp, anchorPath(dir, source)); err != nil {
+		return fmt.Errorf("audit: write anchor: %w", err)
+	}
+	return nil
+}
+
+// readEntries calls fn for every entry in path with its 1-based line number.
+func readEntries(path string, fn func(Entry, int) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("audit: open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for lineNo := 1; ; lineNo++ {
+		line, err := r.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var e Entry
+			if jsonErr := json.Unmarshal(line, &e); jsonErr != nil {
+				return &ChainError{File: path, Line: lineNo, Reason: "malformed entry: " + jsonErr.Error()}
+			}
+			if fnErr := fn(e, lineNo); fnErr != nil {
+				return fnErr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("audit: read %s: %w", path, err)
+		}
+	}
+}
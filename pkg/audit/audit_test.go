@@ -0,0 +1,275 @@
+package audit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func openTestLogger(t *testing.T, opts Options) *Logger {
+	t.Helper()
+	l, err := Open(opts)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l
+}
+
+func appendN(t *testing.T, l *Logger, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		err := l.Append(Event{
+			Type:  TypeToolCall,
+			Actor: Actor{ID: "telegram:1"},
+			Data:  map[string]any{"tool": "exec", "i": i},
+		})
+		if err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+}
+
+func verifyOne(t *testing.T, dir string) ChainReport {
+	t.Helper()
+	reports, err := Verify(dir)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if len(reports) != 1 {
+		t.Fatalf("Verify() returned %d reports, want 1", len(reports))
+	}
+	return reports[0]
+}
+
+func TestAppendAndVerify(t *testing.T) {
+	dir := t.TempDir()
+	l := openTestLogger(t, Options{Dir: dir, Source: "gateway"})
+	appendN(t, l, 3)
+	l.Close()
+
+	// Reopening continues the chain.
+	l = openTestLogger(t, Options{Dir: dir, Source: "gateway"})
+	appendN(t, l, 2)
+
+	r := verifyOne(t, dir)
+	if r.Err != nil || r.Entries != 5 || r.FirstSeq != 1 || r.LastSeq != 5 {
+		t.Fatalf("report = %+v, want 5 intact entries", r)
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(lines []string) []string
+		reason string
+	}{
+		{"modified", func(lines []string) []string {
+			lines[1] = strings.Replace(lines[1], `"exec"`, `"read_file"`, 1)
+			return lines
+		}, "modified"},
+		{"deleted", func(lines []string) []string {
+			return append(lines[:1], lines[2:]...)
+		}, "missing or reordered"},
+		{"reordered", func(lines []string) []string {
+			lines[1], lines[2] = lines[2], lines[1]
+			return lines
+		}, "missing or reordered"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			l := openTestLogger(t, Options{Dir: dir, Source: "gateway"})
+			appendN(t, l, 3)
+			l.Close()
+
+			path := filepath.Join(dir, "gateway.jsonl")
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines := tt.tamper(strings.Split(strings.TrimSpace(string(data)), "\n"))
+			if err = os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			r := verifyOne(t, dir)
+			if r.Err == nil || !strings.Contains(r.Err.Reason, tt.reason) {
+				t.Fatalf("report = %+v, want error containing %q", r, tt.reason)
+			}
+		})
+	}
+}
+
+func TestRotationKeepsChain(t *testing.T) {
+	dir := t.TempDir()
+	l := openTestLogger(t, Options{Dir: dir, Source: "gateway", MaxSizeBytes: 600})
+	appendN(t, l, 10)
+
+	files, err := chainFiles(dir, "gateway")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) < 3 {
+		t.Fatalf("expected rotation into several files, got %v", files)
+	}
+	r := verifyOne(t, dir)
+	if r.Err != nil || r.Entries != 10 || r.Files != len(files) || r.Truncated {
+		t.Fatalf("report = %+v, want 10 intact entries across %d files", r, len(files))
+	}
+}
+
+func TestRotationPrunesOldFiles(t *testing.T) {
+	dir := t.TempDir()
+	l := openTestLogger(t, Options{Dir: dir, Source: "gateway", MaxSizeBytes: 600, MaxFiles: 1})
+	appendN(t, l, 10)
+
+	files, err := chainFiles(dir, "gateway")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("files = %v, want one rotated file plus the active one", files)
+	}
+	r := verifyOne(t, dir)
+	if r.Err != nil || !r.Truncated || r.FirstSeq == 1 || r.LastSeq != 10 {
+		t.Fatalf("report = %+v, want truncated intact chain ending at 10", r)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	dir := t.TempDir()
+	gw := openTestLogger(t, Options{Dir: dir, Source: "gateway"})
+	web := openTestLogger(t, Options{Dir: dir, Source: "web"})
+
+	appendN(t, gw, 2)
+	if err := web.Append(Event{
+		Type:  TypeConfigChange,
+		Actor: Actor{ID: "web:127.0.0.1"},
+		Data:  map[string]any{"changed": []string{"agents.defaults.model_name"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	appendN(t, gw, 1)
+
+	all, err := Query(dir, Filter{})
+	if err != nil || len(all) != 4 {
+		t.Fatalf("Query() = %d entries, %v; want 4", len(all), err)
+	}
+	if all[2].Source != "web" {
+		t.Errorf("entries not merged in time order: %+v", all)
+	}
+
+	changes, _ := Query(dir, Filter{Type: TypeConfigChange})
+	if len(changes) != 1 || changes[0].Actor != "web:127.0.0.1" {
+		t.Errorf("Query(type) = %+v", changes)
+	}
+	newest, _ := Query(dir, Filter{Source: "gateway", Limit: 1})
+	if len(newest) != 1 || newest[0].Seq != 3 {
+		t.Errorf("Query(limit) = %+v, want gateway #3", newest)
+	}
+	if found, _ := Query(dir, Filter{Contains: "model_name"}); len(found) != 1 {
+		t.Errorf("Query(contains) = %+v", found)
+	}
+}
+
+func TestInstallAndRecord(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.AuditConfig{Enabled: true, Dir: dir}
+	if err := Install(cfg, "agent"); err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+	defer Install(config.AuditConfig{}, "agent")
+
+	ctx := WithActor(context.Background(), Actor{ID: "cli:user", Channel: "cli", Session: "s1"})
+	Record(ctx, TypeModelSwitch, map[string]any{"from": "a", "to": "b"})
+
+	entries, err := Query(dir, Filter{})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Query() = %+v, %v", entries, err)
+	}
+	e := entries[0]
+	if e.Source != "agent" || e.Actor != "cli:user" || e.Channel != "cli" || e.Session != "s1" ||
+		e.Type != TypeModelSwitch {
+		t.Errorf("entry = %+v", e)
+	}
+
+	if err = Install(config.AuditConfig{Enabled: false}, "agent"); err != nil {
+		t.Fatal(err)
+	}
+	if Enabled() {
+		t.Error("Enabled() = true after disabling")
+	}
+	Record(ctx, TypeModelSwitch, nil)
+	if entries, _ = Query(dir, Filter{}); len(entries) != 1 {
+		t.Errorf("Record() wrote while disabled")
+	}
+}
+
+func TestConcurrentLoggersShareChain(t *testing.T) {
+	dir := t.TempDir()
+	// Two loggers on one source stand in for two processes.
+	a := openTestLogger(t, Options{Dir: dir, Source: "agent", MaxSizeBytes: 2000})
+	b := openTestLogger(t, Options{Dir: dir, Source: "agent", MaxSizeBytes: 2000})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			if err := b.Append(Event{Type: TypeToolCall, Actor: Actor{ID: "b"}}); err != nil {
+				t.Errorf("Append() error = %v", err)
+				return
+			}
+		}
+	}()
+	appendN(t, a, 20)
+	<-done
+
+	r := verifyOne(t, dir)
+	if r.Err != nil || r.Entries != 40 || r.LastSeq != 40 {
+		t.Fatalf("report = %+v, want 40 intact entries", r)
+	}
+}
+
+func TestVerifyDetectsDeletedHeadFile(t *testing.T) {
+	dir := t.TempDir()
+	l := openTestLogger(t, Options{Dir: dir, Source: "gateway", MaxSizeBytes: 600})
+	appendN(t, l, 10)
+
+	files, err := chainFiles(dir, "gateway")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Remove(files[0]); err != nil {
+		t.Fatal(err)
+	}
+	r := verifyOne(t, dir)
+	if r.Err == nil || !strings.Contains(r.Err.Reason, "earlier entries are missing") {
+		t.Fatalf("report = %+v, want missing entries", r)
+	}
+}
+
+func TestOpenRepairsTornLine(t *testing.T) {
+	dir := t.TempDir()
+	l := openTestLogger(t, Options{Dir: dir, Source: "gateway"})
+	appendN(t, l, 3)
+	l.Close()
+
+	f, err := os.OpenFile(filepath.Join(dir, "gateway.jsonl"), os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"seq":4,"time":"20`)
+	f.Close()
+
+	l = openTestLogger(t, Options{Dir: dir, Source: "gateway"})
+	appendN(t, l, 1)
+	r := verifyOne(t, dir)
+	if r.Err != nil || r.Entries != 4 || r.LastSeq != 4 {
+		t.Fatalf("report = %+v, want 4 intact entries", r)
+	}
+}
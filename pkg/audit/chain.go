@@ -0,0 +1,251 @@
+package audit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// rotatedTimeFormat sorts lexically in time order.
+const rotatedTimeFormat = "20060102T150405.000000000"
+
+// ChainError describes where a chain fails verification.
+type ChainError struct {
+	File   string
+	Line   int
+	Reason string
+}
+
+func (e *ChainError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Reason)
+}
+
+// ChainReport is the result of verifying one source's chain.
+type ChainReport struct {
+	Source   string
+	Files    int
+	Entries  int
+	FirstSeq int64
+	LastSeq  int64
+	// Truncated is set when the oldest entries were pruned by rotation, so
+	// the chain starts mid-way, right after the recorded anchor.
+	Truncated bool
+	// Err is the first inconsistency found, or nil if the chain is intact.
+	Err *ChainError
+}
+
+// Sources returns the names of the chains in dir.
+func Sources(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("audit: read dir: %w", err)
+	}
+	seen := make(map[string]bool)
+	var sources []string
+	for _, e := range entries {
+		source, _, ok := parseFileName(e.Name())
+		if ok && !e.IsDir() && !seen[source] {
+			seen[source] = true
+			sources = append(sources, source)
+		}
+	}
+	sort.Strings(sources)
+	return sources, nil
+}
+
+// parseFileName splits "<source>.jsonl" and "<source>-<time>.jsonl".
+func parseFileName(name string) (source string, rotated bool, ok bool) {
+	base, found := strings.CutSuffix(name, ".jsonl")
+	if !found || base == "" {
+		return "", false, false
+	}
+	if i := strings.LastIndexByte(base, '-'); i > 0 {
+		if _, err := time.Parse(rotatedTimeFormat, base[i+1:]); err == nil {
+			return base[:i], true, true
+		}
+	}
+	return base, false, true
+}
+
+// chainFiles returns the files of source's chain, oldest first; the active
+// file is last.
+func chainFiles(dir, source string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("audit: read dir: %w", err)
+	}
+	var rotated []string
+	for _, e := range entries {
+		s, isRotated, ok := parseFileName(e.Name())
+		if ok && isRotated && s == source {
+			rotated = append(rotated, e.Name())
+		}
+	}
+	sort.Strings(rotated)
+
+	files := make([]string, 0, len(rotated)+1)
+	for _, name := range rotated {
+		files = append(files, filepath.Join(dir, name))
+	}
+	return append(files, filepath.Join(dir, source+".jsonl")), nil
+}
+
+// Verify checks every chain in dir: each entry's hash must match its
+// content, link to its predecessor's hash and follow its sequence number.
+func Verify(dir string) ([]ChainReport, error) {
+	sources, err := Sources(dir)
+	if err != nil {
+		return nil, err
+	}
+	reports := make([]ChainReport, 0, len(sources))
+	for _, source := range sources {
+		report, err := verifyChain(dir, source)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+func verifyChain(dir, source string) (ChainReport, error) {
+	report := ChainReport{Source: source}
+	files, err := chainFiles(dir, source)
+	if err != nil {
+		return report, err
+	}
+
+	var prevHash string
+	var prevSeq int64
+	for _, path := range files {
+		if _, statErr := os.Stat(path); statErr != nil {
+			continue
+		}
+		report.Files++
+		err := readEntries(path, func(e Entry, line int) error {
+			fail := func(format string, args ...any) error {
+				return &ChainError{File: path, Line: line, Reason: fmt.Sprintf(format, args...)}
+			}
+			hash, hashErr := e.computeHash()
+			if hashErr != nil {
+				return fail("cannot hash entry: %v", hashErr)
+			}
+			if hash != e.Hash {
+				return fail("entry %d was modified (hash mismatch)", e.Seq)
+			}
+			if e.Source != source {
+				return fail("entry %d belongs to source %q", e.Seq, e.Source)
+			}
+			if report.Entries == 0 {
+				// The first surviving entry must be the genesis entry, or
+				// follow the anchor rotation left when it pruned older files.
+				switch {
+				case e.Seq == 1 && e.Prev != "":
+					return fail("genesis entry has a predecessor")
+				case e.Seq != 1:
+					a, ok, anchorErr := readAnchor(dir, source)
+					if anchorErr != nil {
+						return fail("%v", anchorErr)
+					}
+					if !ok || e.Seq != a.Seq+1 || e.Prev != a.Hash {
+						return fail("chain starts at entry %d; earlier entries are missing", e.Seq)
+					}
+					report.Truncated = true
+				}
+				report.FirstSeq = e.Seq
+			} else {
+				if e.Seq != prevSeq+1 {
+					return fail("entry %d follows entry %d; entries are missing or reordered", e.Seq, prevSeq)
+				}
+				if e.Prev != prevHash {
+					return fail("entry %d does not link to entry %d", e.Seq, prevSeq)
+				}
+			}
+			prevHash, prevSeq = e.Hash, e.Seq
+			report.Entries++
+			report.LastSeq = e.Seq
+			return nil
+		})
+		if err != nil {
+			if chainErr, ok := err.(*ChainError); ok {
+				report.Err = chainErr
+				return report, nil
+			}
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// Filter selects entries for Query. Zero fields match everything.
+type Filter struct {
+	Source string
+	Type   string
+	Actor  string
+	Since  time.Time
+	Until  time.Time
+	// Contains matches a substring of the entry data.
+	Contains string
+	// Limit keeps only the newest matching entries.
+	Limit int
+}
+
+func (f Filter) match(e Entry) bool {
+	switch {
+	case f.Type != "" && e.Type != f.Type:
+		return false
+	case f.Actor != "" && e.Actor != f.Actor:
+		return false
+	case !f.Since.IsZero() && e.Time.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && e.Time.After(f.Until):
+		return false
+	case f.Contains != "" && !strings.Contains(string(e.Data), f.Contains):
+		return false
+	}
+	return true
+}
+
+// Query returns the entries in dir matching f, oldest first.
+func Query(dir string, f Filter) ([]Entry, error) {
+	sources := []string{f.Source}
+	if f.Source == "" {
+		var err error
+		if sources, err = Sources(dir); err != nil {
+			return nil, err
+		}
+	}
+
+	var result []Entry
+	for _, source := range sources {
+		files, err := chainFiles(dir, source)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range files {
+			err := readEntries(path, func(e Entry, _ int) error {
+				if f.match(e) {
+					result = append(result, e)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Time.Before(result[j].Time) })
+	if f.Limit > 0 && len(result) > f.Limit {
+		result = result[len(result)-f.Limit:]
+	}
+	return result, nil
+}
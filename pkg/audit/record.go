@@ -0,0 +1,123 @@
+package audit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// Actor identifies who caused an event and where.
+type Actor struct {
+	// ID is the sender's canonical "platform:id", or e.g. "web:<ip>".
+	ID      string
+	Channel string
+	ChatID  string
+	Session string
+}
+
+// Event is an action to record.
+type Event struct {
+	Type  string
+	Actor Actor
+	Data  map[string]any
+}
+
+type actorCtxKey struct{}
+
+// WithActor returns a child context attributing recorded events to a.
+func WithActor(ctx context.Context, a Actor) context.Context {
+	return context.WithValue(ctx, actorCtxKey{}, a)
+}
+
+// ActorFrom extracts the actor from ctx.
+func ActorFrom(ctx context.Context) Actor {
+	a, _ := ctx.Value(actorCtxKey{}).(Actor)
+	return a
+}
+
+var (
+	defaultMu   sync.Mutex
+	defaultLog  *Logger
+	defaultOpts Options
+)
+
+// Dir returns the directory configured for audit logs.
+func Dir(cfg config.AuditConfig) string {
+	if cfg.Dir != "" {
+		return cfg.Dir
+	}
+	home := os.Getenv("PICOCLAW_HOME")
+	if home == "" {
+		userHome, _ := os.UserHomeDir()
+		home = filepath.Join(userHome, ".picoclaw")
+	}
+	return filepath.Join(home, "audit")
+}
+
+// Install opens the process-wide audit log for source as configured by cfg,
+// or closes it when auditing is disabled. Calling it again with unchanged
+// settings keeps the open log.
+func Install(cfg config.AuditConfig, source string) error {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+
+	if !cfg.Enabled {
+		closeDefault()
+		return nil
+	}
+	opts := Options{
+		Dir:          Dir(cfg),
+		Source:       source,
+		MaxSizeBytes: int64(cfg.MaxSizeMB) << 20,
+		MaxFiles:     cfg.MaxFiles,
+	}
+	if defaultLog != nil && opts == defaultOpts {
+		return nil
+	}
+	// Open the new log before closing the old one, so a failed reload keeps
+	// auditing on.
+	l, err := Open(opts)
+	if err != nil {
+		return err
+	}
+	closeDefault()
+	defaultLog, defaultOpts = l, opts
+	return nil
+}
+
+func closeDefault() {
+	if defaultLog != nil {
+		defaultLog.Close()
+	}
+	defaultLog, defaultOpts = nil, Options{}
+}
+
+// Enabled reports whether a process-wide audit log is open.
+func Enabled() bool {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	return defaultLog != nil
+}
+
+// Record appends an event to the process-wide audit log, attributed to the
+// actor in ctx. It is a no-op when auditing is disabled; write failures are
+// logged rather than returned so auditing never breaks the action itself.
+func Record(ctx context.Context, eventType string, data map[string]any) {
+	defaultMu.Lock()
+	l := defaultLog
+	defaultMu.Unlock()
+	if l == nil {
+		return
+	}
+	if err := l.Append(Event{Type: eventType, Actor: ActorFrom(ctx), Data: data}); err != nil {
+		logger.ErrorCF("audit", "Failed to write audit entry",
+			map[string]any{
+				"type":  eventType,
+				"error": err.Error(),
+			})
+	}
+}
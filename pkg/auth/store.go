@@ -18,6 +18,11 @@ type AuthCredential struct {
 	AuthMethod   string    `json:"auth_method"`
 	Email        string    `json:"email,omitempty"`
 	ProjectID    string    `json:"project_id,omitempty"`
+	// ClientID, ClientSecret and TokenURL are kept for credentials whose
+	// client was registered at login time (MCP OAuth) so they can be refreshed.
+	ClientID     string `json:"client_id,omitempty"`
+	ClientSecret string `json:"client_secret,omitempty"`
+	TokenURL     string `json:"token_url,omitempty"`
 }
 
 type AuthStore struct {
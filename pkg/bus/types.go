@@ -23,6 +23,7 @@ type InboundMessage struct {
 	Content    string            `json:"content"`
 	Media      []string          `json:"media,omitempty"`
 	Peer       Peer              `json:"peer"`                  // routing peer
+	ThreadID   string            `json:"thread_id,omitempty"`   // thread/topic within the peer, if any
 	MessageID  string            `json:"message_id,omitempty"`  // platform message ID
 	MediaScope string            `json:"media_scope,omitempty"` // media lifecycle scope
 	SessionKey string            `json:"session_key"`
@@ -30,10 +31,41 @@ type InboundMessage struct {
 }
 
 type OutboundMessage struct {
-	Channel          string `json:"channel"`
-	ChatID           string `json:"chat_id"`
-	Content          string `json:"content"`
-	ReplyToMessageID string `json:"reply_to_message_id,omitempty"`
+	Channel          string       `json:"channel"`
+	ChatID           string       `json:"chat_id"`
+	Content          string       `json:"content"`
+	ReplyToMessageID string       `json:"reply_to_message_id,omitempty"`
+	ThreadID         string       `json:"thread_id,omitempty"`   // reply inside this thread/topic
+	Interactive      *Interactive `json:"interactive,omitempty"` // optional buttons/choices
+}
+
+// MetadataThreadID is the inbound metadata key channels use to report the
+// thread or topic a message was posted in. BaseChannel.HandleMessage lifts
+// it into InboundMessage.ThreadID.
+const MetadataThreadID = "thread_id"
+
+// Metadata keys set on an InboundMessage produced by a button click or
+// choice selection (see Interactive).
+const (
+	MetadataInteractionActionID = "interaction_action_id"
+	MetadataInteractionChoiceID = "interaction_choice_id"
+)
+
+// InteractiveChoice is a single selectable option, rendered as a button,
+// menu item or numbered line depending on the channel.
+type InteractiveChoice struct {
+	ID    string `json:"id"`              // value reported back when chosen
+	Label string `json:"label"`           // text shown to the user
+	Style string `json:"style,omitempty"` // "primary" | "danger" | "" (hint, may be ignored)
+}
+
+// Interactive describes the choices attached to an OutboundMessage.
+// When the user picks one, the channel publishes an InboundMessage whose
+// Content is the choice label and whose Metadata carries ActionID and the
+// choice ID under MetadataInteractionActionID / MetadataInteractionChoiceID.
+type Interactive struct {
+	ActionID string              `json:"action_id"` // correlates selections with this prompt
+	Choices  []InteractiveChoice `json:"choices"`
 }
 
 // MediaPart describes a single media attachment to send.
@@ -47,7 +79,8 @@ type MediaPart struct {
 
 // OutboundMediaMessage carries media attachments from Agent to channels via the bus.
 type OutboundMediaMessage struct {
-	Channel string      `json:"channel"`
-	ChatID  string      `json:"chat_id"`
-	Parts   []MediaPart `json:"parts"`
+	Channel  string      `json:"channel"`
+	ChatID   string      `json:"chat_id"`
+	ThreadID string      `json:"thread_id,omitempty"` // send inside this thread/topic
+	Parts    []MediaPart `json:"parts"`
 }
@@ -268,6 +268,7 @@ func (c *BaseChannel) HandleMessage(
 		Content:    content,
 		Media:      media,
 		Peer:       peer,
+		ThreadID:   metadata[bus.MetadataThreadID],
 		MessageID:  messageID,
 		MediaScope: scope,
 		Metadata:   metadata,
@@ -310,6 +311,33 @@ func (c *BaseChannel) HandleMessage(
 	}
 }
 
+// HandleInteraction publishes a button click or choice selection as an
+// InboundMessage. The chosen label becomes the message content so agents see
+// a natural reply, and the action/choice IDs are added to the metadata under
+// bus.MetadataInteractionActionID and bus.MetadataInteractionChoiceID.
+func (c *BaseChannel) HandleInteraction(
+	ctx context.Context,
+	peer bus.Peer,
+	messageID, senderID, chatID string,
+	actionID, choiceID, label string,
+	metadata map[string]string,
+	senderOpts ...bus.SenderInfo,
+) {
+	md := make(map[string]string, len(metadata)+2)
+	for k, v := range metadata {
+		md[k] = v
+	}
+	md[bus.MetadataInteractionActionID] = actionID
+	md[bus.MetadataInteractionChoiceID] = choiceID
+
+	content := label
+	if content == "" {
+		content = choiceID
+	}
+
+	c.HandleMessage(ctx, peer, messageID, senderID, chatID, content, nil, md, senderOpts...)
+}
+
 func (c *BaseChannel) SetRunning(running bool) {
 	c.running.Store(running)
 }
@@ -89,6 +89,7 @@ func (c *DiscordChannel) Start(ctx context.Context) error {
 	c.botUserID = botUser.ID
 
 	c.session.AddHandler(c.handleMessage)
+	c.session.AddHandler(c.handleInteraction)
 
 	if err := c.session.Open(); err != nil {
 		return fmt.Errorf("failed to open discord session: %w", err)
@@ -133,7 +134,7 @@ func (c *DiscordChannel) Send(ctx context.Context, msg bus.OutboundMessage) erro
 		return channels.ErrNotRunning
 	}
 
-	channelID := msg.ChatID
+	channelID := targetChannelID(msg.ChatID, msg.ThreadID)
 	if channelID == "" {
 		return fmt.Errorf("channel ID is empty")
 	}
@@ -151,7 +152,7 @@ func (c *DiscordChannel) SendMedia(ctx context.Context, msg bus.OutboundMediaMes
 		return channels.ErrNotRunning
 	}
 
-	channelID := msg.ChatID
+	channelID := targetChannelID(msg.ChatID, msg.ThreadID)
 	if channelID == "" {
 		return fmt.Errorf("channel ID is empty")
 	}
@@ -449,6 +450,17 @@ func (c *DiscordChannel) handleMessage(s *discordgo.Session, m *discordgo.Messag
 		peerID = senderID
 	}
 
+	// Threads are channels of their own in Discord. Route them under the
+	// parent channel and report the thread separately so sessions can be
+	// scoped per thread; replies still go to the thread via chatID.
+	parentID := ""
+	if m.GuildID != "" {
+		parentID = c.threadParentID(s, m.ChannelID)
+		if parentID != "" {
+			peerID = parentID
+		}
+	}
+
 	peer := bus.Peer{Kind: peerKind, ID: peerID}
 
 	metadata := map[string]string{
@@ -459,10 +471,41 @@ func (c *DiscordChannel) handleMessage(s *discordgo.Session, m *discordgo.Messag
 		"channel_id":   m.ChannelID,
 		"is_dm":        fmt.Sprintf("%t", m.GuildID == ""),
 	}
+	if parentID != "" {
+		metadata["thread_id"] = m.ChannelID
+		metadata["parent_channel_id"] = parentID
+	}
 
 	c.HandleMessage(c.ctx, peer, m.ID, senderID, m.ChannelID, content, mediaPaths, metadata, sender)
 }
 
+// threadParentID returns the parent channel ID when channelID is a thread,
+// or "" otherwise. The state cache is consulted first to avoid a REST call
+// per message.
+func (c *DiscordChannel) threadParentID(s *discordgo.Session, channelID string) string {
+	ch, err := s.State.Channel(channelID)
+	if err != nil {
+		ch, err = s.Channel(channelID)
+		if err != nil {
+			return ""
+		}
+		_ = s.State.ChannelAdd(ch)
+	}
+	if !ch.IsThread() {
+		return ""
+	}
+	return ch.ParentID
+}
+
+// targetChannelID returns the channel a reply should be posted in. Discord
+// threads are channels, so a thread ID takes precedence over the chat ID.
+func targetChannelID(chatID, threadID string) string {
+	if threadID != "" {
+		return threadID
+	}
+	return chatID
+}
+
 // startTyping starts a continuous typing indicator loop for the given chatID.
 // It stops any existing typing loop for that chatID before starting a new one.
 func (c *DiscordChannel) startTyping(chatID string) {
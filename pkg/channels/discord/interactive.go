@@ -0,0 +1,229 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/channels"
+	"github.com/sipeed/picoclaw/pkg/identity"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+const (
+	// Discord component limits: 5 buttons per action row, 5 rows per message,
+	// 100 characters of custom_id and 80 characters of button label.
+	maxButtonsPerRow         = 5
+	maxComponentRows         = 5
+	maxCustomIDLen           = 100
+	maxButtonLabelLen        = 80
+	defaultInteractivePrompt = "Please choose:"
+)
+
+// SendInteractive implements channels.InteractiveCapable.
+// Choices are rendered as message component buttons.
+func (c *DiscordChannel) SendInteractive(ctx context.Context, msg bus.OutboundMessage) error {
+	if !c.IsRunning() {
+		return channels.ErrNotRunning
+	}
+
+	channelID := targetChannelID(msg.ChatID, msg.ThreadID)
+	if channelID == "" {
+		return fmt.Errorf("channel ID is empty")
+	}
+
+	components := buildComponents(msg.Interactive)
+	if len(components) == 0 {
+		return c.Send(ctx, bus.OutboundMessage{
+			Channel: msg.Channel,
+			ChatID:  msg.ChatID,
+			Content: channels.RenderChoicesAsText(msg.Content, msg.Interactive.Choices),
+		})
+	}
+
+	content := msg.Content
+	if content == "" {
+		content = defaultInteractivePrompt
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.session.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+			Content:    content,
+			Components: components,
+		})
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("discord send: %w", channels.ErrTemporary)
+		}
+		return nil
+	case <-sendCtx.Done():
+		return sendCtx.Err()
+	}
+}
+
+// buildComponents lays out choices as button rows. Choices that do not fit
+// Discord's limits are skipped; nil is returned when no button could be built.
+func buildComponents(in *bus.Interactive) []discordgo.MessageComponent {
+	if in == nil {
+		return nil
+	}
+
+	var rows []discordgo.MessageComponent
+	var row discordgo.ActionsRow
+	for _, choice := range in.Choices {
+		customID := channels.EncodeInteractionData(in.ActionID, choice.ID)
+		if len(customID) > maxCustomIDLen {
+			logger.WarnCF("discord", "Choice custom ID too long, skipped", map[string]any{
+				"action_id": in.ActionID,
+				"choice_id": choice.ID,
+			})
+			continue
+		}
+		if len(row.Components) == maxButtonsPerRow {
+			rows = append(rows, row)
+			row = discordgo.ActionsRow{}
+		}
+		if len(rows) == maxComponentRows {
+			break
+		}
+		row.Components = append(row.Components, discordgo.Button{
+			Label:    truncateLabel(choice.Label),
+			Style:    buttonStyle(choice.Style),
+			CustomID: customID,
+		})
+	}
+	if len(row.Components) > 0 && len(rows) < maxComponentRows {
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func buttonStyle(style string) discordgo.ButtonStyle {
+	switch style {
+	case "primary":
+		return discordgo.PrimaryButton
+	case "danger":
+		return discordgo.DangerButton
+	default:
+		return discordgo.SecondaryButton
+	}
+}
+
+func truncateLabel(label string) string {
+	runes := []rune(label)
+	if len(runes) <= maxButtonLabelLen {
+		return label
+	}
+	return string(runes[:maxButtonLabelLen-1]) + "…"
+}
+
+// handleInteraction turns a button click into an InboundMessage. The buttons
+// are removed from the original message so a prompt is answered once.
+func (c *DiscordChannel) handleInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i == nil || i.Interaction == nil || i.Type != discordgo.InteractionMessageComponent {
+		return
+	}
+
+	data := i.MessageComponentData()
+	actionID, choiceID, ok := channels.DecodeInteractionData(data.CustomID)
+	if !ok {
+		return
+	}
+
+	user := i.User
+	if i.Member != nil && i.Member.User != nil {
+		user = i.Member.User
+	}
+	if user == nil {
+		return
+	}
+
+	sender := bus.SenderInfo{
+		Platform:    "discord",
+		PlatformID:  user.ID,
+		CanonicalID: identity.BuildCanonicalID("discord", user.ID),
+		Username:    user.Username,
+		DisplayName: user.Username,
+	}
+	if !c.IsAllowedSender(sender) {
+		logger.DebugCF("discord", "Interaction rejected by allowlist", map[string]any{
+			"user_id": user.ID,
+		})
+		// Acknowledge without changing the message so other users can still answer.
+		_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseDeferredMessageUpdate,
+		})
+		return
+	}
+
+	label := ""
+	content := ""
+	if i.Message != nil {
+		content = i.Message.Content
+		label = componentLabel(i.Message.Components, data.CustomID)
+	}
+
+	// Replace the message without components to disable further clicks.
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    content,
+			Components: []discordgo.MessageComponent{},
+		},
+	})
+	if err != nil {
+		logger.DebugCF("discord", "Failed to acknowledge interaction", map[string]any{
+			"error": err.Error(),
+		})
+	}
+
+	peer := bus.Peer{Kind: "channel", ID: i.ChannelID}
+	parentID := ""
+	if i.GuildID == "" {
+		peer = bus.Peer{Kind: "direct", ID: user.ID}
+	} else if parentID = c.threadParentID(s, i.ChannelID); parentID != "" {
+		peer.ID = parentID
+	}
+
+	metadata := map[string]string{
+		"user_id":      user.ID,
+		"username":     user.Username,
+		"display_name": sender.DisplayName,
+		"guild_id":     i.GuildID,
+		"channel_id":   i.ChannelID,
+		"is_dm":        fmt.Sprintf("%t", i.GuildID == ""),
+	}
+	if parentID != "" {
+		metadata["thread_id"] = i.ChannelID
+		metadata["parent_channel_id"] = parentID
+	}
+
+	c.HandleInteraction(c.ctx, peer, i.ID, user.ID, i.ChannelID,
+		actionID, choiceID, label, metadata, sender)
+}
+
+// componentLabel finds the label of the button whose custom ID matches customID.
+func componentLabel(components []discordgo.MessageComponent, customID string) string {
+	for _, component := range components {
+		row, ok := component.(*discordgo.ActionsRow)
+		if !ok {
+			continue
+		}
+		for _, item := range row.Components {
+			if button, ok := item.(*discordgo.Button); ok && button.CustomID == customID {
+				return button.Label
+			}
+		}
+	}
+	return ""
+}
@@ -6,6 +6,9 @@ import (
 	"strings"
 
 	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/channels"
 )
 
 // mentionPlaceholderRegex matches @_user_N placeholders inserted by Feishu for mentions.
@@ -40,6 +43,59 @@ func buildMarkdownCard(content string) (string, error) {
 	return string(data), nil
 }
 
+// buildInteractiveCard builds a Feishu Interactive Card JSON 2.0 string with
+// markdown content followed by one callback button per choice. Each button
+// carries the encoded interaction data, its label and the prompt in the
+// callback value, so the answered card can be rebuilt without extra state.
+func buildInteractiveCard(content string, in *bus.Interactive) (string, error) {
+	elements := []map[string]any{}
+	if content != "" {
+		elements = append(elements, map[string]any{
+			"tag":     "markdown",
+			"content": content,
+		})
+	}
+	for _, choice := range in.Choices {
+		buttonType := "default"
+		switch choice.Style {
+		case "primary":
+			buttonType = "primary"
+		case "danger":
+			buttonType = "danger"
+		}
+		elements = append(elements, map[string]any{
+			"tag":  "button",
+			"type": buttonType,
+			"text": map[string]any{
+				"tag":     "plain_text",
+				"content": choice.Label,
+			},
+			"behaviors": []map[string]any{
+				{
+					"type": "callback",
+					"value": map[string]any{
+						"data":   channels.EncodeInteractionData(in.ActionID, choice.ID),
+						"label":  choice.Label,
+						"prompt": content,
+					},
+				},
+			},
+		})
+	}
+
+	card := map[string]any{
+		"schema": "2.0",
+		"body": map[string]any{
+			"elements": elements,
+		},
+	}
+	data, err := json.Marshal(card)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
 // extractJSONStringField unmarshals content as JSON and returns the value of the given string field.
 // Returns "" if the content is invalid JSON or the field is missing/empty.
 func extractJSONStringField(content, field string) string {
@@ -5,6 +5,9 @@ import (
 	"testing"
 
 	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/channels"
 )
 
 func TestExtractJSONStringField(t *testing.T) {
@@ -227,6 +230,56 @@ func TestBuildMarkdownCard(t *testing.T) {
 	}
 }
 
+func TestBuildInteractiveCard(t *testing.T) {
+	in := &bus.Interactive{
+		ActionID: "a1",
+		Choices: []bus.InteractiveChoice{
+			{ID: "1", Label: "Yes", Style: "primary"},
+			{ID: "2", Label: "No"},
+		},
+	}
+	result, err := buildInteractiveCard("Deploy now?", in)
+	if err != nil {
+		t.Fatalf("buildInteractiveCard unexpected error: %v", err)
+	}
+
+	var parsed struct {
+		Body struct {
+			Elements []struct {
+				Tag       string `json:"tag"`
+				Type      string `json:"type"`
+				Content   string `json:"content"`
+				Behaviors []struct {
+					Type  string            `json:"type"`
+					Value map[string]string `json:"value"`
+				} `json:"behaviors"`
+			} `json:"elements"`
+		} `json:"body"`
+	}
+	if err := json.Unmarshal([]byte(result), &parsed); err != nil {
+		t.Fatalf("buildInteractiveCard produced invalid JSON: %v", err)
+	}
+
+	elements := parsed.Body.Elements
+	if len(elements) != 3 {
+		t.Fatalf("expected markdown + 2 buttons, got %d elements", len(elements))
+	}
+	if elements[0].Tag != "markdown" || elements[0].Content != "Deploy now?" {
+		t.Errorf("unexpected first element: %+v", elements[0])
+	}
+	button := elements[1]
+	if button.Tag != "button" || button.Type != "primary" || len(button.Behaviors) != 1 {
+		t.Fatalf("unexpected button: %+v", button)
+	}
+	value := button.Behaviors[0].Value
+	if value["data"] != channels.EncodeInteractionData("a1", "1") || value["label"] != "Yes" {
+		t.Errorf("unexpected callback value: %v", value)
+	}
+	if elements[2].Type != "default" {
+		t.Errorf("second button type = %q, want default", elements[2].Type)
+	}
+}
+
 func TestStripMentionPlaceholders(t *testing.T) {
 	strPtr := func(s string) *string { return &s }
 
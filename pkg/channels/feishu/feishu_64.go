@@ -36,6 +36,7 @@ type FeishuChannel struct {
 	wsClient *larkws.Client
 
 	botOpenID atomic.Value // stores string; populated lazily for @mention detection
+	chatTypes sync.Map     // chat_id -> chat_type, used to route card button callbacks
 
 	mu     sync.Mutex
 	cancel context.CancelFunc
@@ -69,7 +70,8 @@ func (c *FeishuChannel) Start(ctx context.Context) error {
 	}
 
 	dispatcher := larkdispatcher.NewEventDispatcher(c.config.VerificationToken, c.config.EncryptKey).
-		OnP2MessageReceiveV1(c.handleMessageReceive)
+		OnP2MessageReceiveV1(c.handleMessageReceive).
+		OnP2CardActionTrigger(c.handleCardAction)
 
 	runCtx, cancel := context.WithCancel(ctx)
 
@@ -390,6 +392,7 @@ func (c *FeishuChannel) handleMessageReceive(ctx context.Context, event *larkim.
 	chatType := stringValue(message.ChatType)
 	if chatType != "" {
 		metadata["chat_type"] = chatType
+		c.chatTypes.Store(chatID, chatType)
 	}
 	if sender != nil && sender.TenantKey != nil {
 		metadata["tenant_key"] = *sender.TenantKey
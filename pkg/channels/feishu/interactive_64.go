@@ -0,0 +1,126 @@
+//go:build amd64 || arm64 || riscv64 || mips64 || ppc64
+
+package feishu
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/larksuite/oapi-sdk-go/v3/event/dispatcher/callback"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/channels"
+	"github.com/sipeed/picoclaw/pkg/identity"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// SendInteractive implements channels.InteractiveCapable.
+// Choices are rendered as callback buttons inside an interactive card.
+func (c *FeishuChannel) SendInteractive(ctx context.Context, msg bus.OutboundMessage) error {
+	if !c.IsRunning() {
+		return channels.ErrNotRunning
+	}
+
+	if msg.ChatID == "" {
+		return fmt.Errorf("chat ID is empty: %w", channels.ErrSendFailed)
+	}
+
+	cardContent, err := buildInteractiveCard(msg.Content, msg.Interactive)
+	if err != nil {
+		return fmt.Errorf("feishu send: card build failed: %w", err)
+	}
+	return c.sendCard(ctx, msg.ChatID, cardContent)
+}
+
+// handleCardAction turns a card button click into an InboundMessage. The
+// returned card replaces the buttons with the selected choice so a prompt is
+// answered once.
+func (c *FeishuChannel) handleCardAction(
+	ctx context.Context,
+	event *callback.CardActionTriggerEvent,
+) (*callback.CardActionTriggerResponse, error) {
+	if event == nil || event.Event == nil || event.Event.Action == nil || event.Event.Context == nil {
+		return nil, nil
+	}
+
+	value := event.Event.Action.Value
+	data, _ := value["data"].(string)
+	actionID, choiceID, ok := channels.DecodeInteractionData(data)
+	if !ok {
+		return nil, nil
+	}
+	label, _ := value["label"].(string)
+	prompt, _ := value["prompt"].(string)
+
+	senderID := ""
+	if op := event.Event.Operator; op != nil {
+		if op.UserID != nil && *op.UserID != "" {
+			senderID = *op.UserID
+		} else {
+			senderID = op.OpenID
+		}
+	}
+	if senderID == "" {
+		return nil, nil
+	}
+
+	senderInfo := bus.SenderInfo{
+		Platform:    "feishu",
+		PlatformID:  senderID,
+		CanonicalID: identity.BuildCanonicalID("feishu", senderID),
+	}
+	if !c.IsAllowedSender(senderInfo) {
+		return nil, nil
+	}
+
+	chatID := event.Event.Context.OpenChatID
+	messageID := event.Event.Context.OpenMessageID
+
+	peer := bus.Peer{Kind: "group", ID: chatID}
+	chatType, _ := c.chatTypes.Load(chatID)
+	if chatType == "p2p" {
+		peer = bus.Peer{Kind: "direct", ID: senderID}
+	}
+
+	metadata := map[string]string{}
+	if messageID != "" {
+		metadata["message_id"] = messageID
+	}
+	if ct, ok := chatType.(string); ok {
+		metadata["chat_type"] = ct
+	}
+	if op := event.Event.Operator; op != nil && op.TenantKey != nil {
+		metadata["tenant_key"] = *op.TenantKey
+	}
+
+	c.HandleInteraction(ctx, peer, messageID, senderID, chatID,
+		actionID, choiceID, label, metadata, senderInfo)
+
+	return answeredCardResponse(prompt, label), nil
+}
+
+// answeredCardResponse rebuilds the prompt card without buttons.
+func answeredCardResponse(prompt, label string) *callback.CardActionTriggerResponse {
+	content := prompt
+	if label != "" {
+		if content != "" {
+			content += "\n\n"
+		}
+		content += "**Selected:** " + label
+	}
+	cardJSON, err := buildMarkdownCard(content)
+	if err != nil {
+		return nil
+	}
+	var card map[string]any
+	if err := json.Unmarshal([]byte(cardJSON), &card); err != nil {
+		logger.DebugCF("feishu", "Failed to build answered card", map[string]any{
+			"error": err.Error(),
+		})
+		return nil
+	}
+	return &callback.CardActionTriggerResponse{
+		Card: &callback.Card{Type: "raw", Data: card},
+	}
+}
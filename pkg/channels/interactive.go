@@ -0,0 +1,52 @@
+package channels
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+// interactionDataPrefix marks callback payloads produced by EncodeInteractionData
+// so channels can tell our buttons apart from other platform callbacks.
+const interactionDataPrefix = "pc1"
+
+// RenderChoicesAsText appends choices to content as a numbered list. It is the
+// fallback used for channels that do not implement InteractiveCapable.
+func RenderChoicesAsText(content string, choices []bus.InteractiveChoice) string {
+	if len(choices) == 0 {
+		return content
+	}
+
+	var sb strings.Builder
+	sb.WriteString(strings.TrimRight(content, "\n"))
+	if sb.Len() > 0 {
+		sb.WriteString("\n\n")
+	}
+	for i, choice := range choices {
+		fmt.Fprintf(&sb, "%d. %s\n", i+1, choice.Label)
+	}
+	sb.WriteString("Reply with the number of your choice.")
+	return sb.String()
+}
+
+// EncodeInteractionData packs an action and choice ID into a compact string
+// suitable for platform callback fields (Telegram callback_data, Discord
+// custom_id, Slack action value).
+func EncodeInteractionData(actionID, choiceID string) string {
+	return interactionDataPrefix + ":" + actionID + ":" + choiceID
+}
+
+// DecodeInteractionData reverses EncodeInteractionData. ok is false when data
+// was not produced by EncodeInteractionData.
+func DecodeInteractionData(data string) (actionID, choiceID string, ok bool) {
+	rest, found := strings.CutPrefix(data, interactionDataPrefix+":")
+	if !found {
+		return "", "", false
+	}
+	actionID, choiceID, found = strings.Cut(rest, ":")
+	if !found || actionID == "" || choiceID == "" {
+		return "", "", false
+	}
+	return actionID, choiceID, true
+}
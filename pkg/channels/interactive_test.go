@@ -0,0 +1,104 @@
+package channels
+
+import (
+	"context"
+	"testing"
+
+	"golang.org/x/time/rate"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+// mockInteractiveChannel records messages routed to SendInteractive.
+type mockInteractiveChannel struct {
+	mockChannel
+	interactive []bus.OutboundMessage
+}
+
+func (m *mockInteractiveChannel) SendInteractive(_ context.Context, msg bus.OutboundMessage) error {
+	m.interactive = append(m.interactive, msg)
+	return nil
+}
+
+func testChoices() *bus.Interactive {
+	return &bus.Interactive{
+		ActionID: "a1",
+		Choices: []bus.InteractiveChoice{
+			{ID: "1", Label: "Yes"},
+			{ID: "2", Label: "No"},
+		},
+	}
+}
+
+func TestRenderChoicesAsText(t *testing.T) {
+	got := RenderChoicesAsText("Deploy now?\n", testChoices().Choices)
+	want := "Deploy now?\n\n1. Yes\n2. No\nReply with the number of your choice."
+	if got != want {
+		t.Errorf("RenderChoicesAsText = %q, want %q", got, want)
+	}
+	if got := RenderChoicesAsText("plain", nil); got != "plain" {
+		t.Errorf("RenderChoicesAsText without choices = %q, want %q", got, "plain")
+	}
+}
+
+func TestInteractionDataRoundTrip(t *testing.T) {
+	data := EncodeInteractionData("a1", "2")
+	actionID, choiceID, ok := DecodeInteractionData(data)
+	if !ok || actionID != "a1" || choiceID != "2" {
+		t.Fatalf("DecodeInteractionData(%q) = %q, %q, %v", data, actionID, choiceID, ok)
+	}
+
+	for _, bad := range []string{"", "a1:2", "pc1:", "pc1:a1", "pc1::2", "other:a1:2"} {
+		if _, _, ok := DecodeInteractionData(bad); ok {
+			t.Errorf("DecodeInteractionData(%q) accepted foreign data", bad)
+		}
+	}
+}
+
+func TestSendWithRetry_InteractiveCapable(t *testing.T) {
+	m := newTestManager()
+	ch := &mockInteractiveChannel{
+		mockChannel: mockChannel{
+			sendFn: func(_ context.Context, _ bus.OutboundMessage) error { return nil },
+		},
+	}
+	w := &channelWorker{ch: ch, limiter: rate.NewLimiter(rate.Inf, 1)}
+
+	m.sendSplit(context.Background(), "test", w, bus.OutboundMessage{
+		Channel: "test", ChatID: "1", Content: "Deploy now?", Interactive: testChoices(),
+	})
+
+	if len(ch.interactive) != 1 || len(ch.sentMessages) != 0 {
+		t.Fatalf("expected 1 SendInteractive and 0 Send calls, got %d and %d",
+			len(ch.interactive), len(ch.sentMessages))
+	}
+	if ch.interactive[0].Interactive.ActionID != "a1" {
+		t.Errorf("unexpected interactive payload: %+v", ch.interactive[0].Interactive)
+	}
+	if ch.placeholdersSent != 0 || ch.editedMessages != 0 {
+		t.Error("interactive prompt must not consume the placeholder")
+	}
+}
+
+func TestSendWithRetry_InteractiveFallsBackToText(t *testing.T) {
+	m := newTestManager()
+	ch := &mockChannel{
+		sendFn: func(_ context.Context, _ bus.OutboundMessage) error { return nil },
+	}
+	w := &channelWorker{ch: ch, limiter: rate.NewLimiter(rate.Inf, 1)}
+
+	m.sendSplit(context.Background(), "test", w, bus.OutboundMessage{
+		Channel: "test", ChatID: "1", Content: "Deploy now?", Interactive: testChoices(),
+	})
+
+	if len(ch.sentMessages) != 1 {
+		t.Fatalf("expected 1 Send call, got %d", len(ch.sentMessages))
+	}
+	sent := ch.sentMessages[0]
+	if sent.Interactive != nil {
+		t.Error("expected Interactive to be dropped for plain channels")
+	}
+	if want := RenderChoicesAsText("Deploy now?", testChoices().Choices); sent.Content != want {
+		t.Errorf("content = %q, want %q", sent.Content, want)
+	}
+}
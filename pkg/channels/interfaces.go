@@ -3,6 +3,7 @@ package channels
 import (
 	"context"
 
+	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/commands"
 )
 
@@ -50,3 +51,12 @@ type PlaceholderRecorder interface {
 type CommandRegistrarCapable interface {
 	RegisterCommands(ctx context.Context, defs []commands.Definition) error
 }
+
+// InteractiveCapable — channels that can render msg.Interactive as native
+// buttons or components (Telegram inline keyboards, Discord components,
+// Slack Block Kit, ...). Selections MUST be reported back through
+// BaseChannel.HandleInteraction. Channels that do not implement this
+// interface receive a numbered text list instead (see RenderChoicesAsText).
+type InteractiveCapable interface {
+	SendInteractive(ctx context.Context, msg bus.OutboundMessage) error
+}
@@ -311,6 +311,14 @@ func (m *Manager) initChannels() error {
 		m.initChannel("irc", "IRC")
 	}
 
+	if m.config.Channels.Webhook.Enabled {
+		m.initChannel("webhook", "Webhook")
+	}
+
+	if m.config.Channels.MQTT.Enabled && m.config.Channels.MQTT.Broker != "" {
+		m.initChannel("mqtt", "MQTT")
+	}
+
 	logger.InfoCF("channels", "Channel initialization completed", map[string]any{
 		"enabled_channels": len(m.channels),
 	})
@@ -355,6 +363,16 @@ func (m *Manager) SetupHTTPServer(addr string, healthServer *health.Server) {
 	}
 }
 
+// RegisterHTTPHandler mounts an additional handler on the shared HTTP server.
+// It must be called after SetupHTTPServer and before StartAll.
+func (m *Manager) RegisterHTTPHandler(pattern string, handler http.Handler) {
+	if m.mux == nil {
+		return
+	}
+	m.mux.Handle(pattern, handler)
+	logger.InfoCF("channels", "HTTP handler registered", map[string]any{"path": pattern})
+}
+
 func (m *Manager) StartAll(ctx context.Context) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -504,26 +522,38 @@ func (m *Manager) runWorker(ctx context.Context, name string, w *channelWorker)
 			if !ok {
 				return
 			}
-			maxLen := 0
-			if mlp, ok := w.ch.(MessageLengthProvider); ok {
-				maxLen = mlp.MaxMessageLength()
-			}
-			if maxLen > 0 && len([]rune(msg.Content)) > maxLen {
-				chunks := SplitMessage(msg.Content, maxLen)
-				for _, chunk := range chunks {
-					chunkMsg := msg
-					chunkMsg.Content = chunk
-					m.sendWithRetry(ctx, name, w, chunkMsg)
-				}
-			} else {
-				m.sendWithRetry(ctx, name, w, msg)
-			}
+			m.sendSplit(ctx, name, w, msg)
 		case <-ctx.Done():
 			return
 		}
 	}
 }
 
+// sendSplit sends msg through sendWithRetry, splitting content that exceeds
+// the channel's maximum message length. Interactive choices the channel
+// cannot render are folded into the text first, and native buttons are
+// attached to the last chunk only.
+func (m *Manager) sendSplit(ctx context.Context, name string, w *channelWorker, msg bus.OutboundMessage) {
+	msg = degradeInteractive(w.ch, msg)
+	maxLen := 0
+	if mlp, ok := w.ch.(MessageLengthProvider); ok {
+		maxLen = mlp.MaxMessageLength()
+	}
+	if maxLen > 0 && len([]rune(msg.Content)) > maxLen {
+		chunks := SplitMessage(msg.Content, maxLen)
+		for i, chunk := range chunks {
+			chunkMsg := msg
+			chunkMsg.Content = chunk
+			if i < len(chunks)-1 {
+				chunkMsg.Interactive = nil
+			}
+			m.sendWithRetry(ctx, name, w, chunkMsg)
+		}
+	} else {
+		m.sendWithRetry(ctx, name, w, msg)
+	}
+}
+
 // sendWithRetry sends a message through the channel with rate limiting and
 // retry logic. It classifies errors to determine the retry strategy:
 //   - ErrNotRunning / ErrSendFailed: permanent, no retry
@@ -536,14 +566,16 @@ func (m *Manager) sendWithRetry(ctx context.Context, name string, w *channelWork
 		return
 	}
 
-	// Pre-send: stop typing and try to edit placeholder
-	if m.preSend(ctx, name, msg, w.ch) {
+	// Pre-send: stop typing and try to edit placeholder.
+	// Interactive prompts are sent mid-turn, so they leave the placeholder
+	// in place for the final response to replace.
+	if msg.Interactive == nil && m.preSend(ctx, name, msg, w.ch) {
 		return // placeholder was edited successfully, skip Send
 	}
 
 	var lastErr error
 	for attempt := 0; attempt <= maxRetries; attempt++ {
-		lastErr = w.ch.Send(ctx, msg)
+		lastErr = sendOutbound(ctx, w.ch, msg)
 		if lastErr == nil {
 			return
 		}
@@ -586,6 +618,32 @@ func (m *Manager) sendWithRetry(ctx context.Context, name string, w *channelWork
 	})
 }
 
+// degradeInteractive renders msg.Interactive as a numbered text list when the
+// channel cannot display native buttons.
+func degradeInteractive(ch Channel, msg bus.OutboundMessage) bus.OutboundMessage {
+	if msg.Interactive == nil {
+		return msg
+	}
+	if _, ok := ch.(InteractiveCapable); ok && len(msg.Interactive.Choices) > 0 {
+		return msg
+	}
+	msg.Content = RenderChoicesAsText(msg.Content, msg.Interactive.Choices)
+	msg.Interactive = nil
+	return msg
+}
+
+// sendOutbound routes msg to SendInteractive when it carries choices and the
+// channel supports them, and to Send otherwise.
+func sendOutbound(ctx context.Context, ch Channel, msg bus.OutboundMessage) error {
+	if msg.Interactive != nil {
+		if ic, ok := ch.(InteractiveCapable); ok {
+			return ic.SendInteractive(ctx, msg)
+		}
+		msg = degradeInteractive(ch, msg)
+	}
+	return ch.Send(ctx, msg)
+}
+
 func dispatchLoop[M any](
 	ctx context.Context,
 	m *Manager,
@@ -865,19 +923,7 @@ func (m *Manager) SendMessage(ctx context.Context, msg bus.OutboundMessage) erro
 		return fmt.Errorf("channel %s has no active worker", msg.Channel)
 	}
 
-	maxLen := 0
-	if mlp, ok := w.ch.(MessageLengthProvider); ok {
-		maxLen = mlp.MaxMessageLength()
-	}
-	if maxLen > 0 && len([]rune(msg.Content)) > maxLen {
-		for _, chunk := range SplitMessage(msg.Content, maxLen) {
-			chunkMsg := msg
-			chunkMsg.Content = chunk
-			m.sendWithRetry(ctx, msg.Channel, w, chunkMsg)
-		}
-	} else {
-		m.sendWithRetry(ctx, msg.Channel, w, msg)
-	}
+	m.sendSplit(ctx, msg.Channel, w, msg)
 	return nil
 }
 
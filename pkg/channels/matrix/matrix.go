@@ -281,17 +281,22 @@ func (c *MatrixChannel) Send(ctx context.Context, msg bus.OutboundMessage) error
 		return channels.ErrNotRunning
 	}
 
-	roomID := id.RoomID(strings.TrimSpace(msg.ChatID))
+	roomID, threadRoot := parseMatrixChatID(msg.ChatID)
 	if roomID == "" {
 		return fmt.Errorf("matrix room ID is empty: %w", channels.ErrSendFailed)
 	}
+	if threadRoot == "" {
+		threadRoot = id.EventID(strings.TrimSpace(msg.ThreadID))
+	}
 
 	content := strings.TrimSpace(msg.Content)
 	if content == "" {
 		return nil
 	}
 
-	_, err := c.client.SendMessageEvent(ctx, roomID, event.EventMessage, c.messageContent(content))
+	mc := c.messageContent(content)
+	setThread(mc, threadRoot)
+	_, err := c.client.SendMessageEvent(ctx, roomID, event.EventMessage, mc)
 	if err != nil {
 		return fmt.Errorf("matrix send: %w", channels.ErrTemporary)
 	}
@@ -317,10 +322,13 @@ func (c *MatrixChannel) SendMedia(ctx context.Context, msg bus.OutboundMediaMess
 		sendCtx = context.Background()
 	}
 
-	roomID := id.RoomID(strings.TrimSpace(msg.ChatID))
+	roomID, threadRoot := parseMatrixChatID(msg.ChatID)
 	if roomID == "" {
 		return fmt.Errorf("matrix room ID is empty: %w", channels.ErrSendFailed)
 	}
+	if threadRoot == "" {
+		threadRoot = id.EventID(strings.TrimSpace(msg.ThreadID))
+	}
 
 	store := c.GetMediaStore()
 	if store == nil {
@@ -406,6 +414,7 @@ func (c *MatrixChannel) SendMedia(ctx context.Context, msg bus.OutboundMediaMess
 			fileInfo.Size(),
 			uploadResp.ContentURI.CUString(),
 		)
+		setThread(content, threadRoot)
 
 		if _, err := c.client.SendMessageEvent(sendCtx, roomID, event.EventMessage, content); err != nil {
 			logger.ErrorCF("matrix", "Failed to send media message", map[string]any{
@@ -426,7 +435,7 @@ func (c *MatrixChannel) StartTyping(ctx context.Context, chatID string) (func(),
 		return func() {}, nil
 	}
 
-	roomID := id.RoomID(strings.TrimSpace(chatID))
+	roomID, _ := parseMatrixChatID(chatID)
 	if roomID == "" {
 		return func() {}, fmt.Errorf("matrix room ID is empty")
 	}
@@ -465,7 +474,7 @@ func (c *MatrixChannel) SendPlaceholder(ctx context.Context, chatID string) (str
 		return "", nil
 	}
 
-	roomID := id.RoomID(strings.TrimSpace(chatID))
+	roomID, threadRoot := parseMatrixChatID(chatID)
 	if roomID == "" {
 		return "", fmt.Errorf("matrix room ID is empty")
 	}
@@ -475,10 +484,12 @@ func (c *MatrixChannel) SendPlaceholder(ctx context.Context, chatID string) (str
 		text = "Thinking... 💭"
 	}
 
-	resp, err := c.client.SendMessageEvent(ctx, roomID, event.EventMessage, &event.MessageEventContent{
+	placeholder := &event.MessageEventContent{
 		MsgType: event.MsgNotice,
 		Body:    text,
-	})
+	}
+	setThread(placeholder, threadRoot)
+	resp, err := c.client.SendMessageEvent(ctx, roomID, event.EventMessage, placeholder)
 	if err != nil {
 		return "", err
 	}
@@ -488,7 +499,7 @@ func (c *MatrixChannel) SendPlaceholder(ctx context.Context, chatID string) (str
 
 // EditMessage implements channels.MessageEditor.
 func (c *MatrixChannel) EditMessage(ctx context.Context, chatID string, messageID string, content string) error {
-	roomID := id.RoomID(strings.TrimSpace(chatID))
+	roomID, _ := parseMatrixChatID(chatID)
 	if roomID == "" {
 		return fmt.Errorf("matrix room ID is empty")
 	}
@@ -629,12 +640,20 @@ func (c *MatrixChannel) handleMessageEvent(ctx context.Context, evt *event.Event
 		metadata["reply_to_msg_id"] = replyTo.String()
 	}
 
+	// Threaded messages reply inside their thread: embed the thread root
+	// in the chat ID as "roomID/threadRoot", like Slack and Telegram topics.
+	chatID := roomID
+	if threadRoot := msgEvt.GetRelatesTo().GetThreadParent(); threadRoot != "" {
+		chatID = roomID + "/" + threadRoot.String()
+		metadata["thread_id"] = threadRoot.String()
+	}
+
 	c.HandleMessage(
 		c.baseContext(),
 		bus.Peer{Kind: peerKind, ID: peerID},
 		evt.ID.String(),
 		senderID,
-		roomID,
+		chatID,
 		content,
 		mediaPaths,
 		metadata,
@@ -642,6 +661,21 @@ func (c *MatrixChannel) handleMessageEvent(ctx context.Context, evt *event.Event
 	)
 }
 
+// parseMatrixChatID splits "roomID/threadRoot" into its components. Room IDs
+// never contain "/", so everything after the first one is the thread root.
+func parseMatrixChatID(chatID string) (id.RoomID, id.EventID) {
+	roomID, threadRoot, _ := strings.Cut(strings.TrimSpace(chatID), "/")
+	return id.RoomID(roomID), id.EventID(threadRoot)
+}
+
+// setThread marks content as part of the thread rooted at threadRoot.
+func setThread(content *event.MessageEventContent, threadRoot id.EventID) {
+	if threadRoot == "" {
+		return
+	}
+	content.RelatesTo = (&event.RelatesTo{}).SetThread(threadRoot, threadRoot)
+}
+
 func (c *MatrixChannel) extractInboundContent(
 	ctx context.Context,
 	msgEvt *event.MessageEventContent,
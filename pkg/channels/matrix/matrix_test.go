@@ -386,3 +386,28 @@ func TestMessageContent(t *testing.T) {
 		t.Errorf("plain: expected no formatting, got format=%q formattedBody=%q", mc.Format, mc.FormattedBody)
 	}
 }
+
+func TestParseMatrixChatID(t *testing.T) {
+	roomID, threadRoot := parseMatrixChatID("!room:example.org")
+	if roomID != "!room:example.org" || threadRoot != "" {
+		t.Fatalf("plain room = %q, %q", roomID, threadRoot)
+	}
+
+	roomID, threadRoot = parseMatrixChatID("!room:example.org/$root/with+slash")
+	if roomID != "!room:example.org" || threadRoot != "$root/with+slash" {
+		t.Fatalf("threaded room = %q, %q", roomID, threadRoot)
+	}
+}
+
+func TestSetThread(t *testing.T) {
+	mc := &event.MessageEventContent{MsgType: event.MsgText, Body: "hi"}
+	setThread(mc, "")
+	if mc.RelatesTo != nil {
+		t.Fatal("expected no relation without thread root")
+	}
+
+	setThread(mc, "$root")
+	if got := mc.RelatesTo.GetThreadParent(); got != "$root" {
+		t.Errorf("thread parent = %q, want $root", got)
+	}
+}
@@ -0,0 +1,497 @@
+package mqtt
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/google/uuid"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/channels"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/identity"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/media"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+const (
+	defaultResponseTopic = "picoclaw/{chat_id}/out"
+	defaultMaxMediaSize  = 5 << 20 // 5 MiB
+	connectTimeout       = 15 * time.Second
+	publishTimeout       = 10 * time.Second
+)
+
+// mqttInbound is the JSON payload accepted on subscribed topics.
+// Plain (non-JSON) payloads are treated as the message content.
+type mqttInbound struct {
+	Content   string        `json:"content"`
+	Sender    string        `json:"sender,omitempty"`
+	MessageID string        `json:"message_id,omitempty"`
+	Media     []mqttPayload `json:"media,omitempty"`
+}
+
+// mqttOutbound is the JSON payload published to the response topic.
+type mqttOutbound struct {
+	ChatID    string        `json:"chat_id"`
+	Content   string        `json:"content,omitempty"`
+	ReplyToID string        `json:"reply_to,omitempty"`
+	Media     []mqttPayload `json:"media,omitempty"`
+}
+
+// mqttPayload carries a media attachment as base64 data.
+type mqttPayload struct {
+	Type        string `json:"type,omitempty"` // "image" | "audio" | "video" | "file"
+	Filename    string `json:"filename,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Caption     string `json:"caption,omitempty"`
+	Data        string `json:"data"` // base64 (standard encoding)
+}
+
+// MQTTChannel implements the Channel interface for MQTT brokers, so IoT
+// devices can talk to the agent over a standard messaging transport.
+type MQTTChannel struct {
+	*channels.BaseChannel
+	config config.MQTTConfig
+	client paho.Client
+	mu     sync.Mutex
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewMQTTChannel creates a new MQTT channel instance.
+func NewMQTTChannel(cfg config.MQTTConfig, messageBus *bus.MessageBus) (*MQTTChannel, error) {
+	if cfg.Broker == "" {
+		return nil, fmt.Errorf("mqtt broker is required")
+	}
+	if cfg.QoS < 0 || cfg.QoS > 1 {
+		return nil, fmt.Errorf("mqtt qos must be 0 or 1, got %d", cfg.QoS)
+	}
+	if len(cfg.SubscribeTopics) == 0 {
+		return nil, fmt.Errorf("mqtt subscribe_topics must not be empty")
+	}
+
+	base := channels.NewBaseChannel("mqtt", cfg, messageBus, cfg.AllowFrom,
+		channels.WithReasoningChannelID(cfg.ReasoningChannelID),
+	)
+
+	return &MQTTChannel{
+		BaseChannel: base,
+		config:      cfg,
+	}, nil
+}
+
+// Start connects to the broker and subscribes to the configured topics.
+// Subscriptions are re-established automatically after a reconnect.
+func (c *MQTTChannel) Start(ctx context.Context) error {
+	logger.InfoCF("mqtt", "Starting MQTT channel", map[string]any{
+		"broker": c.config.Broker,
+	})
+
+	opts, err := c.clientOptions()
+	if err != nil {
+		return err
+	}
+
+	c.ctx, c.cancel = context.WithCancel(ctx)
+
+	client := paho.NewClient(opts)
+	token := client.Connect()
+	if !token.WaitTimeout(connectTimeout) {
+		c.cancel()
+		return fmt.Errorf("mqtt connect to %s timed out", c.config.Broker)
+	}
+	if err := token.Error(); err != nil {
+		c.cancel()
+		return fmt.Errorf("mqtt connect to %s: %w", c.config.Broker, err)
+	}
+
+	c.mu.Lock()
+	c.client = client
+	c.mu.Unlock()
+
+	c.SetRunning(true)
+	logger.InfoCF("mqtt", "MQTT channel started", map[string]any{
+		"topics": []string(c.config.SubscribeTopics),
+	})
+	return nil
+}
+
+// Stop disconnects from the broker.
+func (c *MQTTChannel) Stop(ctx context.Context) error {
+	logger.InfoC("mqtt", "Stopping MQTT channel")
+
+	if c.cancel != nil {
+		c.cancel()
+	}
+
+	c.mu.Lock()
+	client := c.client
+	c.client = nil
+	c.mu.Unlock()
+	if client != nil {
+		client.Disconnect(250)
+	}
+
+	c.SetRunning(false)
+	logger.InfoC("mqtt", "MQTT channel stopped")
+	return nil
+}
+
+func (c *MQTTChannel) clientOptions() (*paho.ClientOptions, error) {
+	// The broker keeps a persistent session per client ID, so only a
+	// configured, stable ID asks for one; a generated ID would leave an
+	// orphaned session behind on every restart.
+	clientID := c.config.ClientID
+	cleanSession := false
+	if clientID == "" {
+		clientID = "picoclaw-" + uuid.NewString()[:8]
+		cleanSession = true
+	}
+
+	opts := paho.NewClientOptions().
+		AddBroker(c.config.Broker).
+		SetClientID(clientID).
+		SetCleanSession(cleanSession).
+		SetAutoReconnect(true).
+		SetConnectRetry(false).
+		SetConnectTimeout(connectTimeout).
+		SetOnConnectHandler(c.onConnect).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			logger.WarnCF("mqtt", "Connection lost, reconnecting", map[string]any{
+				"error": err.Error(),
+			})
+		})
+	if c.config.Username != "" {
+		opts.SetUsername(c.config.Username)
+		opts.SetPassword(c.config.Password)
+	}
+
+	if c.useTLS() {
+		tlsCfg, err := buildTLSConfig(c.config.TLS)
+		if err != nil {
+			return nil, err
+		}
+		opts.SetTLSConfig(tlsCfg)
+	}
+	return opts, nil
+}
+
+func (c *MQTTChannel) useTLS() bool {
+	if c.config.TLS.Enabled {
+		return true
+	}
+	broker := strings.ToLower(c.config.Broker)
+	for _, scheme := range []string{"ssl://", "tls://", "mqtts://", "wss://"} {
+		if strings.HasPrefix(broker, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+func buildTLSConfig(cfg config.MQTTTLSConfig) (*tls.Config, error) {
+	tlsCfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.InsecureSkipVerify, //nolint:gosec // explicit opt-in for self-signed brokers
+	}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read mqtt ca_file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("mqtt ca_file contains no valid certificates")
+		}
+		tlsCfg.RootCAs = pool
+	}
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load mqtt client certificate: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+	return tlsCfg, nil
+}
+
+// onConnect (re)subscribes to all configured topics. It runs on the initial
+// connection and after every automatic reconnect.
+func (c *MQTTChannel) onConnect(client paho.Client) {
+	qos := byte(c.config.QoS)
+	for _, topic := range c.config.SubscribeTopics {
+		token := client.Subscribe(topic, qos, c.handleMessage)
+		if token.WaitTimeout(connectTimeout) && token.Error() == nil {
+			logger.DebugCF("mqtt", "Subscribed", map[string]any{"topic": topic})
+			continue
+		}
+		errMsg := "timeout"
+		if token.Error() != nil {
+			errMsg = token.Error().Error()
+		}
+		logger.ErrorCF("mqtt", "Failed to subscribe", map[string]any{
+			"topic": topic,
+			"error": errMsg,
+		})
+	}
+}
+
+// handleMessage converts an MQTT publish into an InboundMessage.
+func (c *MQTTChannel) handleMessage(_ paho.Client, m paho.Message) {
+	chatID := topicSegment(m.Topic(), c.config.ChatIDSegment)
+	if chatID == "" {
+		logger.WarnCF("mqtt", "Cannot derive chat ID from topic", map[string]any{
+			"topic":   m.Topic(),
+			"segment": c.config.ChatIDSegment,
+		})
+		return
+	}
+
+	in := parseInbound(m.Payload())
+	senderID := in.Sender
+	if senderID == "" {
+		senderID = chatID
+	}
+
+	sender := bus.SenderInfo{
+		Platform:    "mqtt",
+		PlatformID:  senderID,
+		CanonicalID: identity.BuildCanonicalID("mqtt", senderID),
+	}
+	if !c.IsAllowedSender(sender) {
+		return
+	}
+
+	messageID := in.MessageID
+	if messageID == "" && m.MessageID() != 0 {
+		messageID = fmt.Sprintf("%d", m.MessageID())
+	}
+	scope := channels.BuildMediaScope("mqtt", chatID, messageID)
+
+	content := in.Content
+	var mediaRefs []string
+	for _, part := range in.Media {
+		ref, tag := c.storeMedia(part, scope)
+		if ref == "" {
+			continue
+		}
+		mediaRefs = append(mediaRefs, ref)
+		if content != "" {
+			content += "\n"
+		}
+		content += tag
+	}
+	if strings.TrimSpace(content) == "" && len(mediaRefs) == 0 {
+		return
+	}
+
+	logger.DebugCF("mqtt", "Received message", map[string]any{
+		"topic":     m.Topic(),
+		"chat_id":   chatID,
+		"sender_id": senderID,
+		"preview":   utils.Truncate(content, 50),
+	})
+
+	metadata := map[string]string{
+		"platform": "mqtt",
+		"topic":    m.Topic(),
+	}
+
+	c.HandleMessage(c.ctx, bus.Peer{Kind: "direct", ID: chatID}, messageID, senderID, chatID,
+		content, mediaRefs, metadata, sender)
+}
+
+// storeMedia decodes a base64 attachment into the media temp directory and
+// registers it with the MediaStore. It returns the ref (or local path when
+// no store is configured) and an annotation tag for the message content.
+func (c *MQTTChannel) storeMedia(part mqttPayload, scope string) (string, string) {
+	data, err := base64.StdEncoding.DecodeString(part.Data)
+	if err != nil {
+		logger.WarnCF("mqtt", "Invalid base64 media payload", map[string]any{
+			"error": err.Error(),
+		})
+		return "", ""
+	}
+	maxSize := c.config.MaxMediaSize
+	if maxSize <= 0 {
+		maxSize = defaultMaxMediaSize
+	}
+	if len(data) > maxSize {
+		logger.WarnCF("mqtt", "Media payload too large, dropped", map[string]any{
+			"size":     len(data),
+			"max_size": maxSize,
+		})
+		return "", ""
+	}
+
+	mediaDir := media.TempDir()
+	if err := os.MkdirAll(mediaDir, 0o700); err != nil {
+		logger.ErrorCF("mqtt", "Failed to create media directory", map[string]any{
+			"error": err.Error(),
+		})
+		return "", ""
+	}
+	filename := utils.SanitizeFilename(part.Filename)
+	if filename == "" || filename == "." {
+		filename = "payload.bin"
+	}
+	localPath := filepath.Join(mediaDir, uuid.NewString()[:8]+"_"+filename)
+	if err := os.WriteFile(localPath, data, 0o600); err != nil {
+		logger.ErrorCF("mqtt", "Failed to write media payload", map[string]any{
+			"error": err.Error(),
+		})
+		return "", ""
+	}
+
+	kind := part.Type
+	if kind == "" {
+		kind = "file"
+	}
+	tag := fmt.Sprintf("[%s: %s]", kind, filename)
+
+	if store := c.GetMediaStore(); store != nil {
+		ref, err := store.Store(localPath, media.MediaMeta{
+			Filename:    filename,
+			ContentType: part.ContentType,
+			Source:      "mqtt",
+		}, scope)
+		if err == nil {
+			return ref, tag
+		}
+	}
+	return localPath, tag
+}
+
+// Send publishes a text reply to the response topic for msg.ChatID.
+func (c *MQTTChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	if !c.IsRunning() {
+		return channels.ErrNotRunning
+	}
+	return c.publish(ctx, msg.ChatID, mqttOutbound{
+		ChatID:    msg.ChatID,
+		Content:   msg.Content,
+		ReplyToID: msg.ReplyToMessageID,
+	})
+}
+
+// SendMedia implements the channels.MediaSender interface. Attachments are
+// read from the MediaStore and published inline as base64 payloads.
+func (c *MQTTChannel) SendMedia(ctx context.Context, msg bus.OutboundMediaMessage) error {
+	if !c.IsRunning() {
+		return channels.ErrNotRunning
+	}
+
+	store := c.GetMediaStore()
+	if store == nil {
+		return fmt.Errorf("no media store available: %w", channels.ErrSendFailed)
+	}
+
+	out := mqttOutbound{ChatID: msg.ChatID}
+	for _, part := range msg.Parts {
+		localPath, meta, err := store.ResolveWithMeta(part.Ref)
+		if err != nil {
+			logger.ErrorCF("mqtt", "Failed to resolve media ref", map[string]any{
+				"ref":   part.Ref,
+				"error": err.Error(),
+			})
+			continue
+		}
+		data, err := os.ReadFile(localPath)
+		if err != nil {
+			logger.ErrorCF("mqtt", "Failed to read media file", map[string]any{
+				"path":  localPath,
+				"error": err.Error(),
+			})
+			continue
+		}
+
+		filename := part.Filename
+		if filename == "" {
+			filename = meta.Filename
+		}
+		contentType := part.ContentType
+		if contentType == "" {
+			contentType = meta.ContentType
+		}
+		out.Media = append(out.Media, mqttPayload{
+			Type:        part.Type,
+			Filename:    filename,
+			ContentType: contentType,
+			Caption:     part.Caption,
+			Data:        base64.StdEncoding.EncodeToString(data),
+		})
+	}
+	if len(out.Media) == 0 {
+		return fmt.Errorf("no media could be resolved: %w", channels.ErrSendFailed)
+	}
+
+	return c.publish(ctx, msg.ChatID, out)
+}
+
+func (c *MQTTChannel) publish(ctx context.Context, chatID string, out mqttOutbound) error {
+	c.mu.Lock()
+	client := c.client
+	c.mu.Unlock()
+	if client == nil {
+		return channels.ErrNotRunning
+	}
+
+	payload, err := json.Marshal(out)
+	if err != nil {
+		return fmt.Errorf("%w: %v", channels.ErrSendFailed, err)
+	}
+
+	topic := c.responseTopic(chatID)
+	token := client.Publish(topic, byte(c.config.QoS), false, payload)
+
+	select {
+	case <-token.Done():
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(publishTimeout):
+		return fmt.Errorf("%w: publish to %s timed out", channels.ErrTemporary, topic)
+	}
+	if err := token.Error(); err != nil {
+		return channels.ClassifyNetError(err)
+	}
+	return nil
+}
+
+func (c *MQTTChannel) responseTopic(chatID string) string {
+	tmpl := c.config.ResponseTopic
+	if tmpl == "" {
+		tmpl = defaultResponseTopic
+	}
+	return strings.ReplaceAll(tmpl, "{chat_id}", chatID)
+}
+
+// topicSegment returns the topic level at index (0-based), or "" if out of range.
+func topicSegment(topic string, index int) string {
+	parts := strings.Split(topic, "/")
+	if index < 0 || index >= len(parts) {
+		return ""
+	}
+	return parts[index]
+}
+
+// parseInbound decodes a JSON payload, falling back to treating the raw
+// bytes as plain-text content.
+func parseInbound(payload []byte) mqttInbound {
+	var in mqttInbound
+	trimmed := strings.TrimSpace(string(payload))
+	if strings.HasPrefix(trimmed, "{") && json.Unmarshal(payload, &in) == nil {
+		return in
+	}
+	return mqttInbound{Content: trimmed}
+}
@@ -0,0 +1,263 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	mochi "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/mochi-mqtt/server/v2/packets"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/media"
+)
+
+// startBroker runs an in-process MQTT broker that only accepts the
+// "device"/"secret" credentials and returns its tcp:// address.
+func startBroker(t *testing.T) (*mochi.Server, string) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	server := mochi.New(&mochi.Options{InlineClient: true})
+	err = server.AddHook(new(auth.Hook), &auth.Options{
+		Ledger: &auth.Ledger{
+			Auth: auth.AuthRules{
+				{Username: "device", Password: "secret", Allow: true},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := server.AddListener(listeners.NewTCP(listeners.Config{ID: "t1", Address: addr})); err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve()
+	t.Cleanup(func() { server.Close() })
+
+	return server, "tcp://" + addr
+}
+
+func newTestChannel(t *testing.T, broker string, store media.MediaStore) (*MQTTChannel, *bus.MessageBus) {
+	t.Helper()
+	mb := bus.NewMessageBus()
+	ch, err := NewMQTTChannel(config.MQTTConfig{
+		Broker:          broker,
+		ClientID:        "picoclaw-test",
+		Username:        "device",
+		Password:        "secret",
+		QoS:             1,
+		SubscribeTopics: config.FlexibleStringSlice{"devices/+/in"},
+		ChatIDSegment:   1,
+		ResponseTopic:   "devices/{chat_id}/out",
+	}, mb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store != nil {
+		ch.SetMediaStore(store)
+	}
+	if err := ch.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { ch.Stop(context.Background()) })
+	return ch, mb
+}
+
+func waitInbound(t *testing.T, mb *bus.MessageBus) bus.InboundMessage {
+	t.Helper()
+	select {
+	case msg := <-mb.InboundChan():
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for inbound message")
+		return bus.InboundMessage{}
+	}
+}
+
+// publishUntilReceived retries publishing until the channel's subscription
+// is active, since subscribing happens asynchronously after connect.
+func publishUntilReceived(t *testing.T, server *mochi.Server, mb *bus.MessageBus, topic string, payload []byte) bus.InboundMessage {
+	t.Helper()
+	deadline := time.After(5 * time.Second)
+	for {
+		if err := server.Publish(topic, payload, false, 1); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case msg := <-mb.InboundChan():
+			return msg
+		case <-time.After(100 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("timed out waiting for inbound message")
+		}
+	}
+}
+
+func TestTopicSegment(t *testing.T) {
+	if got := topicSegment("devices/cam01/in", 1); got != "cam01" {
+		t.Errorf("topicSegment = %q, want cam01", got)
+	}
+	if got := topicSegment("devices/cam01/in", 5); got != "" {
+		t.Errorf("topicSegment out of range = %q, want empty", got)
+	}
+}
+
+func TestParseInbound(t *testing.T) {
+	if in := parseInbound([]byte("  turn on the light ")); in.Content != "turn on the light" {
+		t.Errorf("plain payload content = %q", in.Content)
+	}
+	in := parseInbound([]byte(`{"content":"hi","sender":"sensor-1"}`))
+	if in.Content != "hi" || in.Sender != "sensor-1" {
+		t.Errorf("json payload = %+v", in)
+	}
+}
+
+func TestNewMQTTChannelValidatesConfig(t *testing.T) {
+	mb := bus.NewMessageBus()
+	if _, err := NewMQTTChannel(config.MQTTConfig{}, mb); err == nil {
+		t.Error("expected error for missing broker")
+	}
+	if _, err := NewMQTTChannel(config.MQTTConfig{
+		Broker: "tcp://x:1883", QoS: 2, SubscribeTopics: config.FlexibleStringSlice{"a/+"},
+	}, mb); err == nil {
+		t.Error("expected error for unsupported qos")
+	}
+}
+
+func TestStartRejectsBadCredentials(t *testing.T) {
+	_, broker := startBroker(t)
+	ch, err := NewMQTTChannel(config.MQTTConfig{
+		Broker:          broker,
+		Username:        "device",
+		Password:        "wrong",
+		SubscribeTopics: config.FlexibleStringSlice{"devices/+/in"},
+	}, bus.NewMessageBus())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ch.Start(context.Background()); err == nil {
+		ch.Stop(context.Background())
+		t.Fatal("expected connect error with bad credentials")
+	}
+}
+
+func TestInboundAndReply(t *testing.T) {
+	server, broker := startBroker(t)
+	ch, mb := newTestChannel(t, broker, nil)
+
+	replies := make(chan []byte, 1)
+	err := server.Subscribe("devices/+/out", 1, func(_ *mochi.Client, _ packets.Subscription, pk packets.Packet) {
+		replies <- pk.Payload
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := publishUntilReceived(t, server, mb, "devices/cam01/in", []byte(`{"content":"status?","sender":"cam01"}`))
+	if msg.ChatID != "cam01" || msg.Content != "status?" || msg.Sender.CanonicalID != "mqtt:cam01" {
+		t.Fatalf("unexpected inbound: %+v", msg)
+	}
+
+	if err := ch.Send(context.Background(), bus.OutboundMessage{ChatID: "cam01", Content: "all good"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	select {
+	case payload := <-replies:
+		var out mqttOutbound
+		if err := json.Unmarshal(payload, &out); err != nil {
+			t.Fatal(err)
+		}
+		if out.ChatID != "cam01" || out.Content != "all good" {
+			t.Errorf("unexpected reply: %+v", out)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for reply")
+	}
+}
+
+func TestInboundMediaStoredInMediaStore(t *testing.T) {
+	server, broker := startBroker(t)
+	store := media.NewFileMediaStore()
+	_, mb := newTestChannel(t, broker, store)
+
+	payload, _ := json.Marshal(mqttInbound{
+		Content: "what is this?",
+		Media: []mqttPayload{{
+			Type:        "image",
+			Filename:    "snap.jpg",
+			ContentType: "image/jpeg",
+			Data:        base64.StdEncoding.EncodeToString([]byte("fake-jpeg")),
+		}},
+	})
+	msg := publishUntilReceived(t, server, mb, "devices/cam02/in", payload)
+	if len(msg.Media) != 1 {
+		t.Fatalf("expected 1 media ref, got %v", msg.Media)
+	}
+
+	path, meta, err := store.ResolveWithMeta(msg.Media[0])
+	if err != nil {
+		t.Fatalf("ResolveWithMeta: %v", err)
+	}
+	defer os.Remove(path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "fake-jpeg" || meta.ContentType != "image/jpeg" {
+		t.Errorf("unexpected stored media: %q %+v", data, meta)
+	}
+}
+
+func TestClientOptions_GeneratesClientID(t *testing.T) {
+	ids := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		ch, err := NewMQTTChannel(config.DefaultConfig().Channels.MQTT, bus.NewMessageBus())
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts, err := ch.clientOptions()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(opts.ClientID, "picoclaw-") {
+			t.Errorf("ClientID = %q, want a generated picoclaw-<random> ID", opts.ClientID)
+		}
+		if !opts.CleanSession {
+			t.Error("a generated client ID must not ask for a persistent session")
+		}
+		ids[opts.ClientID] = true
+	}
+	if len(ids) != 2 {
+		t.Error("instances with the default config share a client ID")
+	}
+
+	cfg := config.DefaultConfig().Channels.MQTT
+	cfg.ClientID = "board-1"
+	ch, err := NewMQTTChannel(cfg, bus.NewMessageBus())
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts, err := ch.clientOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.ClientID != "board-1" || opts.CleanSession {
+		t.Errorf("configured client ID: got %q (clean session %v), want a persistent session for board-1",
+			opts.ClientID, opts.CleanSession)
+	}
+}
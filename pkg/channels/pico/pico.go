@@ -20,13 +20,23 @@ import (
 	"github.com/sipeed/picoclaw/pkg/logger"
 )
 
+// Headers the web launcher's WebSocket proxy sets to say which launcher
+// account a connection belongs to. They are only read from connections that
+// presented the channel token, which the launcher keeps from viewers.
+const (
+	WebUserHeader = "X-Picoclaw-Web-User"
+	WebRoleHeader = "X-Picoclaw-Web-Role"
+)
+
 // picoConn represents a single WebSocket connection.
 type picoConn struct {
 	id        string
 	conn      *websocket.Conn
 	sessionID string
-	writeMu   sync.Mutex
-	closed    atomic.Bool
+	// senderID identifies who sends on this connection.
+	senderID string
+	writeMu  sync.Mutex
+	closed   atomic.Bool
 }
 
 // writeJSON sends a JSON message to the connection with write locking.
@@ -275,6 +285,7 @@ func (c *PicoChannel) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 		id:        uuid.New().String(),
 		conn:      conn,
 		sessionID: sessionID,
+		senderID:  webSenderID(r),
 	}
 
 	c.connections.Store(pc.id, pc)
@@ -333,6 +344,18 @@ func (c *PicoChannel) matchedSubprotocol(r *http.Request) string {
 	return ""
 }
 
+// webSenderID returns the sender ID for a connection. Launcher viewers chat
+// as "viewer:<username>" (canonical "pico:viewer:<username>") so
+// permissions can give them a restricted role; everyone else is "pico-user".
+func webSenderID(r *http.Request) string {
+	if r.Header.Get(WebRoleHeader) == "viewer" {
+		if user := strings.TrimSpace(r.Header.Get(WebUserHeader)); user != "" {
+			return "viewer:" + user
+		}
+	}
+	return "pico-user"
+}
+
 // readLoop reads messages from a WebSocket connection.
 func (c *PicoChannel) readLoop(pc *picoConn) {
 	defer func() {
@@ -449,7 +472,7 @@ func (c *PicoChannel) handleMessageSend(pc *picoConn, msg PicoMessage) {
 	}
 
 	chatID := "pico:" + sessionID
-	senderID := "pico-user"
+	senderID := pc.senderID
 
 	peer := bus.Peer{Kind: "direct", ID: "pico:" + sessionID}
 
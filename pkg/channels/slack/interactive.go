@@ -0,0 +1,213 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/channels"
+	"github.com/sipeed/picoclaw/pkg/identity"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+const (
+	// Slack Block Kit limits: 25 elements per actions block, 255 characters
+	// of action_id and 75 characters of button text.
+	maxActionElements = 25
+	maxActionIDLen    = 255
+	maxButtonTextLen  = 75
+
+	defaultInteractivePrompt = "Please choose:"
+)
+
+// SendInteractive implements channels.InteractiveCapable.
+// Choices are rendered as Block Kit buttons below the message text.
+func (c *SlackChannel) SendInteractive(ctx context.Context, msg bus.OutboundMessage) error {
+	if !c.IsRunning() {
+		return channels.ErrNotRunning
+	}
+
+	channelID, threadTS := parseSlackChatID(msg.ChatID)
+	if channelID == "" {
+		return fmt.Errorf("invalid slack chat ID: %s", msg.ChatID)
+	}
+	if threadTS == "" {
+		threadTS = msg.ThreadID
+	}
+
+	actions := buildActionBlock(msg.Interactive)
+	if actions == nil {
+		return c.Send(ctx, bus.OutboundMessage{
+			Channel: msg.Channel,
+			ChatID:  msg.ChatID,
+			Content: channels.RenderChoicesAsText(msg.Content, msg.Interactive.Choices),
+		})
+	}
+
+	var blocks []slack.Block
+	if msg.Content != "" {
+		blocks = append(blocks, slack.NewSectionBlock(
+			slack.NewTextBlockObject(slack.MarkdownType, msg.Content, false, false), nil, nil))
+	}
+	blocks = append(blocks, actions)
+
+	// Text is the notification fallback for clients that cannot render blocks.
+	text := msg.Content
+	if text == "" {
+		text = defaultInteractivePrompt
+	}
+	opts := []slack.MsgOption{
+		slack.MsgOptionText(text, false),
+		slack.MsgOptionBlocks(blocks...),
+	}
+	if threadTS != "" {
+		opts = append(opts, slack.MsgOptionTS(threadTS))
+	}
+
+	if _, _, err := c.api.PostMessageContext(ctx, channelID, opts...); err != nil {
+		return fmt.Errorf("slack send: %w", channels.ErrTemporary)
+	}
+	return nil
+}
+
+// buildActionBlock converts choices into an actions block. Choices that do not
+// fit Slack's limits are skipped; nil is returned when no button could be built.
+func buildActionBlock(in *bus.Interactive) *slack.ActionBlock {
+	if in == nil {
+		return nil
+	}
+
+	var elements []slack.BlockElement
+	for _, choice := range in.Choices {
+		actionID := channels.EncodeInteractionData(in.ActionID, choice.ID)
+		if len(actionID) > maxActionIDLen || len(elements) == maxActionElements {
+			logger.WarnCF("slack", "Choice does not fit in actions block, skipped", map[string]any{
+				"action_id": in.ActionID,
+				"choice_id": choice.ID,
+			})
+			continue
+		}
+		button := slack.NewButtonBlockElement(actionID, choice.ID,
+			slack.NewTextBlockObject(slack.PlainTextType, truncateButtonText(choice.Label), false, false))
+		switch choice.Style {
+		case "primary":
+			button.WithStyle(slack.StylePrimary)
+		case "danger":
+			button.WithStyle(slack.StyleDanger)
+		}
+		elements = append(elements, button)
+	}
+	if len(elements) == 0 {
+		return nil
+	}
+	return slack.NewActionBlock(in.ActionID, elements...)
+}
+
+func truncateButtonText(text string) string {
+	runes := []rune(text)
+	if len(runes) <= maxButtonTextLen {
+		return text
+	}
+	return string(runes[:maxButtonTextLen-1]) + "…"
+}
+
+// handleInteractive turns a Block Kit button click into an InboundMessage.
+// The buttons are replaced by the selected choice so a prompt is answered once.
+func (c *SlackChannel) handleInteractive(event socketmode.Event) {
+	if event.Request != nil {
+		c.socketClient.Ack(*event.Request)
+	}
+
+	callback, ok := event.Data.(slack.InteractionCallback)
+	if !ok || callback.Type != slack.InteractionTypeBlockActions {
+		return
+	}
+
+	var actionID, choiceID, label string
+	for _, action := range callback.ActionCallback.BlockActions {
+		if a, ch, ok := channels.DecodeInteractionData(action.ActionID); ok {
+			actionID, choiceID, label = a, ch, action.Text.Text
+			break
+		}
+	}
+	if actionID == "" {
+		return
+	}
+
+	senderID := callback.User.ID
+	sender := bus.SenderInfo{
+		Platform:    "slack",
+		PlatformID:  senderID,
+		CanonicalID: identity.BuildCanonicalID("slack", senderID),
+		Username:    callback.User.Name,
+	}
+	if !c.IsAllowedSender(sender) {
+		logger.DebugCF("slack", "Interaction rejected by allowlist", map[string]any{
+			"user_id": senderID,
+		})
+		return
+	}
+
+	channelID := callback.Channel.ID
+	if channelID == "" {
+		channelID = callback.Container.ChannelID
+	}
+	threadTS := callback.Container.ThreadTs
+	messageTS := callback.Container.MessageTs
+
+	c.resolveInteractiveMessage(channelID, messageTS, callback.Message.Text, label)
+
+	chatID := channelID
+	if threadTS != "" {
+		chatID = channelID + "/" + threadTS
+	}
+
+	peer := bus.Peer{Kind: "channel", ID: channelID}
+	if strings.HasPrefix(channelID, "D") {
+		peer = bus.Peer{Kind: "direct", ID: senderID}
+	}
+
+	metadata := map[string]string{
+		"message_ts": messageTS,
+		"channel_id": channelID,
+		"thread_ts":  threadTS,
+		"thread_id":  threadTS,
+		"platform":   "slack",
+		"team_id":    c.teamID,
+	}
+
+	c.HandleInteraction(c.ctx, peer, callback.ActionTs, senderID, chatID,
+		actionID, choiceID, label, metadata, sender)
+}
+
+// resolveInteractiveMessage rewrites an answered prompt without its buttons.
+func (c *SlackChannel) resolveInteractiveMessage(channelID, messageTS, text, label string) {
+	if channelID == "" || messageTS == "" {
+		return
+	}
+
+	blocks := []slack.Block{}
+	if text != "" {
+		blocks = append(blocks, slack.NewSectionBlock(
+			slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil))
+	}
+	if label != "" {
+		blocks = append(blocks, slack.NewContextBlock("",
+			slack.NewTextBlockObject(slack.PlainTextType, "Selected: "+label, false, false)))
+	}
+	if len(blocks) == 0 {
+		return
+	}
+
+	_, _, _, err := c.api.UpdateMessageContext(c.ctx, channelID, messageTS,
+		slack.MsgOptionText(text, false), slack.MsgOptionBlocks(blocks...))
+	if err != nil {
+		logger.DebugCF("slack", "Failed to remove interactive buttons", map[string]any{
+			"error": err.Error(),
+		})
+	}
+}
@@ -117,6 +117,9 @@ func (c *SlackChannel) Send(ctx context.Context, msg bus.OutboundMessage) error
 	if channelID == "" {
 		return fmt.Errorf("invalid slack chat ID: %s", msg.ChatID)
 	}
+	if threadTS == "" {
+		threadTS = msg.ThreadID
+	}
 
 	opts := []slack.MsgOption{
 		slack.MsgOptionText(msg.Content, false),
@@ -157,10 +160,13 @@ func (c *SlackChannel) SendMedia(ctx context.Context, msg bus.OutboundMediaMessa
 		return channels.ErrNotRunning
 	}
 
-	channelID, _ := parseSlackChatID(msg.ChatID)
+	channelID, threadTS := parseSlackChatID(msg.ChatID)
 	if channelID == "" {
 		return fmt.Errorf("invalid slack chat ID: %s", msg.ChatID)
 	}
+	if threadTS == "" {
+		threadTS = msg.ThreadID
+	}
 
 	store := c.GetMediaStore()
 	if store == nil {
@@ -188,10 +194,11 @@ func (c *SlackChannel) SendMedia(ctx context.Context, msg bus.OutboundMediaMessa
 		}
 
 		_, err = c.api.UploadFileV2Context(ctx, slack.UploadFileV2Parameters{
-			Channel:  channelID,
-			File:     localPath,
-			Filename: filename,
-			Title:    title,
+			Channel:         channelID,
+			ThreadTimestamp: threadTS,
+			File:            localPath,
+			Filename:        filename,
+			Title:           title,
 		})
 		if err != nil {
 			logger.ErrorCF("slack", "Failed to upload media", map[string]any{
@@ -242,9 +249,7 @@ func (c *SlackChannel) eventLoop() {
 			case socketmode.EventTypeSlashCommand:
 				c.handleSlashCommand(event)
 			case socketmode.EventTypeInteractive:
-				if event.Request != nil {
-					c.socketClient.Ack(*event.Request)
-				}
+				c.handleInteractive(event)
 			}
 		}
 	}
@@ -365,6 +370,7 @@ func (c *SlackChannel) handleMessageEvent(ev *slackevents.MessageEvent) {
 		"message_ts": messageTS,
 		"channel_id": channelID,
 		"thread_ts":  threadTS,
+		"thread_id":  threadTS,
 		"platform":   "slack",
 		"team_id":    c.teamID,
 	}
@@ -405,12 +411,11 @@ func (c *SlackChannel) handleAppMention(ev *slackevents.AppMentionEvent) {
 	threadTS := ev.ThreadTimeStamp
 	messageTS := ev.TimeStamp
 
-	var chatID string
-	if threadTS != "" {
-		chatID = channelID + "/" + threadTS
-	} else {
-		chatID = channelID + "/" + messageTS
+	// Mentions outside a thread start a new thread rooted at the mention.
+	if threadTS == "" {
+		threadTS = messageTS
 	}
+	chatID := channelID + "/" + threadTS
 
 	c.pendingAcks.Store(chatID, slackMessageRef{
 		ChannelID: channelID,
@@ -436,6 +441,7 @@ func (c *SlackChannel) handleAppMention(ev *slackevents.AppMentionEvent) {
 		"message_ts": messageTS,
 		"channel_id": channelID,
 		"thread_ts":  threadTS,
+		"thread_id":  threadTS,
 		"platform":   "slack",
 		"is_mention": "true",
 		"team_id":    c.teamID,
@@ -0,0 +1,176 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mymmrac/telego"
+	tu "github.com/mymmrac/telego/telegoutil"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/channels"
+	"github.com/sipeed/picoclaw/pkg/identity"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// maxCallbackDataLen is Telegram's limit for inline button callback_data.
+const maxCallbackDataLen = 64
+
+// defaultInteractivePrompt is used when an interactive message has no text,
+// since Telegram rejects empty messages.
+const defaultInteractivePrompt = "Please choose:"
+
+// SendInteractive implements channels.InteractiveCapable.
+// Choices are rendered as an inline keyboard with one button per row.
+func (c *TelegramChannel) SendInteractive(ctx context.Context, msg bus.OutboundMessage) error {
+	if !c.IsRunning() {
+		return channels.ErrNotRunning
+	}
+
+	chatID, threadID, err := parseOutboundTarget(msg.ChatID, msg.ThreadID)
+	if err != nil {
+		return fmt.Errorf("invalid chat ID %s: %w", msg.ChatID, channels.ErrSendFailed)
+	}
+
+	keyboard := buildInlineKeyboard(msg.Interactive)
+	if keyboard == nil {
+		return c.Send(ctx, bus.OutboundMessage{
+			Channel: msg.Channel,
+			ChatID:  msg.ChatID,
+			Content: channels.RenderChoicesAsText(msg.Content, msg.Interactive.Choices),
+		})
+	}
+
+	content := msg.Content
+	if content == "" {
+		content = defaultInteractivePrompt
+	}
+
+	tgMsg := tu.Message(tu.ID(chatID), markdownToTelegramHTML(content))
+	tgMsg.ParseMode = telego.ModeHTML
+	tgMsg.MessageThreadID = threadID
+	tgMsg.ReplyMarkup = keyboard
+
+	if _, err := c.bot.SendMessage(ctx, tgMsg); err != nil {
+		logger.ErrorCF("telegram", "HTML parse failed, falling back to plain text", map[string]any{
+			"error": err.Error(),
+		})
+		tgMsg.Text = content
+		tgMsg.ParseMode = ""
+		if _, err = c.bot.SendMessage(ctx, tgMsg); err != nil {
+			return fmt.Errorf("telegram send: %w", channels.ErrTemporary)
+		}
+	}
+	return nil
+}
+
+// buildInlineKeyboard converts choices into an inline keyboard. Choices whose
+// encoded callback data exceeds Telegram's limit are skipped; nil is returned
+// when no button could be built.
+func buildInlineKeyboard(in *bus.Interactive) *telego.InlineKeyboardMarkup {
+	if in == nil {
+		return nil
+	}
+
+	var rows [][]telego.InlineKeyboardButton
+	for _, choice := range in.Choices {
+		data := channels.EncodeInteractionData(in.ActionID, choice.ID)
+		if len(data) > maxCallbackDataLen {
+			logger.WarnCF("telegram", "Choice callback data too long, skipped", map[string]any{
+				"action_id": in.ActionID,
+				"choice_id": choice.ID,
+			})
+			continue
+		}
+		rows = append(rows, tu.InlineKeyboardRow(
+			tu.InlineKeyboardButton(choice.Label).WithCallbackData(data),
+		))
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return tu.InlineKeyboard(rows...)
+}
+
+// handleCallbackQuery turns an inline keyboard click into an InboundMessage.
+// The keyboard is removed after the first click so a prompt is answered once.
+func (c *TelegramChannel) handleCallbackQuery(ctx context.Context, query telego.CallbackQuery) error {
+	actionID, choiceID, ok := channels.DecodeInteractionData(query.Data)
+	if !ok {
+		return nil
+	}
+
+	// Always answer so the client stops showing the loading spinner.
+	_ = c.bot.AnswerCallbackQuery(ctx, tu.CallbackQuery(query.ID))
+
+	platformID := fmt.Sprintf("%d", query.From.ID)
+	sender := bus.SenderInfo{
+		Platform:    "telegram",
+		PlatformID:  platformID,
+		CanonicalID: identity.BuildCanonicalID("telegram", platformID),
+		Username:    query.From.Username,
+		DisplayName: query.From.FirstName,
+	}
+	if !c.IsAllowedSender(sender) {
+		logger.DebugCF("telegram", "Callback rejected by allowlist", map[string]any{
+			"user_id": platformID,
+		})
+		return nil
+	}
+
+	if query.Message == nil || !query.Message.IsAccessible() {
+		return nil
+	}
+	message := query.Message.Message()
+	chat := message.Chat
+
+	label := choiceLabel(message.ReplyMarkup, query.Data)
+
+	if _, err := c.bot.EditMessageReplyMarkup(ctx,
+		tu.EditMessageReplyMarkup(tu.ID(chat.ID), message.MessageID, nil)); err != nil {
+		logger.DebugCF("telegram", "Failed to remove inline keyboard", map[string]any{
+			"error": err.Error(),
+		})
+	}
+
+	compositeChatID := fmt.Sprintf("%d", chat.ID)
+	if chat.IsForum && message.MessageThreadID != 0 {
+		compositeChatID = fmt.Sprintf("%d/%d", chat.ID, message.MessageThreadID)
+	}
+
+	peer := bus.Peer{Kind: "direct", ID: platformID}
+	if chat.Type != "private" {
+		peer = bus.Peer{Kind: "group", ID: compositeChatID}
+	}
+
+	metadata := map[string]string{
+		"user_id":    platformID,
+		"username":   query.From.Username,
+		"first_name": query.From.FirstName,
+		"is_group":   fmt.Sprintf("%t", chat.Type != "private"),
+	}
+	if chat.IsForum && message.MessageThreadID != 0 {
+		metadata["parent_peer_kind"] = "topic"
+		metadata["parent_peer_id"] = fmt.Sprintf("%d", message.MessageThreadID)
+		metadata["thread_id"] = fmt.Sprintf("%d", message.MessageThreadID)
+	}
+
+	c.HandleInteraction(c.ctx, peer, query.ID, platformID, compositeChatID,
+		actionID, choiceID, label, metadata, sender)
+	return nil
+}
+
+// choiceLabel finds the text of the button whose callback data matches data.
+func choiceLabel(markup *telego.InlineKeyboardMarkup, data string) string {
+	if markup == nil {
+		return ""
+	}
+	for _, row := range markup.InlineKeyboard {
+		for _, button := range row {
+			if button.CallbackData == data {
+				return button.Text
+			}
+		}
+	}
+	return ""
+}
@@ -126,6 +126,10 @@ func (c *TelegramChannel) Start(ctx context.Context) error {
 		return c.handleMessage(ctx, &message)
 	}, th.AnyMessage())
 
+	bh.HandleCallbackQuery(func(ctx *th.Context, query telego.CallbackQuery) error {
+		return c.handleCallbackQuery(ctx, query)
+	}, th.AnyCallbackQuery())
+
 	c.SetRunning(true)
 	logger.InfoCF("telegram", "Telegram bot connected", map[string]any{
 		"username": c.bot.Username(),
@@ -169,7 +173,7 @@ func (c *TelegramChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 		return channels.ErrNotRunning
 	}
 
-	chatID, threadID, err := parseTelegramChatID(msg.ChatID)
+	chatID, threadID, err := parseOutboundTarget(msg.ChatID, msg.ThreadID)
 	if err != nil {
 		return fmt.Errorf("invalid chat ID %s: %w", msg.ChatID, channels.ErrSendFailed)
 	}
@@ -329,7 +333,7 @@ func (c *TelegramChannel) SendMedia(ctx context.Context, msg bus.OutboundMediaMe
 		return channels.ErrNotRunning
 	}
 
-	chatID, threadID, err := parseTelegramChatID(msg.ChatID)
+	chatID, threadID, err := parseOutboundTarget(msg.ChatID, msg.ThreadID)
 	if err != nil {
 		return fmt.Errorf("invalid chat ID %s: %w", msg.ChatID, channels.ErrSendFailed)
 	}
@@ -368,6 +372,16 @@ func (c *TelegramChannel) SendMedia(ctx context.Context, msg bus.OutboundMediaMe
 			}
 			_, err = c.bot.SendPhoto(ctx, params)
 		case "audio":
+			// OGG/Opus audio is shown as a playable voice note.
+			if part.ContentType == "audio/ogg" {
+				_, err = c.bot.SendVoice(ctx, &telego.SendVoiceParams{
+					ChatID:          tu.ID(chatID),
+					MessageThreadID: threadID,
+					Voice:           telego.InputFile{File: file},
+					Caption:         part.Caption,
+				})
+				break
+			}
 			params := &telego.SendAudioParams{
 				ChatID:          tu.ID(chatID),
 				MessageThreadID: threadID,
@@ -570,6 +584,7 @@ func (c *TelegramChannel) handleMessage(ctx context.Context, message *telego.Mes
 	if message.Chat.IsForum && threadID != 0 {
 		metadata["parent_peer_kind"] = "topic"
 		metadata["parent_peer_id"] = fmt.Sprintf("%d", threadID)
+		metadata["thread_id"] = fmt.Sprintf("%d", threadID)
 	}
 
 	c.HandleMessage(c.ctx,
@@ -643,6 +658,20 @@ func parseTelegramChatID(chatID string) (int64, int, error) {
 	return cid, tid, nil
 }
 
+// parseOutboundTarget resolves the chat and topic for an outbound message.
+// A topic embedded in rawChatID wins; otherwise thread is used.
+func parseOutboundTarget(rawChatID, thread string) (int64, int, error) {
+	chatID, threadID, err := parseTelegramChatID(rawChatID)
+	if err != nil || threadID != 0 || thread == "" {
+		return chatID, threadID, err
+	}
+	threadID, err = strconv.Atoi(thread)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid thread ID %q: %w", thread, err)
+	}
+	return chatID, threadID, nil
+}
+
 func markdownToTelegramHTML(text string) string {
 	if text == "" {
 		return ""
@@ -367,6 +367,24 @@ func TestHandleMessage_ForumTopic_SetsMetadata(t *testing.T) {
 	// Parent peer metadata should be set for agent binding
 	assert.Equal(t, "topic", inbound.Metadata["parent_peer_kind"])
 	assert.Equal(t, "42", inbound.Metadata["parent_peer_id"])
+
+	// Topic is reported as the message thread
+	assert.Equal(t, "42", inbound.ThreadID)
+}
+
+func TestParseOutboundTarget(t *testing.T) {
+	cid, tid, err := parseOutboundTarget("-100123", "7")
+	require.NoError(t, err)
+	assert.Equal(t, int64(-100123), cid)
+	assert.Equal(t, 7, tid)
+
+	// A topic embedded in the chat ID wins over ThreadID
+	_, tid, err = parseOutboundTarget("-100123/42", "7")
+	require.NoError(t, err)
+	assert.Equal(t, 42, tid)
+
+	_, _, err = parseOutboundTarget("-100123", "abc")
+	assert.Error(t, err)
 }
 
 func TestHandleMessage_NoForum_NoThreadMetadata(t *testing.T) {
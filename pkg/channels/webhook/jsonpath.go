@@ -0,0 +1,110 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// lookupPath resolves a JSONPath-style expression against a decoded JSON
+// document. Only the subset needed for field mapping is supported:
+// dotted keys with an optional leading "$", and numeric array indexes,
+// e.g. "$.event.text", "items[0].user.id" or "data.tags[2]".
+func lookupPath(doc any, path string) (any, bool) {
+	segments, err := parsePath(path)
+	if err != nil {
+		return nil, false
+	}
+
+	cur := doc
+	for _, seg := range segments {
+		switch v := cur.(type) {
+		case map[string]any:
+			if seg.isIndex {
+				return nil, false
+			}
+			next, ok := v[seg.key]
+			if !ok {
+				return nil, false
+			}
+			cur = next
+		case []any:
+			if !seg.isIndex || seg.index < 0 || seg.index >= len(v) {
+				return nil, false
+			}
+			cur = v[seg.index]
+		default:
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// lookupString resolves path and renders the result as a string.
+// Strings are returned verbatim, numbers and booleans use their JSON
+// form, and objects/arrays are re-encoded as compact JSON.
+func lookupString(doc any, path string) string {
+	if path == "" {
+		return ""
+	}
+	v, ok := lookupPath(doc, path)
+	if !ok || v == nil {
+		return ""
+	}
+	switch t := v.(type) {
+	case string:
+		return t
+	case json.Number:
+		return t.String()
+	case bool:
+		return strconv.FormatBool(t)
+	default:
+		b, err := json.Marshal(t)
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	}
+}
+
+type pathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+func parsePath(path string) ([]pathSegment, error) {
+	p := strings.TrimSpace(path)
+	p = strings.TrimPrefix(p, "$")
+	p = strings.TrimPrefix(p, ".")
+
+	var segments []pathSegment
+	for p != "" {
+		switch {
+		case p[0] == '[':
+			end := strings.IndexByte(p, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated index in %q", path)
+			}
+			inner := strings.Trim(p[1:end], `'"`)
+			if n, err := strconv.Atoi(inner); err == nil {
+				segments = append(segments, pathSegment{index: n, isIndex: true})
+			} else {
+				segments = append(segments, pathSegment{key: inner})
+			}
+			p = strings.TrimPrefix(p[end+1:], ".")
+		default:
+			end := strings.IndexAny(p, ".[")
+			if end < 0 {
+				end = len(p)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("empty segment in %q", path)
+			}
+			segments = append(segments, pathSegment{key: p[:end]})
+			p = strings.TrimPrefix(p[end:], ".")
+		}
+	}
+	return segments, nil
+}
@@ -0,0 +1,361 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/channels"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/identity"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+const (
+	defaultWebhookPath      = "/webhook/generic"
+	defaultSignatureHeader  = "X-Signature-256"
+	defaultContentPath      = "$.content"
+	defaultSenderPath       = "$.sender"
+	defaultChatIDPath       = "$.chat_id"
+	defaultOutboundTimeout  = 10 * time.Second
+	defaultOutboundTemplate = `{"chat_id": {{json .ChatID}}, "content": {{json .Content}}}`
+
+	// Limit request body to prevent memory exhaustion (DoS).
+	maxWebhookBodySize = 1 << 20 // 1 MiB
+
+	retryBaseBackoff = 500 * time.Millisecond
+	retryMaxBackoff  = 8 * time.Second
+)
+
+// outboundData is the value passed to the outbound body template.
+type outboundData struct {
+	Channel          string
+	ChatID           string
+	Content          string
+	ReplyToMessageID string
+}
+
+// WebhookChannel is a generic HTTP channel. It accepts signed JSON POSTs on
+// the shared gateway server and delivers replies by POSTing a templated JSON
+// body to a configured URL. It lets home automation, CI systems and alerting
+// tools talk to the agent without a dedicated Go channel.
+type WebhookChannel struct {
+	*channels.BaseChannel
+	config   config.WebhookConfig
+	client   *http.Client
+	template *template.Template
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+// NewWebhookChannel creates a new generic webhook channel instance.
+func NewWebhookChannel(cfg config.WebhookConfig, messageBus *bus.MessageBus) (*WebhookChannel, error) {
+	tmplText := cfg.OutboundTemplate
+	if tmplText == "" {
+		tmplText = defaultOutboundTemplate
+	}
+	tmpl, err := template.New("outbound").Funcs(template.FuncMap{"json": jsonString}).Parse(tmplText)
+	if err != nil {
+		return nil, fmt.Errorf("webhook outbound_template is invalid: %w", err)
+	}
+
+	timeout := defaultOutboundTimeout
+	if cfg.OutboundTimeout > 0 {
+		timeout = time.Duration(cfg.OutboundTimeout) * time.Second
+	}
+
+	base := channels.NewBaseChannel("webhook", cfg, messageBus, cfg.AllowFrom,
+		channels.WithReasoningChannelID(cfg.ReasoningChannelID),
+	)
+
+	return &WebhookChannel{
+		BaseChannel: base,
+		config:      cfg,
+		client:      &http.Client{Timeout: timeout},
+		template:    tmpl,
+	}, nil
+}
+
+// Start initializes the webhook channel.
+func (c *WebhookChannel) Start(ctx context.Context) error {
+	logger.InfoC("webhook", "Starting webhook channel")
+
+	c.ctx, c.cancel = context.WithCancel(ctx)
+
+	if c.config.Secret == "" {
+		logger.WarnC("webhook", "No secret configured, inbound requests are not authenticated")
+	}
+	if c.config.OutboundURL == "" {
+		logger.WarnC("webhook", "No outbound_url configured, replies will be dropped")
+	}
+
+	c.SetRunning(true)
+	logger.InfoCF("webhook", "Webhook channel started", map[string]any{
+		"path": c.WebhookPath(),
+	})
+	return nil
+}
+
+// Stop gracefully stops the webhook channel.
+func (c *WebhookChannel) Stop(ctx context.Context) error {
+	logger.InfoC("webhook", "Stopping webhook channel")
+
+	if c.cancel != nil {
+		c.cancel()
+	}
+
+	c.SetRunning(false)
+	logger.InfoC("webhook", "Webhook channel stopped")
+	return nil
+}
+
+// WebhookPath returns the path for registering on the shared HTTP server.
+func (c *WebhookChannel) WebhookPath() string {
+	if c.config.WebhookPath != "" {
+		return c.config.WebhookPath
+	}
+	return defaultWebhookPath
+}
+
+// ServeHTTP implements http.Handler for the shared HTTP server.
+func (c *WebhookChannel) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.webhookHandler(w, r)
+}
+
+// webhookHandler handles incoming webhook requests.
+func (c *WebhookChannel) webhookHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBodySize+1))
+	if err != nil {
+		logger.ErrorCF("webhook", "Failed to read request body", map[string]any{
+			"error": err.Error(),
+		})
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	if int64(len(body)) > maxWebhookBodySize {
+		logger.WarnC("webhook", "Webhook request body too large, rejected")
+		http.Error(w, "Request entity too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if !c.verifySignature(body, r.Header.Get(c.signatureHeader())) {
+		logger.WarnC("webhook", "Invalid webhook signature")
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		logger.ErrorCF("webhook", "Failed to parse webhook payload", map[string]any{
+			"error": err.Error(),
+		})
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	content := strings.TrimSpace(lookupString(doc, pathOr(c.config.ContentPath, defaultContentPath)))
+	if content == "" {
+		http.Error(w, "Missing content", http.StatusUnprocessableEntity)
+		return
+	}
+
+	senderID := lookupString(doc, pathOr(c.config.SenderPath, defaultSenderPath))
+	if senderID == "" {
+		senderID = "anonymous"
+	}
+	chatID := lookupString(doc, pathOr(c.config.ChatIDPath, defaultChatIDPath))
+	if chatID == "" {
+		chatID = c.config.DefaultChatID
+	}
+	if chatID == "" {
+		chatID = senderID
+	}
+	messageID := lookupString(doc, c.config.MessageIDPath)
+
+	sender := bus.SenderInfo{
+		Platform:    "webhook",
+		PlatformID:  senderID,
+		CanonicalID: identity.BuildCanonicalID("webhook", senderID),
+	}
+	if !c.IsAllowedSender(sender) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	logger.DebugCF("webhook", "Received message", map[string]any{
+		"sender_id": senderID,
+		"chat_id":   chatID,
+		"preview":   utils.Truncate(content, 50),
+	})
+
+	metadata := map[string]string{
+		"platform":   "webhook",
+		"remote_ip":  r.RemoteAddr,
+		"user_agent": r.UserAgent(),
+	}
+
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	c.HandleMessage(ctx, bus.Peer{Kind: "direct", ID: chatID}, messageID, senderID, chatID,
+		content, nil, metadata, sender)
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// verifySignature validates an HMAC-SHA256 hex signature of the raw body.
+// Both bare hex and GitHub-style "sha256=<hex>" values are accepted.
+// When no secret is configured every request is accepted.
+func (c *WebhookChannel) verifySignature(body []byte, signature string) bool {
+	if c.config.Secret == "" {
+		return true
+	}
+	signature = strings.TrimPrefix(strings.TrimSpace(signature), "sha256=")
+	if signature == "" {
+		return false
+	}
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(c.config.Secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), got)
+}
+
+func (c *WebhookChannel) signatureHeader() string {
+	if c.config.SignatureHeader != "" {
+		return c.config.SignatureHeader
+	}
+	return defaultSignatureHeader
+}
+
+// Send renders the outbound template and POSTs it to the configured URL,
+// retrying transient failures with exponential backoff.
+func (c *WebhookChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	if !c.IsRunning() {
+		return channels.ErrNotRunning
+	}
+	if c.config.OutboundURL == "" {
+		logger.DebugCF("webhook", "Dropping reply, no outbound_url configured", map[string]any{
+			"chat_id": msg.ChatID,
+		})
+		return nil
+	}
+
+	body, err := c.renderBody(msg)
+	if err != nil {
+		return fmt.Errorf("%w: %v", channels.ErrSendFailed, err)
+	}
+
+	retries := max(c.config.OutboundRetries, 0)
+	for attempt := 0; ; attempt++ {
+		err = c.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if attempt >= retries || !isRetryable(err) {
+			return err
+		}
+
+		backoff := min(time.Duration(float64(retryBaseBackoff)*math.Pow(2, float64(attempt))), retryMaxBackoff)
+		logger.DebugCF("webhook", "Outbound delivery failed, retrying", map[string]any{
+			"attempt": attempt + 1,
+			"backoff": backoff.String(),
+			"error":   err.Error(),
+		})
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// renderBody executes the outbound template and checks that it yields valid JSON.
+func (c *WebhookChannel) renderBody(msg bus.OutboundMessage) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := c.template.Execute(&buf, outboundData{
+		Channel:          c.Name(),
+		ChatID:           msg.ChatID,
+		Content:          msg.Content,
+		ReplyToMessageID: msg.ReplyToMessageID,
+	}); err != nil {
+		return nil, fmt.Errorf("render outbound template: %w", err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, fmt.Errorf("outbound template did not produce valid JSON")
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *WebhookChannel) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.config.OutboundURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %v", channels.ErrSendFailed, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range c.config.OutboundHeaders {
+		req.Header.Set(k, v)
+	}
+	if c.config.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(c.config.Secret))
+		mac.Write(body)
+		req.Header.Set(c.signatureHeader(), "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return channels.ClassifyNetError(err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxWebhookBodySize))
+
+	if resp.StatusCode >= 300 {
+		return channels.ClassifySendError(resp.StatusCode,
+			fmt.Errorf("outbound webhook returned status %d", resp.StatusCode))
+	}
+	return nil
+}
+
+func isRetryable(err error) bool {
+	return errors.Is(err, channels.ErrTemporary) || errors.Is(err, channels.ErrRateLimit)
+}
+
+// jsonString encodes v as a JSON literal for use inside the outbound template.
+func jsonString(v any) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func pathOr(path, fallback string) string {
+	if path != "" {
+		return path
+	}
+	return fallback
+}
@@ -0,0 +1,224 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/channels"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newTestChannel(t *testing.T, cfg config.WebhookConfig) (*WebhookChannel, *bus.MessageBus) {
+	t.Helper()
+	mb := bus.NewMessageBus()
+	ch, err := NewWebhookChannel(cfg, mb)
+	if err != nil {
+		t.Fatalf("NewWebhookChannel: %v", err)
+	}
+	if err := ch.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { ch.Stop(context.Background()) })
+	return ch, mb
+}
+
+func TestLookupPath(t *testing.T) {
+	var doc any
+	if err := json.Unmarshal([]byte(`{
+		"event": {"text": "hello", "user": {"id": 42}},
+		"items": [{"name": "a"}, {"name": "b"}],
+		"ok": true
+	}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"$.event.text", "hello"},
+		{"event.text", "hello"},
+		{"event.user.id", "42"},
+		{"$.items[1].name", "b"},
+		{"items[0]", `{"name":"a"}`},
+		{"$['event']['text']", "hello"},
+		{"ok", "true"},
+		{"missing.path", ""},
+		{"items[5].name", ""},
+		{"event[0]", ""},
+	}
+	for _, tt := range tests {
+		if got := lookupString(doc, tt.path); got != tt.want {
+			t.Errorf("lookupString(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestWebhookVerifiesSignature(t *testing.T) {
+	ch, _ := newTestChannel(t, config.WebhookConfig{Secret: "s3cret"})
+	body := `{"content":"hi","sender":"ci"}`
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook/generic", strings.NewReader(body))
+	req.Header.Set("X-Signature-256", sign("wrong", body))
+	rec := httptest.NewRecorder()
+	ch.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("bad signature: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/webhook/generic", strings.NewReader(body))
+	rec = httptest.NewRecorder()
+	ch.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("missing signature: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestWebhookMapsInboundFields(t *testing.T) {
+	ch, mb := newTestChannel(t, config.WebhookConfig{
+		Secret:        "s3cret",
+		ContentPath:   "$.alert.summary",
+		SenderPath:    "$.source",
+		ChatIDPath:    "$.labels[0]",
+		MessageIDPath: "id",
+	})
+	body := `{"id":"evt-1","source":"grafana","alert":{"summary":"CPU high"},"labels":["ops"]}`
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook/generic", strings.NewReader(body))
+	req.Header.Set("X-Signature-256", sign("s3cret", body))
+	rec := httptest.NewRecorder()
+	ch.ServeHTTP(rec, req)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d (%s)", rec.Code, http.StatusAccepted, rec.Body.String())
+	}
+
+	var msg bus.InboundMessage
+	select {
+	case msg = <-mb.InboundChan():
+	case <-time.After(time.Second):
+		t.Fatal("expected inbound message")
+	}
+	if msg.Content != "CPU high" || msg.ChatID != "ops" || msg.MessageID != "evt-1" {
+		t.Errorf("unexpected message: %+v", msg)
+	}
+	if msg.Sender.CanonicalID != "webhook:grafana" {
+		t.Errorf("CanonicalID = %q, want %q", msg.Sender.CanonicalID, "webhook:grafana")
+	}
+}
+
+func TestWebhookRejectsMissingContent(t *testing.T) {
+	ch, _ := newTestChannel(t, config.WebhookConfig{})
+	req := httptest.NewRequest(http.MethodPost, "/webhook/generic", strings.NewReader(`{"sender":"x"}`))
+	rec := httptest.NewRecorder()
+	ch.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusUnprocessableEntity)
+	}
+}
+
+func TestWebhookRejectsOversizedBody(t *testing.T) {
+	ch, _ := newTestChannel(t, config.WebhookConfig{})
+	body := strings.Repeat("A", maxWebhookBodySize+1)
+	req := httptest.NewRequest(http.MethodPost, "/webhook/generic", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	ch.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusRequestEntityTooLarge)
+	}
+}
+
+func TestSendRendersTemplateAndSigns(t *testing.T) {
+	var gotBody, gotSig, gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		gotSig = r.Header.Get("X-Signature-256")
+		gotAuth = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	ch, _ := newTestChannel(t, config.WebhookConfig{
+		Secret:           "s3cret",
+		OutboundURL:      srv.URL,
+		OutboundHeaders:  map[string]string{"Authorization": "Bearer abc"},
+		OutboundTemplate: `{"room": {{json .ChatID}}, "text": {{json .Content}}}`,
+	})
+
+	err := ch.Send(context.Background(), bus.OutboundMessage{ChatID: "ops", Content: `say "hi"`})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if gotBody != `{"room": "ops", "text": "say \"hi\""}` {
+		t.Errorf("body = %s", gotBody)
+	}
+	if gotSig != sign("s3cret", gotBody) {
+		t.Errorf("signature = %q, want %q", gotSig, sign("s3cret", gotBody))
+	}
+	if gotAuth != "Bearer abc" {
+		t.Errorf("Authorization = %q", gotAuth)
+	}
+}
+
+func TestSendRetriesTransientFailures(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	ch, _ := newTestChannel(t, config.WebhookConfig{OutboundURL: srv.URL, OutboundRetries: 2})
+	if err := ch.Send(context.Background(), bus.OutboundMessage{ChatID: "c", Content: "x"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("calls = %d, want 2", calls.Load())
+	}
+}
+
+func TestSendDoesNotRetryPermanentFailures(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	ch, _ := newTestChannel(t, config.WebhookConfig{OutboundURL: srv.URL, OutboundRetries: 3})
+	err := ch.Send(context.Background(), bus.OutboundMessage{ChatID: "c", Content: "x"})
+	if !errors.Is(err, channels.ErrSendFailed) {
+		t.Fatalf("err = %v, want ErrSendFailed", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("calls = %d, want 1", calls.Load())
+	}
+}
+
+func TestNewWebhookChannelRejectsInvalidTemplate(t *testing.T) {
+	_, err := NewWebhookChannel(config.WebhookConfig{OutboundTemplate: "{{"}, bus.NewMessageBus())
+	if err == nil {
+		t.Fatal("expected error for invalid template")
+	}
+}
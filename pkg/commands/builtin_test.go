@@ -143,3 +143,30 @@ func TestBuiltinListAgents_RestoresOldBehavior(t *testing.T) {
 		t.Fatalf("/list agents reply=%q, want agent IDs", reply)
 	}
 }
+
+func TestBuiltinApproveHandler_PassesID(t *testing.T) {
+	approveDef := findDefinitionByName(t, BuiltinDefinitions(), "approve")
+	var gotID, reply string
+	rt := &Runtime{ApproveToolCall: func(_ context.Context, id string) (string, error) {
+		gotID = id
+		return "done", nil
+	}}
+	err := approveDef.Handler(context.Background(), Request{
+		Text:  "/approve a1b2c3",
+		Reply: func(text string) error { reply = text; return nil },
+	}, rt)
+	if err != nil {
+		t.Fatalf("/approve handler error: %v", err)
+	}
+	if gotID != "a1b2c3" || reply != "done" {
+		t.Fatalf("id = %q, reply = %q", gotID, reply)
+	}
+
+	approveDef.Handler(context.Background(), Request{
+		Text:  "/approve",
+		Reply: func(text string) error { reply = text; return nil },
+	}, rt)
+	if !strings.Contains(reply, "Usage: /approve <id>") {
+		t.Fatalf("reply without id = %q", reply)
+	}
+}
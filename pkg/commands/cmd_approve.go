@@ -0,0 +1,25 @@
+package commands
+
+import "context"
+
+func approveCommand() Definition {
+	return Definition{
+		Name:        "approve",
+		Description: "Approve a tool call held after untrusted content",
+		Usage:       "/approve <id>",
+		Handler: func(ctx context.Context, req Request, rt *Runtime) error {
+			id := nthToken(req.Text, 1)
+			if id == "" {
+				return req.Reply("Usage: /approve <id>")
+			}
+			if rt == nil || rt.ApproveToolCall == nil {
+				return req.Reply(unavailableMsg)
+			}
+			reply, err := rt.ApproveToolCall(ctx, id)
+			if err != nil {
+				return req.Reply("Cannot approve: " + err.Error())
+			}
+			return req.Reply(reply)
+		},
+	}
+}
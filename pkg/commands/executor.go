@@ -3,6 +3,8 @@ package commands
 import (
 	"context"
 	"fmt"
+
+	"github.com/sipeed/picoclaw/pkg/permissions"
 )
 
 type Outcome int
@@ -56,6 +58,11 @@ func (e *Executor) executeDefinition(ctx context.Context, req Request, def Defin
 		req.Reply = func(string) error { return nil }
 	}
 
+	if !permissions.RoleFrom(ctx).AllowsCommand(def.Name) {
+		err := req.Reply(fmt.Sprintf("You don't have permission to use /%s.", def.Name))
+		return ExecuteResult{Outcome: OutcomeHandled, Command: def.Name, Err: err}
+	}
+
 	// Simple command — no sub-commands
 	if len(def.SubCommands) == 0 {
 		if def.Handler == nil {
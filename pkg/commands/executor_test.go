@@ -5,6 +5,10 @@ import (
 	"errors"
 	"strings"
 	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/permissions"
 )
 
 func TestExecutor_RegisteredWithoutHandler_ReturnsPassthrough(t *testing.T) {
@@ -258,3 +262,43 @@ func TestExecutor_SubCommand_NilHandler_ReturnsPassthrough(t *testing.T) {
 		t.Fatalf("outcome=%v, want=%v", res.Outcome, OutcomePassthrough)
 	}
 }
+
+func TestExecutor_CommandDeniedByRole_RepliesAndSkipsHandler(t *testing.T) {
+	called := false
+	defs := []Definition{
+		{
+			Name:    "switch",
+			Aliases: []string{"sw"},
+			Handler: func(context.Context, Request, *Runtime) error {
+				called = true
+				return nil
+			},
+		},
+	}
+	policy := permissions.NewPolicy(config.PermissionsConfig{
+		Enabled:     true,
+		DefaultRole: "guest",
+		Roles:       map[string]config.RoleConfig{"guest": {Commands: []string{"help"}}},
+	})
+	ctx := permissions.WithRole(context.Background(), policy.RoleFor(bus.SenderInfo{CanonicalID: "telegram:1"}))
+	ex := NewExecutor(NewRegistry(defs), nil)
+
+	var reply string
+	res := ex.Execute(ctx, Request{
+		Channel: "telegram",
+		Text:    "/sw model to gpt-4o",
+		Reply: func(text string) error {
+			reply = text
+			return nil
+		},
+	})
+	if res.Outcome != OutcomeHandled {
+		t.Fatalf("outcome=%v, want=%v", res.Outcome, OutcomeHandled)
+	}
+	if called {
+		t.Fatal("handler should not run for a denied command")
+	}
+	if reply != "You don't have permission to use /switch." {
+		t.Fatalf("reply=%q", reply)
+	}
+}
@@ -1,6 +1,9 @@
 package commands
 
+import "sync"
+
 type Registry struct {
+	mu    sync.RWMutex
 	defs  []Definition
 	index map[string]int
 }
@@ -11,20 +14,14 @@ func NewRegistry(defs []Definition) *Registry {
 	stored := make([]Definition, len(defs))
 	copy(stored, defs)
 
-	index := make(map[string]int, len(stored)*2)
-	for i, def := range stored {
-		registerCommandName(index, def.Name, i)
-		for _, alias := range def.Aliases {
-			registerCommandName(index, alias, i)
-		}
-	}
-
-	return &Registry{defs: stored, index: index}
+	return &Registry{defs: stored, index: buildIndex(stored)}
 }
 
 // Definitions returns all registered command definitions.
 // Command availability is global and no longer channel-scoped.
 func (r *Registry) Definitions() []Definition {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	out := make([]Definition, len(r.defs))
 	copy(out, r.defs)
 	return out
@@ -36,6 +33,8 @@ func (r *Registry) Lookup(name string) (Definition, bool) {
 	if key == "" {
 		return Definition{}, false
 	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	idx, ok := r.index[key]
 	if !ok {
 		return Definition{}, false
@@ -43,6 +42,66 @@ func (r *Registry) Lookup(name string) (Definition, bool) {
 	return r.defs[idx], true
 }
 
+// Register adds definitions at runtime (for example prompts published by MCP
+// servers). A definition whose name is already taken is skipped so dynamic
+// commands can never shadow built-ins. It returns the names that were added.
+func (r *Registry) Register(defs ...Definition) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var added []string
+	for _, def := range defs {
+		key := normalizeCommandName(def.Name)
+		if key == "" {
+			continue
+		}
+		if _, exists := r.index[key]; exists {
+			continue
+		}
+		r.defs = append(r.defs, def)
+		idx := len(r.defs) - 1
+		registerCommandName(r.index, def.Name, idx)
+		for _, alias := range def.Aliases {
+			registerCommandName(r.index, alias, idx)
+		}
+		added = append(added, def.Name)
+	}
+	return added
+}
+
+// Unregister removes the definitions with the given names.
+func (r *Registry) Unregister(names ...string) {
+	if len(names) == 0 {
+		return
+	}
+	drop := make(map[string]bool, len(names))
+	for _, name := range names {
+		drop[normalizeCommandName(name)] = true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	kept := r.defs[:0:0]
+	for _, def := range r.defs {
+		if !drop[normalizeCommandName(def.Name)] {
+			kept = append(kept, def)
+		}
+	}
+	r.defs = kept
+	r.index = buildIndex(kept)
+}
+
+func buildIndex(defs []Definition) map[string]int {
+	index := make(map[string]int, len(defs)*2)
+	for i, def := range defs {
+		registerCommandName(index, def.Name, i)
+		for _, alias := range def.Aliases {
+			registerCommandName(index, alias, i)
+		}
+	}
+	return index
+}
+
 func registerCommandName(index map[string]int, name string, defIndex int) {
 	key := normalizeCommandName(name)
 	if key == "" {
@@ -47,3 +47,42 @@ func TestRegistry_Lookup_MatchesByLowercaseNameAndAlias(t *testing.T) {
 		t.Fatalf("lookup by uppercase alias failed: ok=%v def=%+v", ok, def)
 	}
 }
+
+func TestRegistry_RegisterSkipsTakenNames(t *testing.T) {
+	r := NewRegistry([]Definition{{Name: "help", Aliases: []string{"h"}}})
+
+	added := r.Register(
+		Definition{Name: "HELP"},
+		Definition{Name: "h"},
+		Definition{Name: "docs_review", Description: "dynamic"},
+	)
+	if len(added) != 1 || added[0] != "docs_review" {
+		t.Fatalf("added = %v, want [docs_review]", added)
+	}
+	if def, ok := r.Lookup("docs_review"); !ok || def.Description != "dynamic" {
+		t.Fatalf("lookup of registered command failed: ok=%v def=%+v", ok, def)
+	}
+	if len(r.Definitions()) != 2 {
+		t.Fatalf("definitions len = %d, want 2", len(r.Definitions()))
+	}
+}
+
+func TestRegistry_UnregisterRebuildsIndex(t *testing.T) {
+	r := NewRegistry([]Definition{{Name: "help"}})
+	r.Register(
+		Definition{Name: "a_one"},
+		Definition{Name: "b_two", Aliases: []string{"two"}},
+	)
+
+	r.Unregister("A_ONE")
+	if _, ok := r.Lookup("a_one"); ok {
+		t.Fatal("a_one should be gone")
+	}
+	def, ok := r.Lookup("two")
+	if !ok || def.Name != "b_two" {
+		t.Fatalf("alias lookup after unregister failed: ok=%v def=%+v", ok, def)
+	}
+	if def, ok := r.Lookup("help"); !ok || def.Name != "help" {
+		t.Fatalf("builtin lookup after unregister failed: ok=%v def=%+v", ok, def)
+	}
+}
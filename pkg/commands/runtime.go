@@ -1,6 +1,10 @@
 package commands
 
-import "github.com/sipeed/picoclaw/pkg/config"
+import (
+	"context"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
 
 // Runtime provides runtime dependencies to command handlers. It is constructed
 // per-request by the agent loop so that per-request state (like session scope)
@@ -14,4 +18,10 @@ type Runtime struct {
 	SwitchModel        func(value string) (oldModel string, err error)
 	SwitchChannel      func(value string) error
 	ClearHistory       func() error
+	// RunPrompt runs text through the agent as if the user had sent it and
+	// returns the reply.
+	RunPrompt func(ctx context.Context, text string) (string, error)
+	// ApproveToolCall approves the tool call held under id, lets the agent
+	// retry it and returns the reply.
+	ApproveToolCall func(ctx context.Context, id string) (string, error)
 }
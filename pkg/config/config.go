@@ -77,19 +77,27 @@ func (f *FlexibleStringSlice) UnmarshalText(text []byte) error {
 }
 
 type Config struct {
-	Agents    AgentsConfig    `json:"agents"`
-	Bindings  []AgentBinding  `json:"bindings,omitempty"`
-	Session   SessionConfig   `json:"session,omitempty"`
-	Channels  ChannelsConfig  `json:"channels"`
-	Providers ProvidersConfig `json:"providers,omitempty"`
-	ModelList []ModelConfig   `json:"model_list"` // New model-centric provider configuration
-	Gateway   GatewayConfig   `json:"gateway"`
-	Tools     ToolsConfig     `json:"tools"`
-	Heartbeat HeartbeatConfig `json:"heartbeat"`
-	Devices   DevicesConfig   `json:"devices"`
-	Voice     VoiceConfig     `json:"voice"`
+	Agents      AgentsConfig      `json:"agents"`
+	Bindings    []AgentBinding    `json:"bindings,omitempty"`
+	Session     SessionConfig     `json:"session,omitempty"`
+	Channels    ChannelsConfig    `json:"channels"`
+	Providers   ProvidersConfig   `json:"providers,omitempty"`
+	ModelList   []ModelConfig     `json:"model_list"` // New model-centric provider configuration
+	Gateway     GatewayConfig     `json:"gateway"`
+	Tools       ToolsConfig       `json:"tools"`
+	Heartbeat   HeartbeatConfig   `json:"heartbeat"`
+	Devices     DevicesConfig     `json:"devices"`
+	Voice       VoiceConfig       `json:"voice"`
+	Redaction   RedactionConfig   `json:"redaction"`
+	Audit       AuditConfig       `json:"audit"`
+	Permissions PermissionsConfig `json:"permissions"`
+	Egress      EgressConfig      `json:"egress"`
 	// BuildInfo contains build-time version information
 	BuildInfo BuildInfo `json:"build_info,omitempty"`
+
+	// dir is the directory of the loaded config file; file:// credential
+	// references are resolved relative to it.
+	dir string
 }
 
 // BuildInfo contains build-time version information
@@ -118,7 +126,7 @@ func (c Config) MarshalJSON() ([]byte, error) {
 	}
 
 	// Only include session if not empty
-	if c.Session.DMScope != "" || len(c.Session.IdentityLinks) > 0 {
+	if c.Session.DMScope != "" || c.Session.ThreadScope != "" || len(c.Session.IdentityLinks) > 0 {
 		aux.Session = &c.Session
 	}
 
@@ -203,7 +211,10 @@ type AgentBinding struct {
 }
 
 type SessionConfig struct {
-	DMScope       string              `json:"dm_scope,omitempty"`
+	DMScope string `json:"dm_scope,omitempty"`
+	// ThreadScope is "shared" (the default) or "per-thread", which gives
+	// every thread or forum topic its own session.
+	ThreadScope   string              `json:"thread_scope,omitempty"`
 	IdentityLinks map[string][]string `json:"identity_links,omitempty"`
 }
 
@@ -274,6 +285,8 @@ type ChannelsConfig struct {
 	WeComWS    WeComWSConfig    `json:"wecom_ws"`
 	Pico       PicoConfig       `json:"pico"`
 	IRC        IRCConfig        `json:"irc"`
+	Webhook    WebhookConfig    `json:"webhook"`
+	MQTT       MQTTConfig       `json:"mqtt"`
 }
 
 // GroupTriggerConfig controls when the bot responds in group chats.
@@ -519,18 +532,171 @@ type IRCConfig struct {
 	ReasoningChannelID string              `json:"reasoning_channel_id"    env:"PICOCLAW_CHANNELS_IRC_REASONING_CHANNEL_ID"`
 }
 
+// WebhookConfig configures the generic HTTP webhook channel. Inbound POSTs
+// are verified with an HMAC-SHA256 signature and mapped to a message using
+// JSONPath-style field paths (e.g. "$.event.text", "items[0].user.id").
+// Outbound replies are POSTed to OutboundURL using OutboundTemplate.
+type WebhookConfig struct {
+	Enabled            bool                `json:"enabled"                     env:"PICOCLAW_CHANNELS_WEBHOOK_ENABLED"`
+	WebhookPath        string              `json:"webhook_path"                env:"PICOCLAW_CHANNELS_WEBHOOK_WEBHOOK_PATH"`
+	Secret             string              `json:"secret"                      env:"PICOCLAW_CHANNELS_WEBHOOK_SECRET"`
+	SignatureHeader    string              `json:"signature_header,omitempty"  env:"PICOCLAW_CHANNELS_WEBHOOK_SIGNATURE_HEADER"`
+	ContentPath        string              `json:"content_path,omitempty"      env:"PICOCLAW_CHANNELS_WEBHOOK_CONTENT_PATH"`
+	SenderPath         string              `json:"sender_path,omitempty"       env:"PICOCLAW_CHANNELS_WEBHOOK_SENDER_PATH"`
+	ChatIDPath         string              `json:"chat_id_path,omitempty"      env:"PICOCLAW_CHANNELS_WEBHOOK_CHAT_ID_PATH"`
+	MessageIDPath      string              `json:"message_id_path,omitempty"   env:"PICOCLAW_CHANNELS_WEBHOOK_MESSAGE_ID_PATH"`
+	DefaultChatID      string              `json:"default_chat_id,omitempty"   env:"PICOCLAW_CHANNELS_WEBHOOK_DEFAULT_CHAT_ID"`
+	OutboundURL        string              `json:"outbound_url"                env:"PICOCLAW_CHANNELS_WEBHOOK_OUTBOUND_URL"`
+	OutboundHeaders    map[string]string   `json:"outbound_headers,omitempty"`
+	OutboundTemplate   string              `json:"outbound_template,omitempty" env:"PICOCLAW_CHANNELS_WEBHOOK_OUTBOUND_TEMPLATE"`
+	OutboundRetries    int                 `json:"outbound_retries,omitempty"  env:"PICOCLAW_CHANNELS_WEBHOOK_OUTBOUND_RETRIES"`
+	OutboundTimeout    int                 `json:"outbound_timeout,omitempty"  env:"PICOCLAW_CHANNELS_WEBHOOK_OUTBOUND_TIMEOUT"` // seconds
+	AllowFrom          FlexibleStringSlice `json:"allow_from"                  env:"PICOCLAW_CHANNELS_WEBHOOK_ALLOW_FROM"`
+	ReasoningChannelID string              `json:"reasoning_channel_id"        env:"PICOCLAW_CHANNELS_WEBHOOK_REASONING_CHANNEL_ID"`
+}
+
+// MQTTConfig configures the MQTT channel for IoT devices. Inbound messages
+// arrive on SubscribeTopics; the topic segment at ChatIDSegment becomes the
+// chat ID, and replies are published to ResponseTopic with "{chat_id}"
+// substituted. An empty ClientID is replaced by "picoclaw-<random>" at connect
+// time, so several instances can share a broker.
+type MQTTConfig struct {
+	Enabled            bool                `json:"enabled"                  env:"PICOCLAW_CHANNELS_MQTT_ENABLED"`
+	Broker             string              `json:"broker"                   env:"PICOCLAW_CHANNELS_MQTT_BROKER"`
+	ClientID           string              `json:"client_id"                env:"PICOCLAW_CHANNELS_MQTT_CLIENT_ID"`
+	Username           string              `json:"username"                 env:"PICOCLAW_CHANNELS_MQTT_USERNAME"`
+	Password           string              `json:"password"                 env:"PICOCLAW_CHANNELS_MQTT_PASSWORD"`
+	QoS                int                 `json:"qos"                      env:"PICOCLAW_CHANNELS_MQTT_QOS"`
+	SubscribeTopics    FlexibleStringSlice `json:"subscribe_topics"         env:"PICOCLAW_CHANNELS_MQTT_SUBSCRIBE_TOPICS"`
+	ChatIDSegment      int                 `json:"chat_id_segment"          env:"PICOCLAW_CHANNELS_MQTT_CHAT_ID_SEGMENT"`
+	ResponseTopic      string              `json:"response_topic"           env:"PICOCLAW_CHANNELS_MQTT_RESPONSE_TOPIC"`
+	TLS                MQTTTLSConfig       `json:"tls,omitempty"`
+	MaxMediaSize       int                 `json:"max_media_size,omitempty" env:"PICOCLAW_CHANNELS_MQTT_MAX_MEDIA_SIZE"` // bytes
+	AllowFrom          FlexibleStringSlice `json:"allow_from"               env:"PICOCLAW_CHANNELS_MQTT_ALLOW_FROM"`
+	ReasoningChannelID string              `json:"reasoning_channel_id"     env:"PICOCLAW_CHANNELS_MQTT_REASONING_CHANNEL_ID"`
+}
+
+// MQTTTLSConfig holds optional TLS settings for the MQTT broker connection.
+// TLS is used when Enabled is set or the broker URL scheme is ssl://, tls://,
+// mqtts:// or wss://.
+type MQTTTLSConfig struct {
+	Enabled            bool   `json:"enabled"                        env:"PICOCLAW_CHANNELS_MQTT_TLS_ENABLED"`
+	CAFile             string `json:"ca_file,omitempty"              env:"PICOCLAW_CHANNELS_MQTT_TLS_CA_FILE"`
+	CertFile           string `json:"cert_file,omitempty"            env:"PICOCLAW_CHANNELS_MQTT_TLS_CERT_FILE"`
+	KeyFile            string `json:"key_file,omitempty"             env:"PICOCLAW_CHANNELS_MQTT_TLS_KEY_FILE"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty" env:"PICOCLAW_CHANNELS_MQTT_TLS_INSECURE_SKIP_VERIFY"`
+}
+
 type HeartbeatConfig struct {
 	Enabled  bool `json:"enabled"  env:"PICOCLAW_HEARTBEAT_ENABLED"`
 	Interval int  `json:"interval" env:"PICOCLAW_HEARTBEAT_INTERVAL"` // minutes, min 5
 }
 
+// RedactionConfig controls masking of secrets in logs, tool results and the
+// web session viewer. Configured secrets and credential-shaped values (API
+// keys, JWTs, private keys) are always masked when enabled.
+type RedactionConfig struct {
+	Enabled bool `json:"enabled" env:"PICOCLAW_REDACTION_ENABLED"`
+	// PII also masks email addresses and phone numbers.
+	PII bool `json:"pii" env:"PICOCLAW_REDACTION_PII"`
+	// Patterns are extra regular expressions to mask.
+	Patterns []string `json:"patterns,omitempty"`
+}
+
+// AuditConfig controls the tamper-evident audit log of agent actions.
+type AuditConfig struct {
+	Enabled bool `json:"enabled" env:"PICOCLAW_AUDIT_ENABLED"`
+	// Dir holds the log files (default: $PICOCLAW_HOME/audit).
+	Dir string `json:"dir,omitempty" env:"PICOCLAW_AUDIT_DIR"`
+	// MaxSizeMB rotates a log file once it reaches this size (0 disables rotation).
+	MaxSizeMB int `json:"max_size_mb" env:"PICOCLAW_AUDIT_MAX_SIZE_MB"`
+	// MaxFiles is the number of rotated files kept per source (0 keeps all).
+	MaxFiles int `json:"max_files" env:"PICOCLAW_AUDIT_MAX_FILES"`
+}
+
+// PermissionsConfig assigns roles to senders and limits what each role may
+// use. Senders must still pass the channel's allow_from list first.
+type PermissionsConfig struct {
+	Enabled bool `json:"enabled" env:"PICOCLAW_PERMISSIONS_ENABLED"`
+	// DefaultRole applies to senders not listed in Users.
+	DefaultRole string `json:"default_role" env:"PICOCLAW_PERMISSIONS_DEFAULT_ROLE"`
+	// Users maps canonical sender IDs ("telegram:123456") to role names.
+	// Legacy allow_from formats ("123456", "@alice") are accepted too.
+	Users map[string]string     `json:"users,omitempty"`
+	Roles map[string]RoleConfig `json:"roles,omitempty"`
+}
+
+// RoleConfig lists what a role may use. Entries are names or glob patterns
+// ("mcp_github_*"); "*" allows everything and an empty list allows nothing.
+type RoleConfig struct {
+	Tools    []string `json:"tools"`
+	Commands []string `json:"commands"`
+	Agents   []string `json:"agents"`
+	// Models are the models the role may select with /switch model.
+	Models []string `json:"models"`
+}
+
+// EgressConfig limits where tools may connect. It applies to every HTTP
+// client built by utils.CreateHTTPClient (web tools, skill installers), the
+// browser, MCP HTTP servers and, with ExecProxy, exec commands.
+type EgressConfig struct {
+	Enabled bool `json:"enabled" env:"PICOCLAW_EGRESS_ENABLED"`
+	EgressRules
+	// Agents overrides the rules per agent ID. Blocked lists add to the
+	// global ones, allowed lists replace them when set.
+	Agents map[string]EgressRules `json:"agents,omitempty"`
+	// ExecProxy routes exec commands through a filtering HTTP proxy. In the
+	// network-isolated exec sandbox the proxy is the only way out; otherwise
+	// only programs honoring HTTP_PROXY use it.
+	ExecProxy bool `json:"exec_proxy" env:"PICOCLAW_EGRESS_EXEC_PROXY"`
+}
+
+// EgressRules are the destinations a connection is checked against.
+// Domains match themselves and their subdomains. When any allowed entry is
+// set, only matching destinations are reachable.
+type EgressRules struct {
+	AllowedDomains []string `json:"allowed_domains,omitempty"`
+	BlockedDomains []string `json:"blocked_domains,omitempty"`
+	AllowedCIDRs   []string `json:"allowed_cidrs,omitempty"`
+	BlockedCIDRs   []string `json:"blocked_cidrs,omitempty"`
+	// BlockPrivate refuses loopback, private, link-local and similar
+	// addresses not listed in AllowedCIDRs.
+	BlockPrivate *bool `json:"block_private,omitempty"`
+}
+
 type DevicesConfig struct {
 	Enabled    bool `json:"enabled"     env:"PICOCLAW_DEVICES_ENABLED"`
 	MonitorUSB bool `json:"monitor_usb" env:"PICOCLAW_DEVICES_MONITOR_USB"`
 }
 
 type VoiceConfig struct {
-	EchoTranscription bool `json:"echo_transcription" env:"PICOCLAW_VOICE_ECHO_TRANSCRIPTION"`
+	EchoTranscription bool      `json:"echo_transcription" env:"PICOCLAW_VOICE_ECHO_TRANSCRIPTION"`
+	TTS               TTSConfig `json:"tts"`
+}
+
+// TTSConfig configures spoken replies to voice messages.
+type TTSConfig struct {
+	Enabled  bool   `json:"enabled"  env:"PICOCLAW_VOICE_TTS_ENABLED"`
+	Provider string `json:"provider" env:"PICOCLAW_VOICE_TTS_PROVIDER"` // "openai" or "command"
+	// OpenAI-compatible /audio/speech settings. APIKey and APIBase fall back
+	// to providers.openai when empty.
+	APIBase string `json:"api_base,omitempty" env:"PICOCLAW_VOICE_TTS_API_BASE"`
+	APIKey  string `json:"api_key,omitempty"  env:"PICOCLAW_VOICE_TTS_API_KEY"`
+	Model   string `json:"model,omitempty"    env:"PICOCLAW_VOICE_TTS_MODEL"`
+	Voice   string `json:"voice,omitempty"    env:"PICOCLAW_VOICE_TTS_VOICE"`
+	// Format is the audio container produced by the engine: opus, mp3 or wav.
+	Format string `json:"format,omitempty" env:"PICOCLAW_VOICE_TTS_FORMAT"`
+	// Command is the argv of a local engine such as piper. The text is written
+	// to stdin; "{output}" in an argument is replaced with the output file,
+	// otherwise the audio is read from stdout.
+	Command []string `json:"command,omitempty" env:"PICOCLAW_VOICE_TTS_COMMAND"`
+	// ReplyWhenSpokenTo answers voice messages with a voice reply. Channels and
+	// Users override it; user keys use the allow_from syntax.
+	ReplyWhenSpokenTo bool            `json:"reply_when_spoken_to" env:"PICOCLAW_VOICE_TTS_REPLY_WHEN_SPOKEN_TO"`
+	Channels          map[string]bool `json:"channels,omitempty"`
+	Users             map[string]bool `json:"users,omitempty"`
+	// SendText also delivers the text reply next to the voice message.
+	SendText bool `json:"send_text" env:"PICOCLAW_VOICE_TTS_SEND_TEXT"`
 }
 
 type ProvidersConfig struct {
@@ -736,11 +902,117 @@ type CronToolsConfig struct {
 
 type ExecConfig struct {
 	ToolConfig          `         envPrefix:"PICOCLAW_TOOLS_EXEC_"`
-	EnableDenyPatterns  bool     `                                 env:"PICOCLAW_TOOLS_EXEC_ENABLE_DENY_PATTERNS"  json:"enable_deny_patterns"`
-	AllowRemote         bool     `                                 env:"PICOCLAW_TOOLS_EXEC_ALLOW_REMOTE"          json:"allow_remote"`
-	CustomDenyPatterns  []string `                                 env:"PICOCLAW_TOOLS_EXEC_CUSTOM_DENY_PATTERNS"  json:"custom_deny_patterns"`
-	CustomAllowPatterns []string `                                 env:"PICOCLAW_TOOLS_EXEC_CUSTOM_ALLOW_PATTERNS" json:"custom_allow_patterns"`
-	TimeoutSeconds      int      `                                 env:"PICOCLAW_TOOLS_EXEC_TIMEOUT_SECONDS"       json:"timeout_seconds"` // 0 means use default (60s)
+	EnableDenyPatterns  bool              `                                 env:"PICOCLAW_TOOLS_EXEC_ENABLE_DENY_PATTERNS"  json:"enable_deny_patterns"`
+	AllowRemote         bool              `                                 env:"PICOCLAW_TOOLS_EXEC_ALLOW_REMOTE"          json:"allow_remote"`
+	CustomDenyPatterns  []string          `                                 env:"PICOCLAW_TOOLS_EXEC_CUSTOM_DENY_PATTERNS"  json:"custom_deny_patterns"`
+	CustomAllowPatterns []string          `                                 env:"PICOCLAW_TOOLS_EXEC_CUSTOM_ALLOW_PATTERNS" json:"custom_allow_patterns"`
+	TimeoutSeconds      int               `                                 env:"PICOCLAW_TOOLS_EXEC_TIMEOUT_SECONDS"       json:"timeout_seconds"` // 0 means use default (60s)
+	Sandbox             ExecSandboxConfig `                                                                                 json:"sandbox"`
+}
+
+// ExecSandboxConfig runs exec commands in Linux user/mount/pid/network
+// namespaces with only the workspace writable. When the sandbox is active the
+// deny patterns are advisory.
+type ExecSandboxConfig struct {
+	Enabled bool `json:"enabled" env:"PICOCLAW_TOOLS_EXEC_SANDBOX_ENABLED"`
+	// Network keeps the host network; by default commands get an isolated
+	// network namespace with no interfaces.
+	Network        bool    `json:"network"          env:"PICOCLAW_TOOLS_EXEC_SANDBOX_NETWORK"`
+	MemoryMB       int     `json:"memory_mb"        env:"PICOCLAW_TOOLS_EXEC_SANDBOX_MEMORY_MB"`
+	CPUs           float64 `json:"cpus"             env:"PICOCLAW_TOOLS_EXEC_SANDBOX_CPUS"`
+	CPUTimeSeconds int     `json:"cpu_time_seconds" env:"PICOCLAW_TOOLS_EXEC_SANDBOX_CPU_TIME_SECONDS"`
+	MaxProcesses   int     `json:"max_processes"    env:"PICOCLAW_TOOLS_EXEC_SANDBOX_MAX_PROCESSES"`
+	// CgroupParent is a delegated cgroup v2 directory under which a cgroup is
+	// created per command. Without it only the CPU time limit applies; memory,
+	// CPU quota and process limits are not enforced.
+	CgroupParent string `json:"cgroup_parent,omitempty" env:"PICOCLAW_TOOLS_EXEC_SANDBOX_CGROUP_PARENT"`
+}
+
+// ShellSessionConfig configures persistent interactive shells. Sessions use
+// the exec tool's workspace restriction, deny patterns and sandbox.
+type ShellSessionConfig struct {
+	ToolConfig         `    envPrefix:"PICOCLAW_TOOLS_SHELL_SESSION_"`
+	MaxSessions        int `                                          env:"PICOCLAW_TOOLS_SHELL_SESSION_MAX_SESSIONS"         json:"max_sessions"`
+	IdleTimeoutMinutes int `                                          env:"PICOCLAW_TOOLS_SHELL_SESSION_IDLE_TIMEOUT_MINUTES" json:"idle_timeout_minutes"`
+	BufferKB           int `                                          env:"PICOCLAW_TOOLS_SHELL_SESSION_BUFFER_KB"            json:"buffer_kb"`
+}
+
+// BrowserToolConfig configures the headless Chromium browser tool. Browser
+// traffic follows the web tools' private_host_whitelist.
+type BrowserToolConfig struct {
+	ToolConfig         `       envPrefix:"PICOCLAW_TOOLS_BROWSER_"`
+	ExecutablePath     string `                                    env:"PICOCLAW_TOOLS_BROWSER_EXECUTABLE_PATH"      json:"executable_path"`
+	TimeoutSeconds     int    `                                    env:"PICOCLAW_TOOLS_BROWSER_TIMEOUT_SECONDS"      json:"timeout_seconds"`
+	IdleTimeoutMinutes int    `                                    env:"PICOCLAW_TOOLS_BROWSER_IDLE_TIMEOUT_MINUTES" json:"idle_timeout_minutes"`
+	MaxChars           int    `                                    env:"PICOCLAW_TOOLS_BROWSER_MAX_CHARS"            json:"max_chars"`
+}
+
+// GenerateImageConfig configures the generate_image tool. Models are
+// model_name aliases from model_list; the first is the default and the agent
+// may pick any of the others.
+type GenerateImageConfig struct {
+	ToolConfig     `         envPrefix:"PICOCLAW_TOOLS_GENERATE_IMAGE_"`
+	Models         []string `                                           env:"PICOCLAW_TOOLS_GENERATE_IMAGE_MODELS"          json:"models"`
+	TimeoutSeconds int      `                                           env:"PICOCLAW_TOOLS_GENERATE_IMAGE_TIMEOUT_SECONDS" json:"timeout_seconds"`
+}
+
+// GitToolConfig configures the git tool. Push and pull are disabled unless
+// allow_remote is set, and then only reach the remotes listed here.
+type GitToolConfig struct {
+	ToolConfig     `                           envPrefix:"PICOCLAW_TOOLS_GIT_"`
+	TimeoutSeconds int                        `                                env:"PICOCLAW_TOOLS_GIT_TIMEOUT_SECONDS" json:"timeout_seconds"`
+	MaxChars       int                        `                                env:"PICOCLAW_TOOLS_GIT_MAX_CHARS"       json:"max_chars"`
+	AuthorName     string                     `                                env:"PICOCLAW_TOOLS_GIT_AUTHOR_NAME"     json:"author_name"`
+	AuthorEmail    string                     `                                env:"PICOCLAW_TOOLS_GIT_AUTHOR_EMAIL"    json:"author_email"`
+	AllowRemote    bool                       `                                env:"PICOCLAW_TOOLS_GIT_ALLOW_REMOTE"    json:"allow_remote"`
+	Remotes        map[string]GitRemoteConfig `                                                                         json:"remotes,omitempty"`
+}
+
+// GitRemoteConfig is a remote the git tool can push to and pull from by name.
+// The repository's own remote configuration is never used, so credentials
+// only go to URLs the operator configured. Token accepts the same plaintext,
+// file:// and enc:// values as model_list api_key.
+type GitRemoteConfig struct {
+	URL        string `json:"url"`
+	Username   string `json:"username,omitempty"`     // HTTPS; defaults to "x-access-token"
+	Token      string `json:"token,omitempty"`        // HTTPS
+	SSHKeyPath string `json:"ssh_key_path,omitempty"` // SSH
+	// PushBranches limits the branches that may be pushed, as path.Match
+	// patterns such as "picoclaw/*". Empty allows every branch.
+	PushBranches []string `json:"push_branches,omitempty"`
+}
+
+// HTTPRequestToolConfig configures the http_request tool. Requests follow the
+// web tools' proxy and private_host_whitelist.
+type HTTPRequestToolConfig struct {
+	ToolConfig       `                           envPrefix:"PICOCLAW_TOOLS_HTTP_REQUEST_"`
+	TimeoutSeconds   int                        `                                         env:"PICOCLAW_TOOLS_HTTP_REQUEST_TIMEOUT_SECONDS"    json:"timeout_seconds"`
+	MaxResponseBytes int64                      `                                         env:"PICOCLAW_TOOLS_HTTP_REQUEST_MAX_RESPONSE_BYTES" json:"max_response_bytes"`
+	AuthProfiles     map[string]HTTPAuthProfile `                                                                                              json:"auth_profiles,omitempty"`
+}
+
+// HTTPAuthProfile is a named set of credentials the agent can attach to
+// http_request calls by name without seeing them. Secret fields accept the
+// same plaintext, file:// and enc:// values as model_list api_key.
+type HTTPAuthProfile struct {
+	// Type is "bearer", "basic", "header" or "oauth_client_credentials".
+	Type string `json:"type"`
+	// AllowedHosts limits the hosts the credentials may be sent to. Entries
+	// are hostnames, optionally with a leading "*." wildcard. Required.
+	AllowedHosts []string `json:"allowed_hosts"`
+	// AllowHTTP lets the credentials go over plain http. By default they
+	// are only sent to https URLs.
+	AllowHTTP bool `json:"allow_http,omitempty"`
+
+	Token        string   `json:"token,omitempty"`         // bearer
+	Username     string   `json:"username,omitempty"`      // basic
+	Password     string   `json:"password,omitempty"`      // basic
+	HeaderName   string   `json:"header_name,omitempty"`   // header
+	HeaderValue  string   `json:"header_value,omitempty"`  // header
+	TokenURL     string   `json:"token_url,omitempty"`     // oauth_client_credentials
+	ClientID     string   `json:"client_id,omitempty"`     // oauth_client_credentials
+	ClientSecret string   `json:"client_secret,omitempty"` // oauth_client_credentials
+	Scopes       []string `json:"scopes,omitempty"`        // oauth_client_credentials
 }
 
 type SkillsToolsConfig struct {
@@ -762,30 +1034,48 @@ type ReadFileToolConfig struct {
 	MaxReadFileSize int  `json:"max_read_file_size"`
 }
 
+// ReadDocumentConfig configures the read_document tool, which returns the
+// extracted text of PDF, office, EPUB, HTML and CSV files page by page.
+type ReadDocumentConfig struct {
+	ToolConfig `    envPrefix:"PICOCLAW_TOOLS_READ_DOCUMENT_"`
+	MaxChars   int `                                          env:"PICOCLAW_TOOLS_READ_DOCUMENT_MAX_CHARS" json:"max_chars"`
+}
+
 type ToolsConfig struct {
-	AllowReadPaths  []string           `json:"allow_read_paths"  env:"PICOCLAW_TOOLS_ALLOW_READ_PATHS"`
-	AllowWritePaths []string           `json:"allow_write_paths" env:"PICOCLAW_TOOLS_ALLOW_WRITE_PATHS"`
-	Web             WebToolsConfig     `json:"web"`
-	Cron            CronToolsConfig    `json:"cron"`
-	Exec            ExecConfig         `json:"exec"`
-	Skills          SkillsToolsConfig  `json:"skills"`
-	MediaCleanup    MediaCleanupConfig `json:"media_cleanup"`
-	MCP             MCPConfig          `json:"mcp"`
-	AppendFile      ToolConfig         `json:"append_file"                                              envPrefix:"PICOCLAW_TOOLS_APPEND_FILE_"`
-	EditFile        ToolConfig         `json:"edit_file"                                                envPrefix:"PICOCLAW_TOOLS_EDIT_FILE_"`
-	FindSkills      ToolConfig         `json:"find_skills"                                              envPrefix:"PICOCLAW_TOOLS_FIND_SKILLS_"`
-	I2C             ToolConfig         `json:"i2c"                                                      envPrefix:"PICOCLAW_TOOLS_I2C_"`
-	InstallSkill    ToolConfig         `json:"install_skill"                                            envPrefix:"PICOCLAW_TOOLS_INSTALL_SKILL_"`
-	ListDir         ToolConfig         `json:"list_dir"                                                 envPrefix:"PICOCLAW_TOOLS_LIST_DIR_"`
-	Message         ToolConfig         `json:"message"                                                  envPrefix:"PICOCLAW_TOOLS_MESSAGE_"`
-	ReadFile        ReadFileToolConfig `json:"read_file"                                                envPrefix:"PICOCLAW_TOOLS_READ_FILE_"`
-	SendFile        ToolConfig         `json:"send_file"                                                envPrefix:"PICOCLAW_TOOLS_SEND_FILE_"`
-	Spawn           ToolConfig         `json:"spawn"                                                    envPrefix:"PICOCLAW_TOOLS_SPAWN_"`
-	SpawnStatus     ToolConfig         `json:"spawn_status"                                             envPrefix:"PICOCLAW_TOOLS_SPAWN_STATUS_"`
-	SPI             ToolConfig         `json:"spi"                                                      envPrefix:"PICOCLAW_TOOLS_SPI_"`
-	Subagent        ToolConfig         `json:"subagent"                                                 envPrefix:"PICOCLAW_TOOLS_SUBAGENT_"`
-	WebFetch        ToolConfig         `json:"web_fetch"                                                envPrefix:"PICOCLAW_TOOLS_WEB_FETCH_"`
-	WriteFile       ToolConfig         `json:"write_file"                                               envPrefix:"PICOCLAW_TOOLS_WRITE_FILE_"`
+	AllowReadPaths  []string              `json:"allow_read_paths"  env:"PICOCLAW_TOOLS_ALLOW_READ_PATHS"`
+	AllowWritePaths []string              `json:"allow_write_paths" env:"PICOCLAW_TOOLS_ALLOW_WRITE_PATHS"`
+	Web             WebToolsConfig        `json:"web"`
+	Cron            CronToolsConfig       `json:"cron"`
+	Exec            ExecConfig            `json:"exec"`
+	Skills          SkillsToolsConfig     `json:"skills"`
+	MediaCleanup    MediaCleanupConfig    `json:"media_cleanup"`
+	MCP             MCPConfig             `json:"mcp"`
+	Trust           ToolTrustConfig       `json:"trust"`
+	AppendFile      ToolConfig            `json:"append_file"                                              envPrefix:"PICOCLAW_TOOLS_APPEND_FILE_"`
+	ApplyPatch      ToolConfig            `json:"apply_patch"                                              envPrefix:"PICOCLAW_TOOLS_APPLY_PATCH_"`
+	AskUser         ToolConfig            `json:"ask_user"                                                 envPrefix:"PICOCLAW_TOOLS_ASK_USER_"`
+	Browser         BrowserToolConfig     `json:"browser"                                                  envPrefix:"PICOCLAW_TOOLS_BROWSER_"`
+	EditFile        ToolConfig            `json:"edit_file"                                                envPrefix:"PICOCLAW_TOOLS_EDIT_FILE_"`
+	FindSkills      ToolConfig            `json:"find_skills"                                              envPrefix:"PICOCLAW_TOOLS_FIND_SKILLS_"`
+	GenerateImage   GenerateImageConfig   `json:"generate_image"                                           envPrefix:"PICOCLAW_TOOLS_GENERATE_IMAGE_"`
+	Git             GitToolConfig         `json:"git"                                                      envPrefix:"PICOCLAW_TOOLS_GIT_"`
+	Glob            ToolConfig            `json:"glob"                                                     envPrefix:"PICOCLAW_TOOLS_GLOB_"`
+	Grep            ToolConfig            `json:"grep"                                                     envPrefix:"PICOCLAW_TOOLS_GREP_"`
+	HTTPRequest     HTTPRequestToolConfig `json:"http_request"                                             envPrefix:"PICOCLAW_TOOLS_HTTP_REQUEST_"`
+	I2C             ToolConfig            `json:"i2c"                                                      envPrefix:"PICOCLAW_TOOLS_I2C_"`
+	InstallSkill    ToolConfig            `json:"install_skill"                                            envPrefix:"PICOCLAW_TOOLS_INSTALL_SKILL_"`
+	ListDir         ToolConfig            `json:"list_dir"                                                 envPrefix:"PICOCLAW_TOOLS_LIST_DIR_"`
+	Message         ToolConfig            `json:"message"                                                  envPrefix:"PICOCLAW_TOOLS_MESSAGE_"`
+	ReadDocument    ReadDocumentConfig    `json:"read_document"                                            envPrefix:"PICOCLAW_TOOLS_READ_DOCUMENT_"`
+	ReadFile        ReadFileToolConfig    `json:"read_file"                                                envPrefix:"PICOCLAW_TOOLS_READ_FILE_"`
+	SendFile        ToolConfig            `json:"send_file"                                                envPrefix:"PICOCLAW_TOOLS_SEND_FILE_"`
+	ShellSession    ShellSessionConfig    `json:"shell_session"                                            envPrefix:"PICOCLAW_TOOLS_SHELL_SESSION_"`
+	Spawn           ToolConfig            `json:"spawn"                                                    envPrefix:"PICOCLAW_TOOLS_SPAWN_"`
+	SpawnStatus     ToolConfig            `json:"spawn_status"                                             envPrefix:"PICOCLAW_TOOLS_SPAWN_STATUS_"`
+	SPI             ToolConfig            `json:"spi"                                                      envPrefix:"PICOCLAW_TOOLS_SPI_"`
+	Subagent        ToolConfig            `json:"subagent"                                                 envPrefix:"PICOCLAW_TOOLS_SUBAGENT_"`
+	WebFetch        ToolConfig            `json:"web_fetch"                                                envPrefix:"PICOCLAW_TOOLS_WEB_FETCH_"`
+	WriteFile       ToolConfig            `json:"write_file"                                               envPrefix:"PICOCLAW_TOOLS_WRITE_FILE_"`
 }
 
 type SearchCacheConfig struct {
@@ -832,6 +1122,36 @@ type MCPServerConfig struct {
 	URL string `json:"url,omitempty"`
 	// Headers are HTTP headers to send with requests (sse/http only)
 	Headers map[string]string `json:"headers,omitempty"`
+	// OAuth enables MCP OAuth authorization for the server (sse/http only)
+	OAuth *MCPOAuthConfig `json:"oauth,omitempty"`
+}
+
+// MCPOAuthConfig configures the MCP authorization flow for a remote server.
+// Tokens are obtained with `picoclaw auth mcp <server>` and kept in the auth
+// store. Without a ClientID the client registers itself dynamically.
+type MCPOAuthConfig struct {
+	Enabled      bool     `json:"enabled"`
+	ClientID     string   `json:"client_id,omitempty"`
+	ClientSecret string   `json:"client_secret,omitempty"`
+	Scopes       []string `json:"scopes,omitempty"`
+}
+
+// ToolTrustConfig controls the defense against prompt injection through tool
+// output. Output of untrusted tools (web, MCP, documents) is wrapped in a
+// delimited envelope, and once a turn has ingested such content, calls to
+// high-risk tools are handled according to Action.
+type ToolTrustConfig struct {
+	Enabled bool `json:"enabled" env:"PICOCLAW_TOOLS_TRUST_ENABLED"`
+	// Action is "warn" (log and allow, the default), "approve" (hold the
+	// call until the user sends /approve with its id) or "block" (refuse it)
+	Action string `json:"action" env:"PICOCLAW_TOOLS_TRUST_ACTION"`
+	// HighRiskTools are the tools the action applies to
+	HighRiskTools []string `json:"high_risk_tools" env:"PICOCLAW_TOOLS_TRUST_HIGH_RISK_TOOLS"`
+	// UntrustedTools marks additional tools (e.g. specific MCP tools) as
+	// returning untrusted output
+	UntrustedTools []string `json:"untrusted_tools" env:"PICOCLAW_TOOLS_TRUST_UNTRUSTED_TOOLS"`
+	// Patterns are extra regular expressions flagged as injection attempts
+	Patterns []string `json:"patterns"`
 }
 
 // MCPConfig defines configuration for all MCP servers
@@ -840,10 +1160,28 @@ type MCPConfig struct {
 	Discovery  ToolDiscoveryConfig `                                json:"discovery"`
 	// Servers is a map of server name to server configuration
 	Servers map[string]MCPServerConfig `json:"servers,omitempty"`
+	// Serve publishes allow-listed local tools and agents as an MCP server
+	Serve MCPServeConfig `json:"serve"`
+}
+
+// MCPServeConfig controls exposing picoclaw itself as an MCP server, over
+// stdio ("picoclaw mcp serve") or streamable HTTP on the gateway.
+type MCPServeConfig struct {
+	// Enabled mounts the HTTP endpoint on the gateway
+	Enabled bool `json:"enabled"          env:"PICOCLAW_TOOLS_MCP_SERVE_ENABLED"`
+	// Path is the gateway path of the HTTP endpoint
+	Path string `json:"path,omitempty"   env:"PICOCLAW_TOOLS_MCP_SERVE_PATH"`
+	// Token is the bearer token HTTP clients must send (supports file:// and enc://)
+	Token string `json:"token,omitempty"  env:"PICOCLAW_TOOLS_MCP_SERVE_TOKEN"`
+	// Tools lists the names of the default agent's tools to expose
+	Tools []string `json:"tools,omitempty"`
+	// Agents lists the IDs of agents to expose as chat_with_<id> tools
+	Agents []string `json:"agents,omitempty"`
 }
 
 func LoadConfig(path string) (*Config, error) {
 	cfg := DefaultConfig()
+	cfg.dir = filepath.Dir(path)
 
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -934,7 +1272,7 @@ func encryptPlaintextAPIKeys(models []ModelConfig, passphrase string) ([]ModelCo
 }
 
 // resolveAPIKeys decrypts or dereferences each api_key in models in-place.
-// Supports plaintext (no-op), file:// (read from configDir), and enc:// (AES-GCM decrypt).
+// Supports plaintext (no-op), file:// (read from configDir), and enc:// (decrypt).
 func resolveAPIKeys(models []ModelConfig, configDir string) error {
 	cr := credential.NewResolver(configDir)
 	for i := range models {
@@ -980,6 +1318,12 @@ func SaveConfig(path string, cfg *Config) error {
 	return fileutil.WriteFileAtomic(path, data, 0o600)
 }
 
+// CredentialResolver returns a resolver for secrets configured outside
+// model_list, such as http_request auth profiles.
+func (c *Config) CredentialResolver() *credential.Resolver {
+	return credential.NewResolver(c.dir)
+}
+
 func (c *Config) WorkspacePath() string {
 	return expandHome(c.Agents.Defaults.Workspace)
 }
@@ -1121,16 +1465,34 @@ func (t *ToolsConfig) IsToolEnabled(name string) bool {
 		return t.Cron.Enabled
 	case "exec":
 		return t.Exec.Enabled
+	case "shell_session":
+		return t.ShellSession.Enabled
 	case "skills":
 		return t.Skills.Enabled
 	case "media_cleanup":
 		return t.MediaCleanup.Enabled
 	case "append_file":
 		return t.AppendFile.Enabled
+	case "apply_patch":
+		return t.ApplyPatch.Enabled
+	case "ask_user":
+		return t.AskUser.Enabled
+	case "browser":
+		return t.Browser.Enabled
 	case "edit_file":
 		return t.EditFile.Enabled
 	case "find_skills":
 		return t.FindSkills.Enabled
+	case "generate_image":
+		return t.GenerateImage.Enabled
+	case "git":
+		return t.Git.Enabled
+	case "glob":
+		return t.Glob.Enabled
+	case "grep":
+		return t.Grep.Enabled
+	case "http_request":
+		return t.HTTPRequest.Enabled
 	case "i2c":
 		return t.I2C.Enabled
 	case "install_skill":
@@ -1139,6 +1501,8 @@ func (t *ToolsConfig) IsToolEnabled(name string) bool {
 		return t.ListDir.Enabled
 	case "message":
 		return t.Message.Enabled
+	case "read_document":
+		return t.ReadDocument.Enabled
 	case "read_file":
 		return t.ReadFile.Enabled
 	case "spawn":
@@ -175,6 +175,27 @@ func DefaultConfig() *Config {
 				MaxConnections: 100,
 				AllowFrom:      FlexibleStringSlice{},
 			},
+			Webhook: WebhookConfig{
+				Enabled:         false,
+				WebhookPath:     "/webhook/generic",
+				SignatureHeader: "X-Signature-256",
+				ContentPath:     "$.content",
+				SenderPath:      "$.sender",
+				ChatIDPath:      "$.chat_id",
+				OutboundRetries: 3,
+				OutboundTimeout: 10,
+				AllowFrom:       FlexibleStringSlice{},
+			},
+			MQTT: MQTTConfig{
+				Enabled:         false,
+				Broker:          "tcp://127.0.0.1:1883",
+				QoS:             1,
+				SubscribeTopics: FlexibleStringSlice{"picoclaw/+/in"},
+				ChatIDSegment:   1,
+				ResponseTopic:   "picoclaw/{chat_id}/out",
+				MaxMediaSize:    5 << 20,
+				AllowFrom:       FlexibleStringSlice{},
+			},
 		},
 		Providers: ProvidersConfig{
 			OpenAI: OpenAIProviderConfig{WebSearch: true},
@@ -463,6 +484,12 @@ func DefaultConfig() *Config {
 				EnableDenyPatterns: true,
 				AllowRemote:        true,
 				TimeoutSeconds:     60,
+				Sandbox: ExecSandboxConfig{
+					Enabled:      false,
+					MemoryMB:     512,
+					CPUs:         1,
+					MaxProcesses: 256,
+				},
 			},
 			Skills: SkillsToolsConfig{
 				ToolConfig: ToolConfig{
@@ -483,6 +510,47 @@ func DefaultConfig() *Config {
 			SendFile: ToolConfig{
 				Enabled: true,
 			},
+			GenerateImage: GenerateImageConfig{
+				ToolConfig: ToolConfig{
+					Enabled: false,
+				},
+				TimeoutSeconds: 120,
+			},
+			Glob: ToolConfig{
+				Enabled: true,
+			},
+			Grep: ToolConfig{
+				Enabled: true,
+			},
+			Git: GitToolConfig{
+				ToolConfig: ToolConfig{
+					Enabled: true,
+				},
+				TimeoutSeconds: 60,
+				MaxChars:       20000,
+				AuthorName:     "PicoClaw",
+				AuthorEmail:    "picoclaw@localhost",
+			},
+			HTTPRequest: HTTPRequestToolConfig{
+				ToolConfig: ToolConfig{
+					Enabled: true,
+				},
+				TimeoutSeconds:   30,
+				MaxResponseBytes: 1048576,
+			},
+			Browser: BrowserToolConfig{
+				TimeoutSeconds:     30,
+				IdleTimeoutMinutes: 10,
+				MaxChars:           50000,
+			},
+			ShellSession: ShellSessionConfig{
+				ToolConfig: ToolConfig{
+					Enabled: true,
+				},
+				MaxSessions:        4,
+				IdleTimeoutMinutes: 30,
+				BufferKB:           64,
+			},
 			MCP: MCPConfig{
 				ToolConfig: ToolConfig{
 					Enabled: false,
@@ -495,10 +563,30 @@ func DefaultConfig() *Config {
 					UseRegex:         false,
 				},
 				Servers: map[string]MCPServerConfig{},
+				Serve: MCPServeConfig{
+					Enabled: false,
+					Path:    "/mcp",
+				},
+			},
+			Trust: ToolTrustConfig{
+				Enabled: true,
+				Action:  "warn",
+				HighRiskTools: []string{
+					"exec", "shell_session", "write_file", "edit_file", "append_file", "apply_patch",
+					"git", "message", "send_file", "spawn", "subagent", "install_skill",
+				},
+				UntrustedTools: []string{},
+				Patterns:       []string{},
+			},
+			AskUser: ToolConfig{
+				Enabled: true,
 			},
 			AppendFile: ToolConfig{
 				Enabled: true,
 			},
+			ApplyPatch: ToolConfig{
+				Enabled: true,
+			},
 			EditFile: ToolConfig{
 				Enabled: true,
 			},
@@ -517,6 +605,12 @@ func DefaultConfig() *Config {
 			Message: ToolConfig{
 				Enabled: true,
 			},
+			ReadDocument: ReadDocumentConfig{
+				ToolConfig: ToolConfig{
+					Enabled: true,
+				},
+				MaxChars: 20000,
+			},
 			ReadFile: ReadFileToolConfig{
 				Enabled:         true,
 				MaxReadFileSize: 64 * 1024, // 64KB
@@ -550,6 +644,31 @@ func DefaultConfig() *Config {
 		},
 		Voice: VoiceConfig{
 			EchoTranscription: false,
+			TTS: TTSConfig{
+				Enabled:           false,
+				Provider:          "openai",
+				Model:             "tts-1",
+				Voice:             "alloy",
+				Format:            "opus",
+				ReplyWhenSpokenTo: true,
+				SendText:          true,
+			},
+		},
+		Redaction: RedactionConfig{
+			Enabled: true,
+			PII:     false,
+		},
+		Audit: AuditConfig{
+			Enabled:   false,
+			MaxSizeMB: 10,
+			MaxFiles:  0,
+		},
+		Permissions: PermissionsConfig{
+			Enabled:     false,
+			DefaultRole: "guest",
+		},
+		Egress: EgressConfig{
+			Enabled: false,
 		},
 		BuildInfo: BuildInfo{
 			Version:   Version,
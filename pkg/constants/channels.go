@@ -4,9 +4,10 @@ package constants
 // internalChannels defines channels that are used for internal communication
 // and should not be exposed to external users or recorded as last active channel.
 var internalChannels = map[string]struct{}{
-	"cli":      {},
-	"system":   {},
-	"subagent": {},
+	"cli":       {},
+	"system":    {},
+	"subagent":  {},
+	"mcp-stdio": {},
 }
 
 // IsInternalChannel returns true if the channel is an internal channel.
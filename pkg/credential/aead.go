@@ -0,0 +1,152 @@
+package credential
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// encV2Prefix marks the versioned format:
+//
+//	enc://v2:<base64( params[6] + salt[16] + nonce[24] + ciphertext )>
+//
+// params are the Argon2id cost parameters (memory KiB as uint32 big-endian,
+// iterations, threads), stored so they can be raised later without breaking
+// existing values. The version string and params are authenticated as
+// associated data.
+const encV2Prefix = encScheme + "v2:"
+
+const v2AAD = "picoclaw-credential-v2"
+
+// argon2Params are Argon2id cost parameters.
+type argon2Params struct {
+	MemoryKiB uint32
+	Time      uint8
+	Threads   uint8
+}
+
+const argon2ParamsLen = 6
+
+// defaultArgon2Params follow the OWASP minimum for Argon2id (19 MiB, 2
+// iterations, 1 thread), which keeps startup fast on small boards.
+var defaultArgon2Params = argon2Params{MemoryKiB: 19 * 1024, Time: 2, Threads: 1}
+
+// Upper bounds accepted when decrypting, so a crafted value cannot make
+// startup allocate gigabytes or spin for minutes.
+const (
+	maxArgon2MemoryKiB = 1024 * 1024
+	maxArgon2Time      = 16
+)
+
+func (p argon2Params) encode() []byte {
+	b := make([]byte, argon2ParamsLen)
+	binary.BigEndian.PutUint32(b, p.MemoryKiB)
+	b[4] = p.Time
+	b[5] = p.Threads
+	return b
+}
+
+func decodeArgon2Params(b []byte) (argon2Params, error) {
+	p := argon2Params{MemoryKiB: binary.BigEndian.Uint32(b), Time: b[4], Threads: b[5]}
+	if p.MemoryKiB < 8*uint32(p.Threads) || p.MemoryKiB > maxArgon2MemoryKiB ||
+		p.Time == 0 || p.Time > maxArgon2Time || p.Threads == 0 {
+		return argon2Params{}, fmt.Errorf("credential: enc://v2 invalid key derivation parameters")
+	}
+	return p, nil
+}
+
+// v2KeyCache remembers derived keys for the life of the process. Argon2id
+// is deliberately slow, and configs are loaded many times (gateway reloads,
+// launcher requests) with the same values.
+var v2KeyCache sync.Map // [sha256.Size]byte -> []byte
+
+func deriveKeyV2(passphrase, sshKeyPath string, salt []byte, p argon2Params) ([]byte, error) {
+	ikm, err := inputKeyMaterial(passphrase, sshKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	h.Write(ikm)
+	h.Write(salt)
+	h.Write(p.encode())
+	var cacheKey [sha256.Size]byte
+	copy(cacheKey[:], h.Sum(nil))
+	if key, ok := v2KeyCache.Load(cacheKey); ok {
+		return key.([]byte), nil
+	}
+
+	key := argon2.IDKey(ikm, salt, uint32(p.Time), p.MemoryKiB, p.Threads, keyLen)
+	v2KeyCache.Store(cacheKey, key)
+	return key, nil
+}
+
+func encryptV2(passphrase, sshKeyPath, plaintext string, p argon2Params) (string, error) {
+	salt := make([]byte, saltLen)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return "", fmt.Errorf("credential: failed to generate salt: %w", err)
+	}
+	key, err := deriveKeyV2(passphrase, sshKeyPath, salt, p)
+	if err != nil {
+		return "", err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return "", fmt.Errorf("credential: cipher init: %w", err)
+	}
+	nonce := make([]byte, chacha20poly1305.NonceSizeX)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("credential: failed to generate nonce: %w", err)
+	}
+
+	params := p.encode()
+	blob := make([]byte, 0, argon2ParamsLen+saltLen+len(nonce)+len(plaintext)+aead.Overhead())
+	blob = append(blob, params...)
+	blob = append(blob, salt...)
+	blob = append(blob, nonce...)
+	blob = aead.Seal(blob, nonce, []byte(plaintext), v2Associated(params))
+	return encV2Prefix + base64.StdEncoding.EncodeToString(blob), nil
+}
+
+func decryptV2(passphrase, sshKeyPath, b64 string) (string, error) {
+	blob, err := base64.StdEncoding.DecodeString(b64)
+	if err != nil {
+		return "", fmt.Errorf("credential: enc://v2 invalid base64: %w", err)
+	}
+	header := argon2ParamsLen + saltLen + chacha20poly1305.NonceSizeX
+	if len(blob) < header+chacha20poly1305.Overhead {
+		return "", fmt.Errorf("credential: enc://v2 payload too short")
+	}
+	params := blob[:argon2ParamsLen]
+	salt := blob[argon2ParamsLen : argon2ParamsLen+saltLen]
+	nonce := blob[argon2ParamsLen+saltLen : header]
+	ciphertext := blob[header:]
+
+	p, err := decodeArgon2Params(params)
+	if err != nil {
+		return "", err
+	}
+	key, err := deriveKeyV2(passphrase, sshKeyPath, salt, p)
+	if err != nil {
+		return "", err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return "", fmt.Errorf("credential: enc://v2 cipher init: %w", err)
+	}
+	plaintext, err := aead.Open(nil, nonce, ciphertext, v2Associated(params))
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrDecryptionFailed, err)
+	}
+	return string(plaintext), nil
+}
+
+func v2Associated(params []byte) []byte {
+	return append([]byte(v2AAD), params...)
+}
@@ -0,0 +1,146 @@
+package credential
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestSSHKey(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "picoclaw_ed25519.key")
+	if err := os.WriteFile(path, []byte("fake-ssh-key-material\n"), 0o600); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	t.Setenv(sshKeyEnv, path)
+	return path
+}
+
+// encryptLegacy produces a value in the pre-v2 AES-256-GCM format.
+func encryptLegacy(t *testing.T, passphrase, sshKeyPath, plaintext string) string {
+	t.Helper()
+	salt := make([]byte, saltLen)
+	nonce := make([]byte, nonceLen)
+	rand.Read(salt)
+	rand.Read(nonce)
+	key, err := deriveKey(passphrase, sshKeyPath, salt)
+	if err != nil {
+		t.Fatalf("deriveKey: %v", err)
+	}
+	block, _ := aes.NewCipher(key)
+	gcm, _ := cipher.NewGCM(block)
+	blob := append(append(salt, nonce...), gcm.Seal(nil, nonce, []byte(plaintext), nil)...)
+	return encScheme + base64.StdEncoding.EncodeToString(blob)
+}
+
+func TestEncrypt_WritesV2(t *testing.T) {
+	sshKey := writeTestSSHKey(t)
+
+	enc, err := Encrypt("passphrase", sshKey, "sk-secret")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if !strings.HasPrefix(enc, encV2Prefix) || IsLegacy(enc) {
+		t.Fatalf("Encrypt() = %q, want %s prefix", enc, encV2Prefix)
+	}
+	got, err := Decrypt("passphrase", sshKey, enc)
+	if err != nil || got != "sk-secret" {
+		t.Fatalf("Decrypt() = %q, %v", got, err)
+	}
+	if _, err := Decrypt("wrong", sshKey, enc); !errors.Is(err, ErrDecryptionFailed) {
+		t.Fatalf("Decrypt() with wrong passphrase error = %v, want ErrDecryptionFailed", err)
+	}
+}
+
+func TestDecrypt_LegacyFormat(t *testing.T) {
+	sshKey := writeTestSSHKey(t)
+	legacy := encryptLegacy(t, "passphrase", sshKey, "sk-legacy")
+	if !IsLegacy(legacy) {
+		t.Fatalf("IsLegacy(%q) = false", legacy)
+	}
+	got, err := Decrypt("passphrase", sshKey, legacy)
+	if err != nil || got != "sk-legacy" {
+		t.Fatalf("Decrypt(legacy) = %q, %v", got, err)
+	}
+}
+
+func TestDecryptV2_RejectsTampering(t *testing.T) {
+	sshKey := writeTestSSHKey(t)
+	enc, err := Encrypt("passphrase", sshKey, "sk-secret")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	blob, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(enc, encV2Prefix))
+
+	// Lowering the stored KDF cost must not go unnoticed.
+	weakened := append([]byte(nil), blob...)
+	weakened[4] = 1
+	if _, err := Decrypt("passphrase", sshKey, encV2Prefix+base64.StdEncoding.EncodeToString(weakened)); err == nil {
+		t.Fatal("Decrypt() accepted modified parameters")
+	}
+
+	flipped := append([]byte(nil), blob...)
+	flipped[len(flipped)-1] ^= 1
+	if _, err := Decrypt("passphrase", sshKey, encV2Prefix+base64.StdEncoding.EncodeToString(flipped)); !errors.Is(err, ErrDecryptionFailed) {
+		t.Fatalf("Decrypt() of modified ciphertext error = %v, want ErrDecryptionFailed", err)
+	}
+
+	huge := append([]byte(nil), blob...)
+	huge[0] = 0xff
+	if _, err := Decrypt("passphrase", sshKey, encV2Prefix+base64.StdEncoding.EncodeToString(huge)); err == nil ||
+		errors.Is(err, ErrDecryptionFailed) {
+		t.Fatalf("Decrypt() with excessive memory cost error = %v, want parameter error", err)
+	}
+}
+
+func TestRewriteEncrypted_ReencryptsAllValues(t *testing.T) {
+	sshKey := writeTestSSHKey(t)
+	v2, _ := Encrypt("old", sshKey, "sk-one")
+	legacy := encryptLegacy(t, "old", sshKey, "sk-two")
+	doc := []byte(`{
+  "model_list": [{"api_key": "` + v2 + `"}],
+  "tools": {"git": {"remotes": {"origin": {"token": "` + legacy + `"}}}},
+  "plain": "sk-plain"
+}`)
+
+	out, n, err := RewriteEncrypted(doc, func(value string) (string, error) {
+		return Reencrypt(value, "old", "", "new", "")
+	})
+	if err != nil {
+		t.Fatalf("RewriteEncrypted: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("rewrote %d values, want 2", n)
+	}
+	if !strings.Contains(string(out), `"plain": "sk-plain"`) || strings.Contains(string(out), legacy) {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+
+	var plaintexts []string
+	_, _, err = RewriteEncrypted(out, func(value string) (string, error) {
+		if IsLegacy(value) {
+			t.Errorf("value %q still in the legacy format", value)
+		}
+		p, err := Decrypt("new", "", value)
+		plaintexts = append(plaintexts, p)
+		return value, err
+	})
+	if err != nil {
+		t.Fatalf("decrypt with new passphrase: %v", err)
+	}
+	if strings.Join(plaintexts, ",") != "sk-one,sk-two" {
+		t.Fatalf("plaintexts = %v", plaintexts)
+	}
+
+	if _, _, err := RewriteEncrypted(doc, func(value string) (string, error) {
+		return Reencrypt(value, "wrong", "", "new", "")
+	}); err == nil {
+		t.Fatal("RewriteEncrypted() with wrong passphrase succeeded")
+	}
+}
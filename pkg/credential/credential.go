@@ -8,14 +8,16 @@
 //
 //   - Plaintext:   "sk-abc123"          → returned as-is
 //   - File ref:    "file://filename.key" → content read from configDir/filename.key
-//   - Encrypted:   "enc://<base64>"     → AES-256-GCM decrypt via PICOCLAW_KEY_PASSPHRASE
+//   - Encrypted:   "enc://v2:<base64>"  → XChaCha20-Poly1305 decrypt via PassphraseProvider
+//   - Legacy:      "enc://<base64>"     → AES-256-GCM decrypt (values written before v2)
 //   - Empty:       ""                   → returned as-is (auth_method=oauth etc.)
 //
-// Encryption uses AES-256-GCM with HKDF-SHA256 key derivation (< 1ms, safe for embedded Linux).
-// An SSH private key is required for both encryption and decryption.
-// Key derivation:
+// Encrypt always writes v2 values. Key derivation mixes the passphrase with
+// an SSH private key, which is required for both encryption and decryption:
 //
-//	HKDF-SHA256(ikm=HMAC-SHA256(SHA256(sshKeyBytes), passphrase), salt, info)
+//	ikm = HMAC-SHA256(SHA256(sshKeyBytes), passphrase)
+//	v2:     Argon2id(ikm, salt, params stored in the value)
+//	legacy: HKDF-SHA256(ikm, salt, info)
 //
 // SSH key path resolution priority:
 //
@@ -29,12 +31,10 @@ import (
 	"crypto/cipher"
 	"crypto/hkdf"
 	"crypto/hmac"
-	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
 	"errors"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -45,17 +45,16 @@ import (
 const PassphraseEnvVar = "PICOCLAW_KEY_PASSPHRASE"
 
 // PassphraseProvider is the function used to retrieve the passphrase for enc://
-// credential decryption. It defaults to reading PICOCLAW_KEY_PASSPHRASE from the
-// process environment. Replace it at startup to use a different source, such as
-// an in-memory SecureStore, so that all LoadConfig() calls everywhere share the
-// same passphrase source without needing os.Environ.
+// credential decryption. It defaults to DefaultPassphrase, which reads
+// PICOCLAW_KEY_PASSPHRASE and then the other configured sources. Replace it
+// at startup to use a different source, such as an in-memory SecureStore, so
+// that all LoadConfig() calls everywhere share the same passphrase source
+// without needing os.Environ.
 //
 // Example (launcher main.go):
 //
 //	credential.PassphraseProvider = apiHandler.passphraseStore.Get
-var PassphraseProvider func() string = func() string {
-	return os.Getenv(PassphraseEnvVar)
-}
+var PassphraseProvider func() string = DefaultPassphrase
 
 // ErrPassphraseRequired is returned when an enc:// credential is encountered but
 // no passphrase is available from PassphraseProvider. Callers can detect this
@@ -71,7 +70,7 @@ const (
 	encScheme  = "enc://"
 	hkdfInfo   = "picoclaw-credential-v1"
 	saltLen    = 16
-	nonceLen   = 12
+	nonceLen   = 12 // legacy AES-GCM nonce
 	keyLen     = 32
 	sshKeyEnv  = "PICOCLAW_SSH_KEY_PATH"
 )
@@ -151,10 +150,30 @@ func resolveEncrypted(raw string) (string, error) {
 	if passphrase == "" {
 		return "", ErrPassphraseRequired
 	}
+	return Decrypt(passphrase, "", raw)
+}
 
-	sshKeyPath := pickSSHKeyPath("") // override="": consult env then auto-detect
+// Decrypt decrypts an enc:// credential of any version.
+//
+// sshKeyPath is the SSH private key file to use; pass "" to auto-detect via
+// PICOCLAW_SSH_KEY_PATH env var or ~/.ssh/picoclaw_ed25519.key.
+func Decrypt(passphrase, sshKeyPath, raw string) (string, error) {
+	if passphrase == "" {
+		return "", ErrPassphraseRequired
+	}
+	sshKeyPath = pickSSHKeyPath(sshKeyPath)
+	if payload, ok := strings.CutPrefix(raw, encV2Prefix); ok {
+		return decryptV2(passphrase, sshKeyPath, payload)
+	}
+	if payload, ok := strings.CutPrefix(raw, encScheme); ok {
+		return decryptLegacy(passphrase, sshKeyPath, payload)
+	}
+	return "", fmt.Errorf("credential: %q is not an enc:// value", redactedPrefix(raw))
+}
 
-	b64 := strings.TrimPrefix(raw, encScheme)
+// decryptLegacy decrypts the original (pre-v2) format:
+// base64(salt[16] + nonce[12] + AES-256-GCM ciphertext).
+func decryptLegacy(passphrase, sshKeyPath, b64 string) (string, error) {
 	blob, err := base64.StdEncoding.DecodeString(b64)
 	if err != nil {
 		return "", fmt.Errorf("credential: enc:// invalid base64: %w", err)
@@ -187,7 +206,7 @@ func resolveEncrypted(raw string) (string, error) {
 	return string(plaintext), nil
 }
 
-// Encrypt encrypts plaintext and returns an enc:// credential string.
+// Encrypt encrypts plaintext and returns an enc://v2: credential string.
 //
 // passphrase is required (PICOCLAW_KEY_PASSPHRASE value).
 // sshKeyPath is the SSH private key file to use; pass "" to auto-detect via
@@ -197,37 +216,21 @@ func Encrypt(passphrase, sshKeyPath, plaintext string) (string, error) {
 	if passphrase == "" {
 		return "", fmt.Errorf("credential: passphrase must not be empty")
 	}
-	sshKeyPath = pickSSHKeyPath(sshKeyPath)
-
-	salt := make([]byte, saltLen)
-	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
-		return "", fmt.Errorf("credential: failed to generate salt: %w", err)
-	}
+	return encryptV2(passphrase, pickSSHKeyPath(sshKeyPath), plaintext, defaultArgon2Params)
+}
 
-	key, err := deriveKey(passphrase, sshKeyPath, salt)
-	if err != nil {
-		return "", err
-	}
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return "", fmt.Errorf("credential: cipher init: %w", err)
-	}
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return "", fmt.Errorf("credential: gcm init: %w", err)
-	}
+// IsLegacy reports whether raw is an enc:// value in the pre-v2 format.
+// "picoclaw credential rotate" upgrades such values.
+func IsLegacy(raw string) bool {
+	return strings.HasPrefix(raw, encScheme) && !strings.HasPrefix(raw, encV2Prefix)
+}
 
-	nonce := make([]byte, nonceLen)
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return "", fmt.Errorf("credential: failed to generate nonce: %w", err)
+// redactedPrefix returns the start of a secret for error messages.
+func redactedPrefix(raw string) string {
+	if len(raw) > 8 {
+		return raw[:8] + "…"
 	}
-
-	ciphertext := gcm.Seal(nil, nonce, []byte(plaintext), nil)
-	blob := make([]byte, 0, saltLen+nonceLen+len(ciphertext))
-	blob = append(blob, salt...)
-	blob = append(blob, nonce...)
-	blob = append(blob, ciphertext...)
-	return encScheme + base64.StdEncoding.EncodeToString(blob), nil
+	return raw
 }
 
 // isWithinDir reports whether path is contained within (or equal to) dir.
@@ -271,12 +274,28 @@ func allowedSSHKeyPath(path string) bool {
 	return false
 }
 
-// deriveKey derives a 32-byte AES-256 key from passphrase and SSH private key.
+// deriveKey derives the legacy 32-byte AES-256 key from passphrase and SSH
+// private key.
 //
-// ikm = HMAC-SHA256(key=SHA256(sshKeyBytes), msg=passphrase)
 // Final key: HKDF-SHA256(ikm, salt, info="picoclaw-credential-v1", 32 bytes)
-// sshKeyPath must be non-empty; returns an error otherwise.
 func deriveKey(passphrase, sshKeyPath string, salt []byte) ([]byte, error) {
+	ikm, err := inputKeyMaterial(passphrase, sshKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	key, err := hkdf.Key(sha256.New, ikm, salt, hkdfInfo, keyLen)
+	if err != nil {
+		return nil, fmt.Errorf("credential: HKDF expand failed: %w", err)
+	}
+	return key, nil
+}
+
+// inputKeyMaterial combines passphrase and SSH private key:
+//
+// ikm = HMAC-SHA256(key=SHA256(sshKeyBytes), msg=passphrase)
+//
+// sshKeyPath must be non-empty; returns an error otherwise.
+func inputKeyMaterial(passphrase, sshKeyPath string) ([]byte, error) {
 	if sshKeyPath == "" {
 		return nil, fmt.Errorf(
 			"credential: SSH private key is required but not found" +
@@ -295,13 +314,7 @@ func deriveKey(passphrase, sshKeyPath string, salt []byte) ([]byte, error) {
 	sshHash := sha256.Sum256(sshBytes)
 	mac := hmac.New(sha256.New, sshHash[:])
 	mac.Write([]byte(passphrase))
-	ikm := mac.Sum(nil)
-
-	key, err := hkdf.Key(sha256.New, ikm, salt, hkdfInfo, keyLen)
-	if err != nil {
-		return nil, fmt.Errorf("credential: HKDF expand failed: %w", err)
-	}
-	return key, nil
+	return mac.Sum(nil), nil
 }
 
 // pickSSHKeyPath returns the SSH private key path to use for encryption/decryption.
@@ -0,0 +1,34 @@
+//go:build linux
+
+package credential
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// readKeyring reads the "user" key named name from the session keyring
+// (which normally links the user keyring), falling back to the user keyring.
+func readKeyring(name string) (string, error) {
+	id, err := unix.KeyctlSearch(unix.KEY_SPEC_SESSION_KEYRING, "user", name, 0)
+	if err != nil {
+		id, err = unix.KeyctlSearch(unix.KEY_SPEC_USER_KEYRING, "user", name, 0)
+	}
+	if err != nil {
+		return "", fmt.Errorf("not found: %w", err)
+	}
+	size, err := unix.KeyctlBuffer(unix.KEYCTL_READ, id, nil, 0)
+	if err != nil {
+		return "", fmt.Errorf("read: %w", err)
+	}
+	buf := make([]byte, size)
+	n, err := unix.KeyctlBuffer(unix.KEYCTL_READ, id, buf, 0)
+	if err != nil {
+		return "", fmt.Errorf("read: %w", err)
+	}
+	if n > size {
+		n = size
+	}
+	return firstLine(buf[:n]), nil
+}
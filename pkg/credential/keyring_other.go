@@ -0,0 +1,9 @@
+//go:build !linux
+
+package credential
+
+import "errors"
+
+func readKeyring(string) (string, error) {
+	return "", errors.New("the kernel keyring is only supported on Linux")
+}
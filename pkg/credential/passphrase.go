@@ -0,0 +1,130 @@
+package credential
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Passphrase sources consulted by DefaultPassphrase, in order, after
+// PassphraseEnvVar.
+const (
+	// KeyringEnvVar names a "user" key in the Linux kernel keyring holding
+	// the passphrase, e.g. one added with `keyctl add user picoclaw ... @u`.
+	KeyringEnvVar = "PICOCLAW_KEY_KEYRING"
+	// PassphraseFileEnvVar is a file whose first line is the passphrase.
+	PassphraseFileEnvVar = "PICOCLAW_KEY_PASSPHRASE_FILE"
+	// PassphraseCommandEnvVar is a command whose first output line is the
+	// passphrase, e.g. "pass show picoclaw" or
+	// "age -d -i key.txt passphrase.age". Arguments are split on spaces.
+	PassphraseCommandEnvVar = "PICOCLAW_KEY_PASSPHRASE_COMMAND"
+)
+
+const passphraseCommandTimeout = 30 * time.Second
+
+// DefaultPassphrase returns the passphrase from the first configured source:
+// PICOCLAW_KEY_PASSPHRASE, the kernel keyring, a passphrase file, or a
+// passphrase command. It returns "" when none is configured. Errors from a
+// configured source are reported on stderr once.
+func DefaultPassphrase() string {
+	passphrase, _, err := ResolvePassphrase()
+	if err != nil {
+		warnPassphraseOnce(err)
+	}
+	return passphrase
+}
+
+// ResolvePassphrase is DefaultPassphrase with the name of the source that
+// supplied the passphrase and any error from a configured source.
+func ResolvePassphrase() (passphrase, source string, err error) {
+	if p := os.Getenv(PassphraseEnvVar); p != "" {
+		return p, PassphraseEnvVar, nil
+	}
+	if name := os.Getenv(KeyringEnvVar); name != "" {
+		p, err := readKeyring(name)
+		if err != nil {
+			return "", KeyringEnvVar, fmt.Errorf("credential: kernel keyring key %q: %w", name, err)
+		}
+		return p, KeyringEnvVar, nil
+	}
+	if path := os.Getenv(PassphraseFileEnvVar); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", PassphraseFileEnvVar, fmt.Errorf("credential: passphrase file: %w", err)
+		}
+		return firstLine(data), PassphraseFileEnvVar, nil
+	}
+	if command := strings.TrimSpace(os.Getenv(PassphraseCommandEnvVar)); command != "" {
+		p, err := runPassphraseCommand(command)
+		if err != nil {
+			return "", PassphraseCommandEnvVar, err
+		}
+		return p, PassphraseCommandEnvVar, nil
+	}
+	return "", "", nil
+}
+
+// firstLine returns the first line of data, as `pass` stores the password
+// on the first line and metadata below it.
+func firstLine(data []byte) string {
+	line, _, _ := bytes.Cut(data, []byte("\n"))
+	return strings.TrimRight(string(line), "\r")
+}
+
+type commandResult struct {
+	passphrase string
+	err        error
+}
+
+var (
+	commandMu    sync.Mutex
+	commandCache = map[string]commandResult{}
+)
+
+// runPassphraseCommand runs command once per process and caches the result,
+// since PassphraseProvider is called for every enc:// value and commands
+// such as `pass` may prompt for a GPG PIN.
+func runPassphraseCommand(command string) (string, error) {
+	commandMu.Lock()
+	defer commandMu.Unlock()
+	if r, ok := commandCache[command]; ok {
+		return r.passphrase, r.err
+	}
+
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return "", errors.New("credential: passphrase command is empty")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), passphraseCommandTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+
+	var r commandResult
+	if err != nil {
+		r.err = fmt.Errorf("credential: passphrase command %q: %w", args[0], err)
+	} else {
+		r.passphrase = firstLine(bytes.TrimLeft(out, "\n"))
+		if r.passphrase == "" {
+			r.err = fmt.Errorf("credential: passphrase command %q printed nothing", args[0])
+		}
+	}
+	commandCache[command] = r
+	return r.passphrase, r.err
+}
+
+var warnedPassphrase sync.Map // error string -> struct{}
+
+func warnPassphraseOnce(err error) {
+	if _, loaded := warnedPassphrase.LoadOrStore(err.Error(), struct{}{}); !loaded {
+		fmt.Fprintf(os.Stderr, "picoclaw: warning: %v\n", err)
+	}
+}
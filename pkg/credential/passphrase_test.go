@@ -0,0 +1,66 @@
+package credential
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func clearPassphraseSources(t *testing.T) {
+	t.Helper()
+	for _, name := range []string{PassphraseEnvVar, KeyringEnvVar, PassphraseFileEnvVar, PassphraseCommandEnvVar} {
+		t.Setenv(name, "")
+	}
+}
+
+func TestResolvePassphrase_Order(t *testing.T) {
+	clearPassphraseSources(t)
+	if p, source, err := ResolvePassphrase(); p != "" || source != "" || err != nil {
+		t.Fatalf("ResolvePassphrase() = %q, %q, %v; want nothing configured", p, source, err)
+	}
+
+	file := filepath.Join(t.TempDir(), "passphrase")
+	if err := os.WriteFile(file, []byte("from-file\nlogin: picoclaw\n"), 0o600); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	t.Setenv(PassphraseFileEnvVar, file)
+	if p, source, err := ResolvePassphrase(); p != "from-file" || source != PassphraseFileEnvVar || err != nil {
+		t.Fatalf("ResolvePassphrase() = %q, %q, %v; want first line of file", p, source, err)
+	}
+
+	t.Setenv(PassphraseEnvVar, "from-env")
+	if p, source, _ := ResolvePassphrase(); p != "from-env" || source != PassphraseEnvVar {
+		t.Fatalf("ResolvePassphrase() = %q, %q; env var should win", p, source)
+	}
+}
+
+func TestResolvePassphrase_MissingFile(t *testing.T) {
+	clearPassphraseSources(t)
+	t.Setenv(PassphraseFileEnvVar, filepath.Join(t.TempDir(), "missing"))
+	if _, _, err := ResolvePassphrase(); err == nil {
+		t.Fatal("ResolvePassphrase() with missing file returned no error")
+	}
+}
+
+func TestResolvePassphrase_Command(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses echo from the shell environment")
+	}
+	clearPassphraseSources(t)
+	t.Setenv(PassphraseCommandEnvVar, "echo from-command")
+	if p, source, err := ResolvePassphrase(); p != "from-command" || source != PassphraseCommandEnvVar || err != nil {
+		t.Fatalf("ResolvePassphrase() = %q, %q, %v", p, source, err)
+	}
+}
+
+func TestResolvePassphrase_BlankCommand(t *testing.T) {
+	clearPassphraseSources(t)
+	t.Setenv(PassphraseCommandEnvVar, "   ")
+	if p, source, err := ResolvePassphrase(); p != "" || source != "" || err != nil {
+		t.Fatalf("ResolvePassphrase() = %q, %q, %v; want a blank command to be ignored", p, source, err)
+	}
+	if _, err := runPassphraseCommand(" \t"); err == nil {
+		t.Error("expected an error for a command without fields")
+	}
+}
@@ -0,0 +1,49 @@
+package credential
+
+import (
+	"regexp"
+)
+
+// encValuePattern matches enc:// JSON string values. Encrypted values are
+// base64 and never contain quotes or escapes.
+var encValuePattern = regexp.MustCompile(`"enc://[A-Za-z0-9+/=:]*"`)
+
+// RewriteEncrypted calls rewrite for every enc:// string in the JSON
+// document data and returns the document with the values replaced. The rest
+// of the document, including formatting and key order, is left untouched.
+// It returns the number of values rewritten, or the first error from
+// rewrite, in which case data should not be written back.
+func RewriteEncrypted(data []byte, rewrite func(value string) (string, error)) ([]byte, int, error) {
+	var (
+		count    int
+		firstErr error
+	)
+	out := encValuePattern.ReplaceAllFunc(data, func(match []byte) []byte {
+		if firstErr != nil {
+			return match
+		}
+		value := string(match[1 : len(match)-1])
+		replaced, err := rewrite(value)
+		if err != nil {
+			firstErr = err
+			return match
+		}
+		count++
+		return []byte(`"` + replaced + `"`)
+	})
+	if firstErr != nil {
+		return nil, 0, firstErr
+	}
+	return out, count, nil
+}
+
+// Reencrypt decrypts value with the old passphrase and SSH key and encrypts
+// it again, in the current format, with the new ones. An empty SSH key path
+// auto-detects the key as Encrypt does.
+func Reencrypt(value, oldPassphrase, oldSSHKeyPath, newPassphrase, newSSHKeyPath string) (string, error) {
+	plaintext, err := Decrypt(oldPassphrase, oldSSHKeyPath, value)
+	if err != nil {
+		return "", err
+	}
+	return Encrypt(newPassphrase, newSSHKeyPath, plaintext)
+}
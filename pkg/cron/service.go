@@ -30,6 +30,9 @@ type CronPayload struct {
 	Deliver bool   `json:"deliver"`
 	Channel string `json:"channel,omitempty"`
 	To      string `json:"to,omitempty"`
+	// CreatedBy is the canonical ID of the sender who scheduled the job; the
+	// job runs with their role.
+	CreatedBy string `json:"createdBy,omitempty"`
 }
 
 type CronJobState struct {
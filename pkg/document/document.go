@@ -0,0 +1,266 @@
+// Package document converts common document formats into paginated plain
+// text so they can be handed to the model.
+//
+// Supported formats are PDF, DOCX, PPTX, XLSX, CSV/TSV, EPUB and HTML. All
+// parsing is done in pure Go so the binary stays self-contained. Formats with
+// natural page boundaries keep them (PDF pages, slides, sheets, EPUB
+// chapters); flowing text is split into pages of roughly PageChars runes at
+// paragraph boundaries.
+package document
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/h2non/filetype"
+)
+
+// Format identifies a supported document format.
+type Format string
+
+const (
+	FormatPDF  Format = "pdf"
+	FormatDOCX Format = "docx"
+	FormatPPTX Format = "pptx"
+	FormatXLSX Format = "xlsx"
+	FormatCSV  Format = "csv"
+	FormatTSV  Format = "tsv"
+	FormatEPUB Format = "epub"
+	FormatHTML Format = "html"
+)
+
+// PageChars is the approximate page size, in runes, used when a format has no
+// page boundaries of its own.
+const PageChars = 4000
+
+// MaxFileSize is the largest document callers should hand to Extract.
+const MaxFileSize = 50 << 20
+
+// ErrUnsupported is returned for files whose format is not recognized.
+var ErrUnsupported = errors.New("unsupported document format")
+
+// Page is one page of extracted text.
+type Page struct {
+	Number int    // 1-based
+	Label  string // optional, e.g. sheet name or chapter title
+	Text   string
+}
+
+// Document is the text extracted from a file.
+type Document struct {
+	Format Format
+	Title  string
+	Author string
+	// Unit names what a page is for this format: "page", "slide", "sheet"
+	// or "chapter".
+	Unit  string
+	Pages []Page
+}
+
+var extensionFormats = map[string]Format{
+	".pdf":   FormatPDF,
+	".docx":  FormatDOCX,
+	".pptx":  FormatPPTX,
+	".xlsx":  FormatXLSX,
+	".xlsm":  FormatXLSX,
+	".csv":   FormatCSV,
+	".tsv":   FormatTSV,
+	".epub":  FormatEPUB,
+	".html":  FormatHTML,
+	".htm":   FormatHTML,
+	".xhtml": FormatHTML,
+}
+
+var mimeFormats = map[string]Format{
+	"application/pdf": FormatPDF,
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document":   FormatDOCX,
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": FormatPPTX,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         FormatXLSX,
+	"text/csv":                  FormatCSV,
+	"text/tab-separated-values": FormatTSV,
+	"application/epub+zip":      FormatEPUB,
+	"text/html":                 FormatHTML,
+	"application/xhtml+xml":     FormatHTML,
+}
+
+// Detect returns the format of a file from its name or MIME type, or "" when
+// the format is not supported. The extension wins over the MIME type because
+// channels often report office files as application/zip or
+// application/octet-stream.
+func Detect(name, mime string) Format {
+	if f, ok := extensionFormats[strings.ToLower(filepath.Ext(name))]; ok {
+		return f
+	}
+	mime = strings.ToLower(strings.TrimSpace(mime))
+	if i := strings.IndexByte(mime, ';'); i >= 0 {
+		mime = strings.TrimSpace(mime[:i])
+	}
+	return mimeFormats[mime]
+}
+
+// Sniff detects the format of data from its magic bytes, for files whose
+// name carries no usable extension.
+func Sniff(data []byte) Format {
+	kind, err := filetype.Match(data)
+	if err != nil || kind == filetype.Unknown {
+		return ""
+	}
+	return mimeFormats[kind.MIME.Value]
+}
+
+// Extract converts data in the given format into a Document.
+func Extract(format Format, data []byte) (doc *Document, err error) {
+	// The parsers walk untrusted input; a malformed file must not take the
+	// agent down with it.
+	defer func() {
+		if r := recover(); r != nil {
+			doc, err = nil, fmt.Errorf("malformed %s document: %v", format, r)
+		}
+	}()
+
+	switch format {
+	case FormatPDF:
+		doc, err = extractPDF(data)
+	case FormatDOCX:
+		doc, err = extractDOCX(data)
+	case FormatPPTX:
+		doc, err = extractPPTX(data)
+	case FormatXLSX:
+		doc, err = extractXLSX(data)
+	case FormatCSV:
+		doc, err = extractCSV(data, ',')
+	case FormatTSV:
+		doc, err = extractCSV(data, '\t')
+	case FormatEPUB:
+		doc, err = extractEPUB(data)
+	case FormatHTML:
+		doc, err = extractHTML(data)
+	default:
+		return nil, ErrUnsupported
+	}
+	if err != nil {
+		return nil, err
+	}
+	doc.Format = format
+	if doc.Unit == "" {
+		doc.Unit = "page"
+	}
+	for i := range doc.Pages {
+		doc.Pages[i].Number = i + 1
+	}
+	return doc, nil
+}
+
+// Text renders pages from..to (1-based, inclusive) with a separator line
+// before each page. Pages outside the document are ignored.
+func (d *Document) Text(from, to int) string {
+	var sb strings.Builder
+	for _, p := range d.Pages {
+		if p.Number < from || p.Number > to {
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteString("\n\n")
+		}
+		fmt.Fprintf(&sb, "--- %s %d", d.Unit, p.Number)
+		if p.Label != "" {
+			fmt.Fprintf(&sb, ": %s", p.Label)
+		}
+		sb.WriteString(" ---\n")
+		sb.WriteString(p.Text)
+	}
+	return sb.String()
+}
+
+// Summary describes the document in one line, e.g.
+// `PDF, 12 pages, title "Quarterly report"`.
+func (d *Document) Summary() string {
+	unit := d.Unit
+	if len(d.Pages) != 1 {
+		unit += "s"
+	}
+	s := fmt.Sprintf("%s, %d %s", strings.ToUpper(string(d.Format)), len(d.Pages), unit)
+	if d.Title != "" {
+		s += fmt.Sprintf(", title %q", d.Title)
+	}
+	if d.Author != "" {
+		s += fmt.Sprintf(", author %q", d.Author)
+	}
+	return s
+}
+
+// paginate splits flowing text into pages of about PageChars runes, breaking
+// at paragraph or line boundaries where possible. Form feeds force a break.
+func paginate(text string) []Page {
+	var pages []Page
+	for _, section := range strings.Split(text, "\f") {
+		for _, chunk := range splitChunks(cleanText(section), PageChars) {
+			pages = append(pages, Page{Text: chunk})
+		}
+	}
+	return pages
+}
+
+func splitChunks(text string, size int) []string {
+	var chunks []string
+	for text != "" {
+		if utf8.RuneCountInString(text) <= size {
+			chunks = append(chunks, text)
+			break
+		}
+		// Byte offset of the size-th rune.
+		limit := 0
+		for n := 0; n < size; n++ {
+			_, w := utf8.DecodeRuneInString(text[limit:])
+			limit += w
+		}
+		cut := strings.LastIndex(text[:limit], "\n\n")
+		if cut < limit/2 {
+			cut = strings.LastIndex(text[:limit], "\n")
+		}
+		if cut < limit/2 {
+			cut = strings.LastIndex(text[:limit], " ")
+		}
+		if cut < limit/2 {
+			cut = limit
+		}
+		chunks = append(chunks, strings.TrimSpace(text[:cut]))
+		text = strings.TrimSpace(text[cut:])
+	}
+	return chunks
+}
+
+// cleanText trims trailing spaces from lines and collapses runs of blank
+// lines left behind by the XML walkers.
+func cleanText(s string) string {
+	lines := strings.Split(s, "\n")
+	out := lines[:0]
+	blank := 0
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			blank++
+			if blank > 1 {
+				continue
+			}
+		} else {
+			blank = 0
+		}
+		out = append(out, line)
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
+
+// formatRow renders table cells on one line, dropping trailing empty cells.
+func formatRow(cells []string) string {
+	for len(cells) > 0 && strings.TrimSpace(cells[len(cells)-1]) == "" {
+		cells = cells[:len(cells)-1]
+	}
+	for i, c := range cells {
+		cells[i] = strings.Join(strings.Fields(c), " ")
+	}
+	return strings.Join(cells, " | ")
+}
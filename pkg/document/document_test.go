@@ -0,0 +1,246 @@
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func buildZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// buildPDF assembles a PDF with one page per content stream, computing the
+// xref offsets so the reader accepts it.
+func buildPDF(pages ...string) []byte {
+	widths := strings.TrimSpace(strings.Repeat("500 ", 95))
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"", // page tree, filled below
+		"<< /Title (Test Report) /Author (Jane Doe) >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /FirstChar 32 /LastChar 126 /Widths [" + widths + "] >>",
+	}
+	var kids []string
+	for _, content := range pages {
+		contentID := len(objects) + 1
+		objects = append(objects, fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+		pageID := len(objects) + 1
+		objects = append(objects, fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 4 0 R >> >> /Contents %d 0 R >>",
+			contentID))
+		kids = append(kids, fmt.Sprintf("%d 0 R", pageID))
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids))
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info 3 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func TestExtractPDF(t *testing.T) {
+	data := buildPDF(
+		"BT /F1 12 Tf 72 720 Td (Hello) Tj 40 0 Td (world) Tj 0 -14 Td (Second line) Tj ET",
+		"BT /F1 12 Tf 72 720 Td (Page two) Tj ET",
+	)
+	doc, err := Extract(FormatPDF, data)
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if doc.Title != "Test Report" || doc.Author != "Jane Doe" || len(doc.Pages) != 2 {
+		t.Fatalf("unexpected document: %+v", doc)
+	}
+	if got := doc.Pages[0].Text; got != "Hello world\nSecond line" {
+		t.Errorf("page 1 = %q", got)
+	}
+	if got := doc.Pages[1].Text; got != "Page two" {
+		t.Errorf("page 2 = %q", got)
+	}
+	if got := doc.Summary(); got != `PDF, 2 pages, title "Test Report", author "Jane Doe"` {
+		t.Errorf("Summary() = %q", got)
+	}
+}
+
+func TestExtractDOCX(t *testing.T) {
+	data := buildZip(t, map[string]string{
+		"docProps/core.xml": `<cp:coreProperties xmlns:cp="x" xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:title>Spec</dc:title><dc:creator>Ann</dc:creator></cp:coreProperties>`,
+		"word/document.xml": `<w:document xmlns:w="w"><w:body>
+			<w:p><w:pPr><w:tabs><w:tab w:val="left" w:pos="720"/></w:tabs></w:pPr><w:r><w:t>Intro</w:t><w:tab/><w:t xml:space="preserve">text</w:t></w:r></w:p>
+			<w:tbl><w:tr><w:tc><w:p><w:r><w:t>a1</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>b1</w:t></w:r></w:p></w:tc></w:tr></w:tbl>
+			<w:p><w:r><w:br w:type="page"/><w:t>After break</w:t></w:r></w:p>
+		</w:body></w:document>`,
+	})
+	doc, err := Extract(FormatDOCX, data)
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if doc.Title != "Spec" || doc.Author != "Ann" || len(doc.Pages) != 2 {
+		t.Fatalf("unexpected document: %+v", doc)
+	}
+	if got := doc.Pages[0].Text; got != "Intro\ttext\na1 | b1" {
+		t.Errorf("page 1 = %q", got)
+	}
+	if got := doc.Pages[1].Text; got != "After break" {
+		t.Errorf("page 2 = %q", got)
+	}
+}
+
+func TestExtractPPTX(t *testing.T) {
+	slide := func(text string) string {
+		return `<p:sld xmlns:p="p" xmlns:a="a"><p:cSld><p:spTree><p:sp><p:txBody><a:p><a:r><a:t>` + text + `</a:t></a:r></a:p></p:txBody></p:sp></p:spTree></p:cSld></p:sld>`
+	}
+	data := buildZip(t, map[string]string{
+		"ppt/presentation.xml":            `<p:presentation xmlns:p="p" xmlns:r="r"><p:sldIdLst><p:sldId id="256" r:id="rId3"/><p:sldId id="257" r:id="rId2"/></p:sldIdLst></p:presentation>`,
+		"ppt/_rels/presentation.xml.rels": `<Relationships><Relationship Id="rId2" Target="slides/slide1.xml"/><Relationship Id="rId3" Target="slides/slide2.xml"/></Relationships>`,
+		"ppt/slides/slide1.xml":           slide("Second in deck"),
+		"ppt/slides/slide2.xml":           slide("First in deck"),
+	})
+	doc, err := Extract(FormatPPTX, data)
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if len(doc.Pages) != 2 || doc.Pages[0].Text != "First in deck" || doc.Pages[1].Text != "Second in deck" {
+		t.Fatalf("slides should follow presentation order: %+v", doc.Pages)
+	}
+	if !strings.HasPrefix(doc.Text(2, 2), "--- slide 2 ---\n") {
+		t.Errorf("Text(2, 2) = %q", doc.Text(2, 2))
+	}
+}
+
+func TestExtractXLSX(t *testing.T) {
+	data := buildZip(t, map[string]string{
+		"xl/workbook.xml":            `<workbook xmlns:r="r"><sheets><sheet name="Budget" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships><Relationship Id="rId1" Target="/xl/worksheets/sheet1.xml"/></Relationships>`,
+		"xl/sharedStrings.xml":       `<sst><si><t>Item</t></si><si><r><t>Co</t></r><r><t>st</t></r><rPh><t>x</t></rPh></si></sst>`,
+		"xl/worksheets/sheet1.xml": `<worksheet><sheetData>
+			<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row>
+			<row r="2"><c r="A2" t="inlineStr"><is><t>Rent</t></is></c><c r="C2"><v>1200</v></c><c r="D2" t="b"><v>1</v></c></row>
+		</sheetData></worksheet>`,
+	})
+	doc, err := Extract(FormatXLSX, data)
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if len(doc.Pages) != 1 || doc.Pages[0].Label != "Budget" {
+		t.Fatalf("unexpected sheets: %+v", doc.Pages)
+	}
+	if got := doc.Pages[0].Text; got != "Item | Cost\nRent |  | 1200 | TRUE" {
+		t.Errorf("sheet text = %q", got)
+	}
+}
+
+func TestExtractEPUB(t *testing.T) {
+	data := buildZip(t, map[string]string{
+		"META-INF/container.xml": `<container><rootfiles><rootfile full-path="OEBPS/content.opf"/></rootfiles></container>`,
+		"OEBPS/content.opf": `<package xmlns:dc="dc"><metadata><dc:title>A Book</dc:title><dc:creator>Author</dc:creator></metadata>
+			<manifest><item id="cover" href="cover.xhtml"/><item id="c1" href="text/chapter%201.xhtml"/></manifest>
+			<spine><itemref idref="cover"/><itemref idref="c1"/></spine></package>`,
+		"OEBPS/cover.xhtml":          `<html><body><img src="cover.jpg"/></body></html>`,
+		"OEBPS/text/chapter 1.xhtml": `<html><head><title>Chapter One</title></head><body><h1>Beginning</h1><p>It was a dark night.</p></body></html>`,
+	})
+	doc, err := Extract(FormatEPUB, data)
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if doc.Title != "A Book" || doc.Author != "Author" || len(doc.Pages) != 1 {
+		t.Fatalf("unexpected document: %+v", doc)
+	}
+	if p := doc.Pages[0]; p.Label != "Chapter One" || !strings.Contains(p.Text, "It was a dark night.") {
+		t.Errorf("chapter = %+v", p)
+	}
+}
+
+func TestExtractHTMLAndCSV(t *testing.T) {
+	doc, err := Extract(FormatHTML, []byte(`<html><head><title> Release  notes </title></head><body><h2>Fixes</h2><ul><li>One</li></ul></body></html>`))
+	if err != nil {
+		t.Fatalf("Extract html: %v", err)
+	}
+	if doc.Title != "Release notes" || len(doc.Pages) != 1 || !strings.Contains(doc.Pages[0].Text, "Fixes") {
+		t.Errorf("unexpected html document: %+v", doc)
+	}
+
+	doc, err = Extract(FormatCSV, []byte("\xef\xbb\xbfname,qty\n\"Widget, large\",3\n"))
+	if err != nil {
+		t.Fatalf("Extract csv: %v", err)
+	}
+	if got := doc.Pages[0].Text; got != "name | qty\nWidget, large | 3" {
+		t.Errorf("csv text = %q", got)
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	para := strings.Repeat("word ", 300) // 1500 runes
+	text := strings.Join([]string{para, para, para, para}, "\n\n") + "\fnext section"
+	pages := paginate(text)
+	if len(pages) != 3 {
+		t.Fatalf("expected 3 pages, got %d", len(pages))
+	}
+	for _, p := range pages[:2] {
+		if len(p.Text) > PageChars || strings.HasPrefix(p.Text, " ") {
+			t.Errorf("page not split at a paragraph boundary: %d runes", len(p.Text))
+		}
+	}
+	if pages[2].Text != "next section" {
+		t.Errorf("form feed should start a new page, got %q", pages[2].Text)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	for _, tc := range []struct {
+		name, mime string
+		want       Format
+	}{
+		{"Report.PDF", "", FormatPDF},
+		{"sheet.xlsx", "application/zip", FormatXLSX},
+		{"upload", "text/csv; charset=utf-8", FormatCSV},
+		{"notes.bin", "application/octet-stream", ""},
+	} {
+		if got := Detect(tc.name, tc.mime); got != tc.want {
+			t.Errorf("Detect(%q, %q) = %q, want %q", tc.name, tc.mime, got, tc.want)
+		}
+	}
+
+	if got := Sniff(buildPDF("BT ET")); got != FormatPDF {
+		t.Errorf("Sniff(pdf) = %q", got)
+	}
+	if got := Sniff([]byte("plain text")); got != "" {
+		t.Errorf("Sniff(text) = %q", got)
+	}
+}
+
+func TestExtract_Malformed(t *testing.T) {
+	if _, err := Extract(FormatDOCX, []byte("not a zip")); err == nil {
+		t.Error("expected malformed docx to fail")
+	}
+	if _, err := Extract(FormatPDF, []byte("%PDF-1.4 garbage")); err == nil {
+		t.Error("expected malformed pdf to fail")
+	}
+	if _, err := Extract("rtf", nil); err != ErrUnsupported {
+		t.Errorf("expected ErrUnsupported, got %v", err)
+	}
+}
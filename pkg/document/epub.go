@@ -0,0 +1,88 @@
+package document
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var markdownImage = regexp.MustCompile(`!\[[^\]]*\]\([^)]*\)`)
+
+func extractEPUB(data []byte) (*Document, error) {
+	z, err := openZip(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var container struct {
+		Rootfiles []struct {
+			FullPath string `xml:"full-path,attr"`
+		} `xml:"rootfiles>rootfile"`
+	}
+	if err := z.unmarshal("META-INF/container.xml", &container); err != nil {
+		return nil, err
+	}
+	if len(container.Rootfiles) == 0 {
+		return nil, fmt.Errorf("epub has no rootfile")
+	}
+	opfPath := container.Rootfiles[0].FullPath
+
+	var pkg struct {
+		Title   []string `xml:"metadata>title"`
+		Creator []string `xml:"metadata>creator"`
+		Items   []struct {
+			ID   string `xml:"id,attr"`
+			Href string `xml:"href,attr"`
+		} `xml:"manifest>item"`
+		Spine []struct {
+			IDRef string `xml:"idref,attr"`
+		} `xml:"spine>itemref"`
+	}
+	if err := z.unmarshal(opfPath, &pkg); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", opfPath, err)
+	}
+
+	doc := &Document{Unit: "chapter"}
+	if len(pkg.Title) > 0 {
+		doc.Title = strings.TrimSpace(pkg.Title[0])
+	}
+	if len(pkg.Creator) > 0 {
+		doc.Author = strings.TrimSpace(pkg.Creator[0])
+	}
+
+	hrefs := make(map[string]string, len(pkg.Items))
+	for _, item := range pkg.Items {
+		hrefs[item.ID] = item.Href
+	}
+	base := path.Dir(opfPath)
+	for _, ref := range pkg.Spine {
+		href, ok := hrefs[ref.IDRef]
+		if !ok {
+			continue
+		}
+		// Hrefs are URL-encoded relative references.
+		href, _, _ = strings.Cut(href, "#")
+		if unescaped, err := url.PathUnescape(href); err == nil {
+			href = unescaped
+		}
+		content, err := z.read(path.Join(base, href))
+		if err != nil {
+			continue
+		}
+		title, text := htmlText(content)
+		if strings.TrimSpace(markdownImage.ReplaceAllString(text, "")) == "" {
+			// Cover and spacer pages carry no text.
+			continue
+		}
+		for i, chunk := range splitChunks(text, PageChars) {
+			page := Page{Label: title, Text: chunk}
+			if i > 0 && title != "" {
+				page.Label = title + " (cont.)"
+			}
+			doc.Pages = append(doc.Pages, page)
+		}
+	}
+	return doc, nil
+}
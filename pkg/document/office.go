@@ -0,0 +1,402 @@
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// maxPartSize bounds how much a single archive member may inflate to, so a
+// zip bomb cannot exhaust memory.
+const maxPartSize = 4 * MaxFileSize
+
+type zipArchive struct {
+	files map[string]*zip.File
+}
+
+func openZip(data []byte) (*zipArchive, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("not a valid zip container: %w", err)
+	}
+	z := &zipArchive{files: make(map[string]*zip.File, len(zr.File))}
+	for _, f := range zr.File {
+		z.files[strings.TrimPrefix(f.Name, "/")] = f
+	}
+	return z, nil
+}
+
+func (z *zipArchive) read(name string) ([]byte, error) {
+	f, ok := z.files[strings.TrimPrefix(name, "/")]
+	if !ok {
+		return nil, fmt.Errorf("missing %s", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, maxPartSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxPartSize {
+		return nil, fmt.Errorf("%s is too large", name)
+	}
+	return data, nil
+}
+
+func (z *zipArchive) unmarshal(name string, v any) error {
+	data, err := z.read(name)
+	if err != nil {
+		return err
+	}
+	return xml.Unmarshal(data, v)
+}
+
+// rels returns the relationship targets of an OOXML part keyed by ID,
+// resolved to archive paths.
+func (z *zipArchive) rels(part string) map[string]string {
+	var doc struct {
+		Rels []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	dir, file := path.Split(part)
+	if err := z.unmarshal(path.Join(dir, "_rels", file+".rels"), &doc); err != nil {
+		return nil
+	}
+	out := make(map[string]string, len(doc.Rels))
+	for _, r := range doc.Rels {
+		if strings.HasPrefix(r.Target, "/") {
+			out[r.ID] = strings.TrimPrefix(r.Target, "/")
+		} else {
+			out[r.ID] = path.Join(dir, r.Target)
+		}
+	}
+	return out
+}
+
+// coreProperties reads the title and author from docProps/core.xml.
+func (z *zipArchive) coreProperties(doc *Document) {
+	var core struct {
+		Title   string `xml:"title"`
+		Creator string `xml:"creator"`
+	}
+	if z.unmarshal("docProps/core.xml", &core) == nil {
+		doc.Title = strings.TrimSpace(core.Title)
+		doc.Author = strings.TrimSpace(core.Creator)
+	}
+}
+
+// markupText flattens WordprocessingML or DrawingML body text. Paragraphs
+// become lines, table cells are joined with " | ", and page breaks (explicit
+// or as last rendered by Word) become form feeds for paginate.
+func markupText(data []byte) (string, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	var sb strings.Builder
+	inText, inTabStops := false, false
+	cellDepth, cellsInRow := 0, 0
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tabs", "tabLst":
+				inTabStops = true
+			case "tab":
+				if !inTabStops {
+					sb.WriteByte('\t')
+				}
+			case "br", "cr":
+				if attr(t, "type") == "page" {
+					sb.WriteByte('\f')
+				} else {
+					sb.WriteByte('\n')
+				}
+			case "lastRenderedPageBreak":
+				sb.WriteByte('\f')
+			case "tr":
+				cellsInRow = 0
+			case "tc":
+				// The previous cell's last paragraph already ended in a space.
+				if cellsInRow > 0 {
+					sb.WriteString("| ")
+				}
+				cellsInRow++
+				cellDepth++
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "tabs", "tabLst":
+				inTabStops = false
+			case "p":
+				if cellDepth > 0 {
+					sb.WriteByte(' ')
+				} else {
+					sb.WriteByte('\n')
+				}
+			case "tc":
+				cellDepth--
+			case "tr":
+				sb.WriteByte('\n')
+			}
+		case xml.CharData:
+			if inText {
+				sb.Write(t)
+			}
+		}
+	}
+	return sb.String(), nil
+}
+
+func attr(e xml.StartElement, local string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func extractDOCX(data []byte) (*Document, error) {
+	z, err := openZip(data)
+	if err != nil {
+		return nil, err
+	}
+	body, err := z.read("word/document.xml")
+	if err != nil {
+		return nil, err
+	}
+	text, err := markupText(body)
+	if err != nil {
+		return nil, fmt.Errorf("parse word/document.xml: %w", err)
+	}
+
+	doc := &Document{}
+	z.coreProperties(doc)
+	doc.Pages = paginate(text)
+	return doc, nil
+}
+
+func extractPPTX(data []byte) (*Document, error) {
+	z, err := openZip(data)
+	if err != nil {
+		return nil, err
+	}
+	var pres struct {
+		Slides []struct {
+			RID string `xml:"id,attr"`
+		} `xml:"sldIdLst>sldId"`
+	}
+	if err := z.unmarshal("ppt/presentation.xml", &pres); err != nil {
+		return nil, err
+	}
+	rels := z.rels("ppt/presentation.xml")
+
+	doc := &Document{Unit: "slide"}
+	z.coreProperties(doc)
+	for _, s := range pres.Slides {
+		var text string
+		if data, err := z.read(rels[s.RID]); err == nil {
+			text, _ = markupText(data)
+		}
+		// Keep empty slides so numbering matches the deck.
+		doc.Pages = append(doc.Pages, Page{Text: cleanText(strings.ReplaceAll(text, "\f", "\n"))})
+	}
+	return doc, nil
+}
+
+func extractXLSX(data []byte) (*Document, error) {
+	z, err := openZip(data)
+	if err != nil {
+		return nil, err
+	}
+	var wb struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			RID  string `xml:"id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := z.unmarshal("xl/workbook.xml", &wb); err != nil {
+		return nil, err
+	}
+	rels := z.rels("xl/workbook.xml")
+
+	var shared []string
+	if data, err := z.read("xl/sharedStrings.xml"); err == nil {
+		if shared, err = sharedStrings(data); err != nil {
+			return nil, fmt.Errorf("parse shared strings: %w", err)
+		}
+	}
+
+	doc := &Document{Unit: "sheet"}
+	z.coreProperties(doc)
+	for _, s := range wb.Sheets {
+		data, err := z.read(rels[s.RID])
+		if err != nil {
+			continue
+		}
+		text, err := sheetText(data, shared)
+		if err != nil {
+			return nil, fmt.Errorf("parse sheet %q: %w", s.Name, err)
+		}
+		chunks := splitChunks(text, PageChars)
+		if len(chunks) == 0 {
+			chunks = []string{""}
+		}
+		for i, chunk := range chunks {
+			label := s.Name
+			if len(chunks) > 1 {
+				label = fmt.Sprintf("%s (part %d/%d)", s.Name, i+1, len(chunks))
+			}
+			doc.Pages = append(doc.Pages, Page{Label: label, Text: chunk})
+		}
+	}
+	return doc, nil
+}
+
+// sharedStrings decodes xl/sharedStrings.xml, skipping phonetic runs.
+func sharedStrings(data []byte) ([]string, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	var out []string
+	var sb strings.Builder
+	inText, inPhonetic := false, false
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return out, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "si":
+				sb.Reset()
+			case "t":
+				inText = true
+			case "rPh":
+				inPhonetic = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "si":
+				out = append(out, sb.String())
+			case "t":
+				inText = false
+			case "rPh":
+				inPhonetic = false
+			}
+		case xml.CharData:
+			if inText && !inPhonetic {
+				sb.Write(t)
+			}
+		}
+	}
+}
+
+// sheetText renders a worksheet row by row. Cells are placed by their
+// reference so sparse rows keep their column alignment.
+func sheetText(data []byte, shared []string) (string, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	var sb strings.Builder
+	var row []string
+	var cellType string
+	var col int
+	var value strings.Builder
+	inValue := false
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return strings.TrimSpace(sb.String()), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "row":
+				row = row[:0]
+			case "c":
+				cellType = attr(t, "t")
+				col = columnIndex(attr(t, "r"))
+				if col < 0 {
+					col = len(row)
+				}
+				value.Reset()
+			case "v", "t":
+				inValue = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "v", "t":
+				inValue = false
+			case "c":
+				v := value.String()
+				switch cellType {
+				case "s":
+					if i, err := strconv.Atoi(strings.TrimSpace(v)); err == nil && i >= 0 && i < len(shared) {
+						v = shared[i]
+					}
+				case "b":
+					if v == "1" {
+						v = "TRUE"
+					} else {
+						v = "FALSE"
+					}
+				}
+				for len(row) <= col {
+					row = append(row, "")
+				}
+				row[col] = v
+			case "row":
+				if line := formatRow(row); line != "" {
+					sb.WriteString(line)
+					sb.WriteByte('\n')
+				}
+			}
+		case xml.CharData:
+			if inValue {
+				value.Write(t)
+			}
+		}
+	}
+}
+
+// columnIndex converts the letters of a cell reference such as "AB12" to a
+// zero-based column index, or -1 if ref has no column letters.
+func columnIndex(ref string) int {
+	col := 0
+	n := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+		n++
+	}
+	// Guard against absurd references blowing up the row slice.
+	if n == 0 || col > 16384 {
+		return -1
+	}
+	return col - 1
+}
@@ -0,0 +1,59 @@
+package document
+
+import (
+	"bytes"
+	"math"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+func extractPDF(data []byte) (*Document, error) {
+	r, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{}
+	info := r.Trailer().Key("Info")
+	doc.Title = strings.TrimSpace(info.Key("Title").Text())
+	doc.Author = strings.TrimSpace(info.Key("Author").Text())
+
+	for i := 1; i <= r.NumPage(); i++ {
+		doc.Pages = append(doc.Pages, Page{Text: pdfPageText(r.Page(i))})
+	}
+	return doc, nil
+}
+
+// pdfPageText reassembles the positioned glyphs of a page into lines. A new
+// line starts when the baseline moves, and a space is inserted where the gap
+// to the previous glyph is wider than a fraction of the font size.
+func pdfPageText(p pdf.Page) (text string) {
+	if p.V.IsNull() {
+		return ""
+	}
+	// Content panics on content streams it cannot interpret; keep whatever
+	// the other pages yield.
+	defer func() {
+		if recover() != nil {
+			text = ""
+		}
+	}()
+
+	var sb strings.Builder
+	var prev pdf.Text
+	for i, t := range p.Content().Text {
+		if i > 0 {
+			size := math.Max(prev.FontSize, 1)
+			switch {
+			case math.Abs(t.Y-prev.Y) > size/2:
+				sb.WriteByte('\n')
+			case t.X-(prev.X+prev.W) > size*0.2 && !strings.HasSuffix(prev.S, " ") && t.S != " ":
+				sb.WriteByte(' ')
+			}
+		}
+		sb.WriteString(t.S)
+		prev = t
+	}
+	return cleanText(sb.String())
+}
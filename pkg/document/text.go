@@ -0,0 +1,75 @@
+package document
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+func extractHTML(data []byte) (*Document, error) {
+	title, text := htmlText(data)
+	return &Document{Title: title, Pages: paginate(text)}, nil
+}
+
+// htmlText returns the <title> of an HTML document and its body converted to
+// Markdown, which keeps headings, lists and links readable as plain text.
+func htmlText(data []byte) (title, text string) {
+	if root, err := html.Parse(bytes.NewReader(data)); err == nil {
+		title = findTitle(root)
+	}
+	text, err := utils.HtmlToMarkdown(string(data))
+	if err != nil {
+		return title, ""
+	}
+	return title, cleanText(text)
+}
+
+func findTitle(n *html.Node) string {
+	if n.Type == html.ElementNode && n.Data == "title" {
+		var sb strings.Builder
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.TextNode {
+				sb.WriteString(c.Data)
+			}
+		}
+		return strings.Join(strings.Fields(sb.String()), " ")
+	}
+	if n.Type == html.ElementNode && n.Data == "body" {
+		return ""
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if t := findTitle(c); t != "" {
+			return t
+		}
+	}
+	return ""
+}
+
+func extractCSV(data []byte, comma rune) (*Document, error) {
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	r.Comma = comma
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	var sb strings.Builder
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line := formatRow(record); line != "" {
+			sb.WriteString(line)
+			sb.WriteByte('\n')
+		}
+	}
+	return &Document{Pages: paginate(sb.String())}, nil
+}
@@ -0,0 +1,173 @@
+package egress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/audit"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// DialFunc is the signature of net.Dialer.DialContext.
+type DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+type agentCtxKey struct{}
+
+// WithAgent returns a child context whose connections are checked against
+// the rules of agent id.
+func WithAgent(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, agentCtxKey{}, id)
+}
+
+// AgentFrom returns the agent ID set by WithAgent, or "".
+func AgentFrom(ctx context.Context) string {
+	id, _ := ctx.Value(agentCtxKey{}).(string)
+	return id
+}
+
+// Check applies the installed policy for the agent in ctx and reports a
+// refusal. See Policy.Check.
+func Check(ctx context.Context, host string, ip net.IP) error {
+	err := Current().Check(AgentFrom(ctx), host, ip)
+	if err != nil {
+		report(ctx, err)
+	}
+	return err
+}
+
+// report logs and audits a refused connection.
+func report(ctx context.Context, err error) {
+	var blocked *BlockedError
+	if !errors.As(err, &blocked) {
+		return
+	}
+	fields := map[string]any{
+		"agent":  AgentFrom(ctx),
+		"host":   blocked.Host,
+		"reason": blocked.Reason,
+	}
+	if blocked.IP != nil {
+		fields["ip"] = blocked.IP.String()
+	}
+	logger.WarnCF("egress", "Blocked outbound connection", fields)
+	audit.Record(ctx, audit.TypeEgressBlocked, fields)
+}
+
+// DialContext wraps dial so every connection is checked against the
+// installed policy. Host names are resolved here and only permitted
+// addresses are dialed, so a name cannot be rebound to a refused address
+// between the check and the connect.
+func DialContext(dial DialFunc) DialFunc {
+	return dialContext(dial, nil)
+}
+
+func dialContext(dial DialFunc, exempt func(address string) bool) DialFunc {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		p := Current()
+		if p == nil || (exempt != nil && exempt(address)) {
+			return dial(ctx, network, address)
+		}
+
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid target address %q: %w", address, err)
+		}
+		agentID := AgentFrom(ctx)
+		if ip := net.ParseIP(host); ip != nil {
+			if err := p.Check(agentID, host, ip); err != nil {
+				report(ctx, err)
+				return nil, err
+			}
+			return dial(ctx, network, address)
+		}
+		if err := p.Check(agentID, host, nil); err != nil {
+			report(ctx, err)
+			return nil, err
+		}
+
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", host, err)
+		}
+		var blocked, lastErr error
+		for _, addr := range addrs {
+			if err := p.Check(agentID, host, addr.IP); err != nil {
+				if blocked == nil {
+					blocked = err
+				}
+				continue
+			}
+			conn, err := dial(ctx, network, net.JoinHostPort(addr.IP.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+		if lastErr != nil {
+			return nil, lastErr
+		}
+		if blocked != nil {
+			report(ctx, blocked)
+			return nil, blocked
+		}
+		return nil, fmt.Errorf("no addresses found for %s", host)
+	}
+}
+
+// Guard makes t obey the installed policy and returns it. Every request's
+// host is checked before it is sent, and direct connections go through
+// DialContext. Connections to the proxy t itself uses are exempt; the proxy
+// resolves the target, so only the host name rules apply behind one.
+func Guard(t *http.Transport) *http.Transport {
+	var proxies sync.Map
+	proxy := t.Proxy
+	t.Proxy = func(req *http.Request) (*url.URL, error) {
+		if err := Check(req.Context(), req.URL.Hostname(), nil); err != nil {
+			return nil, err
+		}
+		if proxy == nil {
+			return nil, nil
+		}
+		u, err := proxy(req)
+		if u != nil {
+			proxies.Store(proxyAddress(u), struct{}{})
+		}
+		return u, err
+	}
+
+	dial := t.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext
+	}
+	t.DialContext = dialContext(dial, func(address string) bool {
+		_, ok := proxies.Load(address)
+		return ok
+	})
+	return t
+}
+
+// proxyAddress returns the host:port a transport dials for proxy u.
+func proxyAddress(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		switch strings.ToLower(u.Scheme) {
+		case "https":
+			port = "443"
+		case "socks5", "socks5h":
+			port = "1080"
+		default:
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
@@ -0,0 +1,281 @@
+// Package egress decides which hosts tools may connect to and enforces it
+// in a shared dialer, in a transport wrapper for HTTP clients and in a
+// filtering HTTP proxy for processes such as exec commands.
+//
+// The policy is process-wide and installed from config with Install; a nil
+// policy (egress disabled) allows everything. Per-agent overrides are
+// selected by the agent ID carried in the context (see WithAgent).
+package egress
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// Policy is a compiled egress configuration.
+type Policy struct {
+	global rules
+	agents map[string]rules
+}
+
+type rules struct {
+	allowedDomains []string
+	blockedDomains []string
+	allowedCIDRs   []*net.IPNet
+	blockedCIDRs   []*net.IPNet
+	blockPrivate   bool
+}
+
+// BlockedError is returned for a destination the policy refuses.
+type BlockedError struct {
+	Host   string
+	IP     net.IP // nil when the host was refused before resolution
+	Reason string
+}
+
+func (e *BlockedError) Error() string {
+	if e.IP != nil && e.IP.String() != e.Host {
+		return fmt.Sprintf("egress to %s (%s) blocked: %s", e.Host, e.IP, e.Reason)
+	}
+	return fmt.Sprintf("egress to %s blocked: %s", e.Host, e.Reason)
+}
+
+var current atomic.Pointer[Policy]
+
+// Install compiles cfg and makes it the process-wide policy, or removes the
+// policy when egress is disabled. An invalid configuration leaves the
+// previous policy in place.
+func Install(cfg config.EgressConfig) error {
+	p, err := NewPolicy(cfg)
+	if err != nil {
+		return err
+	}
+	current.Store(p)
+	return nil
+}
+
+// Current returns the installed policy, or nil when egress is unrestricted.
+func Current() *Policy {
+	return current.Load()
+}
+
+// NewPolicy compiles cfg. It returns nil when egress is disabled.
+func NewPolicy(cfg config.EgressConfig) (*Policy, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	global, err := compileRules(cfg.EgressRules, rules{})
+	if err != nil {
+		return nil, err
+	}
+	p := &Policy{global: global, agents: make(map[string]rules, len(cfg.Agents))}
+	for id, override := range cfg.Agents {
+		r, err := compileRules(override, global)
+		if err != nil {
+			return nil, fmt.Errorf("agent %q: %w", id, err)
+		}
+		p.agents[strings.ToLower(id)] = r
+	}
+	return p, nil
+}
+
+// compileRules parses r on top of base: blocked lists are added, allowed
+// lists replace base's when set and BlockPrivate overrides when set.
+func compileRules(r config.EgressRules, base rules) (rules, error) {
+	out := rules{
+		allowedDomains: base.allowedDomains,
+		blockedDomains: append([]string(nil), base.blockedDomains...),
+		allowedCIDRs:   base.allowedCIDRs,
+		blockedCIDRs:   append([]*net.IPNet(nil), base.blockedCIDRs...),
+		blockPrivate:   base.blockPrivate,
+	}
+	if len(r.AllowedDomains) > 0 || len(r.AllowedCIDRs) > 0 {
+		out.allowedDomains = normalizeDomains(r.AllowedDomains)
+		out.allowedCIDRs = nil
+	}
+	out.blockedDomains = append(out.blockedDomains, normalizeDomains(r.BlockedDomains)...)
+
+	allowed, err := parseCIDRs(r.AllowedCIDRs)
+	if err != nil {
+		return rules{}, fmt.Errorf("allowed_cidrs: %w", err)
+	}
+	if len(allowed) > 0 {
+		out.allowedCIDRs = allowed
+	}
+	blocked, err := parseCIDRs(r.BlockedCIDRs)
+	if err != nil {
+		return rules{}, fmt.Errorf("blocked_cidrs: %w", err)
+	}
+	out.blockedCIDRs = append(out.blockedCIDRs, blocked...)
+
+	if r.BlockPrivate != nil {
+		out.blockPrivate = *r.BlockPrivate
+	}
+	return out, nil
+}
+
+// normalizeDomains lowercases entries and strips a leading "*." or "." so
+// "*.example.com", ".example.com" and "example.com" are equivalent.
+func normalizeDomains(entries []string) []string {
+	out := make([]string, 0, len(entries))
+	for _, d := range entries {
+		d = strings.ToLower(strings.TrimSpace(d))
+		d = strings.TrimPrefix(d, "*.")
+		d = strings.Trim(d, ".")
+		if d != "" {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// parseCIDRs accepts CIDRs and bare IPs.
+func parseCIDRs(entries []string) ([]*net.IPNet, error) {
+	var out []*net.IPNet
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if ip := net.ParseIP(entry); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			out = append(out, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid entry %q: expected IP or CIDR", entry)
+		}
+		out = append(out, network)
+	}
+	return out, nil
+}
+
+func (p *Policy) rulesFor(agentID string) *rules {
+	if r, ok := p.agents[strings.ToLower(agentID)]; ok {
+		return &r
+	}
+	return &p.global
+}
+
+// Check decides whether agentID may connect to host. ip is the resolved
+// address being dialed; when it is nil only the host name is checked, and
+// rules that depend on the address are left to the dial.
+//
+// Blocked domains and CIDRs win over everything. Addresses in allowed_cidrs
+// are then accepted, private addresses refused when block_private is set,
+// and with an allowlist configured anything not on it is refused.
+func (p *Policy) Check(agentID, host string, ip net.IP) error {
+	if p == nil {
+		return nil
+	}
+	r := p.rulesFor(agentID)
+	host = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+	if ip == nil {
+		ip = net.ParseIP(host)
+	}
+	literal := ip != nil && ip.Equal(net.ParseIP(host))
+
+	if !literal && matchDomain(r.blockedDomains, host) {
+		return &BlockedError{Host: host, IP: ip, Reason: "domain is blocked"}
+	}
+	if ip == nil {
+		// The name alone can only be refused by the allowlist when no
+		// allowed CIDR could still match the address it resolves to.
+		if r.hasAllowlist() && len(r.allowedCIDRs) == 0 && !matchDomain(r.allowedDomains, host) {
+			return &BlockedError{Host: host, Reason: "domain is not in the allowlist"}
+		}
+		return nil
+	}
+
+	if containsIP(r.blockedCIDRs, ip) {
+		return &BlockedError{Host: host, IP: ip, Reason: "address is in a blocked range"}
+	}
+	if containsIP(r.allowedCIDRs, ip) {
+		return nil
+	}
+	if r.blockPrivate && IsPrivateIP(ip) {
+		return &BlockedError{Host: host, IP: ip, Reason: "private or local address"}
+	}
+	if r.hasAllowlist() && (literal || !matchDomain(r.allowedDomains, host)) {
+		return &BlockedError{Host: host, IP: ip, Reason: "destination is not in the allowlist"}
+	}
+	return nil
+}
+
+func (r *rules) hasAllowlist() bool {
+	return len(r.allowedDomains) > 0 || len(r.allowedCIDRs) > 0
+}
+
+// matchDomain reports whether host is one of domains or a subdomain of one.
+func matchDomain(domains []string, host string) bool {
+	for _, d := range domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsIP(networks []*net.IPNet, ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPrivateIP returns true for addresses that are not on the public
+// internet: RFC 1918, loopback, link-local (incl. cloud metadata
+// 169.254.x.x), carrier-grade NAT, multicast, unspecified, IPv6
+// unique-local (fc00::/7), and 6to4 (2002::/16) or Teredo (2001:0000::/32)
+// addresses wrapping one of those.
+func IsPrivateIP(ip net.IP) bool {
+	if ip == nil {
+		return true
+	}
+
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsMulticast() || ip.IsUnspecified() {
+		return true
+	}
+
+	if ip4 := ip.To4(); ip4 != nil {
+		// IPv4 private, loopback, link-local, and carrier-grade NAT ranges.
+		return ip4[0] == 10 ||
+			ip4[0] == 127 ||
+			ip4[0] == 0 ||
+			(ip4[0] == 172 && ip4[1] >= 16 && ip4[1] <= 31) ||
+			(ip4[0] == 192 && ip4[1] == 168) ||
+			(ip4[0] == 169 && ip4[1] == 254) ||
+			(ip4[0] == 100 && ip4[1] >= 64 && ip4[1] <= 127)
+	}
+
+	if len(ip) == net.IPv6len {
+		// IPv6 unique local addresses (fc00::/7)
+		if (ip[0] & 0xfe) == 0xfc {
+			return true
+		}
+		// 6to4 addresses (2002::/16): check the embedded IPv4 at bytes [2:6].
+		if ip[0] == 0x20 && ip[1] == 0x02 {
+			return IsPrivateIP(net.IPv4(ip[2], ip[3], ip[4], ip[5]))
+		}
+		// Teredo (2001:0000::/32): client IPv4 is at bytes [12:16], XOR-inverted.
+		if ip[0] == 0x20 && ip[1] == 0x01 && ip[2] == 0x00 && ip[3] == 0x00 {
+			return IsPrivateIP(net.IPv4(ip[12]^0xff, ip[13]^0xff, ip[14]^0xff, ip[15]^0xff))
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,166 @@
+package egress
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func boolPtr(b bool) *bool { return &b }
+
+func testPolicy(t *testing.T) *Policy {
+	t.Helper()
+	p, err := NewPolicy(config.EgressConfig{
+		Enabled: true,
+		EgressRules: config.EgressRules{
+			BlockedDomains: []string{"*.evil.test"},
+			BlockedCIDRs:   []string{"203.0.113.0/24"},
+			BlockPrivate:   boolPtr(true),
+		},
+		Agents: map[string]config.EgressRules{
+			"Research": {
+				AllowedDomains: []string{"example.org"},
+				BlockedDomains: []string{"ads.example.org"},
+			},
+			"local": {BlockPrivate: boolPtr(false)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewPolicy: %v", err)
+	}
+	return p
+}
+
+func TestPolicyCheck(t *testing.T) {
+	p := testPolicy(t)
+	tests := []struct {
+		agent, host, ip string
+		blocked         bool
+	}{
+		{"", "example.com", "93.184.215.14", false},
+		{"", "evil.test", "", true},
+		{"", "www.EVIL.test.", "", true},
+		{"", "notevil.test", "", false},
+		{"", "cdn.example.com", "203.0.113.7", true},
+		{"", "203.0.113.7", "", true},
+		{"", "localhost", "127.0.0.1", true},
+		{"", "169.254.169.254", "", true},
+
+		// Agent overrides: the allowlist replaces the global one, blocked
+		// lists add up and block_private is inherited.
+		{"research", "example.org", "", false},
+		{"research", "api.example.org", "93.184.215.14", false},
+		{"research", "ads.example.org", "", true},
+		{"research", "example.com", "", true},
+		{"research", "evil.test", "", true},
+		{"research", "example.org", "10.0.0.1", true},
+		{"research", "93.184.215.14", "", true},
+		{"local", "localhost", "127.0.0.1", false},
+		{"local", "evil.test", "", true},
+	}
+	for _, tt := range tests {
+		err := p.Check(tt.agent, tt.host, net.ParseIP(tt.ip))
+		var blocked *BlockedError
+		if got := errors.As(err, &blocked); got != tt.blocked {
+			t.Errorf("Check(%q, %q, %q) = %v, want blocked=%v", tt.agent, tt.host, tt.ip, err, tt.blocked)
+		}
+	}
+}
+
+func TestPolicyCheck_AllowedCIDRs(t *testing.T) {
+	p, err := NewPolicy(config.EgressConfig{
+		Enabled: true,
+		EgressRules: config.EgressRules{
+			AllowedCIDRs: []string{"192.0.2.0/24", "10.1.2.3"},
+			BlockPrivate: boolPtr(true),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Check("", "anything.test", nil); err != nil {
+		t.Errorf("name check should wait for the address: %v", err)
+	}
+	if err := p.Check("", "anything.test", net.ParseIP("192.0.2.10")); err != nil {
+		t.Errorf("address in allowed_cidrs refused: %v", err)
+	}
+	if err := p.Check("", "anything.test", net.ParseIP("198.51.100.1")); err == nil {
+		t.Error("address outside allowed_cidrs was allowed")
+	}
+	if err := p.Check("", "internal", net.ParseIP("10.1.2.3")); err != nil {
+		t.Errorf("allowed private address refused: %v", err)
+	}
+	if err := p.Check("", "internal", net.ParseIP("10.1.2.4")); err == nil {
+		t.Error("private address outside allowed_cidrs was allowed")
+	}
+}
+
+func TestNewPolicy(t *testing.T) {
+	if p, err := NewPolicy(config.EgressConfig{}); p != nil || err != nil {
+		t.Errorf("disabled config = %v, %v; want nil, nil", p, err)
+	}
+	if err := (*Policy)(nil).Check("", "169.254.169.254", nil); err != nil {
+		t.Errorf("nil policy refused a connection: %v", err)
+	}
+	_, err := NewPolicy(config.EgressConfig{
+		Enabled: true,
+		Agents:  map[string]config.EgressRules{"main": {BlockedCIDRs: []string{"not-a-cidr"}}},
+	})
+	if err == nil {
+		t.Error("expected an error for an invalid CIDR")
+	}
+}
+
+func TestInstall(t *testing.T) {
+	defer Install(config.EgressConfig{})
+
+	cfg := config.EgressConfig{Enabled: true, EgressRules: config.EgressRules{BlockedDomains: []string{"evil.test"}}}
+	if err := Install(cfg); err != nil {
+		t.Fatal(err)
+	}
+	ctx := WithAgent(context.Background(), "main")
+	if err := Check(ctx, "evil.test", nil); err == nil {
+		t.Error("installed policy not applied")
+	}
+
+	cfg.BlockedCIDRs = []string{"bad"}
+	if err := Install(cfg); err == nil {
+		t.Fatal("expected an error for an invalid CIDR")
+	}
+	if Current() == nil {
+		t.Error("invalid config removed the previous policy")
+	}
+
+	if err := Install(config.EgressConfig{}); err != nil || Current() != nil {
+		t.Errorf("disabling egress: policy %v, err %v", Current(), err)
+	}
+}
+
+func TestIsPrivateIP(t *testing.T) {
+	tests := []struct {
+		ip      string
+		private bool
+	}{
+		{"127.0.0.1", true},
+		{"10.0.0.1", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"100.64.0.1", true},
+		{"0.0.0.0", true},
+		{"::1", true},
+		{"fd00::1", true},
+		{"2002:7f00:1::", true},
+		{"8.8.8.8", false},
+		{"172.32.0.1", false},
+		{"2606:4700::1111", false},
+	}
+	for _, tt := range tests {
+		if got := IsPrivateIP(net.ParseIP(tt.ip)); got != tt.private {
+			t.Errorf("IsPrivateIP(%s) = %v, want %v", tt.ip, got, tt.private)
+		}
+	}
+}
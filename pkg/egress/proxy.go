@@ -0,0 +1,203 @@
+package egress
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// Proxy is a filtering HTTP proxy for processes that cannot use the
+// policy-aware dialer, such as exec commands. It serves plain HTTP requests
+// with absolute URLs and CONNECT tunnels on a loopback TCP port and on a
+// unix socket, checking every destination against the rules of one agent.
+type Proxy struct {
+	agentID string
+	tcp     net.Listener
+	unix    net.Listener
+	dir     string
+	server  *http.Server
+	dial    DialFunc
+	reverse *httputil.ReverseProxy
+}
+
+var (
+	proxiesMu sync.Mutex
+	proxies   = map[string]*Proxy{}
+)
+
+// ProxyFor returns the proxy for agentID, starting it on first use. Proxies
+// live as long as the process and follow policy changes.
+func ProxyFor(agentID string) (*Proxy, error) {
+	proxiesMu.Lock()
+	defer proxiesMu.Unlock()
+	if p, ok := proxies[agentID]; ok {
+		return p, nil
+	}
+	p, err := NewProxy(agentID)
+	if err != nil {
+		return nil, err
+	}
+	proxies[agentID] = p
+	return p, nil
+}
+
+// NewProxy starts a proxy enforcing the rules of agentID.
+func NewProxy(agentID string) (*Proxy, error) {
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	// The socket lets a sandbox without network reach the proxy; its
+	// directory keeps other users out.
+	dir, err := os.MkdirTemp("", "picoclaw-egress-")
+	if err != nil {
+		tcp.Close()
+		return nil, err
+	}
+	unixLn, err := net.Listen("unix", filepath.Join(dir, "proxy.sock"))
+	if err != nil {
+		tcp.Close()
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   15 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	p := &Proxy{
+		agentID: agentID,
+		tcp:     tcp,
+		unix:    unixLn,
+		dir:     dir,
+		dial:    DialContext(dialer.DialContext),
+	}
+	p.reverse = &httputil.ReverseProxy{
+		// Proxy requests carry an absolute URL already; forward it unchanged.
+		Rewrite: func(*httputil.ProxyRequest) {},
+		Transport: &http.Transport{
+			DialContext:           p.dial,
+			MaxIdleConns:          20,
+			IdleConnTimeout:       90 * time.Second,
+			ResponseHeaderTimeout: 60 * time.Second,
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			proxyError(w, err)
+		},
+	}
+	p.server = &http.Server{
+		Handler:           p,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go p.server.Serve(tcp)
+	go p.server.Serve(unixLn)
+	return p, nil
+}
+
+// Addr returns the loopback host:port of the proxy.
+func (p *Proxy) Addr() string {
+	return p.tcp.Addr().String()
+}
+
+// SocketPath returns the unix socket the proxy also listens on.
+func (p *Proxy) SocketPath() string {
+	return p.unix.Addr().String()
+}
+
+// Close stops the proxy and removes its socket.
+func (p *Proxy) Close() error {
+	err := p.server.Close()
+	os.RemoveAll(p.dir)
+	return err
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r = r.WithContext(WithAgent(r.Context(), p.agentID))
+	if r.Method == http.MethodConnect {
+		p.tunnel(w, r)
+		return
+	}
+	if r.URL.Scheme != "http" || r.URL.Host == "" {
+		http.Error(w, "only absolute http URLs can be proxied", http.StatusBadRequest)
+		return
+	}
+	// Pooled connections skip the dial, so check every request.
+	if err := Check(r.Context(), r.URL.Hostname(), nil); err != nil {
+		proxyError(w, err)
+		return
+	}
+	p.reverse.ServeHTTP(w, r)
+}
+
+func (p *Proxy) tunnel(w http.ResponseWriter, r *http.Request) {
+	upstream, err := p.dial(r.Context(), "tcp", r.Host)
+	if err != nil {
+		proxyError(w, err)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		upstream.Close()
+		http.Error(w, "tunneling not supported", http.StatusInternalServerError)
+		return
+	}
+	client, buf, err := hijacker.Hijack()
+	if err != nil {
+		upstream.Close()
+		return
+	}
+	if _, err := client.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		client.Close()
+		upstream.Close()
+		return
+	}
+
+	var once sync.Once
+	closeBoth := func() {
+		client.Close()
+		upstream.Close()
+	}
+	go func() {
+		// Flush anything the client sent along with the CONNECT request.
+		if n := buf.Reader.Buffered(); n > 0 {
+			data, _ := buf.Reader.Peek(n)
+			upstream.Write(data)
+		}
+		io.Copy(upstream, client)
+		once.Do(closeBoth)
+	}()
+	io.Copy(client, upstream)
+	once.Do(closeBoth)
+}
+
+// proxyError answers 403 for a refused destination and 502 otherwise.
+// Refusals were already logged by the dialer.
+func proxyError(w http.ResponseWriter, err error) {
+	var blocked *BlockedError
+	if errors.As(err, &blocked) {
+		http.Error(w, blocked.Error(), http.StatusForbidden)
+		return
+	}
+	logger.DebugCF("egress", "Proxy request failed", map[string]any{"error": err.Error()})
+	http.Error(w, err.Error(), http.StatusBadGateway)
+}
+
+// ProxyEnv returns environment variables pointing HTTP clients at
+// proxyURL. NO_PROXY is cleared so nothing bypasses it.
+func ProxyEnv(proxyURL string) []string {
+	var env []string
+	for _, name := range []string{"http_proxy", "https_proxy", "all_proxy"} {
+		env = append(env, name+"="+proxyURL, strings.ToUpper(name)+"="+proxyURL)
+	}
+	return append(env, "no_proxy=", "NO_PROXY=")
+}
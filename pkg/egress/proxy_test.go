@@ -0,0 +1,136 @@
+package egress
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func startTestProxy(t *testing.T, agentID string, cfg config.EgressConfig) *Proxy {
+	t.Helper()
+	if err := Install(cfg); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { Install(config.EgressConfig{}) })
+	p, err := NewProxy(agentID)
+	if err != nil {
+		t.Fatalf("NewProxy: %v", err)
+	}
+	t.Cleanup(func() { p.Close() })
+	return p
+}
+
+func TestProxy_ForwardsAndBlocks(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "upstream")
+	}))
+	defer upstream.Close()
+	port := upstream.Listener.Addr().(*net.TCPAddr).Port
+
+	p := startTestProxy(t, "research", config.EgressConfig{
+		Enabled: true,
+		Agents: map[string]config.EgressRules{
+			"research": {BlockedDomains: []string{"blocked.test"}},
+		},
+	})
+	proxyURL, _ := url.Parse("http://" + p.Addr())
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+
+	resp, err := client.Get(upstream.URL)
+	if err != nil {
+		t.Fatalf("allowed request: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "upstream" {
+		t.Errorf("allowed request: %d %q", resp.StatusCode, body)
+	}
+
+	resp, err = client.Get(fmt.Sprintf("http://blocked.test:%d/", port))
+	if err != nil {
+		t.Fatalf("blocked request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("blocked request: status %d, want 403", resp.StatusCode)
+	}
+}
+
+func TestProxy_ConnectOverUnixSocket(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer upstream.Close()
+	target := upstream.Listener.Addr().String()
+
+	p := startTestProxy(t, "", config.EgressConfig{Enabled: true})
+	connect := func(host string) string {
+		conn, err := net.Dial("unix", p.SocketPath())
+		if err != nil {
+			t.Fatalf("dial proxy socket: %v", err)
+		}
+		defer conn.Close()
+		fmt.Fprintf(conn, "CONNECT %s HTTP/1.1\r\nHost: %s\r\n\r\n", host, host)
+		status, err := bufio.NewReader(conn).ReadString('\n')
+		if err != nil {
+			t.Fatalf("read CONNECT response: %v", err)
+		}
+		return strings.TrimSpace(status)
+	}
+
+	if status := connect(target); !strings.Contains(status, "200") {
+		t.Errorf("CONNECT %s: %q", target, status)
+	}
+
+	Install(config.EgressConfig{
+		Enabled:     true,
+		EgressRules: config.EgressRules{BlockPrivate: boolPtr(true)},
+	})
+	if status := connect(target); !strings.Contains(status, "403") {
+		t.Errorf("CONNECT to a private address: %q, want 403", status)
+	}
+}
+
+func TestDialContext_AgentRules(t *testing.T) {
+	if err := Install(config.EgressConfig{
+		Enabled: true,
+		Agents:  map[string]config.EgressRules{"main": {BlockedCIDRs: []string{"127.0.0.0/8"}}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer Install(config.EgressConfig{})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	dial := DialContext((&net.Dialer{}).DialContext)
+
+	conn, err := dial(context.Background(), "tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dial without agent: %v", err)
+	}
+	conn.Close()
+
+	_, err = dial(WithAgent(context.Background(), "main"), "tcp", "localhost:"+fmt.Sprint(ln.Addr().(*net.TCPAddr).Port))
+	if _, ok := err.(*BlockedError); !ok {
+		t.Errorf("dial as agent main: %v, want *BlockedError", err)
+	}
+}
+
+func TestProxyEnv(t *testing.T) {
+	env := strings.Join(ProxyEnv("http://127.0.0.1:3128"), " ")
+	for _, want := range []string{"HTTPS_PROXY=http://127.0.0.1:3128", "http_proxy=http://127.0.0.1:3128", "NO_PROXY="} {
+		if !strings.Contains(env, want) {
+			t.Errorf("ProxyEnv missing %q: %s", want, env)
+		}
+	}
+}
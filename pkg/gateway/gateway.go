@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/sipeed/picoclaw/pkg/agent"
+	"github.com/sipeed/picoclaw/pkg/audit"
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/channels"
 	_ "github.com/sipeed/picoclaw/pkg/channels/dingtalk"
@@ -20,22 +21,26 @@ import (
 	_ "github.com/sipeed/picoclaw/pkg/channels/line"
 	_ "github.com/sipeed/picoclaw/pkg/channels/maixcam"
 	_ "github.com/sipeed/picoclaw/pkg/channels/matrix"
+	_ "github.com/sipeed/picoclaw/pkg/channels/mqtt"
 	_ "github.com/sipeed/picoclaw/pkg/channels/onebot"
 	_ "github.com/sipeed/picoclaw/pkg/channels/pico"
 	_ "github.com/sipeed/picoclaw/pkg/channels/qq"
 	_ "github.com/sipeed/picoclaw/pkg/channels/slack"
 	_ "github.com/sipeed/picoclaw/pkg/channels/telegram"
+	_ "github.com/sipeed/picoclaw/pkg/channels/webhook"
 	_ "github.com/sipeed/picoclaw/pkg/channels/wecom"
 	_ "github.com/sipeed/picoclaw/pkg/channels/whatsapp"
 	_ "github.com/sipeed/picoclaw/pkg/channels/whatsapp_native"
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/cron"
 	"github.com/sipeed/picoclaw/pkg/devices"
+	"github.com/sipeed/picoclaw/pkg/egress"
 	"github.com/sipeed/picoclaw/pkg/health"
 	"github.com/sipeed/picoclaw/pkg/heartbeat"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/media"
 	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/redact"
 	"github.com/sipeed/picoclaw/pkg/state"
 	"github.com/sipeed/picoclaw/pkg/tools"
 	"github.com/sipeed/picoclaw/pkg/voice"
@@ -85,6 +90,13 @@ func Run(debug bool, configPath string, allowEmptyStartup bool) error {
 	if err != nil {
 		return fmt.Errorf("error loading config: %w", err)
 	}
+	redact.Install(cfg)
+	if err := audit.Install(cfg.Audit, "gateway"); err != nil {
+		return fmt.Errorf("error opening audit log: %w", err)
+	}
+	if err := egress.Install(cfg.Egress); err != nil {
+		return fmt.Errorf("invalid egress policy: %w", err)
+	}
 
 	provider, modelID, err := createStartupProvider(cfg, allowEmptyStartup)
 	if err != nil {
@@ -229,6 +241,10 @@ func setupAndStartServices(
 		agentLoop.SetTranscriber(transcriber)
 		logger.InfoCF("voice", "Transcription enabled (agent-level)", map[string]any{"provider": transcriber.Name()})
 	}
+	if synthesizer := voice.DetectSynthesizer(cfg); synthesizer != nil {
+		agentLoop.SetSynthesizer(synthesizer)
+		logger.InfoCF("voice", "Voice replies enabled", map[string]any{"provider": synthesizer.Name()})
+	}
 
 	enabledChannels := runningServices.ChannelManager.GetEnabledChannels()
 	if len(enabledChannels) > 0 {
@@ -239,7 +255,11 @@ func setupAndStartServices(
 
 	addr := fmt.Sprintf("%s:%d", cfg.Gateway.Host, cfg.Gateway.Port)
 	runningServices.HealthServer = health.NewServer(cfg.Gateway.Host, cfg.Gateway.Port)
+	runningServices.HealthServer.SetComponent("mcp", func() any { return agentLoop.MCPStatus() })
 	runningServices.ChannelManager.SetupHTTPServer(addr, runningServices.HealthServer)
+	if cfg.Tools.MCP.Serve.Enabled {
+		setupMCPServer(cfg, agentLoop, runningServices.ChannelManager)
+	}
 
 	if err = runningServices.ChannelManager.StartAll(context.Background()); err != nil {
 		return nil, fmt.Errorf("error starting channels: %w", err)
@@ -262,6 +282,36 @@ func setupAndStartServices(
 	return runningServices, nil
 }
 
+// setupMCPServer mounts the MCP server endpoint on the shared HTTP server.
+// Failures are logged rather than returned so a bad MCP setup does not keep
+// the channels from starting.
+func setupMCPServer(cfg *config.Config, agentLoop *agent.AgentLoop, channelManager *channels.Manager) {
+	serveCfg := cfg.Tools.MCP.Serve
+	token, err := cfg.CredentialResolver().Resolve(serveCfg.Token)
+	if err != nil {
+		logger.ErrorCF("mcp", "Failed to resolve MCP server token", map[string]any{"error": err.Error()})
+		return
+	}
+	if token == "" {
+		logger.WarnCF("mcp", "MCP server endpoint not mounted: tools.mcp.serve.token is required", nil)
+		return
+	}
+
+	server, err := agentLoop.NewMCPServer(context.Background(), agent.MCPHTTPChannel)
+	if err != nil {
+		logger.ErrorCF("mcp", "Failed to create MCP server", map[string]any{"error": err.Error()})
+		return
+	}
+
+	path := serveCfg.Path
+	if path == "" {
+		path = "/mcp"
+	}
+	channelManager.RegisterHTTPHandler(path, server.HTTPHandler(token))
+	fmt.Printf("✓ MCP server available at http://%s:%d%s (%d tools)\n",
+		cfg.Gateway.Host, cfg.Gateway.Port, path, len(server.ToolNames()))
+}
+
 func stopAndCleanupServices(runningServices *services, shutdownTimeout time.Duration) {
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), shutdownTimeout)
 	defer shutdownCancel()
@@ -354,6 +404,15 @@ func handleConfigReload(
 	}
 
 	*providerRef = newProvider
+	redact.Install(newCfg)
+	if err := audit.Install(newCfg.Audit, "gateway"); err != nil {
+		logger.ErrorCF("gateway", "Cannot open audit log, keeping the previous one",
+			map[string]any{"error": err.Error()})
+	}
+	if err := egress.Install(newCfg.Egress); err != nil {
+		logger.ErrorCF("gateway", "Invalid egress policy, keeping the previous one",
+			map[string]any{"error": err.Error()})
+	}
 
 	logger.Info("  Restarting all services with new configuration...")
 	if err := restartServices(al, runningServices, msgBus); err != nil {
@@ -427,7 +486,11 @@ func restartServices(
 
 	addr := fmt.Sprintf("%s:%d", cfg.Gateway.Host, cfg.Gateway.Port)
 	runningServices.HealthServer = health.NewServer(cfg.Gateway.Host, cfg.Gateway.Port)
+	runningServices.HealthServer.SetComponent("mcp", func() any { return al.MCPStatus() })
 	runningServices.ChannelManager.SetupHTTPServer(addr, runningServices.HealthServer)
+	if cfg.Tools.MCP.Serve.Enabled {
+		setupMCPServer(cfg, al, runningServices.ChannelManager)
+	}
 
 	if err = runningServices.ChannelManager.StartAll(context.Background()); err != nil {
 		return fmt.Errorf("error restarting channels: %w", err)
@@ -458,6 +521,14 @@ func restartServices(
 		logger.InfoCF("voice", "Transcription disabled", nil)
 	}
 
+	synthesizer := voice.DetectSynthesizer(cfg)
+	al.SetSynthesizer(synthesizer)
+	if synthesizer != nil {
+		logger.InfoCF("voice", "Voice replies re-enabled", map[string]any{"provider": synthesizer.Name()})
+	} else {
+		logger.InfoCF("voice", "Voice replies disabled", nil)
+	}
+
 	return nil
 }
 
@@ -562,6 +633,7 @@ func setupCronTool(
 		if err != nil {
 			return nil, fmt.Errorf("critical error during CronTool initialization: %w", err)
 		}
+		cronTool.SetRoleResolver(agentLoop.RoleForSender)
 
 		agentLoop.RegisterTool(cronTool)
 	}
@@ -12,11 +12,12 @@ import (
 )
 
 type Server struct {
-	server    *http.Server
-	mu        sync.RWMutex
-	ready     bool
-	checks    map[string]Check
-	startTime time.Time
+	server     *http.Server
+	mu         sync.RWMutex
+	ready      bool
+	checks     map[string]Check
+	components map[string]func() any
+	startTime  time.Time
 }
 
 type Check struct {
@@ -31,14 +32,18 @@ type StatusResponse struct {
 	Uptime string           `json:"uptime"`
 	Checks map[string]Check `json:"checks,omitempty"`
 	Pid    int              `json:"pid"`
+	// Components carries live subsystem state reported by SetComponent,
+	// keyed by component name.
+	Components map[string]json.RawMessage `json:"components,omitempty"`
 }
 
 func NewServer(host string, port int) *Server {
 	mux := http.NewServeMux()
 	s := &Server{
-		ready:     false,
-		checks:    make(map[string]Check),
-		startTime: time.Now(),
+		ready:      false,
+		checks:     make(map[string]Check),
+		components: make(map[string]func() any),
+		startTime:  time.Now(),
 	}
 
 	mux.HandleFunc("/health", s.healthHandler)
@@ -106,15 +111,42 @@ func (s *Server) RegisterCheck(name string, checkFn func() (bool, string)) {
 	}
 }
 
+// SetComponent reports the value returned by fn under name in every
+// /health response. fn is called per request and must be cheap.
+func (s *Server) SetComponent(name string, fn func() any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.components[name] = fn
+}
+
+func (s *Server) componentStates() map[string]json.RawMessage {
+	s.mu.RLock()
+	fns := make(map[string]func() any, len(s.components))
+	maps.Copy(fns, s.components)
+	s.mu.RUnlock()
+
+	if len(fns) == 0 {
+		return nil
+	}
+	states := make(map[string]json.RawMessage, len(fns))
+	for name, fn := range fns {
+		if data, err := json.Marshal(fn()); err == nil {
+			states[name] = data
+		}
+	}
+	return states
+}
+
 func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
 	uptime := time.Since(s.startTime)
 	resp := StatusResponse{
-		Status: "ok",
-		Uptime: uptime.String(),
-		Pid:    os.Getpid(),
+		Status:     "ok",
+		Uptime:     uptime.String(),
+		Pid:        os.Getpid(),
+		Components: s.componentStates(),
 	}
 
 	json.NewEncoder(w).Encode(resp)
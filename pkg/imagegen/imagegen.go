@@ -0,0 +1,161 @@
+// Package imagegen generates images from text prompts through pluggable
+// backends: the OpenAI Images API (and compatible endpoints), the Stable
+// Diffusion WebUI API and ComfyUI.
+//
+// Backends are configured as model_list entries and selected by the protocol
+// prefix of their model field:
+//
+//   - "openai/gpt-image-1", "openai/dall-e-3" or any other OpenAI-compatible
+//     protocol → POST {api_base}/images/generations
+//   - "sdwebui/<checkpoint>" → POST {api_base}/sdapi/v1/txt2img
+//   - "comfyui/<checkpoint>" → a txt2img workflow queued on {api_base}/prompt
+package imagegen
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/egress"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// MaxImages bounds how many images one request may produce.
+const MaxImages = 4
+
+const defaultTimeout = 120 * time.Second
+
+// ImageProvider generates images from a prompt.
+type ImageProvider interface {
+	// Name returns the model_list alias the provider was created from.
+	Name() string
+	Generate(ctx context.Context, req Request) ([]Image, error)
+}
+
+// Request describes the images to generate.
+type Request struct {
+	Prompt         string
+	NegativePrompt string
+	// Size is "WIDTHxHEIGHT"; empty selects the backend's default.
+	Size string
+	N    int
+}
+
+// Image is one generated image.
+type Image struct {
+	Data        []byte
+	ContentType string
+	// RevisedPrompt is the prompt the backend actually used, when it rewrites
+	// prompts (DALL·E 3 does).
+	RevisedPrompt string
+}
+
+// NewProvider creates the backend for a model_list entry. timeout applies to
+// the whole generation and is overridden by the entry's request_timeout.
+func NewProvider(mc *config.ModelConfig, timeout time.Duration) (ImageProvider, error) {
+	if mc == nil {
+		return nil, fmt.Errorf("model config is nil")
+	}
+	if mc.RequestTimeout > 0 {
+		timeout = time.Duration(mc.RequestTimeout) * time.Second
+	}
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	client, err := newHTTPClient(mc.Proxy, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	protocol, modelID := providers.ExtractProtocol(mc.Model)
+	switch protocol {
+	case "sdwebui":
+		return &sdWebUIProvider{
+			name:    mc.ModelName,
+			apiBase: apiBaseOrDefault(mc.APIBase, "http://127.0.0.1:7860"),
+			apiKey:  mc.APIKey,
+			model:   modelID,
+			client:  client,
+		}, nil
+	case "comfyui":
+		if modelID == "" {
+			return nil, fmt.Errorf("comfyui model %q needs a checkpoint name, e.g. comfyui/sd_xl_base_1.0.safetensors", mc.ModelName)
+		}
+		return &comfyUIProvider{
+			name:         mc.ModelName,
+			apiBase:      apiBaseOrDefault(mc.APIBase, "http://127.0.0.1:8188"),
+			apiKey:       mc.APIKey,
+			model:        modelID,
+			client:       client,
+			pollInterval: time.Second,
+		}, nil
+	case "openai":
+		return &openAIProvider{
+			name:    mc.ModelName,
+			apiBase: apiBaseOrDefault(mc.APIBase, "https://api.openai.com/v1"),
+			apiKey:  mc.APIKey,
+			model:   modelID,
+			client:  client,
+		}, nil
+	default:
+		// Other protocols are OpenAI-compatible gateways (litellm, openrouter,
+		// ...) and have no well-known images endpoint.
+		if mc.APIBase == "" {
+			return nil, fmt.Errorf("api_base is required for image model %q with protocol %q", mc.ModelName, protocol)
+		}
+		return &openAIProvider{
+			name:    mc.ModelName,
+			apiBase: strings.TrimRight(mc.APIBase, "/"),
+			apiKey:  mc.APIKey,
+			model:   modelID,
+			client:  client,
+		}, nil
+	}
+}
+
+func apiBaseOrDefault(apiBase, fallback string) string {
+	if apiBase == "" {
+		return fallback
+	}
+	return strings.TrimRight(apiBase, "/")
+}
+
+func newHTTPClient(proxy string, timeout time.Duration) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	return &http.Client{Timeout: timeout, Transport: egress.Guard(transport)}, nil
+}
+
+// ParseSize parses "WIDTHxHEIGHT". Empty input returns the fallback size.
+func ParseSize(size string, fallbackW, fallbackH int) (w, h int, err error) {
+	size = strings.TrimSpace(strings.ToLower(size))
+	if size == "" {
+		return fallbackW, fallbackH, nil
+	}
+	ws, hs, ok := strings.Cut(size, "x")
+	if ok {
+		w, err = strconv.Atoi(ws)
+		if err == nil {
+			h, err = strconv.Atoi(hs)
+		}
+	}
+	if !ok || err != nil || w < 64 || h < 64 || w > 4096 || h > 4096 {
+		return 0, 0, fmt.Errorf("invalid size %q: expected WIDTHxHEIGHT between 64 and 4096, e.g. 1024x1024", size)
+	}
+	return w, h, nil
+}
+
+func clampCount(n int) int {
+	return min(max(n, 1), MaxImages)
+}
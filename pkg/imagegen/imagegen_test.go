@@ -0,0 +1,212 @@
+package imagegen
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func testPNG(t *testing.T) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(0, 0, color.RGBA{255, 0, 0, 255})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func newTestProvider(t *testing.T, model, apiBase, apiKey string) ImageProvider {
+	t.Helper()
+	p, err := NewProvider(&config.ModelConfig{ModelName: "test", Model: model, APIBase: apiBase, APIKey: apiKey}, 5*time.Second)
+	if err != nil {
+		t.Fatalf("NewProvider(%s): %v", model, err)
+	}
+	if cp, ok := p.(*comfyUIProvider); ok {
+		cp.pollInterval = 10 * time.Millisecond
+	}
+	return p
+}
+
+func TestOpenAIProvider(t *testing.T) {
+	pngData := testPNG(t)
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/files/img.png" {
+			w.Write(pngData)
+			return
+		}
+		if r.URL.Path != "/v1/images/generations" || r.Header.Get("Authorization") != "Bearer sk-test" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["model"] == "gpt-image-1" {
+			if _, ok := body["response_format"]; ok {
+				http.Error(w, "Unknown parameter: 'response_format'", http.StatusBadRequest)
+				return
+			}
+			fmt.Fprintf(w, `{"data":[{"b64_json":%q}]}`, base64.StdEncoding.EncodeToString(pngData))
+			return
+		}
+		fmt.Fprintf(w, `{"data":[{"url":"%s/files/img.png","revised_prompt":"a red pixel, digital art"}]}`, server.URL)
+	}))
+	defer server.Close()
+
+	images, err := newTestProvider(t, "openai/gpt-image-1", server.URL+"/v1", "sk-test").
+		Generate(context.Background(), Request{Prompt: "a red pixel", N: 1})
+	if err != nil {
+		t.Fatalf("gpt-image-1: %v", err)
+	}
+	if len(images) != 1 || images[0].ContentType != "image/png" || !bytes.Equal(images[0].Data, pngData) {
+		t.Errorf("unexpected images: %+v", images)
+	}
+
+	images, err = newTestProvider(t, "openai/dall-e-3", server.URL+"/v1", "sk-test").
+		Generate(context.Background(), Request{Prompt: "a red pixel"})
+	if err != nil {
+		t.Fatalf("dall-e-3: %v", err)
+	}
+	if len(images) != 1 || images[0].RevisedPrompt != "a red pixel, digital art" || !bytes.Equal(images[0].Data, pngData) {
+		t.Errorf("expected the image URL to be downloaded, got %+v", images)
+	}
+
+	_, err = newTestProvider(t, "openai/dall-e-3", server.URL+"/v1", "wrong").
+		Generate(context.Background(), Request{Prompt: "x"})
+	if err == nil {
+		t.Error("expected API error to be returned")
+	}
+}
+
+func TestSDWebUIProvider(t *testing.T) {
+	pngData := testPNG(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "admin" || pass != "secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var body struct {
+			Width     int `json:"width"`
+			Height    int `json:"height"`
+			BatchSize int `json:"batch_size"`
+			Override  struct {
+				Checkpoint string `json:"sd_model_checkpoint"`
+			} `json:"override_settings"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if r.URL.Path != "/sdapi/v1/txt2img" || body.Width != 768 || body.Height != 512 || body.Override.Checkpoint != "dream.safetensors" {
+			http.Error(w, fmt.Sprintf("unexpected request %s %+v", r.URL.Path, body), http.StatusBadRequest)
+			return
+		}
+		images := make([]string, body.BatchSize)
+		for i := range images {
+			images[i] = base64.StdEncoding.EncodeToString(pngData)
+		}
+		json.NewEncoder(w).Encode(map[string]any{"images": images})
+	}))
+	defer server.Close()
+
+	images, err := newTestProvider(t, "sdwebui/dream.safetensors", server.URL, "admin:secret").
+		Generate(context.Background(), Request{Prompt: "castle", Size: "768x512", N: 2})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if len(images) != 2 || images[1].ContentType != "image/png" {
+		t.Errorf("unexpected images: %+v", images)
+	}
+}
+
+func TestComfyUIProvider(t *testing.T) {
+	pngData := testPNG(t)
+	var polls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/prompt":
+			var body struct {
+				Prompt map[string]struct {
+					ClassType string         `json:"class_type"`
+					Inputs    map[string]any `json:"inputs"`
+				} `json:"prompt"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			if body.Prompt["4"].Inputs["ckpt_name"] != "v1-5.ckpt" || body.Prompt["6"].Inputs["text"] != "a cat" {
+				http.Error(w, "bad workflow", http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, `{"prompt_id":"p1","number":0}`)
+		case "/history/p1":
+			if polls.Add(1) < 3 {
+				fmt.Fprint(w, `{}`)
+				return
+			}
+			fmt.Fprint(w, `{"p1":{"outputs":{"9":{"images":[{"filename":"picoclaw_00001_.png","subfolder":"","type":"output"}]}},"status":{"status_str":"success","completed":true}}}`)
+		case "/view":
+			if r.URL.Query().Get("filename") != "picoclaw_00001_.png" || r.URL.Query().Get("type") != "output" {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write(pngData)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	images, err := newTestProvider(t, "comfyui/v1-5.ckpt", server.URL, "").
+		Generate(context.Background(), Request{Prompt: "a cat"})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if len(images) != 1 || !bytes.Equal(images[0].Data, pngData) {
+		t.Errorf("unexpected images: %+v", images)
+	}
+	if polls.Load() < 3 {
+		t.Errorf("expected history to be polled until completion, got %d polls", polls.Load())
+	}
+}
+
+func TestNewProvider(t *testing.T) {
+	for _, tc := range []struct {
+		model, apiBase string
+		wantErr        bool
+	}{
+		{"openai/dall-e-3", "", false},
+		{"sdwebui/", "", false},
+		{"comfyui/", "", true},
+		{"litellm/flux", "", true},
+		{"litellm/flux", "http://gateway:4000/v1", false},
+	} {
+		_, err := NewProvider(&config.ModelConfig{ModelName: "m", Model: tc.model, APIBase: tc.apiBase}, 0)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("NewProvider(%q, %q) error = %v, wantErr %v", tc.model, tc.apiBase, err, tc.wantErr)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	if w, h, err := ParseSize("", 512, 512); err != nil || w != 512 || h != 512 {
+		t.Errorf("empty size should use fallback, got %d %d %v", w, h, err)
+	}
+	if w, h, err := ParseSize("1024X768", 0, 0); err != nil || w != 1024 || h != 768 {
+		t.Errorf("ParseSize(1024X768) = %d %d %v", w, h, err)
+	}
+	for _, bad := range []string{"big", "1024", "10x10", "99999x1"} {
+		if _, _, err := ParseSize(bad, 0, 0); err == nil {
+			t.Errorf("ParseSize(%q) should fail", bad)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package imagegen
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxImageBytes bounds a single downloaded or decoded image.
+const maxImageBytes = 32 << 20
+
+// openAIProvider calls the OpenAI Images API or a compatible endpoint.
+type openAIProvider struct {
+	name    string
+	apiBase string
+	apiKey  string
+	model   string
+	client  *http.Client
+}
+
+func (p *openAIProvider) Name() string { return p.name }
+
+func (p *openAIProvider) Generate(ctx context.Context, req Request) ([]Image, error) {
+	prompt := req.Prompt
+	if req.NegativePrompt != "" {
+		// The Images API has no negative prompt; state it in the prompt.
+		prompt += "\n\nAvoid: " + req.NegativePrompt
+	}
+	body := map[string]any{
+		"model":  p.model,
+		"prompt": prompt,
+		"n":      clampCount(req.N),
+	}
+	if req.Size != "" {
+		body["size"] = req.Size
+	}
+	// gpt-image models always return base64 and reject response_format.
+	if !strings.HasPrefix(p.model, "gpt-image") {
+		body["response_format"] = "b64_json"
+	}
+
+	var resp struct {
+		Data []struct {
+			B64JSON       string `json:"b64_json"`
+			URL           string `json:"url"`
+			RevisedPrompt string `json:"revised_prompt"`
+		} `json:"data"`
+	}
+	if err := postJSON(ctx, p.client, p.apiBase+"/images/generations", bearer(p.apiKey), body, &resp); err != nil {
+		return nil, err
+	}
+
+	images := make([]Image, 0, len(resp.Data))
+	for _, d := range resp.Data {
+		var data []byte
+		var err error
+		switch {
+		case d.B64JSON != "":
+			data, err = base64.StdEncoding.DecodeString(d.B64JSON)
+		case d.URL != "":
+			data, err = download(ctx, p.client, d.URL)
+		default:
+			err = fmt.Errorf("response contained no image data")
+		}
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, Image{
+			Data:          data,
+			ContentType:   http.DetectContentType(data),
+			RevisedPrompt: d.RevisedPrompt,
+		})
+	}
+	if len(images) == 0 {
+		return nil, fmt.Errorf("no images returned")
+	}
+	return images, nil
+}
+
+func bearer(apiKey string) func(*http.Request) {
+	return func(r *http.Request) {
+		if apiKey != "" {
+			r.Header.Set("Authorization", "Bearer "+apiKey)
+		}
+	}
+}
+
+// postJSON sends body as JSON and decodes a JSON response into out.
+func postJSON(ctx context.Context, client *http.Client, url string, auth func(*http.Request), body, out any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	auth(req)
+	return doJSON(client, req, out)
+}
+
+func doJSON(client *http.Client, req *http.Request, out any) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("API error (status %d): %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	// Base64 images make these responses large.
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 4*maxImageBytes*MaxImages)).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+func download(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	return fetchBody(client, req)
+}
+
+// fetchBody performs req and returns the response body of an image.
+func fetchBody(client *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download image: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download image: status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download image: %w", err)
+	}
+	if len(data) > maxImageBytes {
+		return nil, fmt.Errorf("image exceeds %d bytes", maxImageBytes)
+	}
+	return data, nil
+}
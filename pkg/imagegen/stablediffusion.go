@@ -0,0 +1,218 @@
+package imagegen
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// setAuth authenticates requests to a local backend. Both WebUIs are usually
+// reached directly or through a reverse proxy: a "user:password" key is sent
+// as basic auth (WebUI's --api-auth), anything else as a bearer token.
+func setAuth(apiKey string) func(*http.Request) {
+	return func(r *http.Request) {
+		if user, pass, ok := strings.Cut(apiKey, ":"); ok {
+			r.SetBasicAuth(user, pass)
+		} else if apiKey != "" {
+			r.Header.Set("Authorization", "Bearer "+apiKey)
+		}
+	}
+}
+
+// sdWebUIProvider calls the txt2img endpoint of AUTOMATIC1111's Stable
+// Diffusion WebUI (and API-compatible forks such as Forge).
+type sdWebUIProvider struct {
+	name    string
+	apiBase string
+	apiKey  string
+	model   string // checkpoint; empty keeps the WebUI's current one
+	client  *http.Client
+}
+
+func (p *sdWebUIProvider) Name() string { return p.name }
+
+func (p *sdWebUIProvider) Generate(ctx context.Context, req Request) ([]Image, error) {
+	w, h, err := ParseSize(req.Size, 512, 512)
+	if err != nil {
+		return nil, err
+	}
+	body := map[string]any{
+		"prompt":          req.Prompt,
+		"negative_prompt": req.NegativePrompt,
+		"width":           w,
+		"height":          h,
+		"batch_size":      clampCount(req.N),
+		"steps":           25,
+	}
+	if p.model != "" {
+		body["override_settings"] = map[string]any{"sd_model_checkpoint": p.model}
+	}
+
+	var resp struct {
+		Images []string `json:"images"`
+	}
+	if err := postJSON(ctx, p.client, p.apiBase+"/sdapi/v1/txt2img", setAuth(p.apiKey), body, &resp); err != nil {
+		return nil, err
+	}
+
+	images := make([]Image, 0, len(resp.Images))
+	for _, b64 := range resp.Images {
+		data, err := base64.StdEncoding.DecodeString(b64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode image: %w", err)
+		}
+		images = append(images, Image{Data: data, ContentType: http.DetectContentType(data)})
+	}
+	if len(images) == 0 {
+		return nil, fmt.Errorf("no images returned")
+	}
+	return images, nil
+}
+
+// comfyUIProvider queues a basic txt2img workflow on a ComfyUI server and
+// polls its history until the images are saved.
+type comfyUIProvider struct {
+	name         string
+	apiBase      string
+	apiKey       string
+	model        string // checkpoint file name
+	client       *http.Client
+	pollInterval time.Duration
+}
+
+func (p *comfyUIProvider) Name() string { return p.name }
+
+func (p *comfyUIProvider) Generate(ctx context.Context, req Request) ([]Image, error) {
+	w, h, err := ParseSize(req.Size, 512, 512)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.client.Timeout)
+	defer cancel()
+
+	var queued struct {
+		PromptID string `json:"prompt_id"`
+	}
+	body := map[string]any{
+		"prompt":    comfyWorkflow(p.model, req.Prompt, req.NegativePrompt, w, h, clampCount(req.N)),
+		"client_id": uuid.NewString(),
+	}
+	if err := postJSON(ctx, p.client, p.apiBase+"/prompt", setAuth(p.apiKey), body, &queued); err != nil {
+		return nil, err
+	}
+	if queued.PromptID == "" {
+		return nil, fmt.Errorf("comfyui did not return a prompt_id")
+	}
+
+	outputs, err := p.waitForOutputs(ctx, queued.PromptID)
+	if err != nil {
+		return nil, err
+	}
+
+	var images []Image
+	for _, o := range outputs {
+		q := url.Values{"filename": {o.Filename}, "subfolder": {o.Subfolder}, "type": {o.Type}}
+		r, err := http.NewRequestWithContext(ctx, http.MethodGet, p.apiBase+"/view?"+q.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		setAuth(p.apiKey)(r)
+		data, err := fetchBody(p.client, r)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, Image{Data: data, ContentType: http.DetectContentType(data)})
+	}
+	if len(images) == 0 {
+		return nil, fmt.Errorf("no images returned")
+	}
+	return images, nil
+}
+
+type comfyImage struct {
+	Filename  string `json:"filename"`
+	Subfolder string `json:"subfolder"`
+	Type      string `json:"type"`
+}
+
+func (p *comfyUIProvider) waitForOutputs(ctx context.Context, promptID string) ([]comfyImage, error) {
+	ticker := time.NewTicker(p.pollInterval)
+	defer ticker.Stop()
+	for {
+		r, err := http.NewRequestWithContext(ctx, http.MethodGet, p.apiBase+"/history/"+url.PathEscape(promptID), nil)
+		if err != nil {
+			return nil, err
+		}
+		setAuth(p.apiKey)(r)
+		var history map[string]struct {
+			Outputs map[string]struct {
+				Images []comfyImage `json:"images"`
+			} `json:"outputs"`
+			Status struct {
+				StatusStr string `json:"status_str"`
+				Completed bool   `json:"completed"`
+				Messages  []any  `json:"messages"`
+			} `json:"status"`
+		}
+		if err := doJSON(p.client, r, &history); err != nil {
+			return nil, err
+		}
+
+		if entry, ok := history[promptID]; ok {
+			if entry.Status.StatusStr == "error" {
+				detail, _ := json.Marshal(entry.Status.Messages)
+				return nil, fmt.Errorf("comfyui workflow failed: %s", detail)
+			}
+			if entry.Status.Completed {
+				var images []comfyImage
+				for _, out := range entry.Outputs {
+					images = append(images, out.Images...)
+				}
+				return images, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timed out waiting for comfyui: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// comfyWorkflow builds the API-format graph of ComfyUI's default txt2img
+// workflow.
+func comfyWorkflow(checkpoint, prompt, negative string, width, height, batch int) map[string]any {
+	return map[string]any{
+		"4": node("CheckpointLoaderSimple", map[string]any{"ckpt_name": checkpoint}),
+		"5": node("EmptyLatentImage", map[string]any{"width": width, "height": height, "batch_size": batch}),
+		"6": node("CLIPTextEncode", map[string]any{"text": prompt, "clip": []any{"4", 1}}),
+		"7": node("CLIPTextEncode", map[string]any{"text": negative, "clip": []any{"4", 1}}),
+		"3": node("KSampler", map[string]any{
+			"seed":         rand.Int64N(1 << 48),
+			"steps":        25,
+			"cfg":          7,
+			"sampler_name": "euler",
+			"scheduler":    "normal",
+			"denoise":      1,
+			"model":        []any{"4", 0},
+			"positive":     []any{"6", 0},
+			"negative":     []any{"7", 0},
+			"latent_image": []any{"5", 0},
+		}),
+		"8": node("VAEDecode", map[string]any{"samples": []any{"3", 0}, "vae": []any{"4", 2}}),
+		"9": node("SaveImage", map[string]any{"filename_prefix": "picoclaw", "images": []any{"8", 0}}),
+	}
+}
+
+func node(class string, inputs map[string]any) map[string]any {
+	return map[string]any{"class_type": class, "inputs": inputs}
+}
@@ -1,7 +1,9 @@
 package logger
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -35,6 +37,7 @@ var (
 	logger       zerolog.Logger
 	fileLogger   zerolog.Logger
 	logFile      *os.File
+	redactor     func(string) string
 	once         sync.Once
 	mu           sync.RWMutex
 )
@@ -43,19 +46,30 @@ func init() {
 	once.Do(func() {
 		zerolog.SetGlobalLevel(zerolog.InfoLevel)
 
-		consoleWriter := zerolog.ConsoleWriter{
-			Out:        os.Stdout,
-			TimeFormat: "15:04:05", // TODO: make it configurable???
-
-			// Custom formatter to handle multiline strings and JSON objects
-			FormatFieldValue: formatFieldValue,
-		}
-
-		logger = zerolog.New(consoleWriter).With().Timestamp().Logger()
+		logger = newConsoleLogger(os.Stdout)
 		fileLogger = zerolog.Logger{}
 	})
 }
 
+func newConsoleLogger(out io.Writer) zerolog.Logger {
+	consoleWriter := zerolog.ConsoleWriter{
+		Out:        out,
+		TimeFormat: "15:04:05", // TODO: make it configurable???
+
+		// Custom formatter to handle multiline strings and JSON objects
+		FormatFieldValue: formatFieldValue,
+	}
+	return zerolog.New(consoleWriter).With().Timestamp().Logger()
+}
+
+// SetConsoleOutput redirects console logging, for example to stderr when
+// stdout carries a protocol stream. Call it before logging starts.
+func SetConsoleOutput(out io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+	logger = newConsoleLogger(out)
+}
+
 func formatFieldValue(i any) string {
 	var s string
 
@@ -100,6 +114,14 @@ func GetLevel() LogLevel {
 	return currentLevel
 }
 
+// SetRedactor installs fn to mask secrets in log messages and field values.
+// A nil fn disables masking.
+func SetRedactor(fn func(string) string) {
+	mu.Lock()
+	defer mu.Unlock()
+	redactor = fn
+}
+
 func EnableFileLogging(filePath string) error {
 	mu.Lock()
 	defer mu.Unlock()
@@ -189,6 +211,13 @@ func logMessage(level LogLevel, component string, message string, fields map[str
 
 	callerFile, callerLine, callerFunc := getCallerInfo()
 
+	mu.RLock()
+	redact := redactor
+	mu.RUnlock()
+	if redact != nil {
+		message = redact(message)
+	}
+
 	event := getEvent(logger, level)
 
 	// Build combined field with component and caller
@@ -198,7 +227,7 @@ func logMessage(level LogLevel, component string, message string, fields map[str
 		event.Str("caller", fmt.Sprintf("<none> %s:%d (%s)", callerFile, callerLine, callerFunc))
 	}
 
-	appendFields(event, fields)
+	appendFields(event, fields, redact)
 	event.Msg(message)
 
 	// Also log to file if enabled
@@ -209,7 +238,7 @@ func logMessage(level LogLevel, component string, message string, fields map[str
 			fileEvent.Str("component", component)
 		}
 
-		appendFields(fileEvent, fields)
+		appendFields(fileEvent, fields, redact)
 		fileEvent.Msg(message)
 	}
 
@@ -218,11 +247,14 @@ func logMessage(level LogLevel, component string, message string, fields map[str
 	}
 }
 
-func appendFields(event *zerolog.Event, fields map[string]any) {
+func appendFields(event *zerolog.Event, fields map[string]any, redact func(string) string) {
 	for k, v := range fields {
 		// Type switch to avoid double JSON serialization of strings
 		switch val := v.(type) {
 		case string:
+			if redact != nil {
+				val = redact(val)
+			}
 			event.Str(k, val)
 		case int:
 			event.Int(k, val)
@@ -233,6 +265,17 @@ func appendFields(event *zerolog.Event, fields map[string]any) {
 		case bool:
 			event.Bool(k, val)
 		default:
+			if redact != nil {
+				if data, err := json.Marshal(v); err == nil {
+					masked := []byte(redact(string(data)))
+					if json.Valid(masked) {
+						event.RawJSON(k, masked)
+					} else {
+						event.Str(k, string(masked))
+					}
+					continue
+				}
+			}
 			event.Interface(k, v) // Fallback for struct, slice and maps
 		}
 	}
@@ -1,6 +1,9 @@
 package logger
 
 import (
+	"bytes"
+	"os"
+	"strings"
 	"testing"
 )
 
@@ -252,3 +255,24 @@ func TestFormatFieldValue(t *testing.T) {
 		})
 	}
 }
+
+func TestSetRedactorMasksMessageAndFields(t *testing.T) {
+	var buf bytes.Buffer
+	SetConsoleOutput(&buf)
+	defer SetConsoleOutput(os.Stdout)
+	SetRedactor(func(s string) string { return strings.ReplaceAll(s, "s3cr3t-value", "[REDACTED]") })
+	defer SetRedactor(nil)
+
+	InfoCF("test", "token is s3cr3t-value", map[string]any{
+		"header": "Bearer s3cr3t-value",
+		"nested": map[string]string{"key": "s3cr3t-value"},
+	})
+
+	out := buf.String()
+	if strings.Contains(out, "s3cr3t-value") {
+		t.Fatalf("secret leaked into log output: %s", out)
+	}
+	if !strings.Contains(out, "[REDACTED]") {
+		t.Fatalf("expected redaction placeholder in log output: %s", out)
+	}
+}
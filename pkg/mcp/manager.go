@@ -12,10 +12,12 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/egress"
 	"github.com/sipeed/picoclaw/pkg/logger"
 )
 
@@ -99,10 +101,16 @@ func loadEnvFile(path string) (map[string]string, error) {
 
 // ServerConnection represents a connection to an MCP server
 type ServerConnection struct {
-	Name    string
-	Client  *mcp.Client
-	Session *mcp.ClientSession
-	Tools   []*mcp.Tool
+	Name              string
+	Client            *mcp.Client
+	Session           *mcp.ClientSession
+	Tools             []*mcp.Tool
+	Resources         []*mcp.Resource
+	ResourceTemplates []*mcp.ResourceTemplate
+	Prompts           []*mcp.Prompt
+	// SupportsSubscribe reports whether the server sends
+	// notifications/resources/updated for subscribed resources.
+	SupportsSubscribe bool
 }
 
 // Manager manages multiple MCP server connections
@@ -111,12 +119,44 @@ type Manager struct {
 	mu      sync.RWMutex
 	closed  atomic.Bool    // changed from bool to atomic.Bool to avoid TOCTOU race
 	wg      sync.WaitGroup // tracks in-flight CallTool calls
+
+	// Resource contents are cached only while subscribed, so a server
+	// update notification can invalidate them. Keys are resourceKey values.
+	resourceCache  map[string]*mcp.ReadResourceResult
+	subscribed     map[string]bool
+	invalidations  uint64
+	promptsChanged func(serverName string, prompts []*mcp.Prompt)
+	toolsChanged   func(serverName string, tools []*mcp.Tool)
+
+	// Supervision state, keyed by server name. ctx outlives individual
+	// connections: stdio processes and reconnect loops run under a per-server
+	// child context that RemoveServer and Close cancel.
+	ctx         context.Context
+	cancel      context.CancelFunc
+	configs     map[string]config.MCPServerConfig
+	status      map[string]*ServerStatus
+	supervisors map[string]context.CancelFunc
+
+	healthInterval time.Duration
+	minBackoff     time.Duration
+	maxBackoff     time.Duration
 }
 
 // NewManager creates a new MCP manager
 func NewManager() *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Manager{
-		servers: make(map[string]*ServerConnection),
+		servers:        make(map[string]*ServerConnection),
+		resourceCache:  make(map[string]*mcp.ReadResourceResult),
+		subscribed:     make(map[string]bool),
+		ctx:            ctx,
+		cancel:         cancel,
+		configs:        make(map[string]config.MCPServerConfig),
+		status:         make(map[string]*ServerStatus),
+		supervisors:    make(map[string]context.CancelFunc),
+		healthInterval: defaultHealthInterval,
+		minBackoff:     defaultMinBackoff,
+		maxBackoff:     defaultMaxBackoff,
 	}
 }
 
@@ -127,6 +167,7 @@ func (m *Manager) LoadFromConfig(ctx context.Context, cfg *config.Config) error
 
 // LoadFromMCPConfig loads MCP servers from MCP configuration and workspace path.
 // This is the minimal dependency version that doesn't require the full Config object.
+// Servers that fail to connect keep being retried in the background.
 func (m *Manager) LoadFromMCPConfig(
 	ctx context.Context,
 	mcpCfg config.MCPConfig,
@@ -147,11 +188,50 @@ func (m *Manager) LoadFromMCPConfig(
 			"count": len(mcpCfg.Servers),
 		})
 
+	enabledCount, allErrors := m.connectServers(ctx, mcpCfg.Servers, workspacePath)
+	connectedCount := len(m.GetServers())
+
+	// If all enabled servers failed to connect, return aggregated error
+	if enabledCount > 0 && connectedCount == 0 {
+		logger.ErrorCF("mcp", "All MCP servers failed to connect",
+			map[string]any{
+				"failed": len(allErrors),
+				"total":  enabledCount,
+			})
+		return errors.Join(allErrors...)
+	}
+
+	if len(allErrors) > 0 {
+		logger.WarnCF("mcp", "Some MCP servers failed to connect",
+			map[string]any{
+				"failed":    len(allErrors),
+				"connected": connectedCount,
+				"total":     enabledCount,
+			})
+		// Don't fail completely if some servers successfully connected
+	}
+
+	logger.InfoCF("mcp", "MCP server initialization complete",
+		map[string]any{
+			"connected": connectedCount,
+			"total":     enabledCount,
+		})
+
+	return nil
+}
+
+// connectServers connects the enabled servers in parallel and returns how
+// many were enabled along with the connection errors.
+func (m *Manager) connectServers(
+	ctx context.Context,
+	servers map[string]config.MCPServerConfig,
+	workspacePath string,
+) (int, []error) {
 	var wg sync.WaitGroup
-	errs := make(chan error, len(mcpCfg.Servers))
+	errs := make(chan error, len(servers))
 	enabledCount := 0
 
-	for name, serverCfg := range mcpCfg.Servers {
+	for name, serverCfg := range servers {
 		if !serverCfg.Enabled {
 			logger.DebugCF("mcp", "Skipping disabled server",
 				map[string]any{
@@ -162,30 +242,23 @@ func (m *Manager) LoadFromMCPConfig(
 
 		enabledCount++
 		wg.Add(1)
-		go func(name string, serverCfg config.MCPServerConfig, workspace string) {
+		go func(name string, serverCfg config.MCPServerConfig) {
 			defer wg.Done()
 
-			// Resolve relative envFile paths relative to workspace
-			if serverCfg.EnvFile != "" && !filepath.IsAbs(serverCfg.EnvFile) {
-				if workspace == "" {
-					err := fmt.Errorf(
-						"workspace path is empty while resolving relative envFile %q for server %s",
-						serverCfg.EnvFile,
-						name,
-					)
-					logger.ErrorCF("mcp", "Invalid MCP server configuration",
-						map[string]any{
-							"server":   name,
-							"env_file": serverCfg.EnvFile,
-							"error":    err.Error(),
-						})
-					errs <- err
-					return
-				}
-				serverCfg.EnvFile = filepath.Join(workspace, serverCfg.EnvFile)
+			resolved, err := resolveServerConfig(name, serverCfg, workspacePath)
+			if err != nil {
+				logger.ErrorCF("mcp", "Invalid MCP server configuration",
+					map[string]any{
+						"server":   name,
+						"env_file": serverCfg.EnvFile,
+						"error":    err.Error(),
+					})
+				m.setFailed(name, err)
+				errs <- err
+				return
 			}
 
-			if err := m.ConnectServer(ctx, name, serverCfg); err != nil {
+			if err := m.ConnectServer(ctx, name, resolved); err != nil {
 				logger.ErrorCF("mcp", "Failed to connect to MCP server",
 					map[string]any{
 						"server": name,
@@ -193,50 +266,43 @@ func (m *Manager) LoadFromMCPConfig(
 					})
 				errs <- fmt.Errorf("failed to connect to server %s: %w", name, err)
 			}
-		}(name, serverCfg, workspacePath)
+		}(name, serverCfg)
 	}
 
 	wg.Wait()
 	close(errs)
 
-	// Collect errors
 	var allErrors []error
 	for err := range errs {
 		allErrors = append(allErrors, err)
 	}
+	return enabledCount, allErrors
+}
 
-	connectedCount := len(m.GetServers())
-
-	// If all enabled servers failed to connect, return aggregated error
-	if enabledCount > 0 && connectedCount == 0 {
-		logger.ErrorCF("mcp", "All MCP servers failed to connect",
-			map[string]any{
-				"failed": len(allErrors),
-				"total":  enabledCount,
-			})
-		return errors.Join(allErrors...)
-	}
-
-	if len(allErrors) > 0 {
-		logger.WarnCF("mcp", "Some MCP servers failed to connect",
-			map[string]any{
-				"failed":    len(allErrors),
-				"connected": connectedCount,
-				"total":     enabledCount,
-			})
-		// Don't fail completely if some servers successfully connected
+// resolveServerConfig resolves a relative envFile against the workspace.
+func resolveServerConfig(
+	name string,
+	serverCfg config.MCPServerConfig,
+	workspace string,
+) (config.MCPServerConfig, error) {
+	if serverCfg.EnvFile != "" && !filepath.IsAbs(serverCfg.EnvFile) {
+		if workspace == "" {
+			return serverCfg, fmt.Errorf(
+				"workspace path is empty while resolving relative envFile %q for server %s",
+				serverCfg.EnvFile,
+				name,
+			)
+		}
+		serverCfg.EnvFile = filepath.Join(workspace, serverCfg.EnvFile)
 	}
-
-	logger.InfoCF("mcp", "MCP server initialization complete",
-		map[string]any{
-			"connected": connectedCount,
-			"total":     enabledCount,
-		})
-
-	return nil
+	return serverCfg, nil
 }
 
-// ConnectServer connects to a single MCP server
+// ConnectServer connects to a single MCP server and supervises the
+// connection: it is re-established with backoff whenever it drops. If the
+// first attempt fails for a reason other than invalid configuration, the
+// error is returned and the server keeps being retried in the background
+// until RemoveServer or Close.
 func (m *Manager) ConnectServer(
 	ctx context.Context,
 	name string,
@@ -249,15 +315,56 @@ func (m *Manager) ConnectServer(
 			"args_count": len(cfg.Args),
 		})
 
-	// Create client
-	client := mcp.NewClient(&mcp.Implementation{
+	serverCtx, err := m.supervise(name, cfg)
+	if err != nil {
+		return err
+	}
+
+	transport, err := m.newTransport(serverCtx, name, cfg)
+	if err != nil {
+		m.setFailed(name, err)
+		return err
+	}
+
+	conn, err := m.dial(ctx, name, transport)
+	if err != nil {
+		m.setRetrying(name, 0, err)
+		go m.reconnect(serverCtx, name)
+		return err
+	}
+
+	m.attach(serverCtx, name, conn)
+	return nil
+}
+
+func (m *Manager) newClient(name string) *mcp.Client {
+	return mcp.NewClient(&mcp.Implementation{
 		Name:    "picoclaw",
 		Version: "1.0.0",
-	}, nil)
+	}, &mcp.ClientOptions{
+		ResourceUpdatedHandler: func(_ context.Context, req *mcp.ResourceUpdatedNotificationRequest) {
+			m.invalidateResource(name, req.Params.URI)
+		},
+		// Re-listing issues requests on the session, which must not happen
+		// from inside its notification handler.
+		ToolListChangedHandler: func(context.Context, *mcp.ToolListChangedRequest) {
+			go m.refreshTools(name)
+		},
+		ResourceListChangedHandler: func(context.Context, *mcp.ResourceListChangedRequest) {
+			go m.refreshResources(name)
+		},
+		PromptListChangedHandler: func(context.Context, *mcp.PromptListChangedRequest) {
+			go m.refreshPrompts(name)
+		},
+		// A failed ping closes the session, which the supervisor notices.
+		KeepAlive: m.healthInterval,
+	})
+}
 
-	// Create transport based on configuration
+// newTransport builds the transport for cfg. Errors are configuration
+// problems that retrying cannot fix. Stdio processes are bound to ctx.
+func (m *Manager) newTransport(ctx context.Context, name string, cfg config.MCPServerConfig) (mcp.Transport, error) {
 	// Auto-detect transport type if not explicitly specified
-	var transport mcp.Transport
 	transportType := cfg.Type
 
 	// Auto-detect: if URL is provided, use SSE; if command is provided, use stdio
@@ -267,14 +374,14 @@ func (m *Manager) ConnectServer(
 		} else if cfg.Command != "" {
 			transportType = "stdio"
 		} else {
-			return fmt.Errorf("either URL or command must be provided")
+			return nil, fmt.Errorf("either URL or command must be provided")
 		}
 	}
 
 	switch transportType {
 	case "sse", "http":
 		if cfg.URL == "" {
-			return fmt.Errorf("URL is required for SSE/HTTP transport")
+			return nil, fmt.Errorf("URL is required for SSE/HTTP transport")
 		}
 		logger.DebugCF("mcp", "Using SSE/HTTP transport",
 			map[string]any{
@@ -286,14 +393,24 @@ func (m *Manager) ConnectServer(
 			Endpoint: cfg.URL,
 		}
 
+		// MCP servers are reached on behalf of agents, so they obey the
+		// egress policy like other tool traffic.
+		var roundTripper http.RoundTripper = egress.Guard(http.DefaultTransport.(*http.Transport).Clone())
+
+		// Authorize requests with the tokens from `picoclaw auth mcp`
+		if cfg.OAuth != nil && cfg.OAuth.Enabled {
+			roundTripper = newOAuthTransport(roundTripper, name, cfg.URL)
+			logger.DebugCF("mcp", "Using OAuth authorization",
+				map[string]any{
+					"server": name,
+				})
+		}
+
 		// Add custom headers if provided
 		if len(cfg.Headers) > 0 {
-			// Create a custom HTTP client with header-injecting transport
-			sseTransport.HTTPClient = &http.Client{
-				Transport: &headerTransport{
-					base:    http.DefaultTransport,
-					headers: cfg.Headers,
-				},
+			roundTripper = &headerTransport{
+				base:    roundTripper,
+				headers: cfg.Headers,
 			}
 			logger.DebugCF("mcp", "Added custom HTTP headers",
 				map[string]any{
@@ -302,10 +419,12 @@ func (m *Manager) ConnectServer(
 				})
 		}
 
-		transport = sseTransport
+		sseTransport.HTTPClient = &http.Client{Transport: roundTripper}
+
+		return sseTransport, nil
 	case "stdio":
 		if cfg.Command == "" {
-			return fmt.Errorf("command is required for stdio transport")
+			return nil, fmt.Errorf("command is required for stdio transport")
 		}
 		logger.DebugCF("mcp", "Using stdio transport",
 			map[string]any{
@@ -330,7 +449,7 @@ func (m *Manager) ConnectServer(
 		if cfg.EnvFile != "" {
 			envVars, err := loadEnvFile(cfg.EnvFile)
 			if err != nil {
-				return fmt.Errorf("failed to load env file %s: %w", cfg.EnvFile, err)
+				return nil, fmt.Errorf("failed to load env file %s: %w", cfg.EnvFile, err)
 			}
 			for k, v := range envVars {
 				envMap[k] = v
@@ -355,18 +474,23 @@ func (m *Manager) ConnectServer(
 		}
 		cmd.Env = env
 
-		transport = &mcp.CommandTransport{Command: cmd}
+		return &mcp.CommandTransport{Command: cmd}, nil
 	default:
-		return fmt.Errorf(
+		return nil, fmt.Errorf(
 			"unsupported transport type: %s (supported: stdio, sse, http)",
 			transportType,
 		)
 	}
+}
+
+// dial opens a session over transport and lists what the server offers.
+func (m *Manager) dial(ctx context.Context, name string, transport mcp.Transport) (*ServerConnection, error) {
+	client := m.newClient(name)
 
 	// Connect to server
 	session, err := client.Connect(ctx, transport, nil)
 	if err != nil {
-		return fmt.Errorf("failed to connect: %w", err)
+		return nil, fmt.Errorf("failed to connect: %w", err)
 	}
 
 	// Get server info
@@ -379,39 +503,57 @@ func (m *Manager) ConnectServer(
 			"protocol":      initResult.ProtocolVersion,
 		})
 
+	conn := &ServerConnection{
+		Name:    name,
+		Client:  client,
+		Session: session,
+	}
+
 	// List available tools if supported
-	var tools []*mcp.Tool
 	if initResult.Capabilities.Tools != nil {
-		for tool, err := range session.Tools(ctx, nil) {
-			if err != nil {
-				logger.WarnCF("mcp", "Error listing tool",
-					map[string]any{
-						"server": name,
-						"error":  err.Error(),
-					})
-				continue
-			}
-			tools = append(tools, tool)
-		}
-
+		conn.Tools = listTools(ctx, name, session)
 		logger.InfoCF("mcp", "Listed tools from MCP server",
 			map[string]any{
 				"server":    name,
-				"toolCount": len(tools),
+				"toolCount": len(conn.Tools),
 			})
 	}
-
-	// Store connection
-	m.mu.Lock()
-	m.servers[name] = &ServerConnection{
-		Name:    name,
-		Client:  client,
-		Session: session,
-		Tools:   tools,
+	if caps := initResult.Capabilities.Resources; caps != nil {
+		conn.Resources, conn.ResourceTemplates = listResources(ctx, name, session)
+		conn.SupportsSubscribe = caps.Subscribe
+		logger.InfoCF("mcp", "Listed resources from MCP server",
+			map[string]any{
+				"server":        name,
+				"resourceCount": len(conn.Resources),
+				"templateCount": len(conn.ResourceTemplates),
+			})
+	}
+	if initResult.Capabilities.Prompts != nil {
+		conn.Prompts = listPrompts(ctx, name, session)
+		logger.InfoCF("mcp", "Listed prompts from MCP server",
+			map[string]any{
+				"server":      name,
+				"promptCount": len(conn.Prompts),
+			})
 	}
-	m.mu.Unlock()
 
-	return nil
+	return conn, nil
+}
+
+func listTools(ctx context.Context, serverName string, session *mcp.ClientSession) []*mcp.Tool {
+	var tools []*mcp.Tool
+	for tool, err := range session.Tools(ctx, nil) {
+		if err != nil {
+			logger.WarnCF("mcp", "Error listing tool",
+				map[string]any{
+					"server": serverName,
+					"error":  err.Error(),
+				})
+			break
+		}
+		tools = append(tools, tool)
+	}
+	return tools
 }
 
 // GetServers returns all connected servers
@@ -441,25 +583,9 @@ func (m *Manager) CallTool(
 	serverName, toolName string,
 	arguments map[string]any,
 ) (*mcp.CallToolResult, error) {
-	// Check if closed before acquiring lock (fast path)
-	if m.closed.Load() {
-		return nil, fmt.Errorf("manager is closed")
-	}
-
-	m.mu.RLock()
-	// Double-check after acquiring lock to prevent TOCTOU race
-	if m.closed.Load() {
-		m.mu.RUnlock()
-		return nil, fmt.Errorf("manager is closed")
-	}
-	conn, ok := m.servers[serverName]
-	if ok {
-		m.wg.Add(1) // Add to WaitGroup while holding the lock
-	}
-	m.mu.RUnlock()
-
-	if !ok {
-		return nil, fmt.Errorf("server %s not found", serverName)
+	conn, err := m.acquire(serverName)
+	if err != nil {
+		return nil, err
 	}
 	defer m.wg.Done()
 
@@ -476,6 +602,28 @@ func (m *Manager) CallTool(
 	return result, nil
 }
 
+// acquire returns the connection for serverName and registers an in-flight
+// call that the caller must end with m.wg.Done().
+func (m *Manager) acquire(serverName string) (*ServerConnection, error) {
+	// Check if closed before acquiring lock (fast path)
+	if m.closed.Load() {
+		return nil, fmt.Errorf("manager is closed")
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	// Double-check after acquiring lock to prevent TOCTOU race
+	if m.closed.Load() {
+		return nil, fmt.Errorf("manager is closed")
+	}
+	conn, ok := m.servers[serverName]
+	if !ok {
+		return nil, fmt.Errorf("server %s not found", serverName)
+	}
+	m.wg.Add(1) // Add to WaitGroup while holding the lock
+	return conn, nil
+}
+
 // Close closes all server connections
 func (m *Manager) Close() error {
 	// Use Swap to atomically set closed=true and get the previous value
@@ -488,6 +636,9 @@ func (m *Manager) Close() error {
 	// After closed=true is set, no new CallTool can start (they check closed first)
 	m.wg.Wait()
 
+	// Stop reconnect loops and stdio processes.
+	m.cancel()
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -509,6 +660,11 @@ func (m *Manager) Close() error {
 	}
 
 	m.servers = make(map[string]*ServerConnection)
+	m.resourceCache = make(map[string]*mcp.ReadResourceResult)
+	m.subscribed = make(map[string]bool)
+	m.configs = make(map[string]config.MCPServerConfig)
+	m.status = make(map[string]*ServerStatus)
+	m.supervisors = make(map[string]context.CancelFunc)
 
 	if len(errs) > 0 {
 		return fmt.Errorf("failed to close %d server(s): %w", len(errs), errors.Join(errs...))
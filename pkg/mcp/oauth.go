@@ -0,0 +1,565 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/auth"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/egress"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+const (
+	oauthClientName  = "PicoClaw"
+	oauthHTTPTimeout = 30 * time.Second
+)
+
+// ErrOAuthRequired is returned when a server needs OAuth authorization but
+// no usable token is stored.
+var ErrOAuthRequired = errors.New("MCP server requires authorization")
+
+var resourceMetadataParam = regexp.MustCompile(`resource_metadata="?([^",\s]+)"?`)
+
+// OAuthCredentialKey returns the auth store key holding a server's tokens.
+func OAuthCredentialKey(serverName string) string {
+	return "mcp:" + serverName
+}
+
+// protectedResourceMetadata is the OAuth 2.0 Protected Resource Metadata
+// document (RFC 9728) published by an MCP server.
+type protectedResourceMetadata struct {
+	Resource             string   `json:"resource"`
+	AuthorizationServers []string `json:"authorization_servers"`
+	ScopesSupported      []string `json:"scopes_supported,omitempty"`
+}
+
+// authServerMetadata is the OAuth 2.0 Authorization Server Metadata
+// document (RFC 8414).
+type authServerMetadata struct {
+	Issuer                        string   `json:"issuer"`
+	AuthorizationEndpoint         string   `json:"authorization_endpoint"`
+	TokenEndpoint                 string   `json:"token_endpoint"`
+	RegistrationEndpoint          string   `json:"registration_endpoint,omitempty"`
+	ScopesSupported               []string `json:"scopes_supported,omitempty"`
+	CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported,omitempty"`
+}
+
+// OAuthFlow is a pending authorization of one MCP server. Send the user to
+// AuthURL, then pass the code delivered to RedirectURI to Complete.
+type OAuthFlow struct {
+	Server      string
+	AuthURL     string
+	State       string
+	RedirectURI string
+
+	resource     string
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	codeVerifier string
+}
+
+// StartOAuth discovers the authorization server of a remote MCP server,
+// registers a client if none is configured and builds the PKCE
+// authorization URL.
+func StartOAuth(
+	ctx context.Context,
+	serverName string,
+	cfg config.MCPServerConfig,
+	redirectURI string,
+) (*OAuthFlow, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("server %s has no URL; OAuth requires an sse/http server", serverName)
+	}
+	oauthCfg := config.MCPOAuthConfig{}
+	if cfg.OAuth != nil {
+		oauthCfg = *cfg.OAuth
+	}
+	client := newOAuthHTTPClient()
+
+	meta, scopes, err := discoverAuthServer(ctx, client, cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	if len(meta.CodeChallengeMethodsSupported) > 0 &&
+		!slices.Contains(meta.CodeChallengeMethodsSupported, "S256") {
+		return nil, fmt.Errorf("authorization server %s does not support PKCE S256", meta.Issuer)
+	}
+	if len(oauthCfg.Scopes) > 0 {
+		scopes = oauthCfg.Scopes
+	}
+
+	flow := &OAuthFlow{
+		Server:       serverName,
+		RedirectURI:  redirectURI,
+		resource:     cfg.URL,
+		tokenURL:     meta.TokenEndpoint,
+		clientID:     oauthCfg.ClientID,
+		clientSecret: oauthCfg.ClientSecret,
+	}
+	if flow.clientID == "" {
+		if meta.RegistrationEndpoint == "" {
+			return nil, fmt.Errorf(
+				"authorization server %s does not support dynamic client registration; set oauth.client_id",
+				meta.Issuer,
+			)
+		}
+		flow.clientID, flow.clientSecret, err = registerClient(
+			ctx, client, meta.RegistrationEndpoint, redirectURI, scopes,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	pkce, err := auth.GeneratePKCE()
+	if err != nil {
+		return nil, fmt.Errorf("generating PKCE: %w", err)
+	}
+	state, err := auth.GenerateState()
+	if err != nil {
+		return nil, fmt.Errorf("generating state: %w", err)
+	}
+	flow.State = state
+	flow.codeVerifier = pkce.CodeVerifier
+
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {flow.clientID},
+		"redirect_uri":          {redirectURI},
+		"code_challenge":        {pkce.CodeChallenge},
+		"code_challenge_method": {"S256"},
+		"state":                 {state},
+		"resource":              {cfg.URL},
+	}
+	if len(scopes) > 0 {
+		params.Set("scope", strings.Join(scopes, " "))
+	}
+	sep := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	flow.AuthURL = meta.AuthorizationEndpoint + sep + params.Encode()
+	return flow, nil
+}
+
+// Complete exchanges the authorization code for tokens and stores them
+// under OAuthCredentialKey(Server).
+func (f *OAuthFlow) Complete(ctx context.Context, code string) (*auth.AuthCredential, error) {
+	data := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {f.RedirectURI},
+		"client_id":     {f.clientID},
+		"code_verifier": {f.codeVerifier},
+		"resource":      {f.resource},
+	}
+	if f.clientSecret != "" {
+		data.Set("client_secret", f.clientSecret)
+	}
+
+	client := newOAuthHTTPClient()
+	cred, err := requestToken(ctx, client, f.tokenURL, data)
+	if err != nil {
+		return nil, fmt.Errorf("token exchange failed: %w", err)
+	}
+	cred.Provider = OAuthCredentialKey(f.Server)
+	cred.ClientID = f.clientID
+	cred.ClientSecret = f.clientSecret
+	cred.TokenURL = f.tokenURL
+
+	if err := auth.SetCredential(cred.Provider, cred); err != nil {
+		return nil, fmt.Errorf("saving credentials: %w", err)
+	}
+	return cred, nil
+}
+
+// discoverAuthServer finds the authorization server for serverURL following
+// the MCP authorization spec: the server's protected resource metadata names
+// the authorization server, whose metadata lists the endpoints. Servers
+// without resource metadata are treated as their own authorization server.
+func discoverAuthServer(
+	ctx context.Context,
+	client *http.Client,
+	serverURL string,
+) (meta *authServerMetadata, scopes []string, err error) {
+	issuer, err := originOf(serverURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	prm := fetchProtectedResourceMetadata(ctx, client, serverURL)
+	if prm != nil && len(prm.AuthorizationServers) > 0 {
+		issuer = prm.AuthorizationServers[0]
+		scopes = prm.ScopesSupported
+	}
+
+	for _, candidate := range authServerMetadataURLs(issuer) {
+		var m authServerMetadata
+		if getJSON(ctx, client, candidate, &m) != nil {
+			continue
+		}
+		if m.AuthorizationEndpoint == "" || m.TokenEndpoint == "" {
+			continue
+		}
+		if len(scopes) == 0 {
+			scopes = m.ScopesSupported
+		}
+		return &m, scopes, nil
+	}
+
+	if prm != nil {
+		return nil, nil, fmt.Errorf("no authorization server metadata found for %s", issuer)
+	}
+	// Servers predating resource metadata host the default endpoints.
+	return &authServerMetadata{
+		Issuer:                issuer,
+		AuthorizationEndpoint: issuer + "/authorize",
+		TokenEndpoint:         issuer + "/token",
+		RegistrationEndpoint:  issuer + "/register",
+	}, scopes, nil
+}
+
+// fetchProtectedResourceMetadata probes serverURL for the metadata location
+// advertised in its WWW-Authenticate challenge and falls back to the
+// well-known locations. It returns nil when the server publishes none.
+func fetchProtectedResourceMetadata(
+	ctx context.Context,
+	client *http.Client,
+	serverURL string,
+) *protectedResourceMetadata {
+	var candidates []string
+	if req, err := http.NewRequestWithContext(ctx, http.MethodGet, serverURL, nil); err == nil {
+		req.Header.Set("Accept", "application/json, text/event-stream")
+		if resp, err := client.Do(req); err == nil {
+			resp.Body.Close()
+			if match := resourceMetadataParam.FindStringSubmatch(resp.Header.Get("WWW-Authenticate")); match != nil {
+				candidates = append(candidates, match[1])
+			}
+		}
+	}
+	candidates = append(candidates, wellKnownURLs(serverURL, "oauth-protected-resource")...)
+
+	for _, candidate := range candidates {
+		var prm protectedResourceMetadata
+		if getJSON(ctx, client, candidate, &prm) == nil && len(prm.AuthorizationServers) > 0 {
+			return &prm
+		}
+	}
+	return nil
+}
+
+// authServerMetadataURLs lists the RFC 8414 and OpenID Connect discovery
+// locations for issuer, most specific first.
+func authServerMetadataURLs(issuer string) []string {
+	urls := wellKnownURLs(issuer, "oauth-authorization-server")
+	urls = append(urls, wellKnownURLs(issuer, "openid-configuration")...)
+	if u, err := url.Parse(issuer); err == nil && strings.Trim(u.Path, "/") != "" {
+		urls = append(urls, strings.TrimSuffix(issuer, "/")+"/.well-known/openid-configuration")
+	}
+	return urls
+}
+
+// wellKnownURLs returns the well-known URL for rawURL with its path appended
+// after the well-known suffix, then the one at the root of the origin.
+func wellKnownURLs(rawURL, suffix string) []string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return nil
+	}
+	origin := u.Scheme + "://" + u.Host
+	var urls []string
+	if path := strings.TrimSuffix(u.Path, "/"); path != "" {
+		urls = append(urls, origin+"/.well-known/"+suffix+path)
+	}
+	return append(urls, origin+"/.well-known/"+suffix)
+}
+
+func originOf(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("invalid server URL %q", rawURL)
+	}
+	return u.Scheme + "://" + u.Host, nil
+}
+
+// registerClient performs OAuth 2.0 Dynamic Client Registration (RFC 7591)
+// for a public client using the authorization code grant.
+func registerClient(
+	ctx context.Context,
+	client *http.Client,
+	endpoint, redirectURI string,
+	scopes []string,
+) (clientID, clientSecret string, err error) {
+	body := map[string]any{
+		"client_name":                oauthClientName,
+		"redirect_uris":              []string{redirectURI},
+		"grant_types":                []string{"authorization_code", "refresh_token"},
+		"response_types":             []string{"code"},
+		"token_endpoint_auth_method": "none",
+	}
+	if len(scopes) > 0 {
+		body["scope"] = strings.Join(scopes, " ")
+	}
+	payload, _ := json.Marshal(body)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(string(payload)))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("registering client: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", "", fmt.Errorf("reading registration response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return "", "", fmt.Errorf("client registration failed: %s", strings.TrimSpace(string(respBody)))
+	}
+
+	var registered struct {
+		ClientID     string `json:"client_id"`
+		ClientSecret string `json:"client_secret"`
+	}
+	if err := json.Unmarshal(respBody, &registered); err != nil {
+		return "", "", fmt.Errorf("parsing registration response: %w", err)
+	}
+	if registered.ClientID == "" {
+		return "", "", fmt.Errorf("client registration returned no client_id")
+	}
+	return registered.ClientID, registered.ClientSecret, nil
+}
+
+// refreshOAuthToken exchanges cred's refresh token for a new access token.
+func refreshOAuthToken(
+	ctx context.Context,
+	client *http.Client,
+	cred *auth.AuthCredential,
+	resource string,
+) (*auth.AuthCredential, error) {
+	data := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {cred.RefreshToken},
+		"client_id":     {cred.ClientID},
+		"resource":      {resource},
+	}
+	if cred.ClientSecret != "" {
+		data.Set("client_secret", cred.ClientSecret)
+	}
+
+	refreshed, err := requestToken(ctx, client, cred.TokenURL, data)
+	if err != nil {
+		return nil, err
+	}
+	refreshed.Provider = cred.Provider
+	refreshed.ClientID = cred.ClientID
+	refreshed.ClientSecret = cred.ClientSecret
+	refreshed.TokenURL = cred.TokenURL
+	if refreshed.RefreshToken == "" {
+		refreshed.RefreshToken = cred.RefreshToken
+	}
+	return refreshed, nil
+}
+
+func requestToken(
+	ctx context.Context,
+	client *http.Client,
+	tokenURL string,
+	data url.Values,
+) (*auth.AuthCredential, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("reading token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s", strings.TrimSpace(string(body)))
+	}
+
+	var tokenResp struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		return nil, fmt.Errorf("parsing token response: %w", err)
+	}
+	if tokenResp.AccessToken == "" {
+		return nil, fmt.Errorf("no access token in response")
+	}
+
+	cred := &auth.AuthCredential{
+		AccessToken:  tokenResp.AccessToken,
+		RefreshToken: tokenResp.RefreshToken,
+		AuthMethod:   "oauth",
+	}
+	if tokenResp.ExpiresIn > 0 {
+		cred.ExpiresAt = time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+	}
+	return cred, nil
+}
+
+func getJSON(ctx context.Context, client *http.Client, rawURL string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", rawURL, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// newOAuthHTTPClient returns the client used for discovery and token
+// requests, which obey the egress policy like the MCP connection itself.
+func newOAuthHTTPClient() *http.Client {
+	return &http.Client{
+		Timeout:   oauthHTTPTimeout,
+		Transport: egress.Guard(http.DefaultTransport.(*http.Transport).Clone()),
+	}
+}
+
+// oauthTransport is an http.RoundTripper that authorizes requests with the
+// server's stored OAuth token, refreshing it when it is about to expire. The
+// token is only sent to the server's own origin, not to redirect targets or
+// endpoints elsewhere.
+type oauthTransport struct {
+	base     http.RoundTripper
+	server   string
+	resource string
+	origin   string
+
+	mu   sync.Mutex
+	cred *auth.AuthCredential
+}
+
+func newOAuthTransport(base http.RoundTripper, serverName, serverURL string) *oauthTransport {
+	t := &oauthTransport{base: base, server: serverName, resource: serverURL}
+	if u, err := url.Parse(serverURL); err == nil {
+		t.origin = requestOrigin(u)
+	}
+	return t
+}
+
+// requestOrigin returns scheme://host:port for u, with default ports filled
+// in so equivalent URLs compare equal.
+func requestOrigin(u *url.URL) string {
+	scheme := strings.ToLower(u.Scheme)
+	port := u.Port()
+	switch {
+	case port != "":
+	case scheme == "https":
+		port = "443"
+	case scheme == "http":
+		port = "80"
+	}
+	return scheme + "://" + net.JoinHostPort(strings.ToLower(u.Hostname()), port)
+}
+
+func (t *oauthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.origin == "" || requestOrigin(req.URL) != t.origin {
+		return t.base.RoundTrip(req)
+	}
+	token, err := t.token(req.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := t.base.RoundTrip(req)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized {
+		// Reload from the store next time; the user may have logged in again.
+		t.mu.Lock()
+		if t.cred != nil && t.cred.AccessToken == token {
+			t.cred = nil
+		}
+		t.mu.Unlock()
+	}
+	return resp, err
+}
+
+func (t *oauthTransport) token(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := OAuthCredentialKey(t.server)
+	if t.cred == nil {
+		cred, err := auth.GetCredential(key)
+		if err != nil {
+			return "", fmt.Errorf("loading credentials for %s: %w", t.server, err)
+		}
+		if cred == nil {
+			return "", fmt.Errorf("%w: run `picoclaw auth mcp %s`", ErrOAuthRequired, t.server)
+		}
+		t.cred = cred
+	}
+
+	if !t.cred.NeedsRefresh() {
+		return t.cred.AccessToken, nil
+	}
+	if t.cred.RefreshToken == "" || t.cred.TokenURL == "" {
+		if t.cred.IsExpired() {
+			return "", fmt.Errorf("%w: token expired, run `picoclaw auth mcp %s`", ErrOAuthRequired, t.server)
+		}
+		return t.cred.AccessToken, nil
+	}
+
+	client := &http.Client{Transport: t.base, Timeout: oauthHTTPTimeout}
+	refreshed, err := refreshOAuthToken(ctx, client, t.cred, t.resource)
+	if err != nil {
+		if !t.cred.IsExpired() {
+			logger.WarnCF("mcp", "MCP token refresh failed, using current token",
+				map[string]any{
+					"server": t.server,
+					"error":  err.Error(),
+				})
+			return t.cred.AccessToken, nil
+		}
+		return "", fmt.Errorf("%w: token refresh failed: %v", ErrOAuthRequired, err)
+	}
+	if err := auth.SetCredential(key, refreshed); err != nil {
+		logger.WarnCF("mcp", "Failed to save refreshed MCP token",
+			map[string]any{
+				"server": t.server,
+				"error":  err.Error(),
+			})
+	}
+	t.cred = refreshed
+	return refreshed.AccessToken, nil
+}
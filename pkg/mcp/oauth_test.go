@@ -0,0 +1,256 @@
+package mcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/auth"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// fakeAuthServer is an MCP server protected by an authorization server that
+// supports dynamic client registration and PKCE.
+type fakeAuthServer struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	challenge  string
+	registered []string
+	issued     map[string]bool
+	refreshes  int
+}
+
+func newFakeAuthServer(t *testing.T) *fakeAuthServer {
+	t.Helper()
+	fs := &fakeAuthServer{issued: make(map[string]bool)}
+	mux := http.NewServeMux()
+	writeJSON := func(w http.ResponseWriter, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(v)
+	}
+
+	mux.HandleFunc("/mcp", func(w http.ResponseWriter, r *http.Request) {
+		fs.mu.Lock()
+		ok := fs.issued[r.Header.Get("Authorization")]
+		fs.mu.Unlock()
+		if !ok {
+			w.Header().Set("WWW-Authenticate",
+				`Bearer resource_metadata="`+fs.URL+`/meta/resource"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/meta/resource", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{
+			"resource":              fs.URL + "/mcp",
+			"authorization_servers": []string{fs.URL + "/as"},
+			"scopes_supported":      []string{"mcp:read"},
+		})
+	})
+	mux.HandleFunc("/.well-known/oauth-authorization-server/as", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{
+			"issuer":                           fs.URL + "/as",
+			"authorization_endpoint":           fs.URL + "/as/authorize",
+			"token_endpoint":                   fs.URL + "/as/token",
+			"registration_endpoint":            fs.URL + "/as/register",
+			"code_challenge_methods_supported": []string{"S256"},
+		})
+	})
+	mux.HandleFunc("/as/register", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			RedirectURIs []string `json:"redirect_uris"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		fs.mu.Lock()
+		fs.registered = req.RedirectURIs
+		fs.mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, map[string]any{"client_id": "dyn-client"})
+	})
+	mux.HandleFunc("/as/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if r.Form.Get("client_id") != "dyn-client" || r.Form.Get("resource") != fs.URL+"/mcp" {
+			http.Error(w, `{"error":"invalid_client"}`, http.StatusBadRequest)
+			return
+		}
+		fs.mu.Lock()
+		defer fs.mu.Unlock()
+		var token string
+		switch r.Form.Get("grant_type") {
+		case "authorization_code":
+			sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+			if r.Form.Get("code") != "the-code" ||
+				base64.RawURLEncoding.EncodeToString(sum[:]) != fs.challenge {
+				http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+				return
+			}
+			token = "access-1"
+		case "refresh_token":
+			if r.Form.Get("refresh_token") != "refresh-1" {
+				http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+				return
+			}
+			fs.refreshes++
+			token = "access-2"
+		}
+		fs.issued["Bearer "+token] = true
+		writeJSON(w, map[string]any{
+			"access_token":  token,
+			"refresh_token": "refresh-1",
+			"expires_in":    3600,
+		})
+	})
+
+	fs.Server = httptest.NewServer(mux)
+	t.Cleanup(fs.Close)
+	return fs
+}
+
+func TestOAuthFlow_DiscoversRegistersAndStoresToken(t *testing.T) {
+	t.Setenv("PICOCLAW_HOME", t.TempDir())
+	fs := newFakeAuthServer(t)
+	cfg := config.MCPServerConfig{
+		Type:  "http",
+		URL:   fs.URL + "/mcp",
+		OAuth: &config.MCPOAuthConfig{Enabled: true},
+	}
+
+	flow, err := StartOAuth(context.Background(), "remote", cfg, "http://127.0.0.1:9999/callback")
+	if err != nil {
+		t.Fatalf("StartOAuth: %v", err)
+	}
+	authURL, err := url.Parse(flow.AuthURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := authURL.Query()
+	if authURL.Path != "/as/authorize" || q.Get("client_id") != "dyn-client" ||
+		q.Get("code_challenge_method") != "S256" || q.Get("state") != flow.State ||
+		q.Get("scope") != "mcp:read" || q.Get("resource") != cfg.URL {
+		t.Fatalf("unexpected authorize URL %s", flow.AuthURL)
+	}
+	fs.mu.Lock()
+	registered := fs.registered
+	fs.challenge = q.Get("code_challenge")
+	fs.mu.Unlock()
+	if len(registered) != 1 || registered[0] != flow.RedirectURI {
+		t.Fatalf("registered redirect URIs = %v", registered)
+	}
+
+	cred, err := flow.Complete(context.Background(), "the-code")
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	stored, err := auth.GetCredential(OAuthCredentialKey("remote"))
+	if err != nil || stored == nil {
+		t.Fatalf("stored credential = %v, %v", stored, err)
+	}
+	if stored.AccessToken != "access-1" || stored.ClientID != "dyn-client" ||
+		stored.TokenURL != fs.URL+"/as/token" || cred.Provider != "mcp:remote" {
+		t.Fatalf("unexpected credential %+v", stored)
+	}
+}
+
+func TestOAuthTransport_AuthorizesAndRefreshes(t *testing.T) {
+	t.Setenv("PICOCLAW_HOME", t.TempDir())
+	fs := newFakeAuthServer(t)
+	client := &http.Client{Transport: newOAuthTransport(http.DefaultTransport, "remote", fs.URL+"/mcp")}
+
+	_, err := client.Get(fs.URL + "/mcp")
+	if !errors.Is(err, ErrOAuthRequired) {
+		t.Fatalf("expected ErrOAuthRequired without a token, got %v", err)
+	}
+
+	// A token about to expire is refreshed before use and saved back.
+	err = auth.SetCredential(OAuthCredentialKey("remote"), &auth.AuthCredential{
+		AccessToken:  "access-1",
+		RefreshToken: "refresh-1",
+		ExpiresAt:    time.Now().Add(time.Minute),
+		Provider:     OAuthCredentialKey("remote"),
+		AuthMethod:   "oauth",
+		ClientID:     "dyn-client",
+		TokenURL:     fs.URL + "/as/token",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Get(fs.URL + "/mcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	fs.mu.Lock()
+	refreshes := fs.refreshes
+	fs.mu.Unlock()
+	if resp.StatusCode != http.StatusOK || refreshes != 1 {
+		t.Fatalf("status = %d, refreshes = %d", resp.StatusCode, refreshes)
+	}
+	stored, _ := auth.GetCredential(OAuthCredentialKey("remote"))
+	if stored.AccessToken != "access-2" || stored.RefreshToken != "refresh-1" || stored.ClientID != "dyn-client" {
+		t.Fatalf("refreshed credential not saved: %+v", stored)
+	}
+}
+
+func TestDiscoverAuthServer_FallsBackToDefaultEndpoints(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	meta, _, err := discoverAuthServer(context.Background(), server.Client(), server.URL+"/mcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.AuthorizationEndpoint != server.URL+"/authorize" || meta.TokenEndpoint != server.URL+"/token" {
+		t.Fatalf("unexpected fallback metadata %+v", meta)
+	}
+}
+
+func TestOAuthTransport_SendsTokenOnlyToServerOrigin(t *testing.T) {
+	t.Setenv("PICOCLAW_HOME", t.TempDir())
+	var gotAuth []string
+	record := func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = append(gotAuth, r.Header.Get("Authorization"))
+	}
+	other := httptest.NewServer(http.HandlerFunc(record))
+	defer other.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		record(w, r)
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, other.URL+"/elsewhere", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	err := auth.SetCredential(OAuthCredentialKey("remote"), &auth.AuthCredential{
+		AccessToken: "access-1",
+		ExpiresAt:   time.Now().Add(time.Hour),
+		Provider:    OAuthCredentialKey("remote"),
+		AuthMethod:  "oauth",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: newOAuthTransport(http.DefaultTransport, "remote", server.URL+"/mcp")}
+
+	for _, target := range []string{server.URL + "/mcp", server.URL + "/redirect", other.URL + "/sse"} {
+		resp, err := client.Get(target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	want := []string{"Bearer access-1", "Bearer access-1", "", ""}
+	if !slices.Equal(gotAuth, want) {
+		t.Errorf("Authorization headers = %q, want %q", gotAuth, want)
+	}
+}
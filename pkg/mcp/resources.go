@@ -0,0 +1,262 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// refreshTimeout bounds re-listing after a list_changed notification.
+const refreshTimeout = 30 * time.Second
+
+func listResources(
+	ctx context.Context,
+	serverName string,
+	session *mcp.ClientSession,
+) ([]*mcp.Resource, []*mcp.ResourceTemplate) {
+	var resources []*mcp.Resource
+	for resource, err := range session.Resources(ctx, nil) {
+		if err != nil {
+			logger.WarnCF("mcp", "Error listing resources",
+				map[string]any{
+					"server": serverName,
+					"error":  err.Error(),
+				})
+			break
+		}
+		resources = append(resources, resource)
+	}
+
+	var templates []*mcp.ResourceTemplate
+	for template, err := range session.ResourceTemplates(ctx, nil) {
+		if err != nil {
+			// Templates are optional; servers without them may reject the method.
+			logger.DebugCF("mcp", "Error listing resource templates",
+				map[string]any{
+					"server": serverName,
+					"error":  err.Error(),
+				})
+			break
+		}
+		templates = append(templates, template)
+	}
+	return resources, templates
+}
+
+func listPrompts(ctx context.Context, serverName string, session *mcp.ClientSession) []*mcp.Prompt {
+	var prompts []*mcp.Prompt
+	for prompt, err := range session.Prompts(ctx, nil) {
+		if err != nil {
+			logger.WarnCF("mcp", "Error listing prompts",
+				map[string]any{
+					"server": serverName,
+					"error":  err.Error(),
+				})
+			break
+		}
+		prompts = append(prompts, prompt)
+	}
+	return prompts
+}
+
+func (m *Manager) refreshResources(serverName string) {
+	conn, err := m.acquire(serverName)
+	if err != nil {
+		return
+	}
+	defer m.wg.Done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), refreshTimeout)
+	defer cancel()
+	resources, templates := listResources(ctx, serverName, conn.Session)
+
+	m.mu.Lock()
+	conn.Resources, conn.ResourceTemplates = resources, templates
+	m.mu.Unlock()
+	logger.InfoCF("mcp", "Resource list changed",
+		map[string]any{
+			"server":        serverName,
+			"resourceCount": len(resources),
+		})
+}
+
+func (m *Manager) refreshPrompts(serverName string) {
+	conn, err := m.acquire(serverName)
+	if err != nil {
+		return
+	}
+	defer m.wg.Done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), refreshTimeout)
+	defer cancel()
+	prompts := listPrompts(ctx, serverName, conn.Session)
+
+	m.mu.Lock()
+	conn.Prompts = prompts
+	handler := m.promptsChanged
+	m.mu.Unlock()
+	logger.InfoCF("mcp", "Prompt list changed",
+		map[string]any{
+			"server":      serverName,
+			"promptCount": len(prompts),
+		})
+	if handler != nil {
+		handler(serverName, prompts)
+	}
+}
+
+// SetPromptsChangedHandler registers fn to be called with the new prompt
+// list whenever a server reports that its prompts changed.
+func (m *Manager) SetPromptsChangedHandler(fn func(serverName string, prompts []*mcp.Prompt)) {
+	m.mu.Lock()
+	m.promptsChanged = fn
+	m.mu.Unlock()
+}
+
+// GetAllResources returns the resources listed by each connected server.
+func (m *Manager) GetAllResources() map[string][]*mcp.Resource {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make(map[string][]*mcp.Resource)
+	for name, conn := range m.servers {
+		if len(conn.Resources) > 0 {
+			result[name] = conn.Resources
+		}
+	}
+	return result
+}
+
+// GetAllResourceTemplates returns the resource templates listed by each
+// connected server.
+func (m *Manager) GetAllResourceTemplates() map[string][]*mcp.ResourceTemplate {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make(map[string][]*mcp.ResourceTemplate)
+	for name, conn := range m.servers {
+		if len(conn.ResourceTemplates) > 0 {
+			result[name] = conn.ResourceTemplates
+		}
+	}
+	return result
+}
+
+// GetAllPrompts returns the prompts listed by each connected server.
+func (m *Manager) GetAllPrompts() map[string][]*mcp.Prompt {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make(map[string][]*mcp.Prompt)
+	for name, conn := range m.servers {
+		if len(conn.Prompts) > 0 {
+			result[name] = conn.Prompts
+		}
+	}
+	return result
+}
+
+func resourceKey(serverName, uri string) string {
+	return serverName + "\x00" + uri
+}
+
+// ReadResource reads a resource from a server. On servers that support
+// subscriptions the manager subscribes to the resource and serves later
+// reads from cache until the server reports an update.
+func (m *Manager) ReadResource(ctx context.Context, serverName, uri string) (*mcp.ReadResourceResult, error) {
+	conn, err := m.acquire(serverName)
+	if err != nil {
+		return nil, err
+	}
+	defer m.wg.Done()
+
+	key := resourceKey(serverName, uri)
+	m.mu.RLock()
+	cached, ok := m.resourceCache[key]
+	subscribed := m.subscribed[key]
+	generation := m.invalidations
+	m.mu.RUnlock()
+	if ok {
+		return cached, nil
+	}
+
+	// Subscribe before reading so an update that races with the read is
+	// not lost.
+	if conn.SupportsSubscribe && !subscribed {
+		if err := conn.Session.Subscribe(ctx, &mcp.SubscribeParams{URI: uri}); err != nil {
+			logger.DebugCF("mcp", "Resource subscription failed; reads will not be cached",
+				map[string]any{
+					"server": serverName,
+					"uri":    uri,
+					"error":  err.Error(),
+				})
+		} else {
+			subscribed = true
+			m.mu.Lock()
+			m.subscribed[key] = true
+			m.mu.Unlock()
+		}
+	}
+
+	result, err := conn.Session.ReadResource(ctx, &mcp.ReadResourceParams{URI: uri})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read resource: %w", err)
+	}
+
+	if subscribed {
+		m.mu.Lock()
+		if m.invalidations == generation {
+			m.resourceCache[key] = result
+		}
+		m.mu.Unlock()
+	}
+	return result, nil
+}
+
+// invalidateResource drops cached contents for uri on serverName. The
+// updated URI may be a sub-resource of a subscribed one, so entries related
+// by prefix in either direction are dropped too.
+func (m *Manager) invalidateResource(serverName, uri string) {
+	prefix := resourceKey(serverName, "")
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.invalidations++
+	for key := range m.resourceCache {
+		cachedURI, ok := strings.CutPrefix(key, prefix)
+		if !ok {
+			continue
+		}
+		if cachedURI == uri || strings.HasPrefix(uri, cachedURI) || strings.HasPrefix(cachedURI, uri) {
+			delete(m.resourceCache, key)
+		}
+	}
+	logger.DebugCF("mcp", "Resource updated",
+		map[string]any{
+			"server": serverName,
+			"uri":    uri,
+		})
+}
+
+// GetPrompt renders a prompt on a server with the given arguments.
+func (m *Manager) GetPrompt(
+	ctx context.Context,
+	serverName, promptName string,
+	arguments map[string]string,
+) (*mcp.GetPromptResult, error) {
+	conn, err := m.acquire(serverName)
+	if err != nil {
+		return nil, err
+	}
+	defer m.wg.Done()
+
+	result, err := conn.Session.GetPrompt(ctx, &mcp.GetPromptParams{Name: promptName, Arguments: arguments})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prompt: %w", err)
+	}
+	return result, nil
+}
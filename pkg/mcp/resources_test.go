@@ -0,0 +1,142 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func newResourceTestServer(t *testing.T, reads *atomic.Int32) (*sdkmcp.Server, *Manager) {
+	t.Helper()
+	server := sdkmcp.NewServer(&sdkmcp.Implementation{Name: "docs", Version: "1"}, &sdkmcp.ServerOptions{
+		SubscribeHandler:   func(context.Context, *sdkmcp.SubscribeRequest) error { return nil },
+		UnsubscribeHandler: func(context.Context, *sdkmcp.UnsubscribeRequest) error { return nil },
+	})
+	server.AddResource(&sdkmcp.Resource{URI: "docs://readme", Name: "readme", MIMEType: "text/plain"},
+		func(_ context.Context, req *sdkmcp.ReadResourceRequest) (*sdkmcp.ReadResourceResult, error) {
+			n := reads.Add(1)
+			return &sdkmcp.ReadResourceResult{Contents: []*sdkmcp.ResourceContents{
+				{URI: req.Params.URI, MIMEType: "text/plain", Text: "version " + string(rune('0'+n))},
+			}}, nil
+		})
+	server.AddPrompt(&sdkmcp.Prompt{
+		Name:      "review",
+		Arguments: []*sdkmcp.PromptArgument{{Name: "file", Required: true}},
+	}, func(_ context.Context, req *sdkmcp.GetPromptRequest) (*sdkmcp.GetPromptResult, error) {
+		return &sdkmcp.GetPromptResult{Messages: []*sdkmcp.PromptMessage{
+			{Role: "user", Content: &sdkmcp.TextContent{Text: "Review " + req.Params.Arguments["file"]}},
+		}}, nil
+	})
+
+	httpServer := httptest.NewServer(sdkmcp.NewStreamableHTTPHandler(
+		func(*http.Request) *sdkmcp.Server { return server }, nil))
+	t.Cleanup(httpServer.Close)
+
+	m := NewManager()
+	t.Cleanup(func() { m.Close() })
+	err := m.ConnectServer(context.Background(), "docs", config.MCPServerConfig{
+		Enabled: true,
+		Type:    "http",
+		URL:     httpServer.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, m
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestManager_ResourcesAreCachedUntilUpdated(t *testing.T) {
+	var reads atomic.Int32
+	server, m := newResourceTestServer(t, &reads)
+	ctx := context.Background()
+
+	resources := m.GetAllResources()["docs"]
+	if len(resources) != 1 || resources[0].URI != "docs://readme" {
+		t.Fatalf("unexpected resources: %+v", resources)
+	}
+
+	for range 2 {
+		res, err := m.ReadResource(ctx, "docs", "docs://readme")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Contents[0].Text != "version 1" {
+			t.Errorf("unexpected contents: %q", res.Contents[0].Text)
+		}
+	}
+	if reads.Load() != 1 {
+		t.Errorf("expected the second read to be served from cache, server saw %d reads", reads.Load())
+	}
+
+	if err := server.ResourceUpdated(ctx, &sdkmcp.ResourceUpdatedNotificationParams{URI: "docs://readme"}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "cache invalidation", func() bool {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+		return len(m.resourceCache) == 0
+	})
+
+	res, err := m.ReadResource(ctx, "docs", "docs://readme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Contents[0].Text != "version 2" {
+		t.Errorf("expected fresh contents after update, got %q", res.Contents[0].Text)
+	}
+
+	if _, err := m.ReadResource(ctx, "missing", "docs://readme"); err == nil {
+		t.Error("expected an unknown server to fail")
+	}
+}
+
+func TestManager_Prompts(t *testing.T) {
+	var reads atomic.Int32
+	server, m := newResourceTestServer(t, &reads)
+	ctx := context.Background()
+
+	prompts := m.GetAllPrompts()["docs"]
+	if len(prompts) != 1 || prompts[0].Name != "review" {
+		t.Fatalf("unexpected prompts: %+v", prompts)
+	}
+	res, err := m.GetPrompt(ctx, "docs", "review", map[string]string{"file": "main.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := res.Messages[0].Content.(*sdkmcp.TextContent).Text; text != "Review main.go" {
+		t.Errorf("unexpected prompt text: %q", text)
+	}
+
+	var latest atomic.Int32
+	m.SetPromptsChangedHandler(func(serverName string, prompts []*sdkmcp.Prompt) {
+		if serverName == "docs" {
+			latest.Store(int32(len(prompts)))
+		}
+	})
+	server.AddPrompt(&sdkmcp.Prompt{Name: "summarize"},
+		func(context.Context, *sdkmcp.GetPromptRequest) (*sdkmcp.GetPromptResult, error) {
+			return &sdkmcp.GetPromptResult{}, nil
+		})
+	waitFor(t, "prompt list change", func() bool { return latest.Load() == 2 })
+	if prompts := m.GetAllPrompts()["docs"]; len(prompts) != 2 {
+		t.Errorf("expected the refreshed list to have 2 prompts, got %d", len(prompts))
+	}
+}
@@ -0,0 +1,111 @@
+package mcp
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// LocalTool is a picoclaw capability published by Server.
+type LocalTool struct {
+	Name        string
+	Description string
+	// InputSchema is a JSON schema object with type "object".
+	InputSchema map[string]any
+	// Handler runs the tool. A returned error is reported to the client as a
+	// tool error rather than a protocol error, so the calling model sees it.
+	Handler func(ctx context.Context, args map[string]any) (string, error)
+}
+
+// Server exposes local tools to MCP clients over stdio or streamable HTTP.
+type Server struct {
+	server *mcp.Server
+	names  []string
+}
+
+// NewServer creates a Server publishing the given tools.
+func NewServer(name, version string, tools []LocalTool) *Server {
+	s := &Server{
+		server: mcp.NewServer(&mcp.Implementation{Name: name, Version: version}, nil),
+	}
+	for _, t := range tools {
+		schema := t.InputSchema
+		if schema == nil {
+			schema = map[string]any{"type": "object", "properties": map[string]any{}}
+		}
+		s.server.AddTool(&mcp.Tool{
+			Name:        t.Name,
+			Description: t.Description,
+			InputSchema: schema,
+		}, toolHandler(t.Handler))
+		s.names = append(s.names, t.Name)
+	}
+	return s
+}
+
+func toolHandler(fn func(ctx context.Context, args map[string]any) (string, error)) mcp.ToolHandler {
+	return func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := map[string]any{}
+		if raw := req.Params.Arguments; len(raw) > 0 && string(raw) != "null" {
+			if err := json.Unmarshal(raw, &args); err != nil {
+				return toolError(fmt.Sprintf("arguments must be a JSON object: %v", err)), nil
+			}
+		}
+		text, err := fn(ctx, args)
+		if err != nil {
+			return toolError(err.Error()), nil
+		}
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: text}}}, nil
+	}
+}
+
+func toolError(msg string) *mcp.CallToolResult {
+	return &mcp.CallToolResult{IsError: true, Content: []mcp.Content{&mcp.TextContent{Text: msg}}}
+}
+
+// ToolNames returns the names of the published tools in registration order.
+func (s *Server) ToolNames() []string {
+	return s.names
+}
+
+// ServeStdio serves a single client over newline-delimited JSON on in and
+// out until the client disconnects or ctx is cancelled.
+func (s *Server) ServeStdio(ctx context.Context, in io.Reader, out io.Writer) error {
+	return s.server.Run(ctx, &mcp.IOTransport{
+		Reader: io.NopCloser(in),
+		Writer: nopWriteCloser{out},
+	})
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// HTTPHandler returns a streamable HTTP handler for the server. Every
+// request must carry "Authorization: Bearer <token>"; an empty token
+// rejects all requests.
+func (s *Server) HTTPHandler(token string) http.Handler {
+	handler := mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server {
+		return s.server
+	}, &mcp.StreamableHTTPOptions{SessionTimeout: 30 * time.Minute})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" || !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="picoclaw"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		// Agent turns and event streams outlive the shared server's write
+		// timeout, which is sized for webhooks.
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,110 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type bearerTransport struct {
+	token string
+}
+
+func (t bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func newTestServer() *Server {
+	return NewServer("picoclaw", "test", []LocalTool{
+		{
+			Name:        "echo",
+			Description: "Echo the text argument",
+			InputSchema: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"text": map[string]any{"type": "string"}},
+			},
+			Handler: func(_ context.Context, args map[string]any) (string, error) {
+				text, _ := args["text"].(string)
+				return "echo: " + text, nil
+			},
+		},
+		{
+			Name: "fail",
+			Handler: func(context.Context, map[string]any) (string, error) {
+				return "", errors.New("device not ready")
+			},
+		},
+	})
+}
+
+func TestServer_HTTP(t *testing.T) {
+	srv := httptest.NewServer(newTestServer().HTTPHandler("secret"))
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL, "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", resp.StatusCode)
+	}
+
+	ctx := context.Background()
+	client := mcp.NewClient(&mcp.Implementation{Name: "test", Version: "1"}, nil)
+	session, err := client.Connect(ctx, &mcp.StreamableClientTransport{
+		Endpoint:   srv.URL,
+		HTTPClient: &http.Client{Transport: bearerTransport{token: "secret"}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+
+	list, err := session.ListTools(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Tools) != 2 || list.Tools[0].Name != "echo" || list.Tools[1].Name != "fail" {
+		t.Fatalf("unexpected tools: %+v", list.Tools)
+	}
+
+	res, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "echo", Arguments: map[string]any{"text": "hi"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.IsError || res.Content[0].(*mcp.TextContent).Text != "echo: hi" {
+		t.Errorf("unexpected echo result: %+v", res)
+	}
+
+	res, err = session.CallTool(ctx, &mcp.CallToolParams{Name: "fail"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.IsError || res.Content[0].(*mcp.TextContent).Text != "device not ready" {
+		t.Errorf("expected tool error in result, got %+v", res)
+	}
+}
+
+func TestServer_HTTPRequiresToken(t *testing.T) {
+	srv := httptest.NewServer(newTestServer().HTTPHandler(""))
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader(`{}`))
+	req.Header.Set("Authorization", "Bearer ")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected an empty token to reject every request, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,384 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+const (
+	// defaultHealthInterval is how often sessions are pinged. A missed ping
+	// closes the session and starts a reconnect.
+	defaultHealthInterval = 30 * time.Second
+	defaultMinBackoff     = time.Second
+	defaultMaxBackoff     = 5 * time.Minute
+	// connectTimeout bounds a single reconnect attempt.
+	connectTimeout = 30 * time.Second
+)
+
+// Server states reported by Status.
+const (
+	StateConnected    = "connected"
+	StateReconnecting = "reconnecting"
+	// StateFailed means the server configuration is invalid; it is not
+	// retried until the configuration changes.
+	StateFailed = "failed"
+)
+
+// ServerStatus describes the supervised state of one configured server.
+type ServerStatus struct {
+	Name      string    `json:"name"`
+	State     string    `json:"state"`
+	Tools     int       `json:"tools"`
+	Attempts  int       `json:"attempts,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+	Since     time.Time `json:"since"`
+}
+
+// Status returns the state of every configured server, sorted by name.
+func (m *Manager) Status() []ServerStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]ServerStatus, 0, len(m.status))
+	for _, st := range m.status {
+		result = append(result, *st)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// SetToolsChangedHandler registers fn to be called with a server's tool
+// list whenever it connects, reconnects or reports tools/list_changed, and
+// with nil when the server is removed.
+func (m *Manager) SetToolsChangedHandler(fn func(serverName string, tools []*mcp.Tool)) {
+	m.mu.Lock()
+	m.toolsChanged = fn
+	m.mu.Unlock()
+}
+
+// supervise records cfg for name and returns the context that bounds the
+// server's processes and reconnect loop. An existing server with the same
+// name is replaced.
+func (m *Manager) supervise(name string, cfg config.MCPServerConfig) (context.Context, error) {
+	m.mu.Lock()
+	if m.closed.Load() {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("manager is closed")
+	}
+	if cancel, ok := m.supervisors[name]; ok {
+		cancel()
+	}
+	old := m.servers[name]
+	delete(m.servers, name)
+	m.dropServerCacheLocked(name)
+
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.supervisors[name] = cancel
+	m.configs[name] = cfg
+	m.mu.Unlock()
+
+	if old != nil {
+		_ = old.Session.Close()
+	}
+	return ctx, nil
+}
+
+func (m *Manager) setFailed(name string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.status[name] = &ServerStatus{
+		Name:      name,
+		State:     StateFailed,
+		LastError: err.Error(),
+		Since:     time.Now(),
+	}
+}
+
+func (m *Manager) setRetrying(name string, attempts int, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st, ok := m.status[name]
+	if !ok || st.State != StateReconnecting {
+		st = &ServerStatus{Name: name, State: StateReconnecting, Since: time.Now()}
+		m.status[name] = st
+	}
+	st.Tools = 0
+	st.Attempts = attempts
+	st.LastError = err.Error()
+}
+
+// attach publishes conn as the live connection for name and starts watching
+// it. It reports false when the server was removed in the meantime.
+func (m *Manager) attach(ctx context.Context, name string, conn *ServerConnection) bool {
+	m.mu.Lock()
+	if ctx.Err() != nil || m.closed.Load() {
+		m.mu.Unlock()
+		_ = conn.Session.Close()
+		return false
+	}
+	m.servers[name] = conn
+	m.status[name] = &ServerStatus{
+		Name:  name,
+		State: StateConnected,
+		Tools: len(conn.Tools),
+		Since: time.Now(),
+	}
+	toolsChanged, promptsChanged := m.toolsChanged, m.promptsChanged
+	m.mu.Unlock()
+
+	go m.watch(ctx, name, conn)
+
+	if toolsChanged != nil {
+		toolsChanged(name, conn.Tools)
+	}
+	if promptsChanged != nil {
+		promptsChanged(name, conn.Prompts)
+	}
+	return true
+}
+
+// watch waits for the session to end. If it ended on its own (process exit,
+// failed keepalive, server restart) the server is reconnected.
+func (m *Manager) watch(ctx context.Context, name string, conn *ServerConnection) {
+	err := conn.Session.Wait()
+
+	m.mu.Lock()
+	if m.servers[name] != conn {
+		// Removed, replaced or closed by the manager.
+		m.mu.Unlock()
+		return
+	}
+	delete(m.servers, name)
+	m.dropServerCacheLocked(name)
+	m.mu.Unlock()
+	if ctx.Err() != nil {
+		return
+	}
+
+	if err == nil {
+		err = errors.New("connection closed")
+	}
+	logger.WarnCF("mcp", "MCP server connection lost, reconnecting",
+		map[string]any{
+			"server": name,
+			"error":  err.Error(),
+		})
+	m.setRetrying(name, 0, err)
+	m.reconnect(ctx, name)
+}
+
+// reconnect retries the connection with exponential backoff until it
+// succeeds or ctx is cancelled.
+func (m *Manager) reconnect(ctx context.Context, name string) {
+	backoff := m.minBackoff
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		m.mu.RLock()
+		cfg := m.configs[name]
+		m.mu.RUnlock()
+
+		conn, err := m.connectOnce(ctx, name, cfg)
+		if err == nil {
+			if m.attach(ctx, name, conn) {
+				logger.InfoCF("mcp", "Reconnected to MCP server",
+					map[string]any{
+						"server":   name,
+						"attempts": attempt,
+					})
+			}
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		backoff = min(backoff*2, m.maxBackoff)
+		m.setRetrying(name, attempt, err)
+		logger.WarnCF("mcp", "MCP server reconnect failed",
+			map[string]any{
+				"server":   name,
+				"attempt":  attempt,
+				"retry_in": backoff.String(),
+				"error":    err.Error(),
+			})
+	}
+}
+
+func (m *Manager) connectOnce(ctx context.Context, name string, cfg config.MCPServerConfig) (*ServerConnection, error) {
+	transport, err := m.newTransport(ctx, name, cfg)
+	if err != nil {
+		return nil, err
+	}
+	dialCtx, cancel := context.WithTimeout(ctx, connectTimeout)
+	defer cancel()
+	return m.dial(dialCtx, name, transport)
+}
+
+func (m *Manager) refreshTools(serverName string) {
+	conn, err := m.acquire(serverName)
+	if err != nil {
+		return
+	}
+	defer m.wg.Done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), refreshTimeout)
+	defer cancel()
+	tools := listTools(ctx, serverName, conn.Session)
+
+	m.mu.Lock()
+	if m.servers[serverName] != conn {
+		m.mu.Unlock()
+		return
+	}
+	conn.Tools = tools
+	if st, ok := m.status[serverName]; ok {
+		st.Tools = len(tools)
+	}
+	handler := m.toolsChanged
+	m.mu.Unlock()
+	logger.InfoCF("mcp", "Tool list changed",
+		map[string]any{
+			"server":    serverName,
+			"toolCount": len(tools),
+		})
+	if handler != nil {
+		handler(serverName, tools)
+	}
+}
+
+// dropServerCacheLocked forgets cached resources and subscriptions of a
+// server whose session is gone. m.mu must be held.
+func (m *Manager) dropServerCacheLocked(serverName string) {
+	prefix := resourceKey(serverName, "")
+	m.invalidations++
+	for key := range m.resourceCache {
+		if strings.HasPrefix(key, prefix) {
+			delete(m.resourceCache, key)
+		}
+	}
+	for key := range m.subscribed {
+		if strings.HasPrefix(key, prefix) {
+			delete(m.subscribed, key)
+		}
+	}
+}
+
+// AddServer connects a server at runtime. See ConnectServer for retry
+// behavior.
+func (m *Manager) AddServer(ctx context.Context, name string, cfg config.MCPServerConfig) error {
+	return m.ConnectServer(ctx, name, cfg)
+}
+
+// RemoveServer disconnects a server and stops supervising it.
+func (m *Manager) RemoveServer(name string) error {
+	m.mu.Lock()
+	cancel, ok := m.supervisors[name]
+	if !ok {
+		m.mu.Unlock()
+		return fmt.Errorf("server %s not found", name)
+	}
+	cancel()
+	delete(m.supervisors, name)
+	delete(m.configs, name)
+	delete(m.status, name)
+	conn := m.servers[name]
+	delete(m.servers, name)
+	m.dropServerCacheLocked(name)
+	toolsChanged, promptsChanged := m.toolsChanged, m.promptsChanged
+	m.mu.Unlock()
+
+	if conn != nil {
+		if err := conn.Session.Close(); err != nil {
+			logger.DebugCF("mcp", "Error closing removed server",
+				map[string]any{
+					"server": name,
+					"error":  err.Error(),
+				})
+		}
+	}
+	logger.InfoCF("mcp", "Removed MCP server", map[string]any{"server": name})
+
+	if toolsChanged != nil {
+		toolsChanged(name, nil)
+	}
+	if promptsChanged != nil {
+		promptsChanged(name, nil)
+	}
+	return nil
+}
+
+// ApplyConfig reconciles the supervised servers with mcpCfg: servers that
+// were removed, disabled or changed are disconnected, and new or changed
+// ones are connected. Unchanged servers keep their sessions. The returned
+// error joins the connection failures; failed servers keep being retried.
+func (m *Manager) ApplyConfig(ctx context.Context, mcpCfg config.MCPConfig, workspacePath string) error {
+	desired := make(map[string]config.MCPServerConfig)
+	invalid := make(map[string]error)
+	if mcpCfg.Enabled {
+		for name, serverCfg := range mcpCfg.Servers {
+			if !serverCfg.Enabled {
+				continue
+			}
+			resolved, err := resolveServerConfig(name, serverCfg, workspacePath)
+			if err != nil {
+				invalid[name] = err
+				continue
+			}
+			desired[name] = resolved
+		}
+	}
+
+	m.mu.RLock()
+	current := make(map[string]config.MCPServerConfig, len(m.configs))
+	for name, cfg := range m.configs {
+		current[name] = cfg
+	}
+	m.mu.RUnlock()
+
+	for name, cfg := range current {
+		if want, ok := desired[name]; ok && reflect.DeepEqual(want, cfg) {
+			delete(desired, name)
+			continue
+		}
+		_ = m.RemoveServer(name)
+	}
+
+	var errs []error
+	m.mu.Lock()
+	for name := range m.status {
+		// Drop stale entries of servers whose configuration never resolved.
+		if _, ok := m.configs[name]; !ok {
+			delete(m.status, name)
+		}
+	}
+	m.mu.Unlock()
+	for name, err := range invalid {
+		m.setFailed(name, err)
+		errs = append(errs, err)
+	}
+
+	if len(desired) > 0 {
+		logger.InfoCF("mcp", "Adding MCP servers from updated configuration",
+			map[string]any{
+				"count": len(desired),
+			})
+		_, connectErrs := m.connectServers(ctx, desired, workspacePath)
+		errs = append(errs, connectErrs...)
+	}
+	return errors.Join(errs...)
+}
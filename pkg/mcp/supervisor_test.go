@@ -0,0 +1,240 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// restartableServer serves an MCP server over streamable HTTP. restart drops
+// all sessions as a real server restart would; down makes it unreachable.
+type restartableServer struct {
+	server *sdkmcp.Server
+	url    string
+
+	mu      sync.Mutex
+	handler http.Handler
+}
+
+func newRestartableServer(t *testing.T) *restartableServer {
+	t.Helper()
+	rs := &restartableServer{
+		server: sdkmcp.NewServer(&sdkmcp.Implementation{Name: "tools", Version: "1"}, nil),
+	}
+	addEchoTool(rs.server, "echo")
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rs.mu.Lock()
+		h := rs.handler
+		rs.mu.Unlock()
+		if h == nil {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		h.ServeHTTP(w, r)
+	}))
+	t.Cleanup(httpServer.Close)
+	rs.url = httpServer.URL
+	rs.restart()
+	return rs
+}
+
+func (rs *restartableServer) restart() {
+	rs.mu.Lock()
+	rs.handler = sdkmcp.NewStreamableHTTPHandler(func(*http.Request) *sdkmcp.Server { return rs.server }, nil)
+	rs.mu.Unlock()
+}
+
+func (rs *restartableServer) down() {
+	rs.mu.Lock()
+	rs.handler = nil
+	rs.mu.Unlock()
+}
+
+func addEchoTool(server *sdkmcp.Server, name string) {
+	server.AddTool(&sdkmcp.Tool{
+		Name:        name,
+		InputSchema: map[string]any{"type": "object"},
+	}, func(context.Context, *sdkmcp.CallToolRequest) (*sdkmcp.CallToolResult, error) {
+		return &sdkmcp.CallToolResult{Content: []sdkmcp.Content{&sdkmcp.TextContent{Text: name}}}, nil
+	})
+}
+
+func newSupervisedManager(t *testing.T) *Manager {
+	t.Helper()
+	m := NewManager()
+	m.healthInterval = 50 * time.Millisecond
+	m.minBackoff = 10 * time.Millisecond
+	m.maxBackoff = 50 * time.Millisecond
+	t.Cleanup(func() { m.Close() })
+	return m
+}
+
+// toolRecorder records the latest tool list reported per server.
+type toolRecorder struct {
+	mu     sync.Mutex
+	latest map[string][]*sdkmcp.Tool
+	calls  int
+}
+
+func (r *toolRecorder) record(server string, tools []*sdkmcp.Tool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.latest == nil {
+		r.latest = make(map[string][]*sdkmcp.Tool)
+	}
+	r.latest[server] = tools
+	r.calls++
+}
+
+func (r *toolRecorder) snapshot(server string) ([]*sdkmcp.Tool, bool, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tools, ok := r.latest[server]
+	return tools, ok, r.calls
+}
+
+func serverState(m *Manager, name string) ServerStatus {
+	for _, st := range m.Status() {
+		if st.Name == name {
+			return st
+		}
+	}
+	return ServerStatus{}
+}
+
+func TestManager_ReconnectsAfterServerRestart(t *testing.T) {
+	rs := newRestartableServer(t)
+	m := newSupervisedManager(t)
+	var rec toolRecorder
+	m.SetToolsChangedHandler(rec.record)
+
+	cfg := config.MCPServerConfig{Enabled: true, Type: "http", URL: rs.url}
+	if err := m.ConnectServer(context.Background(), "tools", cfg); err != nil {
+		t.Fatal(err)
+	}
+	first, _ := m.GetServer("tools")
+	_, _, calls := rec.snapshot("tools")
+
+	rs.restart()
+	waitFor(t, "reconnect", func() bool {
+		conn, ok := m.GetServer("tools")
+		return ok && conn != first
+	})
+	waitFor(t, "tools reported after reconnect", func() bool {
+		_, _, n := rec.snapshot("tools")
+		return n > calls
+	})
+
+	result, err := m.CallTool(context.Background(), "tools", "echo", nil)
+	if err != nil {
+		t.Fatalf("CallTool after reconnect: %v", err)
+	}
+	if text := result.Content[0].(*sdkmcp.TextContent).Text; text != "echo" {
+		t.Fatalf("unexpected result %q", text)
+	}
+	if st := serverState(m, "tools"); st.State != StateConnected || st.Tools != 1 {
+		t.Fatalf("status = %+v", st)
+	}
+}
+
+func TestManager_RetriesServerThatIsDownAtStart(t *testing.T) {
+	rs := newRestartableServer(t)
+	rs.down()
+	m := newSupervisedManager(t)
+
+	cfg := config.MCPServerConfig{Enabled: true, Type: "http", URL: rs.url}
+	if err := m.ConnectServer(context.Background(), "tools", cfg); err == nil {
+		t.Fatal("expected initial connect to fail")
+	}
+	waitFor(t, "retry attempts", func() bool {
+		st := serverState(m, "tools")
+		return st.State == StateReconnecting && st.Attempts > 0 && st.LastError != ""
+	})
+
+	rs.restart()
+	waitFor(t, "connected", func() bool {
+		return serverState(m, "tools").State == StateConnected
+	})
+	if _, ok := m.GetServer("tools"); !ok {
+		t.Fatal("server should be available")
+	}
+}
+
+func TestManager_RefreshesToolsOnListChanged(t *testing.T) {
+	rs := newRestartableServer(t)
+	m := newSupervisedManager(t)
+	var rec toolRecorder
+	m.SetToolsChangedHandler(rec.record)
+
+	cfg := config.MCPServerConfig{Enabled: true, Type: "http", URL: rs.url}
+	if err := m.ConnectServer(context.Background(), "tools", cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	addEchoTool(rs.server, "second")
+	waitFor(t, "tools/list_changed refresh", func() bool {
+		tools, _, _ := rec.snapshot("tools")
+		return len(tools) == 2
+	})
+	if got := len(m.GetAllTools()["tools"]); got != 2 {
+		t.Fatalf("manager tools = %d, want 2", got)
+	}
+}
+
+func TestManager_ApplyConfigAddsAndRemovesServers(t *testing.T) {
+	rs := newRestartableServer(t)
+	m := newSupervisedManager(t)
+	var rec toolRecorder
+	m.SetToolsChangedHandler(rec.record)
+
+	mcpCfg := config.MCPConfig{
+		ToolConfig: config.ToolConfig{Enabled: true},
+		Servers: map[string]config.MCPServerConfig{
+			"tools":  {Enabled: true, Type: "http", URL: rs.url},
+			"broken": {Enabled: true, Type: "carrier-pigeon"},
+		},
+	}
+	if err := m.ApplyConfig(context.Background(), mcpCfg, "/tmp"); err == nil {
+		t.Fatal("expected error for the invalid server")
+	}
+	conn, ok := m.GetServer("tools")
+	if !ok {
+		t.Fatal("tools server should be connected")
+	}
+	if st := serverState(m, "broken"); st.State != StateFailed {
+		t.Fatalf("broken status = %+v", st)
+	}
+
+	// Unchanged servers keep their session.
+	delete(mcpCfg.Servers, "broken")
+	if err := m.ApplyConfig(context.Background(), mcpCfg, "/tmp"); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := m.GetServer("tools"); again != conn {
+		t.Fatal("unchanged server was reconnected")
+	}
+	if st := serverState(m, "broken"); st.Name != "" {
+		t.Fatalf("removed server still reported: %+v", st)
+	}
+
+	mcpCfg.Servers = nil
+	if err := m.ApplyConfig(context.Background(), mcpCfg, "/tmp"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.GetServer("tools"); ok {
+		t.Fatal("tools server should be removed")
+	}
+	if tools, ok, _ := rec.snapshot("tools"); !ok || tools != nil {
+		t.Fatalf("removal should report nil tools, got %v (reported=%v)", tools, ok)
+	}
+	if len(m.Status()) != 0 {
+		t.Fatalf("status = %+v, want empty", m.Status())
+	}
+}
@@ -0,0 +1,186 @@
+// Package permissions assigns roles to message senders and decides which
+// tools, commands, agents and models each role may use.
+//
+// Roles are keyed by canonical sender IDs from pkg/identity. A nil *Role
+// stands for an unrestricted caller (permissions disabled, or an internal
+// caller such as the CLI or an MCP client), so checks on it always succeed.
+package permissions
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/identity"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// Role is the set of things a sender may use.
+type Role struct {
+	Name     string
+	tools    []string
+	commands []string
+	agents   []string
+	models   []string
+}
+
+func newRole(name string, cfg config.RoleConfig) *Role {
+	commands := make([]string, len(cfg.Commands))
+	for i, c := range cfg.Commands {
+		commands[i] = strings.TrimPrefix(strings.TrimSpace(c), "/")
+	}
+	return &Role{
+		Name:     name,
+		tools:    cfg.Tools,
+		commands: commands,
+		agents:   cfg.Agents,
+		models:   cfg.Models,
+	}
+}
+
+// AllowsTool reports whether the role may see and call the named tool.
+func (r *Role) AllowsTool(name string) bool {
+	return r == nil || matchAny(r.tools, name)
+}
+
+// AllowsCommand reports whether the role may run the named slash command.
+func (r *Role) AllowsCommand(name string) bool {
+	return r == nil || matchAny(r.commands, strings.TrimPrefix(name, "/"))
+}
+
+// AllowsAgent reports whether the role may talk to the agent.
+func (r *Role) AllowsAgent(id string) bool {
+	return r == nil || matchAny(r.agents, id)
+}
+
+// AllowsModel reports whether the role may switch to the model.
+func (r *Role) AllowsModel(name string) bool {
+	return r == nil || matchAny(r.models, name)
+}
+
+// matchAny matches name case-insensitively against names and glob patterns.
+func matchAny(patterns []string, name string) bool {
+	name = strings.ToLower(name)
+	for _, p := range patterns {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p == "*" || p == name {
+			return true
+		}
+		if ok, err := path.Match(p, name); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Policy maps senders to roles.
+type Policy struct {
+	users       map[string]string
+	userKeys    []string
+	roles       map[string]*Role
+	defaultRole string
+}
+
+// NewPolicy builds the policy described by cfg, or returns nil when
+// permissions are disabled.
+func NewPolicy(cfg config.PermissionsConfig) *Policy {
+	if !cfg.Enabled {
+		return nil
+	}
+	p := &Policy{
+		users:       make(map[string]string, len(cfg.Users)),
+		roles:       make(map[string]*Role, len(cfg.Roles)),
+		defaultRole: cfg.DefaultRole,
+	}
+	for name, rc := range cfg.Roles {
+		p.roles[name] = newRole(name, rc)
+	}
+	for user, role := range cfg.Users {
+		p.users[user] = role
+		p.userKeys = append(p.userKeys, user)
+		if _, ok := p.roles[role]; !ok {
+			logger.WarnCF("permissions", "User assigned to undefined role; it allows nothing",
+				map[string]any{"user": user, "role": role})
+		}
+	}
+	sort.Strings(p.userKeys)
+	if _, ok := p.roles[p.defaultRole]; !ok && p.defaultRole != "" {
+		logger.WarnCF("permissions", "Default role is undefined; unlisted senders may use nothing",
+			map[string]any{"role": p.defaultRole})
+	}
+	return p
+}
+
+// RoleFor returns the role of sender. A nil policy returns nil (no
+// restrictions); an unknown role name yields a role that allows nothing.
+func (p *Policy) RoleFor(sender bus.SenderInfo) *Role {
+	if p == nil {
+		return nil
+	}
+	name, ok := p.lookupUser(sender)
+	if !ok {
+		name = p.defaultRole
+	}
+	if role, ok := p.roles[name]; ok {
+		return role
+	}
+	return &Role{Name: name}
+}
+
+// RoleForID returns the role of the sender with the given canonical ID, for
+// callers that act on a sender's behalf later, such as cron jobs. Users
+// listed by @username cannot be matched this way and get the default role.
+func (p *Policy) RoleForID(canonicalID string) *Role {
+	sender := bus.SenderInfo{CanonicalID: canonicalID}
+	if platform, id, ok := identity.ParseCanonicalID(canonicalID); ok {
+		sender.Platform = platform
+		sender.PlatformID = id
+	}
+	return p.RoleFor(sender)
+}
+
+func (p *Policy) lookupUser(sender bus.SenderInfo) (string, bool) {
+	if sender.CanonicalID != "" {
+		for _, user := range p.userKeys {
+			if strings.EqualFold(user, sender.CanonicalID) {
+				return p.users[user], true
+			}
+		}
+	}
+	for _, user := range p.userKeys {
+		if identity.MatchAllowed(sender, user) {
+			return p.users[user], true
+		}
+	}
+	return "", false
+}
+
+type roleCtxKey struct{}
+
+// WithRole returns a child context carrying the requester's role.
+func WithRole(ctx context.Context, role *Role) context.Context {
+	return context.WithValue(ctx, roleCtxKey{}, role)
+}
+
+// RoleFrom returns the requester's role from ctx, or nil if none was set.
+func RoleFrom(ctx context.Context) *Role {
+	role, _ := ctx.Value(roleCtxKey{}).(*Role)
+	return role
+}
+
+type senderCtxKey struct{}
+
+// WithSender returns a child context carrying the requester's canonical ID.
+func WithSender(ctx context.Context, canonicalID string) context.Context {
+	return context.WithValue(ctx, senderCtxKey{}, canonicalID)
+}
+
+// SenderFrom returns the requester's canonical ID from ctx, or "" if none
+// was set.
+func SenderFrom(ctx context.Context) string {
+	id, _ := ctx.Value(senderCtxKey{}).(string)
+	return id
+}
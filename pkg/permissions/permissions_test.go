@@ -0,0 +1,105 @@
+package permissions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func testPolicy() *Policy {
+	return NewPolicy(config.PermissionsConfig{
+		Enabled:     true,
+		DefaultRole: "guest",
+		Users: map[string]string{
+			"telegram:123": "owner",
+			"@alice":       "operator",
+			"discord:9":    "missing",
+		},
+		Roles: map[string]config.RoleConfig{
+			"owner": {Tools: []string{"*"}, Commands: []string{"*"}, Agents: []string{"*"}, Models: []string{"*"}},
+			"operator": {
+				Tools:    []string{"web_search", "mcp_github_*"},
+				Commands: []string{"help", "/show"},
+				Agents:   []string{"main", "support"},
+				Models:   []string{"gpt-4o-mini"},
+			},
+			"guest": {Tools: []string{"web_search"}, Agents: []string{"main"}},
+		},
+	})
+}
+
+func TestNewPolicyDisabled(t *testing.T) {
+	p := NewPolicy(config.PermissionsConfig{Enabled: false})
+	if p != nil {
+		t.Fatal("disabled permissions should yield a nil policy")
+	}
+	role := p.RoleFor(bus.SenderInfo{CanonicalID: "telegram:1"})
+	if role != nil || !role.AllowsTool("exec") || !role.AllowsCommand("switch") {
+		t.Fatal("nil policy and role should allow everything")
+	}
+}
+
+func TestRoleFor(t *testing.T) {
+	p := testPolicy()
+	tests := []struct {
+		name   string
+		sender bus.SenderInfo
+		want   string
+	}{
+		{"canonical", bus.SenderInfo{Platform: "telegram", PlatformID: "123", CanonicalID: "telegram:123"}, "owner"},
+		{"canonical case", bus.SenderInfo{CanonicalID: "Telegram:123"}, "owner"},
+		{"legacy username", bus.SenderInfo{Platform: "slack", PlatformID: "U1", Username: "alice"}, "operator"},
+		{"unlisted", bus.SenderInfo{Platform: "telegram", PlatformID: "999", CanonicalID: "telegram:999"}, "guest"},
+		{"undefined role", bus.SenderInfo{Platform: "discord", PlatformID: "9", CanonicalID: "discord:9"}, "missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.RoleFor(tt.sender); got == nil || got.Name != tt.want {
+				t.Fatalf("RoleFor() = %+v, want role %q", got, tt.want)
+			}
+		})
+	}
+
+	undefined := p.RoleFor(bus.SenderInfo{CanonicalID: "discord:9"})
+	if undefined.AllowsTool("web_search") || undefined.AllowsAgent("main") {
+		t.Fatal("an undefined role should allow nothing")
+	}
+}
+
+func TestRoleAllows(t *testing.T) {
+	role := testPolicy().RoleFor(bus.SenderInfo{Username: "alice"})
+
+	checks := []struct {
+		name string
+		got  bool
+		want bool
+	}{
+		{"listed tool", role.AllowsTool("web_search"), true},
+		{"glob tool", role.AllowsTool("mcp_github_create_issue"), true},
+		{"unlisted tool", role.AllowsTool("exec"), false},
+		{"command", role.AllowsCommand("help"), true},
+		{"command with slash in config", role.AllowsCommand("show"), true},
+		{"unlisted command", role.AllowsCommand("switch"), false},
+		{"agent", role.AllowsAgent("support"), true},
+		{"unlisted agent", role.AllowsAgent("admin"), false},
+		{"model", role.AllowsModel("GPT-4o-mini"), true},
+		{"unlisted model", role.AllowsModel("gpt-4o"), false},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestRoleContext(t *testing.T) {
+	if RoleFrom(context.Background()) != nil {
+		t.Fatal("expected no role in a bare context")
+	}
+	role := &Role{Name: "guest"}
+	if got := RoleFrom(WithRole(context.Background(), role)); got != role {
+		t.Fatalf("RoleFrom() = %v, want %v", got, role)
+	}
+}
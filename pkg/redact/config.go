@@ -0,0 +1,137 @@
+package redact
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/sipeed/picoclaw/pkg/auth"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// secretFieldName matches the JSON names of config fields holding secrets:
+// api_key, api_keys, token, bot_token, app_secret, password, encrypt_key...
+var secretFieldName = regexp.MustCompile(`(^|_)(keys?|tokens?|secret|password)$`)
+
+// secretMapKey matches header and environment variable names whose values
+// are secrets, such as Authorization or GITHUB_TOKEN.
+var secretMapKey = regexp.MustCompile(`(?i)auth|key|token|secret|password|cookie`)
+
+// FromConfig builds the redactor described by cfg.Redaction. It masks every
+// secret in the configuration (credential references are resolved first)
+// and the tokens in the auth store. It returns nil when redaction is
+// disabled.
+func FromConfig(cfg *config.Config) *Redactor {
+	if cfg == nil || !cfg.Redaction.Enabled {
+		return nil
+	}
+
+	opts := Options{
+		Secrets: ConfigSecrets(cfg),
+		PII:     cfg.Redaction.PII,
+	}
+	if store, err := auth.LoadStore(); err == nil {
+		for _, cred := range store.Credentials {
+			opts.Secrets = append(opts.Secrets, cred.AccessToken, cred.RefreshToken, cred.ClientSecret)
+		}
+	}
+	for _, expr := range cfg.Redaction.Patterns {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			logger.WarnCF("redact", "Ignoring invalid redaction pattern",
+				map[string]any{
+					"pattern": expr,
+					"error":   err.Error(),
+				})
+			continue
+		}
+		opts.Patterns = append(opts.Patterns, re)
+	}
+	return New(opts)
+}
+
+// Install makes the redactor for cfg the process-wide default and applies
+// it to log output.
+func Install(cfg *config.Config) {
+	r := FromConfig(cfg)
+	SetDefault(r)
+	if r == nil {
+		logger.SetRedactor(nil)
+		return
+	}
+	logger.SetRedactor(r.Redact)
+}
+
+// ConfigSecrets returns the secret values configured in cfg: string fields
+// named like secrets and secret-looking header and env entries.
+func ConfigSecrets(cfg *config.Config) []string {
+	resolver := cfg.CredentialResolver()
+	var secrets []string
+	add := func(value string) {
+		if value == "" {
+			return
+		}
+		if resolved, err := resolver.Resolve(value); err == nil {
+			value = resolved
+		}
+		secrets = append(secrets, value)
+		// "Bearer <token>" style values: mask the credential on its own too,
+		// but not the last word of a passphrase.
+		if i := strings.LastIndexByte(value, ' '); i >= 0 && looksLikeToken(value[i+1:]) {
+			secrets = append(secrets, value[i+1:])
+		}
+	}
+	collectSecrets(reflect.ValueOf(cfg).Elem(), false, add)
+	return secrets
+}
+
+// minTokenLen is the shortest word of a multi-word secret that is masked on
+// its own.
+const minTokenLen = 16
+
+// looksLikeToken reports whether s is long and mixes in digits or symbols,
+// unlike ordinary words such as "password".
+func looksLikeToken(s string) bool {
+	return len(s) >= minTokenLen && strings.IndexFunc(s, func(r rune) bool { return !unicode.IsLetter(r) }) >= 0
+}
+
+// collectSecrets walks v, passing every string that is a secret to add.
+// secret reports whether v sits under a field named like a secret.
+func collectSecrets(v reflect.Value, secret bool, add func(string)) {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			collectSecrets(v.Elem(), secret, add)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			collectSecrets(v.Field(i), secretFieldName.MatchString(name), add)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			collectSecrets(v.Index(i), secret, add)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			keySecret := secret
+			if iter.Key().Kind() == reflect.String && iter.Value().Kind() == reflect.String &&
+				secretMapKey.MatchString(iter.Key().String()) {
+				keySecret = true
+			}
+			collectSecrets(iter.Value(), keySecret, add)
+		}
+	case reflect.String:
+		if secret {
+			add(v.String())
+		}
+	}
+}
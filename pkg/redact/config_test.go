@@ -0,0 +1,61 @@
+package redact
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestConfigSecrets(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "openai.key"), []byte("sk-from-file-0123456789\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	data := `{
+		"model_list": [{"model_name": "gpt", "model": "openai/gpt-4", "api_key": "file://openai.key"}],
+		"channels": {"telegram": {"token": "123456:telegram-bot-token"}},
+		"tools": {"mcp": {"servers": {"remote": {
+			"url": "https://mcp.example.com",
+			"headers": {"Authorization": "Bearer mcp-header-token", "Accept": "application/json"},
+			"env": {"DB_PASSWORD": "correct horse battery password"}
+		}}}}
+	}`
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	secrets := ConfigSecrets(cfg)
+	for _, want := range []string{
+		"sk-from-file-0123456789",
+		"123456:telegram-bot-token",
+		"Bearer mcp-header-token",
+		"mcp-header-token",
+		"correct horse battery password",
+	} {
+		if !slices.Contains(secrets, strings.TrimSpace(want)) {
+			t.Errorf("ConfigSecrets() missing %q in %q", want, secrets)
+		}
+	}
+	for _, notWant := range []string{"application/json", "https://mcp.example.com", "file://openai.key", "password"} {
+		if slices.Contains(secrets, notWant) {
+			t.Errorf("ConfigSecrets() unexpectedly contains %q", notWant)
+		}
+	}
+}
+
+func TestFromConfigDisabled(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Redaction.Enabled = false
+	if r := FromConfig(cfg); r != nil {
+		t.Fatalf("FromConfig() = %v, want nil when disabled", r)
+	}
+}
@@ -0,0 +1,139 @@
+// Package redact masks secrets and personal data in text before it is logged,
+// persisted or displayed.
+package redact
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// Placeholder replaces known secret values.
+const Placeholder = "[REDACTED]"
+
+// HasPlaceholder reports whether s contains text masked by a Redactor.
+func HasPlaceholder(s string) bool {
+	return strings.Contains(s, "[REDACTED")
+}
+
+// CountPlaceholders returns how many masked values s contains.
+func CountPlaceholders(s string) int {
+	return strings.Count(s, "[REDACTED")
+}
+
+// minSecretLen is the shortest known secret that is masked; shorter values
+// (ports, "true", short ids) would mangle ordinary text.
+const minSecretLen = 8
+
+type detector struct {
+	kind    string
+	pattern *regexp.Regexp
+}
+
+// secretDetectors match credential shapes that are worth masking even when
+// the value is not configured anywhere.
+var secretDetectors = []detector{
+	{"private_key", regexp.MustCompile(
+		`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`)},
+	{"jwt", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)},
+	{"api_key", regexp.MustCompile(`\bsk-(?:ant-|proj-)?[A-Za-z0-9_-]{20,}`)},
+	{"api_key", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})`)},
+	{"api_key", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+	{"api_key", regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`)},
+	{"api_key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}`)},
+	{"bot_token", regexp.MustCompile(`\b\d{8,10}:AA[A-Za-z0-9_-]{33}`)},
+	{"bearer", regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/-]{20,}=*`)},
+}
+
+// piiDetectors match personal data; they are only applied when enabled.
+var piiDetectors = []detector{
+	{"email", regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`)},
+	{"phone", regexp.MustCompile(`(?:\+\d{1,3}[\s.-]?)?\(?\b\d{3}\)?[\s.-]?\d{3,4}[\s.-]?\d{4}\b`)},
+}
+
+// Options configures a Redactor.
+type Options struct {
+	// Secrets are literal values to mask wherever they appear.
+	Secrets []string
+	// PII also masks email addresses and phone numbers.
+	PII bool
+	// Patterns are additional regular expressions to mask.
+	Patterns []*regexp.Regexp
+}
+
+// Redactor masks known secret values and detected credential shapes.
+// A nil *Redactor returns text unchanged.
+type Redactor struct {
+	secrets   *strings.Replacer
+	detectors []detector
+}
+
+// New builds a Redactor from opts.
+func New(opts Options) *Redactor {
+	seen := make(map[string]struct{}, len(opts.Secrets))
+	secrets := make([]string, 0, len(opts.Secrets))
+	for _, s := range opts.Secrets {
+		s = strings.TrimSpace(s)
+		if len(s) < minSecretLen {
+			continue
+		}
+		if _, ok := seen[s]; ok {
+			continue
+		}
+		seen[s] = struct{}{}
+		secrets = append(secrets, s)
+	}
+	// Longest first so a secret containing another is masked whole.
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+
+	r := &Redactor{}
+	if len(secrets) > 0 {
+		pairs := make([]string, 0, len(secrets)*2)
+		for _, s := range secrets {
+			pairs = append(pairs, s, Placeholder)
+		}
+		r.secrets = strings.NewReplacer(pairs...)
+	}
+	r.detectors = append(r.detectors, secretDetectors...)
+	if opts.PII {
+		r.detectors = append(r.detectors, piiDetectors...)
+	}
+	for _, p := range opts.Patterns {
+		r.detectors = append(r.detectors, detector{kind: "custom", pattern: p})
+	}
+	return r
+}
+
+// Redact returns s with secrets replaced by Placeholder and detected values
+// replaced by "[REDACTED:<kind>]".
+func (r *Redactor) Redact(s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	if r.secrets != nil {
+		s = r.secrets.Replace(s)
+	}
+	for _, d := range r.detectors {
+		s = d.pattern.ReplaceAllString(s, "[REDACTED:"+d.kind+"]")
+	}
+	return s
+}
+
+var defaultRedactor atomic.Pointer[Redactor]
+
+// SetDefault installs r as the process-wide redactor used by String. A nil
+// r disables redaction.
+func SetDefault(r *Redactor) {
+	defaultRedactor.Store(r)
+}
+
+// Default returns the process-wide redactor, or nil if none is installed.
+func Default() *Redactor {
+	return defaultRedactor.Load()
+}
+
+// String redacts s with the process-wide redactor.
+func String(s string) string {
+	return Default().Redact(s)
+}
@@ -12,6 +12,7 @@ type RouteInput struct {
 	AccountID  string
 	Peer       *RoutePeer
 	ParentPeer *RoutePeer
+	ThreadID   string
 	GuildID    string
 	TeamID     string
 }
@@ -48,6 +49,10 @@ func (r *RouteResolver) ResolveRoute(input RouteInput) ResolvedRoute {
 	if dmScope == "" {
 		dmScope = DMScopeMain
 	}
+	threadScope := ThreadScope(r.cfg.Session.ThreadScope)
+	if threadScope == "" {
+		threadScope = ThreadScopeShared
+	}
 	identityLinks := r.cfg.Session.IdentityLinks
 
 	bindings := r.filterBindings(channel, accountID)
@@ -60,6 +65,8 @@ func (r *RouteResolver) ResolveRoute(input RouteInput) ResolvedRoute {
 			AccountID:     accountID,
 			Peer:          peer,
 			DMScope:       dmScope,
+			ThreadID:      input.ThreadID,
+			ThreadScope:   threadScope,
 			IdentityLinks: identityLinks,
 		}))
 		mainSessionKey := strings.ToLower(BuildAgentMainSessionKey(resolvedAgentID))
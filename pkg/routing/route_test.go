@@ -295,3 +295,23 @@ func TestResolveRoute_NoDefaultUsesFirst(t *testing.T) {
 		t.Errorf("AgentID = %q, want 'alpha' (first in list)", route.AgentID)
 	}
 }
+
+func TestResolveRoute_ThreadScope(t *testing.T) {
+	input := RouteInput{
+		Channel:  "discord",
+		Peer:     &RoutePeer{Kind: "channel", ID: "parent1"},
+		ThreadID: "thread9",
+	}
+
+	cfg := testConfig(nil, nil)
+	route := NewRouteResolver(cfg).ResolveRoute(input)
+	if route.SessionKey != "agent:main:discord:channel:parent1" {
+		t.Errorf("empty thread_scope SessionKey = %q, want threads shared", route.SessionKey)
+	}
+
+	cfg.Session.ThreadScope = "per-thread"
+	route = NewRouteResolver(cfg).ResolveRoute(input)
+	if route.SessionKey != "agent:main:discord:channel:parent1:thread:thread9" {
+		t.Errorf("per-thread SessionKey = %q", route.SessionKey)
+	}
+}
@@ -15,6 +15,17 @@ const (
 	DMScopePerAccountChannelPeer DMScope = "per-account-channel-peer"
 )
 
+// ThreadScope controls whether threads get their own session.
+type ThreadScope string
+
+const (
+	// ThreadScopeShared keeps thread replies in the parent conversation's session.
+	ThreadScopeShared ThreadScope = "shared"
+	// ThreadScopePerThread gives every thread (Slack thread, Discord thread,
+	// Telegram topic, Matrix thread) its own session.
+	ThreadScopePerThread ThreadScope = "per-thread"
+)
+
 // RoutePeer represents a chat peer with kind and ID.
 type RoutePeer struct {
 	Kind string // "direct", "group", "channel"
@@ -28,6 +39,8 @@ type SessionKeyParams struct {
 	AccountID     string
 	Peer          *RoutePeer
 	DMScope       DMScope
+	ThreadID      string
+	ThreadScope   ThreadScope
 	IdentityLinks map[string][]string
 }
 
@@ -43,7 +56,21 @@ func BuildAgentMainSessionKey(agentID string) string {
 }
 
 // BuildAgentPeerSessionKey constructs a session key based on agent, channel, peer, and DM scope.
+// With ThreadScopePerThread, messages carrying a ThreadID get ":thread:<id>"
+// appended to the peer session key. The agent main session is never split.
 func BuildAgentPeerSessionKey(params SessionKeyParams) string {
+	key := buildPeerSessionKey(params)
+	threadID := strings.ToLower(strings.TrimSpace(params.ThreadID))
+	if params.ThreadScope != ThreadScopePerThread || threadID == "" {
+		return key
+	}
+	if key == BuildAgentMainSessionKey(params.AgentID) {
+		return key
+	}
+	return fmt.Sprintf("%s:thread:%s", key, threadID)
+}
+
+func buildPeerSessionKey(params SessionKeyParams) string {
 	agentID := NormalizeAgentID(params.AgentID)
 
 	peer := params.Peer
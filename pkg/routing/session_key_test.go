@@ -205,3 +205,46 @@ func TestIsSubagentSessionKey(t *testing.T) {
 		}
 	}
 }
+
+func TestBuildAgentPeerSessionKey_ThreadScopePerThread(t *testing.T) {
+	got := BuildAgentPeerSessionKey(SessionKeyParams{
+		AgentID:     "main",
+		Channel:     "slack",
+		Peer:        &RoutePeer{Kind: "channel", ID: "C123"},
+		ThreadID:    "1700000000.0001",
+		ThreadScope: ThreadScopePerThread,
+	})
+	want := "agent:main:slack:channel:c123:thread:1700000000.0001"
+	if got != want {
+		t.Errorf("ThreadScopePerThread = %q, want %q", got, want)
+	}
+}
+
+func TestBuildAgentPeerSessionKey_ThreadScopeShared(t *testing.T) {
+	got := BuildAgentPeerSessionKey(SessionKeyParams{
+		AgentID:     "main",
+		Channel:     "slack",
+		Peer:        &RoutePeer{Kind: "channel", ID: "C123"},
+		ThreadID:    "1700000000.0001",
+		ThreadScope: ThreadScopeShared,
+	})
+	want := "agent:main:slack:channel:c123"
+	if got != want {
+		t.Errorf("ThreadScopeShared = %q, want %q", got, want)
+	}
+}
+
+func TestBuildAgentPeerSessionKey_ThreadScopeKeepsMainSession(t *testing.T) {
+	got := BuildAgentPeerSessionKey(SessionKeyParams{
+		AgentID:     "main",
+		Channel:     "slack",
+		Peer:        &RoutePeer{Kind: "direct", ID: "U1"},
+		DMScope:     DMScopeMain,
+		ThreadID:    "1700000000.0001",
+		ThreadScope: ThreadScopePerThread,
+	})
+	want := "agent:main:main"
+	if got != want {
+		t.Errorf("ThreadScopePerThread with DMScopeMain = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,668 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ApplyPatchTool applies a patch that may touch several files. Every hunk
+// is checked against the current file contents before anything is written,
+// and a failed write rolls back the files already changed, so the workspace
+// is never left half-patched.
+type ApplyPatchTool struct {
+	fs        fileSystem
+	workspace string
+}
+
+func NewApplyPatchTool(workspace string, restrict bool, allowPaths ...[]*regexp.Regexp) *ApplyPatchTool {
+	var patterns []*regexp.Regexp
+	if len(allowPaths) > 0 {
+		patterns = allowPaths[0]
+	}
+	return &ApplyPatchTool{fs: buildFs(workspace, restrict, patterns), workspace: workspace}
+}
+
+func (t *ApplyPatchTool) Name() string {
+	return "apply_patch"
+}
+
+func (t *ApplyPatchTool) Description() string {
+	return "Apply changes to one or more files in a single call. Accepts a unified diff (as produced by git diff) " +
+		"or this simpler format:\n" +
+		"*** Begin Patch\n" +
+		"*** Update File: path/to/file\n" +
+		"@@ optional line near the change, e.g. a function signature\n" +
+		" unchanged context line\n" +
+		"-line to remove\n" +
+		"+line to add\n" +
+		"*** Add File: path/to/new_file\n" +
+		"+content of the new file\n" +
+		"*** Delete File: path/to/old_file\n" +
+		"*** End Patch\n" +
+		"An Update File section may have several @@ hunks and a \"*** Move to: new/path\" line. " +
+		"Include a few unchanged context lines around each change. " +
+		"If any hunk does not match, no file is changed."
+}
+
+func (t *ApplyPatchTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"patch": map[string]any{
+				"type":        "string",
+				"description": "The patch, as a unified diff or in the *** Begin Patch format.",
+			},
+			"dry_run": map[string]any{
+				"type":        "boolean",
+				"description": "Only check that the patch applies cleanly; do not write anything.",
+			},
+		},
+		"required": []string{"patch"},
+	}
+}
+
+func (t *ApplyPatchTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	patch, _ := args["patch"].(string)
+	if strings.TrimSpace(patch) == "" {
+		return ErrorResult("patch is required")
+	}
+	ops, err := parsePatch(patch)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("invalid patch: %v", err))
+	}
+	var removed, added []string
+	for _, op := range ops {
+		for _, h := range op.hunks {
+			removed = append(removed, h.old...)
+			added = append(added, h.new...)
+		}
+	}
+	if result := rejectRedacted("patch", strings.Join(added, "\n"), strings.Join(removed, "\n")); result != nil {
+		return result
+	}
+
+	set := newPatchFileSet(t.fs, t.workspace)
+	var summary []string
+	var failures []string
+	for _, op := range ops {
+		line, err := set.apply(op)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", op.path, err))
+			continue
+		}
+		summary = append(summary, line)
+	}
+	if len(failures) > 0 {
+		return ErrorResult("Patch not applied; no files were changed.\n" + strings.Join(failures, "\n"))
+	}
+
+	if dryRun, _ := args["dry_run"].(bool); dryRun {
+		return NewToolResult(fmt.Sprintf("Patch applies cleanly to %d file(s):\n%s", len(summary), strings.Join(summary, "\n")))
+	}
+	if err := set.commit(); err != nil {
+		return ErrorResult(err.Error())
+	}
+	return NewToolResult(fmt.Sprintf("Applied patch to %d file(s):\n%s", len(summary), strings.Join(summary, "\n")))
+}
+
+// patchOp is one file section of a patch.
+type patchOp struct {
+	kind   byte // 'A' add, 'M' update, 'D' delete
+	path   string
+	moveTo string
+	hunks  []patchHunk
+	// noNewline is set when the patch marks the new file as not ending in
+	// a newline.
+	noNewline bool
+}
+
+type patchHunk struct {
+	oldStart int    // 1-based line from a unified @@ header, 0 when unknown
+	anchor   string // text of an "@@ anchor" line in the simple format
+	atEOF    bool   // "*** End of File": the hunk must match at the end
+	old      []string
+	new      []string
+	added    int
+	removed  int
+}
+
+func parsePatch(patch string) ([]patchOp, error) {
+	patch = strings.ReplaceAll(patch, "\r\n", "\n")
+	lines := strings.Split(strings.TrimRight(patch, "\n"), "\n")
+	var ops []patchOp
+	var err error
+	if slices.ContainsFunc(lines, func(l string) bool { return strings.TrimSpace(l) == "*** Begin Patch" }) {
+		ops, err = parseEnvelopePatch(lines)
+	} else {
+		ops, err = parseUnifiedDiff(lines)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(ops) == 0 {
+		return nil, errors.New("no file changes found")
+	}
+	for i := range ops {
+		if ops[i].path == "" {
+			return nil, errors.New("file section without a path")
+		}
+		if ops[i].kind == 'M' && len(ops[i].hunks) == 0 && ops[i].moveTo == "" {
+			return nil, fmt.Errorf("%s: no hunks", ops[i].path)
+		}
+	}
+	return ops, nil
+}
+
+// parseEnvelopePatch parses the "*** Begin Patch" format.
+func parseEnvelopePatch(lines []string) ([]patchOp, error) {
+	var ops []patchOp
+	var op *patchOp
+	var hunk *patchHunk
+	started := false
+
+	flushHunk := func() {
+		if op != nil && hunk != nil && op.kind == 'M' {
+			trimBareBlankContext(hunk)
+		}
+		if op != nil && hunk != nil && (len(hunk.old) > 0 || len(hunk.new) > 0) {
+			op.hunks = append(op.hunks, *hunk)
+		}
+		hunk = nil
+	}
+	startOp := func(kind byte, path string) {
+		flushHunk()
+		ops = append(ops, patchOp{kind: kind, path: strings.TrimSpace(path)})
+		op = &ops[len(ops)-1]
+	}
+
+	for i, line := range lines {
+		switch {
+		case strings.TrimSpace(line) == "*** Begin Patch":
+			started = true
+		case !started:
+			continue
+		case strings.TrimSpace(line) == "*** End Patch":
+			flushHunk()
+			return ops, nil
+		case strings.HasPrefix(line, "*** Add File: "):
+			startOp('A', strings.TrimPrefix(line, "*** Add File: "))
+			hunk = &patchHunk{}
+		case strings.HasPrefix(line, "*** Update File: "):
+			startOp('M', strings.TrimPrefix(line, "*** Update File: "))
+		case strings.HasPrefix(line, "*** Delete File: "):
+			startOp('D', strings.TrimPrefix(line, "*** Delete File: "))
+		case op == nil:
+			return nil, fmt.Errorf("line %d: expected a *** Add/Update/Delete File header", i+1)
+		case strings.HasPrefix(line, "*** Move to: "):
+			op.moveTo = strings.TrimSpace(strings.TrimPrefix(line, "*** Move to: "))
+		case strings.TrimSpace(line) == "*** End of File":
+			if hunk != nil {
+				hunk.atEOF = true
+			}
+		case op.kind == 'D':
+			return nil, fmt.Errorf("line %d: unexpected content in Delete File section", i+1)
+		case op.kind == 'A':
+			if !strings.HasPrefix(line, "+") {
+				return nil, fmt.Errorf("line %d: lines of an added file must start with +", i+1)
+			}
+			hunk.new = append(hunk.new, line[1:])
+			hunk.added++
+		case strings.HasPrefix(line, "@@"):
+			flushHunk()
+			hunk = &patchHunk{anchor: strings.TrimSpace(strings.TrimPrefix(line, "@@"))}
+		default:
+			if hunk == nil {
+				hunk = &patchHunk{}
+			}
+			if err := addHunkLine(hunk, line); err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+		}
+	}
+	return nil, errors.New("missing *** End Patch")
+}
+
+var unifiedHunkRE = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+\d+(?:,(\d+))? @@`)
+
+// parseUnifiedDiff parses git-style and plain unified diffs. Line counts in
+// hunk headers are not trusted, since hand-written diffs often get them
+// wrong; a hunk ends at the next header instead. A line that fits no hunk
+// is only accepted as trailing text, such as a git signature: it must not
+// cut a hunk short of its declared counts or be followed by another hunk of
+// the same file.
+func parseUnifiedDiff(lines []string) ([]patchOp, error) {
+	var ops []patchOp
+	var op *patchOp
+	var hunk *patchHunk
+	var renameFrom string
+	var oldCount, newCount int
+	// trailingAt is the 1-based line where text ended the last hunk.
+	trailingAt := 0
+
+	flushHunk := func() {
+		if op != nil && hunk != nil {
+			trimBareBlankContext(hunk)
+			op.hunks = append(op.hunks, *hunk)
+		}
+		hunk = nil
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "diff "):
+			// "diff --git" or the command line in "diff -ru" output.
+			flushHunk()
+			op, renameFrom, trailingAt = nil, "", 0
+		case strings.HasPrefix(line, "rename from "):
+			renameFrom = strings.TrimPrefix(line, "rename from ")
+		case strings.HasPrefix(line, "rename to ") && renameFrom != "":
+			// A pure rename has no ---/+++ lines.
+			ops = append(ops, patchOp{kind: 'M', path: renameFrom, moveTo: strings.TrimPrefix(line, "rename to ")})
+			op = &ops[len(ops)-1]
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			flushHunk()
+			trailingAt = 0
+			oldPath := diffPath(strings.TrimPrefix(line, "--- "))
+			newPath := diffPath(strings.TrimPrefix(lines[i+1], "+++ "))
+			i++
+			if op != nil && op.moveTo != "" && op.path == oldPath && op.moveTo == newPath {
+				continue // rename with changes; keep the op from the rename lines
+			}
+			switch {
+			case oldPath == "" && newPath == "":
+				return nil, fmt.Errorf("line %d: both file paths are /dev/null", i)
+			case oldPath == "":
+				ops = append(ops, patchOp{kind: 'A', path: newPath})
+			case newPath == "":
+				ops = append(ops, patchOp{kind: 'D', path: oldPath})
+			default:
+				o := patchOp{kind: 'M', path: oldPath}
+				if newPath != oldPath {
+					o.moveTo = newPath
+				}
+				ops = append(ops, o)
+			}
+			op = &ops[len(ops)-1]
+		case strings.HasPrefix(line, "@@"):
+			if op == nil {
+				return nil, fmt.Errorf("line %d: hunk without a file header", i+1)
+			}
+			if trailingAt > 0 {
+				return nil, fmt.Errorf("line %d: unexpected line %q in hunk; lines must start with ' ', '-' or '+'",
+					trailingAt, lines[trailingAt-1])
+			}
+			flushHunk()
+			hunk = &patchHunk{}
+			oldCount, newCount = 0, 0
+			if m := unifiedHunkRE.FindStringSubmatch(line); m != nil {
+				hunk.oldStart, _ = strconv.Atoi(m[1])
+				oldCount, newCount = hunkCount(m[2]), hunkCount(m[3])
+			}
+		case hunk != nil && strings.HasPrefix(line, `\`):
+			// "\ No newline at end of file" after a new-side line.
+			if prev := lines[i-1]; !strings.HasPrefix(prev, "-") {
+				op.noNewline = true
+			}
+		case hunk != nil:
+			if err := addHunkLine(hunk, line); err != nil {
+				if len(hunk.old) < oldCount || len(hunk.new) < newCount {
+					return nil, fmt.Errorf("line %d: %w", i+1, err)
+				}
+				flushHunk()
+				trailingAt = i + 1
+			}
+		}
+	}
+	flushHunk()
+	for i := range ops {
+		if ops[i].kind == 'A' {
+			// An added file is the new side of its hunks.
+			var content []string
+			for _, h := range ops[i].hunks {
+				content = append(content, h.new...)
+			}
+			ops[i].hunks = []patchHunk{{new: content, added: len(content)}}
+		}
+	}
+	return ops, nil
+}
+
+// hunkCount parses the line count of one side of a unified hunk header; an
+// omitted count means one line.
+func hunkCount(s string) int {
+	if s == "" {
+		return 1
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// diffPath strips the a/ or b/ prefix and any timestamp from a ---/+++ path.
+// /dev/null becomes "".
+func diffPath(p string) string {
+	if tab := strings.IndexByte(p, '\t'); tab >= 0 {
+		p = p[:tab]
+	}
+	p = strings.TrimSpace(p)
+	if p == "/dev/null" {
+		return ""
+	}
+	if strings.HasPrefix(p, "a/") || strings.HasPrefix(p, "b/") {
+		return p[2:]
+	}
+	return p
+}
+
+func addHunkLine(h *patchHunk, line string) error {
+	if line == "" {
+		// Editors and models often strip the space of blank context lines.
+		h.old = append(h.old, "")
+		h.new = append(h.new, "")
+		return nil
+	}
+	switch line[0] {
+	case ' ':
+		h.old = append(h.old, line[1:])
+		h.new = append(h.new, line[1:])
+	case '-':
+		h.old = append(h.old, line[1:])
+		h.removed++
+	case '+':
+		h.new = append(h.new, line[1:])
+		h.added++
+	default:
+		return fmt.Errorf("unexpected line %q in hunk; lines must start with ' ', '-' or '+'", line)
+	}
+	return nil
+}
+
+// trimBareBlankContext drops trailing blank context lines, which in a
+// unified diff are usually the separator before the next file.
+func trimBareBlankContext(h *patchHunk) {
+	for len(h.old) > 0 && len(h.new) > 0 && h.old[len(h.old)-1] == "" && h.new[len(h.new)-1] == "" {
+		h.old = h.old[:len(h.old)-1]
+		h.new = h.new[:len(h.new)-1]
+	}
+}
+
+// patchFile is the in-memory state of one file while a patch is applied.
+type patchFile struct {
+	original      []byte
+	existed       bool
+	lines         []string
+	exists        bool
+	crlf          bool
+	finalNewline  bool
+	touched       bool
+	displayedPath string
+}
+
+func (f *patchFile) content() []byte {
+	if len(f.lines) == 0 {
+		return nil
+	}
+	sep := "\n"
+	if f.crlf {
+		sep = "\r\n"
+	}
+	s := strings.Join(f.lines, sep)
+	if f.finalNewline {
+		s += sep
+	}
+	return []byte(s)
+}
+
+// patchFileSet applies operations to in-memory copies of the files and
+// writes them all at the end.
+type patchFileSet struct {
+	fs        fileSystem
+	workspace string
+	files     map[string]*patchFile
+	order     []string
+}
+
+func newPatchFileSet(fsys fileSystem, workspace string) *patchFileSet {
+	return &patchFileSet{fs: fsys, workspace: workspace, files: make(map[string]*patchFile)}
+}
+
+func (s *patchFileSet) resolve(path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(s.workspace, filepath.FromSlash(path))
+}
+
+func (s *patchFileSet) load(path string) (*patchFile, error) {
+	key := s.resolve(path)
+	if f, ok := s.files[key]; ok {
+		return f, nil
+	}
+	f := &patchFile{displayedPath: path, finalNewline: true}
+	data, err := s.fs.ReadFile(key)
+	switch {
+	case err == nil:
+		f.original, f.existed, f.exists = data, true, true
+		text := string(data)
+		f.crlf = strings.Contains(text, "\r\n")
+		text = strings.ReplaceAll(text, "\r\n", "\n")
+		f.finalNewline = text == "" || strings.HasSuffix(text, "\n")
+		if text = strings.TrimSuffix(text, "\n"); text != "" || len(data) > 0 {
+			f.lines = strings.Split(text, "\n")
+		}
+	case errors.Is(err, fs.ErrNotExist):
+	default:
+		return nil, err
+	}
+	s.files[key] = f
+	s.order = append(s.order, key)
+	return f, nil
+}
+
+// apply checks op against the in-memory files and records its effect. It
+// returns a summary line such as "M path (+3 -1)".
+func (s *patchFileSet) apply(op patchOp) (string, error) {
+	f, err := s.load(op.path)
+	if err != nil {
+		return "", err
+	}
+	switch op.kind {
+	case 'A':
+		if f.exists {
+			return "", errors.New("file already exists")
+		}
+		f.lines = slices.Clone(op.hunks[0].new)
+		f.exists, f.touched, f.finalNewline = true, true, !op.noNewline
+		return fmt.Sprintf("A %s (+%d)", op.path, len(f.lines)), nil
+	case 'D':
+		if !f.exists {
+			return "", errors.New("file does not exist")
+		}
+		removed := len(f.lines)
+		f.lines, f.exists, f.touched = nil, false, true
+		return fmt.Sprintf("D %s (-%d)", op.path, removed), nil
+	}
+
+	if !f.exists {
+		return "", errors.New("file does not exist")
+	}
+	lines := f.lines
+	added, removed := 0, 0
+	cursor, offset := 0, 0
+	for i, h := range op.hunks {
+		pos, err := findHunk(lines, h, cursor, offset)
+		if err != nil {
+			return "", fmt.Errorf("hunk %d: %w", i+1, err)
+		}
+		lines = slices.Concat(lines[:pos], h.new, lines[pos+len(h.old):])
+		cursor = pos + len(h.new)
+		offset += len(h.new) - len(h.old)
+		added += h.added
+		removed += h.removed
+	}
+	if op.noNewline {
+		f.finalNewline = false
+	}
+	f.lines, f.touched = lines, true
+
+	status := "M " + op.path
+	if op.moveTo != "" {
+		target, err := s.load(op.moveTo)
+		if err != nil {
+			return "", err
+		}
+		if target.exists {
+			return "", fmt.Errorf("cannot move to %s: file already exists", op.moveTo)
+		}
+		target.lines, target.exists, target.touched = f.lines, true, true
+		target.crlf, target.finalNewline = f.crlf, f.finalNewline
+		f.lines, f.exists = nil, false
+		status = fmt.Sprintf("R %s -> %s", op.path, op.moveTo)
+	}
+	if len(op.hunks) == 0 {
+		return status, nil
+	}
+	return fmt.Sprintf("%s (+%d -%d, %d hunk(s))", status, added, removed, len(op.hunks)), nil
+}
+
+// findHunk returns the index in lines at which h.old starts. Matching is
+// exact first, then ignoring trailing whitespace, then ignoring all
+// surrounding whitespace. Among several matches the one closest to the
+// hunk's line number (adjusted by earlier hunks) wins; without one, the
+// first match after the previous hunk.
+func findHunk(lines []string, h patchHunk, cursor, offset int) (int, error) {
+	start := cursor
+	if h.anchor != "" {
+		idx := slices.IndexFunc(lines[cursor:], func(l string) bool {
+			return strings.Contains(l, h.anchor)
+		})
+		if idx < 0 {
+			return 0, fmt.Errorf("anchor line %q not found", h.anchor)
+		}
+		start = cursor + idx + 1
+		if len(h.old) > 0 && strings.TrimSpace(h.old[0]) == h.anchor {
+			start-- // the anchor is also the first context line
+		}
+	}
+
+	if len(h.old) == 0 {
+		switch {
+		case h.atEOF:
+			return len(lines), nil
+		case h.oldStart > 0:
+			return min(max(h.oldStart+offset, 0), len(lines)), nil
+		case h.anchor != "":
+			return start, nil
+		default:
+			return len(lines), nil
+		}
+	}
+
+	hint := -1
+	if h.oldStart > 0 {
+		hint = h.oldStart - 1 + offset
+	}
+	normalizers := []func(string) string{
+		func(s string) string { return s },
+		func(s string) string { return strings.TrimRight(s, " \t") },
+		strings.TrimSpace,
+	}
+	for _, norm := range normalizers {
+		var matches []int
+		for p := start; p+len(h.old) <= len(lines); p++ {
+			if h.atEOF && p+len(h.old) != len(lines) {
+				continue
+			}
+			if hunkMatches(lines[p:p+len(h.old)], h.old, norm) {
+				matches = append(matches, p)
+			}
+		}
+		if len(matches) == 0 {
+			continue
+		}
+		if hint < 0 {
+			return matches[0], nil
+		}
+		best := matches[0]
+		for _, p := range matches[1:] {
+			if abs(p-hint) < abs(best-hint) {
+				best = p
+			}
+		}
+		return best, nil
+	}
+
+	preview := h.old[:min(len(h.old), 3)]
+	return 0, fmt.Errorf("could not find the lines to change:\n  %s", strings.Join(preview, "\n  "))
+}
+
+func hunkMatches(lines, old []string, norm func(string) string) bool {
+	for i := range old {
+		if norm(lines[i]) != norm(old[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// commit writes every changed file. If a write fails, files already
+// written are restored to their original contents.
+func (s *patchFileSet) commit() error {
+	var undo []func() error
+	for _, path := range s.order {
+		f := s.files[path]
+		if !f.touched {
+			continue
+		}
+		var err error
+		switch {
+		case f.exists:
+			err = s.fs.WriteFile(path, f.content())
+			if err == nil {
+				undo = append(undo, s.restore(path, f))
+			}
+		case f.existed:
+			err = s.fs.Remove(path)
+			if err == nil {
+				undo = append(undo, s.restore(path, f))
+			}
+		}
+		if err != nil {
+			var rollbackErrs []string
+			for i := len(undo) - 1; i >= 0; i-- {
+				if rerr := undo[i](); rerr != nil {
+					rollbackErrs = append(rollbackErrs, rerr.Error())
+				}
+			}
+			if len(rollbackErrs) > 0 {
+				return fmt.Errorf("failed to write %s: %v; rollback also failed: %s",
+					f.displayedPath, err, strings.Join(rollbackErrs, "; "))
+			}
+			return fmt.Errorf("failed to write %s: %v; no files were changed", f.displayedPath, err)
+		}
+	}
+	return nil
+}
+
+func (s *patchFileSet) restore(path string, f *patchFile) func() error {
+	return func() error {
+		if f.existed {
+			return s.fs.WriteFile(path, f.original)
+		}
+		return s.fs.Remove(path)
+	}
+}
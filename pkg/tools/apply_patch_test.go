@@ -0,0 +1,237 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readTreeFile(t *testing.T, root, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(name)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestApplyPatchTool_EnvelopeFormat(t *testing.T) {
+	root := writeSearchTree(t, map[string]string{
+		"main.go":     "package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n\nfunc other() {\n\tprintln(\"hello\")\n}\n",
+		"old.txt":     "a\nb\n",
+		"obsolete.md": "gone\n",
+	})
+	tool := NewApplyPatchTool(root, true)
+
+	patch := strings.Join([]string{
+		"*** Begin Patch",
+		"*** Update File: main.go",
+		"@@ func other() {",
+		"-\tprintln(\"hello\")",
+		"+\tprintln(\"other\")",
+		"*** Update File: old.txt",
+		"*** Move to: dir/new.txt",
+		" a",
+		"-b",
+		"+c",
+		"*** Add File: pkg/added.go",
+		"+package pkg",
+		"*** Delete File: obsolete.md",
+		"*** End Patch",
+	}, "\n")
+	result := tool.Execute(context.Background(), map[string]any{"patch": patch})
+	if result.IsError {
+		t.Fatal(result.ForLLM)
+	}
+	for _, want := range []string{
+		"M main.go (+1 -1, 1 hunk(s))",
+		"R old.txt -> dir/new.txt (+1 -1, 1 hunk(s))",
+		"A pkg/added.go (+1)",
+		"D obsolete.md (-1)",
+	} {
+		if !strings.Contains(result.ForLLM, want) {
+			t.Errorf("missing %q in summary:\n%s", want, result.ForLLM)
+		}
+	}
+
+	if got := readTreeFile(t, root, "main.go"); !strings.Contains(got, "main() {\n\tprintln(\"hello\")") ||
+		!strings.Contains(got, "other() {\n\tprintln(\"other\")") {
+		t.Errorf("anchor should select the second occurrence, got:\n%s", got)
+	}
+	if got := readTreeFile(t, root, "dir/new.txt"); got != "a\nc\n" {
+		t.Errorf("unexpected moved file: %q", got)
+	}
+	if got := readTreeFile(t, root, "pkg/added.go"); got != "package pkg\n" {
+		t.Errorf("unexpected added file: %q", got)
+	}
+	for _, name := range []string{"old.txt", "obsolete.md"} {
+		if _, err := os.Stat(filepath.Join(root, name)); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed, got %v", name, err)
+		}
+	}
+}
+
+func TestApplyPatchTool_UnifiedDiff(t *testing.T) {
+	root := writeSearchTree(t, map[string]string{
+		"a.txt": "one\ntwo\nthree\nfour\nfive\nsix\nseven\n",
+		"b.txt": "keep\r\nold\r\n",
+	})
+	tool := NewApplyPatchTool(root, true)
+
+	// Line numbers are off by one and the blank separator has lost its
+	// leading space; both are common in hand-written diffs.
+	patch := strings.Join([]string{
+		"diff --git a/a.txt b/a.txt",
+		"index 1111111..2222222 100644",
+		"--- a/a.txt",
+		"+++ b/a.txt",
+		"@@ -3,2 +3,2 @@",
+		" two",
+		"-three",
+		"+THREE",
+		"@@ -6,2 +6,3 @@",
+		" six",
+		"+six and a half",
+		" seven",
+		"",
+		"--- a/b.txt",
+		"+++ b/b.txt",
+		"@@ -1,2 +1,2 @@",
+		" keep",
+		"-old",
+		"+new",
+		"\\ No newline at end of file",
+		"--- /dev/null",
+		"+++ b/c.txt",
+		"@@ -0,0 +1,2 @@",
+		"+x",
+		"+y",
+	}, "\n")
+	result := tool.Execute(context.Background(), map[string]any{"patch": patch})
+	if result.IsError {
+		t.Fatal(result.ForLLM)
+	}
+	if !strings.Contains(result.ForLLM, "M a.txt (+2 -1, 2 hunk(s))") {
+		t.Errorf("unexpected summary:\n%s", result.ForLLM)
+	}
+	if got := readTreeFile(t, root, "a.txt"); got != "one\ntwo\nTHREE\nfour\nfive\nsix\nsix and a half\nseven\n" {
+		t.Errorf("unexpected a.txt: %q", got)
+	}
+	if got := readTreeFile(t, root, "b.txt"); got != "keep\r\nnew" {
+		t.Errorf("expected CRLF kept and final newline dropped, got %q", got)
+	}
+	if got := readTreeFile(t, root, "c.txt"); got != "x\ny\n" {
+		t.Errorf("unexpected c.txt: %q", got)
+	}
+}
+
+func TestApplyPatchTool_AllOrNothing(t *testing.T) {
+	root := writeSearchTree(t, map[string]string{"a.txt": "a\n", "b.txt": "b\n"})
+	tool := NewApplyPatchTool(root, true)
+
+	patch := strings.Join([]string{
+		"*** Begin Patch",
+		"*** Update File: a.txt",
+		"-a",
+		"+A",
+		"*** Update File: b.txt",
+		"-missing",
+		"+B",
+		"*** Add File: a.txt.bak",
+		"+x",
+		"*** End Patch",
+	}, "\n")
+	result := tool.Execute(context.Background(), map[string]any{"patch": patch})
+	if !result.IsError || !strings.Contains(result.ForLLM, "no files were changed") ||
+		!strings.Contains(result.ForLLM, "b.txt: hunk 1") {
+		t.Fatalf("expected failure naming the bad hunk, got %s", result.ForLLM)
+	}
+	if got := readTreeFile(t, root, "a.txt"); got != "a\n" {
+		t.Errorf("a.txt must be untouched, got %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(root, "a.txt.bak")); !os.IsNotExist(err) {
+		t.Errorf("a.txt.bak must not be created, got %v", err)
+	}
+
+	patch = strings.Replace(patch, "-missing", "-b", 1)
+	result = tool.Execute(context.Background(), map[string]any{"patch": patch, "dry_run": true})
+	if result.IsError || !strings.Contains(result.ForLLM, "applies cleanly to 3 file(s)") {
+		t.Fatalf("unexpected dry run result: %s", result.ForLLM)
+	}
+	if got := readTreeFile(t, root, "a.txt"); got != "a\n" {
+		t.Errorf("dry run must not write, got %q", got)
+	}
+}
+
+func TestApplyPatchTool_Errors(t *testing.T) {
+	root := writeSearchTree(t, map[string]string{"ws/a.txt": "a\n", "outside.txt": "secret\n"})
+	tool := NewApplyPatchTool(filepath.Join(root, "ws"), true)
+
+	for name, patch := range map[string]string{
+		"empty":           "",
+		"no sections":     "just some text",
+		"unterminated":    "*** Begin Patch\n*** Update File: a.txt\n-a\n+b",
+		"add existing":    "*** Begin Patch\n*** Add File: a.txt\n+x\n*** End Patch",
+		"delete missing":  "*** Begin Patch\n*** Delete File: nope.txt\n*** End Patch",
+		"bad add line":    "*** Begin Patch\n*** Add File: b.txt\nx\n*** End Patch",
+		"outside sandbox": "*** Begin Patch\n*** Update File: ../outside.txt\n-secret\n+pwned\n*** End Patch",
+	} {
+		if result := tool.Execute(context.Background(), map[string]any{"patch": patch}); !result.IsError {
+			t.Errorf("%s: expected error, got %s", name, result.ForLLM)
+		}
+	}
+	if got := readTreeFile(t, root, "outside.txt"); got != "secret\n" {
+		t.Errorf("file outside the workspace was modified: %q", got)
+	}
+}
+
+func TestApplyPatchTool_MalformedUnifiedHunk(t *testing.T) {
+	original := "one\ntwo\nthree\nfour\nfive\n"
+	root := writeSearchTree(t, map[string]string{"a.txt": original})
+	tool := NewApplyPatchTool(root, true)
+
+	header := []string{"--- a/a.txt", "+++ b/a.txt"}
+	for name, body := range map[string][]string{
+		// "three" lost its leading space inside the hunk.
+		"short of counts": {"@@ -1,5 +1,5 @@", " one", "-two", "+TWO", "three", " four", " five"},
+		"before next hunk": {
+			"@@ -1,2 +1,2 @@", " one", "-two", "+TWO", "stray text",
+			"@@ -4,2 +4,2 @@", " four", "-five", "+FIVE",
+		},
+	} {
+		patch := strings.Join(append(append([]string{}, header...), body...), "\n")
+		result := tool.Execute(context.Background(), map[string]any{"patch": patch})
+		if !result.IsError || !strings.Contains(result.ForLLM, "unexpected line") {
+			t.Errorf("%s: expected a parse error, got %s", name, result.ForLLM)
+		}
+		if got := readTreeFile(t, root, "a.txt"); got != original {
+			t.Fatalf("%s: a.txt was modified: %q", name, got)
+		}
+	}
+
+	// Text after the last hunk, such as a git signature, is still ignored.
+	patch := strings.Join(append(header, "@@ -1,2 +1,2 @@", " one", "-two", "+TWO", "2.39.5"), "\n")
+	if result := tool.Execute(context.Background(), map[string]any{"patch": patch}); result.IsError {
+		t.Fatalf("trailing text rejected: %s", result.ForLLM)
+	}
+}
+
+func TestFindHunk_WhitespaceFallback(t *testing.T) {
+	lines := []string{"func f() {", "\treturn 1  ", "}"}
+	pos, err := findHunk(lines, patchHunk{old: []string{"    return 1"}}, 0, 0)
+	if err != nil || pos != 1 {
+		t.Errorf("expected whitespace-insensitive match at 1, got %d, %v", pos, err)
+	}
+
+	lines = []string{"x", "y", "x", "y", "x"}
+	pos, _ = findHunk(lines, patchHunk{oldStart: 5, old: []string{"x"}}, 0, 0)
+	if pos != 4 {
+		t.Errorf("expected the match closest to the line hint, got %d", pos)
+	}
+	pos, _ = findHunk(lines, patchHunk{old: []string{"x"}, atEOF: true}, 0, 0)
+	if pos != 4 {
+		t.Errorf("expected the match at end of file, got %d", pos)
+	}
+}
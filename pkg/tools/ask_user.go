@@ -0,0 +1,138 @@
+package tools
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+// maxAskUserOptions bounds the number of choices so prompts stay usable on
+// every channel (Discord allows 25 buttons per message).
+const maxAskUserOptions = 10
+
+// AskCallback delivers a question with its choices to a chat.
+type AskCallback func(channel, chatID, question string, interactive *bus.Interactive) error
+
+// AskUserTool asks the user a question with a fixed set of answers. Channels
+// that support buttons render the options as buttons; others show a numbered
+// list. The user's answer arrives as the next inbound message.
+type AskUserTool struct {
+	askCallback AskCallback
+	sentInRound atomic.Bool
+}
+
+func NewAskUserTool() *AskUserTool {
+	return &AskUserTool{}
+}
+
+func (t *AskUserTool) Name() string {
+	return "ask_user"
+}
+
+func (t *AskUserTool) Description() string {
+	return "Ask the user a question with a fixed set of options (shown as buttons where the channel supports it). " +
+		"Use this for confirmations or when the user must pick between alternatives. " +
+		"The answer arrives as the user's next message, so end your turn after calling this tool."
+}
+
+func (t *AskUserTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"question": map[string]any{
+				"type":        "string",
+				"description": "The question to ask",
+			},
+			"options": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": fmt.Sprintf("Answer options, 2 to %d short labels", maxAskUserOptions),
+			},
+		},
+		"required": []string{"question", "options"},
+	}
+}
+
+// ResetSentInRound resets the per-round send tracker.
+// Called by the agent loop at the start of each inbound message processing round.
+func (t *AskUserTool) ResetSentInRound() {
+	t.sentInRound.Store(false)
+}
+
+// HasSentInRound returns true if a question was sent during the current round.
+func (t *AskUserTool) HasSentInRound() bool {
+	return t.sentInRound.Load()
+}
+
+func (t *AskUserTool) SetAskCallback(callback AskCallback) {
+	t.askCallback = callback
+}
+
+func (t *AskUserTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	question, _ := args["question"].(string)
+	question = strings.TrimSpace(question)
+	if question == "" {
+		return ErrorResult("question is required")
+	}
+
+	rawOptions, ok := args["options"].([]any)
+	if !ok {
+		return ErrorResult("options must be an array of strings")
+	}
+	var choices []bus.InteractiveChoice
+	for _, raw := range rawOptions {
+		label, ok := raw.(string)
+		label = strings.TrimSpace(label)
+		if !ok || label == "" {
+			continue
+		}
+		choices = append(choices, bus.InteractiveChoice{
+			ID:    strconv.Itoa(len(choices) + 1),
+			Label: label,
+		})
+	}
+	if len(choices) < 2 || len(choices) > maxAskUserOptions {
+		return ErrorResult(fmt.Sprintf("options must contain 2 to %d non-empty labels", maxAskUserOptions))
+	}
+
+	channel := ToolChannel(ctx)
+	chatID := ToolChatID(ctx)
+	if channel == "" || chatID == "" {
+		return ErrorResult("No target channel/chat specified")
+	}
+
+	if t.askCallback == nil {
+		return ErrorResult("Asking the user is not configured")
+	}
+
+	interactive := &bus.Interactive{
+		ActionID: newActionID(),
+		Choices:  choices,
+	}
+	if err := t.askCallback(channel, chatID, question, interactive); err != nil {
+		return ErrorResult(fmt.Sprintf("sending question: %v", err)).WithError(err)
+	}
+
+	t.sentInRound.Store(true)
+	return &ToolResult{
+		ForLLM: "Question sent to the user. Their answer will arrive as the next message; " +
+			"end your turn now without repeating the question.",
+		Silent: true,
+	}
+}
+
+// newActionID returns a short random identifier correlating a prompt with
+// the button clicks it produces.
+func newActionID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "ask"
+	}
+	return hex.EncodeToString(b)
+}
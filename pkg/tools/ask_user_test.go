@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+func TestAskUserTool_Execute_Success(t *testing.T) {
+	tool := NewAskUserTool()
+
+	var sentChannel, sentChatID, sentQuestion string
+	var sentInteractive *bus.Interactive
+	tool.SetAskCallback(func(channel, chatID, question string, interactive *bus.Interactive) error {
+		sentChannel, sentChatID, sentQuestion, sentInteractive = channel, chatID, question, interactive
+		return nil
+	})
+
+	ctx := WithToolContext(context.Background(), "telegram", "42")
+	result := tool.Execute(ctx, map[string]any{
+		"question": "Deploy to production?",
+		"options":  []any{"Yes", " ", "No"},
+	})
+
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", result.ForLLM)
+	}
+	if !result.Silent {
+		t.Error("expected Silent=true, the user already received the question")
+	}
+	if sentChannel != "telegram" || sentChatID != "42" || sentQuestion != "Deploy to production?" {
+		t.Errorf("unexpected target: %s:%s %q", sentChannel, sentChatID, sentQuestion)
+	}
+	if sentInteractive == nil || sentInteractive.ActionID == "" {
+		t.Fatal("expected interactive payload with an action ID")
+	}
+	choices := sentInteractive.Choices
+	if len(choices) != 2 || choices[0].ID != "1" || choices[0].Label != "Yes" ||
+		choices[1].ID != "2" || choices[1].Label != "No" {
+		t.Errorf("unexpected choices: %+v", choices)
+	}
+	if !tool.HasSentInRound() {
+		t.Error("expected HasSentInRound after a question was sent")
+	}
+	tool.ResetSentInRound()
+	if tool.HasSentInRound() {
+		t.Error("expected ResetSentInRound to clear the tracker")
+	}
+}
+
+func TestAskUserTool_Execute_InvalidOptions(t *testing.T) {
+	tool := NewAskUserTool()
+	tool.SetAskCallback(func(string, string, string, *bus.Interactive) error { return nil })
+	ctx := WithToolContext(context.Background(), "telegram", "42")
+
+	tests := []map[string]any{
+		{"question": "", "options": []any{"a", "b"}},
+		{"question": "q", "options": "a,b"},
+		{"question": "q", "options": []any{"only"}},
+		{"question": "q", "options": []any{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11"}},
+	}
+	for _, args := range tests {
+		if result := tool.Execute(ctx, args); !result.IsError {
+			t.Errorf("expected error for args %v", args)
+		}
+	}
+	if tool.HasSentInRound() {
+		t.Error("invalid calls must not mark the round as sent")
+	}
+}
+
+func TestAskUserTool_Execute_SendFailure(t *testing.T) {
+	tool := NewAskUserTool()
+	sendErr := errors.New("network down")
+	tool.SetAskCallback(func(string, string, string, *bus.Interactive) error { return sendErr })
+
+	ctx := WithToolContext(context.Background(), "telegram", "42")
+	result := tool.Execute(ctx, map[string]any{"question": "q", "options": []any{"a", "b"}})
+
+	if !result.IsError || !errors.Is(result.Err, sendErr) {
+		t.Errorf("expected send error to propagate, got %+v", result)
+	}
+}
+
+func TestAskUserTool_Execute_NoContext(t *testing.T) {
+	tool := NewAskUserTool()
+	tool.SetAskCallback(func(string, string, string, *bus.Interactive) error { return nil })
+
+	result := tool.Execute(context.Background(), map[string]any{"question": "q", "options": []any{"a", "b"}})
+	if !result.IsError {
+		t.Error("expected error without channel context")
+	}
+}
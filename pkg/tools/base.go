@@ -21,8 +21,9 @@ type Tool interface {
 type toolCtxKey struct{ name string }
 
 var (
-	ctxKeyChannel = &toolCtxKey{"channel"}
-	ctxKeyChatID  = &toolCtxKey{"chatID"}
+	ctxKeyChannel    = &toolCtxKey{"channel"}
+	ctxKeyChatID     = &toolCtxKey{"chatID"}
+	ctxKeySessionKey = &toolCtxKey{"sessionKey"}
 )
 
 // WithToolContext returns a child context carrying channel and chatID.
@@ -44,6 +45,17 @@ func ToolChatID(ctx context.Context) string {
 	return v
 }
 
+// WithToolSessionKey returns a child context carrying the agent session key.
+func WithToolSessionKey(ctx context.Context, sessionKey string) context.Context {
+	return context.WithValue(ctx, ctxKeySessionKey, sessionKey)
+}
+
+// ToolSessionKey extracts the agent session key from ctx, or "" if unset.
+func ToolSessionKey(ctx context.Context) string {
+	v, _ := ctx.Value(ctxKeySessionKey).(string)
+	return v
+}
+
 // AsyncCallback is a function type that async tools use to notify completion.
 // When an async tool finishes its work, it calls this callback with the result.
 //
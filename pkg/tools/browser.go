@@ -0,0 +1,896 @@
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/egress"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/media"
+)
+
+const (
+	defaultBrowserTimeout     = 30 * time.Second
+	defaultBrowserIdleTimeout = 10 * time.Minute
+	defaultBrowserMaxChars    = 50000
+	// browserSettle is how long to wait after a click or submit for a
+	// navigation to start before reading the page again.
+	browserSettle = 300 * time.Millisecond
+	// maxScreenshotHeight caps full-page screenshots of endless pages.
+	maxScreenshotHeight = 10000
+)
+
+var (
+	browserCandidates = []string{
+		"chromium",
+		"chromium-browser",
+		"google-chrome",
+		"google-chrome-stable",
+		"chrome",
+		"microsoft-edge",
+	}
+	browserCandidatesDarwin = []string{
+		"/Applications/Chromium.app/Contents/MacOS/Chromium",
+		"/Applications/Google Chrome.app/Contents/MacOS/Google Chrome",
+	}
+	blankLinesRe = regexp.MustCompile(`\n\s*\n\s*\n+`)
+)
+
+// BrowserToolOptions configures NewBrowserTool. Zero values select defaults.
+type BrowserToolOptions struct {
+	ExecutablePath       string
+	Timeout              time.Duration
+	IdleTimeout          time.Duration
+	MaxChars             int
+	PrivateHostWhitelist []string
+}
+
+// BrowserTool drives a locally installed Chromium over the DevTools protocol
+// for pages that need JavaScript. The browser is started on first use and
+// every conversation gets its own tab. All browser traffic goes through a
+// local proxy enforcing the web_fetch SSRF rules.
+type BrowserTool struct {
+	executable  string
+	timeout     time.Duration
+	idleTimeout time.Duration
+	maxChars    int
+	whitelist   *privateHostWhitelist
+	mediaStore  media.MediaStore
+
+	mu      sync.Mutex
+	proc    *browserProcess
+	tabs    map[string]*browserTab // owner -> tab
+	reaping bool
+}
+
+type browserProcess struct {
+	cmd     *exec.Cmd
+	conn    *cdpConn
+	proxy   *browserProxy
+	dataDir string
+	done    chan struct{}
+}
+
+type browserTab struct {
+	targetID  string
+	sessionID string
+
+	mu       sync.Mutex // serializes actions on the tab
+	lastUsed time.Time
+}
+
+func NewBrowserTool(opts BrowserToolOptions) (*BrowserTool, error) {
+	whitelist, err := newPrivateHostWhitelist(opts.PrivateHostWhitelist)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse browser private host whitelist: %w", err)
+	}
+	t := &BrowserTool{
+		executable:  opts.ExecutablePath,
+		timeout:     opts.Timeout,
+		idleTimeout: opts.IdleTimeout,
+		maxChars:    opts.MaxChars,
+		whitelist:   whitelist,
+		tabs:        make(map[string]*browserTab),
+	}
+	if t.timeout <= 0 {
+		t.timeout = defaultBrowserTimeout
+	}
+	if t.idleTimeout <= 0 {
+		t.idleTimeout = defaultBrowserIdleTimeout
+	}
+	if t.maxChars <= 0 {
+		t.maxChars = defaultBrowserMaxChars
+	}
+	return t, nil
+}
+
+func (t *BrowserTool) Name() string {
+	return "browser"
+}
+
+// UntrustedOutput implements UntrustedSource. Page text comes from arbitrary websites.
+func (t *BrowserTool) UntrustedOutput() bool {
+	return true
+}
+
+func (t *BrowserTool) Description() string {
+	return "Control a headless Chromium browser for pages that need JavaScript. " +
+		"Actions: navigate to a URL, click or type into an element by CSS selector, wait for a selector to appear, " +
+		"read the page's readable text, take a screenshot you can look at, or close the tab. " +
+		"Each conversation has its own tab that keeps its state between calls. " +
+		"Private and local network addresses cannot be reached. Prefer web_fetch for static pages."
+}
+
+func (t *BrowserTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"enum":        []string{"navigate", "click", "type", "wait", "text", "screenshot", "close"},
+				"description": "Browser action to perform",
+			},
+			"url": map[string]any{
+				"type":        "string",
+				"description": "http(s) URL to open (navigate)",
+			},
+			"selector": map[string]any{
+				"type":        "string",
+				"description": "CSS selector of the target element (click, type, wait; optional for text to limit extraction)",
+			},
+			"text": map[string]any{
+				"type":        "string",
+				"description": "Text to type into the element (type)",
+			},
+			"clear": map[string]any{
+				"type":        "boolean",
+				"description": "Clear the field before typing (type, default false)",
+			},
+			"submit": map[string]any{
+				"type":        "boolean",
+				"description": "Press Enter after typing (type, default false)",
+			},
+			"timeout_ms": map[string]any{
+				"type":        "integer",
+				"description": "How long to wait for the selector (wait, default and maximum is the tool timeout)",
+			},
+			"full_page": map[string]any{
+				"type":        "boolean",
+				"description": "Capture the whole page instead of the viewport (screenshot, default false)",
+			},
+			"send": map[string]any{
+				"type":        "boolean",
+				"description": "Also send the screenshot to the user (screenshot, default false)",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *BrowserTool) SetMediaStore(store media.MediaStore) {
+	t.mediaStore = store
+}
+
+func (t *BrowserTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	action, _ := args["action"].(string)
+	selector, _ := args["selector"].(string)
+	owner := ToolSessionKey(ctx)
+	if owner == "" {
+		owner = ToolChannel(ctx) + ":" + ToolChatID(ctx)
+	}
+
+	switch action {
+	case "navigate", "click", "type", "wait", "text", "screenshot", "close":
+	default:
+		return ErrorResult("action must be one of navigate, click, type, wait, text, screenshot, close")
+	}
+	if action == "close" {
+		if !t.closeTab(owner) {
+			return NewToolResult("No browser tab is open.")
+		}
+		return NewToolResult("Browser tab closed.")
+	}
+	if (action == "click" || action == "type" || action == "wait") && strings.TrimSpace(selector) == "" {
+		return ErrorResult(fmt.Sprintf("selector is required for %s", action))
+	}
+
+	var target *url.URL
+	if action == "navigate" {
+		rawURL, _ := args["url"].(string)
+		var err error
+		if target, err = t.checkURL(rawURL); err != nil {
+			return ErrorResult(err.Error())
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	tab, err := t.tab(ctx, owner)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to start browser: %v", err)).WithError(err)
+	}
+	tab.mu.Lock()
+	defer tab.mu.Unlock()
+	defer tab.touch()
+
+	switch action {
+	case "navigate":
+		return t.navigate(ctx, tab, target)
+	case "click":
+		return t.click(ctx, tab, selector)
+	case "type":
+		text, _ := args["text"].(string)
+		clear, _ := args["clear"].(bool)
+		submit, _ := args["submit"].(bool)
+		return t.typeText(ctx, tab, selector, text, clear, submit)
+	case "wait":
+		wait := t.timeout
+		if ms, ok := args["timeout_ms"].(float64); ok && ms > 0 && time.Duration(ms)*time.Millisecond < wait {
+			wait = time.Duration(ms) * time.Millisecond
+		}
+		return t.waitFor(ctx, tab, selector, wait)
+	case "text":
+		return t.text(ctx, tab, selector)
+	default: // screenshot
+		fullPage, _ := args["full_page"].(bool)
+		send, _ := args["send"].(bool)
+		return t.screenshot(ctx, tab, fullPage, send)
+	}
+}
+
+// checkURL validates a navigation target. The check only rejects obvious
+// cases up front; the proxy enforces the rules for every connection the page
+// makes.
+func (t *BrowserTool) checkURL(rawURL string) (*url.URL, error) {
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, errors.New("url must be an absolute http or https URL")
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return nil, errors.New("only http and https URLs are allowed")
+	}
+	if parsed.Host == "" {
+		return nil, errors.New("url must be an absolute http or https URL")
+	}
+	if isObviousPrivateHost(parsed.Hostname(), t.whitelist) {
+		return nil, errors.New("navigating to private or local network hosts is not allowed")
+	}
+	return parsed, nil
+}
+
+func (t *BrowserTool) navigate(ctx context.Context, tab *browserTab, target *url.URL) *ToolResult {
+	if err := t.markDocument(ctx, tab); err != nil {
+		return ErrorResult(err.Error()).WithError(err)
+	}
+	var nav struct {
+		LoaderID  string `json:"loaderId"`
+		ErrorText string `json:"errorText"`
+	}
+	if err := t.call(ctx, tab, "Page.navigate", map[string]any{"url": target.String()}, &nav); err != nil {
+		return ErrorResult(fmt.Sprintf("navigation failed: %v", err)).WithError(err)
+	}
+	if nav.ErrorText != "" {
+		return ErrorResult(fmt.Sprintf(
+			"navigation failed: %s (private, local and unreachable hosts are blocked)", nav.ErrorText))
+	}
+	if nav.LoaderID != "" {
+		t.waitForNewDocument(ctx, tab, t.timeout)
+	}
+	return NewToolResult(t.summary(ctx, tab))
+}
+
+func (t *BrowserTool) click(ctx context.Context, tab *browserTab, selector string) *ToolResult {
+	var box *struct {
+		X, Y, W, H float64
+	}
+	expr := fmt.Sprintf(`(() => {
+		const el = document.querySelector(%s);
+		if (!el) return null;
+		el.scrollIntoView({block: "center", inline: "center"});
+		const r = el.getBoundingClientRect();
+		return {X: r.left + r.width / 2, Y: r.top + r.height / 2, W: r.width, H: r.height};
+	})()`, jsString(selector))
+	if err := t.eval(ctx, tab, expr, &box); err != nil {
+		return ErrorResult(fmt.Sprintf("click failed: %v", err)).WithError(err)
+	}
+	if box == nil {
+		return ErrorResult(fmt.Sprintf("no element matches selector %q", selector))
+	}
+	if box.W == 0 && box.H == 0 {
+		return ErrorResult(fmt.Sprintf("element %q is not visible", selector))
+	}
+
+	if err := t.markDocument(ctx, tab); err != nil {
+		return ErrorResult(err.Error()).WithError(err)
+	}
+	for _, typ := range []string{"mouseMoved", "mousePressed", "mouseReleased"} {
+		params := map[string]any{"type": typ, "x": box.X, "y": box.Y}
+		if typ != "mouseMoved" {
+			params["button"] = "left"
+			params["clickCount"] = 1
+		}
+		if err := t.call(ctx, tab, "Input.dispatchMouseEvent", params, nil); err != nil {
+			return ErrorResult(fmt.Sprintf("click failed: %v", err)).WithError(err)
+		}
+	}
+	t.settle(ctx, tab)
+	return NewToolResult(fmt.Sprintf("Clicked %s. %s", selector, t.summary(ctx, tab)))
+}
+
+func (t *BrowserTool) typeText(
+	ctx context.Context,
+	tab *browserTab,
+	selector, text string,
+	clear, submit bool,
+) *ToolResult {
+	var found bool
+	expr := fmt.Sprintf(`(() => {
+		const el = document.querySelector(%s);
+		if (!el) return false;
+		el.scrollIntoView({block: "center"});
+		el.focus();
+		if (%t) {
+			if ("value" in el) el.value = "";
+			else if (el.isContentEditable) el.textContent = "";
+		}
+		return true;
+	})()`, jsString(selector), clear)
+	if err := t.eval(ctx, tab, expr, &found); err != nil {
+		return ErrorResult(fmt.Sprintf("type failed: %v", err)).WithError(err)
+	}
+	if !found {
+		return ErrorResult(fmt.Sprintf("no element matches selector %q", selector))
+	}
+	if text != "" {
+		if err := t.call(ctx, tab, "Input.insertText", map[string]any{"text": text}, nil); err != nil {
+			return ErrorResult(fmt.Sprintf("type failed: %v", err)).WithError(err)
+		}
+	}
+	if !submit {
+		return NewToolResult(fmt.Sprintf("Typed %d characters into %s.", len([]rune(text)), selector))
+	}
+
+	if err := t.markDocument(ctx, tab); err != nil {
+		return ErrorResult(err.Error()).WithError(err)
+	}
+	for _, typ := range []string{"keyDown", "keyUp"} {
+		params := map[string]any{
+			"type":                  typ,
+			"key":                   "Enter",
+			"code":                  "Enter",
+			"windowsVirtualKeyCode": 13,
+			"nativeVirtualKeyCode":  13,
+		}
+		if typ == "keyDown" {
+			params["text"] = "\r"
+		}
+		if err := t.call(ctx, tab, "Input.dispatchKeyEvent", params, nil); err != nil {
+			return ErrorResult(fmt.Sprintf("submit failed: %v", err)).WithError(err)
+		}
+	}
+	t.settle(ctx, tab)
+	return NewToolResult(fmt.Sprintf("Typed %d characters into %s and pressed Enter. %s",
+		len([]rune(text)), selector, t.summary(ctx, tab)))
+}
+
+func (t *BrowserTool) waitFor(ctx context.Context, tab *browserTab, selector string, wait time.Duration) *ToolResult {
+	expr := fmt.Sprintf(`(() => {
+		const el = document.querySelector(%s);
+		if (!el) return false;
+		const r = el.getBoundingClientRect();
+		return r.width > 0 || r.height > 0;
+	})()`, jsString(selector))
+
+	deadline := time.Now().Add(wait)
+	for {
+		var visible bool
+		if err := t.eval(ctx, tab, expr, &visible); err != nil && !isTransientEvalError(err) {
+			return ErrorResult(fmt.Sprintf("wait failed: %v", err)).WithError(err)
+		}
+		if visible {
+			return NewToolResult(fmt.Sprintf("Element %s is visible.", selector))
+		}
+		if time.Now().After(deadline) {
+			return ErrorResult(fmt.Sprintf("timed out after %s waiting for %s", wait, selector))
+		}
+		select {
+		case <-ctx.Done():
+			return ErrorResult(fmt.Sprintf("timed out waiting for %s", selector))
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func (t *BrowserTool) text(ctx context.Context, tab *browserTab, selector string) *ToolResult {
+	var page *struct {
+		Title string `json:"title"`
+		URL   string `json:"url"`
+		Text  string `json:"text"`
+	}
+	root := `document.querySelector("main, article, [role=main]") || document.body`
+	if selector != "" {
+		root = fmt.Sprintf("document.querySelector(%s)", jsString(selector))
+	}
+	expr := fmt.Sprintf(`(() => {
+		const root = %s;
+		if (!root) return null;
+		return {title: document.title, url: location.href, text: root.innerText || ""};
+	})()`, root)
+	if err := t.eval(ctx, tab, expr, &page); err != nil {
+		return ErrorResult(fmt.Sprintf("text extraction failed: %v", err)).WithError(err)
+	}
+	if page == nil {
+		if selector != "" {
+			return ErrorResult(fmt.Sprintf("no element matches selector %q", selector))
+		}
+		return ErrorResult("page has no content; navigate first")
+	}
+
+	text := strings.TrimSpace(blankLinesRe.ReplaceAllString(page.Text, "\n\n"))
+	truncated := false
+	if runes := []rune(text); len(runes) > t.maxChars {
+		text = string(runes[:t.maxChars])
+		truncated = true
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Title: %s\nURL: %s\n\n%s", page.Title, page.URL, text)
+	if truncated {
+		fmt.Fprintf(&sb, "\n\n[truncated to %d characters]", t.maxChars)
+	}
+	return NewToolResult(sb.String())
+}
+
+func (t *BrowserTool) screenshot(ctx context.Context, tab *browserTab, fullPage, send bool) *ToolResult {
+	if t.mediaStore == nil {
+		return ErrorResult("media store not configured")
+	}
+
+	params := map[string]any{"format": "png"}
+	if fullPage {
+		var metrics struct {
+			CSSContentSize struct {
+				Width  float64 `json:"width"`
+				Height float64 `json:"height"`
+			} `json:"cssContentSize"`
+		}
+		if err := t.call(ctx, tab, "Page.getLayoutMetrics", nil, &metrics); err != nil {
+			return ErrorResult(fmt.Sprintf("screenshot failed: %v", err)).WithError(err)
+		}
+		height := min(metrics.CSSContentSize.Height, maxScreenshotHeight)
+		params["captureBeyondViewport"] = true
+		params["clip"] = map[string]any{
+			"x": 0, "y": 0, "width": metrics.CSSContentSize.Width, "height": height, "scale": 1,
+		}
+	}
+	var shot struct {
+		Data string `json:"data"`
+	}
+	if err := t.call(ctx, tab, "Page.captureScreenshot", params, &shot); err != nil {
+		return ErrorResult(fmt.Sprintf("screenshot failed: %v", err)).WithError(err)
+	}
+	data, err := base64.StdEncoding.DecodeString(shot.Data)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("screenshot failed: %v", err)).WithError(err)
+	}
+
+	if err := os.MkdirAll(media.TempDir(), 0o700); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to save screenshot: %v", err)).WithError(err)
+	}
+	f, err := os.CreateTemp(media.TempDir(), "browser-*.png")
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to save screenshot: %v", err)).WithError(err)
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return ErrorResult(fmt.Sprintf("failed to save screenshot: %v", err)).WithError(err)
+	}
+
+	scope := fmt.Sprintf("tool:browser:%s:%s", ToolChannel(ctx), ToolChatID(ctx))
+	ref, err := t.mediaStore.Store(f.Name(), media.MediaMeta{
+		Filename:    filepath.Base(f.Name()),
+		ContentType: "image/png",
+		Source:      "tool:browser",
+	}, scope)
+	if err != nil {
+		os.Remove(f.Name())
+		return ErrorResult(fmt.Sprintf("failed to register screenshot: %v", err)).WithError(err)
+	}
+
+	result := NewToolResult(fmt.Sprintf("Screenshot captured and attached for you to view. %s", t.summary(ctx, tab)))
+	result.LLMMedia = []string{ref}
+	if send {
+		result.Media = []string{ref}
+	}
+	return result
+}
+
+// summary describes the page currently shown in tab.
+func (t *BrowserTool) summary(ctx context.Context, tab *browserTab) string {
+	var page struct {
+		Title string `json:"title"`
+		URL   string `json:"url"`
+	}
+	if err := t.eval(ctx, tab, `({title: document.title, url: location.href})`, &page); err != nil {
+		return "The page is still loading."
+	}
+	if page.Title == "" {
+		return fmt.Sprintf("Current page: %s", page.URL)
+	}
+	return fmt.Sprintf("Current page: %q (%s)", page.Title, page.URL)
+}
+
+// markDocument tags the current document so waitForNewDocument can tell
+// when a navigation has replaced it.
+func (t *BrowserTool) markDocument(ctx context.Context, tab *browserTab) error {
+	return t.eval(ctx, tab, `window.__picoclawDocument = true`, nil)
+}
+
+// settle gives a click or submit a moment to start a navigation and, if one
+// started, waits for the new document to load.
+func (t *BrowserTool) settle(ctx context.Context, tab *browserTab) {
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(browserSettle):
+	}
+	var same bool
+	if err := t.eval(ctx, tab, `window.__picoclawDocument === true`, &same); err == nil && same {
+		return
+	}
+	t.waitForNewDocument(ctx, tab, t.timeout)
+}
+
+// waitForNewDocument polls until the marked document has been replaced and
+// the new one finished loading. Pages that never finish loading are left as
+// they are once wait elapses; the agent can still inspect them.
+func (t *BrowserTool) waitForNewDocument(ctx context.Context, tab *browserTab, wait time.Duration) {
+	deadline := time.Now().Add(wait)
+	for time.Now().Before(deadline) {
+		var state struct {
+			Marked bool   `json:"marked"`
+			Ready  string `json:"ready"`
+		}
+		err := t.eval(ctx, tab, `({marked: window.__picoclawDocument === true, ready: document.readyState})`, &state)
+		if err == nil && !state.Marked && state.Ready == "complete" {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func (t *BrowserTool) eval(ctx context.Context, tab *browserTab, expr string, out any) error {
+	var res struct {
+		Result struct {
+			Value json.RawMessage `json:"value"`
+		} `json:"result"`
+		ExceptionDetails *struct {
+			Text      string `json:"text"`
+			Exception *struct {
+				Description string `json:"description"`
+			} `json:"exception"`
+		} `json:"exceptionDetails"`
+	}
+	params := map[string]any{
+		"expression":    expr,
+		"returnByValue": true,
+		"awaitPromise":  true,
+	}
+	if err := t.call(ctx, tab, "Runtime.evaluate", params, &res); err != nil {
+		return err
+	}
+	if ex := res.ExceptionDetails; ex != nil {
+		if ex.Exception != nil && ex.Exception.Description != "" {
+			return errors.New(ex.Exception.Description)
+		}
+		return errors.New(ex.Text)
+	}
+	if out != nil && len(res.Result.Value) > 0 {
+		return json.Unmarshal(res.Result.Value, out)
+	}
+	return nil
+}
+
+func (t *BrowserTool) call(ctx context.Context, tab *browserTab, method string, params, out any) error {
+	t.mu.Lock()
+	proc := t.proc
+	t.mu.Unlock()
+	if proc == nil {
+		return errCDPClosed
+	}
+	return proc.conn.call(ctx, tab.sessionID, method, params, out)
+}
+
+// isTransientEvalError reports errors caused by evaluating while the page
+// is navigating, which polling loops retry.
+func isTransientEvalError(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "context was destroyed") || strings.Contains(msg, "Cannot find context")
+}
+
+// tab returns the owner's tab, launching the browser if needed.
+func (t *BrowserTool) tab(ctx context.Context, owner string) (*browserTab, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.proc != nil {
+		select {
+		case <-t.proc.done:
+			logger.WarnCF("tool", "Browser exited; restarting", nil)
+			t.proc.shutdown()
+			t.proc = nil
+			t.tabs = make(map[string]*browserTab)
+		default:
+		}
+	}
+	if tab := t.tabs[owner]; tab != nil {
+		return tab, nil
+	}
+	if t.proc == nil {
+		proc, err := launchBrowser(ctx, t.executable, t.whitelist)
+		if err != nil {
+			return nil, err
+		}
+		t.proc = proc
+	}
+
+	var target struct {
+		TargetID string `json:"targetId"`
+	}
+	if err := t.proc.conn.call(ctx, "", "Target.createTarget", map[string]any{"url": "about:blank"}, &target); err != nil {
+		return nil, err
+	}
+	var attached struct {
+		SessionID string `json:"sessionId"`
+	}
+	params := map[string]any{"targetId": target.TargetID, "flatten": true}
+	if err := t.proc.conn.call(ctx, "", "Target.attachToTarget", params, &attached); err != nil {
+		return nil, err
+	}
+
+	tab := &browserTab{targetID: target.TargetID, sessionID: attached.SessionID, lastUsed: time.Now()}
+	t.tabs[owner] = tab
+	if !t.reaping {
+		t.reaping = true
+		go t.reap()
+	}
+	return tab, nil
+}
+
+func (t *BrowserTool) closeTab(owner string) bool {
+	t.mu.Lock()
+	tab := t.tabs[owner]
+	delete(t.tabs, owner)
+	proc := t.proc
+	t.mu.Unlock()
+	if tab == nil || proc == nil {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	proc.conn.call(ctx, "", "Target.closeTarget", map[string]any{"targetId": tab.targetID}, nil)
+	return true
+}
+
+// reap closes idle tabs and shuts the browser down once none are left.
+func (t *BrowserTool) reap() {
+	interval := min(t.idleTimeout/4, time.Minute)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		var idle []string
+		t.mu.Lock()
+		for owner, tab := range t.tabs {
+			if tab.idleFor(t.idleTimeout) {
+				idle = append(idle, owner)
+			}
+		}
+		t.mu.Unlock()
+
+		for _, owner := range idle {
+			t.closeTab(owner)
+		}
+
+		t.mu.Lock()
+		if len(t.tabs) > 0 {
+			t.mu.Unlock()
+			continue
+		}
+		proc := t.proc
+		t.proc = nil
+		t.reaping = false
+		t.mu.Unlock()
+
+		if proc != nil {
+			logger.InfoCF("tool", "Browser closed after idle timeout", nil)
+			proc.shutdown()
+		}
+		return
+	}
+}
+
+// Close shuts the browser down.
+func (t *BrowserTool) Close() {
+	t.mu.Lock()
+	proc := t.proc
+	t.proc = nil
+	t.tabs = make(map[string]*browserTab)
+	t.mu.Unlock()
+	if proc != nil {
+		proc.shutdown()
+	}
+}
+
+func (tab *browserTab) touch() {
+	tab.lastUsed = time.Now()
+}
+
+func (tab *browserTab) idleFor(d time.Duration) bool {
+	if !tab.mu.TryLock() {
+		return false // an action is in progress
+	}
+	defer tab.mu.Unlock()
+	return time.Since(tab.lastUsed) > d
+}
+
+// findBrowserExecutable resolves the configured browser or looks for a
+// Chromium-based browser on PATH.
+func findBrowserExecutable(configured string) (string, error) {
+	if configured != "" {
+		return exec.LookPath(configured)
+	}
+	for _, name := range browserCandidates {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+	if runtime.GOOS == "darwin" {
+		for _, path := range browserCandidatesDarwin {
+			if _, err := os.Stat(path); err == nil {
+				return path, nil
+			}
+		}
+	}
+	return "", errors.New("no Chromium-based browser found; install chromium or set tools.browser.executable_path")
+}
+
+func launchBrowser(ctx context.Context, executable string, whitelist *privateHostWhitelist) (*browserProcess, error) {
+	exe, err := findBrowserExecutable(executable)
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := newBrowserProxy(egress.AgentFrom(ctx), whitelist)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start browser proxy: %w", err)
+	}
+	dataDir, err := os.MkdirTemp("", "picoclaw-browser-")
+	if err != nil {
+		proxy.Close()
+		return nil, err
+	}
+	proc := &browserProcess{proxy: proxy, dataDir: dataDir, done: make(chan struct{})}
+
+	cmdRead, cmdWrite, err := os.Pipe()
+	if err != nil {
+		proc.shutdown()
+		return nil, err
+	}
+	respRead, respWrite, err := os.Pipe()
+	if err != nil {
+		cmdRead.Close()
+		cmdWrite.Close()
+		proc.shutdown()
+		return nil, err
+	}
+
+	args := []string{
+		"--headless=new",
+		"--remote-debugging-pipe",
+		"--user-data-dir=" + dataDir,
+		"--proxy-server=" + proxy.URL(),
+		// Chromium bypasses proxies for loopback by default; route it
+		// through the proxy so it is checked too.
+		"--proxy-bypass-list=<-loopback>",
+		"--force-webrtc-ip-handling-policy=disable_non_proxied_udp",
+		"--no-first-run",
+		"--no-default-browser-check",
+		"--disable-background-networking",
+		"--disable-component-update",
+		"--disable-default-apps",
+		"--disable-extensions",
+		"--disable-sync",
+		"--disable-gpu",
+		"--mute-audio",
+		"--hide-scrollbars",
+		"--window-size=1280,800",
+	}
+	if browserNeedsNoSandbox() {
+		args = append(args, "--no-sandbox")
+	}
+	args = append(args, "about:blank")
+
+	cmd := exec.Command(exe, args...)
+	prepareCommandForTermination(cmd)
+	if err := attachDebugPipe(cmd, cmdRead, respWrite); err != nil {
+		cmdRead.Close()
+		cmdWrite.Close()
+		respRead.Close()
+		respWrite.Close()
+		proc.shutdown()
+		return nil, err
+	}
+	err = cmd.Start()
+	cmdRead.Close()
+	respWrite.Close()
+	if err != nil {
+		cmdWrite.Close()
+		respRead.Close()
+		proc.shutdown()
+		return nil, fmt.Errorf("failed to start %s: %w", exe, err)
+	}
+	proc.cmd = cmd
+	proc.conn = newCDPConn(respRead, cmdWrite)
+	go func() {
+		cmd.Wait()
+		respRead.Close()
+		close(proc.done)
+	}()
+
+	var version struct {
+		Product string `json:"product"`
+	}
+	if err := proc.conn.call(ctx, "", "Browser.getVersion", nil, &version); err != nil {
+		proc.shutdown()
+		return nil, fmt.Errorf("browser did not respond: %w", err)
+	}
+	logger.InfoCF("tool", "Browser started", map[string]any{
+		"executable": exe,
+		"version":    version.Product,
+	})
+	return proc, nil
+}
+
+func (p *browserProcess) shutdown() {
+	if p.cmd != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		p.conn.call(ctx, "", "Browser.close", nil, nil)
+		cancel()
+		select {
+		case <-p.done:
+		case <-time.After(3 * time.Second):
+		}
+		terminateProcessTree(p.cmd)
+		<-p.done
+		p.conn.close()
+	}
+	p.proxy.Close()
+	os.RemoveAll(p.dataDir)
+}
+
+// jsString quotes s as a JavaScript string literal.
+func jsString(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
@@ -0,0 +1,144 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// cdpConn speaks the Chrome DevTools Protocol over the pipe transport
+// enabled by --remote-debugging-pipe: requests and responses are JSON
+// messages terminated by a NUL byte. Events are read and discarded; callers
+// poll page state instead of subscribing.
+type cdpConn struct {
+	w      io.WriteCloser
+	wmu    sync.Mutex
+	nextID atomic.Int64
+
+	mu      sync.Mutex
+	pending map[int64]chan cdpResponse
+	closed  chan struct{}
+	err     error
+}
+
+type cdpRequest struct {
+	ID        int64  `json:"id"`
+	SessionID string `json:"sessionId,omitempty"`
+	Method    string `json:"method"`
+	Params    any    `json:"params,omitempty"`
+}
+
+type cdpResponse struct {
+	ID     int64           `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *cdpError       `json:"error"`
+}
+
+type cdpError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *cdpError) Error() string {
+	return fmt.Sprintf("cdp error %d: %s", e.Code, e.Message)
+}
+
+var errCDPClosed = errors.New("browser connection closed")
+
+func newCDPConn(r io.Reader, w io.WriteCloser) *cdpConn {
+	c := &cdpConn{
+		w:       w,
+		pending: make(map[int64]chan cdpResponse),
+		closed:  make(chan struct{}),
+	}
+	go c.readLoop(r)
+	return c
+}
+
+func (c *cdpConn) readLoop(r io.Reader) {
+	br := bufio.NewReaderSize(r, 64*1024)
+	var err error
+	for {
+		var msg []byte
+		msg, err = br.ReadBytes(0)
+		if err != nil {
+			break
+		}
+		msg = bytes.TrimSuffix(msg, []byte{0})
+
+		var resp cdpResponse
+		if json.Unmarshal(msg, &resp) != nil || resp.ID == 0 {
+			continue // event or malformed message
+		}
+		c.mu.Lock()
+		ch := c.pending[resp.ID]
+		delete(c.pending, resp.ID)
+		c.mu.Unlock()
+		if ch != nil {
+			ch <- resp
+		}
+	}
+
+	c.mu.Lock()
+	c.err = err
+	if errors.Is(err, io.EOF) {
+		c.err = errCDPClosed
+	}
+	c.mu.Unlock()
+	close(c.closed)
+}
+
+// call sends method to the browser (or to the attached target identified by
+// sessionID) and decodes the result into out when out is non-nil.
+func (c *cdpConn) call(ctx context.Context, sessionID, method string, params, out any) error {
+	id := c.nextID.Add(1)
+	data, err := json.Marshal(cdpRequest{ID: id, SessionID: sessionID, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+
+	ch := make(chan cdpResponse, 1)
+	c.mu.Lock()
+	c.pending[id] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	c.wmu.Lock()
+	_, err = c.w.Write(append(data, 0))
+	c.wmu.Unlock()
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return fmt.Errorf("%s: %w", method, resp.Error)
+		}
+		if out != nil && len(resp.Result) > 0 {
+			return json.Unmarshal(resp.Result, out)
+		}
+		return nil
+	case <-c.closed:
+		c.mu.Lock()
+		err := c.err
+		c.mu.Unlock()
+		return fmt.Errorf("%s: %w", method, err)
+	case <-ctx.Done():
+		return fmt.Errorf("%s: %w", method, ctx.Err())
+	}
+}
+
+func (c *cdpConn) close() error {
+	return c.w.Close()
+}
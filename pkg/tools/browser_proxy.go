@@ -0,0 +1,135 @@
+package tools
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/egress"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// browserProxy is a loopback HTTP proxy that all Chromium traffic is routed
+// through. Every upstream connection, including redirects, subresources and
+// requests made by page scripts, is dialed with newSafeDialContext and the
+// egress policy so the browser obeys the same rules as web_fetch.
+type browserProxy struct {
+	agentID  string
+	listener net.Listener
+	server   *http.Server
+	dial     func(context.Context, string, string) (net.Conn, error)
+	reverse  *httputil.ReverseProxy
+}
+
+// newBrowserProxy starts the proxy; agentID selects the egress rules.
+func newBrowserProxy(agentID string, whitelist *privateHostWhitelist) (*browserProxy, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   15 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	p := &browserProxy{
+		agentID:  agentID,
+		listener: ln,
+		dial:     egress.DialContext(newSafeDialContext(dialer, whitelist)),
+	}
+	p.reverse = &httputil.ReverseProxy{
+		// Proxy requests carry an absolute URL already; forward it unchanged.
+		Rewrite: func(*httputil.ProxyRequest) {},
+		Transport: &http.Transport{
+			DialContext:           p.dial,
+			MaxIdleConns:          20,
+			IdleConnTimeout:       90 * time.Second,
+			ResponseHeaderTimeout: 30 * time.Second,
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			browserProxyError(w, r.Host, err)
+		},
+	}
+	p.server = &http.Server{
+		Handler:           p,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go p.server.Serve(ln)
+	return p, nil
+}
+
+// URL returns the value for Chromium's --proxy-server flag.
+func (p *browserProxy) URL() string {
+	return "http://" + p.listener.Addr().String()
+}
+
+func (p *browserProxy) Close() error {
+	return p.server.Close()
+}
+
+func (p *browserProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r = r.WithContext(egress.WithAgent(r.Context(), p.agentID))
+	if r.Method == http.MethodConnect {
+		p.tunnel(w, r)
+		return
+	}
+	if r.URL.Scheme != "http" || r.URL.Host == "" {
+		http.Error(w, "only absolute http URLs can be proxied", http.StatusBadRequest)
+		return
+	}
+	p.reverse.ServeHTTP(w, r)
+}
+
+func (p *browserProxy) tunnel(w http.ResponseWriter, r *http.Request) {
+	upstream, err := p.dial(r.Context(), "tcp", r.Host)
+	if err != nil {
+		browserProxyError(w, r.Host, err)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		upstream.Close()
+		http.Error(w, "tunneling not supported", http.StatusInternalServerError)
+		return
+	}
+	client, buf, err := hijacker.Hijack()
+	if err != nil {
+		upstream.Close()
+		return
+	}
+	if _, err := client.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		client.Close()
+		upstream.Close()
+		return
+	}
+
+	var once sync.Once
+	closeBoth := func() {
+		client.Close()
+		upstream.Close()
+	}
+	go func() {
+		// Flush anything the client sent along with the CONNECT request.
+		if n := buf.Reader.Buffered(); n > 0 {
+			data, _ := buf.Reader.Peek(n)
+			upstream.Write(data)
+		}
+		io.Copy(upstream, client)
+		once.Do(closeBoth)
+	}()
+	io.Copy(client, upstream)
+	once.Do(closeBoth)
+}
+
+func browserProxyError(w http.ResponseWriter, host string, err error) {
+	logger.DebugCF("tool", "Browser request rejected", map[string]any{
+		"host":  host,
+		"error": err.Error(),
+	})
+	http.Error(w, err.Error(), http.StatusBadGateway)
+}
@@ -0,0 +1,234 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/media"
+)
+
+func newTestBrowserProxy(t *testing.T, whitelist []string) *http.Client {
+	t.Helper()
+	wl, err := newPrivateHostWhitelist(whitelist)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy, err := newBrowserProxy("", wl)
+	if err != nil {
+		t.Fatalf("newBrowserProxy: %v", err)
+	}
+	t.Cleanup(func() { proxy.Close() })
+
+	proxyURL, _ := url.Parse(proxy.URL())
+	return &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			Proxy: http.ProxyURL(proxyURL),
+		},
+	}
+}
+
+func TestBrowserProxy_BlocksPrivateTargets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "secret")
+	}))
+	defer server.Close()
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "secret")
+	}))
+	defer tlsServer.Close()
+
+	client := newTestBrowserProxy(t, nil)
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("GET through proxy: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway || strings.Contains(string(body), "secret") {
+		t.Errorf("expected loopback target to be blocked, got %d %q", resp.StatusCode, body)
+	}
+
+	client.Transport.(*http.Transport).TLSClientConfig = tlsServer.Client().Transport.(*http.Transport).TLSClientConfig
+	if resp, err := client.Get(tlsServer.URL); err == nil {
+		resp.Body.Close()
+		t.Error("expected CONNECT to a loopback target to be refused")
+	}
+}
+
+func TestBrowserProxy_AllowsWhitelistedTargets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "plain")
+	}))
+	defer server.Close()
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "tunneled")
+	}))
+	defer tlsServer.Close()
+
+	client := newTestBrowserProxy(t, []string{"127.0.0.0/8"})
+	client.Transport.(*http.Transport).TLSClientConfig = tlsServer.Client().Transport.(*http.Transport).TLSClientConfig
+
+	for target, want := range map[string]string{server.URL: "plain", tlsServer.URL: "tunneled"} {
+		resp, err := client.Get(target)
+		if err != nil {
+			t.Fatalf("GET %s: %v", target, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != want {
+			t.Errorf("GET %s = %q, want %q", target, body, want)
+		}
+	}
+}
+
+func TestCDPConn_Call(t *testing.T) {
+	toBrowser, commands := io.Pipe()
+	responses, fromBrowser := io.Pipe()
+	conn := newCDPConn(responses, commands)
+	defer conn.close()
+
+	// Fake browser: emit an event before every response and fail one method.
+	go func() {
+		r := bufio.NewReader(toBrowser)
+		for {
+			msg, err := r.ReadBytes(0)
+			if err != nil {
+				fromBrowser.Close()
+				return
+			}
+			var req cdpRequest
+			json.Unmarshal(bytes.TrimSuffix(msg, []byte{0}), &req)
+			fromBrowser.Write([]byte(`{"method":"Page.loadEventFired","params":{}}` + "\x00"))
+			var resp string
+			if req.Method == "Fail.method" {
+				resp = fmt.Sprintf(`{"id":%d,"error":{"code":-32000,"message":"nope"}}`, req.ID)
+			} else {
+				resp = fmt.Sprintf(`{"id":%d,"result":{"echo":%q,"session":%q}}`, req.ID, req.Method, req.SessionID)
+			}
+			fromBrowser.Write([]byte(resp + "\x00"))
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var out struct {
+		Echo    string `json:"echo"`
+		Session string `json:"session"`
+	}
+	if err := conn.call(ctx, "S1", "Page.navigate", nil, &out); err != nil {
+		t.Fatalf("call: %v", err)
+	}
+	if out.Echo != "Page.navigate" || out.Session != "S1" {
+		t.Errorf("unexpected result %+v", out)
+	}
+
+	var cdpErr *cdpError
+	if err := conn.call(ctx, "", "Fail.method", nil, nil); !errors.As(err, &cdpErr) || cdpErr.Message != "nope" {
+		t.Errorf("expected protocol error, got %v", err)
+	}
+
+	toBrowser.Close()
+	if err := conn.call(ctx, "", "Browser.getVersion", nil, nil); err == nil {
+		t.Error("expected call on a closed connection to fail")
+	}
+}
+
+func TestBrowserTool_RejectsBeforeLaunch(t *testing.T) {
+	tool, err := NewBrowserTool(BrowserToolOptions{ExecutablePath: "/nonexistent/chromium"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		args map[string]any
+		want string
+	}{
+		{map[string]any{"action": "navigate", "url": "file:///etc/passwd"}, "only http and https"},
+		{map[string]any{"action": "navigate", "url": "http://127.0.0.1:8080/"}, "private or local"},
+		{map[string]any{"action": "navigate", "url": "http://localhost/"}, "private or local"},
+		{map[string]any{"action": "navigate", "url": "http://169.254.169.254/latest/meta-data"}, "private or local"},
+		{map[string]any{"action": "click"}, "selector is required"},
+		{map[string]any{"action": "scroll"}, "action must be one of"},
+	} {
+		result := tool.Execute(ctx, tc.args)
+		if !result.IsError || !strings.Contains(result.ForLLM, tc.want) {
+			t.Errorf("Execute(%v) = %q, want error containing %q", tc.args, result.ForLLM, tc.want)
+		}
+	}
+	if tool.proc != nil {
+		t.Error("browser should not have been launched")
+	}
+
+	result := tool.Execute(ctx, map[string]any{"action": "navigate", "url": "https://example.com"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "failed to start browser") {
+		t.Errorf("expected a missing browser to be reported, got %q", result.ForLLM)
+	}
+}
+
+func TestBrowserTool_Chromium(t *testing.T) {
+	if _, err := findBrowserExecutable(""); err != nil {
+		t.Skip("no Chromium-based browser installed")
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if q := r.URL.Query().Get("q"); q != "" {
+			fmt.Fprintf(w, "<html><head><title>Results</title></head><body><main>You searched for %s</main></body></html>", q)
+			return
+		}
+		fmt.Fprint(w, `<html><head><title>Search</title></head><body>
+			<form action="/"><input id="q" name="q"></form>
+			<script>setTimeout(() => {
+				const p = document.createElement("p");
+				p.id = "late";
+				p.textContent = "rendered by script";
+				document.body.appendChild(p);
+			}, 200);</script>
+		</body></html>`)
+	}))
+	defer server.Close()
+
+	tool, err := NewBrowserTool(BrowserToolOptions{PrivateHostWhitelist: []string{"127.0.0.0/8"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tool.Close()
+	tool.SetMediaStore(media.NewFileMediaStore())
+	ctx := WithToolContext(context.Background(), "cli", "direct")
+
+	steps := []struct {
+		args map[string]any
+		want string
+	}{
+		{map[string]any{"action": "navigate", "url": server.URL}, `"Search"`},
+		{map[string]any{"action": "wait", "selector": "#late"}, "visible"},
+		{map[string]any{"action": "text"}, "rendered by script"},
+		{map[string]any{"action": "type", "selector": "#q", "text": "picoclaw", "submit": true}, `"Results"`},
+		{map[string]any{"action": "text"}, "You searched for picoclaw"},
+	}
+	for _, step := range steps {
+		result := tool.Execute(ctx, step.args)
+		if result.IsError || !strings.Contains(result.ForLLM, step.want) {
+			t.Fatalf("Execute(%v) = %q, want %q", step.args, result.ForLLM, step.want)
+		}
+	}
+
+	result := tool.Execute(ctx, map[string]any{"action": "screenshot"})
+	if result.IsError || len(result.LLMMedia) != 1 || len(result.Media) != 0 {
+		t.Fatalf("screenshot = %+v", result)
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !windows
+
+package tools
+
+import (
+	"os"
+	"os/exec"
+)
+
+// attachDebugPipe passes the CDP pipe to Chromium as fds 3 (commands) and
+// 4 (responses), as expected by --remote-debugging-pipe.
+func attachDebugPipe(cmd *exec.Cmd, commands, responses *os.File) error {
+	cmd.ExtraFiles = []*os.File{commands, responses}
+	return nil
+}
+
+// browserNeedsNoSandbox reports whether Chromium must run without its own
+// sandbox, which it refuses to start with as root.
+func browserNeedsNoSandbox() bool {
+	return os.Geteuid() == 0
+}
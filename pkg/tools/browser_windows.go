@@ -0,0 +1,17 @@
+//go:build windows
+
+package tools
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+func attachDebugPipe(cmd *exec.Cmd, commands, responses *os.File) error {
+	return errors.New("the browser tool is not supported on Windows")
+}
+
+func browserNeedsNoSandbox() bool {
+	return false
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/constants"
 	"github.com/sipeed/picoclaw/pkg/cron"
+	"github.com/sipeed/picoclaw/pkg/permissions"
 	"github.com/sipeed/picoclaw/pkg/utils"
 )
 
@@ -26,6 +27,7 @@ type CronTool struct {
 	execTool     *ExecTool
 	allowCommand bool
 	execEnabled  bool
+	roleFor      func(canonicalID string) *permissions.Role
 }
 
 // NewCronTool creates a new CronTool
@@ -63,6 +65,12 @@ func NewCronTool(
 	}, nil
 }
 
+// SetRoleResolver sets how the role of a job's creator is looked up when the
+// job runs through the agent. Without it, jobs run unrestricted.
+func (t *CronTool) SetRoleResolver(roleFor func(canonicalID string) *permissions.Role) {
+	t.roleFor = roleFor
+}
+
 // Name returns the tool name
 func (t *CronTool) Name() string {
 	return "cron"
@@ -230,8 +238,9 @@ func (t *CronTool) addJob(ctx context.Context, args map[string]any) *ToolResult
 		return ErrorResult(fmt.Sprintf("Error adding job: %v", err))
 	}
 
-	if command != "" {
+	if creator := permissions.SenderFrom(ctx); command != "" || creator != "" {
 		job.Payload.Command = command
+		job.Payload.CreatedBy = creator
 		// Need to save the updated payload
 		t.cronService.UpdateJob(job)
 	}
@@ -359,7 +368,13 @@ func (t *CronTool) ExecuteJob(ctx context.Context, job *cron.CronJob) string {
 		return "ok"
 	}
 
-	// For deliver=false, process through agent (for complex tasks)
+	// For deliver=false, process through agent (for complex tasks) with the
+	// creator's role, so a job cannot use tools its creator may not. Jobs
+	// from remote channels that predate CreatedBy get the default role.
+	if t.roleFor != nil && (job.Payload.CreatedBy != "" || !constants.IsInternalChannel(channel)) {
+		ctx = permissions.WithSender(ctx, job.Payload.CreatedBy)
+		ctx = permissions.WithRole(ctx, t.roleFor(job.Payload.CreatedBy))
+	}
 	sessionKey := fmt.Sprintf("cron-%s", job.ID)
 
 	// Call agent with job's message
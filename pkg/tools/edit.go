@@ -69,6 +69,10 @@ func (t *EditFileTool) Execute(ctx context.Context, args map[string]any) *ToolRe
 		return ErrorResult("new_text is required")
 	}
 
+	if result := rejectRedacted("new_text", newText, oldText); result != nil {
+		return result
+	}
+
 	if err := editFile(t.fs, path, oldText, newText); err != nil {
 		return ErrorResult(err.Error())
 	}
@@ -123,6 +127,11 @@ func (t *AppendFileTool) Execute(ctx context.Context, args map[string]any) *Tool
 		return ErrorResult("content is required")
 	}
 
+	existing, _ := t.fs.ReadFile(path)
+	if result := rejectRedacted("content", content, string(existing)); result != nil {
+		return result
+	}
+
 	if err := appendFile(t.fs, path, content); err != nil {
 		return ErrorResult(err.Error())
 	}
@@ -0,0 +1,598 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	defaultGlobMaxResults = 200
+	maxGlobMaxResults     = 1000
+	defaultGrepMaxResults = 100
+	maxGrepMaxResults     = 1000
+	maxGrepContext        = 10
+	maxGrepFileSize       = 4 << 20
+	maxGrepLineLength     = 300
+	// maxSearchEntries bounds how many directory entries one search visits.
+	maxSearchEntries = 200000
+)
+
+var errSearchLimit = errors.New("search stopped: too many files, narrow the path")
+
+// fileSearcher walks directory trees through a fileSystem, so glob and grep
+// are subject to the same workspace restriction and allow paths as read_file.
+type fileSearcher struct {
+	fs        fileSystem
+	workspace string
+}
+
+func newFileSearcher(workspace string, restrict bool, allowPaths [][]*regexp.Regexp) fileSearcher {
+	var patterns []*regexp.Regexp
+	if len(allowPaths) > 0 {
+		patterns = allowPaths[0]
+	}
+	return fileSearcher{fs: buildFs(workspace, restrict, patterns), workspace: workspace}
+}
+
+// resolve makes path absolute, relative paths being relative to the
+// workspace whichever fileSystem is in use.
+func (s fileSearcher) resolve(path string) string {
+	if path == "" {
+		path = "."
+	}
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(s.workspace, path)
+}
+
+// display returns path relative to the workspace when it is inside it.
+func (s fileSearcher) display(path string) string {
+	if rel, err := filepath.Rel(s.workspace, path); err == nil && filepath.IsLocal(rel) {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+// walk calls fn for every regular file below root in lexical order. .git
+// directories are always skipped, and with useIgnore so is everything matched
+// by .gitignore files from the workspace down. Symbolic links are not
+// followed.
+func (s fileSearcher) walk(ctx context.Context, root string, useIgnore bool, fn func(path string) error) error {
+	var rules []ignoreRule
+	if useIgnore {
+		rules = s.parentIgnoreRules(root)
+	}
+	visited := 0
+	var visit func(dir string, rules []ignoreRule, top bool) error
+	visit = func(dir string, rules []ignoreRule, top bool) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		entries, err := s.fs.ReadDir(dir)
+		if err != nil {
+			if top {
+				return err
+			}
+			return nil // unreadable subdirectories are skipped
+		}
+		if useIgnore {
+			rules = append(slices.Clip(rules), s.loadIgnoreFile(dir)...)
+		}
+		for _, entry := range entries {
+			if visited++; visited > maxSearchEntries {
+				return errSearchLimit
+			}
+			path := filepath.Join(dir, entry.Name())
+			switch {
+			case entry.IsDir():
+				if entry.Name() == ".git" || (useIgnore && isIgnored(rules, path, true)) {
+					continue
+				}
+				if err := visit(path, rules, false); err != nil {
+					return err
+				}
+			case entry.Type().IsRegular():
+				if useIgnore && isIgnored(rules, path, false) {
+					continue
+				}
+				if err := fn(path); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return visit(root, rules, true)
+}
+
+// parentIgnoreRules loads the .gitignore files between the workspace and
+// root, which also apply when a search starts in a subdirectory.
+func (s fileSearcher) parentIgnoreRules(root string) []ignoreRule {
+	rel, err := filepath.Rel(s.workspace, root)
+	if err != nil || rel == "." || !filepath.IsLocal(rel) {
+		return nil
+	}
+	var rules []ignoreRule
+	dir := s.workspace
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		rules = append(rules, s.loadIgnoreFile(dir)...)
+		dir = filepath.Join(dir, part)
+	}
+	return rules
+}
+
+// ignoreRule is one pattern of a .gitignore file.
+type ignoreRule struct {
+	base    string // directory containing the .gitignore
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+func (s fileSearcher) loadIgnoreFile(dir string) []ignoreRule {
+	data, err := s.fs.ReadFile(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		return nil
+	}
+	return parseIgnoreFile(dir, string(data))
+}
+
+func parseIgnoreFile(base, content string) []ignoreRule {
+	var rules []ignoreRule
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.HasSuffix(line, `\ `) {
+			line = strings.TrimRight(line[:len(line)-2], " ") + `\ `
+		} else {
+			line = strings.TrimRight(line, " ")
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := ignoreRule{base: base}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		// A pattern with a slash other than a trailing one is relative to
+		// the .gitignore's directory; otherwise it matches at any depth.
+		prefix := "^(?:.*/)?"
+		if strings.Contains(line, "/") {
+			prefix = "^"
+			line = strings.TrimPrefix(line, "/")
+		}
+		expr, err := globToRegexp(line, false)
+		if err != nil || line == "" {
+			continue
+		}
+		re, err := regexp.Compile(prefix + expr + "$")
+		if err != nil {
+			continue
+		}
+		rule.re = re
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// isIgnored applies rules in order; the last matching rule wins.
+func isIgnored(rules []ignoreRule, path string, isDir bool) bool {
+	ignored := false
+	for _, r := range rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		rel, err := filepath.Rel(r.base, path)
+		if err != nil || !filepath.IsLocal(rel) {
+			continue
+		}
+		if r.re.MatchString(filepath.ToSlash(rel)) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// globToRegexp translates a glob into an unanchored regular expression.
+// "*" and "?" stay within one path segment, "**" spans segments and
+// "[...]" is a character class. With braces, "{a,b}" matches either
+// alternative.
+func globToRegexp(glob string, braces bool) (string, error) {
+	var sb strings.Builder
+	depth := 0
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case c == '*' && strings.HasPrefix(glob[i:], "**"):
+			atStart := i == 0 || glob[i-1] == '/'
+			i++
+			switch {
+			case atStart && i+1 < len(glob) && glob[i+1] == '/':
+				sb.WriteString("(?:.*/)?")
+				i++
+			case atStart && i+1 == len(glob):
+				sb.WriteString(".*")
+			default:
+				sb.WriteString("[^/]*")
+			}
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '\\' && i+1 < len(glob):
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(glob[i])))
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case braces && c == '{':
+			depth++
+			sb.WriteString("(?:")
+		case braces && c == ',' && depth > 0:
+			sb.WriteString("|")
+		case braces && c == '}' && depth > 0:
+			depth--
+			sb.WriteString(")")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if depth != 0 {
+		return "", fmt.Errorf("unbalanced braces in %q", glob)
+	}
+	return sb.String(), nil
+}
+
+func resultLimitArg(args map[string]any, def, maxVal int) (int, error) {
+	n, err := getInt64Arg(args, "max_results", int64(def))
+	if err != nil {
+		return 0, err
+	}
+	if n < 1 {
+		return 0, fmt.Errorf("max_results must be at least 1")
+	}
+	return int(min(n, int64(maxVal))), nil
+}
+
+// GlobTool finds files by name pattern.
+type GlobTool struct {
+	search fileSearcher
+}
+
+func NewGlobTool(workspace string, restrict bool, allowPaths ...[]*regexp.Regexp) *GlobTool {
+	return &GlobTool{search: newFileSearcher(workspace, restrict, allowPaths)}
+}
+
+func (t *GlobTool) Name() string {
+	return "glob"
+}
+
+func (t *GlobTool) Description() string {
+	return "Find files whose path matches a glob pattern such as \"**/*.go\" or \"docs/*.{md,txt}\". " +
+		"Files ignored by .gitignore are skipped unless include_ignored is set."
+}
+
+func (t *GlobTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"pattern": map[string]any{
+				"type": "string",
+				"description": "Glob matched against paths relative to `path`: * and ? stay within a directory, " +
+					"** matches any number of directories, {a,b} matches either alternative.",
+			},
+			"path": map[string]any{
+				"type":        "string",
+				"description": "Directory to search (default: the workspace).",
+			},
+			"include_ignored": map[string]any{
+				"type":        "boolean",
+				"description": "Also return files ignored by .gitignore.",
+			},
+			"max_results": map[string]any{
+				"type":        "integer",
+				"description": fmt.Sprintf("Maximum number of files to return (default %d, max %d).", defaultGlobMaxResults, maxGlobMaxResults),
+			},
+		},
+		"required": []string{"pattern"},
+	}
+}
+
+func (t *GlobTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	pattern, _ := args["pattern"].(string)
+	pattern = strings.TrimPrefix(strings.TrimSpace(pattern), "./")
+	if pattern == "" {
+		return ErrorResult("pattern is required")
+	}
+	if strings.HasPrefix(pattern, "/") {
+		return ErrorResult("pattern must be relative; set path to choose the directory to search")
+	}
+	expr, err := globToRegexp(pattern, true)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("invalid pattern: %v", err))
+	}
+	limit, err := resultLimitArg(args, defaultGlobMaxResults, maxGlobMaxResults)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	path, _ := args["path"].(string)
+	root := t.search.resolve(path)
+	includeIgnored, _ := args["include_ignored"].(bool)
+
+	var matches []string
+	total := 0
+	err = t.search.walk(ctx, root, !includeIgnored, func(p string) error {
+		rel, err := filepath.Rel(root, p)
+		if err != nil || !re.MatchString(filepath.ToSlash(rel)) {
+			return nil
+		}
+		total++
+		if len(matches) < limit {
+			matches = append(matches, t.search.display(p))
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errSearchLimit) {
+		return ErrorResult(fmt.Sprintf("failed to search %s: %v", path, err))
+	}
+
+	if total == 0 {
+		return NewToolResult(fmt.Sprintf("No files found matching %q.", pattern))
+	}
+	out := strings.Join(matches, "\n")
+	if total > len(matches) {
+		out += fmt.Sprintf("\n\n[Showing %d of %d files. Narrow the pattern or path to see the rest.]", len(matches), total)
+	}
+	if err != nil {
+		out += "\n\n[" + err.Error() + "]"
+	}
+	return NewToolResult(out)
+}
+
+// GrepTool searches file contents with a regular expression.
+type GrepTool struct {
+	search fileSearcher
+}
+
+func NewGrepTool(workspace string, restrict bool, allowPaths ...[]*regexp.Regexp) *GrepTool {
+	return &GrepTool{search: newFileSearcher(workspace, restrict, allowPaths)}
+}
+
+func (t *GrepTool) Name() string {
+	return "grep"
+}
+
+func (t *GrepTool) Description() string {
+	return "Search file contents for a regular expression (RE2 syntax) and return matching lines with line numbers, " +
+		"grouped by file. Binary files and files ignored by .gitignore are skipped."
+}
+
+func (t *GrepTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"pattern": map[string]any{
+				"type":        "string",
+				"description": "Regular expression to search for.",
+			},
+			"path": map[string]any{
+				"type":        "string",
+				"description": "File or directory to search (default: the workspace).",
+			},
+			"include": map[string]any{
+				"type": "string",
+				"description": "Only search files matching this glob, e.g. \"*.go\" or \"src/**/*.{ts,tsx}\". " +
+					"Globs without a slash match file names.",
+			},
+			"ignore_case": map[string]any{
+				"type":        "boolean",
+				"description": "Match case-insensitively.",
+			},
+			"literal": map[string]any{
+				"type":        "boolean",
+				"description": "Treat pattern as a literal string instead of a regular expression.",
+			},
+			"context": map[string]any{
+				"type":        "integer",
+				"description": fmt.Sprintf("Lines of context to show before and after each match (max %d).", maxGrepContext),
+			},
+			"include_ignored": map[string]any{
+				"type":        "boolean",
+				"description": "Also search files ignored by .gitignore.",
+			},
+			"max_results": map[string]any{
+				"type":        "integer",
+				"description": fmt.Sprintf("Maximum number of matching lines (default %d, max %d).", defaultGrepMaxResults, maxGrepMaxResults),
+			},
+		},
+		"required": []string{"pattern"},
+	}
+}
+
+func (t *GrepTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	pattern, _ := args["pattern"].(string)
+	if pattern == "" {
+		return ErrorResult("pattern is required")
+	}
+	if literal, _ := args["literal"].(bool); literal {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if ignoreCase, _ := args["ignore_case"].(bool); ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("invalid pattern: %v", err))
+	}
+
+	var include *regexp.Regexp
+	includeNames := false
+	if glob, _ := args["include"].(string); glob != "" {
+		expr, err := globToRegexp(glob, true)
+		if err == nil {
+			include, err = regexp.Compile("^" + expr + "$")
+		}
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("invalid include glob: %v", err))
+		}
+		includeNames = !strings.Contains(glob, "/")
+	}
+
+	contextLines, err := getInt64Arg(args, "context", 0)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	contextLines = min(max(contextLines, 0), maxGrepContext)
+	limit, err := resultLimitArg(args, defaultGrepMaxResults, maxGrepMaxResults)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	path, _ := args["path"].(string)
+	root := t.search.resolve(path)
+	includeIgnored, _ := args["include_ignored"].(bool)
+
+	g := grepRun{re: re, context: int(contextLines), limit: limit}
+	searchFile := func(p string) error {
+		if include != nil {
+			name := filepath.Base(p)
+			if !includeNames {
+				rel, _ := filepath.Rel(root, p)
+				name = filepath.ToSlash(rel)
+			}
+			if !include.MatchString(name) {
+				return nil
+			}
+		}
+		return g.file(t.search, p)
+	}
+
+	if _, dirErr := t.search.fs.ReadDir(root); dirErr != nil {
+		// Not a directory: search the single file, which also reports why
+		// it cannot be read.
+		if err := g.file(t.search, root); err != nil && !errors.Is(err, errGrepLimit) {
+			return ErrorResult(err.Error())
+		}
+	} else {
+		err = t.search.walk(ctx, root, !includeIgnored, searchFile)
+		if err != nil && !errors.Is(err, errGrepLimit) && !errors.Is(err, errSearchLimit) {
+			return ErrorResult(fmt.Sprintf("failed to search %s: %v", path, err))
+		}
+	}
+
+	if g.matches == 0 {
+		return NewToolResult(fmt.Sprintf("No matches found for %q.", pattern))
+	}
+	out := strings.TrimRight(g.out.String(), "\n")
+	if g.limited {
+		out += fmt.Sprintf("\n\n[Stopped after %d matching lines. Narrow the pattern, path or include glob to see more.]", limit)
+	} else {
+		out += fmt.Sprintf("\n\n[%d matching lines in %d files]", g.matches, g.files)
+	}
+	if errors.Is(err, errSearchLimit) {
+		out += "\n[" + errSearchLimit.Error() + "]"
+	}
+	return NewToolResult(out)
+}
+
+var errGrepLimit = errors.New("grep result limit reached")
+
+// grepRun accumulates grep output in a heading-per-file format: the file
+// path, then "N:line" for matches and "N-line" for context, with "--"
+// between non-adjacent groups.
+type grepRun struct {
+	re      *regexp.Regexp
+	context int
+	limit   int
+	out     bytes.Buffer
+	matches int
+	files   int
+	limited bool
+}
+
+func (g *grepRun) file(s fileSearcher, path string) error {
+	f, err := s.fs.Open(path)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(io.LimitReader(f, maxGrepFileSize+1))
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", s.display(path), err)
+	}
+	if len(data) > maxGrepFileSize || bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0 {
+		return nil // too large or binary
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	printed := -1 // index of the last line written for this file
+	for i, line := range lines {
+		if !g.re.MatchString(line) {
+			continue
+		}
+		if g.matches >= g.limit {
+			g.limited = true
+			return errGrepLimit
+		}
+		if printed < 0 {
+			if g.files > 0 {
+				g.out.WriteString("\n")
+			}
+			g.out.WriteString(s.display(path) + "\n")
+			g.files++
+		}
+		start := max(i-g.context, printed+1, 0)
+		if printed >= 0 && start > printed+1 {
+			g.out.WriteString("--\n")
+		}
+		for j := start; j < i; j++ {
+			g.writeLine(j, '-', lines[j])
+		}
+		g.writeLine(i, ':', line)
+		g.matches++
+		printed = i
+		// Trailing context stops at the next match, which prints itself.
+		for j := i + 1; j <= min(i+g.context, len(lines)-1) && !g.re.MatchString(lines[j]); j++ {
+			g.writeLine(j, '-', lines[j])
+			printed = j
+		}
+	}
+	return nil
+}
+
+func (g *grepRun) writeLine(index int, sep byte, line string) {
+	line = strings.TrimRight(line, "\r")
+	if len(line) > maxGrepLineLength {
+		cut := maxGrepLineLength
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		line = line[:cut] + "…"
+	}
+	fmt.Fprintf(&g.out, "%d%c%s\n", index+1, sep, line)
+}
@@ -0,0 +1,189 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func writeSearchTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+var searchTree = map[string]string{
+	".gitignore":            "build/\n*.log\n!keep.log\n",
+	"main.go":               "package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n",
+	"pkg/util/util.go":      "package util\n\n// Hello says hello.\nfunc Hello() string { return \"hello\" }\n",
+	"pkg/util/util_test.go": "package util\n",
+	"pkg/.gitignore":        "/generated.go\n",
+	"pkg/generated.go":      "package pkg // hello\n",
+	"docs/readme.md":        "Say hello\n",
+	"build/out.go":          "package build // hello\n",
+	"debug.log":             "hello\n",
+	"keep.log":              "hello\n",
+	"image.bin":             "hello\x00\x01",
+	".git/config":           "hello\n",
+}
+
+func TestGlobTool(t *testing.T) {
+	root := writeSearchTree(t, searchTree)
+	tool := NewGlobTool(root, true)
+
+	for _, tc := range []struct {
+		args map[string]any
+		want []string
+	}{
+		{map[string]any{"pattern": "**/*.go"}, []string{"main.go", "pkg/util/util.go", "pkg/util/util_test.go"}},
+		{map[string]any{"pattern": "*.go"}, []string{"main.go"}},
+		{map[string]any{"pattern": "*.{md,log}", "path": "docs"}, []string{"docs/readme.md"}},
+		{map[string]any{"pattern": "*.log"}, []string{"keep.log"}},
+		{map[string]any{"pattern": "util/*_test.go", "path": "pkg"}, []string{"pkg/util/util_test.go"}},
+		{
+			map[string]any{"pattern": "**/*.go", "include_ignored": true},
+			[]string{"build/out.go", "main.go", "pkg/generated.go", "pkg/util/util.go", "pkg/util/util_test.go"},
+		},
+	} {
+		result := tool.Execute(context.Background(), tc.args)
+		if result.IsError {
+			t.Fatalf("%v: %s", tc.args, result.ForLLM)
+		}
+		if got := strings.Split(result.ForLLM, "\n"); strings.Join(got, ",") != strings.Join(tc.want, ",") {
+			t.Errorf("%v: got %q, want %q", tc.args, got, tc.want)
+		}
+	}
+
+	result := tool.Execute(context.Background(), map[string]any{"pattern": "**/*.go", "max_results": float64(1)})
+	if !strings.HasPrefix(result.ForLLM, "main.go\n\n[Showing 1 of 3 files.") {
+		t.Errorf("expected truncation note, got %q", result.ForLLM)
+	}
+	if result := tool.Execute(context.Background(), map[string]any{"pattern": "*.nothing"}); result.IsError ||
+		!strings.Contains(result.ForLLM, "No files found") {
+		t.Errorf("unexpected result for no matches: %+v", result)
+	}
+}
+
+func TestGrepTool(t *testing.T) {
+	root := writeSearchTree(t, searchTree)
+	tool := NewGrepTool(root, true)
+
+	result := tool.Execute(context.Background(), map[string]any{"pattern": "hello"})
+	if result.IsError {
+		t.Fatal(result.ForLLM)
+	}
+	for _, want := range []string{
+		"main.go\n4:\tprintln(\"hello\")",
+		"pkg/util/util.go\n3:// Hello says hello.\n4:func Hello()",
+		"docs/readme.md\n1:Say hello",
+		"keep.log\n1:hello",
+	} {
+		if !strings.Contains(result.ForLLM, want) {
+			t.Errorf("missing %q in:\n%s", want, result.ForLLM)
+		}
+	}
+	for _, unwanted := range []string{"build/", "debug.log", "generated.go", "image.bin", ".git"} {
+		if strings.Contains(result.ForLLM, unwanted) {
+			t.Errorf("unexpected %q in:\n%s", unwanted, result.ForLLM)
+		}
+	}
+
+	result = tool.Execute(context.Background(), map[string]any{
+		"pattern": "HELLO", "ignore_case": true, "include": "*.go", "path": "pkg", "context": float64(1),
+	})
+	want := "pkg/util/util.go\n2-\n3:// Hello says hello.\n4:func Hello() string { return \"hello\" }\n\n[2 matching lines in 1 files]"
+	if result.ForLLM != want {
+		t.Errorf("got:\n%s\nwant:\n%s", result.ForLLM, want)
+	}
+
+	result = tool.Execute(context.Background(), map[string]any{"pattern": "println(", "literal": true, "path": "main.go"})
+	if result.IsError || !strings.HasPrefix(result.ForLLM, "main.go\n4:") {
+		t.Errorf("unexpected single-file result: %s", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]any{"pattern": "hello", "max_results": float64(2)})
+	if !strings.Contains(result.ForLLM, "[Stopped after 2 matching lines.") {
+		t.Errorf("expected limit note, got:\n%s", result.ForLLM)
+	}
+
+	if result := tool.Execute(context.Background(), map[string]any{"pattern": "("}); !result.IsError {
+		t.Error("expected invalid regexp to fail")
+	}
+}
+
+func TestGrepTool_ContextSeparatesGroups(t *testing.T) {
+	root := writeSearchTree(t, map[string]string{"a.txt": "x\nmatch\ny\nz\nw\nmatch\nq\n"})
+	result := NewGrepTool(root, true).Execute(context.Background(), map[string]any{"pattern": "match", "context": float64(1)})
+	want := "a.txt\n1-x\n2:match\n3-y\n--\n5-w\n6:match\n7-q\n\n[2 matching lines in 1 files]"
+	if result.ForLLM != want {
+		t.Errorf("got:\n%s\nwant:\n%s", result.ForLLM, want)
+	}
+}
+
+func TestFileSearch_RespectsWorkspaceRestriction(t *testing.T) {
+	root := writeSearchTree(t, map[string]string{"ws/a.txt": "secret\n", "outside/b.txt": "secret\n"})
+	workspace := filepath.Join(root, "ws")
+	outside := filepath.Join(root, "outside")
+
+	grep := NewGrepTool(workspace, true)
+	if result := grep.Execute(context.Background(), map[string]any{"pattern": "secret", "path": outside}); !result.IsError {
+		t.Errorf("expected access outside the workspace to fail, got %s", result.ForLLM)
+	}
+	glob := NewGlobTool(workspace, true)
+	if result := glob.Execute(context.Background(), map[string]any{"pattern": "*", "path": "../outside"}); !result.IsError {
+		t.Errorf("expected access outside the workspace to fail, got %s", result.ForLLM)
+	}
+
+	allowed := []*regexp.Regexp{regexp.MustCompile("^" + regexp.QuoteMeta(outside))}
+	result := NewGrepTool(workspace, true, allowed).Execute(context.Background(), map[string]any{"pattern": "secret", "path": outside})
+	if result.IsError || !strings.Contains(result.ForLLM, filepath.Join(outside, "b.txt")) {
+		t.Errorf("expected allow path to be searchable, got %s", result.ForLLM)
+	}
+}
+
+func TestGlobToRegexp(t *testing.T) {
+	for _, tc := range []struct {
+		glob  string
+		match []string
+		miss  []string
+	}{
+		{"*.go", []string{"a.go"}, []string{"a/b.go", "a.gox"}},
+		{"**/*.go", []string{"a.go", "a/b/c.go"}, []string{"a.txt"}},
+		{"src/**", []string{"src/a", "src/a/b"}, []string{"src"}},
+		{"a/**/b", []string{"a/b", "a/x/y/b"}, []string{"a/xb"}},
+		{"file?.[ch]", []string{"file1.c", "filex.h"}, []string{"file10.c", "file1.o"}},
+		{"[!a]*", []string{"b"}, []string{"a"}},
+		{"*.{ts,tsx}", []string{"a.ts", "a.tsx"}, []string{"a.t"}},
+	} {
+		expr, err := globToRegexp(tc.glob, true)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.glob, err)
+		}
+		re := regexp.MustCompile("^" + expr + "$")
+		for _, m := range tc.match {
+			if !re.MatchString(m) {
+				t.Errorf("%s should match %s", tc.glob, m)
+			}
+		}
+		for _, m := range tc.miss {
+			if re.MatchString(m) {
+				t.Errorf("%s should not match %s", tc.glob, m)
+			}
+		}
+	}
+	if _, err := globToRegexp("{a,b", true); err == nil {
+		t.Error("expected unbalanced braces to fail")
+	}
+}
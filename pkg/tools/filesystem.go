@@ -527,6 +527,11 @@ func (t *WriteFileTool) Execute(ctx context.Context, args map[string]any) *ToolR
 		return ErrorResult("content is required")
 	}
 
+	existing, _ := t.fs.ReadFile(path)
+	if result := rejectRedacted("content", content, string(existing)); result != nil {
+		return result
+	}
+
 	if err := t.fs.WriteFile(path, []byte(content)); err != nil {
 		return ErrorResult(err.Error())
 	}
@@ -599,6 +604,7 @@ type fileSystem interface {
 	WriteFile(path string, data []byte) error
 	ReadDir(path string) ([]os.DirEntry, error)
 	Open(path string) (fs.File, error)
+	Remove(path string) error
 }
 
 // hostFs is an unrestricted fileReadWriter that operates directly on the host filesystem.
@@ -642,6 +648,10 @@ func (h *hostFs) Open(path string) (fs.File, error) {
 	return f, nil
 }
 
+func (h *hostFs) Remove(path string) error {
+	return os.Remove(path)
+}
+
 // sandboxFs is a sandboxed fileSystem that operates within a strictly defined workspace using os.Root.
 type sandboxFs struct {
 	workspace string
@@ -773,6 +783,12 @@ func (r *sandboxFs) Open(path string) (fs.File, error) {
 	return f, err
 }
 
+func (r *sandboxFs) Remove(path string) error {
+	return r.execute(path, func(root *os.Root, relPath string) error {
+		return root.Remove(relPath)
+	})
+}
+
 // whitelistFs wraps a sandboxFs and allows access to specific paths outside
 // the workspace when they match any of the provided patterns.
 type whitelistFs struct {
@@ -813,6 +829,13 @@ func (w *whitelistFs) Open(path string) (fs.File, error) {
 	return w.sandbox.Open(path)
 }
 
+func (w *whitelistFs) Remove(path string) error {
+	if w.matches(path) {
+		return w.host.Remove(path)
+	}
+	return w.sandbox.Remove(path)
+}
+
 // buildFs returns the appropriate fileSystem implementation based on restriction
 // settings and optional path whitelist patterns.
 func buildFs(workspace string, restrict bool, patterns []*regexp.Regexp) fileSystem {
@@ -0,0 +1,183 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/imagegen"
+	"github.com/sipeed/picoclaw/pkg/media"
+)
+
+// GenerateImageTool creates images from a text prompt and sends them to the
+// user through the MediaStore pipeline.
+type GenerateImageTool struct {
+	providers  []imagegen.ImageProvider // first is the default
+	mediaStore media.MediaStore
+}
+
+func NewGenerateImageTool(providers []imagegen.ImageProvider) (*GenerateImageTool, error) {
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("no image models configured")
+	}
+	return &GenerateImageTool{providers: providers}, nil
+}
+
+func (t *GenerateImageTool) Name() string { return "generate_image" }
+
+func (t *GenerateImageTool) Description() string {
+	return "Generate images from a text description and send them to the user on the current chat channel. " +
+		"Write a detailed prompt covering subject, style, composition and lighting."
+}
+
+func (t *GenerateImageTool) Parameters() map[string]any {
+	props := map[string]any{
+		"prompt": map[string]any{
+			"type":        "string",
+			"description": "Description of the image to generate.",
+		},
+		"negative_prompt": map[string]any{
+			"type":        "string",
+			"description": "Optional description of what the image should not contain.",
+		},
+		"size": map[string]any{
+			"type":        "string",
+			"description": `Optional image size as WIDTHxHEIGHT, e.g. "1024x1024" or "1024x1536".`,
+		},
+		"n": map[string]any{
+			"type":        "integer",
+			"description": fmt.Sprintf("Number of images to generate (1-%d).", imagegen.MaxImages),
+			"default":     1,
+		},
+	}
+	if len(t.providers) > 1 {
+		names := make([]string, len(t.providers))
+		for i, p := range t.providers {
+			names[i] = p.Name()
+		}
+		props["model"] = map[string]any{
+			"type":        "string",
+			"enum":        names,
+			"description": fmt.Sprintf("Image model to use. Defaults to %q.", names[0]),
+		}
+	}
+	return map[string]any{
+		"type":       "object",
+		"properties": props,
+		"required":   []string{"prompt"},
+	}
+}
+
+func (t *GenerateImageTool) SetMediaStore(store media.MediaStore) {
+	t.mediaStore = store
+}
+
+func (t *GenerateImageTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	prompt, _ := args["prompt"].(string)
+	if strings.TrimSpace(prompt) == "" {
+		return ErrorResult("prompt is required")
+	}
+	channel, chatID := ToolChannel(ctx), ToolChatID(ctx)
+	if channel == "" || chatID == "" {
+		return ErrorResult("no target channel/chat available")
+	}
+	if t.mediaStore == nil {
+		return ErrorResult("media store not configured")
+	}
+
+	provider := t.providers[0]
+	if name, _ := args["model"].(string); name != "" {
+		provider = nil
+		for _, p := range t.providers {
+			if p.Name() == name {
+				provider = p
+				break
+			}
+		}
+		if provider == nil {
+			return ErrorResult(fmt.Sprintf("unknown image model %q", name))
+		}
+	}
+
+	n, err := getInt64Arg(args, "n", 1)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	if n < 1 || n > imagegen.MaxImages {
+		return ErrorResult(fmt.Sprintf("n must be between 1 and %d", imagegen.MaxImages))
+	}
+	size, _ := args["size"].(string)
+	if size != "" {
+		if _, _, err := imagegen.ParseSize(size, 0, 0); err != nil {
+			return ErrorResult(err.Error())
+		}
+	}
+	negative, _ := args["negative_prompt"].(string)
+
+	images, err := provider.Generate(ctx, imagegen.Request{
+		Prompt:         prompt,
+		NegativePrompt: negative,
+		Size:           size,
+		N:              int(n),
+	})
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("image generation failed: %v", err)).WithError(err)
+	}
+
+	scope := fmt.Sprintf("tool:image-gen:%s:%s", channel, chatID)
+	refs := make([]string, 0, len(images))
+	var revised []string
+	for i, img := range images {
+		ref, err := t.storeImage(img, i+1, scope)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("failed to store image: %v", err))
+		}
+		refs = append(refs, ref)
+		if img.RevisedPrompt != "" {
+			revised = append(revised, img.RevisedPrompt)
+		}
+	}
+
+	msg := fmt.Sprintf("Generated %d image(s) with %s and sent them to the user.", len(refs), provider.Name())
+	if len(revised) > 0 {
+		msg += "\nRevised prompt: " + strings.Join(revised, "\n")
+	}
+	return MediaResult(msg, refs)
+}
+
+func (t *GenerateImageTool) storeImage(img imagegen.Image, index int, scope string) (string, error) {
+	ext := ".png"
+	switch img.ContentType {
+	case "image/jpeg":
+		ext = ".jpg"
+	case "image/webp":
+		ext = ".webp"
+	}
+	if err := os.MkdirAll(media.TempDir(), 0o700); err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp(media.TempDir(), "image-*"+ext)
+	if err != nil {
+		return "", err
+	}
+	_, err = f.Write(img.Data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+
+	ref, err := t.mediaStore.Store(f.Name(), media.MediaMeta{
+		Filename:    fmt.Sprintf("image-%d%s", index, ext),
+		ContentType: img.ContentType,
+		Source:      "tool:image-gen",
+	}, scope)
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return ref, nil
+}
@@ -0,0 +1,104 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/imagegen"
+	"github.com/sipeed/picoclaw/pkg/media"
+)
+
+type fakeImageProvider struct {
+	name string
+	last imagegen.Request
+	err  error
+}
+
+func (p *fakeImageProvider) Name() string { return p.name }
+
+func (p *fakeImageProvider) Generate(_ context.Context, req imagegen.Request) ([]imagegen.Image, error) {
+	p.last = req
+	if p.err != nil {
+		return nil, p.err
+	}
+	images := make([]imagegen.Image, req.N)
+	for i := range images {
+		images[i] = imagegen.Image{Data: []byte("\x89PNG\r\n\x1a\nfake"), ContentType: "image/png"}
+	}
+	images[0].RevisedPrompt = "a fluffy cat"
+	return images, nil
+}
+
+func TestGenerateImageTool_StoresImages(t *testing.T) {
+	store := media.NewFileMediaStore()
+	provider := &fakeImageProvider{name: "gpt-image"}
+	tool, err := NewGenerateImageTool([]imagegen.ImageProvider{provider})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tool.SetMediaStore(store)
+
+	ctx := WithToolContext(context.Background(), "telegram", "42")
+	result := tool.Execute(ctx, map[string]any{"prompt": "a cat", "n": float64(2), "size": "1024x1024"})
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", result.ForLLM)
+	}
+	if len(result.Media) != 2 {
+		t.Fatalf("expected 2 media refs, got %v", result.Media)
+	}
+	if provider.last.Size != "1024x1024" || provider.last.N != 2 {
+		t.Errorf("unexpected request: %+v", provider.last)
+	}
+	if !strings.Contains(result.ForLLM, "Revised prompt: a fluffy cat") {
+		t.Errorf("expected revised prompt in result, got %q", result.ForLLM)
+	}
+
+	path, meta, err := store.ResolveWithMeta(result.Media[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Source != "tool:image-gen" || meta.Filename != "image-2.png" || meta.ContentType != "image/png" {
+		t.Errorf("unexpected meta: %+v", meta)
+	}
+	if err := store.ReleaseAll("tool:image-gen:telegram:42"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected image to be released with its scope, stat err = %v", err)
+	}
+}
+
+func TestGenerateImageTool_Validation(t *testing.T) {
+	if _, err := NewGenerateImageTool(nil); err == nil {
+		t.Error("expected error without providers")
+	}
+
+	tool, _ := NewGenerateImageTool([]imagegen.ImageProvider{
+		&fakeImageProvider{name: "a"},
+		&fakeImageProvider{name: "b", err: errors.New("backend down")},
+	})
+	tool.SetMediaStore(media.NewFileMediaStore())
+	if _, ok := tool.Parameters()["properties"].(map[string]any)["model"]; !ok {
+		t.Error("expected model parameter with several providers")
+	}
+
+	ctx := WithToolContext(context.Background(), "telegram", "42")
+	for name, tc := range map[string]struct {
+		ctx  context.Context
+		args map[string]any
+	}{
+		"no prompt":     {ctx, map[string]any{}},
+		"no channel":    {context.Background(), map[string]any{"prompt": "x"}},
+		"unknown model": {ctx, map[string]any{"prompt": "x", "model": "c"}},
+		"too many":      {ctx, map[string]any{"prompt": "x", "n": float64(imagegen.MaxImages + 1)}},
+		"bad size":      {ctx, map[string]any{"prompt": "x", "size": "huge"}},
+		"backend error": {ctx, map[string]any{"prompt": "x", "model": "b"}},
+	} {
+		if result := tool.Execute(tc.ctx, tc.args); !result.IsError {
+			t.Errorf("%s: expected error, got %q", name, result.ForLLM)
+		}
+	}
+}
@@ -0,0 +1,949 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/credential"
+)
+
+const (
+	defaultGitTimeout  = 60 * time.Second
+	defaultGitMaxChars = 20000
+	defaultGitLogLimit = 10
+	maxGitLogLimit     = 100
+	defaultGitUsername = "x-access-token"
+)
+
+var (
+	gitLocalActions  = []string{"status", "diff", "log", "commit", "branch", "checkout", "stash"}
+	gitStashActions  = []string{"push", "pop", "apply", "list", "drop"}
+	gitHunkHeaderRE  = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+	gitStashRefRE    = regexp.MustCompile(`^stash@\{\d+\}$`)
+	gitDriverKeyRE   = regexp.MustCompile(`^(filter|merge)\.(.+)\.(clean|smudge|process|driver)$`)
+	gitSafeConfigArg = []string{
+		// Repositories live in the agent-writable workspace, so their config
+		// must not make git run programs on our behalf. These cover the
+		// single-valued settings; per-name filter and merge drivers are
+		// neutralised by driverOverrides, and diffs never use external or
+		// textconv drivers.
+		"-c", "core.hooksPath=" + os.DevNull,
+		"-c", "core.fsmonitor=false",
+		"-c", "core.sshCommand=",
+		"-c", "core.askPass=",
+		"-c", "credential.helper=",
+		"-c", "commit.gpgSign=false",
+		"-c", "tag.gpgSign=false",
+		"-c", "diff.external=",
+		"-c", "protocol.ext.allow=never",
+		"-c", "http.sslVerify=true",
+		"-c", "core.pager=cat",
+		"-c", "core.quotePath=false",
+		"-c", "color.ui=false",
+		"-c", "advice.detachedHead=false",
+	}
+)
+
+// gitRedirectKeys matches settings that change where a remote action connects
+// or which certificates it trusts; see redirectingConfig.
+const gitRedirectKeys = `^(url\..*\.(insteadof|pushinsteadof)|http\..*(proxy|sslverify|sslcainfo|sslcapath|curloptresolve))$`
+
+// GitToolOptions configures NewGitTool. Zero values select defaults.
+type GitToolOptions struct {
+	Workspace   string
+	Restrict    bool
+	AllowPaths  []*regexp.Regexp
+	Timeout     time.Duration
+	MaxChars    int
+	AuthorName  string
+	AuthorEmail string
+	AllowRemote bool
+	Remotes     map[string]config.GitRemoteConfig
+	Resolver    *credential.Resolver
+}
+
+// GitTool gives the agent structured access to git repositories inside its
+// allowed paths. Push and pull only reach remotes from the config, with
+// credentials passed to git through its environment rather than the command
+// line or the repository config.
+type GitTool struct {
+	gitPath     string
+	workspace   string
+	restrict    bool
+	allowPaths  []*regexp.Regexp
+	timeout     time.Duration
+	maxChars    int
+	authorName  string
+	authorEmail string
+	remotes     map[string]config.GitRemoteConfig
+	resolver    *credential.Resolver
+}
+
+func NewGitTool(opts GitToolOptions) (*GitTool, error) {
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		return nil, fmt.Errorf("git executable not found: %w", err)
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultGitTimeout
+	}
+	if opts.MaxChars <= 0 {
+		opts.MaxChars = defaultGitMaxChars
+	}
+	if opts.Resolver == nil {
+		opts.Resolver = credential.NewResolver("")
+	}
+	remotes := opts.Remotes
+	if !opts.AllowRemote {
+		remotes = nil
+	}
+	for name, remote := range remotes {
+		if remote.URL == "" {
+			return nil, fmt.Errorf("git remote %q: url is required", name)
+		}
+		if strings.HasPrefix(remote.URL, "-") || strings.Contains(remote.URL, "::") {
+			return nil, fmt.Errorf("git remote %q: unsupported url %q", name, remote.URL)
+		}
+		for _, pattern := range remote.PushBranches {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("git remote %q: invalid push_branches pattern %q", name, pattern)
+			}
+		}
+	}
+	return &GitTool{
+		gitPath:     gitPath,
+		workspace:   opts.Workspace,
+		restrict:    opts.Restrict,
+		allowPaths:  opts.AllowPaths,
+		timeout:     opts.Timeout,
+		maxChars:    opts.MaxChars,
+		authorName:  opts.AuthorName,
+		authorEmail: opts.AuthorEmail,
+		remotes:     remotes,
+		resolver:    opts.Resolver,
+	}, nil
+}
+
+func (t *GitTool) Name() string {
+	return "git"
+}
+
+func (t *GitTool) Description() string {
+	desc := "Work with git repositories in the workspace: status, diff, log, commit, branch, checkout and stash. " +
+		"status, diff, log and branch listings are returned as JSON. Repository hooks are not run."
+	if len(t.remotes) == 0 {
+		return desc
+	}
+	return desc + " push and pull exchange commits with these configured remotes: " +
+		strings.Join(t.remoteNames(), ", ") + ". Force pushes are not possible and pulls only fast-forward."
+}
+
+func (t *GitTool) remoteNames() []string {
+	names := make([]string, 0, len(t.remotes))
+	for name := range t.remotes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (t *GitTool) actions() []string {
+	if len(t.remotes) == 0 {
+		return gitLocalActions
+	}
+	return append(slices.Clone(gitLocalActions), "push", "pull")
+}
+
+func (t *GitTool) Parameters() map[string]any {
+	props := map[string]any{
+		"action": map[string]any{
+			"type":        "string",
+			"enum":        t.actions(),
+			"description": "Git operation to run",
+		},
+		"repo": map[string]any{
+			"type":        "string",
+			"description": "Path to the repository or a directory inside it (default: the workspace)",
+		},
+		"paths": map[string]any{
+			"type":        "array",
+			"items":       map[string]any{"type": "string"},
+			"description": "diff/log: limit to these paths. commit: stage and commit only these paths.",
+		},
+		"staged": map[string]any{
+			"type":        "boolean",
+			"description": "diff: show staged changes instead of unstaged ones",
+		},
+		"ref": map[string]any{
+			"type": "string",
+			"description": "diff: commit or branch to compare against. log: where to start. " +
+				"checkout: branch or commit to switch to. branch: start point of a new branch. " +
+				"stash: entry to pop, apply or drop (e.g. stash@{1}).",
+		},
+		"name": map[string]any{
+			"type":        "string",
+			"description": "branch: name of the branch to create or delete (omit to list branches)",
+		},
+		"delete": map[string]any{
+			"type":        "boolean",
+			"description": "branch: delete the named branch; it must be fully merged",
+		},
+		"create": map[string]any{
+			"type":        "boolean",
+			"description": "checkout: create a new branch named ref from the current commit and switch to it",
+		},
+		"message": map[string]any{
+			"type":        "string",
+			"description": "commit: commit message (required). stash push: optional description.",
+		},
+		"all": map[string]any{
+			"type":        "boolean",
+			"description": "commit: stage all changes, including new and deleted files, before committing",
+		},
+		"stash_action": map[string]any{
+			"type":        "string",
+			"enum":        gitStashActions,
+			"description": "stash: operation (default push)",
+		},
+		"limit": map[string]any{
+			"type":        "integer",
+			"description": fmt.Sprintf("log: number of commits (default %d, max %d)", defaultGitLogLimit, maxGitLogLimit),
+		},
+	}
+	if len(t.remotes) > 0 {
+		props["remote"] = map[string]any{
+			"type":        "string",
+			"enum":        t.remoteNames(),
+			"description": "push/pull: configured remote (required when more than one is configured)",
+		}
+		props["branch"] = map[string]any{
+			"type":        "string",
+			"description": "push/pull: branch name, the same locally and on the remote (default: current branch)",
+		}
+	}
+	return map[string]any{
+		"type":       "object",
+		"properties": props,
+		"required":   []string{"action"},
+	}
+}
+
+func (t *GitTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	action, _ := args["action"].(string)
+	if !slices.Contains(t.actions(), action) {
+		return ErrorResult(fmt.Sprintf("unknown action %q (want one of %s)", action, strings.Join(t.actions(), ", ")))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	repoArg, _ := args["repo"].(string)
+	if repoArg == "" {
+		repoArg = "."
+	}
+	repo, err := t.resolveRepo(ctx, repoArg)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	var result *ToolResult
+	switch action {
+	case "status":
+		result = t.status(ctx, repo)
+	case "diff":
+		result = t.diff(ctx, repo, args)
+	case "log":
+		result = t.log(ctx, repo, args)
+	case "commit":
+		result = t.commit(ctx, repo, args)
+	case "branch":
+		result = t.branch(ctx, repo, args)
+	case "checkout":
+		result = t.checkout(ctx, repo, args)
+	case "stash":
+		result = t.stash(ctx, repo, args)
+	case "push", "pull":
+		result = t.remoteOp(ctx, repo, action, args)
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return ErrorResult(fmt.Sprintf("git %s timed out after %s", action, t.timeout))
+	}
+	return result
+}
+
+// resolveRepo returns the top-level directory of the repository containing
+// dir. Both dir and the top level must be inside the allowed paths, so a
+// workspace that is itself inside a larger repository cannot reach it.
+func (t *GitTool) resolveRepo(ctx context.Context, dir string) (string, error) {
+	absDir, err := validatePathWithAllowPaths(dir, t.workspace, t.restrict, t.allowPaths)
+	if err != nil {
+		return "", err
+	}
+	if info, err := os.Stat(absDir); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("repo %s is not a directory", dir)
+	}
+	out, err := t.git(ctx, absDir, nil, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", fmt.Errorf("%s is not inside a git repository", dir)
+	}
+	top := filepath.Clean(strings.TrimSpace(out))
+	if _, err := validatePathWithAllowPaths(top, t.workspace, t.restrict, t.allowPaths); err != nil {
+		return "", fmt.Errorf("repository %s is outside the allowed paths", top)
+	}
+	return top, nil
+}
+
+// git runs git in dir and returns its stdout. On failure the error carries
+// git's own message.
+func (t *GitTool) git(ctx context.Context, dir string, env []string, args ...string) (string, error) {
+	return t.run(ctx, dir, env, append(t.driverOverrides(ctx, dir), args...)...)
+}
+
+// driverOverrides returns -c arguments that blank out every filter and merge
+// driver defined in the config visible from dir. Drivers are looked up by a
+// name chosen in .gitattributes, so they cannot be disabled by a fixed list.
+// git config exits non-zero when nothing matches; an unreadable config makes
+// the real command fail on its own.
+func (t *GitTool) driverOverrides(ctx context.Context, dir string) []string {
+	out, _ := t.run(ctx, dir, nil, "config", "--null", "--name-only", "--get-regexp", `^(filter|merge)\.`)
+	var overrides []string
+	seen := map[string]bool{}
+	for _, key := range strings.Split(out, "\x00") {
+		m := gitDriverKeyRE.FindStringSubmatch(key)
+		if m == nil || seen[m[1]+"."+m[2]] {
+			continue
+		}
+		seen[m[1]+"."+m[2]] = true
+		prefix := m[1] + "." + m[2] + "."
+		if m[1] == "merge" {
+			overrides = append(overrides, "-c", prefix+"driver=")
+			continue
+		}
+		overrides = append(overrides,
+			"-c", prefix+"clean=", "-c", prefix+"smudge=", "-c", prefix+"process=", "-c", prefix+"required=false")
+	}
+	return overrides
+}
+
+// redirectingConfig returns the config keys that would let a planted config
+// intercept a remote action and the credentials sent with it: URL rewrites
+// from any scope, and proxy, TLS verification, CA and DNS overrides from the
+// repository's own config. A URL-scoped http.<url>.* setting outranks any
+// -c override, so these are refused rather than overridden.
+func (t *GitTool) redirectingConfig(ctx context.Context, dir string) []string {
+	out, _ := t.run(ctx, dir, nil, "config", "--null", "--name-only", "--show-scope", "--get-regexp", gitRedirectKeys)
+	fields := strings.Split(out, "\x00")
+	var keys []string
+	for i := 0; i+1 < len(fields); i += 2 {
+		scope, key := fields[i], fields[i+1]
+		if strings.HasPrefix(key, "url.") || scope == "local" || scope == "worktree" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// run executes git with the safe config arguments and no driver overrides.
+func (t *GitTool) run(ctx context.Context, dir string, env []string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, t.gitPath, append(slices.Clone(gitSafeConfigArg), args...)...)
+	cmd.Dir = dir
+	cmd.Env = append(gitBaseEnv(), env...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = strings.TrimSpace(stdout.String())
+		}
+		if msg == "" {
+			msg = err.Error()
+		}
+		return stdout.String(), errors.New(msg)
+	}
+	if stderr.Len() > 0 && stdout.Len() == 0 {
+		// Commands such as push, checkout and stash report on stderr.
+		return stderr.String(), nil
+	}
+	return stdout.String(), nil
+}
+
+// gitBaseEnv is the process environment without variables that would point
+// git at another repository or configuration, plus non-interactive settings.
+func gitBaseEnv() []string {
+	env := make([]string, 0, len(os.Environ())+4)
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, "GIT_") {
+			env = append(env, kv)
+		}
+	}
+	// An empty GIT_PROXY_COMMAND takes precedence over core.gitProxy.
+	return append(env, "GIT_TERMINAL_PROMPT=0", "GIT_OPTIONAL_LOCKS=0", "GIT_PROXY_COMMAND=", "LC_ALL=C")
+}
+
+// gitArgError reports values that git would parse as options.
+func gitArgError(values ...string) error {
+	for _, v := range values {
+		if strings.HasPrefix(v, "-") {
+			return fmt.Errorf("invalid argument %q", v)
+		}
+	}
+	return nil
+}
+
+func stringListArg(args map[string]any, key string) []string {
+	raw, _ := args[key].([]any)
+	out := make([]string, 0, len(raw))
+	for _, v := range raw {
+		if s, ok := v.(string); ok && s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func (t *GitTool) jsonResult(v any) *ToolResult {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to encode result: %v", err))
+	}
+	return NewToolResult(string(data))
+}
+
+func (t *GitTool) textResult(out string) *ToolResult {
+	out = strings.TrimSpace(out)
+	if out == "" {
+		out = "Done."
+	}
+	if len(out) > t.maxChars {
+		out = out[:t.maxChars] + fmt.Sprintf("\n... [output truncated at %d chars]", t.maxChars)
+	}
+	return NewToolResult(out)
+}
+
+type gitStatusEntry struct {
+	Path    string `json:"path"`
+	OldPath string `json:"old_path,omitempty"`
+	Status  string `json:"status"`
+}
+
+type gitStatus struct {
+	Branch     string           `json:"branch"`
+	Commit     string           `json:"commit,omitempty"`
+	Upstream   string           `json:"upstream,omitempty"`
+	Ahead      int              `json:"ahead,omitempty"`
+	Behind     int              `json:"behind,omitempty"`
+	Clean      bool             `json:"clean"`
+	Staged     []gitStatusEntry `json:"staged,omitempty"`
+	Unstaged   []gitStatusEntry `json:"unstaged,omitempty"`
+	Untracked  []string         `json:"untracked,omitempty"`
+	Conflicted []string         `json:"conflicted,omitempty"`
+}
+
+var gitStatusCodes = map[byte]string{
+	'M': "modified",
+	'T': "type_changed",
+	'A': "added",
+	'D': "deleted",
+	'R': "renamed",
+	'C': "copied",
+}
+
+func (t *GitTool) status(ctx context.Context, repo string) *ToolResult {
+	out, err := t.git(ctx, repo, nil, "status", "--porcelain=v2", "--branch", "-z", "--untracked-files=all")
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("git status failed: %v", err))
+	}
+	return t.jsonResult(parseGitStatus(out))
+}
+
+// parseGitStatus parses `git status --porcelain=v2 --branch -z`.
+func parseGitStatus(out string) gitStatus {
+	var st gitStatus
+	fields := strings.Split(out, "\x00")
+	for i := 0; i < len(fields); i++ {
+		line := fields[i]
+		if line == "" {
+			continue
+		}
+		switch line[0] {
+		case '#':
+			key, value, _ := strings.Cut(strings.TrimPrefix(line, "# "), " ")
+			switch key {
+			case "branch.oid":
+				if value != "(initial)" {
+					st.Commit = value
+				}
+			case "branch.head":
+				st.Branch = value
+			case "branch.upstream":
+				st.Upstream = value
+			case "branch.ab":
+				fmt.Sscanf(value, "+%d -%d", &st.Ahead, &st.Behind)
+			}
+		case '1', '2':
+			// 1 XY sub mH mI mW hH hI path
+			// 2 XY sub mH mI mW hH hI Xscore path, then origPath as the next field
+			n := 9
+			if line[0] == '2' {
+				n = 10
+			}
+			parts := strings.SplitN(line, " ", n)
+			if len(parts) < n {
+				continue
+			}
+			entry := gitStatusEntry{Path: parts[n-1]}
+			if line[0] == '2' && i+1 < len(fields) {
+				i++
+				entry.OldPath = fields[i]
+			}
+			xy := parts[1]
+			if s, ok := gitStatusCodes[xy[0]]; ok {
+				e := entry
+				e.Status = s
+				st.Staged = append(st.Staged, e)
+			}
+			if s, ok := gitStatusCodes[xy[1]]; ok {
+				e := entry
+				e.Status = s
+				e.OldPath = ""
+				st.Unstaged = append(st.Unstaged, e)
+			}
+		case 'u':
+			parts := strings.SplitN(line, " ", 11)
+			if len(parts) == 11 {
+				st.Conflicted = append(st.Conflicted, parts[10])
+			}
+		case '?':
+			st.Untracked = append(st.Untracked, strings.TrimPrefix(line, "? "))
+		}
+	}
+	st.Clean = len(st.Staged)+len(st.Unstaged)+len(st.Untracked)+len(st.Conflicted) == 0
+	return st
+}
+
+type gitDiffHunk struct {
+	Header   string   `json:"header"`
+	OldStart int      `json:"old_start"`
+	OldLines int      `json:"old_lines"`
+	NewStart int      `json:"new_start"`
+	NewLines int      `json:"new_lines"`
+	Lines    []string `json:"lines"`
+}
+
+type gitDiffFile struct {
+	Path      string        `json:"path"`
+	OldPath   string        `json:"old_path,omitempty"`
+	Status    string        `json:"status"`
+	Binary    bool          `json:"binary,omitempty"`
+	Additions int           `json:"additions"`
+	Deletions int           `json:"deletions"`
+	Hunks     []gitDiffHunk `json:"hunks,omitempty"`
+}
+
+type gitDiff struct {
+	Files     []gitDiffFile `json:"files"`
+	Additions int           `json:"additions"`
+	Deletions int           `json:"deletions"`
+	// Truncated is set when hunks were left out to respect the size limit;
+	// the per-file counts are still complete.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+func (t *GitTool) diff(ctx context.Context, repo string, args map[string]any) *ToolResult {
+	ref, _ := args["ref"].(string)
+	paths := stringListArg(args, "paths")
+	if err := gitArgError(ref); err != nil {
+		return ErrorResult(err.Error())
+	}
+	gitArgs := []string{"diff", "--no-color", "--no-ext-diff", "--no-textconv", "-M"}
+	if staged, _ := args["staged"].(bool); staged {
+		gitArgs = append(gitArgs, "--cached")
+	}
+	if ref != "" {
+		gitArgs = append(gitArgs, ref)
+	}
+	gitArgs = append(append(gitArgs, "--"), paths...)
+	out, err := t.git(ctx, repo, nil, gitArgs...)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("git diff failed: %v", err))
+	}
+	return t.jsonResult(parseGitDiff(out, t.maxChars))
+}
+
+// parseGitDiff parses a unified git diff. Hunk lines are kept until about
+// maxChars characters have been collected.
+func parseGitDiff(out string, maxChars int) gitDiff {
+	d := gitDiff{Files: []gitDiffFile{}}
+	var file *gitDiffFile
+	var hunk *gitDiffHunk
+	budget := maxChars
+
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			d.Files = append(d.Files, gitDiffFile{Status: "modified"})
+			file = &d.Files[len(d.Files)-1]
+			hunk = nil
+			// "a/x b/x": exact for unchanged names, which is all that is
+			// left when no ---/+++ or rename lines follow.
+			names := strings.TrimPrefix(line, "diff --git ")
+			if half := len(names) / 2; len(names)%2 == 1 && names[half] == ' ' {
+				file.Path = strings.TrimPrefix(names[half+1:], "b/")
+			}
+		case file == nil:
+			continue
+		case hunk == nil && strings.HasPrefix(line, "new file mode"):
+			file.Status = "added"
+		case hunk == nil && strings.HasPrefix(line, "deleted file mode"):
+			file.Status = "deleted"
+		case hunk == nil && strings.HasPrefix(line, "rename from "):
+			file.Status = "renamed"
+			file.OldPath = strings.TrimPrefix(line, "rename from ")
+		case hunk == nil && strings.HasPrefix(line, "rename to "):
+			file.Path = strings.TrimPrefix(line, "rename to ")
+		case hunk == nil && strings.HasPrefix(line, "copy from "):
+			file.Status = "copied"
+			file.OldPath = strings.TrimPrefix(line, "copy from ")
+		case hunk == nil && strings.HasPrefix(line, "copy to "):
+			file.Path = strings.TrimPrefix(line, "copy to ")
+		case hunk == nil && strings.HasPrefix(line, "Binary files "):
+			file.Binary = true
+		case hunk == nil && strings.HasPrefix(line, "--- "):
+			if p := strings.TrimPrefix(line, "--- "); p != "/dev/null" && file.Status == "deleted" {
+				file.Path = strings.TrimPrefix(p, "a/")
+			}
+		case hunk == nil && strings.HasPrefix(line, "+++ "):
+			if p := strings.TrimPrefix(line, "+++ "); p != "/dev/null" {
+				file.Path = strings.TrimPrefix(p, "b/")
+			}
+		case strings.HasPrefix(line, "@@"):
+			m := gitHunkHeaderRE.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			h := gitDiffHunk{Header: line, OldLines: 1, NewLines: 1}
+			h.OldStart, _ = strconv.Atoi(m[1])
+			h.NewStart, _ = strconv.Atoi(m[3])
+			if m[2] != "" {
+				h.OldLines, _ = strconv.Atoi(m[2])
+			}
+			if m[4] != "" {
+				h.NewLines, _ = strconv.Atoi(m[4])
+			}
+			if budget > 0 {
+				file.Hunks = append(file.Hunks, h)
+				hunk = &file.Hunks[len(file.Hunks)-1]
+			} else {
+				d.Truncated = true
+				hunk = &h
+			}
+		case hunk != nil && line != "":
+			switch line[0] {
+			case '+':
+				file.Additions++
+			case '-':
+				file.Deletions++
+			}
+			if budget > 0 {
+				hunk.Lines = append(hunk.Lines, line)
+				budget -= len(line) + 1
+			} else {
+				d.Truncated = true
+			}
+		}
+	}
+	for _, f := range d.Files {
+		d.Additions += f.Additions
+		d.Deletions += f.Deletions
+	}
+	return d
+}
+
+type gitCommit struct {
+	Hash    string `json:"hash"`
+	Author  string `json:"author"`
+	Email   string `json:"email"`
+	Date    string `json:"date"`
+	Subject string `json:"subject"`
+}
+
+func (t *GitTool) log(ctx context.Context, repo string, args map[string]any) *ToolResult {
+	limit, err := getInt64Arg(args, "limit", defaultGitLogLimit)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	limit = min(max(limit, 1), maxGitLogLimit)
+	ref, _ := args["ref"].(string)
+	if err := gitArgError(ref); err != nil {
+		return ErrorResult(err.Error())
+	}
+	gitArgs := []string{"log", "-n", strconv.FormatInt(limit, 10), "--format=%H%x1f%an%x1f%ae%x1f%aI%x1f%s%x1e"}
+	if ref != "" {
+		gitArgs = append(gitArgs, ref)
+	}
+	gitArgs = append(append(gitArgs, "--"), stringListArg(args, "paths")...)
+	out, err := t.git(ctx, repo, nil, gitArgs...)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("git log failed: %v", err))
+	}
+	commits := []gitCommit{}
+	for _, rec := range strings.Split(out, "\x1e") {
+		f := strings.Split(strings.TrimSpace(rec), "\x1f")
+		if len(f) != 5 {
+			continue
+		}
+		commits = append(commits, gitCommit{Hash: f[0], Author: f[1], Email: f[2], Date: f[3], Subject: f[4]})
+	}
+	return t.jsonResult(commits)
+}
+
+func (t *GitTool) commit(ctx context.Context, repo string, args map[string]any) *ToolResult {
+	message, _ := args["message"].(string)
+	if strings.TrimSpace(message) == "" {
+		return ErrorResult("message is required for commit")
+	}
+	paths := stringListArg(args, "paths")
+	all, _ := args["all"].(bool)
+
+	if all || len(paths) > 0 {
+		addArgs := append([]string{"add", "-A", "--"}, paths...)
+		if _, err := t.git(ctx, repo, nil, addArgs...); err != nil {
+			return ErrorResult(fmt.Sprintf("git add failed: %v", err))
+		}
+	}
+
+	// Fall back to the configured identity only when the repository and
+	// user config have none, so an existing identity is never overridden.
+	var env []string
+	if name, _ := t.git(ctx, repo, nil, "config", "user.name"); strings.TrimSpace(name) == "" {
+		env = append(env, "GIT_AUTHOR_NAME="+t.authorName, "GIT_COMMITTER_NAME="+t.authorName)
+	}
+	if email, _ := t.git(ctx, repo, nil, "config", "user.email"); strings.TrimSpace(email) == "" {
+		env = append(env, "GIT_AUTHOR_EMAIL="+t.authorEmail, "GIT_COMMITTER_EMAIL="+t.authorEmail)
+	}
+
+	commitArgs := []string{"commit", "--no-verify", "-m", message}
+	if len(paths) > 0 {
+		commitArgs = append(append(commitArgs, "--"), paths...)
+	}
+	out, err := t.git(ctx, repo, env, commitArgs...)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("git commit failed: %v", err))
+	}
+	return t.textResult(out)
+}
+
+type gitBranch struct {
+	Name     string `json:"name"`
+	Commit   string `json:"commit"`
+	Current  bool   `json:"current,omitempty"`
+	Upstream string `json:"upstream,omitempty"`
+}
+
+func (t *GitTool) branch(ctx context.Context, repo string, args map[string]any) *ToolResult {
+	name, _ := args["name"].(string)
+	ref, _ := args["ref"].(string)
+	if err := gitArgError(name, ref); err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	if name == "" {
+		out, err := t.git(ctx, repo, nil, "branch", "--list",
+			"--format=%(refname:short)%1f%(objectname:short)%1f%(HEAD)%1f%(upstream:short)")
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("git branch failed: %v", err))
+		}
+		branches := []gitBranch{}
+		for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+			f := strings.Split(line, "\x1f")
+			if len(f) != 4 {
+				continue
+			}
+			branches = append(branches, gitBranch{Name: f[0], Commit: f[1], Current: f[2] == "*", Upstream: f[3]})
+		}
+		return t.jsonResult(branches)
+	}
+
+	gitArgs := []string{"branch", name}
+	if del, _ := args["delete"].(bool); del {
+		gitArgs = []string{"branch", "-d", name}
+	} else if ref != "" {
+		gitArgs = append(gitArgs, ref)
+	}
+	out, err := t.git(ctx, repo, nil, gitArgs...)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("git branch failed: %v", err))
+	}
+	if strings.TrimSpace(out) == "" {
+		out = fmt.Sprintf("Created branch %s.", name)
+	}
+	return t.textResult(out)
+}
+
+func (t *GitTool) checkout(ctx context.Context, repo string, args map[string]any) *ToolResult {
+	ref, _ := args["ref"].(string)
+	if ref == "" {
+		return ErrorResult("ref is required for checkout")
+	}
+	if err := gitArgError(ref); err != nil {
+		return ErrorResult(err.Error())
+	}
+	gitArgs := []string{"checkout", ref, "--"}
+	if create, _ := args["create"].(bool); create {
+		gitArgs = []string{"checkout", "-b", ref, "--"}
+	}
+	out, err := t.git(ctx, repo, nil, gitArgs...)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("git checkout failed: %v", err))
+	}
+	return t.textResult(out)
+}
+
+func (t *GitTool) stash(ctx context.Context, repo string, args map[string]any) *ToolResult {
+	op, _ := args["stash_action"].(string)
+	if op == "" {
+		op = "push"
+	}
+	ref, _ := args["ref"].(string)
+	if ref != "" && !gitStashRefRE.MatchString(ref) {
+		return ErrorResult(fmt.Sprintf("invalid stash entry %q (want e.g. stash@{0})", ref))
+	}
+
+	var gitArgs []string
+	switch op {
+	case "push":
+		gitArgs = []string{"stash", "push", "--include-untracked"}
+		if message, _ := args["message"].(string); message != "" {
+			gitArgs = append(gitArgs, "-m", message)
+		}
+	case "list":
+		gitArgs = []string{"stash", "list"}
+	case "pop", "apply", "drop":
+		gitArgs = []string{"stash", op}
+		if ref != "" {
+			gitArgs = append(gitArgs, ref)
+		}
+	default:
+		return ErrorResult(fmt.Sprintf("unknown stash_action %q", op))
+	}
+	out, err := t.git(ctx, repo, nil, gitArgs...)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("git stash %s failed: %v", op, err))
+	}
+	if op == "list" && strings.TrimSpace(out) == "" {
+		out = "No stash entries."
+	}
+	return t.textResult(out)
+}
+
+func (t *GitTool) remoteOp(ctx context.Context, repo, action string, args map[string]any) *ToolResult {
+	name, _ := args["remote"].(string)
+	if name == "" && len(t.remotes) == 1 {
+		name = t.remoteNames()[0]
+	}
+	remote, ok := t.remotes[name]
+	if !ok {
+		return ErrorResult(fmt.Sprintf("remote must be one of: %s", strings.Join(t.remoteNames(), ", ")))
+	}
+
+	branch, _ := args["branch"].(string)
+	if branch == "" {
+		out, err := t.git(ctx, repo, nil, "symbolic-ref", "--short", "HEAD")
+		if err != nil {
+			return ErrorResult("HEAD is detached; specify branch")
+		}
+		branch = strings.TrimSpace(out)
+	}
+	if err := gitArgError(branch); err != nil {
+		return ErrorResult(err.Error())
+	}
+	if _, err := t.git(ctx, repo, nil, "check-ref-format", "--branch", branch); err != nil {
+		return ErrorResult(fmt.Sprintf("invalid branch name %q", branch))
+	}
+
+	if keys := t.redirectingConfig(ctx, repo); len(keys) > 0 {
+		return ErrorResult(fmt.Sprintf("refusing to %s: the git config sets %s, which could redirect the connection",
+			action, strings.Join(keys, ", ")))
+	}
+
+	env, secrets, err := t.remoteEnv(remote)
+	if err != nil {
+		// The error never contains the secret values themselves.
+		return ErrorResult(fmt.Sprintf("remote %q: %v", name, err))
+	}
+
+	refspec := "refs/heads/" + branch
+	var gitArgs []string
+	if action == "push" {
+		if !pushAllowed(branch, remote.PushBranches) {
+			return ErrorResult(fmt.Sprintf("pushing branch %q to remote %q is not allowed", branch, name))
+		}
+		gitArgs = []string{"push", "--porcelain", remote.URL, refspec + ":" + refspec}
+	} else {
+		gitArgs = []string{"pull", "--ff-only", "--no-rebase", remote.URL, refspec}
+	}
+	out, err := t.git(ctx, repo, env, gitArgs...)
+	if err != nil {
+		return ErrorResult(redactSecrets(fmt.Sprintf("git %s failed: %v", action, err), secrets))
+	}
+	return t.textResult(redactSecrets(out, secrets))
+}
+
+func pushAllowed(branch string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, branch); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteEnv returns the environment that authenticates git against remote,
+// and the secret values that must be scrubbed from its output. HTTPS
+// credentials become an extra header scoped to the remote URL via
+// GIT_CONFIG_*; SSH always goes through a fixed ssh command so the
+// repository config cannot substitute its own.
+func (t *GitTool) remoteEnv(remote config.GitRemoteConfig) ([]string, []string, error) {
+	sshCommand := "ssh -o BatchMode=yes -o StrictHostKeyChecking=accept-new"
+	if remote.SSHKeyPath != "" {
+		sshCommand += " -o IdentitiesOnly=yes -i '" + strings.ReplaceAll(remote.SSHKeyPath, "'", `'\''`) + "'"
+	}
+	env := []string{"GIT_SSH_COMMAND=" + sshCommand}
+	if remote.Token == "" {
+		return env, nil, nil
+	}
+
+	token, err := t.resolver.Resolve(remote.Token)
+	if err != nil {
+		return nil, nil, err
+	}
+	username := remote.Username
+	if username == "" {
+		username = defaultGitUsername
+	}
+	basic := base64.StdEncoding.EncodeToString([]byte(username + ":" + token))
+	env = append(env,
+		"GIT_CONFIG_COUNT=1",
+		"GIT_CONFIG_KEY_0=http."+remote.URL+".extraHeader",
+		"GIT_CONFIG_VALUE_0=Authorization: Basic "+basic,
+	)
+	return env, []string{token, basic}, nil
+}
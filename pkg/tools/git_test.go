@@ -0,0 +1,327 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func requireGit(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=Test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=Test", "GIT_COMMITTER_EMAIL=test@example.com")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return string(out)
+}
+
+func newTestGitRepo(t *testing.T) string {
+	t.Helper()
+	requireGit(t)
+	dir := t.TempDir()
+	runGit(t, dir, "init", "-q", "-b", "main")
+	os.WriteFile(filepath.Join(dir, "README.md"), []byte("hello\n"), 0o644)
+	runGit(t, dir, "add", "README.md")
+	runGit(t, dir, "commit", "-q", "-m", "initial")
+	return dir
+}
+
+func execGit(t *testing.T, tool *GitTool, args map[string]any) string {
+	t.Helper()
+	result := tool.Execute(context.Background(), args)
+	if result.IsError {
+		t.Fatalf("git %v: %s", args, result.ForLLM)
+	}
+	return result.ForLLM
+}
+
+func TestGitTool_StatusDiffCommitLog(t *testing.T) {
+	// Hide any global identity so the configured fallback is used.
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	repo := newTestGitRepo(t)
+	tool, err := NewGitTool(GitToolOptions{Workspace: repo, Restrict: true, AuthorName: "Bot", AuthorEmail: "bot@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os.WriteFile(filepath.Join(repo, "README.md"), []byte("hello\nworld\n"), 0o644)
+	os.WriteFile(filepath.Join(repo, "new.txt"), []byte("new\n"), 0o644)
+
+	var status gitStatus
+	json.Unmarshal([]byte(execGit(t, tool, map[string]any{"action": "status"})), &status)
+	if status.Branch != "main" || status.Clean || len(status.Unstaged) != 1 ||
+		status.Unstaged[0].Path != "README.md" || status.Unstaged[0].Status != "modified" ||
+		len(status.Untracked) != 1 || status.Untracked[0] != "new.txt" {
+		t.Errorf("unexpected status: %+v", status)
+	}
+
+	var diff gitDiff
+	json.Unmarshal([]byte(execGit(t, tool, map[string]any{"action": "diff"})), &diff)
+	if len(diff.Files) != 1 || diff.Files[0].Path != "README.md" || diff.Additions != 1 || diff.Deletions != 0 ||
+		len(diff.Files[0].Hunks) != 1 || diff.Files[0].Hunks[0].Lines[len(diff.Files[0].Hunks[0].Lines)-1] != "+world" {
+		t.Errorf("unexpected diff: %+v", diff)
+	}
+
+	out := execGit(t, tool, map[string]any{"action": "commit", "message": "add world", "all": true})
+	if !strings.Contains(out, "add world") {
+		t.Errorf("unexpected commit output: %s", out)
+	}
+
+	var commits []gitCommit
+	json.Unmarshal([]byte(execGit(t, tool, map[string]any{"action": "log", "limit": float64(5)})), &commits)
+	if len(commits) != 2 || commits[0].Subject != "add world" || commits[1].Subject != "initial" {
+		t.Fatalf("unexpected log: %+v", commits)
+	}
+	if commits[0].Author != "Bot" || commits[0].Email != "bot@example.com" {
+		t.Errorf("expected the fallback identity without a configured one, got %+v", commits[0])
+	}
+
+	json.Unmarshal([]byte(execGit(t, tool, map[string]any{"action": "status"})), &status)
+	if !status.Clean {
+		t.Errorf("expected clean tree after commit, got %+v", status)
+	}
+}
+
+func TestGitTool_BranchCheckoutStash(t *testing.T) {
+	repo := newTestGitRepo(t)
+	tool, _ := NewGitTool(GitToolOptions{Workspace: repo, Restrict: true})
+
+	execGit(t, tool, map[string]any{"action": "checkout", "ref": "feature", "create": true})
+	var branches []gitBranch
+	json.Unmarshal([]byte(execGit(t, tool, map[string]any{"action": "branch"})), &branches)
+	if len(branches) != 2 || branches[0].Name != "feature" || !branches[0].Current {
+		t.Errorf("unexpected branches: %+v", branches)
+	}
+
+	os.WriteFile(filepath.Join(repo, "README.md"), []byte("changed\n"), 0o644)
+	execGit(t, tool, map[string]any{"action": "stash", "message": "wip"})
+	if data, _ := os.ReadFile(filepath.Join(repo, "README.md")); string(data) != "hello\n" {
+		t.Errorf("expected stash to revert the change, got %q", data)
+	}
+	if out := execGit(t, tool, map[string]any{"action": "stash", "stash_action": "list"}); !strings.Contains(out, "wip") {
+		t.Errorf("unexpected stash list: %s", out)
+	}
+	execGit(t, tool, map[string]any{"action": "stash", "stash_action": "pop"})
+	if data, _ := os.ReadFile(filepath.Join(repo, "README.md")); string(data) != "changed\n" {
+		t.Errorf("expected stash pop to restore the change, got %q", data)
+	}
+}
+
+func TestGitTool_RejectsUnsafeInput(t *testing.T) {
+	repo := newTestGitRepo(t)
+	workspace := filepath.Join(repo, "workspace")
+	os.MkdirAll(workspace, 0o755)
+	tool, _ := NewGitTool(GitToolOptions{Workspace: workspace, Restrict: true})
+
+	for name, args := range map[string]map[string]any{
+		"repo above workspace": {"action": "status"},
+		"outside path":         {"action": "status", "repo": "/"},
+		"push disabled":        {"action": "push"},
+	} {
+		if result := tool.Execute(context.Background(), args); !result.IsError {
+			t.Errorf("%s: expected error, got %s", name, result.ForLLM)
+		}
+	}
+
+	tool, _ = NewGitTool(GitToolOptions{Workspace: repo, Restrict: true})
+	for name, args := range map[string]map[string]any{
+		"option as ref":    {"action": "diff", "ref": "--output=/tmp/pwned"},
+		"option as branch": {"action": "branch", "name": "-D"},
+		"bad stash ref":    {"action": "stash", "stash_action": "drop", "ref": "--all"},
+		"commit message":   {"action": "commit"},
+	} {
+		if result := tool.Execute(context.Background(), args); !result.IsError {
+			t.Errorf("%s: expected error, got %s", name, result.ForLLM)
+		}
+	}
+}
+
+func TestGitTool_HostileRepoConfigRunsNoPrograms(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not installed")
+	}
+	repo := newTestGitRepo(t)
+	marker := filepath.Join(t.TempDir(), "pwned")
+	script := filepath.Join(t.TempDir(), "evil.sh")
+	os.WriteFile(script, []byte("#!/bin/sh\necho \"$0 $*\" >> "+marker+"\ncat\n"), 0o755)
+
+	for _, kv := range [][2]string{
+		{"commit.gpgsign", "true"},
+		{"gpg.program", script},
+		{"core.fsmonitor", script},
+		{"core.sshCommand", script},
+		{"filter.evil.clean", script},
+		{"filter.evil.smudge", script},
+		{"filter.evil.required", "true"},
+		{"diff.evil.textconv", script},
+		{"diff.external", script},
+	} {
+		runGit(t, repo, "config", kv[0], kv[1])
+	}
+	os.WriteFile(filepath.Join(repo, ".gitattributes"), []byte("*.txt filter=evil diff=evil\n"), 0o644)
+	os.WriteFile(filepath.Join(repo, "a.txt"), []byte("a\n"), 0o644)
+
+	tool, err := NewGitTool(GitToolOptions{Workspace: repo, Restrict: true, AuthorName: "Bot", AuthorEmail: "bot@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	execGit(t, tool, map[string]any{"action": "status"})
+	execGit(t, tool, map[string]any{"action": "commit", "message": "add a", "all": true})
+	os.WriteFile(filepath.Join(repo, "a.txt"), []byte("b\n"), 0o644)
+	execGit(t, tool, map[string]any{"action": "diff"})
+	execGit(t, tool, map[string]any{"action": "stash"})
+	execGit(t, tool, map[string]any{"action": "stash", "stash_action": "pop"})
+
+	if data, err := os.ReadFile(marker); err == nil {
+		t.Fatalf("repository config ran a program:\n%s", data)
+	}
+}
+
+func TestGitTool_PushPull(t *testing.T) {
+	repo := newTestGitRepo(t)
+	remoteDir := filepath.Join(t.TempDir(), "remote.git")
+	runGit(t, filepath.Dir(remoteDir), "init", "-q", "--bare", "-b", "main", remoteDir)
+
+	tool, err := NewGitTool(GitToolOptions{
+		Workspace:   repo,
+		Restrict:    true,
+		AllowRemote: true,
+		Remotes: map[string]config.GitRemoteConfig{
+			"origin": {URL: remoteDir, PushBranches: []string{"main", "picoclaw/*"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(tool.Description(), "origin") {
+		t.Errorf("description should list remotes: %s", tool.Description())
+	}
+
+	execGit(t, tool, map[string]any{"action": "push"})
+	if out := runGit(t, remoteDir, "log", "--format=%s", "main"); strings.TrimSpace(out) != "initial" {
+		t.Errorf("expected main to be pushed, remote log: %q", out)
+	}
+
+	execGit(t, tool, map[string]any{"action": "checkout", "ref": "other", "create": true})
+	if result := tool.Execute(context.Background(), map[string]any{"action": "push"}); !result.IsError {
+		t.Error("expected push of a branch outside push_branches to fail")
+	}
+
+	// A commit made elsewhere arrives through a fast-forward pull.
+	clone := filepath.Join(t.TempDir(), "clone")
+	runGit(t, filepath.Dir(clone), "clone", "-q", remoteDir, clone)
+	os.WriteFile(filepath.Join(clone, "remote.txt"), []byte("x\n"), 0o644)
+	runGit(t, clone, "add", "remote.txt")
+	runGit(t, clone, "commit", "-q", "-m", "remote change")
+	runGit(t, clone, "push", "-q", "origin", "main")
+
+	execGit(t, tool, map[string]any{"action": "checkout", "ref": "main"})
+	execGit(t, tool, map[string]any{"action": "pull", "remote": "origin"})
+	if _, err := os.Stat(filepath.Join(repo, "remote.txt")); err != nil {
+		t.Errorf("expected pulled file: %v", err)
+	}
+
+	// A planted config must not redirect the connection or its credentials.
+	for _, kv := range [][2]string{
+		{"url." + clone + ".insteadOf", remoteDir},
+		{"http.https://example.com/.proxy", "http://127.0.0.1:1"},
+		{"http.sslVerify", "false"},
+	} {
+		runGit(t, repo, "config", kv[0], kv[1])
+		if result := tool.Execute(context.Background(), map[string]any{"action": "push"}); !result.IsError {
+			t.Errorf("expected push to be refused with %s set", kv[0])
+		}
+		runGit(t, repo, "config", "--unset", kv[0])
+	}
+}
+
+func TestGitTool_RemoteEnvKeepsTokenOutOfArgs(t *testing.T) {
+	requireGit(t)
+	tool, err := NewGitTool(GitToolOptions{AllowRemote: true, Remotes: map[string]config.GitRemoteConfig{
+		"gh": {URL: "https://github.com/acme/repo.git", Token: "ghp_secret"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, secrets, err := tool.remoteEnv(tool.remotes["gh"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	joined := strings.Join(env, "\n")
+	if !strings.Contains(joined, "GIT_CONFIG_KEY_0=http.https://github.com/acme/repo.git.extraHeader") ||
+		!strings.Contains(joined, "GIT_CONFIG_VALUE_0=Authorization: Basic ") {
+		t.Errorf("unexpected env: %v", env)
+	}
+	if got := redactSecrets("token ghp_secret leaked", secrets); strings.Contains(got, "ghp_secret") {
+		t.Errorf("token not redacted: %q", got)
+	}
+
+	if _, err := NewGitTool(GitToolOptions{AllowRemote: true, Remotes: map[string]config.GitRemoteConfig{
+		"evil": {URL: "ext::sh -c touch% /tmp/pwned"},
+	}}); err == nil {
+		t.Error("expected ext:: remote URL to be rejected")
+	}
+}
+
+func TestParseGitDiff(t *testing.T) {
+	out := strings.Join([]string{
+		"diff --git a/old.go b/new.go",
+		"similarity index 90%",
+		"rename from old.go",
+		"rename to new.go",
+		"--- a/old.go",
+		"+++ b/new.go",
+		"@@ -1,2 +1,2 @@ package x",
+		" package x",
+		"-var a = 1",
+		"+var a = 2",
+		"diff --git a/gone.txt b/gone.txt",
+		"deleted file mode 100644",
+		"--- a/gone.txt",
+		"+++ /dev/null",
+		"@@ -1 +0,0 @@",
+		"--- not a header",
+		"diff --git a/img.png b/img.png",
+		"Binary files a/img.png and b/img.png differ",
+		"",
+	}, "\n")
+
+	d := parseGitDiff(out, 10000)
+	if len(d.Files) != 3 {
+		t.Fatalf("expected 3 files, got %+v", d.Files)
+	}
+	if f := d.Files[0]; f.Path != "new.go" || f.OldPath != "old.go" || f.Status != "renamed" ||
+		f.Additions != 1 || f.Deletions != 1 || f.Hunks[0].OldStart != 1 || f.Hunks[0].NewLines != 2 {
+		t.Errorf("unexpected rename: %+v", f)
+	}
+	if f := d.Files[1]; f.Path != "gone.txt" || f.Status != "deleted" || f.Deletions != 1 {
+		t.Errorf("unexpected deletion: %+v", f)
+	}
+	if f := d.Files[2]; f.Path != "img.png" || !f.Binary {
+		t.Errorf("unexpected binary: %+v", f)
+	}
+
+	d = parseGitDiff(out, 20)
+	if !d.Truncated || d.Additions != 1 || d.Deletions != 2 {
+		t.Errorf("expected truncated diff with full counts, got %+v", d)
+	}
+}
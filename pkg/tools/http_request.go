@@ -0,0 +1,428 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/credential"
+	"github.com/sipeed/picoclaw/pkg/redact"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+const (
+	defaultHTTPRequestTimeout  = 30 * time.Second
+	defaultHTTPRequestMaxBytes = 1024 * 1024
+	maxHTTPRequestBodyBytes    = 1024 * 1024
+)
+
+var httpRequestMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
+
+// HTTPRequestToolOptions configures NewHTTPRequestTool. Zero values select
+// defaults.
+type HTTPRequestToolOptions struct {
+	Timeout              time.Duration
+	MaxResponseBytes     int64
+	Proxy                string
+	PrivateHostWhitelist []string
+	AuthProfiles         map[string]config.HTTPAuthProfile
+	Resolver             *credential.Resolver
+}
+
+// HTTPRequestTool lets the agent call HTTP APIs with any method, headers and
+// body. Credentials come from named auth profiles: the agent picks a profile
+// by name and never sees the secrets, which are also scrubbed from responses.
+type HTTPRequestTool struct {
+	client       *http.Client
+	tokenClient  *http.Client
+	maxBytes     int64
+	whitelist    *privateHostWhitelist
+	profiles     map[string]config.HTTPAuthProfile
+	resolver     *credential.Resolver
+	mu           sync.Mutex
+	tokenSources map[string]oauth2.TokenSource
+}
+
+func NewHTTPRequestTool(opts HTTPRequestToolOptions) (*HTTPRequestTool, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultHTTPRequestTimeout
+	}
+	if opts.MaxResponseBytes <= 0 {
+		opts.MaxResponseBytes = defaultHTTPRequestMaxBytes
+	}
+	if opts.Resolver == nil {
+		opts.Resolver = credential.NewResolver("")
+	}
+	whitelist, err := newPrivateHostWhitelist(opts.PrivateHostWhitelist)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse http_request private host whitelist: %w", err)
+	}
+	for name, profile := range opts.AuthProfiles {
+		if err := validateHTTPAuthProfile(profile); err != nil {
+			return nil, fmt.Errorf("auth profile %q: %w", name, err)
+		}
+	}
+	client, err := newSafeHTTPClient(opts.Proxy, opts.Timeout, whitelist)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client for http_request: %w", err)
+	}
+	// Token endpoints come from the operator's config rather than the model,
+	// so they may live on the local network.
+	tokenClient, err := utils.CreateHTTPClient(opts.Proxy, opts.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client for http_request: %w", err)
+	}
+	return &HTTPRequestTool{
+		client:       client,
+		tokenClient:  tokenClient,
+		maxBytes:     opts.MaxResponseBytes,
+		whitelist:    whitelist,
+		profiles:     opts.AuthProfiles,
+		resolver:     opts.Resolver,
+		tokenSources: make(map[string]oauth2.TokenSource),
+	}, nil
+}
+
+func validateHTTPAuthProfile(p config.HTTPAuthProfile) error {
+	if len(p.AllowedHosts) == 0 {
+		return errors.New("allowed_hosts is required")
+	}
+	switch p.Type {
+	case "bearer":
+		if p.Token == "" {
+			return errors.New("token is required")
+		}
+	case "basic":
+		if p.Username == "" {
+			return errors.New("username is required")
+		}
+	case "header":
+		if p.HeaderName == "" || p.HeaderValue == "" {
+			return errors.New("header_name and header_value are required")
+		}
+	case "oauth_client_credentials":
+		if p.TokenURL == "" || p.ClientID == "" {
+			return errors.New("token_url and client_id are required")
+		}
+	default:
+		return fmt.Errorf("unknown type %q (want bearer, basic, header or oauth_client_credentials)", p.Type)
+	}
+	return nil
+}
+
+func (t *HTTPRequestTool) Name() string {
+	return "http_request"
+}
+
+// UntrustedOutput implements UntrustedSource. Response bodies come from remote servers.
+func (t *HTTPRequestTool) UntrustedOutput() bool {
+	return true
+}
+
+func (t *HTTPRequestTool) Description() string {
+	desc := "Make an HTTP request to call a REST API: choose the method, headers and body, " +
+		"and get back the status, response headers and body. " +
+		"Private and local network addresses cannot be reached. Use web_fetch to read web pages."
+	if len(t.profiles) == 0 {
+		return desc
+	}
+	names := make([]string, 0, len(t.profiles))
+	for name, p := range t.profiles {
+		names = append(names, fmt.Sprintf("%s (%s)", name, strings.Join(p.AllowedHosts, ", ")))
+	}
+	sort.Strings(names)
+	return desc + " Set auth_profile to authenticate with stored credentials; available profiles and their hosts: " +
+		strings.Join(names, "; ") + "."
+}
+
+func (t *HTTPRequestTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"method": map[string]any{
+				"type":        "string",
+				"enum":        httpRequestMethods,
+				"description": "HTTP method (default GET)",
+			},
+			"url": map[string]any{
+				"type":        "string",
+				"description": "Absolute http or https URL",
+			},
+			"headers": map[string]any{
+				"type":                 "object",
+				"additionalProperties": map[string]any{"type": "string"},
+				"description":          "Request headers",
+			},
+			"body": map[string]any{
+				"type":        "string",
+				"description": "Raw request body",
+			},
+			"json": map[string]any{
+				"description": "Request body to send as JSON; sets Content-Type to application/json. Overrides body.",
+			},
+			"auth_profile": map[string]any{
+				"type":        "string",
+				"description": "Name of a configured auth profile whose credentials are added to the request",
+			},
+		},
+		"required": []string{"url"},
+	}
+}
+
+func (t *HTTPRequestTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	method, _ := args["method"].(string)
+	method = strings.ToUpper(strings.TrimSpace(method))
+	if method == "" {
+		method = http.MethodGet
+	}
+	if !slices.Contains(httpRequestMethods, method) {
+		return ErrorResult(fmt.Sprintf("unsupported method %q", method))
+	}
+
+	rawURL, _ := args["url"].(string)
+	target, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return ErrorResult("url must be an absolute http or https URL")
+	}
+	if isObviousPrivateHost(target.Hostname(), t.whitelist) {
+		return ErrorResult("requests to private or local network hosts are not allowed")
+	}
+
+	var body io.Reader
+	contentType := ""
+	if payload, ok := args["json"]; ok && payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("invalid json body: %v", err))
+		}
+		body = bytes.NewReader(data)
+		contentType = "application/json"
+	} else if raw, _ := args["body"].(string); raw != "" {
+		body = strings.NewReader(raw)
+	}
+	if r, ok := body.(interface{ Len() int }); ok && r.Len() > maxHTTPRequestBodyBytes {
+		return ErrorResult(fmt.Sprintf("request body too large (max %d bytes)", maxHTTPRequestBodyBytes))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), body)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to build request: %v", err))
+	}
+	req.Header.Set("User-Agent", userAgent)
+	if headers, ok := args["headers"].(map[string]any); ok {
+		for k, v := range headers {
+			if s, ok := v.(string); ok {
+				req.Header.Set(k, s)
+			}
+		}
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	client := t.client
+	var secrets []string
+	if name, _ := args["auth_profile"].(string); name != "" {
+		profile, ok := t.profiles[name]
+		if !ok {
+			return ErrorResult(fmt.Sprintf("unknown auth profile %q", name))
+		}
+		if !hostAllowed(target.Hostname(), profile.AllowedHosts) {
+			return ErrorResult(fmt.Sprintf("auth profile %q may not be used for host %s", name, target.Hostname()))
+		}
+		if target.Scheme != "https" && !profile.AllowHTTP {
+			return ErrorResult(fmt.Sprintf("auth profile %q is only sent over https", name))
+		}
+		if secrets, err = t.authorize(ctx, req, name, profile); err != nil {
+			// The error never contains the secret values themselves.
+			return ErrorResult(fmt.Sprintf("auth profile %q: %v", name, err)).WithError(err)
+		}
+		client = t.clientForProfile(profile)
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return ErrorResult(redactSecrets(fmt.Sprintf("request failed: %v", err), secrets)).WithError(err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, t.maxBytes+1))
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to read response: %v", err)).WithError(err)
+	}
+	truncated := int64(len(data)) > t.maxBytes
+	if truncated {
+		data = data[:t.maxBytes]
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s\n", resp.Proto, resp.Status)
+	keys := make([]string, 0, len(resp.Header))
+	for k := range resp.Header {
+		if k != "Set-Cookie" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&sb, "%s: %s\n", k, strings.Join(resp.Header[k], ", "))
+	}
+	fmt.Fprintf(&sb, "(%d bytes in %s)\n\n", len(data), time.Since(start).Round(time.Millisecond))
+	if isTextContent(resp.Header.Get("Content-Type"), data) {
+		sb.Write(data)
+		if truncated {
+			fmt.Fprintf(&sb, "\n\n[response truncated at %d bytes]", t.maxBytes)
+		}
+	} else if len(data) > 0 {
+		fmt.Fprintf(&sb, "[binary body omitted: %s]", resp.Header.Get("Content-Type"))
+	}
+
+	result := NewToolResult(redactSecrets(sb.String(), secrets))
+	result.IsError = resp.StatusCode >= 400
+	return result
+}
+
+// authorize adds the profile's credentials to req and returns the secret
+// values that must be scrubbed from anything shown to the model.
+func (t *HTTPRequestTool) authorize(
+	ctx context.Context,
+	req *http.Request,
+	name string,
+	p config.HTTPAuthProfile,
+) ([]string, error) {
+	switch p.Type {
+	case "bearer":
+		token, err := t.resolver.Resolve(p.Token)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		return []string{token}, nil
+	case "basic":
+		password, err := t.resolver.Resolve(p.Password)
+		if err != nil {
+			return nil, err
+		}
+		req.SetBasicAuth(p.Username, password)
+		return []string{password}, nil
+	case "header":
+		value, err := t.resolver.Resolve(p.HeaderValue)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set(p.HeaderName, value)
+		return []string{value}, nil
+	default: // oauth_client_credentials
+		source, secret, err := t.tokenSource(name, p)
+		if err != nil {
+			return nil, err
+		}
+		token, err := source.Token()
+		if err != nil {
+			return nil, fmt.Errorf("failed to obtain access token: %w", err)
+		}
+		token.SetAuthHeader(req)
+		return []string{secret, token.AccessToken}, nil
+	}
+}
+
+// tokenSource returns a cached, self-refreshing token source for an OAuth
+// client-credentials profile.
+func (t *HTTPRequestTool) tokenSource(name string, p config.HTTPAuthProfile) (oauth2.TokenSource, string, error) {
+	secret, err := t.resolver.Resolve(p.ClientSecret)
+	if err != nil {
+		return nil, "", err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if source, ok := t.tokenSources[name]; ok {
+		return source, secret, nil
+	}
+	cc := &clientcredentials.Config{
+		ClientID:     p.ClientID,
+		ClientSecret: secret,
+		TokenURL:     p.TokenURL,
+		Scopes:       p.Scopes,
+	}
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, t.tokenClient)
+	source := cc.TokenSource(ctx)
+	t.tokenSources[name] = source
+	return source, secret, nil
+}
+
+// clientForProfile returns a client that refuses redirects leaving the
+// profile's allowed hosts or https, so credentials in custom headers cannot
+// follow a redirect to another site or onto plain http.
+func (t *HTTPRequestTool) clientForProfile(p config.HTTPAuthProfile) *http.Client {
+	client := *t.client
+	base := t.client.CheckRedirect
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if !hostAllowed(req.URL.Hostname(), p.AllowedHosts) {
+			return fmt.Errorf("redirect to %s is outside the auth profile's allowed hosts", req.URL.Hostname())
+		}
+		if req.URL.Scheme != "https" && !p.AllowHTTP {
+			return fmt.Errorf("redirect to %s leaves https", req.URL.Redacted())
+		}
+		return base(req, via)
+	}
+	return &client
+}
+
+// hostAllowed matches host against hostnames and "*.domain" wildcards.
+func hostAllowed(host string, allowed []string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, pattern := range allowed {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+			continue
+		}
+		if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+func redactSecrets(s string, secrets []string) string {
+	for _, secret := range secrets {
+		if len(secret) >= 4 {
+			s = strings.ReplaceAll(s, secret, redact.Placeholder)
+		}
+	}
+	return s
+}
+
+func isTextContent(contentType string, data []byte) bool {
+	if contentType != "" {
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		if strings.HasPrefix(mediaType, "text/") ||
+			strings.HasSuffix(mediaType, "json") ||
+			strings.HasSuffix(mediaType, "xml") ||
+			mediaType == "application/javascript" ||
+			mediaType == "application/x-www-form-urlencoded" {
+			return true
+		}
+	}
+	return utf8.Valid(data)
+}
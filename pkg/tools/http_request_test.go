@@ -0,0 +1,244 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/credential"
+)
+
+// echoServer replies with the request it received as JSON.
+func echoServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"method":        r.Method,
+			"content_type":  r.Header.Get("Content-Type"),
+			"authorization": r.Header.Get("Authorization"),
+			"api_key":       r.Header.Get("X-Api-Key"),
+			"body":          string(body),
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestHTTPRequestTool(t *testing.T, profiles map[string]config.HTTPAuthProfile) *HTTPRequestTool {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "api.key"), []byte("file-secret-value\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tool, err := NewHTTPRequestTool(HTTPRequestToolOptions{
+		PrivateHostWhitelist: []string{"127.0.0.1"},
+		AuthProfiles:         profiles,
+		Resolver:             credential.NewResolver(dir),
+	})
+	if err != nil {
+		t.Fatalf("NewHTTPRequestTool: %v", err)
+	}
+	return tool
+}
+
+func TestHTTPRequestTool_PostJSON(t *testing.T) {
+	server := echoServer(t)
+	tool := newTestHTTPRequestTool(t, nil)
+
+	result := tool.Execute(context.Background(), map[string]any{
+		"method": "post",
+		"url":    server.URL + "/items",
+		"json":   map[string]any{"name": "widget"},
+	})
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", result.ForLLM)
+	}
+	for _, want := range []string{"200 OK", `"method":"POST"`, `"content_type":"application/json"`, `{\"name\":\"widget\"}`} {
+		if !strings.Contains(result.ForLLM, want) {
+			t.Errorf("result missing %q:\n%s", want, result.ForLLM)
+		}
+	}
+}
+
+func TestHTTPRequestTool_SSRFProtection(t *testing.T) {
+	server := echoServer(t)
+	tool, err := NewHTTPRequestTool(HTTPRequestToolOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, target := range []string{server.URL, "http://localhost/", "http://169.254.169.254/", "file:///etc/passwd"} {
+		result := tool.Execute(context.Background(), map[string]any{"url": target})
+		if !result.IsError {
+			t.Errorf("expected request to %s to be blocked, got: %s", target, result.ForLLM)
+		}
+	}
+}
+
+func TestHTTPRequestTool_AuthProfilesHideSecrets(t *testing.T) {
+	server := echoServer(t)
+	tool := newTestHTTPRequestTool(t, map[string]config.HTTPAuthProfile{
+		"bearer": {Type: "bearer", Token: "plain-bearer-token", AllowedHosts: []string{"127.0.0.1"}, AllowHTTP: true},
+		"key": {
+			Type: "header", HeaderName: "X-Api-Key", HeaderValue: "file://api.key",
+			AllowedHosts: []string{"127.0.0.1"}, AllowHTTP: true,
+		},
+		"other": {Type: "bearer", Token: "other-token", AllowedHosts: []string{"api.example.com"}},
+		"https": {Type: "bearer", Token: "https-only-token", AllowedHosts: []string{"127.0.0.1"}},
+	})
+
+	if strings.Contains(tool.Description(), "plain-bearer-token") || !strings.Contains(tool.Description(), "bearer (127.0.0.1)") {
+		t.Errorf("description should list profiles without secrets: %s", tool.Description())
+	}
+
+	result := tool.Execute(context.Background(), map[string]any{"url": server.URL, "auth_profile": "bearer"})
+	if result.IsError || !strings.Contains(result.ForLLM, `"authorization":"Bearer [REDACTED]"`) {
+		t.Errorf("expected the bearer token to be sent and redacted, got: %s", result.ForLLM)
+	}
+	result = tool.Execute(context.Background(), map[string]any{"url": server.URL, "auth_profile": "key"})
+	if result.IsError || !strings.Contains(result.ForLLM, `"api_key":"[REDACTED]"`) {
+		t.Errorf("expected the file:// header secret to be sent and redacted, got: %s", result.ForLLM)
+	}
+	if strings.Contains(result.ForLLM, "file-secret-value") {
+		t.Errorf("secret leaked: %s", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]any{"url": server.URL, "auth_profile": "other"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "may not be used for host") {
+		t.Errorf("expected profile to be refused for a host it does not allow, got: %s", result.ForLLM)
+	}
+	result = tool.Execute(context.Background(), map[string]any{"url": server.URL, "auth_profile": "https"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "only sent over https") {
+		t.Errorf("expected profile to be refused over plain http, got: %s", result.ForLLM)
+	}
+	result = tool.Execute(context.Background(), map[string]any{"url": server.URL, "auth_profile": "missing"})
+	if !result.IsError {
+		t.Error("expected unknown profile to be rejected")
+	}
+}
+
+func TestHTTPRequestTool_OAuthClientCredentials(t *testing.T) {
+	var tokenRequests atomic.Int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenRequests.Add(1)
+		if user, pass, _ := r.BasicAuth(); user != "client" || pass != "client-secret" {
+			r.ParseForm()
+			if r.PostForm.Get("client_id") != "client" || r.PostForm.Get("client_secret") != "client-secret" {
+				http.Error(w, "bad client", http.StatusUnauthorized)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token":"issued-access-token","token_type":"bearer","expires_in":3600}`)
+	}))
+	defer tokenServer.Close()
+	server := echoServer(t)
+
+	tool := newTestHTTPRequestTool(t, map[string]config.HTTPAuthProfile{
+		"api": {
+			Type:         "oauth_client_credentials",
+			TokenURL:     tokenServer.URL,
+			ClientID:     "client",
+			ClientSecret: "client-secret",
+			AllowedHosts: []string{"127.0.0.1"},
+			AllowHTTP:    true,
+		},
+	})
+
+	for range 2 {
+		result := tool.Execute(context.Background(), map[string]any{"url": server.URL, "auth_profile": "api"})
+		if result.IsError || !strings.Contains(result.ForLLM, `"authorization":"Bearer [REDACTED]"`) {
+			t.Fatalf("unexpected result: %s", result.ForLLM)
+		}
+	}
+	if n := tokenRequests.Load(); n != 1 {
+		t.Errorf("expected the access token to be cached, token endpoint called %d times", n)
+	}
+}
+
+func TestHTTPRequestTool_ProfileRedirectStaysOnAllowedHosts(t *testing.T) {
+	withPrivateWebFetchHostsAllowed(t)
+	server := echoServer(t)
+	redirector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, strings.Replace(server.URL, "127.0.0.1", "localhost", 1), http.StatusFound)
+	}))
+	defer redirector.Close()
+
+	tool := newTestHTTPRequestTool(t, map[string]config.HTTPAuthProfile{
+		"key": {
+			Type: "header", HeaderName: "X-Api-Key", HeaderValue: "header-secret",
+			AllowedHosts: []string{"127.0.0.1"}, AllowHTTP: true,
+		},
+	})
+
+	result := tool.Execute(context.Background(), map[string]any{"url": redirector.URL, "auth_profile": "key"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "outside the auth profile's allowed hosts") {
+		t.Errorf("expected redirect off the allowed hosts to be refused, got: %s", result.ForLLM)
+	}
+	result = tool.Execute(context.Background(), map[string]any{"url": redirector.URL})
+	if result.IsError {
+		t.Errorf("expected unauthenticated redirect to be followed, got: %s", result.ForLLM)
+	}
+}
+
+func TestHTTPRequestTool_ResponseLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(w, strings.Repeat("a", 100))
+	}))
+	defer server.Close()
+
+	tool, err := NewHTTPRequestTool(HTTPRequestToolOptions{
+		MaxResponseBytes:     10,
+		PrivateHostWhitelist: []string{"127.0.0.1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := tool.Execute(context.Background(), map[string]any{"url": server.URL})
+	if !strings.Contains(result.ForLLM, "[response truncated at 10 bytes]") || strings.Contains(result.ForLLM, strings.Repeat("a", 11)) {
+		t.Errorf("expected truncated body, got: %s", result.ForLLM)
+	}
+}
+
+func TestNewHTTPRequestTool_ValidatesProfiles(t *testing.T) {
+	for name, profile := range map[string]config.HTTPAuthProfile{
+		"no hosts":     {Type: "bearer", Token: "t"},
+		"unknown type": {Type: "digest", AllowedHosts: []string{"a.example"}},
+		"no token":     {Type: "bearer", AllowedHosts: []string{"a.example"}},
+	} {
+		_, err := NewHTTPRequestTool(HTTPRequestToolOptions{
+			AuthProfiles: map[string]config.HTTPAuthProfile{"p": profile},
+		})
+		if err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
+
+func TestHostAllowed(t *testing.T) {
+	allowed := []string{"api.example.com", "*.svc.example.com"}
+	for host, want := range map[string]bool{
+		"api.example.com":      true,
+		"API.example.com.":     true,
+		"a.svc.example.com":    true,
+		"svc.example.com":      false,
+		"evil-api.example.com": false,
+		"api.example.com.evil": false,
+	} {
+		if got := hostAllowed(host, allowed); got != want {
+			t.Errorf("hostAllowed(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
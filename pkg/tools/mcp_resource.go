@@ -0,0 +1,195 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// mcpResourceMaxChars bounds the text returned by mcp_read_resource.
+const mcpResourceMaxChars = 50000
+
+// MCPResourceManager defines the manager operations used by the MCP resource tools.
+type MCPResourceManager interface {
+	GetAllResources() map[string][]*mcp.Resource
+	GetAllResourceTemplates() map[string][]*mcp.ResourceTemplate
+	ReadResource(ctx context.Context, serverName, uri string) (*mcp.ReadResourceResult, error)
+}
+
+// MCPListResourcesTool lists the resources offered by connected MCP servers.
+type MCPListResourcesTool struct {
+	manager MCPResourceManager
+}
+
+func NewMCPListResourcesTool(manager MCPResourceManager) *MCPListResourcesTool {
+	return &MCPListResourcesTool{manager: manager}
+}
+
+func (t *MCPListResourcesTool) Name() string {
+	return "mcp_list_resources"
+}
+
+func (t *MCPListResourcesTool) Description() string {
+	return "List resources (documents, files, records) offered by connected MCP servers, " +
+		"with their URIs. Read one with mcp_read_resource."
+}
+
+func (t *MCPListResourcesTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"server": map[string]any{
+				"type":        "string",
+				"description": "Only list resources of this MCP server.",
+			},
+		},
+	}
+}
+
+func (t *MCPListResourcesTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	filter, _ := args["server"].(string)
+	resources := t.manager.GetAllResources()
+	templates := t.manager.GetAllResourceTemplates()
+
+	names := make(map[string]bool)
+	for name := range resources {
+		names[name] = true
+	}
+	for name := range templates {
+		names[name] = true
+	}
+	servers := make([]string, 0, len(names))
+	for name := range names {
+		if filter == "" || name == filter {
+			servers = append(servers, name)
+		}
+	}
+	sort.Strings(servers)
+
+	if len(servers) == 0 {
+		if filter != "" {
+			return NewToolResult(fmt.Sprintf("MCP server %q offers no resources.", filter))
+		}
+		return NewToolResult("No MCP resources available.")
+	}
+
+	var b strings.Builder
+	for i, server := range servers {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "Server: %s\n", server)
+		for _, r := range resources[server] {
+			fmt.Fprintf(&b, "- %s", r.URI)
+			if label := resourceLabel(r.Title, r.Name, r.MIMEType); label != "" {
+				fmt.Fprintf(&b, " (%s)", label)
+			}
+			if r.Description != "" {
+				fmt.Fprintf(&b, ": %s", r.Description)
+			}
+			b.WriteString("\n")
+		}
+		for _, rt := range templates[server] {
+			fmt.Fprintf(&b, "- template %s", rt.URITemplate)
+			if label := resourceLabel(rt.Title, rt.Name, rt.MIMEType); label != "" {
+				fmt.Fprintf(&b, " (%s)", label)
+			}
+			if rt.Description != "" {
+				fmt.Fprintf(&b, ": %s", rt.Description)
+			}
+			b.WriteString("\n")
+		}
+	}
+	return NewToolResult(strings.TrimRight(b.String(), "\n"))
+}
+
+func resourceLabel(title, name, mimeType string) string {
+	label := title
+	if label == "" {
+		label = name
+	}
+	if mimeType != "" {
+		if label != "" {
+			label += ", "
+		}
+		label += mimeType
+	}
+	return label
+}
+
+// MCPReadResourceTool reads a resource from a connected MCP server.
+type MCPReadResourceTool struct {
+	manager MCPResourceManager
+}
+
+func NewMCPReadResourceTool(manager MCPResourceManager) *MCPReadResourceTool {
+	return &MCPReadResourceTool{manager: manager}
+}
+
+func (t *MCPReadResourceTool) Name() string {
+	return "mcp_read_resource"
+}
+
+// UntrustedOutput implements UntrustedSource. Resource contents come from a third-party MCP server.
+func (t *MCPReadResourceTool) UntrustedOutput() bool {
+	return true
+}
+
+func (t *MCPReadResourceTool) Description() string {
+	return "Read a resource from an MCP server by URI. Use mcp_list_resources to find URIs; " +
+		"URIs built from a listed template are accepted too."
+}
+
+func (t *MCPReadResourceTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"server": map[string]any{
+				"type":        "string",
+				"description": "Name of the MCP server offering the resource.",
+			},
+			"uri": map[string]any{
+				"type":        "string",
+				"description": "URI of the resource.",
+			},
+		},
+		"required": []string{"server", "uri"},
+	}
+}
+
+func (t *MCPReadResourceTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	server, _ := args["server"].(string)
+	uri, _ := args["uri"].(string)
+	if server == "" || uri == "" {
+		return ErrorResult("server and uri are required")
+	}
+
+	result, err := t.manager.ReadResource(ctx, server, uri)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to read MCP resource: %v", err)).WithError(err)
+	}
+
+	var parts []string
+	for _, c := range result.Contents {
+		switch {
+		case c.Blob != nil:
+			parts = append(parts, fmt.Sprintf("[Binary content: %s, %s, %d bytes]", c.URI, c.MIMEType, len(c.Blob)))
+		case len(result.Contents) > 1:
+			parts = append(parts, fmt.Sprintf("--- %s ---\n%s", c.URI, c.Text))
+		default:
+			parts = append(parts, c.Text)
+		}
+	}
+	if len(parts) == 0 {
+		return NewToolResult("(empty resource)")
+	}
+
+	text := strings.Join(parts, "\n\n")
+	if len(text) > mcpResourceMaxChars {
+		text = text[:mcpResourceMaxChars] + fmt.Sprintf("\n\n[Truncated: resource is %d characters]", len(text))
+	}
+	return NewToolResult(text)
+}
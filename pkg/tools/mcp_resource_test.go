@@ -0,0 +1,119 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type mockMCPResourceManager struct {
+	resources map[string][]*mcp.Resource
+	templates map[string][]*mcp.ResourceTemplate
+	contents  map[string]*mcp.ReadResourceResult
+}
+
+func (m *mockMCPResourceManager) GetAllResources() map[string][]*mcp.Resource {
+	return m.resources
+}
+
+func (m *mockMCPResourceManager) GetAllResourceTemplates() map[string][]*mcp.ResourceTemplate {
+	return m.templates
+}
+
+func (m *mockMCPResourceManager) ReadResource(
+	ctx context.Context,
+	serverName, uri string,
+) (*mcp.ReadResourceResult, error) {
+	result, ok := m.contents[serverName+" "+uri]
+	if !ok {
+		return nil, errors.New("resource not found")
+	}
+	return result, nil
+}
+
+func newMockResourceManager() *mockMCPResourceManager {
+	return &mockMCPResourceManager{
+		resources: map[string][]*mcp.Resource{
+			"docs": {{URI: "docs://readme", Name: "readme", MIMEType: "text/markdown", Description: "Project readme"}},
+			"db":   {{URI: "db://schema", Name: "schema"}},
+		},
+		templates: map[string][]*mcp.ResourceTemplate{
+			"db": {{URITemplate: "db://tables/{name}", Name: "table"}},
+		},
+		contents: map[string]*mcp.ReadResourceResult{
+			"docs docs://readme": {Contents: []*mcp.ResourceContents{{URI: "docs://readme", Text: "# Readme"}}},
+			"db db://logo": {Contents: []*mcp.ResourceContents{
+				{URI: "db://logo", MIMEType: "image/png", Blob: []byte{1, 2, 3}},
+			}},
+			"docs docs://big": {Contents: []*mcp.ResourceContents{
+				{URI: "docs://big", Text: strings.Repeat("x", mcpResourceMaxChars+10)},
+			}},
+		},
+	}
+}
+
+func TestMCPListResourcesTool(t *testing.T) {
+	tool := NewMCPListResourcesTool(newMockResourceManager())
+
+	result := tool.Execute(context.Background(), map[string]any{})
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", result.ForLLM)
+	}
+	for _, want := range []string{
+		"Server: db",
+		"- db://schema (schema)",
+		"- template db://tables/{name} (table)",
+		"Server: docs",
+		"- docs://readme (readme, text/markdown): Project readme",
+	} {
+		if !strings.Contains(result.ForLLM, want) {
+			t.Errorf("output missing %q:\n%s", want, result.ForLLM)
+		}
+	}
+	if strings.Index(result.ForLLM, "Server: db") > strings.Index(result.ForLLM, "Server: docs") {
+		t.Errorf("servers should be sorted:\n%s", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]any{"server": "docs"})
+	if strings.Contains(result.ForLLM, "db://") {
+		t.Errorf("server filter not applied:\n%s", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]any{"server": "missing"})
+	if !strings.Contains(result.ForLLM, "offers no resources") {
+		t.Errorf("unexpected output for unknown server: %s", result.ForLLM)
+	}
+}
+
+func TestMCPReadResourceTool(t *testing.T) {
+	tool := NewMCPReadResourceTool(newMockResourceManager())
+	ctx := context.Background()
+
+	result := tool.Execute(ctx, map[string]any{"server": "docs", "uri": "docs://readme"})
+	if result.IsError || result.ForLLM != "# Readme" {
+		t.Fatalf("text read = %+v", result)
+	}
+
+	result = tool.Execute(ctx, map[string]any{"server": "db", "uri": "db://logo"})
+	if result.ForLLM != "[Binary content: db://logo, image/png, 3 bytes]" {
+		t.Fatalf("blob read = %q", result.ForLLM)
+	}
+
+	result = tool.Execute(ctx, map[string]any{"server": "docs", "uri": "docs://big"})
+	if !strings.Contains(result.ForLLM, "[Truncated: resource is") {
+		t.Fatalf("large resource was not truncated")
+	}
+
+	result = tool.Execute(ctx, map[string]any{"server": "docs", "uri": "docs://missing"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "resource not found") {
+		t.Fatalf("missing resource = %+v", result)
+	}
+
+	result = tool.Execute(ctx, map[string]any{"server": "docs"})
+	if !result.IsError {
+		t.Fatal("missing uri should be an error")
+	}
+}
@@ -124,6 +124,11 @@ func (t *MCPTool) Name() string {
 	return base + "_" + suffix
 }
 
+// UntrustedOutput implements UntrustedSource. Results come from a third-party MCP server.
+func (t *MCPTool) UntrustedOutput() bool {
+	return true
+}
+
 // Description returns the tool description
 func (t *MCPTool) Description() string {
 	desc := t.tool.Description
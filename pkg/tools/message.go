@@ -21,6 +21,14 @@ func (t *MessageTool) Name() string {
 	return "message"
 }
 
+// HighRisk implements RiskAssessor: only messages to another chat can leak
+// conversation content.
+func (t *MessageTool) HighRisk(ctx context.Context, args map[string]any) bool {
+	channel, _ := args["channel"].(string)
+	chatID, _ := args["chat_id"].(string)
+	return (channel != "" && channel != ToolChannel(ctx)) || (chatID != "" && chatID != ToolChatID(ctx))
+}
+
 func (t *MessageTool) Description() string {
 	return "Send a message to user on a chat channel. Use this when you want to communicate something."
 }
@@ -0,0 +1,170 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/document"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+const defaultReadDocumentMaxChars = 20000
+
+// ReadDocumentTool returns the extracted text of PDF, DOCX, PPTX, XLSX, CSV,
+// EPUB and HTML files, one page range at a time.
+type ReadDocumentTool struct {
+	fs       fileSystem
+	maxChars int
+}
+
+func NewReadDocumentTool(
+	workspace string,
+	restrict bool,
+	maxChars int,
+	allowPaths ...[]*regexp.Regexp,
+) *ReadDocumentTool {
+	var patterns []*regexp.Regexp
+	if len(allowPaths) > 0 {
+		patterns = allowPaths[0]
+	}
+	if maxChars <= 0 {
+		maxChars = defaultReadDocumentMaxChars
+	}
+	return &ReadDocumentTool{
+		fs:       buildFs(workspace, restrict, patterns),
+		maxChars: maxChars,
+	}
+}
+
+func (t *ReadDocumentTool) Name() string {
+	return "read_document"
+}
+
+// UntrustedOutput implements UntrustedSource. Documents are often received from other people.
+func (t *ReadDocumentTool) UntrustedOutput() bool {
+	return true
+}
+
+func (t *ReadDocumentTool) Description() string {
+	return "Extract the text of a PDF, Word (docx), PowerPoint (pptx), Excel (xlsx), CSV, EPUB or HTML file. " +
+		"Returns document metadata and the requested pages (slides, sheets or chapters for those formats). " +
+		"Use `pages` to continue where a previous call stopped."
+}
+
+func (t *ReadDocumentTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"path": map[string]any{
+				"type":        "string",
+				"description": "Path to the document.",
+			},
+			"pages": map[string]any{
+				"type": "string",
+				"description": `Page range to read: "3", "2-5" or "4-" for page 4 onwards. ` +
+					"Defaults to reading from the first page. Output stops at the character limit.",
+			},
+		},
+		"required": []string{"path"},
+	}
+}
+
+func (t *ReadDocumentTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	path, ok := args["path"].(string)
+	if !ok || path == "" {
+		return ErrorResult("path is required")
+	}
+	pages, _ := args["pages"].(string)
+	from, to, err := parsePageRange(pages)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	file, err := t.fs.Open(path)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	defer file.Close()
+	if info, statErr := file.Stat(); statErr == nil && info.Size() > document.MaxFileSize {
+		return ErrorResult(fmt.Sprintf("document is too large (%d bytes, limit %d)", info.Size(), document.MaxFileSize))
+	}
+	data, err := io.ReadAll(io.LimitReader(file, document.MaxFileSize+1))
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to read document: %v", err))
+	}
+	if len(data) > document.MaxFileSize {
+		return ErrorResult(fmt.Sprintf("document is too large (limit %d bytes)", document.MaxFileSize))
+	}
+
+	format := document.Detect(path, "")
+	if format == "" {
+		format = document.Sniff(data)
+	}
+	if format == "" {
+		return ErrorResult("unsupported document format; use read_file for plain text files")
+	}
+
+	doc, err := document.Extract(format, data)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to extract text: %v", err))
+	}
+	total := len(doc.Pages)
+	if total == 0 {
+		return NewToolResult(fmt.Sprintf("%s: %s\n\n[No extractable text.]", path, doc.Summary()))
+	}
+	if from > total {
+		return ErrorResult(fmt.Sprintf("%s %d is out of range: the document has %d %ss", doc.Unit, from, total, doc.Unit))
+	}
+	to = min(to, total)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s: %s\n\n", path, doc.Summary())
+	header := sb.Len()
+	last := from - 1
+	for n := from; n <= to; n++ {
+		page := doc.Text(n, n)
+		if n > from && sb.Len()-header+len(page) > t.maxChars {
+			break
+		}
+		if n > from {
+			sb.WriteString("\n\n")
+		}
+		sb.WriteString(utils.Truncate(page, t.maxChars))
+		last = n
+	}
+	if last < total {
+		fmt.Fprintf(&sb, "\n\n[Showing %ss %d-%d of %d. Call again with pages=\"%d-\" to continue.]",
+			doc.Unit, from, last, total, last+1)
+	}
+	return NewToolResult(sb.String())
+}
+
+// parsePageRange parses "", "N", "N-M" or "N-" into an inclusive 1-based
+// range. An open end is returned as a very large number.
+func parsePageRange(s string) (from, to int, err error) {
+	const open = int(^uint(0) >> 1)
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 1, open, nil
+	}
+	start, end, isRange := strings.Cut(s, "-")
+	from, err = strconv.Atoi(strings.TrimSpace(start))
+	if err != nil || from < 1 {
+		return 0, 0, fmt.Errorf("invalid pages %q: expected e.g. \"3\", \"2-5\" or \"4-\"", s)
+	}
+	switch {
+	case !isRange:
+		return from, from, nil
+	case strings.TrimSpace(end) == "":
+		return from, open, nil
+	}
+	to, err = strconv.Atoi(strings.TrimSpace(end))
+	if err != nil || to < from {
+		return 0, 0, fmt.Errorf("invalid pages %q: expected e.g. \"3\", \"2-5\" or \"4-\"", s)
+	}
+	return from, to, nil
+}
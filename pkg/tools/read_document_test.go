@@ -0,0 +1,74 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestCSV(t *testing.T, dir, name string, rows int) string {
+	t.Helper()
+	var sb strings.Builder
+	sb.WriteString("id,description\n")
+	for i := 1; i <= rows; i++ {
+		fmt.Fprintf(&sb, "%d,%s\n", i, strings.Repeat("x", 60))
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(sb.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadDocumentTool_Pages(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTestCSV(t, dir, "data.csv", 150) // ~10k chars, 3 pages
+	tool := NewReadDocumentTool(dir, true, 5000)
+	ctx := context.Background()
+
+	result := tool.Execute(ctx, map[string]any{"path": path})
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", result.ForLLM)
+	}
+	for _, want := range []string{"CSV, 3 pages", "--- page 1 ---", "id | description", `[Showing pages 1-1 of 3. Call again with pages="2-" to continue.]`} {
+		if !strings.Contains(result.ForLLM, want) {
+			t.Errorf("result missing %q:\n%s", want, result.ForLLM[:200])
+		}
+	}
+
+	result = tool.Execute(ctx, map[string]any{"path": "data.csv", "pages": "3"})
+	if result.IsError || !strings.Contains(result.ForLLM, "--- page 3 ---") || strings.Contains(result.ForLLM, "--- page 2 ---") {
+		t.Errorf("expected only page 3, got: %s", result.ForLLM)
+	}
+	if !strings.Contains(result.ForLLM, "150 | ") || strings.Contains(result.ForLLM, "[Showing") {
+		t.Errorf("last page should end the document without a continuation hint: %s", result.ForLLM)
+	}
+
+	for _, pages := range []string{"0", "3-2", "abc", "9"} {
+		result = tool.Execute(ctx, map[string]any{"path": path, "pages": pages})
+		if !result.IsError {
+			t.Errorf("pages=%q: expected an error, got: %s", pages, result.ForLLM)
+		}
+	}
+}
+
+func TestReadDocumentTool_RejectsUnsupportedAndOutsidePaths(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("plain"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	outside := writeTestCSV(t, t.TempDir(), "secret.csv", 1)
+	tool := NewReadDocumentTool(dir, true, 0)
+
+	result := tool.Execute(context.Background(), map[string]any{"path": "notes.txt"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "unsupported document format") {
+		t.Errorf("expected unsupported format error, got: %s", result.ForLLM)
+	}
+	result = tool.Execute(context.Background(), map[string]any{"path": outside})
+	if !result.IsError {
+		t.Errorf("expected path outside the workspace to be refused, got: %s", result.ForLLM)
+	}
+}
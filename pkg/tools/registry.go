@@ -2,14 +2,19 @@ package tools
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/sipeed/picoclaw/pkg/audit"
 	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/permissions"
 	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/redact"
+	"github.com/sipeed/picoclaw/pkg/utils"
 )
 
 type ToolEntry struct {
@@ -21,7 +26,8 @@ type ToolEntry struct {
 type ToolRegistry struct {
 	tools   map[string]*ToolEntry
 	mu      sync.RWMutex
-	version atomic.Uint64 // incremented on Register/RegisterHidden for cache invalidation
+	version atomic.Uint64 // incremented on Register/RegisterHidden/Unregister for cache invalidation
+	trust   atomic.Pointer[TrustPolicy]
 }
 
 func NewToolRegistry() *ToolRegistry {
@@ -65,6 +71,23 @@ func (r *ToolRegistry) RegisterHidden(tool Tool) {
 	logger.DebugCF("tools", "Registered hidden tool", map[string]any{"name": name})
 }
 
+// Unregister removes the named tools. Unknown names are ignored.
+func (r *ToolRegistry) Unregister(names ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	removed := 0
+	for _, name := range names {
+		if _, exists := r.tools[name]; exists {
+			delete(r.tools, name)
+			removed++
+		}
+	}
+	if removed > 0 {
+		r.version.Add(1)
+		logger.DebugCF("tools", "Unregistered tools", map[string]any{"count": removed})
+	}
+}
+
 // PromoteTools atomically sets the TTL for multiple non-core tools.
 // This prevents a concurrent TickTTL from decrementing between promotions.
 func (r *ToolRegistry) PromoteTools(names []string, ttl int) {
@@ -136,6 +159,17 @@ func (r *ToolRegistry) SnapshotHiddenTools() HiddenToolSnapshot {
 	}
 }
 
+// SetTrustPolicy installs the prompt-injection policy applied to tool calls.
+// A nil policy disables it.
+func (r *ToolRegistry) SetTrustPolicy(p *TrustPolicy) {
+	r.trust.Store(p)
+}
+
+// TrustPolicy returns the installed trust policy, or nil.
+func (r *ToolRegistry) TrustPolicy() *TrustPolicy {
+	return r.trust.Load()
+}
+
 func (r *ToolRegistry) Get(name string) (Tool, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -180,10 +214,29 @@ func (r *ToolRegistry) ExecuteWithContext(
 		return ErrorResult(fmt.Sprintf("tool %q not found", name)).WithError(fmt.Errorf("tool not found"))
 	}
 
+	// The requester's role hides tools from the LLM; refuse calls to them
+	// in case the model names one anyway.
+	if role := permissions.RoleFrom(ctx); !role.AllowsTool(name) {
+		logger.WarnCF("tool", "Tool call denied by role",
+			map[string]any{
+				"tool": name,
+				"role": role.Name,
+			})
+		recordToolCall(ctx, name, args, "denied", 0)
+		return ErrorResult(fmt.Sprintf("permission denied: the current user may not use tool %q", name)).
+			WithError(fmt.Errorf("tool not permitted"))
+	}
+
 	// Inject channel/chatID into ctx so tools read them via ToolChannel(ctx)/ToolChatID(ctx).
 	// Always inject — tools validate what they require.
 	ctx = WithToolContext(ctx, channel, chatID)
 
+	trust := r.trust.Load()
+	if held := trust.checkCall(ctx, tool, args); held != nil {
+		recordToolCall(ctx, name, args, "held", 0)
+		return held
+	}
+
 	// If tool implements AsyncExecutor and callback is provided, use ExecuteAsync.
 	// The callback is a call parameter, not mutable state on the tool instance.
 	var result *ToolResult
@@ -193,11 +246,25 @@ func (r *ToolRegistry) ExecuteWithContext(
 			map[string]any{
 				"tool": name,
 			})
-		result = asyncExec.ExecuteAsync(ctx, args, asyncCallback)
+		result = asyncExec.ExecuteAsync(ctx, args, func(cbCtx context.Context, res *ToolResult) {
+			redactResult(res)
+			trust.applyOutput(ctx, tool, res)
+			asyncCallback(cbCtx, res)
+		})
 	} else {
 		result = tool.Execute(ctx, args)
 	}
 	duration := time.Since(start)
+	redactResult(result)
+	trust.applyOutput(ctx, tool, result)
+
+	status := "ok"
+	if result.IsError {
+		status = "error"
+	} else if result.Async {
+		status = "async"
+	}
+	recordToolCall(ctx, name, args, status, duration)
 
 	// Log based on result type
 	if result.IsError {
@@ -225,6 +292,62 @@ func (r *ToolRegistry) ExecuteWithContext(
 	return result
 }
 
+// maxAuditArgsLen bounds the tool arguments kept in the audit log.
+const maxAuditArgsLen = 2000
+
+// recordToolCall adds a tool call to the audit log, with secrets in its
+// arguments masked.
+func recordToolCall(ctx context.Context, name string, args map[string]any, status string, duration time.Duration) {
+	if !audit.Enabled() {
+		return
+	}
+	argsJSON, _ := json.Marshal(args)
+	audit.Record(ctx, audit.TypeToolCall, map[string]any{
+		"tool":        name,
+		"args":        utils.Truncate(redact.String(string(argsJSON)), maxAuditArgsLen),
+		"status":      status,
+		"duration_ms": duration.Milliseconds(),
+	})
+}
+
+// redactResult masks secrets in the text the LLM sees, which is also what
+// ends up in session history.
+func redactResult(result *ToolResult) {
+	if result != nil {
+		result.ForLLM = redact.String(result.ForLLM)
+	}
+}
+
+// rejectRedacted refuses file content that adds redaction placeholders to
+// original, the text it replaces. Secrets are masked in what the LLM reads,
+// so content copied from read_file would otherwise overwrite the real values
+// on disk; placeholders already in original are literal text and may stay.
+func rejectRedacted(field, text, original string) *ToolResult {
+	if redact.Default() == nil || redact.CountPlaceholders(text) <= redact.CountPlaceholders(original) {
+		return nil
+	}
+	return ErrorResult(fmt.Sprintf(
+		"%s contains %s: secrets are masked in tool output, so writing it would replace the real value. "+
+			"Leave the lines holding secrets unchanged and edit around them.", field, redact.Placeholder))
+}
+
+// Close releases resources held by tools that implement Close, such as the
+// browser and running shell sessions.
+func (r *ToolRegistry) Close() {
+	r.mu.RLock()
+	var closers []interface{ Close() }
+	for _, name := range r.sortedToolNames() {
+		if c, ok := r.tools[name].Tool.(interface{ Close() }); ok {
+			closers = append(closers, c)
+		}
+	}
+	r.mu.RUnlock()
+
+	for _, c := range closers {
+		c.Close()
+	}
+}
+
 // sortedToolNames returns tool names in sorted order for deterministic iteration.
 // This is critical for KV cache stability: non-deterministic map iteration would
 // produce different system prompts and tool definitions on each call, invalidating
@@ -257,11 +380,13 @@ func (r *ToolRegistry) GetDefinitions() []map[string]any {
 }
 
 // ToProviderDefs converts tool definitions to provider-compatible format.
-// This is the format expected by LLM provider APIs.
-func (r *ToolRegistry) ToProviderDefs() []providers.ToolDefinition {
+// This is the format expected by LLM provider APIs. Tools the requester's
+// role (see permissions.WithRole) may not use are left out.
+func (r *ToolRegistry) ToProviderDefs(ctx context.Context) []providers.ToolDefinition {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
+	role := permissions.RoleFrom(ctx)
 	sorted := r.sortedToolNames()
 	definitions := make([]providers.ToolDefinition, 0, len(sorted))
 	for _, name := range sorted {
@@ -270,6 +395,9 @@ func (r *ToolRegistry) ToProviderDefs() []providers.ToolDefinition {
 		if !entry.IsCore && entry.TTL <= 0 {
 			continue
 		}
+		if !role.AllowsTool(name) {
+			continue
+		}
 
 		schema := ToolToSchema(entry.Tool)
 
@@ -2,11 +2,17 @@ package tools
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
 
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/permissions"
 	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/redact"
 )
 
 // --- mock types ---
@@ -104,6 +110,30 @@ func TestToolRegistry_RegisterOverwrite(t *testing.T) {
 	}
 }
 
+func TestToolRegistry_Unregister(t *testing.T) {
+	r := NewToolRegistry()
+	r.Register(newMockTool("keep", "kept"))
+	r.RegisterHidden(newMockTool("drop", "dropped"))
+	before := r.Version()
+
+	r.Unregister("drop", "missing")
+	if _, ok := r.tools["drop"]; ok {
+		t.Fatal("expected drop to be removed")
+	}
+	if _, ok := r.Get("keep"); !ok {
+		t.Fatal("expected keep to remain")
+	}
+	if r.Version() == before {
+		t.Fatal("expected version to change after unregister")
+	}
+
+	before = r.Version()
+	r.Unregister("missing")
+	if r.Version() != before {
+		t.Fatal("unregistering unknown tools should not change the version")
+	}
+}
+
 func TestToolRegistry_Execute_Success(t *testing.T) {
 	r := NewToolRegistry()
 	r.Register(&mockRegistryTool{
@@ -122,6 +152,94 @@ func TestToolRegistry_Execute_Success(t *testing.T) {
 	}
 }
 
+func TestToolRegistry_Execute_RedactsForLLM(t *testing.T) {
+	redact.SetDefault(redact.New(redact.Options{Secrets: []string{"tool-output-secret"}}))
+	defer redact.SetDefault(nil)
+
+	r := NewToolRegistry()
+	r.Register(&mockRegistryTool{
+		name:   "leak",
+		desc:   "prints a secret",
+		params: map[string]any{},
+		result: SilentResult("value=tool-output-secret"),
+	})
+
+	result := r.Execute(context.Background(), "leak", nil)
+	if result.ForLLM != "value="+redact.Placeholder {
+		t.Errorf("expected redacted ForLLM, got %q", result.ForLLM)
+	}
+}
+
+func TestToolRegistry_RedactedReadCannotBeWrittenBack(t *testing.T) {
+	redact.SetDefault(redact.New(redact.Options{Secrets: []string{"file-content-secret"}}))
+	defer redact.SetDefault(nil)
+
+	dir := t.TempDir()
+	r := NewToolRegistry()
+	r.Register(NewReadFileTool(dir, true, MaxReadFileSize))
+	r.Register(NewEditFileTool(dir, true))
+	r.Register(NewWriteFileTool(dir, true))
+	r.Register(NewApplyPatchTool(dir, true))
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte("TOKEN=file-content-secret\nDEBUG=0\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	read := r.Execute(context.Background(), "read_file", map[string]any{"path": ".env"})
+	if !strings.Contains(read.ForLLM, "TOKEN="+redact.Placeholder) {
+		t.Fatalf("read_file ForLLM = %q, want the secret masked", read.ForLLM)
+	}
+	calls := map[string]map[string]any{
+		"edit_file":  {"path": ".env", "old_text": "DEBUG=0", "new_text": "TOKEN=" + redact.Placeholder + "\nDEBUG=1"},
+		"write_file": {"path": ".env", "content": "TOKEN=" + redact.Placeholder + "\nDEBUG=1\n"},
+		"apply_patch": {"patch": "*** Begin Patch\n*** Update File: .env\n@@\n-DEBUG=0\n+TOKEN=" +
+			redact.Placeholder + "\n*** End Patch"},
+	}
+	for name, args := range calls {
+		if result := r.Execute(context.Background(), name, args); !result.IsError {
+			t.Errorf("%s wrote the redaction placeholder: %q", name, result.ForLLM)
+		}
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, ".env"))
+	if string(data) != "TOKEN=file-content-secret\nDEBUG=0\n" {
+		t.Errorf("file changed to %q", data)
+	}
+}
+
+func TestToolRegistry_LiteralPlaceholderCanBeKept(t *testing.T) {
+	redact.SetDefault(redact.New(redact.Options{Secrets: []string{"file-content-secret"}}))
+	defer redact.SetDefault(nil)
+
+	dir := t.TempDir()
+	r := NewToolRegistry()
+	r.Register(NewEditFileTool(dir, true))
+	r.Register(NewWriteFileTool(dir, true))
+	r.Register(NewApplyPatchTool(dir, true))
+	doc := "Logs show " + redact.Placeholder + " for secrets.\n"
+	if err := os.WriteFile(filepath.Join(dir, "doc.md"), []byte(doc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := []struct {
+		name string
+		args map[string]any
+	}{
+		{"edit_file", map[string]any{"path": "doc.md", "old_text": "Logs show " + redact.Placeholder,
+			"new_text": "Tool output shows " + redact.Placeholder}},
+		{"apply_patch", map[string]any{"patch": "*** Begin Patch\n*** Update File: doc.md\n@@\n-Tool output shows " +
+			redact.Placeholder + " for secrets.\n+Output shows " + redact.Placeholder + " for secrets.\n*** End Patch"}},
+		{"write_file", map[string]any{"path": "doc.md", "content": "Output shows " + redact.Placeholder + " instead.\n"}},
+	}
+	for _, c := range calls {
+		if result := r.Execute(context.Background(), c.name, c.args); result.IsError {
+			t.Errorf("%s refused a placeholder already in the file: %s", c.name, result.ForLLM)
+		}
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "doc.md"))
+	if string(data) != "Output shows "+redact.Placeholder+" instead.\n" {
+		t.Errorf("file = %q", data)
+	}
+}
+
 func TestToolRegistry_Execute_NotFound(t *testing.T) {
 	r := NewToolRegistry()
 	result := r.Execute(context.Background(), "missing", nil)
@@ -235,7 +353,7 @@ func TestToolRegistry_ToProviderDefs(t *testing.T) {
 		result: SilentResult("ok"),
 	})
 
-	defs := r.ToProviderDefs()
+	defs := r.ToProviderDefs(context.Background())
 	if len(defs) != 1 {
 		t.Fatalf("expected 1 provider def, got %d", len(defs))
 	}
@@ -260,6 +378,35 @@ func TestToolRegistry_ToProviderDefs(t *testing.T) {
 	}
 }
 
+func TestToolRegistry_RoleFiltersTools(t *testing.T) {
+	r := NewToolRegistry()
+	for _, name := range []string{"exec", "web_search"} {
+		r.Register(&mockRegistryTool{name: name, desc: name, params: map[string]any{}, result: SilentResult("ok")})
+	}
+	policy := permissions.NewPolicy(config.PermissionsConfig{
+		Enabled:     true,
+		DefaultRole: "guest",
+		Roles:       map[string]config.RoleConfig{"guest": {Tools: []string{"web_search"}}},
+	})
+	ctx := permissions.WithRole(context.Background(), policy.RoleFor(bus.SenderInfo{CanonicalID: "telegram:1"}))
+
+	defs := r.ToProviderDefs(ctx)
+	if len(defs) != 1 || defs[0].Function.Name != "web_search" {
+		t.Fatalf("expected only web_search, got %+v", defs)
+	}
+	if all := r.ToProviderDefs(context.Background()); len(all) != 2 {
+		t.Fatalf("expected both tools without a role, got %d", len(all))
+	}
+
+	result := r.Execute(ctx, "exec", nil)
+	if !result.IsError || !strings.Contains(result.ForLLM, "permission denied") {
+		t.Fatalf("expected denied exec, got %+v", result)
+	}
+	if result := r.Execute(ctx, "web_search", nil); result.IsError {
+		t.Fatalf("web_search should run, got %+v", result)
+	}
+}
+
 func TestToolRegistry_List(t *testing.T) {
 	r := NewToolRegistry()
 	r.Register(newMockTool("x", ""))
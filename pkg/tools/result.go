@@ -34,6 +34,11 @@ type ToolResult struct {
 	// Media contains media store refs produced by this tool.
 	// When non-empty, the agent will publish these as OutboundMediaMessage.
 	Media []string `json:"media,omitempty"`
+
+	// LLMMedia contains media store refs of images the LLM should look at
+	// on its next iteration, such as screenshots. They are not sent to the
+	// user unless also listed in Media.
+	LLMMedia []string `json:"llm_media,omitempty"`
 }
 
 // NewToolResult creates a basic ToolResult with content for the LLM.
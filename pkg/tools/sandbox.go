@@ -0,0 +1,50 @@
+package tools
+
+import (
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// sandboxInitArg0 is the argv[0] the exec sandbox uses when it re-executes the
+// current binary to set up namespaces before running the shell.
+const sandboxInitArg0 = "picoclaw-sandbox-init"
+
+// sandboxProxyArg0 is the argv[0] of the helper that forwards
+// sandboxProxyAddr to the egress proxy inside an isolated network.
+const sandboxProxyArg0 = "picoclaw-sandbox-proxy"
+
+// sandboxProxyAddr is where sandboxed commands find the egress proxy when
+// the sandbox has no network of its own.
+const sandboxProxyAddr = "127.0.0.1:3128"
+
+// execSandbox runs exec commands isolated from the host. The workspace is the
+// only writable path; see sandbox_linux.go for the implementation.
+type execSandbox struct {
+	workspace string
+	cfg       config.ExecSandboxConfig
+}
+
+// sandboxSpec is handed to the sandbox init process as JSON in argv[1].
+type sandboxSpec struct {
+	Workspace      string `json:"workspace"`
+	Dir            string `json:"dir"`
+	Network        bool   `json:"network"`
+	CPUTimeSeconds uint64 `json:"cpu_time_seconds,omitempty"`
+	// ProxySocket is the egress proxy's unix socket, forwarded to
+	// sandboxProxyAddr inside the isolated network.
+	ProxySocket string   `json:"proxy_socket,omitempty"`
+	Command     []string `json:"command,omitempty"` // empty: only verify setup
+}
+
+// newExecSandbox returns a sandbox confining commands to workspace, or an
+// error when the host cannot provide one.
+func newExecSandbox(workspace string, cfg config.ExecSandboxConfig) (*execSandbox, error) {
+	if err := probeSandbox(); err != nil {
+		return nil, err
+	}
+	if cfg.CgroupParent == "" && (cfg.MemoryMB > 0 || cfg.CPUs > 0 || cfg.MaxProcesses > 0) {
+		logger.WarnCF("tool", "Exec sandbox memory, CPU and process limits need cgroup_parent and are not enforced",
+			map[string]any{"memory_mb": cfg.MemoryMB, "cpus": cfg.CPUs, "max_processes": cfg.MaxProcesses})
+	}
+	return &execSandbox{workspace: workspace, cfg: cfg}, nil
+}
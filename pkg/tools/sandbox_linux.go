@@ -0,0 +1,399 @@
+//go:build linux
+
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/sipeed/picoclaw/pkg/egress"
+)
+
+// sandboxTmpSize bounds the private /tmp each sandboxed command gets.
+const sandboxTmpSize = "64m"
+
+// sandboxProbe runs the namespace setup once without a command so a host
+// lacking unprivileged user namespaces or mount_setattr (Linux 5.12+) is
+// detected when the tool is built rather than on the first command.
+var sandboxProbe = sync.OnceValue(func() error {
+	s := &execSandbox{workspace: os.TempDir()}
+	cmd, cleanup, err := s.command(context.Background(), "", nil)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("namespace setup failed: %v: %s", err, out)
+	}
+	return nil
+})
+
+// init turns the process into the sandbox init when it was re-executed by
+// execSandbox.command, or into its proxy forwarder. It never returns in
+// those cases.
+func init() {
+	if len(os.Args) < 2 {
+		return
+	}
+	if os.Args[0] == sandboxProxyArg0 {
+		if err := runSandboxProxy(os.Args[1]); err != nil {
+			fmt.Fprintf(os.Stderr, "sandbox proxy: %v\n", err)
+			os.Exit(126)
+		}
+		os.Exit(0)
+	}
+	if os.Args[0] != sandboxInitArg0 {
+		return
+	}
+	// Capabilities and the final exec apply to the calling thread.
+	runtime.LockOSThread()
+	if err := runSandboxInit(os.Args[1]); err != nil {
+		fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+		os.Exit(126)
+	}
+	os.Exit(0)
+}
+
+func probeSandbox() error {
+	return sandboxProbe()
+}
+
+// command builds a Cmd that re-executes the current binary inside fresh
+// user, mount, pid, ipc and uts namespaces (plus network unless enabled),
+// which then sets up the filesystem and execs argv in dir. When proxy is
+// set the command is pointed at it; without a network of its own the proxy
+// is the command's only way out. The returned cleanup must be called after
+// the command has exited.
+func (s *execSandbox) command(
+	ctx context.Context,
+	dir string,
+	proxy *egress.Proxy,
+	argv ...string,
+) (*exec.Cmd, func(), error) {
+	if s.workspace == "" || filepath.Clean(s.workspace) == "/" {
+		return nil, nil, fmt.Errorf("sandbox workspace must be a directory below /")
+	}
+
+	spec := sandboxSpec{
+		Workspace: s.workspace,
+		Dir:       dir,
+		Network:   s.cfg.Network,
+		Command:   argv,
+	}
+	if s.cfg.CPUTimeSeconds > 0 {
+		spec.CPUTimeSeconds = uint64(s.cfg.CPUTimeSeconds)
+	}
+	if proxy != nil && !s.cfg.Network {
+		spec.ProxySocket = proxy.SocketPath()
+	}
+
+	cleanup := func() {}
+	cgroupFD := -1
+	if s.cfg.CgroupParent != "" {
+		fd, remove, err := s.createCgroup()
+		if err != nil {
+			return nil, nil, err
+		}
+		cgroupFD, cleanup = fd, remove
+	}
+
+	data, err := json.Marshal(spec)
+	if err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("encode sandbox spec: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, "/proc/self/exe", string(data))
+	cmd.Args[0] = sandboxInitArg0
+	if dir != "" {
+		cmd.Dir = dir
+	}
+	if proxy != nil && s.cfg.Network {
+		cmd.Env = append(os.Environ(), egress.ProxyEnv("http://"+proxy.Addr())...)
+	}
+
+	flags := uintptr(unix.CLONE_NEWUSER | unix.CLONE_NEWNS | unix.CLONE_NEWPID |
+		unix.CLONE_NEWIPC | unix.CLONE_NEWUTS)
+	if !s.cfg.Network {
+		flags |= unix.CLONE_NEWNET
+	}
+	// CAP_SYS_ADMIN in the new user namespace is needed for the mounts and
+	// CAP_NET_ADMIN to bring up loopback for the proxy; the init drops them
+	// before running the command.
+	caps := []uintptr{unix.CAP_SYS_ADMIN}
+	if spec.ProxySocket != "" {
+		caps = append(caps, unix.CAP_NET_ADMIN)
+	}
+	uid, gid := os.Getuid(), os.Getgid()
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags:  flags,
+		UidMappings: []syscall.SysProcIDMap{{ContainerID: sandboxID(uid), HostID: uid, Size: 1}},
+		GidMappings: []syscall.SysProcIDMap{{ContainerID: sandboxID(gid), HostID: gid, Size: 1}},
+		AmbientCaps: caps,
+	}
+	if cgroupFD >= 0 {
+		cmd.SysProcAttr.UseCgroupFD = true
+		cmd.SysProcAttr.CgroupFD = cgroupFD
+	}
+	return cmd, cleanup, nil
+}
+
+// sandboxID maps a host ID into the sandbox. Root is mapped to nobody so
+// the command never runs with root's implicit capabilities.
+func sandboxID(id int) int {
+	if id == 0 {
+		return 65534
+	}
+	return id
+}
+
+// createCgroup creates a per-command cgroup below CgroupParent with the
+// configured limits and returns a directory fd for CLONE_INTO_CGROUP.
+func (s *execSandbox) createCgroup() (int, func(), error) {
+	dir, err := os.MkdirTemp(s.cfg.CgroupParent, "exec-")
+	if err != nil {
+		return -1, nil, fmt.Errorf("create sandbox cgroup: %w", err)
+	}
+
+	limits := map[string]string{}
+	if s.cfg.MemoryMB > 0 {
+		limits["memory.max"] = strconv.FormatInt(int64(s.cfg.MemoryMB)<<20, 10)
+	}
+	if s.cfg.CPUs > 0 {
+		const period = 100000
+		limits["cpu.max"] = fmt.Sprintf("%d %d", int64(s.cfg.CPUs*period), period)
+	}
+	if s.cfg.MaxProcesses > 0 {
+		limits["pids.max"] = strconv.Itoa(s.cfg.MaxProcesses)
+	}
+	for name, value := range limits {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(value), 0o644); err != nil {
+			os.Remove(dir)
+			return -1, nil, fmt.Errorf("set sandbox cgroup %s: %w", name, err)
+		}
+	}
+
+	fd, err := unix.Open(dir, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		os.Remove(dir)
+		return -1, nil, fmt.Errorf("open sandbox cgroup: %w", err)
+	}
+	return fd, func() {
+		unix.Close(fd)
+		os.Remove(dir)
+	}, nil
+}
+
+// runSandboxInit runs as pid 1 of the new namespaces. It makes every mount
+// read-only, gives the command a private /tmp and /proc, bind-mounts the
+// workspace read-write, applies rlimits, drops capabilities and execs the
+// command.
+func runSandboxInit(raw string) error {
+	var spec sandboxSpec
+	if err := json.Unmarshal([]byte(raw), &spec); err != nil {
+		return fmt.Errorf("decode spec: %w", err)
+	}
+
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("make mounts private: %w", err)
+	}
+
+	// Hold the workspace open: a workspace below /tmp is hidden by the
+	// private /tmp and is bound back from this descriptor.
+	wsFD, err := unix.Open(spec.Workspace, unix.O_PATH|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("open workspace: %w", err)
+	}
+	defer unix.Close(wsFD)
+
+	// The proxy socket usually lives below /tmp too.
+	proxyDirFD := -1
+	if spec.ProxySocket != "" {
+		proxyDirFD, err = unix.Open(filepath.Dir(spec.ProxySocket), unix.O_PATH|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+		if err != nil {
+			return fmt.Errorf("open proxy socket directory: %w", err)
+		}
+		defer unix.Close(proxyDirFD)
+	}
+
+	readOnly := &unix.MountAttr{Attr_set: unix.MOUNT_ATTR_RDONLY}
+	if err := unix.MountSetattr(unix.AT_FDCWD, "/", unix.AT_RECURSIVE, readOnly); err != nil {
+		return fmt.Errorf("make filesystem read-only: %w", err)
+	}
+
+	if err := unix.Mount("tmpfs", "/tmp", "tmpfs", unix.MS_NOSUID|unix.MS_NODEV,
+		"mode=1777,size="+sandboxTmpSize); err != nil {
+		return fmt.Errorf("mount /tmp: %w", err)
+	}
+
+	if err := os.MkdirAll(spec.Workspace, 0o755); err != nil {
+		return fmt.Errorf("create workspace mount point: %w", err)
+	}
+	wsSource := fmt.Sprintf("/proc/self/fd/%d", wsFD)
+	if err := unix.Mount(wsSource, spec.Workspace, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+		return fmt.Errorf("bind workspace: %w", err)
+	}
+	readWrite := &unix.MountAttr{Attr_clr: unix.MOUNT_ATTR_RDONLY}
+	if err := unix.MountSetattr(unix.AT_FDCWD, spec.Workspace, unix.AT_RECURSIVE, readWrite); err != nil {
+		return fmt.Errorf("make workspace writable: %w", err)
+	}
+
+	if err := unix.Mount("proc", "/proc", "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, ""); err != nil {
+		return fmt.Errorf("mount /proc: %w", err)
+	}
+
+	// The forwarder starts before the limits below, which are meant for
+	// the command alone.
+	if proxyDirFD >= 0 {
+		if err := startSandboxProxy(proxyDirFD, filepath.Base(spec.ProxySocket)); err != nil {
+			return err
+		}
+	}
+
+	// Memory is only limited through memory.max: RLIMIT_AS caps address
+	// space, which runtimes such as Node, the JVM and Go reserve far beyond
+	// what they use.
+	if spec.CPUTimeSeconds > 0 {
+		limit := &unix.Rlimit{Cur: spec.CPUTimeSeconds, Max: spec.CPUTimeSeconds}
+		if err := unix.Setrlimit(unix.RLIMIT_CPU, limit); err != nil {
+			return fmt.Errorf("set CPU time limit: %w", err)
+		}
+	}
+
+	if spec.Dir != "" {
+		// Re-enter the directory so it resolves through the new mounts.
+		if err := unix.Chdir(spec.Dir); err != nil {
+			return fmt.Errorf("change directory: %w", err)
+		}
+	}
+
+	if len(spec.Command) == 0 {
+		return nil
+	}
+
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("set no_new_privs: %w", err)
+	}
+	if err := unix.Prctl(unix.PR_CAP_AMBIENT, unix.PR_CAP_AMBIENT_CLEAR_ALL, 0, 0, 0); err != nil {
+		return fmt.Errorf("drop capabilities: %w", err)
+	}
+
+	env := os.Environ()
+	if proxyDirFD >= 0 {
+		env = withProxyEnv(env, "http://"+sandboxProxyAddr)
+	}
+	path, err := exec.LookPath(spec.Command[0])
+	if err != nil {
+		return err
+	}
+	return unix.Exec(path, spec.Command, env)
+}
+
+// startSandboxProxy brings up loopback, listens on sandboxProxyAddr and
+// starts a helper forwarding connections to the proxy socket named name in
+// the directory dirFD. The helper outlives the exec of the command and
+// dies with the pid namespace.
+func startSandboxProxy(dirFD int, name string) error {
+	if err := loopbackUp(); err != nil {
+		return fmt.Errorf("bring up loopback: %w", err)
+	}
+	ln, err := net.Listen("tcp", sandboxProxyAddr)
+	if err != nil {
+		return fmt.Errorf("listen for proxy: %w", err)
+	}
+	defer ln.Close()
+	lnFile, err := ln.(*net.TCPListener).File()
+	if err != nil {
+		return fmt.Errorf("listen for proxy: %w", err)
+	}
+	defer lnFile.Close()
+
+	// The helper runs without capabilities.
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("set no_new_privs: %w", err)
+	}
+	if err := unix.Prctl(unix.PR_CAP_AMBIENT, unix.PR_CAP_AMBIENT_CLEAR_ALL, 0, 0, 0); err != nil {
+		return fmt.Errorf("drop capabilities: %w", err)
+	}
+	helper := exec.Command("/proc/self/exe", name)
+	helper.Args[0] = sandboxProxyArg0
+	helper.ExtraFiles = []*os.File{lnFile, os.NewFile(uintptr(dirFD), "proxy-dir")}
+	helper.Stderr = os.Stderr
+	if err := helper.Start(); err != nil {
+		return fmt.Errorf("start proxy forwarder: %w", err)
+	}
+	return nil
+}
+
+func loopbackUp() error {
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+	ifr, err := unix.NewIfreq("lo")
+	if err != nil {
+		return err
+	}
+	if err := unix.IoctlIfreq(fd, unix.SIOCGIFFLAGS, ifr); err != nil {
+		return err
+	}
+	ifr.SetUint16(ifr.Uint16() | unix.IFF_UP)
+	return unix.IoctlIfreq(fd, unix.SIOCSIFFLAGS, ifr)
+}
+
+// withProxyEnv replaces any proxy settings in env with proxyURL.
+func withProxyEnv(env []string, proxyURL string) []string {
+	out := make([]string, 0, len(env)+8)
+	for _, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		switch strings.ToLower(name) {
+		case "http_proxy", "https_proxy", "all_proxy", "no_proxy":
+			continue
+		}
+		out = append(out, kv)
+	}
+	return append(out, egress.ProxyEnv(proxyURL)...)
+}
+
+// runSandboxProxy is the forwarder started by startSandboxProxy. It
+// inherits the listener as fd 3 and the socket directory as fd 4.
+func runSandboxProxy(name string) error {
+	ln, err := net.FileListener(os.NewFile(3, "proxy-listener"))
+	if err != nil {
+		return err
+	}
+	target := "/proc/self/fd/4/" + name
+	for {
+		client, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer client.Close()
+			upstream, err := net.Dial("unix", target)
+			if err != nil {
+				return
+			}
+			defer upstream.Close()
+			go func() {
+				io.Copy(upstream, client)
+				upstream.(*net.UnixConn).CloseWrite()
+			}()
+			io.Copy(client, upstream)
+		}()
+	}
+}
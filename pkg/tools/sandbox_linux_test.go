@@ -0,0 +1,170 @@
+//go:build linux
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/egress"
+)
+
+func newSandboxedExecTool(t *testing.T, workspace string) *ExecTool {
+	t.Helper()
+	if err := probeSandbox(); err != nil {
+		t.Skipf("sandbox unavailable: %v", err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.Tools.Exec.Sandbox.Enabled = true
+	tool, err := NewExecToolWithConfig(workspace, true, cfg)
+	if err != nil {
+		t.Fatalf("NewExecToolWithConfig: %v", err)
+	}
+	if tool.sandbox == nil {
+		t.Fatal("expected sandbox to be active")
+	}
+	return tool
+}
+
+func TestExecSandbox_WorkspaceWritable(t *testing.T) {
+	workspace := t.TempDir()
+	tool := newSandboxedExecTool(t, workspace)
+
+	result := tool.Execute(context.Background(), map[string]any{"command": "echo hi > out.txt && cat out.txt"})
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", result.ForLLM)
+	}
+	data, err := os.ReadFile(filepath.Join(workspace, "out.txt"))
+	if err != nil || strings.TrimSpace(string(data)) != "hi" {
+		t.Errorf("workspace file = %q, %v", data, err)
+	}
+}
+
+func TestExecSandbox_HostFilesystemProtected(t *testing.T) {
+	workspace := t.TempDir()
+	outside := t.TempDir()
+	tool := newSandboxedExecTool(t, workspace)
+	tool.SetRestrictToWorkspace(false)
+
+	target := filepath.Join(outside, "escaped.txt")
+	result := tool.Execute(context.Background(), map[string]any{"command": "echo x > " + target})
+	if !result.IsError {
+		t.Errorf("expected write outside the workspace to fail, got: %s", result.ForLLM)
+	}
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Errorf("file outside the workspace was created: %v", err)
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" || strings.HasPrefix(workspace, home) {
+		return
+	}
+	result = tool.Execute(context.Background(), map[string]any{"command": "touch " + home + "/.picoclaw-sandbox-test"})
+	if !result.IsError {
+		os.Remove(filepath.Join(home, ".picoclaw-sandbox-test"))
+		t.Error("expected home directory to be read-only")
+	}
+}
+
+func TestExecSandbox_IsolatedNamespaces(t *testing.T) {
+	tool := newSandboxedExecTool(t, t.TempDir())
+	tool.SetRestrictToWorkspace(false)
+
+	result := tool.Execute(context.Background(), map[string]any{"command": "echo $$; tail -n +3 /proc/net/dev"})
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", result.ForLLM)
+	}
+	lines := strings.Split(strings.TrimSpace(result.ForLLM), "\n")
+	if lines[0] != "1" {
+		t.Errorf("expected the shell to be pid 1, got %q", lines[0])
+	}
+	for _, line := range lines[1:] {
+		if iface := strings.TrimSpace(strings.SplitN(line, ":", 2)[0]); iface != "lo" {
+			t.Errorf("unexpected network interface %q in sandbox", iface)
+		}
+	}
+}
+
+func TestExecSandbox_DenyPatternsAdvisory(t *testing.T) {
+	tool := newSandboxedExecTool(t, t.TempDir())
+
+	result := tool.Execute(context.Background(), map[string]any{"command": "echo $(echo ok); kill -0 $$"})
+	if result.IsError {
+		t.Fatalf("expected deny-listed command to run in the sandbox, got: %s", result.ForLLM)
+	}
+	if !strings.Contains(result.ForLLM, "ok") {
+		t.Errorf("unexpected output: %s", result.ForLLM)
+	}
+}
+
+func TestExecSandbox_ShellSession(t *testing.T) {
+	workspace := t.TempDir()
+	tool := NewShellSessionTool(newSandboxedExecTool(t, workspace), 0, 0, 0)
+	ctx := WithToolSessionKey(WithToolContext(context.Background(), "cli", "direct"), "sandbox")
+	defer tool.Execute(ctx, map[string]any{"action": "kill"})
+
+	if result := tool.Execute(ctx, map[string]any{"action": "start"}); result.IsError {
+		t.Fatalf("start: %s", result.ForLLM)
+	}
+	result := tool.Execute(ctx, map[string]any{"action": "send", "input": "echo $$ > pid.txt; echo done"})
+	if result.IsError || !strings.Contains(result.ForLLM, "done") {
+		t.Fatalf("send: %s", result.ForLLM)
+	}
+	data, err := os.ReadFile(filepath.Join(workspace, "pid.txt"))
+	if err != nil || strings.TrimSpace(string(data)) != "1" {
+		t.Errorf("expected the session shell to be pid 1 of its namespace, got %q, %v", data, err)
+	}
+}
+
+func TestExecSandbox_EgressProxy(t *testing.T) {
+	if _, err := exec.LookPath("curl"); err != nil {
+		t.Skip("curl not installed")
+	}
+	if err := probeSandbox(); err != nil {
+		t.Skipf("sandbox unavailable: %v", err)
+	}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("upstream"))
+	}))
+	defer upstream.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Tools.Exec.Sandbox.Enabled = true
+	cfg.Egress.Enabled = true
+	cfg.Egress.ExecProxy = true
+	cfg.Egress.BlockedDomains = []string{"blocked.test"}
+	if err := egress.Install(cfg.Egress); err != nil {
+		t.Fatal(err)
+	}
+	defer egress.Install(config.EgressConfig{})
+	tool, err := NewExecToolWithConfig(t.TempDir(), false, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	port := upstream.Listener.Addr().(*net.TCPAddr).Port
+	run := func(args string) string {
+		result := tool.Execute(context.Background(), map[string]any{
+			"command": "curl -s -o /dev/null -w '%{http_code}' " + args,
+		})
+		return strings.TrimSpace(strings.SplitN(result.ForLLM, "\n", 2)[0])
+	}
+	if got := run(fmt.Sprintf("http://127.0.0.1:%d/", port)); got != "200" {
+		t.Errorf("allowed request through proxy: status %q", got)
+	}
+	if got := run(fmt.Sprintf("http://blocked.test:%d/", port)); got != "403" {
+		t.Errorf("blocked request through proxy: status %q", got)
+	}
+	if got := run(fmt.Sprintf("--noproxy '*' http://127.0.0.1:%d/", port)); got != "000" {
+		t.Errorf("expected direct connection to fail, got status %q", got)
+	}
+}
@@ -0,0 +1,26 @@
+//go:build !linux
+
+package tools
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+
+	"github.com/sipeed/picoclaw/pkg/egress"
+)
+
+var errSandboxUnsupported = errors.New("exec sandbox requires Linux")
+
+func probeSandbox() error {
+	return errSandboxUnsupported
+}
+
+func (s *execSandbox) command(
+	ctx context.Context,
+	dir string,
+	proxy *egress.Proxy,
+	argv ...string,
+) (*exec.Cmd, func(), error) {
+	return nil, nil, errSandboxUnsupported
+}
@@ -5,10 +5,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
 
 	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/permissions"
 	"github.com/sipeed/picoclaw/pkg/utils"
 )
 
@@ -69,7 +71,7 @@ func (t *RegexSearchTool) Execute(ctx context.Context, args map[string]any) *Too
 	}
 
 	logger.InfoCF("discovery", "Regex search completed", map[string]any{"pattern": pattern, "results": len(res)})
-	return formatDiscoveryResponse(t.registry, res, t.ttl)
+	return formatDiscoveryResponse(ctx, t.registry, res, t.ttl)
 }
 
 type BM25SearchTool struct {
@@ -139,7 +141,7 @@ func (t *BM25SearchTool) Execute(ctx context.Context, args map[string]any) *Tool
 	}
 
 	logger.InfoCF("discovery", "BM25 search completed", map[string]any{"query": query, "results": len(results)})
-	return formatDiscoveryResponse(t.registry, results, t.ttl)
+	return formatDiscoveryResponse(ctx, t.registry, results, t.ttl)
 }
 
 // ToolSearchResult represents the result returned to the LLM.
@@ -188,7 +190,15 @@ func (r *ToolRegistry) SearchRegex(pattern string, maxSearchResults int) ([]Tool
 	return results, nil
 }
 
-func formatDiscoveryResponse(registry *ToolRegistry, results []ToolSearchResult, ttl int) *ToolResult {
+func formatDiscoveryResponse(
+	ctx context.Context,
+	registry *ToolRegistry,
+	results []ToolSearchResult,
+	ttl int,
+) *ToolResult {
+	// Never reveal tools the requester's role may not use.
+	role := permissions.RoleFrom(ctx)
+	results = slices.DeleteFunc(results, func(r ToolSearchResult) bool { return !role.AllowsTool(r.Name) })
 	if len(results) == 0 {
 		return SilentResult("No tools found matching the query.")
 	}
@@ -15,6 +15,8 @@ import (
 
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/constants"
+	"github.com/sipeed/picoclaw/pkg/egress"
+	"github.com/sipeed/picoclaw/pkg/logger"
 )
 
 type ExecTool struct {
@@ -26,6 +28,8 @@ type ExecTool struct {
 	allowedPathPatterns []*regexp.Regexp
 	restrictToWorkspace bool
 	allowRemote         bool
+	sandbox             *execSandbox
+	egressProxy         bool
 }
 
 var (
@@ -150,6 +154,23 @@ func NewExecToolWithConfig(
 		timeout = time.Duration(config.Tools.Exec.TimeoutSeconds) * time.Second
 	}
 
+	var sandbox *execSandbox
+	if config != nil && config.Tools.Exec.Sandbox.Enabled {
+		workspace := workingDir
+		if workspace == "" {
+			workspace, _ = os.Getwd()
+		}
+		workspace, _ = filepath.Abs(workspace)
+		sb, err := newExecSandbox(workspace, config.Tools.Exec.Sandbox)
+		if err != nil {
+			// Fail safe: without the sandbox the deny patterns stay enforced.
+			logger.WarnCF("tool", "Exec sandbox unavailable, using deny patterns only",
+				map[string]any{"error": err.Error()})
+		} else {
+			sandbox = sb
+		}
+	}
+
 	return &ExecTool{
 		workingDir:          workingDir,
 		timeout:             timeout,
@@ -159,6 +180,8 @@ func NewExecToolWithConfig(
 		allowedPathPatterns: allowedPathPatterns,
 		restrictToWorkspace: restrict,
 		allowRemote:         allowRemote,
+		sandbox:             sandbox,
+		egressProxy:         config != nil && config.Egress.Enabled && config.Egress.ExecProxy,
 	}, nil
 }
 
@@ -167,6 +190,14 @@ func (t *ExecTool) Name() string {
 }
 
 func (t *ExecTool) Description() string {
+	if t.sandbox != nil {
+		network := "Network access is disabled."
+		if t.sandbox.cfg.Network {
+			network = "Network access is available."
+		}
+		return "Execute a shell command in a sandbox and return its output. " +
+			"Only the workspace is writable and /tmp is private to the command. " + network
+	}
 	return "Execute a shell command and return its output. Use with caution."
 }
 
@@ -193,37 +224,14 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]any) *ToolResult
 		return ErrorResult("command is required")
 	}
 
-	// GHSA-pv8c-p6jf-3fpp: block exec from remote channels (e.g. Telegram webhooks)
-	// unless explicitly opted-in via config. Fail-closed: empty channel = blocked.
-	if !t.allowRemote {
-		channel := ToolChannel(ctx)
-		if channel == "" {
-			channel, _ = args["__channel"].(string)
-		}
-		channel = strings.TrimSpace(channel)
-		if channel == "" || !constants.IsInternalChannel(channel) {
-			return ErrorResult("exec is restricted to internal channels")
-		}
-	}
-
-	cwd := t.workingDir
-	if wd, ok := args["working_dir"].(string); ok && wd != "" {
-		if t.restrictToWorkspace && t.workingDir != "" {
-			resolvedWD, err := validatePathWithAllowPaths(wd, t.workingDir, true, t.allowedPathPatterns)
-			if err != nil {
-				return ErrorResult("Command blocked by safety guard (" + err.Error() + ")")
-			}
-			cwd = resolvedWD
-		} else {
-			cwd = wd
-		}
+	if t.remoteDenied(ctx, args) {
+		return ErrorResult("exec is restricted to internal channels")
 	}
 
-	if cwd == "" {
-		wd, err := os.Getwd()
-		if err == nil {
-			cwd = wd
-		}
+	wd, _ := args["working_dir"].(string)
+	cwd, wdErr := t.resolveWorkingDir(wd)
+	if wdErr != nil {
+		return ErrorResult("Command blocked by safety guard (" + wdErr.Error() + ")")
 	}
 
 	if guardError := t.guardCommand(command, cwd); guardError != "" {
@@ -263,11 +271,29 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]any) *ToolResult
 	}
 	defer cancel()
 
+	proxy, err := t.proxyFor(ctx)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to start egress proxy: %v", err))
+	}
+
 	var cmd *exec.Cmd
-	if runtime.GOOS == "windows" {
-		cmd = exec.CommandContext(cmdCtx, "powershell", "-NoProfile", "-NonInteractive", "-Command", command)
+	if t.sandbox != nil {
+		var cleanup func()
+		var err error
+		cmd, cleanup, err = t.sandbox.command(cmdCtx, cwd, proxy, "sh", "-c", command)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("failed to prepare sandbox: %v", err))
+		}
+		defer cleanup()
 	} else {
-		cmd = exec.CommandContext(cmdCtx, "sh", "-c", command)
+		if runtime.GOOS == "windows" {
+			cmd = exec.CommandContext(cmdCtx, "powershell", "-NoProfile", "-NonInteractive", "-Command", command)
+		} else {
+			cmd = exec.CommandContext(cmdCtx, "sh", "-c", command)
+		}
+		if proxy != nil {
+			cmd.Env = append(os.Environ(), egress.ProxyEnv("http://"+proxy.Addr())...)
+		}
 	}
 	if cwd != "" {
 		cmd.Dir = cwd
@@ -288,7 +314,6 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]any) *ToolResult
 		done <- cmd.Wait()
 	}()
 
-	var err error
 	select {
 	case err = <-done:
 	case <-cmdCtx.Done():
@@ -344,6 +369,57 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]any) *ToolResult
 	}
 }
 
+// proxyFor returns the egress proxy commands of the calling agent are routed
+// through, or nil when egress.exec_proxy is off.
+func (t *ExecTool) proxyFor(ctx context.Context) (*egress.Proxy, error) {
+	if !t.egressProxy {
+		return nil, nil
+	}
+	return egress.ProxyFor(egress.AgentFrom(ctx))
+}
+
+// remoteDenied reports whether the call comes from a channel that may not run
+// commands.
+//
+// GHSA-pv8c-p6jf-3fpp: block exec from remote channels (e.g. Telegram webhooks)
+// unless explicitly opted-in via config. Fail-closed: empty channel = blocked.
+func (t *ExecTool) remoteDenied(ctx context.Context, args map[string]any) bool {
+	if t.allowRemote {
+		return false
+	}
+	channel := ToolChannel(ctx)
+	if channel == "" {
+		channel, _ = args["__channel"].(string)
+	}
+	channel = strings.TrimSpace(channel)
+	return channel == "" || !constants.IsInternalChannel(channel)
+}
+
+// resolveWorkingDir validates an optional working directory against the
+// workspace restriction and falls back to the tool's working directory.
+func (t *ExecTool) resolveWorkingDir(wd string) (string, error) {
+	cwd := t.workingDir
+	if wd != "" {
+		if t.restrictToWorkspace && t.workingDir != "" {
+			resolvedWD, err := validatePathWithAllowPaths(wd, t.workingDir, true, t.allowedPathPatterns)
+			if err != nil {
+				return "", err
+			}
+			cwd = resolvedWD
+		} else {
+			cwd = wd
+		}
+	}
+
+	if cwd == "" {
+		wd, err := os.Getwd()
+		if err == nil {
+			cwd = wd
+		}
+	}
+	return cwd, nil
+}
+
 func (t *ExecTool) guardCommand(command, cwd string) string {
 	cmd := strings.TrimSpace(command)
 	lower := strings.ToLower(cmd)
@@ -359,9 +435,16 @@ func (t *ExecTool) guardCommand(command, cwd string) string {
 
 	if !explicitlyAllowed {
 		for _, pattern := range t.denyPatterns {
-			if pattern.MatchString(lower) {
-				return "Command blocked by safety guard (dangerous pattern detected)"
+			if !pattern.MatchString(lower) {
+				continue
+			}
+			// The sandbox contains the command, so deny patterns are advisory.
+			if t.sandbox != nil {
+				logger.InfoCF("tool", "Sandboxed command matches deny pattern",
+					map[string]any{"pattern": pattern.String()})
+				break
 			}
+			return "Command blocked by safety guard (dangerous pattern detected)"
 		}
 	}
 
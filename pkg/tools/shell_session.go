@@ -0,0 +1,641 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/egress"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+const (
+	defaultShellSessionBufferBytes = 64 * 1024
+	defaultShellSessionIdleTimeout = 30 * time.Minute
+	defaultShellSessionMax         = 4
+	defaultShellSessionWait        = 1 * time.Second
+	maxShellSessionWait            = 30 * time.Second
+	// shellSessionSettle is how long output must stay quiet before a wait
+	// returns early.
+	shellSessionSettle    = 300 * time.Millisecond
+	maxShellSessionOutput = 10000
+)
+
+var (
+	shellSessionNameRe = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+	// ansiEscapeRe matches CSI and OSC terminal escape sequences.
+	ansiEscapeRe = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(\x07|\x1b\\)|\x1b[()][A-Z0-9]|\x1b[=>]`)
+)
+
+// ShellSessionTool keeps named interactive shells alive across tool calls so
+// the working directory, environment and background processes persist.
+// Sessions belong to the agent session that started them and are subject to
+// the same workspace restriction, deny patterns and sandbox as ExecTool.
+type ShellSessionTool struct {
+	exec        *ExecTool
+	bufferBytes int
+	idleTimeout time.Duration
+	maxSessions int
+
+	mu       sync.Mutex
+	sessions map[string]map[string]*shellSession // owner -> name -> session
+	reaping  bool
+}
+
+type shellSession struct {
+	name    string
+	dir     string
+	cmd     *exec.Cmd
+	pty     *os.File
+	output  *outputBuffer
+	cleanup func()
+	done    chan struct{}
+
+	mu       sync.Mutex
+	offset   int64 // output already returned to the agent
+	lastUsed time.Time
+	exitErr  error
+	// pending is input typed with enter=false that the shell has not run
+	// yet; it is guarded together with the rest of its line.
+	pending string
+}
+
+// NewShellSessionTool creates the tool on top of execTool's guard settings.
+// Zero values select the defaults.
+func NewShellSessionTool(
+	execTool *ExecTool,
+	maxSessions, bufferKB int,
+	idleTimeout time.Duration,
+) *ShellSessionTool {
+	t := &ShellSessionTool{
+		exec:        execTool,
+		bufferBytes: bufferKB * 1024,
+		idleTimeout: idleTimeout,
+		maxSessions: maxSessions,
+		sessions:    make(map[string]map[string]*shellSession),
+	}
+	if t.bufferBytes <= 0 {
+		t.bufferBytes = defaultShellSessionBufferBytes
+	}
+	if t.idleTimeout <= 0 {
+		t.idleTimeout = defaultShellSessionIdleTimeout
+	}
+	if t.maxSessions <= 0 {
+		t.maxSessions = defaultShellSessionMax
+	}
+	return t
+}
+
+func (t *ShellSessionTool) Name() string {
+	return "shell_session"
+}
+
+func (t *ShellSessionTool) Description() string {
+	return "Manage persistent interactive shell sessions. Unlike exec, the working directory, environment " +
+		"variables and background processes survive between calls. Actions: start a named session, send input " +
+		"(a newline is appended unless enter=false), read new output, kill a session, or list sessions. " +
+		fmt.Sprintf("Idle sessions are closed after %v.", t.idleTimeout)
+}
+
+func (t *ShellSessionTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"enum":        []string{"start", "send", "read", "kill", "list"},
+				"description": "Operation to perform",
+			},
+			"name": map[string]any{
+				"type":        "string",
+				"description": "Session name (letters, digits, '-' and '_'); defaults to \"default\"",
+			},
+			"input": map[string]any{
+				"type":        "string",
+				"description": "Text to type into the session (send)",
+			},
+			"enter": map[string]any{
+				"type":        "boolean",
+				"description": "Append a newline to input (default true); use false for key presses such as \"\\u0003\" (Ctrl-C)",
+			},
+			"working_dir": map[string]any{
+				"type":        "string",
+				"description": "Initial working directory (start)",
+			},
+			"wait_ms": map[string]any{
+				"type":        "integer",
+				"description": "How long to wait for output after start/send/read, in milliseconds (default 1000, max 30000)",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *ShellSessionTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	if t.exec.remoteDenied(ctx, args) {
+		return ErrorResult("shell_session is restricted to internal channels")
+	}
+
+	action, _ := args["action"].(string)
+	name, _ := args["name"].(string)
+	if name == "" {
+		name = "default"
+	}
+	if action != "list" && !shellSessionNameRe.MatchString(name) {
+		return ErrorResult("invalid session name: use 1-32 letters, digits, '-' or '_'")
+	}
+
+	owner := ToolSessionKey(ctx)
+	if owner == "" {
+		owner = ToolChannel(ctx) + ":" + ToolChatID(ctx)
+	}
+	wait := shellSessionWait(args)
+
+	switch action {
+	case "start":
+		wd, _ := args["working_dir"].(string)
+		proxy, err := t.exec.proxyFor(ctx)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("failed to start egress proxy: %v", err))
+		}
+		return t.start(owner, name, wd, wait, proxy)
+	case "send":
+		input, ok := args["input"].(string)
+		if !ok {
+			return ErrorResult("input is required for send")
+		}
+		enter := true
+		if v, ok := args["enter"].(bool); ok {
+			enter = v
+		}
+		return t.send(owner, name, input, enter, wait)
+	case "read":
+		s := t.get(owner, name)
+		if s == nil {
+			return ErrorResult(fmt.Sprintf("no session named %q; start it first", name))
+		}
+		return NewToolResult(s.collect(wait, t.exec))
+	case "kill":
+		s := t.remove(owner, name)
+		if s == nil {
+			return ErrorResult(fmt.Sprintf("no session named %q", name))
+		}
+		s.kill()
+		return NewToolResult(fmt.Sprintf("Session %q killed.", name))
+	case "list":
+		return NewToolResult(t.list(owner))
+	default:
+		return ErrorResult("action must be one of start, send, read, kill, list")
+	}
+}
+
+func (t *ShellSessionTool) start(owner, name, wd string, wait time.Duration, proxy *egress.Proxy) *ToolResult {
+	dir, err := t.exec.resolveWorkingDir(wd)
+	if err != nil {
+		return ErrorResult("Session blocked by safety guard (" + err.Error() + ")")
+	}
+
+	t.mu.Lock()
+	owned := t.sessions[owner]
+	if _, exists := owned[name]; exists {
+		t.mu.Unlock()
+		return ErrorResult(fmt.Sprintf("session %q is already running; use send, or kill it first", name))
+	}
+	if len(owned) >= t.maxSessions {
+		t.mu.Unlock()
+		return ErrorResult(fmt.Sprintf("too many sessions (max %d); kill one first", t.maxSessions))
+	}
+	// Reserve the name while the shell starts.
+	if owned == nil {
+		owned = make(map[string]*shellSession)
+		t.sessions[owner] = owned
+	}
+	owned[name] = nil
+	t.mu.Unlock()
+
+	s, err := t.spawn(name, dir, proxy)
+
+	t.mu.Lock()
+	if err != nil {
+		delete(owned, name)
+		t.mu.Unlock()
+		return ErrorResult(fmt.Sprintf("failed to start session: %v", err)).WithError(err)
+	}
+	owned[name] = s
+	if !t.reaping {
+		t.reaping = true
+		go t.reap()
+	}
+	t.mu.Unlock()
+
+	logger.InfoCF("tool", "Shell session started", map[string]any{
+		"session": name,
+		"owner":   owner,
+		"dir":     dir,
+	})
+
+	out := s.collect(wait, t.exec)
+	return NewToolResult(fmt.Sprintf("Session %q started in %s.\n%s", name, dir, out))
+}
+
+// spawn starts an interactive shell on a new pseudo-terminal, routed
+// through proxy when it is set.
+func (t *ShellSessionTool) spawn(name, dir string, proxy *egress.Proxy) (*shellSession, error) {
+	argv := []string{"sh", "-i"}
+	if _, err := exec.LookPath("bash"); err == nil {
+		argv = []string{"bash", "--noprofile", "--norc", "-i"}
+	}
+
+	var cmd *exec.Cmd
+	cleanup := func() {}
+	if t.exec.sandbox != nil {
+		var err error
+		cmd, cleanup, err = t.exec.sandbox.command(context.Background(), dir, proxy, argv...)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		cmd = exec.Command(argv[0], argv[1:]...)
+		if proxy != nil {
+			cmd.Env = append(os.Environ(), egress.ProxyEnv("http://"+proxy.Addr())...)
+		}
+	}
+	cmd.Dir = dir
+	cmd.Env = append(cmd.Environ(), "TERM=dumb", "PS1=$ ", "PAGER=cat", "GIT_PAGER=cat")
+
+	f, err := startPTY(cmd)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	s := &shellSession{
+		name:     name,
+		dir:      dir,
+		cmd:      cmd,
+		pty:      f,
+		output:   newOutputBuffer(t.bufferBytes),
+		cleanup:  cleanup,
+		done:     make(chan struct{}),
+		lastUsed: time.Now(),
+	}
+	go s.pump()
+	return s, nil
+}
+
+func (t *ShellSessionTool) send(owner, name, input string, enter bool, wait time.Duration) *ToolResult {
+	s := t.get(owner, name)
+	if s == nil {
+		return ErrorResult(fmt.Sprintf("no session named %q; start it first", name))
+	}
+	if s.exited() {
+		return ErrorResult(fmt.Sprintf("session %q has exited; kill it and start a new one", name))
+	}
+
+	cwd := s.cwd()
+	if cwd == "" {
+		return ErrorResult(fmt.Sprintf("cannot determine the working directory of session %q", name))
+	}
+	// Input sent in pieces runs as one line, so check the whole line.
+	s.mu.Lock()
+	line := s.pending + input
+	s.mu.Unlock()
+	if guardError := t.exec.guardCommand(line, cwd); guardError != "" {
+		return ErrorResult(guardError)
+	}
+
+	if enter {
+		input += "\n"
+	}
+	s.mu.Lock()
+	if enter {
+		s.pending = ""
+	} else {
+		s.pending = line[strings.LastIndex(line, "\n")+1:]
+	}
+	s.lastUsed = time.Now()
+	s.mu.Unlock()
+	if _, err := s.pty.Write([]byte(input)); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to write to session: %v", err)).WithError(err)
+	}
+	return NewToolResult(s.collect(wait, t.exec))
+}
+
+func (t *ShellSessionTool) get(owner, name string) *shellSession {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.sessions[owner][name]
+}
+
+func (t *ShellSessionTool) remove(owner, name string) *shellSession {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := t.sessions[owner][name]
+	if s != nil {
+		delete(t.sessions[owner], name)
+		if len(t.sessions[owner]) == 0 {
+			delete(t.sessions, owner)
+		}
+	}
+	return s
+}
+
+func (t *ShellSessionTool) list(owner string) string {
+	t.mu.Lock()
+	var sessions []*shellSession
+	for _, s := range t.sessions[owner] {
+		if s != nil {
+			sessions = append(sessions, s)
+		}
+	}
+	t.mu.Unlock()
+
+	if len(sessions) == 0 {
+		return "No shell sessions."
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].name < sessions[j].name })
+
+	var sb strings.Builder
+	for _, s := range sessions {
+		status := "running"
+		if s.exited() {
+			status = "exited"
+		}
+		s.mu.Lock()
+		idle := time.Since(s.lastUsed).Round(time.Second)
+		s.mu.Unlock()
+		fmt.Fprintf(&sb, "%s\t%s\tidle %v\t%s\n", s.name, status, idle, s.dir)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// Close kills every running session. It is called on shutdown, so shells
+// and their background processes do not outlive the agent.
+func (t *ShellSessionTool) Close() {
+	t.mu.Lock()
+	var all []*shellSession
+	for _, owned := range t.sessions {
+		for _, s := range owned {
+			if s != nil {
+				all = append(all, s)
+			}
+		}
+	}
+	t.sessions = make(map[string]map[string]*shellSession)
+	t.mu.Unlock()
+
+	for _, s := range all {
+		s.kill()
+	}
+}
+
+// reap kills sessions that have been idle for longer than idleTimeout. It
+// stops once no sessions are left and is restarted by the next start.
+func (t *ShellSessionTool) reap() {
+	interval := t.idleTimeout / 4
+	if interval > time.Minute {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		var idle []*shellSession
+		t.mu.Lock()
+		for owner, owned := range t.sessions {
+			for name, s := range owned {
+				if s == nil || !s.idleFor(t.idleTimeout) {
+					continue
+				}
+				idle = append(idle, s)
+				delete(owned, name)
+			}
+			if len(owned) == 0 {
+				delete(t.sessions, owner)
+			}
+		}
+		done := len(t.sessions) == 0
+		if done {
+			t.reaping = false
+		}
+		t.mu.Unlock()
+
+		for _, s := range idle {
+			logger.InfoCF("tool", "Shell session closed after idle timeout", map[string]any{"session": s.name})
+			s.kill()
+		}
+		if done {
+			return
+		}
+	}
+}
+
+// pump copies terminal output into the buffer until the shell exits.
+func (s *shellSession) pump() {
+	buf := make([]byte, 4096)
+	for {
+		n, err := s.pty.Read(buf)
+		if n > 0 {
+			s.output.Write(buf[:n])
+		}
+		if err != nil {
+			break
+		}
+	}
+	err := s.cmd.Wait()
+	s.mu.Lock()
+	s.exitErr = err
+	s.mu.Unlock()
+	s.cleanup()
+	close(s.done)
+}
+
+// collect waits up to wait for output to settle and returns everything the
+// agent has not seen yet. When the workspace is restricted and the shell
+// has left it, the shell is moved back.
+func (s *shellSession) collect(wait time.Duration, guard *ExecTool) string {
+	s.output.waitQuiet(wait, shellSessionSettle, s.done)
+	s.touch()
+
+	var notes []string
+	if workspace := guard.workingDir; guard.restrictToWorkspace && workspace != "" && !s.exited() {
+		cwd := s.cwd()
+		if cwd != "" && !withinDir(cwd, workspace) && !isAllowedPath(cwd, guard.allowedPathPatterns) {
+			_, _ = s.pty.Write([]byte("cd " + shellQuote(workspace) + "\n"))
+			notes = append(notes, fmt.Sprintf("[working directory %s is outside the workspace; moved back to %s]",
+				cwd, workspace))
+			s.output.waitQuiet(defaultShellSessionWait, shellSessionSettle, s.done)
+		}
+	}
+
+	s.mu.Lock()
+	data, next, dropped := s.output.since(s.offset)
+	s.offset = next
+	exitErr := s.exitErr
+	s.mu.Unlock()
+
+	out := cleanTerminalOutput(data)
+	if dropped > 0 {
+		out = fmt.Sprintf("[... %d bytes of earlier output dropped ...]\n", dropped) + out
+	}
+	if len(out) > maxShellSessionOutput {
+		cut := len(out) - maxShellSessionOutput
+		out = fmt.Sprintf("[... %d chars truncated ...]\n", cut) + out[cut:]
+	}
+	if out == "" {
+		out = "(no new output)"
+	}
+	if s.exited() {
+		status := "[session exited]"
+		if exitErr != nil {
+			status = fmt.Sprintf("[session exited: %v]", exitErr)
+		}
+		notes = append(notes, status)
+	}
+	if len(notes) > 0 {
+		out += "\n" + strings.Join(notes, "\n")
+	}
+	return out
+}
+
+func (s *shellSession) kill() {
+	_ = s.pty.Close()
+	_ = terminateProcessTree(s.cmd)
+	select {
+	case <-s.done:
+	case <-time.After(2 * time.Second):
+	}
+}
+
+func (s *shellSession) exited() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *shellSession) touch() {
+	s.mu.Lock()
+	s.lastUsed = time.Now()
+	s.mu.Unlock()
+}
+
+func (s *shellSession) idleFor(d time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Since(s.lastUsed) > d
+}
+
+// cwd returns the shell's current directory, or its start directory where
+// the platform does not expose it.
+func (s *shellSession) cwd() string {
+	if s.cmd.Process != nil {
+		if dir, err := os.Readlink(fmt.Sprintf("/proc/%d/cwd", s.cmd.Process.Pid)); err == nil {
+			return dir
+		}
+	}
+	return s.dir
+}
+
+// outputBuffer keeps the most recent terminal output up to a byte limit
+// while counting everything written, so readers can resume from an offset.
+type outputBuffer struct {
+	mu        sync.Mutex
+	data      []byte
+	limit     int
+	total     int64
+	lastWrite time.Time
+}
+
+func newOutputBuffer(limit int) *outputBuffer {
+	return &outputBuffer{limit: limit}
+}
+
+func (b *outputBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.data = append(b.data, p...)
+	if over := len(b.data) - b.limit; over > 0 {
+		b.data = append([]byte(nil), b.data[over:]...)
+	}
+	b.total += int64(len(p))
+	b.lastWrite = time.Now()
+	return len(p), nil
+}
+
+// since returns the output written after offset, the offset to continue
+// from, and how many bytes after offset were already discarded.
+func (b *outputBuffer) since(offset int64) (string, int64, int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	start := b.total - int64(len(b.data))
+	var dropped int64
+	if offset < start {
+		dropped = start - offset
+		offset = start
+	}
+	return string(b.data[offset-start:]), b.total, dropped
+}
+
+// waitQuiet blocks until new output arrived and then stayed quiet for
+// settle, max elapsed, or done is closed.
+func (b *outputBuffer) waitQuiet(max, settle time.Duration, done <-chan struct{}) {
+	b.mu.Lock()
+	startTotal := b.total
+	b.mu.Unlock()
+
+	deadline := time.Now().Add(max)
+	for time.Now().Before(deadline) {
+		b.mu.Lock()
+		quiet := b.total > startTotal && time.Since(b.lastWrite) >= settle
+		b.mu.Unlock()
+		if quiet {
+			return
+		}
+		select {
+		case <-done:
+			return
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+func shellSessionWait(args map[string]any) time.Duration {
+	ms, ok := args["wait_ms"].(float64)
+	if !ok || ms < 0 {
+		return defaultShellSessionWait
+	}
+	wait := time.Duration(ms) * time.Millisecond
+	if wait > maxShellSessionWait {
+		wait = maxShellSessionWait
+	}
+	return wait
+}
+
+func cleanTerminalOutput(s string) string {
+	s = ansiEscapeRe.ReplaceAllString(s, "")
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.ReplaceAll(s, "\r", "")
+}
+
+func withinDir(path, dir string) bool {
+	dir, _ = filepath.Abs(dir)
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && filepath.IsLocal(rel)
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,180 @@
+//go:build !windows
+
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestShellSessionTool(t *testing.T, workspace string, restrict bool) *ShellSessionTool {
+	t.Helper()
+	execTool, err := NewExecTool(workspace, restrict)
+	if err != nil {
+		t.Fatalf("NewExecTool: %v", err)
+	}
+	tool := NewShellSessionTool(execTool, 2, 0, 0)
+	t.Cleanup(tool.Close)
+	return tool
+}
+
+func shellSessionCtx(sessionKey string) context.Context {
+	ctx := WithToolContext(context.Background(), "cli", "direct")
+	return WithToolSessionKey(ctx, sessionKey)
+}
+
+func TestShellSessionTool_StatePersists(t *testing.T) {
+	workspace := t.TempDir()
+	if err := os.Mkdir(filepath.Join(workspace, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	tool := newTestShellSessionTool(t, workspace, true)
+	ctx := shellSessionCtx("agent:main:test")
+
+	if result := tool.Execute(ctx, map[string]any{"action": "start"}); result.IsError {
+		t.Fatalf("start: %s", result.ForLLM)
+	}
+	tool.Execute(ctx, map[string]any{"action": "send", "input": "cd sub && export GREETING=hello"})
+	result := tool.Execute(ctx, map[string]any{"action": "send", "input": "echo \"$GREETING from $PWD\""})
+	if result.IsError {
+		t.Fatalf("send: %s", result.ForLLM)
+	}
+	if !strings.Contains(result.ForLLM, "hello from "+filepath.Join(workspace, "sub")) {
+		t.Errorf("expected state to persist across calls, got: %q", result.ForLLM)
+	}
+
+	if result := tool.Execute(ctx, map[string]any{"action": "read", "wait_ms": float64(0)}); strings.Contains(result.ForLLM, "hello from "+filepath.Join(workspace, "sub")) {
+		t.Errorf("read returned output that was already delivered: %q", result.ForLLM)
+	}
+}
+
+func TestShellSessionTool_SessionsAreScopedPerAgentSession(t *testing.T) {
+	tool := newTestShellSessionTool(t, t.TempDir(), false)
+	a := shellSessionCtx("session-a")
+	b := shellSessionCtx("session-b")
+
+	if result := tool.Execute(a, map[string]any{"action": "start", "name": "build"}); result.IsError {
+		t.Fatalf("start: %s", result.ForLLM)
+	}
+	if result := tool.Execute(b, map[string]any{"action": "send", "name": "build", "input": "true"}); !result.IsError {
+		t.Error("expected another agent session not to see the session")
+	}
+	if result := tool.Execute(a, map[string]any{"action": "start", "name": "build"}); !result.IsError {
+		t.Error("expected duplicate session name to be rejected")
+	}
+	if result := tool.Execute(a, map[string]any{"action": "list"}); !strings.Contains(result.ForLLM, "build") {
+		t.Errorf("list = %q, want build", result.ForLLM)
+	}
+	if result := tool.Execute(a, map[string]any{"action": "kill", "name": "build"}); result.IsError {
+		t.Fatalf("kill: %s", result.ForLLM)
+	}
+	if result := tool.Execute(a, map[string]any{"action": "list"}); result.ForLLM != "No shell sessions." {
+		t.Errorf("list after kill = %q", result.ForLLM)
+	}
+}
+
+func TestShellSessionTool_GuardsInput(t *testing.T) {
+	workspace := t.TempDir()
+	tool := newTestShellSessionTool(t, workspace, true)
+	ctx := shellSessionCtx("guard")
+
+	if result := tool.Execute(ctx, map[string]any{"action": "start"}); result.IsError {
+		t.Fatalf("start: %s", result.ForLLM)
+	}
+	if result := tool.Execute(ctx, map[string]any{"action": "send", "input": "sudo ls"}); !result.IsError {
+		t.Error("expected deny pattern to block input")
+	}
+	if result := tool.Execute(ctx, map[string]any{"action": "start", "name": "other", "working_dir": "/etc"}); !result.IsError {
+		t.Error("expected working_dir outside the workspace to be rejected")
+	}
+
+	result := tool.Execute(ctx, map[string]any{"action": "send", "input": "cd /"})
+	if _, err := os.Stat("/proc/self/cwd"); err == nil && !strings.Contains(result.ForLLM, "outside the workspace") {
+		t.Errorf("expected the shell to be moved back into the workspace, got: %q", result.ForLLM)
+	}
+}
+
+func TestShellSessionTool_GuardsInputSplitAcrossSends(t *testing.T) {
+	tool := newTestShellSessionTool(t, t.TempDir(), true)
+	ctx := shellSessionCtx("split")
+
+	if result := tool.Execute(ctx, map[string]any{"action": "start"}); result.IsError {
+		t.Fatalf("start: %s", result.ForLLM)
+	}
+	if result := tool.Execute(ctx, map[string]any{"action": "send", "input": "rm ", "enter": false}); result.IsError {
+		t.Fatalf("send: %s", result.ForLLM)
+	}
+	if result := tool.Execute(ctx, map[string]any{"action": "send", "input": "-rf sub"}); !result.IsError {
+		t.Error("expected deny pattern to block a command typed in pieces")
+	}
+}
+
+func TestShellSessionTool_IdleReaping(t *testing.T) {
+	execTool, err := NewExecTool(t.TempDir(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tool := NewShellSessionTool(execTool, 0, 0, 100*time.Millisecond)
+	ctx := shellSessionCtx("idle")
+
+	if result := tool.Execute(ctx, map[string]any{"action": "start", "wait_ms": float64(0)}); result.IsError {
+		t.Fatalf("start: %s", result.ForLLM)
+	}
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if tool.get("idle", "default") == nil {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatal("expected idle session to be reaped")
+}
+
+func TestShellSessionTool_RegistryCloseKillsSessions(t *testing.T) {
+	tool := newTestShellSessionTool(t, t.TempDir(), false)
+	registry := NewToolRegistry()
+	registry.Register(tool)
+	ctx := shellSessionCtx("shutdown")
+
+	if result := tool.Execute(ctx, map[string]any{"action": "start", "wait_ms": float64(0)}); result.IsError {
+		t.Fatalf("start: %s", result.ForLLM)
+	}
+	s := tool.get("shutdown", "default")
+	if s == nil {
+		t.Fatal("session not started")
+	}
+
+	registry.Close()
+	select {
+	case <-s.done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("shell still running after the registry was closed")
+	}
+	if tool.get("shutdown", "default") != nil {
+		t.Error("closed session still listed")
+	}
+}
+
+func TestOutputBuffer_DropsOldestOutput(t *testing.T) {
+	b := newOutputBuffer(8)
+	b.Write([]byte("hello "))
+	out, next, dropped := b.since(0)
+	if out != "hello " || next != 6 || dropped != 0 {
+		t.Fatalf("since(0) = %q, %d, %d", out, next, dropped)
+	}
+
+	b.Write([]byte("world!"))
+	out, next, dropped = b.since(next)
+	if out != "world!" || next != 12 || dropped != 0 {
+		t.Fatalf("since(6) = %q, %d, %d", out, next, dropped)
+	}
+
+	out, _, dropped = b.since(0)
+	if out != "o world!" || dropped != 4 {
+		t.Errorf("since(0) after overflow = %q, dropped %d", out, dropped)
+	}
+}
@@ -0,0 +1,23 @@
+//go:build !windows
+
+package tools
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+
+	"github.com/creack/pty"
+)
+
+// startPTY starts cmd as a session leader with a new pseudo-terminal as its
+// controlling terminal, keeping any namespace settings already on cmd.
+func startPTY(cmd *exec.Cmd) (*os.File, error) {
+	attrs := cmd.SysProcAttr
+	if attrs == nil {
+		attrs = &syscall.SysProcAttr{}
+	}
+	attrs.Setsid = true
+	attrs.Setctty = true
+	return pty.StartWithAttrs(cmd, &pty.Winsize{Rows: 40, Cols: 200}, attrs)
+}
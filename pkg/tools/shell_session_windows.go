@@ -0,0 +1,13 @@
+//go:build windows
+
+package tools
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+func startPTY(cmd *exec.Cmd) (*os.File, error) {
+	return nil, errors.New("shell sessions are not supported on Windows")
+}
@@ -43,6 +43,11 @@ func RunToolLoop(
 	iteration := 0
 	var finalContent string
 
+	// Track untrusted content per run unless the caller's turn already does.
+	if TurnTrustFrom(ctx) == nil {
+		ctx = WithTurnTrust(ctx, NewTurnTrust())
+	}
+
 	for iteration < config.MaxIterations {
 		iteration++
 
@@ -55,7 +60,7 @@ func RunToolLoop(
 		// 1. Build tool definitions
 		var providerToolDefs []providers.ToolDefinition
 		if config.Tools != nil {
-			providerToolDefs = config.Tools.ToProviderDefs()
+			providerToolDefs = config.Tools.ToProviderDefs(ctx)
 		}
 
 		// 2. Set default LLM options
@@ -16,6 +16,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/sipeed/picoclaw/pkg/egress"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/utils"
 )
@@ -24,7 +25,7 @@ const (
 	userAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
 
 	// HTTP client timeouts for web tool providers.
-	searchTimeout     = 10 * time.Second // Brave, Tavily, DuckDuckGo
+	searchTimeout     = 10 * time.Second // Brave, SearXNG, Tavily, DuckDuckGo
 	perplexityTimeout = 30 * time.Second // Perplexity (LLM-based, slower)
 	fetchTimeout      = 60 * time.Second // WebFetchTool
 
@@ -469,6 +470,7 @@ func (p *PerplexitySearchProvider) Search(ctx context.Context, query string, cou
 
 type SearXNGSearchProvider struct {
 	baseURL string
+	client  *http.Client
 }
 
 func (p *SearXNGSearchProvider) Search(ctx context.Context, query string, count int) (string, error) {
@@ -481,8 +483,7 @@ func (p *SearXNGSearchProvider) Search(ctx context.Context, query string, count
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := p.client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("request failed: %w", err)
 	}
@@ -667,7 +668,13 @@ func NewWebSearchTool(opts WebSearchToolOptions) (*WebSearchTool, error) {
 			maxResults = opts.BraveMaxResults
 		}
 	} else if opts.SearXNGEnabled && opts.SearXNGBaseURL != "" {
-		provider = &SearXNGSearchProvider{baseURL: opts.SearXNGBaseURL}
+		// SearXNG is usually self-hosted, so it is reached without the
+		// configured proxy.
+		client, err := utils.CreateHTTPClient("", searchTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create HTTP client for SearXNG: %w", err)
+		}
+		provider = &SearXNGSearchProvider{baseURL: opts.SearXNGBaseURL, client: client}
 		if opts.SearXNGMaxResults > 0 {
 			maxResults = opts.SearXNGMaxResults
 		}
@@ -727,6 +734,11 @@ func (t *WebSearchTool) Name() string {
 	return "web_search"
 }
 
+// UntrustedOutput implements UntrustedSource. Search results come from arbitrary web pages.
+func (t *WebSearchTool) UntrustedOutput() bool {
+	return true
+}
+
 func (t *WebSearchTool) Description() string {
 	return "Search the web for current information. Returns titles, URLs, and snippets from search results."
 }
@@ -821,26 +833,10 @@ func NewWebFetchToolWithConfig(
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse web fetch private host whitelist: %w", err)
 	}
-	client, err := utils.CreateHTTPClient(proxy, fetchTimeout)
+	client, err := newSafeHTTPClient(proxy, fetchTimeout, whitelist)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create HTTP client for web fetch: %w", err)
 	}
-	if transport, ok := client.Transport.(*http.Transport); ok {
-		dialer := &net.Dialer{
-			Timeout:   15 * time.Second,
-			KeepAlive: 30 * time.Second,
-		}
-		transport.DialContext = newSafeDialContext(dialer, whitelist)
-	}
-	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
-		if len(via) >= maxRedirects {
-			return fmt.Errorf("stopped after %d redirects", maxRedirects)
-		}
-		if isObviousPrivateHost(req.URL.Hostname(), whitelist) {
-			return fmt.Errorf("redirect target is private or local network host")
-		}
-		return nil
-	}
 	if fetchLimitBytes <= 0 {
 		fetchLimitBytes = 10 * 1024 * 1024 // Security Fallback
 	}
@@ -858,6 +854,11 @@ func (t *WebFetchTool) Name() string {
 	return "web_fetch"
 }
 
+// UntrustedOutput implements UntrustedSource. Fetched pages are attacker-controllable.
+func (t *WebFetchTool) UntrustedOutput() bool {
+	return true
+}
+
 func (t *WebFetchTool) Description() string {
 	return "Fetch a URL and extract readable content (HTML to text). Use this to get weather info, news, articles, or any web content."
 }
@@ -1063,6 +1064,33 @@ func (t *WebFetchTool) extractText(htmlContent string) string {
 	return strings.Join(cleanLines, "\n")
 }
 
+// newSafeHTTPClient returns a client whose connections, including those made
+// for redirects, are checked by newSafeDialContext and the egress policy.
+func newSafeHTTPClient(
+	proxy string,
+	timeout time.Duration,
+	whitelist *privateHostWhitelist,
+) (*http.Client, error) {
+	dialer := &net.Dialer{
+		Timeout:   15 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	client, err := utils.CreateHTTPClientWithDialer(proxy, timeout, newSafeDialContext(dialer, whitelist))
+	if err != nil {
+		return nil, err
+	}
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		if isObviousPrivateHost(req.URL.Hostname(), whitelist) {
+			return fmt.Errorf("redirect target is private or local network host")
+		}
+		return nil
+	}
+	return client, nil
+}
+
 // newSafeDialContext re-resolves DNS at connect time to mitigate DNS rebinding (TOCTOU)
 // where a hostname resolves to a public IP during pre-flight but a private IP at connect time.
 func newSafeDialContext(
@@ -1205,49 +1233,7 @@ func isObviousPrivateHost(host string, whitelist *privateHostWhitelist) bool {
 	return false
 }
 
-// isPrivateOrRestrictedIP returns true for IPs that should never be reached via web_fetch:
-// RFC 1918, loopback, link-local (incl. cloud metadata 169.254.x.x), carrier-grade NAT,
-// IPv6 unique-local (fc00::/7), 6to4 (2002::/16), and Teredo (2001:0000::/32).
+// isPrivateOrRestrictedIP returns true for IPs that should never be reached via web_fetch.
 func isPrivateOrRestrictedIP(ip net.IP) bool {
-	if ip == nil {
-		return true
-	}
-
-	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
-		ip.IsMulticast() || ip.IsUnspecified() {
-		return true
-	}
-
-	if ip4 := ip.To4(); ip4 != nil {
-		// IPv4 private, loopback, link-local, and carrier-grade NAT ranges.
-		if ip4[0] == 10 ||
-			ip4[0] == 127 ||
-			ip4[0] == 0 ||
-			(ip4[0] == 172 && ip4[1] >= 16 && ip4[1] <= 31) ||
-			(ip4[0] == 192 && ip4[1] == 168) ||
-			(ip4[0] == 169 && ip4[1] == 254) ||
-			(ip4[0] == 100 && ip4[1] >= 64 && ip4[1] <= 127) {
-			return true
-		}
-		return false
-	}
-
-	if len(ip) == net.IPv6len {
-		// IPv6 unique local addresses (fc00::/7)
-		if (ip[0] & 0xfe) == 0xfc {
-			return true
-		}
-		// 6to4 addresses (2002::/16): check the embedded IPv4 at bytes [2:6].
-		if ip[0] == 0x20 && ip[1] == 0x02 {
-			embedded := net.IPv4(ip[2], ip[3], ip[4], ip[5])
-			return isPrivateOrRestrictedIP(embedded)
-		}
-		// Teredo (2001:0000::/32): client IPv4 is at bytes [12:16], XOR-inverted.
-		if ip[0] == 0x20 && ip[1] == 0x01 && ip[2] == 0x00 && ip[3] == 0x00 {
-			client := net.IPv4(ip[12]^0xff, ip[13]^0xff, ip[14]^0xff, ip[15]^0xff)
-			return isPrivateOrRestrictedIP(client)
-		}
-	}
-
-	return false
+	return egress.IsPrivateIP(ip)
 }
@@ -12,6 +12,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/egress"
 	"github.com/sipeed/picoclaw/pkg/logger"
 )
 
@@ -1189,3 +1191,26 @@ func TestWebTool_GLMSearch_Priority(t *testing.T) {
 		t.Errorf("Expected GLMSearchProvider when only GLM enabled, got %T", tool2.provider)
 	}
 }
+
+func TestWebSearchTool_SearXNGObeysEgressPolicy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"results":[]}`))
+	}))
+	defer server.Close()
+
+	cfg := config.EgressConfig{Enabled: true}
+	cfg.BlockedCIDRs = []string{"127.0.0.0/8"}
+	if err := egress.Install(cfg); err != nil {
+		t.Fatal(err)
+	}
+	defer egress.Install(config.EgressConfig{})
+
+	tool, err := NewWebSearchTool(WebSearchToolOptions{SearXNGEnabled: true, SearXNGBaseURL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := tool.Execute(context.Background(), map[string]any{"query": "picoclaw"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "blocked range") {
+		t.Errorf("SearXNG search to a blocked address = %q, want it refused", result.ForLLM)
+	}
+}
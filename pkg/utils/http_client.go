@@ -6,15 +6,29 @@ import (
 	"net/url"
 	"strings"
 	"time"
+
+	"github.com/sipeed/picoclaw/pkg/egress"
 )
 
 // CreateHTTPClient creates an HTTP client with optional proxy support.
 // If proxyURL is empty, it uses the system environment proxy settings.
 // Supported proxy schemes: http, https, socks5, socks5h.
+// Requests and connections are checked against the egress policy.
 func CreateHTTPClient(proxyURL string, timeout time.Duration) (*http.Client, error) {
+	return CreateHTTPClientWithDialer(proxyURL, timeout, nil)
+}
+
+// CreateHTTPClientWithDialer is CreateHTTPClient with dial as the dialer
+// beneath the egress policy. A nil dial uses the transport default.
+func CreateHTTPClientWithDialer(
+	proxyURL string,
+	timeout time.Duration,
+	dial egress.DialFunc,
+) (*http.Client, error) {
 	client := &http.Client{
 		Timeout: timeout,
 		Transport: &http.Transport{
+			DialContext:         dial,
 			MaxIdleConns:        10,
 			IdleConnTimeout:     30 * time.Second,
 			DisableCompression:  false,
@@ -43,6 +57,7 @@ func CreateHTTPClient(proxyURL string, timeout time.Duration) (*http.Client, err
 	} else {
 		client.Transport.(*http.Transport).Proxy = http.ProxyFromEnvironment
 	}
+	egress.Guard(client.Transport.(*http.Transport))
 
 	return client, nil
 }
@@ -1,10 +1,15 @@
 package utils
 
 import (
+	"errors"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/egress"
 )
 
 func TestCreateHTTPClient_ProxyConfigured(t *testing.T) {
@@ -108,3 +113,31 @@ func TestCreateHTTPClient_ProxyFromEnvironmentWhenConfigEmpty(t *testing.T) {
 		t.Fatalf("transport.Proxy(req) error: %v", err)
 	}
 }
+
+func TestCreateHTTPClient_EgressPolicy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	client, err := CreateHTTPClient("", 5*time.Second)
+	if err != nil {
+		t.Fatalf("createHTTPClient() error: %v", err)
+	}
+	if err := egress.Install(config.EgressConfig{
+		Enabled:     true,
+		EgressRules: config.EgressRules{BlockedCIDRs: []string{"127.0.0.0/8"}},
+	}); err != nil {
+		t.Fatalf("egress.Install() error: %v", err)
+	}
+	_, err = client.Get(server.URL)
+	egress.Install(config.EgressConfig{})
+	var blocked *egress.BlockedError
+	if !errors.As(err, &blocked) {
+		t.Fatalf("Get() error = %v, want *egress.BlockedError", err)
+	}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() after removing the policy error: %v", err)
+	}
+	resp.Body.Close()
+}
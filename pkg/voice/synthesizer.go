@@ -0,0 +1,309 @@
+package voice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/identity"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/media"
+)
+
+// MaxSynthesisChars bounds the text sent to an engine. Longer replies are
+// delivered as text only; OpenAI's /audio/speech rejects input above 4096
+// characters and local engines get slow well before that.
+const MaxSynthesisChars = 4096
+
+const commandOutputPlaceholder = "{output}"
+
+type Synthesizer interface {
+	Name() string
+	Synthesize(ctx context.Context, text string) (*SynthesisResult, error)
+}
+
+// SynthesisResult describes an audio file written by a Synthesizer. The
+// caller owns the file and should register it with a MediaStore.
+type SynthesisResult struct {
+	Path        string
+	ContentType string
+}
+
+// OpenAISynthesizer calls an OpenAI-compatible /audio/speech endpoint.
+type OpenAISynthesizer struct {
+	apiKey     string
+	apiBase    string
+	model      string
+	voice      string
+	format     string
+	httpClient *http.Client
+}
+
+func NewOpenAISynthesizer(apiBase, apiKey, model, voice, format string) *OpenAISynthesizer {
+	logger.DebugCF("voice", "Creating OpenAI synthesizer", map[string]any{
+		"has_api_key": apiKey != "",
+		"model":       model,
+	})
+
+	if apiBase == "" {
+		apiBase = "https://api.openai.com/v1"
+	}
+	return &OpenAISynthesizer{
+		apiKey:  apiKey,
+		apiBase: strings.TrimRight(apiBase, "/"),
+		model:   model,
+		voice:   voice,
+		format:  normalizeFormat(format, "opus"),
+		httpClient: &http.Client{
+			Timeout: 60 * time.Second,
+		},
+	}
+}
+
+func (s *OpenAISynthesizer) Name() string {
+	return "openai"
+}
+
+func (s *OpenAISynthesizer) Synthesize(ctx context.Context, text string) (*SynthesisResult, error) {
+	payload, err := json.Marshal(map[string]string{
+		"model":           s.model,
+		"voice":           s.voice,
+		"input":           text,
+		"response_format": s.format,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := s.apiBase + "/audio/speech"
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	out, err := createOutputFile(s.format)
+	if err != nil {
+		return nil, err
+	}
+	written, err := io.Copy(out, resp.Body)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(out.Name())
+		return nil, fmt.Errorf("failed to write audio: %w", err)
+	}
+
+	logger.InfoCF("voice", "Speech synthesized", map[string]any{
+		"provider":    s.Name(),
+		"text_length": len(text),
+		"audio_bytes": written,
+	})
+
+	return &SynthesisResult{Path: out.Name(), ContentType: formatContentType(s.format)}, nil
+}
+
+// CommandSynthesizer runs a local engine such as piper. The text is written to
+// the command's stdin; the audio is read from the file substituted for
+// "{output}" or, when no argument contains the placeholder, from stdout.
+type CommandSynthesizer struct {
+	command []string
+	format  string
+	timeout time.Duration
+}
+
+func NewCommandSynthesizer(command []string, format string) *CommandSynthesizer {
+	return &CommandSynthesizer{
+		command: command,
+		format:  normalizeFormat(format, "wav"),
+		timeout: 60 * time.Second,
+	}
+}
+
+func (s *CommandSynthesizer) Name() string {
+	if len(s.command) == 0 {
+		return "command"
+	}
+	return filepath.Base(s.command[0])
+}
+
+func (s *CommandSynthesizer) Synthesize(ctx context.Context, text string) (*SynthesisResult, error) {
+	if len(s.command) == 0 {
+		return nil, fmt.Errorf("no synthesis command configured")
+	}
+
+	out, err := createOutputFile(s.format)
+	if err != nil {
+		return nil, err
+	}
+	outPath := out.Name()
+
+	args := make([]string, 0, len(s.command)-1)
+	toFile := false
+	for _, arg := range s.command[1:] {
+		if strings.Contains(arg, commandOutputPlaceholder) {
+			toFile = true
+			arg = strings.ReplaceAll(arg, commandOutputPlaceholder, outPath)
+		}
+		args = append(args, arg)
+	}
+
+	cmdCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(cmdCtx, s.command[0], args...)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stderr = &stderr
+	if !toFile {
+		cmd.Stdout = out
+	}
+
+	err = cmd.Run()
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(outPath)
+		return nil, fmt.Errorf("synthesis command failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	info, err := os.Stat(outPath)
+	if err != nil || info.Size() == 0 {
+		os.Remove(outPath)
+		return nil, fmt.Errorf("synthesis command produced no audio")
+	}
+
+	logger.InfoCF("voice", "Speech synthesized", map[string]any{
+		"provider":    s.Name(),
+		"text_length": len(text),
+		"audio_bytes": info.Size(),
+	})
+
+	return &SynthesisResult{Path: outPath, ContentType: formatContentType(s.format)}, nil
+}
+
+// DetectSynthesizer inspects cfg and returns the configured Synthesizer, or
+// nil if text-to-speech is disabled or incomplete.
+func DetectSynthesizer(cfg *config.Config) Synthesizer {
+	tts := cfg.Voice.TTS
+	if !tts.Enabled {
+		return nil
+	}
+
+	switch tts.Provider {
+	case "command":
+		if len(tts.Command) == 0 {
+			logger.WarnCF("voice", "TTS provider \"command\" requires voice.tts.command", nil)
+			return nil
+		}
+		return NewCommandSynthesizer(tts.Command, tts.Format)
+	case "", "openai":
+		apiKey, apiBase := tts.APIKey, tts.APIBase
+		if apiKey == "" {
+			apiKey = cfg.Providers.OpenAI.APIKey
+		}
+		if apiBase == "" {
+			apiBase = cfg.Providers.OpenAI.APIBase
+		}
+		if apiKey == "" && apiBase == "" {
+			logger.WarnCF("voice", "TTS provider \"openai\" requires an API key or API base", nil)
+			return nil
+		}
+		return NewOpenAISynthesizer(apiBase, apiKey, tts.Model, tts.Voice, tts.Format)
+	default:
+		logger.WarnCF("voice", "Unknown TTS provider", map[string]any{"provider": tts.Provider})
+		return nil
+	}
+}
+
+// ShouldReplyWithVoice reports whether a voice message from sender on channel
+// should be answered with a voice reply. A matching user entry takes
+// precedence over a channel entry, which takes precedence over the default.
+// When several user entries match, the first in sorted order wins.
+func ShouldReplyWithVoice(tts config.TTSConfig, channel string, sender bus.SenderInfo) bool {
+	keys := make([]string, 0, len(tts.Users))
+	for key := range tts.Users {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if identity.MatchAllowed(sender, key) {
+			return tts.Users[key]
+		}
+	}
+	if enabled, ok := tts.Channels[channel]; ok {
+		return enabled
+	}
+	return tts.ReplyWhenSpokenTo
+}
+
+func normalizeFormat(format, fallback string) string {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" {
+		return fallback
+	}
+	return format
+}
+
+func formatContentType(format string) string {
+	switch format {
+	case "opus":
+		return "audio/ogg"
+	case "mp3":
+		return "audio/mpeg"
+	case "wav":
+		return "audio/wav"
+	case "aac":
+		return "audio/aac"
+	case "flac":
+		return "audio/flac"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+func formatExtension(format string) string {
+	if format == "opus" {
+		return ".ogg"
+	}
+	return "." + format
+}
+
+func createOutputFile(format string) (*os.File, error) {
+	dir := media.TempDir()
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create media directory: %w", err)
+	}
+	f, err := os.CreateTemp(dir, "tts-*"+formatExtension(format))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create audio file: %w", err)
+	}
+	return f, nil
+}
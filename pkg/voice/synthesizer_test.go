@@ -0,0 +1,177 @@
+package voice
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+var (
+	_ Synthesizer = (*OpenAISynthesizer)(nil)
+	_ Synthesizer = (*CommandSynthesizer)(nil)
+)
+
+func TestOpenAISynthesizer_Synthesize(t *testing.T) {
+	var got map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/audio/speech" {
+			t.Errorf("path = %q, want /audio/speech", r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer sk-test" {
+			t.Errorf("Authorization = %q", auth)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		w.Write([]byte("OggS-fake-audio"))
+	}))
+	defer srv.Close()
+
+	s := NewOpenAISynthesizer(srv.URL+"/", "sk-test", "tts-1", "alloy", "")
+	result, err := s.Synthesize(context.Background(), "hello there")
+	if err != nil {
+		t.Fatalf("Synthesize: %v", err)
+	}
+	defer os.Remove(result.Path)
+
+	if got["input"] != "hello there" || got["voice"] != "alloy" || got["response_format"] != "opus" {
+		t.Errorf("unexpected request body: %v", got)
+	}
+	if result.ContentType != "audio/ogg" {
+		t.Errorf("ContentType = %q, want audio/ogg", result.ContentType)
+	}
+	data, err := os.ReadFile(result.Path)
+	if err != nil || string(data) != "OggS-fake-audio" {
+		t.Errorf("audio file = %q, %v", data, err)
+	}
+}
+
+func TestOpenAISynthesizer_APIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad voice", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	s := NewOpenAISynthesizer(srv.URL, "sk-test", "tts-1", "nope", "mp3")
+	if _, err := s.Synthesize(context.Background(), "hello"); err == nil {
+		t.Fatal("expected error for non-200 response")
+	}
+}
+
+func TestCommandSynthesizer(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	tests := []struct {
+		name    string
+		command []string
+	}{
+		{name: "stdout", command: []string{"cat"}},
+		{name: "output file", command: []string{"sh", "-c", "cat > \"$1\"", "sh", "{output}"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewCommandSynthesizer(tt.command, "")
+			result, err := s.Synthesize(context.Background(), "spoken text")
+			if err != nil {
+				t.Fatalf("Synthesize: %v", err)
+			}
+			defer os.Remove(result.Path)
+
+			if result.ContentType != "audio/wav" {
+				t.Errorf("ContentType = %q, want audio/wav", result.ContentType)
+			}
+			data, err := os.ReadFile(result.Path)
+			if err != nil || string(data) != "spoken text" {
+				t.Errorf("audio file = %q, %v", data, err)
+			}
+		})
+	}
+}
+
+func TestCommandSynthesizer_Failure(t *testing.T) {
+	if _, err := exec.LookPath("false"); err != nil {
+		t.Skip("false not available")
+	}
+	s := NewCommandSynthesizer([]string{"false"}, "wav")
+	if _, err := s.Synthesize(context.Background(), "hello"); err == nil {
+		t.Fatal("expected error when the command fails")
+	}
+}
+
+func TestDetectSynthesizer(t *testing.T) {
+	tests := []struct {
+		name     string
+		tts      config.TTSConfig
+		openAI   string
+		wantName string
+	}{
+		{name: "disabled", tts: config.TTSConfig{Provider: "openai", APIKey: "sk"}},
+		{name: "openai key", tts: config.TTSConfig{Enabled: true, Provider: "openai", APIKey: "sk"}, wantName: "openai"},
+		{name: "openai provider fallback", tts: config.TTSConfig{Enabled: true}, openAI: "sk", wantName: "openai"},
+		{name: "openai without credentials", tts: config.TTSConfig{Enabled: true, Provider: "openai"}},
+		{
+			name:     "command",
+			tts:      config.TTSConfig{Enabled: true, Provider: "command", Command: []string{"/usr/bin/piper", "-f", "{output}"}},
+			wantName: "piper",
+		},
+		{name: "command without argv", tts: config.TTSConfig{Enabled: true, Provider: "command"}},
+		{name: "unknown provider", tts: config.TTSConfig{Enabled: true, Provider: "espeak"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Voice: config.VoiceConfig{TTS: tt.tts}}
+			cfg.Providers.OpenAI.APIKey = tt.openAI
+			s := DetectSynthesizer(cfg)
+			if tt.wantName == "" {
+				if s != nil {
+					t.Errorf("DetectSynthesizer() = %v, want nil", s)
+				}
+				return
+			}
+			if s == nil || s.Name() != tt.wantName {
+				t.Errorf("DetectSynthesizer() = %v, want %q", s, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestShouldReplyWithVoice(t *testing.T) {
+	alice := bus.SenderInfo{Platform: "telegram", PlatformID: "42", CanonicalID: "telegram:42"}
+	bob := bus.SenderInfo{Platform: "telegram", PlatformID: "7", CanonicalID: "telegram:7"}
+	tts := config.TTSConfig{
+		ReplyWhenSpokenTo: true,
+		Channels:          map[string]bool{"telegram": false},
+		Users:             map[string]bool{"telegram:42": true},
+	}
+
+	if !ShouldReplyWithVoice(tts, "telegram", alice) {
+		t.Error("user override should enable voice replies")
+	}
+	if ShouldReplyWithVoice(tts, "telegram", bob) {
+		t.Error("channel override should disable voice replies")
+	}
+	if !ShouldReplyWithVoice(tts, "discord", bob) {
+		t.Error("default should apply to channels without an override")
+	}
+}
+
+func TestShouldReplyWithVoice_OverlappingUserEntries(t *testing.T) {
+	alice := bus.SenderInfo{Platform: "telegram", PlatformID: "42", CanonicalID: "telegram:42"}
+	tts := config.TTSConfig{Users: map[string]bool{"42": true, "telegram:42": false}}
+
+	// "42" sorts first, so it wins every time.
+	for i := 0; i < 20; i++ {
+		if !ShouldReplyWithVoice(tts, "telegram", alice) {
+			t.Fatal("overlapping user entries gave a different answer")
+		}
+	}
+}
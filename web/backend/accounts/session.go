@@ -0,0 +1,165 @@
+package accounts
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Session cookie and CSRF settings.
+const (
+	// SessionCookie holds the session ID. It is HttpOnly.
+	SessionCookie = "picoclaw_session"
+	// CSRFCookie holds the session's CSRF token so the frontend can echo it
+	// in the CSRFHeader of state-changing requests.
+	CSRFCookie = "picoclaw_csrf"
+	CSRFHeader = "X-CSRF-Token"
+
+	// SessionTTL is how long a session lasts without activity.
+	SessionTTL = 24 * time.Hour
+)
+
+// Session is a logged-in browser session.
+type Session struct {
+	ID       string
+	CSRF     string
+	Username string
+	Expires  time.Time
+}
+
+// Sessions keeps login sessions in memory; restarting the launcher logs
+// everyone out.
+type Sessions struct {
+	mu       sync.Mutex
+	sessions map[string]*Session
+	now      func() time.Time
+}
+
+// NewSessions creates an empty session table.
+func NewSessions() *Sessions {
+	return &Sessions{sessions: make(map[string]*Session), now: time.Now}
+}
+
+// Create starts a session for username.
+func (s *Sessions) Create(username string) (Session, error) {
+	id, err := randomHex(32)
+	if err != nil {
+		return Session{}, err
+	}
+	csrf, err := randomHex(32)
+	if err != nil {
+		return Session{}, err
+	}
+	sess := &Session{ID: id, CSRF: csrf, Username: username, Expires: s.now().Add(SessionTTL)}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gc()
+	s.sessions[id] = sess
+	return *sess, nil
+}
+
+// Get returns the live session with id and extends its lifetime.
+func (s *Sessions) Get(id string) (Session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[id]
+	if !ok {
+		return Session{}, false
+	}
+	now := s.now()
+	if now.After(sess.Expires) {
+		delete(s.sessions, id)
+		return Session{}, false
+	}
+	sess.Expires = now.Add(SessionTTL)
+	return *sess, true
+}
+
+// Delete ends a session.
+func (s *Sessions) Delete(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+}
+
+// DeleteUser ends all of username's sessions except keepID.
+func (s *Sessions) DeleteUser(username, keepID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, sess := range s.sessions {
+		if id != keepID && strings.EqualFold(sess.Username, username) {
+			delete(s.sessions, id)
+		}
+	}
+}
+
+// gc drops expired sessions. The caller holds s.mu.
+func (s *Sessions) gc() {
+	now := s.now()
+	for id, sess := range s.sessions {
+		if now.After(sess.Expires) {
+			delete(s.sessions, id)
+		}
+	}
+}
+
+// Login throttling.
+const (
+	maxLoginFailures = 5
+	loginLockout     = 5 * time.Minute
+)
+
+type loginFailures struct {
+	count int
+	last  time.Time
+}
+
+// LoginLimiter slows down password guessing: after maxLoginFailures failed
+// attempts for the same key, further attempts are refused until loginLockout
+// has passed since the last failure.
+type LoginLimiter struct {
+	mu       sync.Mutex
+	failures map[string]*loginFailures
+	now      func() time.Time
+}
+
+// NewLoginLimiter creates a limiter.
+func NewLoginLimiter() *LoginLimiter {
+	return &LoginLimiter{failures: make(map[string]*loginFailures), now: time.Now}
+}
+
+// Allowed reports whether key may attempt to log in.
+func (l *LoginLimiter) Allowed(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, ok := l.failures[key]
+	if !ok {
+		return true
+	}
+	if l.now().Sub(f.last) > loginLockout {
+		delete(l.failures, key)
+		return true
+	}
+	return f.count < maxLoginFailures
+}
+
+// Fail records a failed attempt for key.
+func (l *LoginLimiter) Fail(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, ok := l.failures[key]
+	if !ok {
+		f = &loginFailures{}
+		l.failures[key] = f
+	}
+	f.count++
+	f.last = l.now()
+}
+
+// Reset clears key's failures after a successful login.
+func (l *LoginLimiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.failures, key)
+}
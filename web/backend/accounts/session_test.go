@@ -0,0 +1,71 @@
+package accounts
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionsExpireAndSlide(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	s := NewSessions()
+	s.now = func() time.Time { return now }
+
+	sess, err := s.Create("alice")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	now = now.Add(SessionTTL - time.Minute)
+	if _, ok := s.Get(sess.ID); !ok {
+		t.Fatal("session expired before its TTL")
+	}
+	// The Get above extended the session.
+	now = now.Add(SessionTTL - time.Minute)
+	if _, ok := s.Get(sess.ID); !ok {
+		t.Fatal("session did not slide")
+	}
+	now = now.Add(SessionTTL + time.Minute)
+	if _, ok := s.Get(sess.ID); ok {
+		t.Fatal("idle session still valid")
+	}
+}
+
+func TestSessionsDeleteUser(t *testing.T) {
+	s := NewSessions()
+	keep, _ := s.Create("alice")
+	drop, _ := s.Create("Alice")
+	other, _ := s.Create("bob")
+
+	s.DeleteUser("alice", keep.ID)
+	if _, ok := s.Get(keep.ID); !ok {
+		t.Fatal("kept session was deleted")
+	}
+	if _, ok := s.Get(drop.ID); ok {
+		t.Fatal("other session of the user survived")
+	}
+	if _, ok := s.Get(other.ID); !ok {
+		t.Fatal("another user's session was deleted")
+	}
+}
+
+func TestLoginLimiter(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	l := NewLoginLimiter()
+	l.now = func() time.Time { return now }
+
+	for range maxLoginFailures {
+		if !l.Allowed("alice|1.2.3.4") {
+			t.Fatal("locked out too early")
+		}
+		l.Fail("alice|1.2.3.4")
+	}
+	if l.Allowed("alice|1.2.3.4") {
+		t.Fatal("not locked out after repeated failures")
+	}
+	if !l.Allowed("alice|5.6.7.8") {
+		t.Fatal("lockout leaked to another key")
+	}
+	now = now.Add(loginLockout + time.Second)
+	if !l.Allowed("alice|1.2.3.4") {
+		t.Fatal("lockout did not expire")
+	}
+}
@@ -0,0 +1,544 @@
+// Package accounts manages the web launcher's local user accounts: hashed
+// passwords, optional TOTP, API tokens for scripted access and login
+// sessions.
+//
+// Accounts are stored in launcher-users.json next to the app config. While
+// no account exists the launcher stays open, as it was before accounts were
+// introduced; creating the first (admin) account turns login on.
+package accounts
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// FileName is the account store file name.
+const FileName = "launcher-users.json"
+
+// Roles.
+const (
+	// RoleAdmin has full control over the launcher.
+	RoleAdmin = "admin"
+	// RoleViewer can chat and read status, sessions and logs, but cannot
+	// change settings or see secrets.
+	RoleViewer = "viewer"
+)
+
+// MinPasswordLength is the shortest accepted password.
+const MinPasswordLength = 8
+
+// tokenPrefix marks API tokens so they are recognizable in scripts and logs.
+const tokenPrefix = "pct_"
+
+var (
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrTOTPRequired       = errors.New("a TOTP code is required")
+	ErrInvalidTOTP        = errors.New("invalid TOTP code")
+	ErrUserExists         = errors.New("user already exists")
+	ErrUserNotFound       = errors.New("user not found")
+	ErrLastAdmin          = errors.New("at least one admin account must remain")
+	ErrTokenNotFound      = errors.New("token not found")
+)
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9._@-]{1,64}$`)
+
+// User is a launcher account.
+type User struct {
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"password_hash"`
+	Role         string    `json:"role"`
+	TOTPSecret   string    `json:"totp_secret,omitempty"`
+	TOTPEnabled  bool      `json:"totp_enabled,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Token is an API token. Only a hash of the secret is stored.
+type Token struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Username  string    `json:"username"`
+	Hash      string    `json:"hash"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type storeFile struct {
+	Users  []*User  `json:"users"`
+	Tokens []*Token `json:"tokens,omitempty"`
+}
+
+// Store is the file-backed account store. It is safe for concurrent use.
+type Store struct {
+	path string
+
+	mu     sync.Mutex
+	users  map[string]*User
+	tokens []*Token
+	// lastTOTPStep remembers the last accepted TOTP time step per user so a
+	// code cannot be replayed.
+	lastTOTPStep map[string]int64
+}
+
+// PathForAppConfig returns the account store path next to the app config.
+func PathForAppConfig(appConfigPath string) string {
+	return filepath.Join(filepath.Dir(appConfigPath), FileName)
+}
+
+// Open loads the store at path. A missing file yields an empty store.
+func Open(path string) (*Store, error) {
+	s := &Store{
+		path:         path,
+		users:        make(map[string]*User),
+		lastTOTPStep: make(map[string]int64),
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("read account store: %w", err)
+	}
+	var f storeFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse account store: %w", err)
+	}
+	for _, u := range f.Users {
+		s.users[strings.ToLower(u.Username)] = u
+	}
+	s.tokens = f.Tokens
+	return s, nil
+}
+
+// save writes the store to disk. The caller holds s.mu.
+func (s *Store) save() error {
+	f := storeFile{Tokens: s.tokens}
+	for _, u := range s.users {
+		f.Users = append(f.Users, u)
+	}
+	sort.Slice(f.Users, func(i, j int) bool { return f.Users[i].Username < f.Users[j].Username })
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// HasUsers reports whether any account exists, i.e. whether login is required.
+func (s *Store) HasUsers() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.users) > 0
+}
+
+// Users returns copies of all accounts, sorted by name.
+func (s *Store) Users() []User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	users := make([]User, 0, len(s.users))
+	for _, u := range s.users {
+		users = append(users, *u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Username < users[j].Username })
+	return users
+}
+
+// User returns a copy of the named account.
+func (s *Store) User(username string) (User, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[strings.ToLower(username)]
+	if !ok {
+		return User{}, false
+	}
+	return *u, true
+}
+
+// ValidRole reports whether role is a known role.
+func ValidRole(role string) bool {
+	return role == RoleAdmin || role == RoleViewer
+}
+
+func validatePassword(password string) error {
+	if len(password) < MinPasswordLength {
+		return fmt.Errorf("password must be at least %d characters", MinPasswordLength)
+	}
+	// bcrypt ignores everything past 72 bytes.
+	if len(password) > 72 {
+		return errors.New("password must be at most 72 bytes")
+	}
+	return nil
+}
+
+func hashPassword(password string) (string, error) {
+	if err := validatePassword(password); err != nil {
+		return "", err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CreateUser adds an account.
+func (s *Store) CreateUser(username, password, role string) (User, error) {
+	return s.createUser(username, password, role, false)
+}
+
+// CreateFirstAdmin creates the initial admin account. It fails once any
+// account exists, so it cannot be used to take over a configured launcher.
+func (s *Store) CreateFirstAdmin(username, password string) (User, error) {
+	return s.createUser(username, password, RoleAdmin, true)
+}
+
+func (s *Store) createUser(username, password, role string, first bool) (User, error) {
+	if !usernamePattern.MatchString(username) {
+		return User{}, errors.New("username must be 1-64 letters, digits or . _ @ -")
+	}
+	if !ValidRole(role) {
+		return User{}, fmt.Errorf("unknown role %q", role)
+	}
+	hash, err := hashPassword(password)
+	if err != nil {
+		return User{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if first && len(s.users) > 0 {
+		return User{}, ErrUserExists
+	}
+	key := strings.ToLower(username)
+	if _, exists := s.users[key]; exists {
+		return User{}, ErrUserExists
+	}
+	u := &User{Username: username, PasswordHash: hash, Role: role, CreatedAt: time.Now().UTC()}
+	s.users[key] = u
+	if err := s.save(); err != nil {
+		delete(s.users, key)
+		return User{}, err
+	}
+	return *u, nil
+}
+
+// adminCount returns the number of admins. The caller holds s.mu.
+func (s *Store) adminCount() int {
+	n := 0
+	for _, u := range s.users {
+		if u.Role == RoleAdmin {
+			n++
+		}
+	}
+	return n
+}
+
+// SetRole changes an account's role. The last admin cannot be demoted.
+func (s *Store) SetRole(username, role string) error {
+	if !ValidRole(role) {
+		return fmt.Errorf("unknown role %q", role)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[strings.ToLower(username)]
+	if !ok {
+		return ErrUserNotFound
+	}
+	if u.Role == RoleAdmin && role != RoleAdmin && s.adminCount() == 1 {
+		return ErrLastAdmin
+	}
+	old := u.Role
+	u.Role = role
+	if err := s.save(); err != nil {
+		u.Role = old
+		return err
+	}
+	return nil
+}
+
+// SetPassword replaces an account's password.
+func (s *Store) SetPassword(username, password string) error {
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[strings.ToLower(username)]
+	if !ok {
+		return ErrUserNotFound
+	}
+	old := u.PasswordHash
+	u.PasswordHash = hash
+	if err := s.save(); err != nil {
+		u.PasswordHash = old
+		return err
+	}
+	return nil
+}
+
+// DeleteUser removes an account and its API tokens. The last admin cannot be
+// deleted.
+func (s *Store) DeleteUser(username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := strings.ToLower(username)
+	u, ok := s.users[key]
+	if !ok {
+		return ErrUserNotFound
+	}
+	if u.Role == RoleAdmin && s.adminCount() == 1 {
+		return ErrLastAdmin
+	}
+	oldTokens := s.tokens
+	delete(s.users, key)
+	s.tokens = s.tokensExcept(func(t *Token) bool { return strings.EqualFold(t.Username, username) })
+	if err := s.save(); err != nil {
+		s.users[key] = u
+		s.tokens = oldTokens
+		return err
+	}
+	return nil
+}
+
+// tokensExcept returns the tokens for which drop is false. The caller holds s.mu.
+func (s *Store) tokensExcept(drop func(*Token) bool) []*Token {
+	kept := make([]*Token, 0, len(s.tokens))
+	for _, t := range s.tokens {
+		if !drop(t) {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+// dummyHash is compared against when the user does not exist, so a login
+// attempt takes the same time either way.
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("picoclaw-dummy-password"), bcrypt.DefaultCost)
+	return hash
+})
+
+// VerifyPassword checks a username and password.
+func (s *Store) VerifyPassword(username, password string) (User, error) {
+	u, ok := s.User(username)
+	if !ok {
+		_ = bcrypt.CompareHashAndPassword(dummyHash(), []byte(password))
+		return User{}, ErrInvalidCredentials
+	}
+	if bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) != nil {
+		return User{}, ErrInvalidCredentials
+	}
+	return u, nil
+}
+
+// Authenticate checks a login: the password, then the TOTP code when the
+// account has TOTP enabled.
+func (s *Store) Authenticate(username, password, code string, now time.Time) (User, error) {
+	u, err := s.VerifyPassword(username, password)
+	if err != nil {
+		return User{}, err
+	}
+	if u.TOTPEnabled {
+		if code == "" {
+			return User{}, ErrTOTPRequired
+		}
+		if err := s.checkTOTP(u.Username, u.TOTPSecret, code, now); err != nil {
+			return User{}, err
+		}
+	}
+	return u, nil
+}
+
+// checkTOTP validates code against secret and rejects reuse of a time step.
+func (s *Store) checkTOTP(username, secret, code string, now time.Time) error {
+	step, ok := ValidateTOTP(secret, code, now)
+	if !ok {
+		return ErrInvalidTOTP
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := strings.ToLower(username)
+	if step <= s.lastTOTPStep[key] {
+		return ErrInvalidTOTP
+	}
+	s.lastTOTPStep[key] = step
+	return nil
+}
+
+// BeginTOTP generates a new secret for username. TOTP stays off until the
+// secret is confirmed with EnableTOTP.
+func (s *Store) BeginTOTP(username string) (string, error) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[strings.ToLower(username)]
+	if !ok {
+		return "", ErrUserNotFound
+	}
+	if u.TOTPEnabled {
+		return "", errors.New("TOTP is already enabled")
+	}
+	u.TOTPSecret = secret
+	if err := s.save(); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// EnableTOTP turns TOTP on once code proves the user's authenticator has
+// the secret from BeginTOTP.
+func (s *Store) EnableTOTP(username, code string, now time.Time) error {
+	u, ok := s.User(username)
+	if !ok {
+		return ErrUserNotFound
+	}
+	if u.TOTPSecret == "" {
+		return errors.New("start TOTP setup first")
+	}
+	if err := s.checkTOTP(username, u.TOTPSecret, code, now); err != nil {
+		return err
+	}
+	return s.setTOTP(username, u.TOTPSecret, true)
+}
+
+// DisableTOTP turns TOTP off and forgets the secret.
+func (s *Store) DisableTOTP(username string) error {
+	return s.setTOTP(username, "", false)
+}
+
+func (s *Store) setTOTP(username, secret string, enabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[strings.ToLower(username)]
+	if !ok {
+		return ErrUserNotFound
+	}
+	oldSecret, oldEnabled := u.TOTPSecret, u.TOTPEnabled
+	u.TOTPSecret, u.TOTPEnabled = secret, enabled
+	if err := s.save(); err != nil {
+		u.TOTPSecret, u.TOTPEnabled = oldSecret, oldEnabled
+		return err
+	}
+	return nil
+}
+
+func hashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// CreateToken issues an API token for username. The returned secret is
+// shown once; only its hash is stored.
+func (s *Store) CreateToken(username, name string) (Token, string, error) {
+	if _, ok := s.User(username); !ok {
+		return Token{}, "", ErrUserNotFound
+	}
+	id, err := randomHex(4)
+	if err != nil {
+		return Token{}, "", err
+	}
+	secretHex, err := randomHex(32)
+	if err != nil {
+		return Token{}, "", err
+	}
+	secret := tokenPrefix + secretHex
+	t := &Token{
+		ID:        id,
+		Name:      strings.TrimSpace(name),
+		Username:  username,
+		Hash:      hashToken(secret),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens = append(s.tokens, t)
+	if err := s.save(); err != nil {
+		s.tokens = s.tokens[:len(s.tokens)-1]
+		return Token{}, "", err
+	}
+	return *t, secret, nil
+}
+
+// Tokens returns the API tokens of username.
+func (s *Store) Tokens(username string) []Token {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var tokens []Token
+	for _, t := range s.tokens {
+		if strings.EqualFold(t.Username, username) {
+			tokens = append(tokens, *t)
+		}
+	}
+	return tokens
+}
+
+// RevokeToken deletes one of username's API tokens.
+func (s *Store) RevokeToken(username, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old := s.tokens
+	s.tokens = s.tokensExcept(func(t *Token) bool {
+		return t.ID == id && strings.EqualFold(t.Username, username)
+	})
+	if len(s.tokens) == len(old) {
+		return ErrTokenNotFound
+	}
+	if err := s.save(); err != nil {
+		s.tokens = old
+		return err
+	}
+	return nil
+}
+
+// LookupToken returns the account owning an API token secret.
+func (s *Store) LookupToken(secret string) (User, bool) {
+	if !strings.HasPrefix(secret, tokenPrefix) {
+		return User{}, false
+	}
+	hash := hashToken(secret)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.tokens {
+		if subtle.ConstantTimeCompare([]byte(t.Hash), []byte(hash)) == 1 {
+			u, ok := s.users[strings.ToLower(t.Username)]
+			if !ok {
+				return User{}, false
+			}
+			return *u, true
+		}
+	}
+	return User{}, false
+}
@@ -0,0 +1,151 @@
+package accounts
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T) (*Store, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), FileName)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	return s, path
+}
+
+func TestStoreFirstAdminAndPersistence(t *testing.T) {
+	s, path := newTestStore(t)
+	if s.HasUsers() {
+		t.Fatal("new store should have no users")
+	}
+	if _, err := s.CreateFirstAdmin("alice", "short"); err == nil {
+		t.Fatal("CreateFirstAdmin() accepted a too-short password")
+	}
+	u, err := s.CreateFirstAdmin("alice", "correct horse")
+	if err != nil {
+		t.Fatalf("CreateFirstAdmin() error = %v", err)
+	}
+	if u.Role != RoleAdmin {
+		t.Fatalf("first account role = %q, want %q", u.Role, RoleAdmin)
+	}
+	if _, err := s.CreateFirstAdmin("mallory", "correct horse"); !errors.Is(err, ErrUserExists) {
+		t.Fatalf("second CreateFirstAdmin() error = %v, want ErrUserExists", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Fatalf("store permissions = %o, want 600", perm)
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if _, err := reopened.VerifyPassword("ALICE", "correct horse"); err != nil {
+		t.Fatalf("VerifyPassword() after reopen error = %v", err)
+	}
+	if _, err := reopened.VerifyPassword("alice", "wrong password"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("VerifyPassword() with wrong password error = %v", err)
+	}
+	if _, err := reopened.VerifyPassword("nobody", "correct horse"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("VerifyPassword() for unknown user error = %v", err)
+	}
+}
+
+func TestStoreKeepsLastAdmin(t *testing.T) {
+	s, _ := newTestStore(t)
+	if _, err := s.CreateFirstAdmin("alice", "correct horse"); err != nil {
+		t.Fatalf("CreateFirstAdmin() error = %v", err)
+	}
+	if _, err := s.CreateUser("bob", "battery staple", RoleViewer); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	if err := s.SetRole("alice", RoleViewer); !errors.Is(err, ErrLastAdmin) {
+		t.Fatalf("SetRole() demoting last admin error = %v, want ErrLastAdmin", err)
+	}
+	if err := s.DeleteUser("alice"); !errors.Is(err, ErrLastAdmin) {
+		t.Fatalf("DeleteUser() of last admin error = %v, want ErrLastAdmin", err)
+	}
+	if err := s.SetRole("bob", RoleAdmin); err != nil {
+		t.Fatalf("SetRole() error = %v", err)
+	}
+	if err := s.DeleteUser("alice"); err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+}
+
+func TestStoreTokens(t *testing.T) {
+	s, _ := newTestStore(t)
+	if _, err := s.CreateFirstAdmin("alice", "correct horse"); err != nil {
+		t.Fatalf("CreateFirstAdmin() error = %v", err)
+	}
+	tok, secret, err := s.CreateToken("alice", "ci")
+	if err != nil {
+		t.Fatalf("CreateToken() error = %v", err)
+	}
+	if tok.Hash == secret {
+		t.Fatal("token secret stored in clear")
+	}
+	if u, ok := s.LookupToken(secret); !ok || u.Username != "alice" {
+		t.Fatalf("LookupToken() = %+v, %v", u, ok)
+	}
+	if _, ok := s.LookupToken(secret + "x"); ok {
+		t.Fatal("LookupToken() accepted a wrong secret")
+	}
+	if err := s.RevokeToken("bob", tok.ID); !errors.Is(err, ErrTokenNotFound) {
+		t.Fatalf("RevokeToken() by another user error = %v", err)
+	}
+	if err := s.RevokeToken("alice", tok.ID); err != nil {
+		t.Fatalf("RevokeToken() error = %v", err)
+	}
+	if _, ok := s.LookupToken(secret); ok {
+		t.Fatal("revoked token still valid")
+	}
+}
+
+func TestStoreTOTPLogin(t *testing.T) {
+	s, _ := newTestStore(t)
+	if _, err := s.CreateFirstAdmin("alice", "correct horse"); err != nil {
+		t.Fatalf("CreateFirstAdmin() error = %v", err)
+	}
+	secret, err := s.BeginTOTP("alice")
+	if err != nil {
+		t.Fatalf("BeginTOTP() error = %v", err)
+	}
+	now := time.Unix(1_700_000_000, 0)
+	if err := s.EnableTOTP("alice", codeAt(t, secret, now), now); err != nil {
+		t.Fatalf("EnableTOTP() error = %v", err)
+	}
+
+	later := now.Add(time.Minute)
+	if _, err := s.Authenticate("alice", "correct horse", "", later); !errors.Is(err, ErrTOTPRequired) {
+		t.Fatalf("Authenticate() without code error = %v, want ErrTOTPRequired", err)
+	}
+	if _, err := s.Authenticate("alice", "correct horse", "000000", later); !errors.Is(err, ErrInvalidTOTP) {
+		t.Fatalf("Authenticate() with wrong code error = %v, want ErrInvalidTOTP", err)
+	}
+	code := codeAt(t, secret, later)
+	if _, err := s.Authenticate("alice", "correct horse", code, later); err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if _, err := s.Authenticate("alice", "correct horse", code, later); !errors.Is(err, ErrInvalidTOTP) {
+		t.Fatalf("replayed code error = %v, want ErrInvalidTOTP", err)
+	}
+}
+
+func codeAt(t *testing.T, secret string, now time.Time) string {
+	t.Helper()
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("decode secret: %v", err)
+	}
+	return totpCode(key, now.Unix()/totpPeriod)
+}
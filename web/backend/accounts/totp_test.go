@@ -0,0 +1,49 @@
+package accounts
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 test key from RFC 6238 ("12345678901234567890").
+var rfc6238Secret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestValidateTOTPMatchesRFC6238(t *testing.T) {
+	// RFC 6238 Appendix B lists 8-digit codes; the 6-digit code is their
+	// last six digits.
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tt := range tests {
+		if _, ok := ValidateTOTP(rfc6238Secret, tt.code, time.Unix(tt.unix, 0)); !ok {
+			t.Errorf("ValidateTOTP(%d, %s) = false, want true", tt.unix, tt.code)
+		}
+	}
+}
+
+func TestValidateTOTPSkew(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	if _, ok := ValidateTOTP(rfc6238Secret, "081804", now.Add(totpPeriod*time.Second)); !ok {
+		t.Fatal("code from the previous period should be accepted")
+	}
+	if _, ok := ValidateTOTP(rfc6238Secret, "081804", now.Add(3*totpPeriod*time.Second)); ok {
+		t.Fatal("code from three periods ago should be rejected")
+	}
+	if _, ok := ValidateTOTP(rfc6238Secret, "08180", now); ok {
+		t.Fatal("short code should be rejected")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI("PicoClaw", "alice", "ABC")
+	if !strings.HasPrefix(uri, "otpauth://totp/PicoClaw:alice?") || !strings.Contains(uri, "secret=ABC") {
+		t.Fatalf("TOTPURI() = %q", uri)
+	}
+}
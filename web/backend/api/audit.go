@@ -0,0 +1,103 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"reflect"
+	"sort"
+
+	"github.com/sipeed/picoclaw/pkg/audit"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// auditSource names the launcher's audit chain.
+const auditSource = "web"
+
+// auditConfigChange records a config update made through the launcher. Only
+// the paths of changed settings are logged, never their values. The audit
+// log is (re)opened from the new config first, so enabling auditing records
+// the change that enabled it.
+func auditConfigChange(r *http.Request, oldCfg, newCfg *config.Config) {
+	if err := audit.Install(newCfg.Audit, auditSource); err != nil {
+		log.Printf("Failed to open audit log: %v", err)
+		return
+	}
+	if !audit.Enabled() {
+		return
+	}
+	actor := "web:" + remoteHost(r)
+	if p, ok := principalFrom(r); ok && p.Username != "" {
+		actor = "web:" + p.Username
+	}
+	ctx := audit.WithActor(r.Context(), audit.Actor{ID: actor, Channel: auditSource})
+	audit.Record(ctx, audit.TypeConfigChange, map[string]any{
+		"method":  r.Method,
+		"changed": changedConfigPaths(oldCfg, newCfg),
+	})
+}
+
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// maxChangedPathDepth bounds how deep changedConfigPaths descends, e.g.
+// "tools.exec.enabled".
+const maxChangedPathDepth = 3
+
+// changedConfigPaths returns the dotted JSON paths that differ between two
+// configs.
+func changedConfigPaths(oldCfg, newCfg *config.Config) []string {
+	var oldMap, newMap map[string]any
+	if oldCfg != nil {
+		oldMap = configAsMap(oldCfg)
+	}
+	newMap = configAsMap(newCfg)
+
+	var paths []string
+	diffMaps("", oldMap, newMap, 1, &paths)
+	sort.Strings(paths)
+	return paths
+}
+
+func configAsMap(cfg *config.Config) map[string]any {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil
+	}
+	var m map[string]any
+	_ = json.Unmarshal(data, &m)
+	return m
+}
+
+func diffMaps(prefix string, a, b map[string]any, depth int, paths *[]string) {
+	keys := make(map[string]struct{}, len(a)+len(b))
+	for k := range a {
+		keys[k] = struct{}{}
+	}
+	for k := range b {
+		keys[k] = struct{}{}
+	}
+	for k := range keys {
+		path := k
+		if prefix != "" {
+			path = prefix + "." + k
+		}
+		av, bv := a[k], b[k]
+		if reflect.DeepEqual(av, bv) {
+			continue
+		}
+		am, aIsMap := av.(map[string]any)
+		bm, bIsMap := bv.(map[string]any)
+		if depth < maxChangedPathDepth && (aIsMap || av == nil) && (bIsMap || bv == nil) {
+			diffMaps(path, am, bm, depth+1, paths)
+			continue
+		}
+		*paths = append(*paths, path)
+	}
+}
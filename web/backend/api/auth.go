@@ -0,0 +1,490 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/web/backend/accounts"
+)
+
+// totpIssuer names the launcher in authenticator apps.
+const totpIssuer = "PicoClaw"
+
+type authUserView struct {
+	Username    string `json:"username"`
+	Role        string `json:"role"`
+	TOTPEnabled bool   `json:"totp_enabled"`
+	CreatedAt   string `json:"created_at,omitempty"`
+}
+
+func userView(u accounts.User) authUserView {
+	return authUserView{
+		Username:    u.Username,
+		Role:        u.Role,
+		TOTPEnabled: u.TOTPEnabled,
+		CreatedAt:   u.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+type authStatusResponse struct {
+	// AccountsEnabled is false while no account exists and the launcher is
+	// open to anyone who can reach it.
+	AccountsEnabled bool          `json:"accounts_enabled"`
+	Authenticated   bool          `json:"authenticated"`
+	Role            string        `json:"role,omitempty"`
+	User            *authUserView `json:"user,omitempty"`
+	CSRFToken       string        `json:"csrf_token,omitempty"`
+}
+
+type authTokenView struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	CreatedAt string `json:"created_at"`
+	// Token is the secret, returned only when the token is created.
+	Token string `json:"token,omitempty"`
+}
+
+func tokenView(t accounts.Token) authTokenView {
+	return authTokenView{ID: t.ID, Name: t.Name, CreatedAt: t.CreatedAt.Format(time.RFC3339)}
+}
+
+// registerAuthRoutes binds login, account and API token endpoints.
+func (h *Handler) registerAuthRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /login", h.handleLoginPage)
+	mux.HandleFunc("POST /login", h.handleLoginForm)
+
+	mux.HandleFunc("GET /api/auth/status", h.handleAuthStatus)
+	mux.HandleFunc("POST /api/auth/setup", h.handleAuthSetup)
+	mux.HandleFunc("POST /api/auth/login", h.handleAuthLogin)
+	mux.HandleFunc("POST /api/auth/logout", h.handleAuthLogout)
+	mux.HandleFunc("POST /api/auth/password", h.handleChangePassword)
+
+	mux.HandleFunc("POST /api/auth/totp/setup", h.handleTOTPSetup)
+	mux.HandleFunc("POST /api/auth/totp/enable", h.handleTOTPEnable)
+	mux.HandleFunc("POST /api/auth/totp/disable", h.handleTOTPDisable)
+
+	mux.HandleFunc("GET /api/auth/tokens", h.handleListTokens)
+	mux.HandleFunc("POST /api/auth/tokens", h.handleCreateToken)
+	mux.HandleFunc("DELETE /api/auth/tokens/{id}", h.handleRevokeToken)
+
+	// Admin only; enforced by RequireAuth.
+	mux.HandleFunc("GET /api/auth/users", h.handleListUsers)
+	mux.HandleFunc("POST /api/auth/users", h.handleCreateUser)
+	mux.HandleFunc("PUT /api/auth/users/{username}", h.handleUpdateUser)
+	mux.HandleFunc("DELETE /api/auth/users/{username}", h.handleDeleteUser)
+}
+
+func writeAuthError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+func writeAuthJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func decodeAuthRequest(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(v); err != nil {
+		writeAuthError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// accountPrincipal returns the caller's account, replying with an error when
+// the request is not tied to one (open mode).
+func accountPrincipal(w http.ResponseWriter, r *http.Request) (principal, bool) {
+	p, ok := principalFrom(r)
+	if !ok || p.Username == "" {
+		writeAuthError(w, http.StatusBadRequest, "no account is logged in")
+		return principal{}, false
+	}
+	return p, true
+}
+
+// statusFor describes the caller's login state.
+func (h *Handler) statusFor(r *http.Request) authStatusResponse {
+	if !h.accounts.HasUsers() {
+		return authStatusResponse{Authenticated: true, Role: accounts.RoleAdmin}
+	}
+	resp := authStatusResponse{AccountsEnabled: true}
+	p, ok := h.authenticate(r)
+	if !ok {
+		return resp
+	}
+	resp.Authenticated = true
+	resp.Role = p.Role
+	resp.CSRFToken = p.CSRF
+	if u, found := h.accounts.User(p.Username); found {
+		view := userView(u)
+		resp.User = &view
+	}
+	return resp
+}
+
+// GET /api/auth/status
+func (h *Handler) handleAuthStatus(w http.ResponseWriter, r *http.Request) {
+	writeAuthJSON(w, h.statusFor(r))
+}
+
+// startSession logs username in and sets the session and CSRF cookies.
+func (h *Handler) startSession(w http.ResponseWriter, r *http.Request, username string) (accounts.Session, error) {
+	sess, err := h.sessions.Create(username)
+	if err != nil {
+		return accounts.Session{}, err
+	}
+	secure := r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+	http.SetCookie(w, &http.Cookie{
+		Name:     accounts.SessionCookie,
+		Value:    sess.ID,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     accounts.CSRFCookie,
+		Value:    sess.CSRF,
+		Path:     "/",
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return sess, nil
+}
+
+func clearSessionCookies(w http.ResponseWriter) {
+	for _, name := range []string{accounts.SessionCookie, accounts.CSRFCookie} {
+		http.SetCookie(w, &http.Cookie{Name: name, Value: "", Path: "/", MaxAge: -1})
+	}
+}
+
+func (h *Handler) loggedInStatus(sess accounts.Session) authStatusResponse {
+	resp := authStatusResponse{AccountsEnabled: true, Authenticated: true, CSRFToken: sess.CSRF}
+	if u, ok := h.accounts.User(sess.Username); ok {
+		view := userView(u)
+		resp.User = &view
+		resp.Role = u.Role
+	}
+	return resp
+}
+
+type credentialsRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	TOTPCode string `json:"totp_code,omitempty"`
+}
+
+// POST /api/auth/setup creates the first admin account. It is only
+// available while no account exists.
+func (h *Handler) handleAuthSetup(w http.ResponseWriter, r *http.Request) {
+	var req credentialsRequest
+	if !decodeAuthRequest(w, r, &req) {
+		return
+	}
+	u, err := h.accounts.CreateFirstAdmin(strings.TrimSpace(req.Username), req.Password)
+	if errors.Is(err, accounts.ErrUserExists) {
+		writeAuthError(w, http.StatusConflict, "accounts are already set up")
+		return
+	}
+	if err != nil {
+		writeAuthError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	log.Printf("Web launcher: created admin account %q; login is now required", u.Username)
+	sess, err := h.startSession(w, r, u.Username)
+	if err != nil {
+		writeAuthError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeAuthJSON(w, h.loggedInStatus(sess))
+}
+
+// login checks credentials with throttling and starts a session.
+func (h *Handler) login(w http.ResponseWriter, r *http.Request, req credentialsRequest) (accounts.Session, int, error) {
+	limitKey := strings.ToLower(strings.TrimSpace(req.Username)) + "|" + remoteHost(r)
+	if !h.loginLimiter.Allowed(limitKey) {
+		return accounts.Session{}, http.StatusTooManyRequests, errors.New("too many failed attempts; try again later")
+	}
+	u, err := h.accounts.Authenticate(strings.TrimSpace(req.Username), req.Password, req.TOTPCode, time.Now())
+	switch {
+	case errors.Is(err, accounts.ErrTOTPRequired):
+		return accounts.Session{}, http.StatusUnauthorized, err
+	case err != nil:
+		h.loginLimiter.Fail(limitKey)
+		log.Printf("Web launcher: failed login for %q from %s", req.Username, remoteHost(r))
+		return accounts.Session{}, http.StatusUnauthorized, err
+	}
+	h.loginLimiter.Reset(limitKey)
+	sess, err := h.startSession(w, r, u.Username)
+	if err != nil {
+		return accounts.Session{}, http.StatusInternalServerError, err
+	}
+	return sess, http.StatusOK, nil
+}
+
+// POST /api/auth/login
+func (h *Handler) handleAuthLogin(w http.ResponseWriter, r *http.Request) {
+	var req credentialsRequest
+	if !decodeAuthRequest(w, r, &req) {
+		return
+	}
+	if !h.accounts.HasUsers() {
+		writeAuthError(w, http.StatusConflict, "no accounts exist; create one with /api/auth/setup")
+		return
+	}
+	sess, status, err := h.login(w, r, req)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"error":         err.Error(),
+			"totp_required": errors.Is(err, accounts.ErrTOTPRequired),
+		})
+		return
+	}
+	writeAuthJSON(w, h.loggedInStatus(sess))
+}
+
+// POST /api/auth/logout
+func (h *Handler) handleAuthLogout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(accounts.SessionCookie); err == nil {
+		h.sessions.Delete(cookie.Value)
+	}
+	clearSessionCookies(w)
+	writeAuthJSON(w, map[string]string{"status": "ok"})
+}
+
+// POST /api/auth/password changes the caller's password and ends their
+// other sessions.
+func (h *Handler) handleChangePassword(w http.ResponseWriter, r *http.Request) {
+	p, ok := accountPrincipal(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+	if !decodeAuthRequest(w, r, &req) {
+		return
+	}
+	if _, err := h.accounts.VerifyPassword(p.Username, req.CurrentPassword); err != nil {
+		writeAuthError(w, http.StatusForbidden, "current password is incorrect")
+		return
+	}
+	if err := h.accounts.SetPassword(p.Username, req.NewPassword); err != nil {
+		writeAuthError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.sessions.DeleteUser(p.Username, p.SessionID)
+	writeAuthJSON(w, map[string]string{"status": "ok"})
+}
+
+// POST /api/auth/totp/setup returns a new TOTP secret to add to an
+// authenticator app. TOTP is enabled once a code is confirmed.
+func (h *Handler) handleTOTPSetup(w http.ResponseWriter, r *http.Request) {
+	p, ok := accountPrincipal(w, r)
+	if !ok {
+		return
+	}
+	secret, err := h.accounts.BeginTOTP(p.Username)
+	if err != nil {
+		writeAuthError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeAuthJSON(w, map[string]string{
+		"secret": secret,
+		"uri":    accounts.TOTPURI(totpIssuer, p.Username, secret),
+	})
+}
+
+// POST /api/auth/totp/enable
+func (h *Handler) handleTOTPEnable(w http.ResponseWriter, r *http.Request) {
+	p, ok := accountPrincipal(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Code string `json:"code"`
+	}
+	if !decodeAuthRequest(w, r, &req) {
+		return
+	}
+	if err := h.accounts.EnableTOTP(p.Username, req.Code, time.Now()); err != nil {
+		writeAuthError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeAuthJSON(w, map[string]string{"status": "ok"})
+}
+
+// POST /api/auth/totp/disable requires the password again.
+func (h *Handler) handleTOTPDisable(w http.ResponseWriter, r *http.Request) {
+	p, ok := accountPrincipal(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Password string `json:"password"`
+	}
+	if !decodeAuthRequest(w, r, &req) {
+		return
+	}
+	if _, err := h.accounts.VerifyPassword(p.Username, req.Password); err != nil {
+		writeAuthError(w, http.StatusForbidden, "password is incorrect")
+		return
+	}
+	if err := h.accounts.DisableTOTP(p.Username); err != nil {
+		writeAuthError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeAuthJSON(w, map[string]string{"status": "ok"})
+}
+
+// GET /api/auth/tokens lists the caller's API tokens.
+func (h *Handler) handleListTokens(w http.ResponseWriter, r *http.Request) {
+	p, ok := accountPrincipal(w, r)
+	if !ok {
+		return
+	}
+	tokens := h.accounts.Tokens(p.Username)
+	views := make([]authTokenView, 0, len(tokens))
+	for _, t := range tokens {
+		views = append(views, tokenView(t))
+	}
+	writeAuthJSON(w, map[string]any{"tokens": views})
+}
+
+// POST /api/auth/tokens issues an API token with the caller's role. The
+// secret is only shown in this response.
+func (h *Handler) handleCreateToken(w http.ResponseWriter, r *http.Request) {
+	p, ok := accountPrincipal(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Name string `json:"name"`
+	}
+	if !decodeAuthRequest(w, r, &req) {
+		return
+	}
+	t, secret, err := h.accounts.CreateToken(p.Username, req.Name)
+	if err != nil {
+		writeAuthError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	view := tokenView(t)
+	view.Token = secret
+	writeAuthJSON(w, view)
+}
+
+// DELETE /api/auth/tokens/{id}
+func (h *Handler) handleRevokeToken(w http.ResponseWriter, r *http.Request) {
+	p, ok := accountPrincipal(w, r)
+	if !ok {
+		return
+	}
+	if err := h.accounts.RevokeToken(p.Username, r.PathValue("id")); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, accounts.ErrTokenNotFound) {
+			status = http.StatusNotFound
+		}
+		writeAuthError(w, status, err.Error())
+		return
+	}
+	writeAuthJSON(w, map[string]string{"status": "ok"})
+}
+
+// GET /api/auth/users
+func (h *Handler) handleListUsers(w http.ResponseWriter, r *http.Request) {
+	users := h.accounts.Users()
+	views := make([]authUserView, 0, len(users))
+	for _, u := range users {
+		views = append(views, userView(u))
+	}
+	writeAuthJSON(w, map[string]any{"users": views})
+}
+
+// POST /api/auth/users
+func (h *Handler) handleCreateUser(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Role     string `json:"role"`
+	}
+	if !decodeAuthRequest(w, r, &req) {
+		return
+	}
+	if req.Role == "" {
+		req.Role = accounts.RoleViewer
+	}
+	u, err := h.accounts.CreateUser(strings.TrimSpace(req.Username), req.Password, req.Role)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, accounts.ErrUserExists) {
+			status = http.StatusConflict
+		}
+		writeAuthError(w, status, err.Error())
+		return
+	}
+	writeAuthJSON(w, userView(u))
+}
+
+// PUT /api/auth/users/{username} changes a role or resets a password. Either
+// change logs the user out everywhere.
+func (h *Handler) handleUpdateUser(w http.ResponseWriter, r *http.Request) {
+	username := r.PathValue("username")
+	var req struct {
+		Role     string `json:"role,omitempty"`
+		Password string `json:"password,omitempty"`
+	}
+	if !decodeAuthRequest(w, r, &req) {
+		return
+	}
+	if req.Role != "" {
+		if err := h.accounts.SetRole(username, req.Role); err != nil {
+			writeUserError(w, err)
+			return
+		}
+	}
+	if req.Password != "" {
+		if err := h.accounts.SetPassword(username, req.Password); err != nil {
+			writeUserError(w, err)
+			return
+		}
+	}
+	h.sessions.DeleteUser(username, "")
+	u, ok := h.accounts.User(username)
+	if !ok {
+		writeUserError(w, accounts.ErrUserNotFound)
+		return
+	}
+	writeAuthJSON(w, userView(u))
+}
+
+// DELETE /api/auth/users/{username}
+func (h *Handler) handleDeleteUser(w http.ResponseWriter, r *http.Request) {
+	username := r.PathValue("username")
+	if err := h.accounts.DeleteUser(username); err != nil {
+		writeUserError(w, err)
+		return
+	}
+	h.sessions.DeleteUser(username, "")
+	writeAuthJSON(w, map[string]string{"status": "ok"})
+}
+
+func writeUserError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, accounts.ErrUserNotFound):
+		writeAuthError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, accounts.ErrLastAdmin):
+		writeAuthError(w, http.StatusConflict, err.Error())
+	default:
+		writeAuthError(w, http.StatusBadRequest, err.Error())
+	}
+}
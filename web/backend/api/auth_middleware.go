@@ -0,0 +1,163 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/sipeed/picoclaw/web/backend/accounts"
+)
+
+// principal is the authenticated caller of a request.
+type principal struct {
+	// Username is empty while no account exists (open mode).
+	Username string
+	Role     string
+	// SessionID is set for browser sessions and empty for API tokens.
+	SessionID string
+	CSRF      string
+}
+
+type principalCtxKey struct{}
+
+func withPrincipal(r *http.Request, p principal) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), principalCtxKey{}, p))
+}
+
+func principalFrom(r *http.Request) (principal, bool) {
+	p, ok := r.Context().Value(principalCtxKey{}).(principal)
+	return p, ok
+}
+
+// publicPaths are reachable without logging in.
+var publicPaths = map[string]bool{
+	"/login":           true,
+	"/favicon.ico":     true,
+	"/api/auth/status": true,
+	"/api/auth/login":  true,
+	"/api/auth/setup":  true,
+	"/api/auth/logout": true,
+}
+
+// viewerHiddenPaths reveal secrets and are closed to viewers even for reads.
+var viewerHiddenPaths = map[string]bool{
+	"/api/config": true,
+}
+
+// AccountsEnabled reports whether login is required, i.e. at least one
+// account exists.
+func (h *Handler) AccountsEnabled() bool {
+	return h.accountsErr == nil && h.accounts.HasUsers()
+}
+
+// RequireAuth wraps next with login, CSRF and role checks. While no account
+// exists every request is let through as an admin, as before accounts were
+// introduced.
+func (h *Handler) RequireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.accountsErr != nil {
+			writeAuthError(w, http.StatusServiceUnavailable, "account store unavailable: "+h.accountsErr.Error())
+			return
+		}
+		if publicPaths[r.URL.Path] {
+			// Public forms carry no CSRF token yet; refuse cross-site posts
+			// so another site cannot log a browser into its own account.
+			if !isSafeMethod(r.Method) && !sameOrigin(r) {
+				writeAuthError(w, http.StatusForbidden, "cross-origin request refused")
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !h.accounts.HasUsers() {
+			next.ServeHTTP(w, withPrincipal(r, principal{Role: accounts.RoleAdmin}))
+			return
+		}
+
+		p, ok := h.authenticate(r)
+		if !ok {
+			if isAPIPath(r.URL.Path) {
+				writeAuthError(w, http.StatusUnauthorized, "login required")
+				return
+			}
+			http.Redirect(w, r, "/login?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusFound)
+			return
+		}
+		if p.SessionID != "" && !isSafeMethod(r.Method) &&
+			subtle.ConstantTimeCompare([]byte(r.Header.Get(accounts.CSRFHeader)), []byte(p.CSRF)) != 1 {
+			writeAuthError(w, http.StatusForbidden, "missing or invalid CSRF token")
+			return
+		}
+		if !roleAllows(p.Role, r) {
+			writeAuthError(w, http.StatusForbidden, "this action requires the admin role")
+			return
+		}
+		next.ServeHTTP(w, withPrincipal(r, p))
+	})
+}
+
+// authenticate resolves the caller from an API token or a session cookie.
+func (h *Handler) authenticate(r *http.Request) (principal, bool) {
+	if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		u, found := h.accounts.LookupToken(strings.TrimSpace(bearer))
+		if !found {
+			return principal{}, false
+		}
+		return principal{Username: u.Username, Role: u.Role}, true
+	}
+
+	cookie, err := r.Cookie(accounts.SessionCookie)
+	if err != nil || cookie.Value == "" {
+		return principal{}, false
+	}
+	sess, ok := h.sessions.Get(cookie.Value)
+	if !ok {
+		return principal{}, false
+	}
+	// Read the role from the store so role changes apply immediately.
+	u, ok := h.accounts.User(sess.Username)
+	if !ok {
+		h.sessions.Delete(sess.ID)
+		return principal{}, false
+	}
+	return principal{Username: u.Username, Role: u.Role, SessionID: sess.ID, CSRF: sess.CSRF}, true
+}
+
+// roleAllows reports whether role may make request r. Admins may do
+// anything. Viewers may read everything except secrets and manage their
+// own login (password, TOTP, API tokens). Chatting works through the
+// WebSocket, which is a read; the proxy decides what viewers may do there.
+func roleAllows(role string, r *http.Request) bool {
+	if role == accounts.RoleAdmin {
+		return true
+	}
+	path := r.URL.Path
+	if strings.HasPrefix(path, "/api/auth/users") {
+		return false
+	}
+	if isSafeMethod(r.Method) {
+		return !viewerHiddenPaths[path]
+	}
+	return strings.HasPrefix(path, "/api/auth/")
+}
+
+// sameOrigin reports whether the request's Origin header, when present,
+// matches the host it was sent to.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+func isAPIPath(path string) bool {
+	return path == "/api" || strings.HasPrefix(path, "/api/")
+}
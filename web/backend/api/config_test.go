@@ -2,10 +2,13 @@ package api
 
 import (
 	"bytes"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/sipeed/picoclaw/pkg/audit"
 	"github.com/sipeed/picoclaw/pkg/config"
 )
 
@@ -165,3 +168,53 @@ func TestHandlePatchConfig_AllowsInvalidDenyRegexPatternsWhenDenyPatternsDisable
 		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
 	}
 }
+
+func TestHandlePatchConfig_RecordsAuditEntry(t *testing.T) {
+	configPath, cleanup := setupOAuthTestEnv(t)
+	defer cleanup()
+	defer audit.Install(config.AuditConfig{}, auditSource)
+
+	auditDir := t.TempDir()
+	h := NewHandler(configPath)
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	body := fmt.Sprintf(`{"audit": {"enabled": true, "dir": %q}, "tools": {"exec": {"enabled": false}}}`, auditDir)
+	req := httptest.NewRequest(http.MethodPatch, "/api/config", bytes.NewBufferString(body))
+	req.RemoteAddr = "192.0.2.10:51000"
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	entries, err := audit.Query(auditDir, audit.Filter{Type: audit.TypeConfigChange})
+	if err != nil {
+		t.Fatalf("audit.Query() error = %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("len(entries) = %d, want 1", len(entries))
+	}
+	e := entries[0]
+	if e.Source != auditSource || e.Actor != "web:192.0.2.10" {
+		t.Errorf("entry = %+v", e)
+	}
+	for _, want := range []string{`"method":"PATCH"`, `"audit.enabled"`, `"tools.exec.enabled"`} {
+		if !strings.Contains(string(e.Data), want) {
+			t.Errorf("entry data %s missing %s", e.Data, want)
+		}
+	}
+}
+
+func TestChangedConfigPaths(t *testing.T) {
+	oldCfg := config.DefaultConfig()
+	newCfg := config.DefaultConfig()
+	newCfg.Agents.Defaults.ModelName = "other"
+	newCfg.Channels.Telegram.Token = "secret-token"
+
+	got := changedConfigPaths(oldCfg, newCfg)
+	want := []string{"agents.defaults.model_name", "channels.telegram.token"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("changedConfigPaths() = %v, want %v", got, want)
+	}
+}
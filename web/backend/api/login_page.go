@@ -0,0 +1,129 @@
+package api
+
+import (
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/sipeed/picoclaw/web/backend/accounts"
+)
+
+// The login page is rendered by the backend so it works before the frontend
+// bundle (and any data it loads) is reachable.
+var loginPageTemplate = template.Must(template.New("login").Parse(`<!doctype html>
+<html><head><meta charset="utf-8"><meta name="viewport" content="width=device-width,initial-scale=1">
+<title>PicoClaw · {{if .Setup}}Create admin account{{else}}Log in{{end}}</title>
+<style>
+body{font-family:Inter,system-ui,sans-serif;background:#f5f5f5;display:flex;justify-content:center;align-items:center;min-height:100vh;margin:0}
+form{background:#fff;padding:32px;border-radius:12px;box-shadow:0 1px 4px rgba(0,0,0,.1);width:320px}
+h2{margin:0 0 8px}p{color:#555;font-size:14px}
+label{display:block;font-size:14px;margin:12px 0 4px}
+input{width:100%;box-sizing:border-box;padding:8px;border:1px solid #ccc;border-radius:6px}
+button{margin-top:20px;width:100%;padding:10px;border:0;border-radius:6px;background:#111;color:#fff;cursor:pointer}
+.error{color:#b00020}
+</style></head><body>
+<form method="post" action="/login">
+<h2>{{if .Setup}}Create admin account{{else}}Log in to PicoClaw{{end}}</h2>
+{{if .Setup}}<p>No accounts exist yet. The first account is an admin; once it is created, everyone must log in.</p>{{end}}
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<input type="hidden" name="next" value="{{.Next}}">
+<label for="username">Username</label>
+<input id="username" name="username" autocomplete="username" value="{{.Username}}" required autofocus>
+<label for="password">Password</label>
+<input id="password" name="password" type="password" autocomplete="{{if .Setup}}new-password{{else}}current-password{{end}}" required>
+{{if .Setup}}<label for="confirm">Confirm password</label>
+<input id="confirm" name="confirm" type="password" autocomplete="new-password" required>
+{{else}}<label for="totp_code">Authenticator code{{if not .TOTPRequired}} (if enabled){{end}}</label>
+<input id="totp_code" name="totp_code" inputmode="numeric" autocomplete="one-time-code">
+{{end}}<button type="submit">{{if .Setup}}Create account{{else}}Log in{{end}}</button>
+</form></body></html>`))
+
+type loginPageData struct {
+	Setup        bool
+	Next         string
+	Username     string
+	Error        string
+	TOTPRequired bool
+}
+
+func renderLoginPage(w http.ResponseWriter, status int, data loginPageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := loginPageTemplate.Execute(w, data); err != nil {
+		log.Printf("Failed to render login page: %v", err)
+	}
+}
+
+// safeNext returns next if it is a local path, so the login form cannot be
+// used as an open redirect.
+func safeNext(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
+
+// GET /login
+func (h *Handler) handleLoginPage(w http.ResponseWriter, r *http.Request) {
+	next := safeNext(r.URL.Query().Get("next"))
+	if h.accounts.HasUsers() {
+		if _, ok := h.authenticate(r); ok {
+			http.Redirect(w, r, next, http.StatusFound)
+			return
+		}
+	}
+	renderLoginPage(w, http.StatusOK, loginPageData{Setup: !h.accounts.HasUsers(), Next: next})
+}
+
+// POST /login handles both the login and the first-admin setup forms.
+func (h *Handler) handleLoginForm(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 64<<10)
+	if err := r.ParseForm(); err != nil {
+		renderLoginPage(w, http.StatusBadRequest, loginPageData{Error: "Invalid form submission."})
+		return
+	}
+	data := loginPageData{
+		Setup:    !h.accounts.HasUsers(),
+		Next:     safeNext(r.PostFormValue("next")),
+		Username: strings.TrimSpace(r.PostFormValue("username")),
+	}
+	password := r.PostFormValue("password")
+
+	if data.Setup {
+		if password != r.PostFormValue("confirm") {
+			data.Error = "Passwords do not match."
+			renderLoginPage(w, http.StatusBadRequest, data)
+			return
+		}
+		u, err := h.accounts.CreateFirstAdmin(data.Username, password)
+		if err != nil {
+			data.Error = err.Error()
+			data.Setup = !errors.Is(err, accounts.ErrUserExists)
+			renderLoginPage(w, http.StatusBadRequest, data)
+			return
+		}
+		log.Printf("Web launcher: created admin account %q; login is now required", u.Username)
+		if _, err := h.startSession(w, r, u.Username); err != nil {
+			data.Error = err.Error()
+			renderLoginPage(w, http.StatusInternalServerError, data)
+			return
+		}
+		http.Redirect(w, r, data.Next, http.StatusSeeOther)
+		return
+	}
+
+	_, status, err := h.login(w, r, credentialsRequest{
+		Username: data.Username,
+		Password: password,
+		TOTPCode: r.PostFormValue("totp_code"),
+	})
+	if err != nil {
+		data.TOTPRequired = errors.Is(err, accounts.ErrTOTPRequired)
+		data.Error = err.Error()
+		renderLoginPage(w, status, data)
+		return
+	}
+	http.Redirect(w, r, data.Next, http.StatusSeeOther)
+}
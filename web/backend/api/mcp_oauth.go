@@ -0,0 +1,204 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/auth"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/mcp"
+)
+
+var (
+	oauthStartMCP    = mcp.StartOAuth
+	oauthCompleteMCP = func(ctx context.Context, flow *mcp.OAuthFlow, code string) (*auth.AuthCredential, error) {
+		return flow.Complete(ctx, code)
+	}
+)
+
+type mcpOAuthServerStatus struct {
+	Server       string `json:"server"`
+	URL          string `json:"url"`
+	OAuthEnabled bool   `json:"oauth_enabled"`
+	LoggedIn     bool   `json:"logged_in"`
+	Status       string `json:"status"`
+	ExpiresAt    string `json:"expires_at,omitempty"`
+}
+
+func (h *Handler) handleListMCPOAuthServers(w http.ResponseWriter, r *http.Request) {
+	cfg, err := oauthLoadConfig(h.configPath)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to load config: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	servers := make([]mcpOAuthServerStatus, 0, len(cfg.Tools.MCP.Servers))
+	for name, serverCfg := range cfg.Tools.MCP.Servers {
+		// Only remote servers can use OAuth.
+		if serverCfg.URL == "" {
+			continue
+		}
+		cred, err := oauthGetCredential(mcp.OAuthCredentialKey(name))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to load credentials: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		item := mcpOAuthServerStatus{
+			Server:       name,
+			URL:          serverCfg.URL,
+			OAuthEnabled: serverCfg.OAuth != nil && serverCfg.OAuth.Enabled,
+			Status:       "not_logged_in",
+		}
+		if cred != nil {
+			item.LoggedIn = true
+			item.Status = oauthCredentialStatus(cred)
+			if !cred.ExpiresAt.IsZero() {
+				item.ExpiresAt = cred.ExpiresAt.Format(time.RFC3339)
+			}
+		}
+		servers = append(servers, item)
+	}
+	sort.Slice(servers, func(i, j int) bool { return servers[i].Server < servers[j].Server })
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"servers": servers,
+	})
+}
+
+func (h *Handler) handleMCPOAuthLogin(w http.ResponseWriter, r *http.Request) {
+	serverName, serverCfg, ok := h.readMCPOAuthServer(w, r)
+	if !ok {
+		return
+	}
+
+	redirectURI := buildOAuthRedirectURI(r)
+	mcpFlow, err := oauthStartMCP(r.Context(), serverName, serverCfg, redirectURI)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to start authorization: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	now := oauthNow()
+	flow := &oauthFlow{
+		ID:          newOAuthFlowID(),
+		Provider:    mcp.OAuthCredentialKey(serverName),
+		Method:      oauthMethodBrowser,
+		Status:      oauthFlowPending,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		ExpiresAt:   now.Add(oauthBrowserFlowTTL),
+		OAuthState:  mcpFlow.State,
+		RedirectURI: redirectURI,
+		MCP:         mcpFlow,
+	}
+	h.storeOAuthFlow(flow)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"status":     "ok",
+		"server":     serverName,
+		"flow_id":    flow.ID,
+		"auth_url":   mcpFlow.AuthURL,
+		"expires_at": flow.ExpiresAt.Format(time.RFC3339),
+	})
+}
+
+func (h *Handler) handleMCPOAuthLogout(w http.ResponseWriter, r *http.Request) {
+	serverName, _, ok := h.readMCPOAuthServer(w, r)
+	if !ok {
+		return
+	}
+
+	if err := oauthDeleteCredential(mcp.OAuthCredentialKey(serverName)); err != nil {
+		http.Error(w, fmt.Sprintf("failed to delete credential: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"status": "ok",
+		"server": serverName,
+	})
+}
+
+// readMCPOAuthServer decodes {"server": name} and looks the server up in the
+// config. On failure it writes the error response and returns false.
+func (h *Handler) readMCPOAuthServer(
+	w http.ResponseWriter,
+	r *http.Request,
+) (string, config.MCPServerConfig, bool) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return "", config.MCPServerConfig{}, false
+	}
+	defer r.Body.Close()
+
+	var req struct {
+		Server string `json:"server"`
+	}
+	if err = json.Unmarshal(body, &req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid JSON: %v", err), http.StatusBadRequest)
+		return "", config.MCPServerConfig{}, false
+	}
+	serverName := strings.TrimPrefix(strings.TrimSpace(req.Server), mcp.OAuthCredentialKey(""))
+	if serverName == "" {
+		http.Error(w, "server is required", http.StatusBadRequest)
+		return "", config.MCPServerConfig{}, false
+	}
+
+	cfg, err := oauthLoadConfig(h.configPath)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to load config: %v", err), http.StatusInternalServerError)
+		return "", config.MCPServerConfig{}, false
+	}
+	serverCfg, ok := cfg.Tools.MCP.Servers[serverName]
+	if !ok {
+		http.Error(w, fmt.Sprintf("MCP server %q is not configured", serverName), http.StatusNotFound)
+		return "", config.MCPServerConfig{}, false
+	}
+	return serverName, serverCfg, true
+}
+
+// completeMCPOAuth finishes an MCP authorization from the OAuth callback and
+// enables OAuth for the server so the gateway sends the new token.
+func (h *Handler) completeMCPOAuth(w http.ResponseWriter, r *http.Request, flow *oauthFlow, code string) {
+	if _, err := oauthCompleteMCP(r.Context(), flow.MCP, code); err != nil {
+		h.setOAuthFlowError(flow.ID, err.Error())
+		renderOAuthCallbackPage(w, flow.ID, oauthFlowError, "Token exchange failed", err.Error())
+		return
+	}
+	if err := h.enableMCPOAuth(flow.MCP.Server); err != nil {
+		h.setOAuthFlowError(flow.ID, fmt.Sprintf("failed to update config: %v", err))
+		renderOAuthCallbackPage(w, flow.ID, oauthFlowError, "Failed to update config", err.Error())
+		return
+	}
+
+	h.setOAuthFlowSuccess(flow.ID)
+	renderOAuthCallbackPage(w, flow.ID, oauthFlowSuccess, "Authentication successful", "")
+}
+
+func (h *Handler) enableMCPOAuth(serverName string) error {
+	cfg, err := oauthLoadConfig(h.configPath)
+	if err != nil {
+		return err
+	}
+	serverCfg, ok := cfg.Tools.MCP.Servers[serverName]
+	if !ok || (serverCfg.OAuth != nil && serverCfg.OAuth.Enabled) {
+		return nil
+	}
+	if serverCfg.OAuth == nil {
+		serverCfg.OAuth = &config.MCPOAuthConfig{}
+	}
+	serverCfg.OAuth.Enabled = true
+	cfg.Tools.MCP.Servers[serverName] = serverCfg
+	return oauthSaveConfig(h.configPath, cfg)
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/auth"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/mcp"
+)
+
+func TestMCPOAuthBrowserFlowCompletesViaCallback(t *testing.T) {
+	configPath, cleanup := setupOAuthTestEnv(t)
+	defer cleanup()
+	resetOAuthHooks(t)
+
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig error: %v", err)
+	}
+	cfg.Tools.MCP.Servers = map[string]config.MCPServerConfig{
+		"remote": {Enabled: true, Type: "http", URL: "https://mcp.example.com/mcp"},
+		"local":  {Enabled: true, Command: "mcp-local"},
+	}
+	if err = config.SaveConfig(configPath, cfg); err != nil {
+		t.Fatalf("SaveConfig error: %v", err)
+	}
+
+	var gotRedirect string
+	oauthStartMCP = func(
+		_ context.Context,
+		serverName string,
+		_ config.MCPServerConfig,
+		redirectURI string,
+	) (*mcp.OAuthFlow, error) {
+		gotRedirect = redirectURI
+		return &mcp.OAuthFlow{
+			Server:      serverName,
+			AuthURL:     "https://auth.example.com/authorize?state=mcp-state",
+			State:       "mcp-state",
+			RedirectURI: redirectURI,
+		}, nil
+	}
+	var gotCode string
+	oauthCompleteMCP = func(_ context.Context, flow *mcp.OAuthFlow, code string) (*auth.AuthCredential, error) {
+		gotCode = code
+		cred := &auth.AuthCredential{AccessToken: "mcp-token", Provider: mcp.OAuthCredentialKey(flow.Server)}
+		return cred, auth.SetCredential(cred.Provider, cred)
+	}
+
+	h := NewHandler(configPath)
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/oauth/mcp/login", strings.NewReader(`{"server":"remote"}`))
+	req.Host = "localhost:18800"
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("login status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+	var loginResp map[string]any
+	if err = json.Unmarshal(rec.Body.Bytes(), &loginResp); err != nil {
+		t.Fatalf("unmarshal login response: %v", err)
+	}
+	flowID, _ := loginResp["flow_id"].(string)
+	if flowID == "" || loginResp["auth_url"] != "https://auth.example.com/authorize?state=mcp-state" {
+		t.Fatalf("unexpected login response: %v", loginResp)
+	}
+	if gotRedirect != "http://localhost:18800/oauth/callback" {
+		t.Fatalf("redirect URI = %q", gotRedirect)
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/oauth/callback?state=mcp-state&code=abc", nil)
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("callback status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+	if gotCode != "abc" {
+		t.Fatalf("code = %q, want abc", gotCode)
+	}
+	if flow, ok := h.getOAuthFlow(flowID); !ok || flow.Status != oauthFlowSuccess {
+		t.Fatalf("flow = %+v, want success", flow)
+	}
+
+	updated, err := config.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig error: %v", err)
+	}
+	if oauthCfg := updated.Tools.MCP.Servers["remote"].OAuth; oauthCfg == nil || !oauthCfg.Enabled {
+		t.Fatalf("oauth not enabled for server: %+v", updated.Tools.MCP.Servers["remote"])
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/api/oauth/mcp/servers", nil)
+	mux.ServeHTTP(rec, req)
+	var listResp struct {
+		Servers []mcpOAuthServerStatus `json:"servers"`
+	}
+	if err = json.Unmarshal(rec.Body.Bytes(), &listResp); err != nil {
+		t.Fatalf("unmarshal list response: %v", err)
+	}
+	if len(listResp.Servers) != 1 || listResp.Servers[0].Server != "remote" ||
+		!listResp.Servers[0].LoggedIn || listResp.Servers[0].Status != "connected" {
+		t.Fatalf("unexpected servers: %+v", listResp.Servers)
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/api/oauth/mcp/logout", strings.NewReader(`{"server":"remote"}`))
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("logout status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+	if cred, _ := auth.GetCredential(mcp.OAuthCredentialKey("remote")); cred != nil {
+		t.Fatalf("credential not removed: %+v", cred)
+	}
+}
+
+func TestMCPOAuthLoginRejectsUnknownServer(t *testing.T) {
+	configPath, cleanup := setupOAuthTestEnv(t)
+	defer cleanup()
+	resetOAuthHooks(t)
+
+	h := NewHandler(configPath)
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/oauth/mcp/login", strings.NewReader(`{"server":"missing"}`))
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusNotFound, rec.Body.String())
+	}
+}
@@ -14,6 +14,7 @@ import (
 
 	"github.com/sipeed/picoclaw/pkg/auth"
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/mcp"
 	"github.com/sipeed/picoclaw/pkg/providers"
 )
 
@@ -89,6 +90,7 @@ type oauthFlow struct {
 	UserCode     string
 	VerifyURL    string
 	Interval     int
+	MCP          *mcp.OAuthFlow
 }
 
 type oauthProviderStatus struct {
@@ -124,6 +126,11 @@ func (h *Handler) registerOAuthRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("POST /api/oauth/flows/{id}/poll", h.handlePollOAuthFlow)
 	mux.HandleFunc("POST /api/oauth/logout", h.handleOAuthLogout)
 	mux.HandleFunc("GET /oauth/callback", h.handleOAuthCallback)
+
+	// MCP server authorization shares the flow store and callback.
+	mux.HandleFunc("GET /api/oauth/mcp/servers", h.handleListMCPOAuthServers)
+	mux.HandleFunc("POST /api/oauth/mcp/login", h.handleMCPOAuthLogin)
+	mux.HandleFunc("POST /api/oauth/mcp/logout", h.handleMCPOAuthLogout)
 }
 
 func (h *Handler) handleListOAuthProviders(w http.ResponseWriter, r *http.Request) {
@@ -151,14 +158,7 @@ func (h *Handler) handleListOAuthProviders(w http.ResponseWriter, r *http.Reques
 			if !cred.ExpiresAt.IsZero() {
 				item.ExpiresAt = cred.ExpiresAt.Format(time.RFC3339)
 			}
-			switch {
-			case cred.IsExpired():
-				item.Status = "expired"
-			case cred.NeedsRefresh():
-				item.Status = "needs_refresh"
-			default:
-				item.Status = "connected"
-			}
+			item.Status = oauthCredentialStatus(cred)
 		}
 
 		providersResp = append(providersResp, item)
@@ -170,6 +170,17 @@ func (h *Handler) handleListOAuthProviders(w http.ResponseWriter, r *http.Reques
 	})
 }
 
+func oauthCredentialStatus(cred *auth.AuthCredential) string {
+	switch {
+	case cred.IsExpired():
+		return "expired"
+	case cred.NeedsRefresh():
+		return "needs_refresh"
+	default:
+		return "connected"
+	}
+}
+
 func (h *Handler) handleOAuthLogin(w http.ResponseWriter, r *http.Request) {
 	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
 	if err != nil {
@@ -428,6 +439,11 @@ func (h *Handler) handleOAuthCallback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if flow.MCP != nil {
+		h.completeMCPOAuth(w, r, flow, code)
+		return
+	}
+
 	cfg, err := oauthConfigForProvider(flow.Provider)
 	if err != nil {
 		h.setOAuthFlowError(flow.ID, err.Error())
@@ -273,6 +273,8 @@ func resetOAuthHooks(t *testing.T) {
 	origSaveConfig := oauthSaveConfig
 	origFetchProject := oauthFetchAntigravityProject
 	origFetchGoogleEmail := oauthFetchGoogleUserEmailFunc
+	origStartMCP := oauthStartMCP
+	origCompleteMCP := oauthCompleteMCP
 
 	t.Cleanup(func() {
 		oauthNow = origNow
@@ -289,5 +291,7 @@ func resetOAuthHooks(t *testing.T) {
 		oauthSaveConfig = origSaveConfig
 		oauthFetchAntigravityProject = origFetchProject
 		oauthFetchGoogleUserEmailFunc = origFetchGoogleEmail
+		oauthStartMCP = origStartMCP
+		oauthCompleteMCP = origCompleteMCP
 	})
 }
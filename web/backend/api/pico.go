@@ -9,7 +9,9 @@ import (
 	"net/http/httputil"
 	"time"
 
+	"github.com/sipeed/picoclaw/pkg/channels/pico"
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/web/backend/accounts"
 )
 
 // registerPicoRoutes binds Pico Channel management endpoints to the ServeMux.
@@ -35,15 +37,36 @@ func (h *Handler) createWsProxy() *httputil.ReverseProxy {
 }
 
 // handleWebSocketProxy wraps a reverse proxy to handle WebSocket connections.
-// The reverse proxy forwards the incoming upgrade handshake as-is.
+// The proxy authenticates to the gateway with the Pico token itself, so the
+// browser never needs it, and tells the gateway which account is chatting.
+// Viewers chat as "pico:viewer:<username>" and are refused unless the gateway
+// has permissions enabled to give them a role.
 func (h *Handler) handleWebSocketProxy() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if p, ok := principalFrom(r); ok {
+			cfg, err := config.LoadConfig(h.configPath)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Failed to load config: %v", err), http.StatusInternalServerError)
+				return
+			}
+			if p.Role != accounts.RoleAdmin && !cfg.Permissions.Enabled {
+				http.Error(w, "viewers can chat only when permissions are enabled in the gateway config",
+					http.StatusForbidden)
+				return
+			}
+			r = r.Clone(r.Context())
+			r.Header.Set("Authorization", "Bearer "+cfg.Channels.Pico.Token)
+			r.Header.Set(pico.WebUserHeader, p.Username)
+			r.Header.Set(pico.WebRoleHeader, p.Role)
+		}
 		proxy := h.createWsProxy()
 		proxy.ServeHTTP(w, r)
 	}
 }
 
 // handleGetPicoToken returns the current WS token and URL for the frontend.
+// The token is a secret and is left out for viewers; the WebSocket proxy
+// supplies it on their behalf.
 //
 //	GET /api/pico/token
 func (h *Handler) handleGetPicoToken(w http.ResponseWriter, r *http.Request) {
@@ -54,10 +77,14 @@ func (h *Handler) handleGetPicoToken(w http.ResponseWriter, r *http.Request) {
 	}
 
 	wsURL := h.buildWsURL(r, cfg)
+	token := cfg.Channels.Pico.Token
+	if p, ok := principalFrom(r); ok && p.Role != accounts.RoleAdmin {
+		token = ""
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]any{
-		"token":   cfg.Channels.Pico.Token,
+		"token":   token,
 		"ws_url":  wsURL,
 		"enabled": cfg.Channels.Pico.Enabled,
 	})
@@ -1,9 +1,11 @@
 package api
 
 import (
+	"log"
 	"net/http"
 	"sync"
 
+	"github.com/sipeed/picoclaw/web/backend/accounts"
 	"github.com/sipeed/picoclaw/web/backend/launcherconfig"
 )
 
@@ -17,16 +19,27 @@ type Handler struct {
 	oauthMu              sync.Mutex
 	oauthFlows           map[string]*oauthFlow
 	oauthState           map[string]string
+	accounts             *accounts.Store
+	accountsErr          error
+	sessions             *accounts.Sessions
+	loginLimiter         *accounts.LoginLimiter
 }
 
 // NewHandler creates an instance of the API handler.
 func NewHandler(configPath string) *Handler {
-	return &Handler{
-		configPath: configPath,
-		serverPort: launcherconfig.DefaultPort,
-		oauthFlows: make(map[string]*oauthFlow),
-		oauthState: make(map[string]string),
+	h := &Handler{
+		configPath:   configPath,
+		serverPort:   launcherconfig.DefaultPort,
+		oauthFlows:   make(map[string]*oauthFlow),
+		oauthState:   make(map[string]string),
+		sessions:     accounts.NewSessions(),
+		loginLimiter: accounts.NewLoginLimiter(),
 	}
+	h.accounts, h.accountsErr = accounts.Open(accounts.PathForAppConfig(configPath))
+	if h.accountsErr != nil {
+		log.Printf("Failed to load launcher accounts: %v", h.accountsErr)
+	}
+	return h
 }
 
 // SetServerOptions stores current backend listen options for fallback behavior.
@@ -69,6 +82,9 @@ func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
 
 	// Launcher service parameters (port/public)
 	h.registerLauncherConfigRoutes(mux)
+
+	// Login, accounts and API tokens
+	h.registerAuthRoutes(mux)
 }
 
 func (h *Handler) Shutdown() {}
@@ -14,6 +14,7 @@ import (
 
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/redact"
 )
 
 // registerSessionRoutes binds session list and detail endpoints to the ServeMux.
@@ -232,6 +233,27 @@ func buildSessionListItem(sessionID string, sess sessionFile) sessionListItem {
 	}
 }
 
+// sessionRedactor returns the redactor configured for the gateway, so the
+// viewer masks the same secrets the gateway does.
+func (h *Handler) sessionRedactor() *redact.Redactor {
+	cfg, err := config.LoadConfig(h.configPath)
+	if err != nil {
+		return nil
+	}
+	return redact.FromConfig(cfg)
+}
+
+// redactSession masks secrets in the summary and message contents of sess.
+func redactSession(sess *sessionFile, r *redact.Redactor) {
+	if r == nil {
+		return
+	}
+	sess.Summary = r.Redact(sess.Summary)
+	for i := range sess.Messages {
+		sess.Messages[i].Content = r.Redact(sess.Messages[i].Content)
+	}
+}
+
 func isEmptySession(sess sessionFile) bool {
 	return len(sess.Messages) == 0 && strings.TrimSpace(sess.Summary) == ""
 }
@@ -294,6 +316,7 @@ func (h *Handler) handleListSessions(w http.ResponseWriter, r *http.Request) {
 
 	items := []sessionListItem{}
 	seen := make(map[string]struct{})
+	redactor := h.sessionRedactor()
 
 	for _, entry := range entries {
 		if entry.IsDir() {
@@ -362,6 +385,7 @@ func (h *Handler) handleListSessions(w http.ResponseWriter, r *http.Request) {
 		}
 
 		seen[sessionID] = struct{}{}
+		redactSession(&sess, redactor)
 		items = append(items, buildSessionListItem(sessionID, sess))
 	}
 
@@ -437,6 +461,8 @@ func (h *Handler) handleGetSession(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	redactSession(&sess, h.sessionRedactor())
+
 	// Convert to a simpler format for the frontend
 	type chatMessage struct {
 		Role    string `json:"role"`
@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"net/http"
 	"runtime"
+	"sort"
+	"time"
 
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/mcp"
 )
 
 type toolCatalogEntry struct {
@@ -26,7 +29,18 @@ type toolSupportItem struct {
 }
 
 type toolSupportResponse struct {
-	Tools []toolSupportItem `json:"tools"`
+	Tools      []toolSupportItem      `json:"tools"`
+	MCPServers []mcpServerSupportItem `json:"mcp_servers,omitempty"`
+}
+
+// mcpServerSupportItem is the live state of a configured MCP server as
+// reported by the running gateway.
+type mcpServerSupportItem struct {
+	Name      string `json:"name"`
+	State     string `json:"state"`
+	Tools     int    `json:"tools"`
+	Attempts  int    `json:"attempts,omitempty"`
+	LastError string `json:"last_error,omitempty"`
 }
 
 type toolStateRequest struct {
@@ -40,6 +54,12 @@ var toolCatalog = []toolCatalogEntry{
 		Category:    "filesystem",
 		ConfigKey:   "read_file",
 	},
+	{
+		Name:        "read_document",
+		Description: "Extract text from PDF, office, EPUB, HTML and CSV documents page by page.",
+		Category:    "filesystem",
+		ConfigKey:   "read_document",
+	},
 	{
 		Name:        "write_file",
 		Description: "Create or overwrite files within the writable workspace scope.",
@@ -52,6 +72,18 @@ var toolCatalog = []toolCatalogEntry{
 		Category:    "filesystem",
 		ConfigKey:   "list_dir",
 	},
+	{
+		Name:        "glob",
+		Description: "Find files by path pattern, skipping anything ignored by .gitignore.",
+		Category:    "filesystem",
+		ConfigKey:   "glob",
+	},
+	{
+		Name:        "grep",
+		Description: "Search file contents with regular expressions and show matching lines with context.",
+		Category:    "filesystem",
+		ConfigKey:   "grep",
+	},
 	{
 		Name:        "edit_file",
 		Description: "Apply targeted edits to existing files without rewriting everything.",
@@ -64,12 +96,30 @@ var toolCatalog = []toolCatalogEntry{
 		Category:    "filesystem",
 		ConfigKey:   "append_file",
 	},
+	{
+		Name:        "apply_patch",
+		Description: "Apply a multi-file patch atomically, checking every hunk before writing.",
+		Category:    "filesystem",
+		ConfigKey:   "apply_patch",
+	},
+	{
+		Name:        "git",
+		Description: "Inspect and commit changes in workspace repositories, with opt-in push and pull to configured remotes.",
+		Category:    "filesystem",
+		ConfigKey:   "git",
+	},
 	{
 		Name:        "exec",
 		Description: "Run shell commands inside the configured workspace sandbox.",
 		Category:    "filesystem",
 		ConfigKey:   "exec",
 	},
+	{
+		Name:        "shell_session",
+		Description: "Keep interactive shells open across calls so directories, variables, and processes persist.",
+		Category:    "filesystem",
+		ConfigKey:   "shell_session",
+	},
 	{
 		Name:        "cron",
 		Description: "Schedule one-time or recurring reminders, jobs, and shell commands.",
@@ -88,18 +138,42 @@ var toolCatalog = []toolCatalogEntry{
 		Category:    "web",
 		ConfigKey:   "web_fetch",
 	},
+	{
+		Name:        "http_request",
+		Description: "Call HTTP APIs with any method, headers, and body, using stored auth profiles.",
+		Category:    "web",
+		ConfigKey:   "http_request",
+	},
+	{
+		Name:        "browser",
+		Description: "Drive a headless Chromium to read, click through, and screenshot JavaScript-heavy pages.",
+		Category:    "web",
+		ConfigKey:   "browser",
+	},
 	{
 		Name:        "message",
 		Description: "Send a follow-up message back to the active user or chat.",
 		Category:    "communication",
 		ConfigKey:   "message",
 	},
+	{
+		Name:        "ask_user",
+		Description: "Ask the user a question with fixed options, shown as buttons where supported.",
+		Category:    "communication",
+		ConfigKey:   "ask_user",
+	},
 	{
 		Name:        "send_file",
 		Description: "Send an outbound file or media attachment to the active chat.",
 		Category:    "communication",
 		ConfigKey:   "send_file",
 	},
+	{
+		Name:        "generate_image",
+		Description: "Generate images from a prompt with configured image models and send them to the chat.",
+		Category:    "communication",
+		ConfigKey:   "generate_image",
+	},
 	{
 		Name:        "find_skills",
 		Description: "Search external skill registries for installable skills.",
@@ -164,7 +238,8 @@ func (h *Handler) handleListTools(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(toolSupportResponse{
-		Tools: buildToolSupport(cfg),
+		Tools:      buildToolSupport(cfg),
+		MCPServers: h.buildMCPServerSupport(cfg),
 	})
 }
 
@@ -224,6 +299,15 @@ func buildToolSupport(cfg *config.Config) []toolSupportItem {
 			status, reasonCode = resolveDiscoveryToolSupport(cfg, cfg.Tools.MCP.Discovery.UseRegex)
 		case "tool_search_tool_bm25":
 			status, reasonCode = resolveDiscoveryToolSupport(cfg, cfg.Tools.MCP.Discovery.UseBM25)
+		case "shell_session":
+			if cfg.Tools.IsToolEnabled(entry.ConfigKey) {
+				if cfg.Tools.IsToolEnabled("exec") {
+					status = "enabled"
+				} else {
+					status = "blocked"
+					reasonCode = "requires_exec"
+				}
+			}
 		case "i2c", "spi":
 			status, reasonCode = resolveHardwareToolSupport(cfg.Tools.IsToolEnabled(entry.ConfigKey))
 		default:
@@ -244,6 +328,54 @@ func buildToolSupport(cfg *config.Config) []toolSupportItem {
 	return items
 }
 
+// buildMCPServerSupport lists the configured MCP servers with the state the
+// gateway reports for them: "offline" when the gateway is not running and
+// "pending" when it has not picked the server up yet.
+func (h *Handler) buildMCPServerSupport(cfg *config.Config) []mcpServerSupportItem {
+	if !cfg.Tools.IsToolEnabled("mcp") || len(cfg.Tools.MCP.Servers) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(cfg.Tools.MCP.Servers))
+	for name := range cfg.Tools.MCP.Servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var live map[string]mcp.ServerStatus
+	if resp, statusCode, err := h.getGatewayHealth(cfg, time.Second); err == nil && statusCode == http.StatusOK {
+		var statuses []mcp.ServerStatus
+		if raw, ok := resp.Components["mcp"]; ok {
+			_ = json.Unmarshal(raw, &statuses)
+		}
+		live = make(map[string]mcp.ServerStatus, len(statuses))
+		for _, st := range statuses {
+			live[st.Name] = st
+		}
+	}
+
+	items := make([]mcpServerSupportItem, 0, len(names))
+	for _, name := range names {
+		item := mcpServerSupportItem{Name: name}
+		st, ok := live[name]
+		switch {
+		case !cfg.Tools.MCP.Servers[name].Enabled:
+			item.State = "disabled"
+		case live == nil:
+			item.State = "offline"
+		case !ok:
+			item.State = "pending"
+		default:
+			item.State = st.State
+			item.Tools = st.Tools
+			item.Attempts = st.Attempts
+			item.LastError = st.LastError
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
 func resolveHardwareToolSupport(enabled bool) (string, string) {
 	if !enabled {
 		return "disabled", ""
@@ -271,26 +403,46 @@ func applyToolState(cfg *config.Config, toolName string, enabled bool) error {
 	switch toolName {
 	case "read_file":
 		cfg.Tools.ReadFile.Enabled = enabled
+	case "read_document":
+		cfg.Tools.ReadDocument.Enabled = enabled
 	case "write_file":
 		cfg.Tools.WriteFile.Enabled = enabled
 	case "list_dir":
 		cfg.Tools.ListDir.Enabled = enabled
+	case "glob":
+		cfg.Tools.Glob.Enabled = enabled
+	case "grep":
+		cfg.Tools.Grep.Enabled = enabled
 	case "edit_file":
 		cfg.Tools.EditFile.Enabled = enabled
 	case "append_file":
 		cfg.Tools.AppendFile.Enabled = enabled
+	case "apply_patch":
+		cfg.Tools.ApplyPatch.Enabled = enabled
+	case "git":
+		cfg.Tools.Git.Enabled = enabled
 	case "exec":
 		cfg.Tools.Exec.Enabled = enabled
+	case "shell_session":
+		cfg.Tools.ShellSession.Enabled = enabled
 	case "cron":
 		cfg.Tools.Cron.Enabled = enabled
 	case "web_search":
 		cfg.Tools.Web.Enabled = enabled
 	case "web_fetch":
 		cfg.Tools.WebFetch.Enabled = enabled
+	case "http_request":
+		cfg.Tools.HTTPRequest.Enabled = enabled
+	case "browser":
+		cfg.Tools.Browser.Enabled = enabled
 	case "message":
 		cfg.Tools.Message.Enabled = enabled
+	case "ask_user":
+		cfg.Tools.AskUser.Enabled = enabled
 	case "send_file":
 		cfg.Tools.SendFile.Enabled = enabled
+	case "generate_image":
+		cfg.Tools.GenerateImage.Enabled = enabled
 	case "find_skills":
 		cfg.Tools.FindSkills.Enabled = enabled
 		if enabled {
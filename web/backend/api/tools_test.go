@@ -3,10 +3,14 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/sipeed/picoclaw/pkg/config"
 )
@@ -26,6 +30,8 @@ func TestHandleListTools(t *testing.T) {
 	cfg.Tools.Skills.Enabled = true
 	cfg.Tools.Spawn.Enabled = true
 	cfg.Tools.Subagent.Enabled = false
+	cfg.Tools.ShellSession.Enabled = true
+	cfg.Tools.Exec.Enabled = false
 	cfg.Tools.MCP.Enabled = true
 	cfg.Tools.MCP.Discovery.Enabled = true
 	cfg.Tools.MCP.Discovery.UseRegex = true
@@ -67,6 +73,9 @@ func TestHandleListTools(t *testing.T) {
 	if gotTools["spawn"].Status != "blocked" || gotTools["spawn"].ReasonCode != "requires_subagent" {
 		t.Fatalf("spawn = %#v, want blocked/requires_subagent", gotTools["spawn"])
 	}
+	if gotTools["shell_session"].Status != "blocked" || gotTools["shell_session"].ReasonCode != "requires_exec" {
+		t.Fatalf("shell_session = %#v, want blocked/requires_exec", gotTools["shell_session"])
+	}
 	if gotTools["find_skills"].Status != "enabled" {
 		t.Fatalf("find_skills status = %q, want enabled", gotTools["find_skills"].Status)
 	}
@@ -123,6 +132,75 @@ func TestHandleListTools(t *testing.T) {
 	}
 }
 
+func TestHandleListToolsReportsMCPServers(t *testing.T) {
+	configPath, cleanup := setupOAuthTestEnv(t)
+	defer cleanup()
+
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	cfg.Tools.MCP.Enabled = true
+	cfg.Tools.MCP.Servers = map[string]config.MCPServerConfig{
+		"docs":   {Enabled: true, URL: "http://localhost:1"},
+		"github": {Enabled: true, Command: "gh-mcp"},
+		"new":    {Enabled: true, Command: "new-mcp"},
+		"off":    {Enabled: false, Command: "off-mcp"},
+	}
+	if err := config.SaveConfig(configPath, cfg); err != nil {
+		t.Fatalf("SaveConfig() error = %v", err)
+	}
+
+	originalHealthGet := gatewayHealthGet
+	t.Cleanup(func() {
+		gatewayHealthGet = originalHealthGet
+	})
+	gatewayUp := true
+	gatewayHealthGet = func(url string, timeout time.Duration) (*http.Response, error) {
+		if !gatewayUp {
+			return nil, errors.New("connection refused")
+		}
+		body := `{"status":"ok","pid":1,"components":{"mcp":[` +
+			`{"name":"docs","state":"connected","tools":3},` +
+			`{"name":"github","state":"reconnecting","attempts":2,"last_error":"exit status 1"}]}}`
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}, nil
+	}
+
+	h := NewHandler(configPath)
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	list := func() map[string]mcpServerSupportItem {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/tools", nil))
+		var resp toolSupportResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		got := make(map[string]mcpServerSupportItem, len(resp.MCPServers))
+		for _, item := range resp.MCPServers {
+			got[item.Name] = item
+		}
+		return got
+	}
+
+	got := list()
+	if item := got["docs"]; item.State != "connected" || item.Tools != 3 {
+		t.Fatalf("docs = %#v", item)
+	}
+	if item := got["github"]; item.State != "reconnecting" || item.Attempts != 2 || item.LastError == "" {
+		t.Fatalf("github = %#v", item)
+	}
+	if got["new"].State != "pending" || got["off"].State != "disabled" {
+		t.Fatalf("new = %#v, off = %#v", got["new"], got["off"])
+	}
+
+	gatewayUp = false
+	if got := list(); got["docs"].State != "offline" {
+		t.Fatalf("docs without gateway = %#v", got["docs"])
+	}
+}
+
 func TestHandleUpdateToolState(t *testing.T) {
 	configPath, cleanup := setupOAuthTestEnv(t)
 	defer cleanup()
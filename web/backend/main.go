@@ -141,7 +141,11 @@ func main() {
 	// Frontend Embedded Assets
 	registerEmbedRoutes(mux)
 
-	accessControlledMux, err := middleware.IPAllowlist(launcherCfg.AllowedCIDRs, mux)
+	if effectivePublic && !apiHandler.AccountsEnabled() {
+		log.Printf("Warning: the launcher is reachable from the network but has no accounts; open it and create an admin account to require login")
+	}
+
+	accessControlledMux, err := middleware.IPAllowlist(launcherCfg.AllowedCIDRs, apiHandler.RequireAuth(mux))
 	if err != nil {
 		log.Fatalf("Invalid allowed CIDR configuration: %v", err)
 	}
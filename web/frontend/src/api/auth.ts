@@ -0,0 +1,185 @@
+export type AccountRole = "admin" | "viewer"
+
+export interface AccountUser {
+  username: string
+  role: AccountRole
+  totp_enabled: boolean
+  created_at?: string
+}
+
+export interface AuthStatus {
+  accounts_enabled: boolean
+  authenticated: boolean
+  role?: AccountRole
+  user?: AccountUser
+  csrf_token?: string
+}
+
+export interface APIToken {
+  id: string
+  name: string
+  created_at: string
+  token?: string
+}
+
+const BASE_URL = ""
+
+// CSRF_COOKIE and CSRF_HEADER mirror the backend's session settings.
+const CSRF_COOKIE = "picoclaw_csrf"
+const CSRF_HEADER = "X-CSRF-Token"
+
+function readCookie(name: string): string {
+  const prefix = `${name}=`
+  for (const part of document.cookie.split(";")) {
+    const item = part.trim()
+    if (item.startsWith(prefix)) {
+      return decodeURIComponent(item.slice(prefix.length))
+    }
+  }
+  return ""
+}
+
+function isSafeMethod(method: string): boolean {
+  return ["GET", "HEAD", "OPTIONS"].includes(method.toUpperCase())
+}
+
+// installAuthFetch wraps window.fetch so every state-changing request carries
+// the session's CSRF token, and an expired login sends the browser back to
+// the login page.
+export function installAuthFetch() {
+  const originalFetch = window.fetch.bind(window)
+  window.fetch = async (input, init) => {
+    const method =
+      init?.method ?? (input instanceof Request ? input.method : "GET")
+    const csrf = readCookie(CSRF_COOKIE)
+    if (csrf && !isSafeMethod(method)) {
+      const headers = new Headers(
+        init?.headers ?? (input instanceof Request ? input.headers : undefined),
+      )
+      headers.set(CSRF_HEADER, csrf)
+      init = { ...init, headers }
+    }
+    const res = await originalFetch(input, init)
+    const url =
+      typeof input === "string"
+        ? input
+        : input instanceof URL
+          ? input.pathname
+          : input.url
+    if (
+      res.status === 401 &&
+      url.includes("/api/") &&
+      !url.includes("/api/auth/")
+    ) {
+      const next = window.location.pathname + window.location.search
+      window.location.assign(`/login?next=${encodeURIComponent(next)}`)
+    }
+    return res
+  }
+}
+
+async function request<T>(path: string, options?: RequestInit): Promise<T> {
+  const res = await fetch(`${BASE_URL}${path}`, options)
+  if (!res.ok) {
+    let message = ""
+    try {
+      const body = (await res.json()) as { error?: string }
+      message = body.error ?? ""
+    } catch {
+      // fall back to the status text
+    }
+    throw new Error(message || `API error: ${res.status} ${res.statusText}`)
+  }
+  return res.json() as Promise<T>
+}
+
+function postJSON<T>(path: string, payload: unknown): Promise<T> {
+  return request<T>(path, {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify(payload),
+  })
+}
+
+export async function getAuthStatus(): Promise<AuthStatus> {
+  return request<AuthStatus>("/api/auth/status")
+}
+
+export async function logout(): Promise<void> {
+  await postJSON<{ status: string }>("/api/auth/logout", {})
+  window.location.assign("/login")
+}
+
+export async function changePassword(
+  currentPassword: string,
+  newPassword: string,
+): Promise<{ status: string }> {
+  return postJSON("/api/auth/password", {
+    current_password: currentPassword,
+    new_password: newPassword,
+  })
+}
+
+export async function beginTOTP(): Promise<{ secret: string; uri: string }> {
+  return postJSON("/api/auth/totp/setup", {})
+}
+
+export async function enableTOTP(code: string): Promise<{ status: string }> {
+  return postJSON("/api/auth/totp/enable", { code })
+}
+
+export async function disableTOTP(
+  password: string,
+): Promise<{ status: string }> {
+  return postJSON("/api/auth/totp/disable", { password })
+}
+
+export async function getAPITokens(): Promise<{ tokens: APIToken[] }> {
+  return request<{ tokens: APIToken[] }>("/api/auth/tokens")
+}
+
+export async function createAPIToken(name: string): Promise<APIToken> {
+  return postJSON<APIToken>("/api/auth/tokens", { name })
+}
+
+export async function revokeAPIToken(id: string): Promise<{ status: string }> {
+  return request<{ status: string }>(
+    `/api/auth/tokens/${encodeURIComponent(id)}`,
+    { method: "DELETE" },
+  )
+}
+
+export async function getAccounts(): Promise<{ users: AccountUser[] }> {
+  return request<{ users: AccountUser[] }>("/api/auth/users")
+}
+
+export async function createAccount(
+  username: string,
+  password: string,
+  role: AccountRole,
+): Promise<AccountUser> {
+  return postJSON<AccountUser>("/api/auth/users", { username, password, role })
+}
+
+export async function updateAccount(
+  username: string,
+  update: { role?: AccountRole; password?: string },
+): Promise<AccountUser> {
+  return request<AccountUser>(
+    `/api/auth/users/${encodeURIComponent(username)}`,
+    {
+      method: "PUT",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify(update),
+    },
+  )
+}
+
+export async function deleteAccount(
+  username: string,
+): Promise<{ status: string }> {
+  return request<{ status: string }>(
+    `/api/auth/users/${encodeURIComponent(username)}`,
+    { method: "DELETE" },
+  )
+}
@@ -100,3 +100,46 @@ export async function logoutOAuth(
     },
   )
 }
+
+export interface MCPOAuthServerStatus {
+  server: string
+  url: string
+  oauth_enabled: boolean
+  logged_in: boolean
+  status: "connected" | "expired" | "needs_refresh" | "not_logged_in"
+  expires_at?: string
+}
+
+export interface MCPOAuthLoginResponse {
+  status: string
+  server: string
+  flow_id: string
+  auth_url: string
+  expires_at: string
+}
+
+export async function getMCPOAuthServers(): Promise<{
+  servers: MCPOAuthServerStatus[]
+}> {
+  return request<{ servers: MCPOAuthServerStatus[] }>("/api/oauth/mcp/servers")
+}
+
+export async function loginMCPOAuth(
+  server: string,
+): Promise<MCPOAuthLoginResponse> {
+  return request<MCPOAuthLoginResponse>("/api/oauth/mcp/login", {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ server }),
+  })
+}
+
+export async function logoutMCPOAuth(
+  server: string,
+): Promise<{ status: string; server: string }> {
+  return request<{ status: string; server: string }>("/api/oauth/mcp/logout", {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ server }),
+  })
+}
@@ -42,6 +42,7 @@ const SECRET_FIELD_MAP: Record<string, string> = {
   password: "_password",
   nickserv_password: "_nickserv_password",
   sasl_password: "_sasl_password",
+  secret: "_secret",
 }
 
 function asRecord(value: unknown): Record<string, unknown> {
@@ -166,6 +167,10 @@ function isConfigured(
       )
     case "irc":
       return asString(config.server) !== ""
+    case "webhook":
+      return asString(config.webhook_path) !== ""
+    case "mqtt":
+      return asString(config.broker) !== ""
     default:
       return false
   }
@@ -205,6 +210,10 @@ function getRequiredFieldKeys(channelName: string): string[] {
       return ["homeserver", "user_id", "access_token"]
     case "irc":
       return ["server"]
+    case "webhook":
+      return ["webhook_path"]
+    case "mqtt":
+      return ["broker", "subscribe_topics"]
     default:
       return []
   }
@@ -232,6 +241,8 @@ const CHANNELS_WITHOUT_DOCS = new Set([
   "wecom",
   "matrix",
   "irc",
+  "webhook",
+  "mqtt",
   "whatsapp",
   "whatsapp_native",
 ])
@@ -31,6 +31,7 @@ const SECRET_FIELDS = new Set([
   "password",
   "nickserv_password",
   "sasl_password",
+  "secret",
 ])
 
 // Fields to skip in the generic form (handled by enabled toggle or internal).
@@ -44,6 +45,8 @@ const OBJECT_FIELDS = new Set([
   "allow_token_query",
   "allow_from",
   "allow_origins",
+  "outbound_headers",
+  "tls",
 ])
 
 function formatLabel(key: string): string {
@@ -208,6 +208,7 @@ export function ConfigPage() {
           },
           session: {
             dm_scope: dmScope,
+            thread_scope: form.threadScope,
           },
           tools: {
             cron: {
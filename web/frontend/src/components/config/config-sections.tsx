@@ -4,6 +4,7 @@ import { useTranslation } from "react-i18next"
 import {
   type CoreConfigForm,
   DM_SCOPE_OPTIONS,
+  THREAD_SCOPE_OPTIONS,
   type LauncherForm,
 } from "@/components/config/form-model"
 import { Field, SwitchCardField } from "@/components/shared-form"
@@ -255,6 +256,9 @@ export function RuntimeSection({ form, onFieldChange }: RuntimeSectionProps) {
   const selectedDmScopeOption = DM_SCOPE_OPTIONS.find(
     (scope) => scope.value === form.dmScope,
   )
+  const selectedThreadScopeOption = THREAD_SCOPE_OPTIONS.find(
+    (scope) => scope.value === form.threadScope,
+  )
 
   return (
     <ConfigSectionCard title={t("pages.config.sections.runtime")}>
@@ -292,6 +296,40 @@ export function RuntimeSection({ form, onFieldChange }: RuntimeSectionProps) {
         </Select>
       </Field>
 
+      <Field
+        label={t("pages.config.thread_scope")}
+        hint={t("pages.config.thread_scope_hint")}
+        layout="setting-row"
+      >
+        <Select
+          value={form.threadScope}
+          onValueChange={(value) => onFieldChange("threadScope", value)}
+        >
+          <SelectTrigger className="w-full">
+            <SelectValue>
+              {selectedThreadScopeOption
+                ? t(
+                    selectedThreadScopeOption.labelKey,
+                    selectedThreadScopeOption.labelDefault,
+                  )
+                : form.threadScope}
+            </SelectValue>
+          </SelectTrigger>
+          <SelectContent>
+            {THREAD_SCOPE_OPTIONS.map((scope) => (
+              <SelectItem key={scope.value} value={scope.value}>
+                <div className="flex flex-col gap-0.5">
+                  <span className="font-medium">{t(scope.labelKey)}</span>
+                  <span className="text-muted-foreground text-xs">
+                    {t(scope.descKey)}
+                  </span>
+                </div>
+              </SelectItem>
+            ))}
+          </SelectContent>
+        </Select>
+      </Field>
+
       <SwitchCardField
         label={t("pages.config.heartbeat_enabled")}
         hint={t("pages.config.heartbeat_enabled_hint")}
@@ -16,6 +16,7 @@ export interface CoreConfigForm {
   summarizeMessageThreshold: string
   summarizeTokenPercent: string
   dmScope: string
+  threadScope: string
   heartbeatEnabled: boolean
   heartbeatInterval: string
   devicesEnabled: boolean
@@ -59,6 +60,23 @@ export const DM_SCOPE_OPTIONS = [
   },
 ] as const
 
+export const THREAD_SCOPE_OPTIONS = [
+  {
+    value: "per-thread",
+    labelKey: "pages.config.thread_scope_per_thread",
+    labelDefault: "Per Thread",
+    descKey: "pages.config.thread_scope_per_thread_desc",
+    descDefault: "Each thread or forum topic gets its own context.",
+  },
+  {
+    value: "shared",
+    labelKey: "pages.config.thread_scope_shared",
+    labelDefault: "Shared",
+    descKey: "pages.config.thread_scope_shared_desc",
+    descDefault: "Threads share the context of their parent chat.",
+  },
+] as const
+
 export const EMPTY_FORM: CoreConfigForm = {
   workspace: "",
   restrictToWorkspace: true,
@@ -75,6 +93,7 @@ export const EMPTY_FORM: CoreConfigForm = {
   summarizeMessageThreshold: "20",
   summarizeTokenPercent: "75",
   dmScope: "per-channel-peer",
+  threadScope: "shared",
   heartbeatEnabled: true,
   heartbeatInterval: "30",
   devicesEnabled: false,
@@ -177,6 +196,7 @@ export function buildFormFromConfig(config: unknown): CoreConfigForm {
       EMPTY_FORM.summarizeTokenPercent,
     ),
     dmScope: asString(session.dm_scope) || EMPTY_FORM.dmScope,
+    threadScope: asString(session.thread_scope) || EMPTY_FORM.threadScope,
     heartbeatEnabled:
       heartbeat.enabled === undefined
         ? EMPTY_FORM.heartbeatEnabled
@@ -139,17 +139,12 @@ export async function connectChat() {
       return
     }
 
-    if (!token) {
-      console.error("No pico token available")
-      updateChatStore({ connectionState: "error" })
-      isConnecting = false
-      scheduleReconnect(generation, sessionId)
-      return
-    }
-
     const finalWsUrl = normalizeWsUrlForBrowser(ws_url)
     const url = `${finalWsUrl}?session_id=${encodeURIComponent(sessionId)}`
-    const socket = new WebSocket(url, [`token.${token}`])
+    // Viewers get no token; the launcher's proxy authenticates for them.
+    const socket = token
+      ? new WebSocket(url, [`token.${token}`])
+      : new WebSocket(url)
 
     if (generation !== connectionGeneration) {
       isConnecting = false
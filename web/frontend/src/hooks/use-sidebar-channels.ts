@@ -9,10 +9,12 @@ import {
   IconBrandTelegram,
   IconBrandWechat,
   IconBrandWhatsapp,
+  IconBroadcast,
   IconCamera,
   IconMessages,
   IconPlug,
   IconRobot,
+  IconWebhook,
 } from "@tabler/icons-react"
 import type { TFunction } from "i18next"
 import { useAtomValue } from "jotai"
@@ -44,6 +46,8 @@ const CHANNEL_IMPORTANCE_ORDER = [
   "pico",
   "maixcam",
   "irc",
+  "webhook",
+  "mqtt",
   "whatsapp",
   "whatsapp_native",
 ]
@@ -85,6 +89,8 @@ const CHANNEL_ICON_MAP: Record<
   onebot: IconRobot,
   pico: IconBrandChrome,
   irc: IconMessages,
+  webhook: IconWebhook,
+  mqtt: IconBroadcast,
 }
 
 function asRecord(value: unknown): Record<string, unknown> {
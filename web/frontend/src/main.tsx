@@ -3,10 +3,13 @@ import { RouterProvider, createRouter } from "@tanstack/react-router"
 import { StrictMode } from "react"
 import ReactDOM from "react-dom/client"
 
+import { installAuthFetch } from "./api/auth"
 import "./i18n"
 import "./index.css"
 import { routeTree } from "./routeTree.gen"
 
+installAuthFetch()
+
 const queryClient = new QueryClient()
 
 const router = createRouter({